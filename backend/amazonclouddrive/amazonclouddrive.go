@@ -221,7 +221,7 @@ func (f *Fs) shouldRetry(ctx context.Context, resp *http.Response, err error) (b
 			return true, err
 		}
 	}
-	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), fserrors.RateLimitedHTTP(resp, err)
 }
 
 // If query parameters contain X-Amz-Algorithm remove Authorization header
@@ -393,11 +393,11 @@ func (f *Fs) shouldRetry(ctx context.Context, err error) (bool, error) {
 		statusCode := storageErr.Response().StatusCode
 		for _, e := range retryErrorCodes {
 			if statusCode == e {
-				return true, err
+				return true, fserrors.RateLimitedStatus(e, err)
 			}
 		}
 	} else if httpErr, ok := err.(httpError); ok {
-		return fserrors.ShouldRetryHTTP(httpErr.Response, retryErrorCodes), err
+		return fserrors.ShouldRetryHTTP(httpErr.Response, retryErrorCodes), fserrors.RateLimitedHTTP(httpErr.Response, err)
 	}
 	return fserrors.ShouldRetry(err), err
 }
@@ -29,6 +29,7 @@ import (
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/fshttp"
 	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
 	"github.com/rclone/rclone/fs/walk"
 	"github.com/rclone/rclone/lib/bucket"
 	"github.com/rclone/rclone/lib/encoder"
@@ -110,6 +111,20 @@ in the [b2 integrations checklist](https://www.backblaze.com/b2/docs/integration
 			Name:    "hard_delete",
 			Help:    "Permanently delete files on remote removal, otherwise hide files.",
 			Default: false,
+		}, {
+			Name: "hide_instead_of_delete",
+			Help: `Hide files instead of deleting their versions when purging.
+
+Normally purging a directory deletes every version of every file in
+it. If this is set then rclone only hides the files, like it does when
+deleting a single file, and leaves the bucket in place, so the
+bucket's lifecycle rules ("daysFromHidingToDeleting") delete the
+hidden versions later. This makes purges of big directories much
+quicker and lets them be undone until the rules run.
+
+This can't be used with --b2-hard-delete.`,
+			Default:  false,
+			Advanced: true,
 		}, {
 			Name: "upload_cutoff",
 			Help: `Cutoff for switching to chunked upload.
@@ -168,6 +183,18 @@ The duration before the download authorization token will expire.
 The minimum value is 1 second. The maximum value is one week.`,
 			Default:  fs.Duration(7 * 24 * time.Hour),
 			Advanced: true,
+		}, {
+			Name: "directory_marker",
+			Help: `Name of the placeholder file to mark directories with.
+
+If set, Mkdir uploads an empty file with this name into the directory
+and Rmdir deletes it. The B2 web interface uses ".bzEmpty" to keep
+folders it creates.
+
+These files aren't shown in listings but keep the directories they are
+in, so empty directories made by rclone or the web interface are
+preserved and can be synced.`,
+			Advanced: true,
 		}, {
 			Name:     "memory_pool_flush_time",
 			Default:  memoryPoolFlushTime,
@@ -202,12 +229,14 @@ type Options struct {
 	TestMode                      string               `config:"test_mode"`
 	Versions                      bool                 `config:"versions"`
 	HardDelete                    bool                 `config:"hard_delete"`
+	HideInsteadOfDelete           bool                 `config:"hide_instead_of_delete"`
 	UploadCutoff                  fs.SizeSuffix        `config:"upload_cutoff"`
 	CopyCutoff                    fs.SizeSuffix        `config:"copy_cutoff"`
 	ChunkSize                     fs.SizeSuffix        `config:"chunk_size"`
 	DisableCheckSum               bool                 `config:"disable_checksum"`
 	DownloadURL                   string               `config:"download_url"`
 	DownloadAuthorizationDuration fs.Duration          `config:"download_auth_duration"`
+	DirectoryMarker               string               `config:"directory_marker"`
 	MemoryPoolFlushTime           fs.Duration          `config:"memory_pool_flush_time"`
 	MemoryPoolUseMmap             bool                 `config:"memory_pool_use_mmap"`
 	Enc                           encoder.MultiEncoder `config:"encoding"`
@@ -324,7 +353,7 @@ func (f *Fs) shouldRetryNoReauth(ctx context.Context, resp *http.Response, err e
 		}
 		return true, pacer.RetryAfterError(err, time.Duration(retryAfter)*time.Second)
 	}
-	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), fserrors.RateLimitedHTTP(resp, err)
 }
 
 // shouldRetry returns a boolean as to whether this resp and err
@@ -418,6 +447,9 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	if err != nil {
 		return nil, errors.Wrap(err, "b2: chunk size")
 	}
+	if opt.HardDelete && opt.HideInsteadOfDelete {
+		return nil, errors.New("b2: can't use --b2-hard-delete with --b2-hide-instead-of-delete")
+	}
 	if opt.Account == "" {
 		return nil, errors.New("account not found")
 	}
@@ -452,6 +484,8 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		WriteMimeType:     true,
 		BucketBased:       true,
 		BucketBasedRootOK: true,
+
+		CanHaveEmptyDirectories: opt.DirectoryMarker != "",
 	}).Fill(ctx, f)
 	// Set the test flag if required
 	if opt.TestMode != "" {
@@ -718,6 +752,26 @@ func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBuck
 			if addBucket {
 				remote = path.Join(bucket, remote)
 			}
+			// is this a directory marker? These are returned
+			// from listings of all the versions as they need
+			// deleting with everything else
+			if !isDirectory && !hidden && !findFile && f.isDirectoryMarker(remote) {
+				// When recursing there are no directories in
+				// the listing so return the directory the
+				// marker is in unless it is the one being
+				// listed
+				if recurse && file.Name != directory+f.opt.DirectoryMarker {
+					dir := path.Dir(remote)
+					err = fn(dir, &api.File{Name: dir}, true)
+					if err != nil {
+						if err == errEndList {
+							return nil
+						}
+						return err
+					}
+				}
+				continue
+			}
 			// Send object
 			err = fn(remote, file, isDirectory)
 			if err != nil {
@@ -745,6 +799,9 @@ func (f *Fs) itemToDirEntry(ctx context.Context, remote string, object *api.File
 		d := fs.NewDir(remote, time.Time{})
 		return d, nil
 	}
+	if f.isDirectoryMarker(remote) {
+		return nil, nil
+	}
 	if remote == *last {
 		remote = object.UploadTimestamp.AddVersion(remote)
 	} else {
@@ -1008,10 +1065,54 @@ func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, opt
 	return f.Put(ctx, in, src, options...)
 }
 
-// Mkdir creates the bucket if it doesn't exist
+// Mkdir creates the bucket if it doesn't exist and the directory
+// marker if using directory markers
 func (f *Fs) Mkdir(ctx context.Context, dir string) error {
-	bucket, _ := f.split(dir)
-	return f.makeBucket(ctx, bucket)
+	bucket, directory := f.split(dir)
+	err := f.makeBucket(ctx, bucket)
+	if err != nil || f.opt.DirectoryMarker == "" || directory == "" {
+		return err
+	}
+	marker := path.Join(dir, f.opt.DirectoryMarker)
+	src := object.NewStaticObjectInfo(marker, time.Now(), 0, true, nil, nil)
+	_, err = f.Put(ctx, bytes.NewReader(nil), src)
+	if err != nil {
+		return errors.Wrap(err, "failed to create directory marker")
+	}
+	fs.Debugf(f, "Created directory marker %q", marker)
+	return nil
+}
+
+// isDirectoryMarker returns whether remote is the placeholder file
+// marking a directory
+func (f *Fs) isDirectoryMarker(remote string) bool {
+	return f.opt.DirectoryMarker != "" && path.Base(remote) == f.opt.DirectoryMarker
+}
+
+// removeDirectoryMarker deletes the marker in dir returning an error
+// if the directory isn't empty
+func (f *Fs) removeDirectoryMarker(ctx context.Context, dir string) error {
+	entries, err := f.List(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 0 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	marker := path.Join(dir, f.opt.DirectoryMarker)
+	o, err := f.NewObject(ctx, marker)
+	if err == fs.ErrorObjectNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	_, bucketPath := f.split(marker)
+	err = f.deleteByID(ctx, o.(*Object).id, bucketPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to remove directory marker")
+	}
+	fs.Debugf(f, "Removed directory marker for %q", dir)
+	return nil
 }
 
 // makeBucket creates the bucket if it doesn't exist
@@ -1054,11 +1155,15 @@ func (f *Fs) makeBucket(ctx context.Context, bucket string) error {
 	}, nil)
 }
 
-// Rmdir deletes the bucket if the fs is at the root
+// Rmdir deletes the bucket if the fs is at the root or the directory
+// marker if using directory markers
 //
 // Returns an error if it isn't empty
 func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	bucket, directory := f.split(dir)
+	if bucket != "" && directory != "" && f.opt.DirectoryMarker != "" {
+		return f.removeDirectoryMarker(ctx, dir)
+	}
 	if bucket == "" || directory != "" {
 		return nil
 	}
@@ -1177,6 +1282,10 @@ func (f *Fs) purge(ctx context.Context, dir string, oldOnly bool) error {
 		return false
 	}
 
+	// Hide the current versions only and leave the rest to the
+	// lifecycle rules if required
+	hideOnly := !oldOnly && f.opt.HideInsteadOfDelete
+
 	// Delete Config.Transfers in parallel
 	toBeDeleted := make(chan *api.File, f.ci.Transfers)
 	var wg sync.WaitGroup
@@ -1191,7 +1300,11 @@ func (f *Fs) purge(ctx context.Context, dir string, oldOnly bool) error {
 					continue
 				}
 				tr := accounting.Stats(ctx).NewCheckingTransfer(oi)
-				err = f.deleteByID(ctx, object.ID, object.Name)
+				if hideOnly {
+					err = f.hide(ctx, bucket, object.Name)
+				} else {
+					err = f.deleteByID(ctx, object.ID, object.Name)
+				}
 				checkErr(err)
 				tr.Done(ctx, err)
 			}
@@ -1205,7 +1318,12 @@ func (f *Fs) purge(ctx context.Context, dir string, oldOnly bool) error {
 				fs.Errorf(object, "Can't create object %+v", err)
 			}
 			tr := accounting.Stats(ctx).NewCheckingTransfer(oi)
-			if oldOnly && last != remote {
+			if hideOnly {
+				if last != remote && object.Action == "upload" {
+					fs.Debugf(remote, "Hiding (id %q)", object.ID)
+					toBeDeleted <- object
+				}
+			} else if oldOnly && last != remote {
 				// Check current version of the file
 				if object.Action == "hide" {
 					fs.Debugf(remote, "Deleting current version (id %q) as it is a hide marker", object.ID)
@@ -1228,7 +1346,7 @@ func (f *Fs) purge(ctx context.Context, dir string, oldOnly bool) error {
 	close(toBeDeleted)
 	wg.Wait()
 
-	if !oldOnly {
+	if !oldOnly && !hideOnly {
 		checkErr(f.Rmdir(ctx, dir))
 	}
 	return errReturn
@@ -1244,6 +1362,108 @@ func (f *Fs) CleanUp(ctx context.Context) error {
 	return f.purge(ctx, "", true)
 }
 
+// ListTrash lists the files in dir and its subdirectories which have
+// been hidden and so are deleted.
+func (f *Fs) ListTrash(ctx context.Context, dir string) (entries []fs.TrashEntry, err error) {
+	bucket, directory := f.split(dir)
+	if bucket == "" {
+		return nil, errors.New("can't list deleted files without a bucket")
+	}
+	last := ""
+	var hidden *fs.TrashEntry
+	err = f.list(ctx, bucket, directory, f.rootDirectory, f.rootBucket == "", true, 0, true, false, func(remote string, object *api.File, isDirectory bool) error {
+		if isDirectory || f.isDirectoryMarker(remote) {
+			return nil
+		}
+		// versions are listed newest first, so a hide marker
+		// which is the current version is followed by the
+		// version it hides
+		if remote == last {
+			if hidden != nil && object.Action == "upload" {
+				hidden.Size = object.Size
+				entries = append(entries, *hidden)
+				hidden = nil
+			}
+			return nil
+		}
+		if hidden != nil {
+			entries = append(entries, *hidden)
+			hidden = nil
+		}
+		last = remote
+		if object.Action == "hide" {
+			hidden = &fs.TrashEntry{
+				Remote:  remote,
+				Size:    -1,
+				Deleted: time.Time(object.UploadTimestamp),
+				ID:      object.ID,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if hidden != nil {
+		entries = append(entries, *hidden)
+	}
+	return entries, nil
+}
+
+// Undelete restores a deleted file by removing its hide marker
+func (f *Fs) Undelete(ctx context.Context, entry fs.TrashEntry) (fs.Object, error) {
+	_, bucketPath := f.split(entry.Remote)
+	err := f.deleteByID(ctx, entry.ID, bucketPath)
+	if err != nil {
+		return nil, err
+	}
+	return f.NewObject(ctx, entry.Remote)
+}
+
+// ListAt lists the files and directories in dir as they were at t,
+// that is the newest version of each file uploaded at or before t
+// unless that is a hide marker.
+func (f *Fs) ListAt(ctx context.Context, dir string, t time.Time) (entries fs.DirEntries, err error) {
+	bucket, directory := f.split(dir)
+	if bucket == "" {
+		return f.List(ctx, dir)
+	}
+	if f.opt.DownloadURL != "" {
+		return nil, errors.New("can't list old versions with download_url set as they are downloaded by name")
+	}
+	last := ""
+	err = f.list(ctx, bucket, directory, f.rootDirectory, f.rootBucket == "", false, 0, true, false, func(remote string, object *api.File, isDirectory bool) error {
+		if isDirectory {
+			entries = append(entries, fs.NewDir(remote, time.Time{}))
+			return nil
+		}
+		// versions are listed newest first so the first one
+		// at or before t is the one which was current then
+		if remote == last || time.Time(object.UploadTimestamp).After(t) || f.isDirectoryMarker(remote) {
+			return nil
+		}
+		switch object.Action {
+		case "hide":
+			last = remote
+			return nil
+		case "upload":
+			last = remote
+		default:
+			return nil
+		}
+		o, err := f.newObjectWithInfo(ctx, remote, object)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, o)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
 // copy does a server-side copy from dstObj <- srcObj
 //
 // If newInfo is nil then the metadata will be copied otherwise it
@@ -1991,14 +2211,17 @@ func (o *Object) ID() string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs           = &Fs{}
-	_ fs.Purger       = &Fs{}
-	_ fs.Copier       = &Fs{}
-	_ fs.PutStreamer  = &Fs{}
-	_ fs.CleanUpper   = &Fs{}
-	_ fs.ListRer      = &Fs{}
-	_ fs.PublicLinker = &Fs{}
-	_ fs.Object       = &Object{}
-	_ fs.MimeTyper    = &Object{}
-	_ fs.IDer         = &Object{}
+	_ fs.Fs             = &Fs{}
+	_ fs.Purger         = &Fs{}
+	_ fs.Copier         = &Fs{}
+	_ fs.PutStreamer    = &Fs{}
+	_ fs.CleanUpper     = &Fs{}
+	_ fs.TrashLister    = &Fs{}
+	_ fs.Undeleter      = &Fs{}
+	_ fs.SnapshotLister = &Fs{}
+	_ fs.ListRer        = &Fs{}
+	_ fs.PublicLinker   = &Fs{}
+	_ fs.Object         = &Object{}
+	_ fs.MimeTyper      = &Object{}
+	_ fs.IDer           = &Object{}
 )
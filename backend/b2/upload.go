@@ -80,6 +80,8 @@ type largeUpload struct {
 	size      int64                           // total size
 	parts     int64                           // calculated number of parts, if known
 	sha1s     []string                        // slice of SHA1s for each part
+	sha1      string                          // large_file_sha1 set when starting the upload, if any
+	hash      gohash.Hash                     // SHA1 of the data read from in, if being calculated
 	uploadMu  sync.Mutex                      // lock for upload variable
 	uploads   []*api.GetUploadPartURLResponse // result of get upload URL calls
 	chunkSize int64                           // chunk size to use
@@ -154,6 +156,7 @@ func (f *Fs) newLargeUpload(ctx context.Context, o *Object, in io.Reader, src fs
 		parts:     parts,
 		sha1s:     make([]string, sha1SliceSize),
 		chunkSize: int64(chunkSize),
+		sha1:      request.Info[sha1Key],
 	}
 	// unwrap the accounting from the input, we use wrap to put it
 	// back on after the buffering
@@ -162,6 +165,12 @@ func (f *Fs) newLargeUpload(ctx context.Context, o *Object, in io.Reader, src fs
 		up.src = src.(*Object)
 	} else {
 		up.in, up.wrap = accounting.UnWrap(in)
+		// Calculate the SHA1 of the whole file to check the
+		// large_file_sha1 with when finishing
+		if !f.opt.DisableCheckSum {
+			up.hash = sha1.New()
+			up.in = io.TeeReader(up.in, up.hash)
+		}
 	}
 	return up, nil
 }
@@ -310,6 +319,14 @@ func (up *largeUpload) copyChunk(ctx context.Context, part int64, partSize int64
 // finish closes off the large upload
 func (up *largeUpload) finish(ctx context.Context) error {
 	fs.Debugf(up.o, "Finishing large file %s with %d parts", up.what, up.parts)
+	if up.hash != nil {
+		calculatedSha1 := hex.EncodeToString(up.hash.Sum(nil))
+		if up.sha1 == "" {
+			fs.Debugf(up.o, "SHA1 %s not stored as it wasn't known when the upload started", calculatedSha1)
+		} else if !strings.EqualFold(up.sha1, calculatedSha1) {
+			return errors.Errorf("corrupted on transfer: SHA1 of source %s differs from SHA1 of data read %s", up.sha1, calculatedSha1)
+		}
+	}
 	opts := rest.Opts{
 		Method: "POST",
 		Path:   "/b2_finish_large_file",
@@ -384,6 +401,9 @@ func (up *largeUpload) Stream(ctx context.Context, initialUploadBlock []byte) (e
 			var n int
 			if part == 1 {
 				n = len(buf)
+				if up.hash != nil {
+					_, _ = up.hash.Write(buf)
+				}
 			} else {
 				n, err = io.ReadFull(up.in, buf)
 				if err == io.ErrUnexpectedEOF {
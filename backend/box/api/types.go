@@ -87,8 +87,10 @@ type Item struct {
 	ContentModifiedAt Time    `json:"content_modified_at"`
 	ItemStatus        string  `json:"item_status"` // active, trashed if the file has been moved to the trash, and deleted if the file has been permanently deleted
 	SharedLink        struct {
-		URL    string `json:"url,omitempty"`
-		Access string `json:"access,omitempty"`
+		URL               string `json:"url,omitempty"`
+		Access            string `json:"access,omitempty"`
+		UnsharedAt        *Time  `json:"unshared_at,omitempty"`
+		IsPasswordEnabled bool   `json:"is_password_enabled,omitempty"`
 	} `json:"shared_link"`
 }
 
@@ -184,11 +186,18 @@ type CopyFile struct {
 // CreateSharedLink is the request for Public Link
 type CreateSharedLink struct {
 	SharedLink struct {
-		URL    string `json:"url,omitempty"`
-		Access string `json:"access,omitempty"`
+		URL        string `json:"url,omitempty"`
+		Access     string `json:"access,omitempty"`
+		Password   string `json:"password,omitempty"`
+		UnsharedAt *Time  `json:"unshared_at,omitempty"`
 	} `json:"shared_link"`
 }
 
+// RemoveSharedLink is the request to remove a Public Link
+type RemoveSharedLink struct {
+	SharedLink *struct{} `json:"shared_link"` // must be nil
+}
+
 // UploadSessionRequest is uses in Create Upload Session
 type UploadSessionRequest struct {
 	FolderID string `json:"folder_id,omitempty"` // don't pass for update
@@ -326,7 +326,7 @@ func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, err
 		authRetry = true
 		fs.Debugf(nil, "Should retry: %v", err)
 	}
-	return authRetry || fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+	return authRetry || fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), fserrors.RateLimitedHTTP(resp, err)
 }
 
 // readMetaDataForPath reads the metadata from the path
@@ -1035,42 +1035,115 @@ func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string
 
 // PublicLink adds a "readable by anyone with link" permission on the given file or folder.
 func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	return f.publicLink(ctx, remote, expire, unlink, "")
+}
+
+// PublicLinkWithPassword makes a link to the given file or folder
+// which needs password to use it.
+func (f *Fs) PublicLinkWithPassword(ctx context.Context, remote string, expire fs.Duration, password string) (string, error) {
+	return f.publicLink(ctx, remote, expire, false, password)
+}
+
+// itemPath returns the API path of the file or folder at remote and
+// the object if it is a file
+func (f *Fs) itemPath(ctx context.Context, remote string) (string, *Object, error) {
 	id, err := f.dirCache.FindDir(ctx, remote, false)
-	var opts rest.Opts
 	if err == nil {
-		fs.Debugf(f, "attempting to share directory '%s'", remote)
+		return "/folders/" + id, nil, nil
+	}
+	o, err := f.NewObject(ctx, remote)
+	if err != nil {
+		return "", nil, err
+	}
+	return "/files/" + o.(*Object).id, o.(*Object), nil
+}
 
-		opts = rest.Opts{
-			Method:     "PUT",
-			Path:       "/folders/" + id,
-			Parameters: fieldsValue(),
-		}
+// publicLink makes, or removes if unlink is set, the shared link to
+// remote, protecting it with password if set
+func (f *Fs) publicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool, password string) (string, error) {
+	itemPath, o, err := f.itemPath(ctx, remote)
+	if err != nil {
+		return "", err
+	}
+	if o == nil {
+		fs.Debugf(f, "attempting to share directory '%s'", remote)
 	} else {
 		fs.Debugf(f, "attempting to share single file '%s'", remote)
-		o, err := f.NewObject(ctx, remote)
-		if err != nil {
-			return "", err
+		if o.publicLink != "" && !unlink && password == "" && expire == fs.DurationOff {
+			return o.publicLink, nil
 		}
+	}
 
-		if o.(*Object).publicLink != "" {
-			return o.(*Object).publicLink, nil
+	opts := rest.Opts{
+		Method:     "PUT",
+		Path:       itemPath,
+		Parameters: fieldsValue(),
+	}
+	var request interface{}
+	if unlink {
+		request = api.RemoveSharedLink{}
+	} else {
+		shareLink := api.CreateSharedLink{}
+		if password != "" {
+			// passwords can only be set on open links
+			shareLink.SharedLink.Access = "open"
+			shareLink.SharedLink.Password = password
 		}
-
-		opts = rest.Opts{
-			Method:     "PUT",
-			Path:       "/files/" + o.(*Object).id,
-			Parameters: fieldsValue(),
+		if expire < fs.DurationOff {
+			unsharedAt := api.Time(time.Now().Add(time.Duration(expire)))
+			shareLink.SharedLink.UnsharedAt = &unsharedAt
 		}
+		request = &shareLink
 	}
+	var info api.Item
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(ctx, &opts, request, &info)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return "", err
+	}
+	if o != nil {
+		o.publicLink = info.SharedLink.URL
+	}
+	return info.SharedLink.URL, nil
+}
 
-	shareLink := api.CreateSharedLink{}
+// ListPublicLinks returns the shared link to remote if it has one.
+func (f *Fs) ListPublicLinks(ctx context.Context, remote string) ([]fs.PublicLinkInfo, error) {
+	itemPath, _, err := f.itemPath(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       itemPath,
+		Parameters: fieldsValue(),
+	}
 	var info api.Item
 	var resp *http.Response
 	err = f.pacer.Call(func() (bool, error) {
-		resp, err = f.srv.CallJSON(ctx, &opts, &shareLink, &info)
+		resp, err = f.srv.CallJSON(ctx, &opts, nil, &info)
 		return shouldRetry(ctx, resp, err)
 	})
-	return info.SharedLink.URL, err
+	if err != nil {
+		return nil, err
+	}
+	links := []fs.PublicLinkInfo{}
+	if info.SharedLink.URL != "" {
+		link := fs.PublicLinkInfo{
+			Path:     remote,
+			URL:      info.SharedLink.URL,
+			Password: info.SharedLink.IsPasswordEnabled,
+		}
+		if info.SharedLink.UnsharedAt != nil {
+			expires := time.Time(*info.SharedLink.UnsharedAt)
+			link.Expires = &expires
+		}
+		links = append(links, link)
+	}
+	return links, nil
 }
 
 // deletePermanently permanently deletes a trashed file
@@ -1368,16 +1441,18 @@ func (o *Object) ID() string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs              = (*Fs)(nil)
-	_ fs.Purger          = (*Fs)(nil)
-	_ fs.PutStreamer     = (*Fs)(nil)
-	_ fs.Copier          = (*Fs)(nil)
-	_ fs.Abouter         = (*Fs)(nil)
-	_ fs.Mover           = (*Fs)(nil)
-	_ fs.DirMover        = (*Fs)(nil)
-	_ fs.DirCacheFlusher = (*Fs)(nil)
-	_ fs.PublicLinker    = (*Fs)(nil)
-	_ fs.CleanUpper      = (*Fs)(nil)
-	_ fs.Object          = (*Object)(nil)
-	_ fs.IDer            = (*Object)(nil)
+	_ fs.Fs                   = (*Fs)(nil)
+	_ fs.Purger               = (*Fs)(nil)
+	_ fs.PutStreamer          = (*Fs)(nil)
+	_ fs.Copier               = (*Fs)(nil)
+	_ fs.Abouter              = (*Fs)(nil)
+	_ fs.Mover                = (*Fs)(nil)
+	_ fs.DirMover             = (*Fs)(nil)
+	_ fs.DirCacheFlusher      = (*Fs)(nil)
+	_ fs.PublicLinker         = (*Fs)(nil)
+	_ fs.PublicLinkPassworder = (*Fs)(nil)
+	_ fs.PublicLinkLister     = (*Fs)(nil)
+	_ fs.CleanUpper           = (*Fs)(nil)
+	_ fs.Object               = (*Object)(nil)
+	_ fs.IDer                 = (*Object)(nil)
 )
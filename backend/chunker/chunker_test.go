@@ -38,8 +38,15 @@ func TestIntegration(t *testing.T) {
 		},
 		UnimplementableFsMethods: []string{
 			"PublicLink",
+			"PublicLinkWithPassword",
+			"ListPublicLinks",
+			"ListTrash",
+			"Undelete",
+			"ListVersions",
+			"ListAt",
 			"OpenWriterAt",
 			"MergeDirs",
+			"ListP",
 			"DirCacheFlush",
 			"UserInfo",
 			"Disconnect",
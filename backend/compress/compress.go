@@ -995,6 +995,21 @@ func (f *Fs) PublicLink(ctx context.Context, remote string, duration fs.Duration
 	return do(ctx, o.(*Object).Object.Remote(), duration, unlink)
 }
 
+// PublicLinkWithPassword generates a public link to the remote path
+// which needs password to use it
+func (f *Fs) PublicLinkWithPassword(ctx context.Context, remote string, duration fs.Duration, password string) (string, error) {
+	do := f.Fs.Features().PublicLinkWithPassword
+	if do == nil {
+		return "", errors.New("can't PublicLinkWithPassword: not supported by underlying remote")
+	}
+	o, err := f.NewObject(ctx, remote)
+	if err != nil {
+		// assume it is a directory
+		return do(ctx, remote, duration, password)
+	}
+	return do(ctx, o.(*Object).Object.Remote(), duration, password)
+}
+
 /*** OBJECT FUNCTIONS ***/
 
 // ObjectMetadata describes the metadata for an Object.
@@ -1390,27 +1405,28 @@ func (f *Fs) Precision() time.Duration {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs              = (*Fs)(nil)
-	_ fs.Purger          = (*Fs)(nil)
-	_ fs.Copier          = (*Fs)(nil)
-	_ fs.Mover           = (*Fs)(nil)
-	_ fs.DirMover        = (*Fs)(nil)
-	_ fs.PutStreamer     = (*Fs)(nil)
-	_ fs.CleanUpper      = (*Fs)(nil)
-	_ fs.UnWrapper       = (*Fs)(nil)
-	_ fs.ListRer         = (*Fs)(nil)
-	_ fs.Abouter         = (*Fs)(nil)
-	_ fs.Wrapper         = (*Fs)(nil)
-	_ fs.MergeDirser     = (*Fs)(nil)
-	_ fs.DirCacheFlusher = (*Fs)(nil)
-	_ fs.ChangeNotifier  = (*Fs)(nil)
-	_ fs.PublicLinker    = (*Fs)(nil)
-	_ fs.Shutdowner      = (*Fs)(nil)
-	_ fs.ObjectInfo      = (*ObjectInfo)(nil)
-	_ fs.GetTierer       = (*Object)(nil)
-	_ fs.SetTierer       = (*Object)(nil)
-	_ fs.Object          = (*Object)(nil)
-	_ fs.ObjectUnWrapper = (*Object)(nil)
-	_ fs.IDer            = (*Object)(nil)
-	_ fs.MimeTyper       = (*Object)(nil)
+	_ fs.Fs                   = (*Fs)(nil)
+	_ fs.Purger               = (*Fs)(nil)
+	_ fs.Copier               = (*Fs)(nil)
+	_ fs.Mover                = (*Fs)(nil)
+	_ fs.DirMover             = (*Fs)(nil)
+	_ fs.PutStreamer          = (*Fs)(nil)
+	_ fs.CleanUpper           = (*Fs)(nil)
+	_ fs.UnWrapper            = (*Fs)(nil)
+	_ fs.ListRer              = (*Fs)(nil)
+	_ fs.Abouter              = (*Fs)(nil)
+	_ fs.Wrapper              = (*Fs)(nil)
+	_ fs.MergeDirser          = (*Fs)(nil)
+	_ fs.DirCacheFlusher      = (*Fs)(nil)
+	_ fs.ChangeNotifier       = (*Fs)(nil)
+	_ fs.PublicLinker         = (*Fs)(nil)
+	_ fs.PublicLinkPassworder = (*Fs)(nil)
+	_ fs.Shutdowner           = (*Fs)(nil)
+	_ fs.ObjectInfo           = (*ObjectInfo)(nil)
+	_ fs.GetTierer            = (*Object)(nil)
+	_ fs.SetTierer            = (*Object)(nil)
+	_ fs.Object               = (*Object)(nil)
+	_ fs.ObjectUnWrapper      = (*Object)(nil)
+	_ fs.IDer                 = (*Object)(nil)
+	_ fs.MimeTyper            = (*Object)(nil)
 )
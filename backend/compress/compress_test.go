@@ -23,10 +23,16 @@ func TestIntegration(t *testing.T) {
 			"OpenWriterAt",
 			"MergeDirs",
 			"DirCacheFlush",
+			"ListP",
 			"PutUnchecked",
 			"PutStream",
 			"UserInfo",
 			"Disconnect",
+			"ListPublicLinks",
+			"ListTrash",
+			"Undelete",
+			"ListVersions",
+			"ListAt",
 		},
 		TiersToTest:                  []string{"STANDARD", "STANDARD_IA"},
 		UnimplementableObjectMethods: []string{}}
@@ -47,10 +53,16 @@ func TestRemoteGzip(t *testing.T) {
 			"OpenWriterAt",
 			"MergeDirs",
 			"DirCacheFlush",
+			"ListP",
 			"PutUnchecked",
 			"PutStream",
 			"UserInfo",
 			"Disconnect",
+			"ListPublicLinks",
+			"ListTrash",
+			"Undelete",
+			"ListVersions",
+			"ListAt",
 		},
 		UnimplementableObjectMethods: []string{
 			"GetTier",
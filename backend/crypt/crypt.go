@@ -18,6 +18,7 @@ import (
 	"github.com/rclone/rclone/fs/config/obscure"
 	"github.com/rclone/rclone/fs/fspath"
 	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/version"
 )
 
 // Globals
@@ -349,6 +350,24 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 	})
 }
 
+// ListP lists the objects and directories of the directory dir
+// calling callback with each page of entries as it is read.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	return f.Fs.Features().ListP(ctx, f.cipher.EncryptDirName(dir), func(entries fs.DirEntries) error {
+		newEntries, err := f.encryptEntries(ctx, entries)
+		if err != nil {
+			return err
+		}
+		return callback(newEntries)
+	})
+}
+
 // NewObject finds the Object at remote.
 func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 	o, err := f.Fs.NewObject(ctx, f.cipher.EncryptFileName(remote))
@@ -715,6 +734,125 @@ func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration,
 	return do(ctx, o.(*Object).Object.Remote(), expire, unlink)
 }
 
+// PublicLinkWithPassword generates a public link to the remote path
+// which needs password to use it
+func (f *Fs) PublicLinkWithPassword(ctx context.Context, remote string, expire fs.Duration, password string) (string, error) {
+	do := f.Fs.Features().PublicLinkWithPassword
+	if do == nil {
+		return "", errors.New("PublicLinkWithPassword not supported")
+	}
+	o, err := f.NewObject(ctx, remote)
+	if err != nil {
+		// assume it is a directory
+		return do(ctx, f.cipher.EncryptDirName(remote), expire, password)
+	}
+	return do(ctx, o.(*Object).Object.Remote(), expire, password)
+}
+
+// ListPublicLinks lists the public links to the remote path
+func (f *Fs) ListPublicLinks(ctx context.Context, remote string) ([]fs.PublicLinkInfo, error) {
+	do := f.Fs.Features().ListPublicLinks
+	if do == nil {
+		return nil, errors.New("ListPublicLinks not supported")
+	}
+	encryptedRemote := f.cipher.EncryptDirName(remote)
+	o, err := f.NewObject(ctx, remote)
+	if err == nil {
+		encryptedRemote = o.(*Object).Object.Remote()
+	}
+	links, err := do(ctx, encryptedRemote)
+	if err != nil {
+		return nil, err
+	}
+	for i := range links {
+		decrypted, err := f.cipher.DecryptFileName(links[i].Path)
+		if err != nil {
+			decrypted, err = f.cipher.DecryptDirName(links[i].Path)
+		}
+		if err != nil {
+			fs.Debugf(links[i].Path, "Skipping undecryptable file name: %v", err)
+			continue
+		}
+		links[i].Path = decrypted
+	}
+	return links, nil
+}
+
+// ListTrash lists the deleted files in dir and its subdirectories
+func (f *Fs) ListTrash(ctx context.Context, dir string) ([]fs.TrashEntry, error) {
+	do := f.Fs.Features().ListTrash
+	if do == nil {
+		return nil, errors.New("ListTrash not supported")
+	}
+	entries, err := do(ctx, f.cipher.EncryptDirName(dir))
+	if err != nil {
+		return nil, err
+	}
+	out := entries[:0]
+	for _, entry := range entries {
+		decrypted, err := f.cipher.DecryptFileName(entry.Remote)
+		if err != nil {
+			fs.Debugf(entry.Remote, "Skipping undecryptable file name: %v", err)
+			continue
+		}
+		entry.Remote = decrypted
+		if entry.Size >= 0 && !f.opt.NoDataEncryption {
+			entry.Size, err = f.cipher.DecryptedSize(entry.Size)
+			if err != nil {
+				fs.Debugf(entry.Remote, "Bad size for decrypt: %v", err)
+			}
+		}
+		out = append(out, entry)
+	}
+	return out, nil
+}
+
+// Undelete restores a deleted file
+func (f *Fs) Undelete(ctx context.Context, entry fs.TrashEntry) (fs.Object, error) {
+	do := f.Fs.Features().Undelete
+	if do == nil {
+		return nil, errors.New("Undelete not supported")
+	}
+	entry.Remote = f.cipher.EncryptFileName(entry.Remote)
+	o, err := do(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// ListVersions lists the old versions of the files in dir
+func (f *Fs) ListVersions(ctx context.Context, dir string) (fs.DirEntries, error) {
+	do := f.Fs.Features().ListVersions
+	if do == nil {
+		return nil, errors.New("ListVersions not supported")
+	}
+	entries, err := do(ctx, f.cipher.EncryptDirName(dir))
+	if err != nil {
+		return nil, err
+	}
+	out := entries[:0]
+	for _, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			out = append(out, f.newObject(o))
+		}
+	}
+	return out, nil
+}
+
+// ListAt lists the files and directories in dir as they were at t
+func (f *Fs) ListAt(ctx context.Context, dir string, t time.Time) (fs.DirEntries, error) {
+	do := f.Fs.Features().ListAt
+	if do == nil {
+		return nil, errors.New("ListAt not supported")
+	}
+	entries, err := do(ctx, f.cipher.EncryptDirName(dir), t)
+	if err != nil {
+		return nil, err
+	}
+	return f.encryptEntries(ctx, entries)
+}
+
 // ChangeNotify calls the passed function with a path
 // that has had changes. If the implementation
 // uses polling, it should adhere to the given interval.
@@ -841,6 +979,12 @@ func (o *Object) Remote() string {
 	remote := o.Object.Remote()
 	decryptedName, err := o.f.cipher.DecryptFileName(remote)
 	if err != nil {
+		// old versions have the version added to the encrypted name
+		if t, encryptedName := version.Remove(remote); !t.IsZero() {
+			if decryptedName, err := o.f.cipher.DecryptFileName(encryptedName); err == nil {
+				return version.Add(decryptedName, t)
+			}
+		}
 		fs.Debugf(remote, "Undecryptable file name: %v", err)
 		return remote
 	}
@@ -1051,30 +1195,37 @@ func (o *Object) GetTier() string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs              = (*Fs)(nil)
-	_ fs.Purger          = (*Fs)(nil)
-	_ fs.Copier          = (*Fs)(nil)
-	_ fs.Mover           = (*Fs)(nil)
-	_ fs.DirMover        = (*Fs)(nil)
-	_ fs.Commander       = (*Fs)(nil)
-	_ fs.PutUncheckeder  = (*Fs)(nil)
-	_ fs.PutStreamer     = (*Fs)(nil)
-	_ fs.CleanUpper      = (*Fs)(nil)
-	_ fs.UnWrapper       = (*Fs)(nil)
-	_ fs.ListRer         = (*Fs)(nil)
-	_ fs.Abouter         = (*Fs)(nil)
-	_ fs.Wrapper         = (*Fs)(nil)
-	_ fs.MergeDirser     = (*Fs)(nil)
-	_ fs.DirCacheFlusher = (*Fs)(nil)
-	_ fs.ChangeNotifier  = (*Fs)(nil)
-	_ fs.PublicLinker    = (*Fs)(nil)
-	_ fs.UserInfoer      = (*Fs)(nil)
-	_ fs.Disconnecter    = (*Fs)(nil)
-	_ fs.Shutdowner      = (*Fs)(nil)
-	_ fs.ObjectInfo      = (*ObjectInfo)(nil)
-	_ fs.Object          = (*Object)(nil)
-	_ fs.ObjectUnWrapper = (*Object)(nil)
-	_ fs.IDer            = (*Object)(nil)
-	_ fs.SetTierer       = (*Object)(nil)
-	_ fs.GetTierer       = (*Object)(nil)
+	_ fs.Fs                   = (*Fs)(nil)
+	_ fs.Purger               = (*Fs)(nil)
+	_ fs.Copier               = (*Fs)(nil)
+	_ fs.Mover                = (*Fs)(nil)
+	_ fs.DirMover             = (*Fs)(nil)
+	_ fs.Commander            = (*Fs)(nil)
+	_ fs.PutUncheckeder       = (*Fs)(nil)
+	_ fs.PutStreamer          = (*Fs)(nil)
+	_ fs.CleanUpper           = (*Fs)(nil)
+	_ fs.UnWrapper            = (*Fs)(nil)
+	_ fs.ListRer              = (*Fs)(nil)
+	_ fs.ListPer              = (*Fs)(nil)
+	_ fs.Abouter              = (*Fs)(nil)
+	_ fs.Wrapper              = (*Fs)(nil)
+	_ fs.MergeDirser          = (*Fs)(nil)
+	_ fs.DirCacheFlusher      = (*Fs)(nil)
+	_ fs.ChangeNotifier       = (*Fs)(nil)
+	_ fs.PublicLinker         = (*Fs)(nil)
+	_ fs.PublicLinkPassworder = (*Fs)(nil)
+	_ fs.PublicLinkLister     = (*Fs)(nil)
+	_ fs.TrashLister          = (*Fs)(nil)
+	_ fs.Undeleter            = (*Fs)(nil)
+	_ fs.VersionLister        = (*Fs)(nil)
+	_ fs.SnapshotLister       = (*Fs)(nil)
+	_ fs.UserInfoer           = (*Fs)(nil)
+	_ fs.Disconnecter         = (*Fs)(nil)
+	_ fs.Shutdowner           = (*Fs)(nil)
+	_ fs.ObjectInfo           = (*ObjectInfo)(nil)
+	_ fs.Object               = (*Object)(nil)
+	_ fs.ObjectUnWrapper      = (*Object)(nil)
+	_ fs.IDer                 = (*Object)(nil)
+	_ fs.SetTierer            = (*Object)(nil)
+	_ fs.GetTierer            = (*Object)(nil)
 )
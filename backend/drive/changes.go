@@ -0,0 +1,333 @@
+// Recursive listings kept up to date with the changes API for --drive-use-changes
+
+package drive
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/walk"
+	"golang.org/x/sync/errgroup"
+	drive "google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// changesSnapshotVersion should be increased if the snapshot format
+// changes so old snapshots are rebuilt
+const changesSnapshotVersion = 1
+
+// changesSnapshot is the listing of everything under a root directory
+// stored on disk along with the page token of the changes since
+type changesSnapshot struct {
+	Version   int                    `json:"version"`
+	RootID    string                 `json:"rootID"`
+	PageToken string                 `json:"pageToken"`
+	Files     map[string]*drive.File `json:"files"` // by the ID of the file or shortcut
+}
+
+// changesSnapshotPath returns the file the snapshot of rootID is
+// stored in
+func (f *Fs) changesSnapshotPath(rootID string) string {
+	h := sha1.Sum([]byte(f.name + ":" + rootID))
+	return filepath.Join(config.CacheDir, "drive-changes", hex.EncodeToString(h[:])+".json")
+}
+
+// readChangesSnapshot reads the snapshot of rootID returning nil if
+// there isn't a usable one
+func (f *Fs) readChangesSnapshot(rootID string) *changesSnapshot {
+	data, err := ioutil.ReadFile(f.changesSnapshotPath(rootID))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fs.Debugf(f, "Failed to read changes snapshot: %v", err)
+		}
+		return nil
+	}
+	var snapshot changesSnapshot
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		fs.Debugf(f, "Failed to decode changes snapshot: %v", err)
+		return nil
+	}
+	if snapshot.Version != changesSnapshotVersion || snapshot.RootID != rootID || snapshot.PageToken == "" || snapshot.Files == nil {
+		return nil
+	}
+	return &snapshot
+}
+
+// writeChangesSnapshot stores the snapshot
+func (f *Fs) writeChangesSnapshot(snapshot *changesSnapshot) (err error) {
+	snapshotPath := f.changesSnapshotPath(snapshot.RootID)
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	dirPath := filepath.Dir(snapshotPath)
+	err = os.MkdirAll(dirPath, 0700)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it into place so other
+	// rclones never see a partial snapshot
+	tmp, err := ioutil.TempFile(dirPath, filepath.Base(snapshotPath)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), snapshotPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// buildChangesSnapshot lists everything under rootID into a new
+// snapshot
+func (f *Fs) buildChangesSnapshot(ctx context.Context, rootID string) (*changesSnapshot, error) {
+	// Read the page token first so no changes made while listing are missed
+	pageToken, err := f.changeNotifyStartPageToken(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read start page token")
+	}
+	snapshot := &changesSnapshot{
+		Version:   changesSnapshotVersion,
+		RootID:    rootID,
+		PageToken: pageToken,
+		Files:     make(map[string]*drive.File),
+	}
+	var (
+		mu      sync.Mutex // protects snapshot.Files
+		g, gCtx = errgroup.WithContext(ctx)
+		tokens  = make(chan struct{}, f.ci.Checkers)
+	)
+	var listDir func(dirID string)
+	listDir = func(dirID string) {
+		g.Go(func() error {
+			tokens <- struct{}{}
+			defer func() { <-tokens }()
+			var dirs []string
+			_, err := f.list(gCtx, []string{dirID}, "", false, false, false, false, func(item *drive.File) bool {
+				mu.Lock()
+				snapshot.Files[shortcutID(item.Id)] = item
+				mu.Unlock()
+				if item.MimeType == driveFolderType {
+					dirs = append(dirs, actualID(item.Id))
+				}
+				return false
+			})
+			if err != nil {
+				return err
+			}
+			for _, dir := range dirs {
+				listDir(dir)
+			}
+			return nil
+		})
+	}
+	listDir(rootID)
+	err = g.Wait()
+	if err != nil {
+		return nil, err
+	}
+	fs.Debugf(f, "Built changes snapshot with %d items", len(snapshot.Files))
+	return snapshot, nil
+}
+
+// applyChanges reads the changes since the snapshot was made into it
+func (f *Fs) applyChanges(ctx context.Context, snapshot *changesSnapshot) error {
+	pageToken := snapshot.PageToken
+	fields := "nextPageToken,newStartPageToken,changes(fileId,removed,file(" + string(f.fileFields) + "))"
+	changed := 0
+	for {
+		var changeList *drive.ChangeList
+		err := f.pacer.Call(func() (bool, error) {
+			changesCall := f.svc.Changes.List(pageToken).Fields(googleapi.Field(fields))
+			if f.opt.ListChunk > 0 {
+				changesCall.PageSize(f.opt.ListChunk)
+			}
+			changesCall.SupportsAllDrives(true)
+			changesCall.IncludeItemsFromAllDrives(true)
+			changesCall.IncludeRemoved(true)
+			if f.isTeamDrive {
+				changesCall.DriveId(f.opt.TeamDriveID)
+			}
+			// If using appDataFolder then need to add Spaces
+			if f.rootFolderID == "appDataFolder" {
+				changesCall.Spaces("appDataFolder")
+			}
+			var err error
+			changeList, err = changesCall.Context(ctx).Do()
+			return f.shouldRetry(ctx, err)
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to read changes")
+		}
+		for _, change := range changeList.Changes {
+			changed++
+			item := change.File
+			if change.Removed || item == nil || item.Trashed {
+				delete(snapshot.Files, change.FileId)
+				continue
+			}
+			item.Name = f.opt.Enc.ToStandardName(item.Name)
+			if isShortcut(item) {
+				if f.opt.SkipShortcuts {
+					delete(snapshot.Files, change.FileId)
+					continue
+				}
+				item, err = f.resolveShortcut(ctx, item)
+				if err != nil {
+					return err
+				}
+			}
+			snapshot.Files[change.FileId] = item
+		}
+		switch {
+		case changeList.NewStartPageToken != "":
+			snapshot.PageToken = changeList.NewStartPageToken
+			fs.Debugf(f, "Read %d changes into changes snapshot", changed)
+			return nil
+		case changeList.NextPageToken != "":
+			pageToken = changeList.NextPageToken
+		default:
+			return errors.New("changes listing returned no page token")
+		}
+	}
+}
+
+// walk calls fn with the path relative to directoryID of every item
+// under it and forgets the items which aren't under the root
+func (snapshot *changesSnapshot) walk(directoryID string, fn func(remote string, item *drive.File) error) error {
+	// Index the items by parent
+	children := make(map[string][]*drive.File, len(snapshot.Files))
+	for _, item := range snapshot.Files {
+		for _, parent := range item.Parents {
+			children[parent] = append(children[parent], item)
+		}
+	}
+
+	// Walk the tree from the root noting which items are in it
+	reachable := make(map[string]struct{}, len(snapshot.Files))
+	var walkDir func(dirID, dirPath string, send bool) error
+	walkDir = func(dirID, dirPath string, send bool) error {
+		for _, item := range children[dirID] {
+			id := shortcutID(item.Id)
+			if _, found := reachable[id]; found {
+				continue
+			}
+			reachable[id] = struct{}{}
+			remote := path.Join(dirPath, item.Name)
+			if send {
+				err := fn(remote, item)
+				if err != nil {
+					return err
+				}
+			}
+			if item.MimeType == driveFolderType {
+				childPath, childSend := remote, send
+				if actualID(item.Id) == directoryID {
+					childPath, childSend = "", true
+				}
+				err := walkDir(actualID(item.Id), childPath, childSend)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	err := walkDir(snapshot.RootID, "", directoryID == snapshot.RootID)
+	if err != nil {
+		return err
+	}
+
+	// Forget the items which aren't in the tree
+	for id := range snapshot.Files {
+		if _, found := reachable[id]; !found {
+			delete(snapshot.Files, id)
+		}
+	}
+	return nil
+}
+
+// resolveFolderAlias returns the real ID of the folder with ID if it
+// is an alias like "root" which the parents of items never use
+func (f *Fs) resolveFolderAlias(ctx context.Context, ID string) (string, error) {
+	if ID != "root" && ID != "appDataFolder" {
+		return ID, nil
+	}
+	info, err := f.getFile(ctx, ID, "id")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read ID of %q", ID)
+	}
+	return info.Id, nil
+}
+
+// listRChanges lists dir, which has ID directoryID, recursively into
+// callback from the snapshot kept up to date with the changes API
+func (f *Fs) listRChanges(ctx context.Context, dir, directoryID string, callback fs.ListRCallback) error {
+	f.changesMu.Lock()
+	defer f.changesMu.Unlock()
+	rootID, err := f.dirCache.RootID(ctx, false)
+	if err != nil {
+		return err
+	}
+	rootID, err = f.resolveFolderAlias(ctx, actualID(rootID))
+	if err != nil {
+		return err
+	}
+	directoryID, err = f.resolveFolderAlias(ctx, directoryID)
+	if err != nil {
+		return err
+	}
+	snapshot := f.readChangesSnapshot(rootID)
+	if snapshot != nil {
+		err = f.applyChanges(ctx, snapshot)
+		if err != nil {
+			fs.Logf(f, "Rebuilding changes snapshot: %v", err)
+			snapshot = nil
+		}
+	}
+	if snapshot == nil {
+		snapshot, err = f.buildChangesSnapshot(ctx, rootID)
+		if err != nil {
+			return err
+		}
+	}
+
+	list := walk.NewListRHelper(callback)
+	err = snapshot.walk(directoryID, func(remote string, item *drive.File) error {
+		entry, err := f.itemToDirEntry(ctx, path.Join(dir, remote), item)
+		if err != nil || entry == nil {
+			return err
+		}
+		return list.Add(entry)
+	})
+	if err != nil {
+		return err
+	}
+	err = list.Flush()
+	if err != nil {
+		return err
+	}
+	err = f.writeChangesSnapshot(snapshot)
+	if err != nil {
+		fs.Errorf(f, "Failed to write changes snapshot: %v", err)
+	}
+	return nil
+}
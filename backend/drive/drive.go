@@ -44,6 +44,7 @@ import (
 	"github.com/rclone/rclone/lib/oauthutil"
 	"github.com/rclone/rclone/lib/pacer"
 	"github.com/rclone/rclone/lib/readers"
+	"github.com/rclone/rclone/lib/version"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	drive_v2 "google.golang.org/api/drive/v2"
@@ -69,10 +70,14 @@ const (
 	// 1<<18 is the minimum size supported by the Google uploader, and there is no maximum.
 	minChunkSize     = 256 * fs.Kibi
 	defaultChunkSize = 8 * fs.Mebi
-	partialFields    = "id,name,size,md5Checksum,trashed,explicitlyTrashed,modifiedTime,createdTime,mimeType,parents,webViewLink,shortcutDetails,exportLinks"
+	partialFields    = "id,name,size,md5Checksum,trashed,explicitlyTrashed,trashedTime,modifiedTime,createdTime,mimeType,parents,webViewLink,shortcutDetails,exportLinks,appProperties"
 	listRGrouping    = 50   // number of IDs to search at once when using ListR
 	listRInputBuffer = 1000 // size of input buffer when using ListR
 	defaultXDGIcon   = "text-html"
+	// appProperties set on imported docs so their modification time
+	// matches the source while they are unchanged
+	appPropSrcModTime    = "rcloneSrcModTime"    // modification time of the imported file
+	appPropImportModTime = "rcloneImportModTime" // modifiedTime of the doc once imported
 )
 
 // Globals
@@ -341,9 +346,13 @@ commands (copy, sync, etc.), and with all other commands too.`,
 			Advanced: true,
 			Hide:     fs.OptionHideConfigurator,
 		}, {
-			Name:     "export_formats",
-			Default:  defaultExportExtensions,
-			Help:     "Comma separated list of preferred formats for downloading Google docs.",
+			Name:    "export_formats",
+			Default: defaultExportExtensions,
+			Help: `Comma separated list of preferred formats for downloading Google docs.
+
+Any format may be prefixed with the type of document it is for and
+"=", e.g. "spreadsheet=ods" or "application/vnd.google-apps.drawing=png",
+to be preferred for that type of document before the other formats.`,
 			Advanced: true,
 		}, {
 			Name:     "import_formats",
@@ -532,6 +541,25 @@ Google don't document so it may break in the future.
 Normally rclone dereferences shortcut files making them appear as if
 they are the original file (see [the shortcuts section](#shortcuts)).
 If this flag is set then rclone will ignore shortcut files completely.
+`,
+			Advanced: true,
+			Default:  false,
+		}, {
+			Name: "use_changes",
+			Help: `Use the changes feed to list recursively
+
+If this is set then fast list (--fast-list) reads a snapshot of the
+whole tree saved in the cache directory and updates it with the
+changes made since from the Drive changes feed, rather than listing
+every directory.
+
+The first listing builds the snapshot so is as slow as a normal
+listing, but later listings of big trees which haven't changed much
+are nearly instant.
+
+This isn't used with --drive-shared-with-me, --drive-starred-only or
+--drive-trashed-only. Changes to the targets of shortcuts to files
+outside the tree may not be seen.
 `,
 			Advanced: true,
 			Default:  false,
@@ -594,30 +622,33 @@ type Options struct {
 	StopOnUploadLimit         bool                 `config:"stop_on_upload_limit"`
 	StopOnDownloadLimit       bool                 `config:"stop_on_download_limit"`
 	SkipShortcuts             bool                 `config:"skip_shortcuts"`
+	UseChanges                bool                 `config:"use_changes"`
 	Enc                       encoder.MultiEncoder `config:"encoding"`
 }
 
 // Fs represents a remote drive server
 type Fs struct {
-	name             string             // name of this remote
-	root             string             // the path we are working on
-	opt              Options            // parsed options
-	ci               *fs.ConfigInfo     // global config
-	features         *fs.Features       // optional features
-	svc              *drive.Service     // the connection to the drive server
-	v2Svc            *drive_v2.Service  // used to create download links for the v2 api
-	client           *http.Client       // authorized client
-	rootFolderID     string             // the id of the root folder
-	dirCache         *dircache.DirCache // Map of directory path to directory id
-	pacer            *fs.Pacer          // To pace the API calls
-	exportExtensions []string           // preferred extensions to download docs
-	importMimeTypes  []string           // MIME types to convert to docs
-	isTeamDrive      bool               // true if this is a team drive
-	fileFields       googleapi.Field    // fields to fetch file info with
+	name             string              // name of this remote
+	root             string              // the path we are working on
+	opt              Options             // parsed options
+	ci               *fs.ConfigInfo      // global config
+	features         *fs.Features        // optional features
+	svc              *drive.Service      // the connection to the drive server
+	v2Svc            *drive_v2.Service   // used to create download links for the v2 api
+	client           *http.Client        // authorized client
+	rootFolderID     string              // the id of the root folder
+	dirCache         *dircache.DirCache  // Map of directory path to directory id
+	pacer            *fs.Pacer           // To pace the API calls
+	exportExtensions []string            // preferred extensions to download docs
+	exportByMimeType map[string][]string // preferred extensions for each type of doc, tried first
+	importMimeTypes  []string            // MIME types to convert to docs
+	isTeamDrive      bool                // true if this is a team drive
+	fileFields       googleapi.Field     // fields to fetch file info with
 	m                configmap.Mapper
 	grouping         int32               // number of IDs to search at once in ListR - read with atomic
 	listRmu          *sync.Mutex         // protects listRempties
 	listRempties     map[string]struct{} // IDs of supposedly empty directories which triggered grouping disable
+	changesMu        *sync.Mutex         // serialises the use of the changes snapshot in ListR
 }
 
 type baseObject struct {
@@ -649,6 +680,14 @@ type Object struct {
 	v2Download bool   // generate v2 download link ondemand
 }
 
+// revisionObject describes an old revision of a drive file
+type revisionObject struct {
+	baseObject
+	revisionID string // Drive Id of this revision
+	url        string // Download URL of this revision
+	md5sum     string // md5sum of the revision
+}
+
 // ------------------------------------------------------------
 
 // Name of the remote (as passed into NewFs)
@@ -686,7 +725,7 @@ func (f *Fs) shouldRetry(ctx context.Context, err error) (bool, error) {
 	case *googleapi.Error:
 		if gerr.Code >= 500 && gerr.Code < 600 {
 			// All 5xx errors should be retried
-			return true, err
+			return true, fserrors.RateLimitedStatus(gerr.Code, err)
 		}
 		if len(gerr.Errors) > 0 {
 			reason := gerr.Errors[0].Reason
@@ -695,7 +734,7 @@ func (f *Fs) shouldRetry(ctx context.Context, err error) (bool, error) {
 					fs.Errorf(f, "Received upload limit error: %v", err)
 					return false, fserrors.FatalError(err)
 				}
-				return true, err
+				return true, pacer.RateLimitedError(err)
 			} else if f.opt.StopOnDownloadLimit && reason == "downloadQuotaExceeded" {
 				fs.Errorf(f, "Received download limit error: %v", err)
 				return false, fserrors.FatalError(err)
@@ -806,7 +845,7 @@ func (f *Fs) list(ctx context.Context, dirIDs []string, title string, directorie
 			// If the search title has an extension that is in the export extensions add a search
 			// for the filename without the extension.
 			// Assume that export extensions don't contain escape sequences.
-			for _, ext := range f.exportExtensions {
+			for _, ext := range f.allExportExtensions() {
 				if strings.HasSuffix(searchTitle, ext) {
 					stems = append(stems, title[:len(title)-len(ext)])
 					_, _ = fmt.Fprintf(&titleQuery, " or name='%s'", searchTitle[:len(searchTitle)-len(ext)])
@@ -980,6 +1019,47 @@ func parseExtensions(extensionsIn ...string) (extensions, mimeTypes []string, er
 	return
 }
 
+// parseExportFormats parses the export_formats into the extensions
+// for all documents, with the defaults added, and the extensions
+// preferred for each type of document.
+//
+// A format may be prefixed with the document MIME type or the short
+// name for it, eg "spreadsheet=ods", to apply to that type only.
+func parseExportFormats(formats string) (extensions []string, byMimeType map[string][]string, err error) {
+	var common []string
+	for _, format := range strings.Split(formats, ",") {
+		i := strings.IndexRune(format, '=')
+		if i < 0 {
+			common = append(common, format)
+			continue
+		}
+		docMimeType := strings.ToLower(strings.TrimSpace(format[:i]))
+		if docMimeType == "" {
+			return nil, nil, errors.Errorf("no document type in export format %q", format)
+		}
+		if !strings.Contains(docMimeType, "/") {
+			docMimeType = "application/vnd.google-apps." + docMimeType
+		}
+		docExtensions, _, err := parseExtensions(format[i+1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		if byMimeType == nil {
+			byMimeType = make(map[string][]string)
+		}
+		for _, extension := range docExtensions {
+			if !containsString(byMimeType[docMimeType], extension) {
+				byMimeType[docMimeType] = append(byMimeType[docMimeType], extension)
+			}
+		}
+	}
+	extensions, _, err = parseExtensions(strings.Join(common, ","), defaultExportExtensions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return extensions, byMimeType, nil
+}
+
 // getClient makes an http client according to the options
 func getClient(ctx context.Context, opt *Options) *http.Client {
 	t := fshttp.NewTransportCustom(ctx, func(t *http.Transport) {
@@ -1103,6 +1183,7 @@ func newFs(ctx context.Context, name, path string, m configmap.Mapper) (*Fs, err
 		grouping:     listRGrouping,
 		listRmu:      new(sync.Mutex),
 		listRempties: make(map[string]struct{}),
+		changesMu:    new(sync.Mutex),
 	}
 	f.isTeamDrive = opt.TeamDriveID != ""
 	f.fileFields = f.getFileFields()
@@ -1170,7 +1251,7 @@ func NewFs(ctx context.Context, name, path string, m configmap.Mapper) (fs.Fs, e
 		}
 		f.opt.Extensions, f.opt.ExportExtensions = "", f.opt.Extensions
 	}
-	f.exportExtensions, _, err = parseExtensions(f.opt.ExportExtensions, defaultExportExtensions)
+	f.exportExtensions, f.exportByMimeType, err = parseExportFormats(f.opt.ExportExtensions)
 	if err != nil {
 		return nil, err
 	}
@@ -1269,6 +1350,20 @@ func (f *Fs) newRegularObject(remote string, info *drive.File) fs.Object {
 	}
 }
 
+// newRevisionObject creates an fs.Object for an old revision of the
+// file info
+func (f *Fs) newRevisionObject(remote string, info *drive.File, revision *drive.Revision) fs.Object {
+	o := &revisionObject{
+		baseObject: f.newBaseObject(remote, info),
+		revisionID: revision.Id,
+		url:        fmt.Sprintf("%sfiles/%s/revisions/%s?alt=media", f.svc.BasePath, actualID(info.Id), revision.Id),
+		md5sum:     strings.ToLower(revision.Md5Checksum),
+	}
+	o.modifiedDate = revision.ModifiedTime
+	o.bytes = revision.Size
+	return o
+}
+
 // newDocumentObject creates an fs.Object for a google docs drive.File
 func (f *Fs) newDocumentObject(remote string, info *drive.File, extension, exportMimeType string) (fs.Object, error) {
 	mediaType, _, err := mime.ParseMediaType(exportMimeType)
@@ -1279,6 +1374,9 @@ func (f *Fs) newDocumentObject(remote string, info *drive.File, extension, expor
 	baseObject := f.newBaseObject(remote+extension, info)
 	baseObject.bytes = -1
 	baseObject.mimeType = exportMimeType
+	if srcModTime := f.importedModTime(info); srcModTime != "" {
+		baseObject.modifiedDate = srcModTime
+	}
 	return &documentObject{
 		baseObject:       baseObject,
 		url:              url,
@@ -1287,6 +1385,51 @@ func (f *Fs) newDocumentObject(remote string, info *drive.File, extension, expor
 	}, nil
 }
 
+// importedModTime returns the modification time of the file the doc
+// was imported from if it is unchanged since or "" otherwise.
+//
+// Drive doesn't always keep the modifiedTime set when converting a
+// file into a doc so this stops syncs copying the file again.
+func (f *Fs) importedModTime(info *drive.File) string {
+	if f.opt.UseCreatedDate || f.opt.UseSharedDate {
+		return ""
+	}
+	srcModTime := info.AppProperties[appPropSrcModTime]
+	if srcModTime == "" || info.AppProperties[appPropImportModTime] != info.ModifiedTime {
+		return ""
+	}
+	return srcModTime
+}
+
+// recordImport stores the modification time of the source on the doc
+// info just imported from it, returning the updated info
+func (f *Fs) recordImport(ctx context.Context, info *drive.File, srcModTime time.Time) (*drive.File, error) {
+	updateInfo := &drive.File{
+		// Set the modifiedTime the doc has so it doesn't change
+		ModifiedTime: info.ModifiedTime,
+		AppProperties: map[string]string{
+			appPropSrcModTime:    srcModTime.Format(timeFormatOut),
+			appPropImportModTime: info.ModifiedTime,
+		},
+	}
+	var newInfo *drive.File
+	err := f.pacer.Call(func() (bool, error) {
+		var err error
+		newInfo, err = f.svc.Files.Update(actualID(info.Id), updateInfo).
+			Fields(partialFields).
+			SupportsAllDrives(true).
+			Context(ctx).Do()
+		return f.shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to record import")
+	}
+	if newInfo.ModifiedTime != info.ModifiedTime {
+		fs.Debugf(f, "modifiedTime of imported doc %q changed from %s to %s", info.Name, info.ModifiedTime, newInfo.ModifiedTime)
+	}
+	return newInfo, nil
+}
+
 // newLinkObject creates an fs.Object that represents a link a google docs drive.File
 func (f *Fs) newLinkObject(remote string, info *drive.File, extension, exportMimeType string) (fs.Object, error) {
 	t := linkTemplate(exportMimeType)
@@ -1523,17 +1666,20 @@ func (f *Fs) findExportFormatByMimeType(ctx context.Context, itemMimeType string
 	extension, mimeType string, isDocument bool) {
 	exportMimeTypes, isDocument := f.exportFormats(ctx)[itemMimeType]
 	if isDocument {
-		for _, _extension := range f.exportExtensions {
-			_mimeType := mime.TypeByExtension(_extension)
-			if isLinkMimeType(_mimeType) {
-				return _extension, _mimeType, true
-			}
-			for _, emt := range exportMimeTypes {
-				if emt == _mimeType {
-					return _extension, emt, true
+		// Try the formats for this type of document first
+		for _, extensions := range [][]string{f.exportByMimeType[itemMimeType], f.exportExtensions} {
+			for _, _extension := range extensions {
+				_mimeType := mime.TypeByExtension(_extension)
+				if isLinkMimeType(_mimeType) {
+					return _extension, _mimeType, true
 				}
-				if _mimeType == _mimeTypeCustomTransform[emt] {
-					return _extension, emt, true
+				for _, emt := range exportMimeTypes {
+					if emt == _mimeType {
+						return _extension, emt, true
+					}
+					if _mimeType == _mimeTypeCustomTransform[emt] {
+						return _extension, emt, true
+					}
 				}
 			}
 		}
@@ -1543,6 +1689,23 @@ func (f *Fs) findExportFormatByMimeType(ctx context.Context, itemMimeType string
 	return "", "", isDocument
 }
 
+// allExportExtensions returns all the extensions docs may be exported
+// with
+func (f *Fs) allExportExtensions() []string {
+	if len(f.exportByMimeType) == 0 {
+		return f.exportExtensions
+	}
+	extensions := append([]string(nil), f.exportExtensions...)
+	for _, docExtensions := range f.exportByMimeType {
+		for _, extension := range docExtensions {
+			if !containsString(extensions, extension) {
+				extensions = append(extensions, extension)
+			}
+		}
+	}
+	return extensions
+}
+
 // findExportFormatByMimeType works out the optimum export settings
 // for the given drive.File.
 //
@@ -1808,6 +1971,10 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 	}
 	directoryID = actualID(directoryID)
 
+	if f.opt.UseChanges && !f.opt.SharedWithMe && !f.opt.StarredOnly && !f.opt.TrashedOnly {
+		return f.listRChanges(ctx, dir, directoryID, callback)
+	}
+
 	mu := sync.Mutex{} // protects in and overflow
 	wg := sync.WaitGroup{}
 	in := make(chan listREntry, listRInputBuffer)
@@ -2133,6 +2300,12 @@ func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo,
 			return nil, err
 		}
 	}
+	if isInternalMimeType(importMimeType) {
+		info, err = f.recordImport(ctx, info, modTime)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return f.newObjectWithInfo(ctx, remote, info)
 }
 
@@ -2575,17 +2748,12 @@ func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object,
 
 // PublicLink adds a "readable by anyone with link" permission on the given file or folder.
 func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (link string, err error) {
-	id, err := f.dirCache.FindDir(ctx, remote, false)
-	if err == nil {
-		fs.Debugf(f, "attempting to share directory '%s'", remote)
-		id = shortcutID(id)
-	} else {
-		fs.Debugf(f, "attempting to share single file '%s'", remote)
-		o, err := f.NewObject(ctx, remote)
-		if err != nil {
-			return "", err
-		}
-		id = shortcutID(o.(fs.IDer).ID())
+	id, err := f.shareID(ctx, remote)
+	if err != nil {
+		return "", err
+	}
+	if unlink {
+		return "", f.unlink(ctx, id)
 	}
 
 	permission := &drive.Permission{
@@ -2606,7 +2774,97 @@ func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration,
 	if err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("https://drive.google.com/open?id=%s", id), nil
+	return publicLinkURL(id), nil
+}
+
+// publicLinkURL returns the URL of the public link to id
+func publicLinkURL(id string) string {
+	return fmt.Sprintf("https://drive.google.com/open?id=%s", id)
+}
+
+// shareID returns the ID to use for sharing the file or directory
+// at remote
+func (f *Fs) shareID(ctx context.Context, remote string) (id string, err error) {
+	id, err = f.dirCache.FindDir(ctx, remote, false)
+	if err == nil {
+		fs.Debugf(f, "attempting to share directory '%s'", remote)
+		return shortcutID(id), nil
+	}
+	fs.Debugf(f, "attempting to share single file '%s'", remote)
+	o, err := f.NewObject(ctx, remote)
+	if err != nil {
+		return "", err
+	}
+	return shortcutID(o.(fs.IDer).ID()), nil
+}
+
+// anyonePermissions returns the "anyone" permissions on id - these
+// are what make the public link work
+func (f *Fs) anyonePermissions(ctx context.Context, id string) (perms []*drive.Permission, err error) {
+	var list *drive.PermissionList
+	err = f.pacer.Call(func() (bool, error) {
+		list, err = f.svc.Permissions.List(id).
+			Fields("permissions(id,type,expirationTime)").
+			SupportsAllDrives(true).
+			Context(ctx).Do()
+		return f.shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list permissions")
+	}
+	for _, perm := range list.Permissions {
+		if perm.Type == "anyone" {
+			perms = append(perms, perm)
+		}
+	}
+	return perms, nil
+}
+
+// unlink removes the "anyone" permissions from id
+func (f *Fs) unlink(ctx context.Context, id string) error {
+	perms, err := f.anyonePermissions(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, perm := range perms {
+		err = f.pacer.Call(func() (bool, error) {
+			err = f.svc.Permissions.Delete(id, perm.Id).
+				SupportsAllDrives(true).
+				Context(ctx).Do()
+			return f.shouldRetry(ctx, err)
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to remove public link")
+		}
+	}
+	return nil
+}
+
+// ListPublicLinks returns the public link to remote if it has one.
+func (f *Fs) ListPublicLinks(ctx context.Context, remote string) (links []fs.PublicLinkInfo, err error) {
+	id, err := f.shareID(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	perms, err := f.anyonePermissions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	links = []fs.PublicLinkInfo{}
+	if len(perms) > 0 {
+		link := fs.PublicLinkInfo{
+			Path: remote,
+			URL:  publicLinkURL(id),
+		}
+		if perms[0].ExpirationTime != "" {
+			expires, err := time.Parse(time.RFC3339, perms[0].ExpirationTime)
+			if err == nil {
+				link.Expires = &expires
+			}
+		}
+		links = append(links, link)
+	}
+	return links, nil
 }
 
 // DirMove moves src, srcRemote to this remote at dstRemote
@@ -3039,6 +3297,148 @@ func (f *Fs) unTrashDir(ctx context.Context, dir string, recurse bool) (r unTras
 	return f.unTrash(ctx, dir, directoryID, true)
 }
 
+// listTrash appends the explicitly trashed files in dir, directoryID
+// and its subdirectories to entries
+func (f *Fs) listTrash(ctx context.Context, dir string, directoryID string, entries *[]fs.TrashEntry) (err error) {
+	var iErr error
+	_, err = f.list(ctx, []string{actualID(directoryID)}, "", false, false, false, true, func(item *drive.File) bool {
+		remote := path.Join(dir, f.opt.Enc.ToStandardName(item.Name))
+		if item.MimeType == driveFolderType {
+			// files in trashed folders aren't explicitly trashed
+			if !item.Trashed && !isShortcutID(item.Id) {
+				iErr = f.listTrash(ctx, remote, item.Id, entries)
+				if iErr != nil {
+					return true
+				}
+			}
+			return false
+		}
+		if !item.ExplicitlyTrashed {
+			return false
+		}
+		o, err := f.newObjectWithInfo(ctx, remote, item)
+		if err != nil || o == nil {
+			fs.Debugf(remote, "Ignoring trashed item: %v", err)
+			return false
+		}
+		entry := fs.TrashEntry{
+			Remote: o.Remote(),
+			Size:   o.Size(),
+			ID:     item.Id,
+		}
+		if item.TrashedTime != "" {
+			entry.Deleted, err = time.Parse(timeFormatIn, item.TrashedTime)
+			if err != nil {
+				fs.Debugf(remote, "Failed to parse trashed time %q: %v", item.TrashedTime, err)
+			}
+		}
+		*entries = append(*entries, entry)
+		return false
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to list trash")
+	}
+	return iErr
+}
+
+// ListTrash lists the files in dir and its subdirectories which are
+// in the trash.
+//
+// Files in a directory which is in the trash aren't listed.
+func (f *Fs) ListTrash(ctx context.Context, dir string) (entries []fs.TrashEntry, err error) {
+	directoryID, err := f.dirCache.FindDir(ctx, dir, false)
+	if err != nil {
+		return nil, err
+	}
+	err = f.listTrash(ctx, dir, directoryID, &entries)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Undelete restores a file from the trash
+func (f *Fs) Undelete(ctx context.Context, entry fs.TrashEntry) (fs.Object, error) {
+	update := drive.File{
+		ForceSendFields: []string{"Trashed"}, // necessary to set false value
+		Trashed:         false,
+	}
+	err := f.pacer.Call(func() (bool, error) {
+		_, err := f.svc.Files.Update(entry.ID, &update).
+			SupportsAllDrives(true).
+			Fields("trashed").
+			Context(ctx).Do()
+		return f.shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to restore")
+	}
+	return f.NewObject(ctx, entry.Remote)
+}
+
+// ListVersions lists the old revisions of the files in dir with the
+// time they were modified added to their names.
+//
+// Google docs don't have revisions which can be downloaded so they
+// aren't listed.
+func (f *Fs) ListVersions(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	directoryID, err := f.dirCache.FindDir(ctx, dir, false)
+	if err != nil {
+		return nil, err
+	}
+	var files []*drive.File
+	_, err = f.list(ctx, []string{actualID(directoryID)}, "", false, true, false, false, func(item *drive.File) bool {
+		if item.Md5Checksum != "" {
+			files = append(files, item)
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range files {
+		remote := path.Join(dir, f.opt.Enc.ToStandardName(item.Name))
+		revisions, err := f.listRevisions(ctx, item.Id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list revisions of %q", remote)
+		}
+		// the last revision is the current one
+		for _, revision := range revisions[:len(revisions)-1] {
+			modTime, err := time.Parse(timeFormatIn, revision.ModifiedTime)
+			if err != nil {
+				fs.Debugf(remote, "Ignoring revision with bad modification time %q: %v", revision.ModifiedTime, err)
+				continue
+			}
+			entries = append(entries, f.newRevisionObject(version.Add(remote, modTime), item, revision))
+		}
+	}
+	return entries, nil
+}
+
+// listRevisions lists the revisions of the file with id, oldest first
+func (f *Fs) listRevisions(ctx context.Context, id string) (revisions []*drive.Revision, err error) {
+	list := f.svc.Revisions.List(actualID(id)).Fields("revisions(id,modifiedTime,size,md5Checksum),nextPageToken")
+	for {
+		var revisionList *drive.RevisionList
+		err = f.pacer.Call(func() (bool, error) {
+			revisionList, err = list.Context(ctx).Do()
+			return f.shouldRetry(ctx, err)
+		})
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revisionList.Revisions...)
+		if revisionList.NextPageToken == "" {
+			break
+		}
+		list.PageToken(revisionList.NextPageToken)
+	}
+	if len(revisions) == 0 {
+		return nil, errors.New("no revisions found")
+	}
+	return revisions, nil
+}
+
 // copy file with id to dest
 func (f *Fs) copyID(ctx context.Context, id, dest string) (err error) {
 	info, err := f.getFile(ctx, id, f.fileFields)
@@ -3694,6 +4094,10 @@ func (o *documentObject) Update(ctx context.Context, in io.Reader, src fs.Object
 	if err != nil {
 		return err
 	}
+	info, err = o.fs.recordImport(ctx, info, src.ModTime(ctx))
+	if err != nil {
+		return err
+	}
 
 	remote := src.Remote()
 	remote = remote[:len(remote)-o.extLen]
@@ -3716,6 +4120,40 @@ func (o *linkObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo
 	return errors.New("cannot update link files")
 }
 
+// errNotWithVersion is returned when trying to change an old revision
+var errNotWithVersion = errors.New("can't modify or overwrite an old revision")
+
+// Hash returns the Md5sum of the revision returning a lowercase hex string
+func (o *revisionObject) Hash(ctx context.Context, t hash.Type) (string, error) {
+	if t != hash.MD5 {
+		return "", hash.ErrUnsupported
+	}
+	return o.md5sum, nil
+}
+
+// SetModTime can't be done on old revisions
+func (o *revisionObject) SetModTime(ctx context.Context, modTime time.Time) error {
+	return errNotWithVersion
+}
+
+// Open the revision for read
+func (o *revisionObject) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	return o.baseObject.open(ctx, o.url, options...)
+}
+
+// Update can't be done on old revisions
+func (o *revisionObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return errNotWithVersion
+}
+
+// Remove deletes the revision
+func (o *revisionObject) Remove(ctx context.Context) error {
+	return o.fs.pacer.Call(func() (bool, error) {
+		err := o.fs.svc.Revisions.Delete(actualID(o.id), o.revisionID).Context(ctx).Do()
+		return o.fs.shouldRetry(ctx, err)
+	})
+}
+
 // Remove an object
 func (o *baseObject) Remove(ctx context.Context) error {
 	if len(o.parents) > 1 {
@@ -3784,31 +4222,38 @@ Type=Link
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs              = (*Fs)(nil)
-	_ fs.Purger          = (*Fs)(nil)
-	_ fs.CleanUpper      = (*Fs)(nil)
-	_ fs.PutStreamer     = (*Fs)(nil)
-	_ fs.Copier          = (*Fs)(nil)
-	_ fs.Mover           = (*Fs)(nil)
-	_ fs.DirMover        = (*Fs)(nil)
-	_ fs.Commander       = (*Fs)(nil)
-	_ fs.DirCacheFlusher = (*Fs)(nil)
-	_ fs.ChangeNotifier  = (*Fs)(nil)
-	_ fs.PutUncheckeder  = (*Fs)(nil)
-	_ fs.PublicLinker    = (*Fs)(nil)
-	_ fs.ListRer         = (*Fs)(nil)
-	_ fs.MergeDirser     = (*Fs)(nil)
-	_ fs.Abouter         = (*Fs)(nil)
-	_ fs.Object          = (*Object)(nil)
-	_ fs.MimeTyper       = (*Object)(nil)
-	_ fs.IDer            = (*Object)(nil)
-	_ fs.ParentIDer      = (*Object)(nil)
-	_ fs.Object          = (*documentObject)(nil)
-	_ fs.MimeTyper       = (*documentObject)(nil)
-	_ fs.IDer            = (*documentObject)(nil)
-	_ fs.ParentIDer      = (*documentObject)(nil)
-	_ fs.Object          = (*linkObject)(nil)
-	_ fs.MimeTyper       = (*linkObject)(nil)
-	_ fs.IDer            = (*linkObject)(nil)
-	_ fs.ParentIDer      = (*linkObject)(nil)
+	_ fs.Fs               = (*Fs)(nil)
+	_ fs.Purger           = (*Fs)(nil)
+	_ fs.CleanUpper       = (*Fs)(nil)
+	_ fs.TrashLister      = (*Fs)(nil)
+	_ fs.Undeleter        = (*Fs)(nil)
+	_ fs.VersionLister    = (*Fs)(nil)
+	_ fs.PutStreamer      = (*Fs)(nil)
+	_ fs.Copier           = (*Fs)(nil)
+	_ fs.Mover            = (*Fs)(nil)
+	_ fs.DirMover         = (*Fs)(nil)
+	_ fs.Commander        = (*Fs)(nil)
+	_ fs.DirCacheFlusher  = (*Fs)(nil)
+	_ fs.ChangeNotifier   = (*Fs)(nil)
+	_ fs.PutUncheckeder   = (*Fs)(nil)
+	_ fs.PublicLinker     = (*Fs)(nil)
+	_ fs.PublicLinkLister = (*Fs)(nil)
+	_ fs.ListRer          = (*Fs)(nil)
+	_ fs.MergeDirser      = (*Fs)(nil)
+	_ fs.Abouter          = (*Fs)(nil)
+	_ fs.Object           = (*Object)(nil)
+	_ fs.MimeTyper        = (*Object)(nil)
+	_ fs.IDer             = (*Object)(nil)
+	_ fs.ParentIDer       = (*Object)(nil)
+	_ fs.Object           = (*documentObject)(nil)
+	_ fs.MimeTyper        = (*documentObject)(nil)
+	_ fs.IDer             = (*documentObject)(nil)
+	_ fs.ParentIDer       = (*documentObject)(nil)
+	_ fs.Object           = (*revisionObject)(nil)
+	_ fs.MimeTyper        = (*revisionObject)(nil)
+	_ fs.IDer             = (*revisionObject)(nil)
+	_ fs.Object           = (*linkObject)(nil)
+	_ fs.MimeTyper        = (*linkObject)(nil)
+	_ fs.IDer             = (*linkObject)(nil)
+	_ fs.ParentIDer       = (*linkObject)(nil)
 )
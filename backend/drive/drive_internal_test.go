@@ -10,6 +10,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -141,6 +142,100 @@ func TestInternalFindExportFormat(t *testing.T) {
 	}
 }
 
+func TestInternalParseExportFormats(t *testing.T) {
+	extensions, byMimeType, err := parseExportFormats("pdf, spreadsheet=ods,application/vnd.google-apps.drawing=png,spreadsheet=csv,Spreadsheet=ODS")
+	require.NoError(t, err)
+	assert.Equal(t, []string{".pdf", ".docx", ".xlsx", ".pptx", ".svg"}, extensions)
+	assert.Equal(t, map[string][]string{
+		"application/vnd.google-apps.spreadsheet": {".ods", ".csv"},
+		"application/vnd.google-apps.drawing":     {".png"},
+	}, byMimeType)
+
+	extensions, byMimeType, err = parseExportFormats(defaultExportExtensions)
+	require.NoError(t, err)
+	assert.Equal(t, []string{".docx", ".xlsx", ".pptx", ".svg"}, extensions)
+	assert.Nil(t, byMimeType)
+
+	_, _, err = parseExportFormats("=pdf")
+	assert.Error(t, err)
+	_, _, err = parseExportFormats("document=potato")
+	assert.Error(t, err)
+}
+
+func TestInternalFindExportFormatByMimeType(t *testing.T) {
+	ctx := context.Background()
+	f := new(Fs)
+	f.exportExtensions, f.exportByMimeType = []string{".pdf", ".docx"}, map[string][]string{
+		"application/vnd.google-apps.spreadsheet": {".xls", ".ods"},
+	}
+	extension, _, _ := f.findExportFormatByMimeType(ctx, "application/vnd.google-apps.spreadsheet")
+	assert.Equal(t, ".ods", extension)
+	extension, _, _ = f.findExportFormatByMimeType(ctx, "application/vnd.google-apps.document")
+	assert.Equal(t, ".pdf", extension)
+	assert.Equal(t, []string{".pdf", ".docx", ".xls", ".ods"}, f.allExportExtensions())
+}
+
+func TestInternalImportedModTime(t *testing.T) {
+	f := new(Fs)
+	info := &drive.File{
+		ModifiedTime: "2021-01-02T03:04:05.000Z",
+	}
+	assert.Equal(t, "", f.importedModTime(info))
+	info.AppProperties = map[string]string{
+		appPropSrcModTime:    "2020-01-01T00:00:00.000000000Z",
+		appPropImportModTime: "2021-01-02T03:04:05.000Z",
+	}
+	assert.Equal(t, "2020-01-01T00:00:00.000000000Z", f.importedModTime(info))
+
+	// Changed since the import
+	info.ModifiedTime = "2021-02-02T03:04:05.000Z"
+	assert.Equal(t, "", f.importedModTime(info))
+}
+
+func TestInternalChangesSnapshotWalk(t *testing.T) {
+	newSnapshot := func() *changesSnapshot {
+		return &changesSnapshot{
+			RootID: "root",
+			Files: map[string]*drive.File{
+				"dir":      {Id: "dir", Name: "dir", MimeType: driveFolderType, Parents: []string{"root"}},
+				"file1":    {Id: "file1", Name: "file1", Parents: []string{"root"}},
+				"file2":    {Id: "file2", Name: "file2", Parents: []string{"dir"}},
+				"subdir":   {Id: "subdir", Name: "subdir", MimeType: driveFolderType, Parents: []string{"dir"}},
+				"file3":    {Id: "file3", Name: "file3", Parents: []string{"subdir"}},
+				"shortcut": {Id: joinID("target", "shortcut"), Name: "link", MimeType: driveFolderType, Parents: []string{"root"}},
+				"file4":    {Id: "file4", Name: "file4", Parents: []string{"target"}},
+				"outside":  {Id: "outside", Name: "outside", Parents: []string{"elsewhere"}},
+			},
+		}
+	}
+	list := func(snapshot *changesSnapshot, directoryID string) (remotes []string) {
+		err := snapshot.walk(directoryID, func(remote string, item *drive.File) error {
+			remotes = append(remotes, remote)
+			return nil
+		})
+		require.NoError(t, err)
+		sort.Strings(remotes)
+		return remotes
+	}
+
+	snapshot := newSnapshot()
+	assert.Equal(t, []string{"dir", "dir/file2", "dir/subdir", "dir/subdir/file3", "file1", "link", "link/file4"}, list(snapshot, "root"))
+	_, found := snapshot.Files["outside"]
+	assert.False(t, found, "items outside the root should be forgotten")
+	assert.Len(t, snapshot.Files, 7)
+
+	assert.Equal(t, []string{"file2", "subdir", "subdir/file3"}, list(newSnapshot(), "dir"))
+	assert.Equal(t, []string{"file4"}, list(newSnapshot(), "target"))
+	assert.Equal(t, []string(nil), list(newSnapshot(), "file1"))
+
+	// Errors stop the walk
+	wantErr := errors.New("boom")
+	err := newSnapshot().walk("root", func(remote string, item *drive.File) error {
+		return wantErr
+	})
+	assert.Equal(t, wantErr, err)
+}
+
 func TestMimeTypesToExtension(t *testing.T) {
 	for mimeType, extension := range _mimeTypeToExtension {
 		extensions, err := mime.ExtensionsByType(mimeType)
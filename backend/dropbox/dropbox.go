@@ -181,6 +181,21 @@ You will have to use your own App (setting your own client_id and
 client_secret) to use this option as currently rclone's default set of
 permissions doesn't include "members.read". This can be added once
 v1.55 or later is in use everywhere.
+`,
+			Default:  "",
+			Advanced: true,
+		}, {
+			Name: "root_namespace",
+			Help: `Use this namespace ID as the root of all paths.
+
+Normally paths are relative to your personal folder, or to the root of
+the team if they start with a "/" (see [Dropbox for
+business](https://rclone.org/dropbox/#dropbox-for-business)).
+
+Set this to the ID of a namespace, such as that of a Team Space, a
+team folder, a shared folder or, with --dropbox-impersonate, another
+member's home folder, to work within it instead. This sends the
+Dropbox-API-Path-Root header so all paths are relative to it.
 `,
 			Default:  "",
 			Advanced: true,
@@ -287,6 +302,7 @@ default based on the batch_mode in use.
 type Options struct {
 	ChunkSize     fs.SizeSuffix        `config:"chunk_size"`
 	Impersonate   string               `config:"impersonate"`
+	RootNamespace string               `config:"root_namespace"`
 	SharedFiles   bool                 `config:"shared_files"`
 	SharedFolders bool                 `config:"shared_folders"`
 	BatchMode     string               `config:"batch_mode"`
@@ -537,8 +553,12 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 
 	f.features.Fill(ctx, f)
 
-	// If root starts with / then use the actual root
-	if strings.HasPrefix(root, "/") {
+	if opt.RootNamespace != "" {
+		// Use the namespace asked for
+		f.ns = opt.RootNamespace
+		fs.Debugf(f, "Using root namespace %q", f.ns)
+	} else if strings.HasPrefix(root, "/") {
+		// If root starts with / then use the actual root
 		var acc *users.FullAccount
 		err = f.pacer.Call(func() (bool, error) {
 			acc, err = f.users.GetCurrentAccount()
@@ -1142,14 +1162,79 @@ func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object,
 
 // PublicLink adds a "readable by anyone with link" permission on the given file or folder.
 func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (link string, err error) {
+	if unlink {
+		return "", f.unlink(ctx, remote)
+	}
+	return f.publicLink(ctx, remote, expire, "")
+}
+
+// PublicLinkWithPassword makes a link to the given file or folder
+// which needs password to use it.
+func (f *Fs) PublicLinkWithPassword(ctx context.Context, remote string, expire fs.Duration, password string) (link string, err error) {
+	return f.publicLink(ctx, remote, expire, password)
+}
+
+// linkMetadata returns the metadata common to all types of shared link
+func linkMetadata(linkRes sharing.IsSharedLinkMetadata) (*sharing.SharedLinkMetadata, error) {
+	switch res := linkRes.(type) {
+	case *sharing.FileLinkMetadata:
+		return &res.SharedLinkMetadata, nil
+	case *sharing.FolderLinkMetadata:
+		return &res.SharedLinkMetadata, nil
+	}
+	return nil, fmt.Errorf("Don't know how to extract link, response has unknown format: %T", linkRes)
+}
+
+// listLinks lists the shared links to absPath (or all the shared
+// links if it is empty) calling fn for each one
+func (f *Fs) listLinks(ctx context.Context, absPath string, fn func(*sharing.SharedLinkMetadata) error) error {
+	listArg := sharing.ListSharedLinksArg{
+		Path:       absPath,
+		DirectOnly: absPath != "",
+	}
+	for {
+		var listRes *sharing.ListSharedLinksResult
+		err := f.pacer.Call(func() (bool, error) {
+			var err error
+			listRes, err = f.sharing.ListSharedLinks(&listArg)
+			return shouldRetry(ctx, err)
+		})
+		if err != nil {
+			return err
+		}
+		for _, linkRes := range listRes.Links {
+			link, err := linkMetadata(linkRes)
+			if err != nil {
+				return err
+			}
+			err = fn(link)
+			if err != nil {
+				return err
+			}
+		}
+		if !listRes.HasMore || listRes.Cursor == "" {
+			return nil
+		}
+		listArg.Cursor = listRes.Cursor
+	}
+}
+
+// publicLink makes a public link to remote which needs password to
+// use it if it is set
+func (f *Fs) publicLink(ctx context.Context, remote string, expire fs.Duration, password string) (link string, err error) {
 	absPath := f.opt.Enc.FromStandardPath(path.Join(f.slashRoot, remote))
 	fs.Debugf(f, "attempting to share '%s' (absolute path: %s)", remote, absPath)
+	visibility := sharing.RequestedVisibilityPublic
+	if password != "" {
+		visibility = sharing.RequestedVisibilityPassword
+	}
 	createArg := sharing.CreateSharedLinkWithSettingsArg{
 		Path: absPath,
 		Settings: &sharing.SharedLinkSettings{
 			RequestedVisibility: &sharing.RequestedVisibility{
-				Tagged: dropbox.Tagged{Tag: sharing.RequestedVisibilityPublic},
+				Tagged: dropbox.Tagged{Tag: visibility},
 			},
+			LinkPassword: password,
 			Audience: &sharing.LinkAudience{
 				Tagged: dropbox.Tagged{Tag: sharing.LinkAudiencePublic},
 			},
@@ -1165,8 +1250,8 @@ func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration,
 	// FIXME note we can't set Settings for non enterprise dropbox
 	// because of https://github.com/dropbox/dropbox-sdk-go-unofficial/issues/75
 	// however this only goes wrong when we set Expires, so as a
-	// work-around remove Settings unless expire is set.
-	if expire == fs.DurationOff {
+	// work-around remove Settings unless expire or password is set.
+	if expire == fs.DurationOff && password == "" {
 		createArg.Settings = nil
 	}
 
@@ -1179,35 +1264,209 @@ func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration,
 	if err != nil && strings.Contains(err.Error(),
 		sharing.CreateSharedLinkWithSettingsErrorSharedLinkAlreadyExists) {
 		fs.Debugf(absPath, "has a public link already, attempting to retrieve it")
-		listArg := sharing.ListSharedLinksArg{
-			Path:       absPath,
-			DirectOnly: true,
-		}
-		var listRes *sharing.ListSharedLinksResult
-		err = f.pacer.Call(func() (bool, error) {
-			listRes, err = f.sharing.ListSharedLinks(&listArg)
-			return shouldRetry(ctx, err)
+		var existing *sharing.SharedLinkMetadata
+		err = f.listLinks(ctx, absPath, func(link *sharing.SharedLinkMetadata) error {
+			if existing == nil {
+				existing = link
+			}
+			return nil
 		})
 		if err != nil {
 			return
 		}
-		if len(listRes.Links) == 0 {
+		if existing == nil {
 			err = errors.New("Dropbox says the sharing link already exists, but list came back empty")
 			return
 		}
-		linkRes = listRes.Links[0]
+		if password == "" {
+			return existing.Url, nil
+		}
+		// Add the password to the existing link
+		modifyArg := sharing.ModifySharedLinkSettingsArgs{
+			Url:      existing.Url,
+			Settings: createArg.Settings,
+		}
+		err = f.pacer.Call(func() (bool, error) {
+			linkRes, err = f.sharing.ModifySharedLinkSettings(&modifyArg)
+			return shouldRetry(ctx, err)
+		})
 	}
-	if err == nil {
-		switch res := linkRes.(type) {
-		case *sharing.FileLinkMetadata:
-			link = res.Url
-		case *sharing.FolderLinkMetadata:
-			link = res.Url
-		default:
-			err = fmt.Errorf("Don't know how to extract link, response has unknown format: %T", res)
+	if err != nil {
+		return "", err
+	}
+	res, err := linkMetadata(linkRes)
+	if err != nil {
+		return "", err
+	}
+	return res.Url, nil
+}
+
+// unlink revokes the public links to remote
+func (f *Fs) unlink(ctx context.Context, remote string) error {
+	absPath := f.opt.Enc.FromStandardPath(path.Join(f.slashRoot, remote))
+	var urls []string
+	err := f.listLinks(ctx, absPath, func(link *sharing.SharedLinkMetadata) error {
+		urls = append(urls, link.Url)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(urls) == 0 {
+		fs.Debugf(absPath, "has no public links to remove")
+	}
+	for _, url := range urls {
+		revokeArg := sharing.RevokeSharedLinkArg{
+			Url: url,
+		}
+		err = f.pacer.Call(func() (bool, error) {
+			err = f.sharing.RevokeSharedLink(&revokeArg)
+			return shouldRetry(ctx, err)
+		})
+		if err != nil {
+			return err
 		}
+		fs.Debugf(absPath, "removed public link %s", url)
 	}
-	return
+	return nil
+}
+
+// ListPublicLinks returns the public links to remote and to anything
+// inside it if it is a folder.
+func (f *Fs) ListPublicLinks(ctx context.Context, remote string) (links []fs.PublicLinkInfo, err error) {
+	rootLower := strings.ToLower(f.opt.Enc.FromStandardPath(f.slashRoot))
+	absLower := strings.ToLower(f.opt.Enc.FromStandardPath(path.Join(f.slashRoot, remote)))
+	err = f.listLinks(ctx, "", func(link *sharing.SharedLinkMetadata) error {
+		if link.PathLower == "" {
+			return nil // not in the user's Dropbox
+		}
+		if absLower != "/" && link.PathLower != absLower && !strings.HasPrefix(link.PathLower, absLower+"/") {
+			return nil
+		}
+		relative := strings.Trim(strings.TrimPrefix(link.PathLower, rootLower), "/")
+		info := fs.PublicLinkInfo{
+			Path: f.opt.Enc.ToStandardPath(relative),
+			URL:  link.Url,
+		}
+		if !link.Expires.IsZero() {
+			expires := link.Expires
+			info.Expires = &expires
+		}
+		if perms := link.LinkPermissions; perms != nil && perms.ResolvedVisibility != nil {
+			switch perms.ResolvedVisibility.Tag {
+			case sharing.ResolvedVisibilityPassword, sharing.ResolvedVisibilityTeamAndPassword:
+				info.Password = true
+			}
+		}
+		links = append(links, info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// ListTrash lists the deleted files in dir and its subdirectories
+// which can still be restored from their revisions.
+func (f *Fs) ListTrash(ctx context.Context, dir string) (entries []fs.TrashEntry, err error) {
+	if f.opt.SharedFiles || f.opt.SharedFolders {
+		return nil, errNotSupportedInSharedMode
+	}
+	root := path.Join(f.slashRoot, dir)
+	prefixLower := strings.ToLower(f.slashRootSlash)
+	var deleted []string
+	started := false
+	var res *files.ListFolderResult
+	for {
+		if !started {
+			arg := files.ListFolderArg{
+				Path:           f.opt.Enc.FromStandardPath(root),
+				Recursive:      true,
+				IncludeDeleted: true,
+			}
+			if root == "/" {
+				arg.Path = "" // Specify root folder as empty string
+			}
+			err = f.pacer.Call(func() (bool, error) {
+				res, err = f.srv.ListFolder(&arg)
+				return shouldRetry(ctx, err)
+			})
+			if err != nil {
+				return nil, errors.Wrap(err, "list deleted")
+			}
+			started = true
+		} else {
+			arg := files.ListFolderContinueArg{
+				Cursor: res.Cursor,
+			}
+			err = f.pacer.Call(func() (bool, error) {
+				res, err = f.srv.ListFolderContinue(&arg)
+				return shouldRetry(ctx, err)
+			})
+			if err != nil {
+				return nil, errors.Wrap(err, "list deleted continue")
+			}
+		}
+		for _, entry := range res.Entries {
+			if info, ok := entry.(*files.DeletedMetadata); ok {
+				deleted = append(deleted, info.PathDisplay)
+			}
+		}
+		if !res.HasMore {
+			break
+		}
+	}
+	// Deleted folders are listed too so only keep the entries
+	// which have revisions to restore
+	for _, entryPath := range deleted {
+		var revs *files.ListRevisionsResult
+		arg := files.NewListRevisionsArg(entryPath)
+		arg.Limit = 1
+		err = f.pacer.Call(func() (bool, error) {
+			revs, err = f.srv.ListRevisions(arg)
+			return shouldRetry(ctx, err)
+		})
+		if err != nil {
+			fs.Debugf(f, "Ignoring deleted %q: %v", entryPath, err)
+			continue
+		}
+		if !revs.IsDeleted || len(revs.Entries) == 0 {
+			continue
+		}
+		remote := f.opt.Enc.ToStandardPath(entryPath)
+		if !strings.HasPrefix(strings.ToLower(remote), prefixLower) {
+			fs.Debugf(f, "Odd name received %q", remote)
+			continue
+		}
+		rev := revs.Entries[0]
+		entries = append(entries, fs.TrashEntry{
+			Remote:  remote[len(prefixLower):],
+			Size:    int64(rev.Size),
+			Deleted: revs.ServerDeleted,
+			ID:      rev.Rev,
+		})
+	}
+	return entries, nil
+}
+
+// Undelete restores a deleted file to the revision it had when it
+// was deleted
+func (f *Fs) Undelete(ctx context.Context, entry fs.TrashEntry) (fs.Object, error) {
+	arg := files.RestoreArg{
+		Path: f.opt.Enc.FromStandardPath(f.slashRootSlash + entry.Remote),
+		Rev:  entry.ID,
+	}
+	var info *files.FileMetadata
+	var err error
+	err = f.pacer.Call(func() (bool, error) {
+		info, err = f.srv.Restore(&arg)
+		return shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "restore failed")
+	}
+	return f.newObjectWithInfo(ctx, entry.Remote, info)
 }
 
 // DirMove moves src, srcRemote to this remote at dstRemote
@@ -1356,6 +1615,17 @@ func (f *Fs) changeNotifyCursor(ctx context.Context) (cursor string, err error)
 	return startCursor.Cursor, nil
 }
 
+// changeNotifyPath returns the remote for the pathDisplay of a change
+// or "" if it isn't inside the root. Dropbox is case insensitive so
+// the case of the root in pathDisplay may not match.
+func (f *Fs) changeNotifyPath(pathDisplay string) string {
+	prefix := f.opt.Enc.FromStandardPath(f.slashRootSlash)
+	if len(pathDisplay) <= len(prefix) || !strings.EqualFold(pathDisplay[:len(prefix)], prefix) {
+		return ""
+	}
+	return f.opt.Enc.ToStandardPath(pathDisplay[len(prefix):])
+}
+
 func (f *Fs) changeNotifyRunner(ctx context.Context, notifyFunc func(string, fs.EntryType), startCursor string) (newCursor string, err error) {
 	cursor := startCursor
 	var res *files.ListFolderLongpollResult
@@ -1390,7 +1660,11 @@ func (f *Fs) changeNotifyRunner(ctx context.Context, notifyFunc func(string, fs.
 
 	if res.Backoff != 0 {
 		fs.Debugf(f, "Waiting to poll for %d seconds", res.Backoff)
-		time.Sleep(time.Duration(res.Backoff) * time.Second)
+		select {
+		case <-time.After(time.Duration(res.Backoff) * time.Second):
+		case <-ctx.Done():
+			return cursor, ctx.Err()
+		}
 	}
 
 	for {
@@ -1413,13 +1687,13 @@ func (f *Fs) changeNotifyRunner(ctx context.Context, notifyFunc func(string, fs.
 			switch info := entry.(type) {
 			case *files.FolderMetadata:
 				entryType = fs.EntryDirectory
-				entryPath = strings.TrimPrefix(info.PathDisplay, f.slashRootSlash)
+				entryPath = f.changeNotifyPath(info.PathDisplay)
 			case *files.FileMetadata:
 				entryType = fs.EntryObject
-				entryPath = strings.TrimPrefix(info.PathDisplay, f.slashRootSlash)
+				entryPath = f.changeNotifyPath(info.PathDisplay)
 			case *files.DeletedMetadata:
 				entryType = fs.EntryObject
-				entryPath = strings.TrimPrefix(info.PathDisplay, f.slashRootSlash)
+				entryPath = f.changeNotifyPath(info.PathDisplay)
 			default:
 				fs.Errorf(entry, "dropbox ChangeNotify: ignoring unknown EntryType %T", entry)
 				continue
@@ -1789,15 +2063,19 @@ func (o *Object) Remove(ctx context.Context) (err error) {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs           = (*Fs)(nil)
-	_ fs.Copier       = (*Fs)(nil)
-	_ fs.Purger       = (*Fs)(nil)
-	_ fs.PutStreamer  = (*Fs)(nil)
-	_ fs.Mover        = (*Fs)(nil)
-	_ fs.PublicLinker = (*Fs)(nil)
-	_ fs.DirMover     = (*Fs)(nil)
-	_ fs.Abouter      = (*Fs)(nil)
-	_ fs.Shutdowner   = &Fs{}
-	_ fs.Object       = (*Object)(nil)
-	_ fs.IDer         = (*Object)(nil)
+	_ fs.Fs                   = (*Fs)(nil)
+	_ fs.Copier               = (*Fs)(nil)
+	_ fs.Purger               = (*Fs)(nil)
+	_ fs.PutStreamer          = (*Fs)(nil)
+	_ fs.Mover                = (*Fs)(nil)
+	_ fs.PublicLinker         = (*Fs)(nil)
+	_ fs.PublicLinkPassworder = (*Fs)(nil)
+	_ fs.PublicLinkLister     = (*Fs)(nil)
+	_ fs.DirMover             = (*Fs)(nil)
+	_ fs.TrashLister          = (*Fs)(nil)
+	_ fs.Undeleter            = (*Fs)(nil)
+	_ fs.Abouter              = (*Fs)(nil)
+	_ fs.Shutdowner           = &Fs{}
+	_ fs.Object               = (*Object)(nil)
+	_ fs.IDer                 = (*Object)(nil)
 )
@@ -3,6 +3,7 @@ package dropbox
 import (
 	"testing"
 
+	"github.com/rclone/rclone/lib/encoder"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,3 +43,26 @@ func TestInternalCheckPathLength(t *testing.T) {
 		assert.Equal(t, test.ok, err == nil, test.in)
 	}
 }
+
+func TestInternalChangeNotifyPath(t *testing.T) {
+	f := &Fs{}
+	f.opt.Enc = encoder.Base | encoder.EncodeRightSpace
+	for _, test := range []struct {
+		root        string
+		pathDisplay string
+		want        string
+	}{
+		{"", "/", ""},
+		{"", "/file", "file"},
+		{"", "/dir/file", "dir/file"},
+		{"dir", "/dir", ""},
+		{"dir", "/dir/file", "file"},
+		{"dir", "/DIR/sub/file", "sub/file"},
+		{"dir", "/other/file", ""},
+		{"dir", "/directory/file", ""},
+		{"", "/file␠", "file "},
+	} {
+		f.setRoot(test.root)
+		assert.Equal(t, test.want, f.changeNotifyPath(test.pathDisplay), test)
+	}
+}
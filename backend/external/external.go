@@ -0,0 +1,546 @@
+// Package external provides an interface to backends run as an
+// external program
+package external
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/atexit"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "external",
+		Description: "Backend run as an external program",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "command",
+			Help: `Command to run the external backend, with its arguments.
+
+The program is run once for each different command and is sent
+requests on its stdin and sends responses on its stdout. Anything it
+writes to stderr is passed through to rclone's stderr.
+
+Use "rclone serve backend remote:" to run any backend built into
+rclone as an external backend.`,
+			Required: true,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Command fs.SpaceSepList `config:"command"`
+}
+
+// Fs represents a backend run as an external program
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on if any
+	opt      Options      // parsed config options
+	features *fs.Features // optional features
+	c        *conn        // connection to the program
+	info     InfoReply    // information about the Fs the program serves
+	hashes   hash.Set     // hashes the program supports
+}
+
+// Object describes an object read from the external program
+type Object struct {
+	fs      *Fs
+	remote  string
+	size    int64
+	modTime *time.Time // nil if not read yet
+	id      string
+	hashes  map[hash.Type]string
+}
+
+// conn is a connection to a running external program
+type conn struct {
+	cmd    *exec.Cmd
+	client *rpc.Client
+}
+
+var (
+	connsMu sync.Mutex
+	conns   = map[string]*conn{} // running programs by command line
+)
+
+// getConn returns the connection to the program run by command,
+// starting it if it isn't running.
+func getConn(command fs.SpaceSepList) (*conn, error) {
+	if len(command) == 0 {
+		return nil, errors.New("command must be set")
+	}
+	key := strings.Join(command, "\x00")
+	connsMu.Lock()
+	defer connsMu.Unlock()
+	if c, ok := conns[key]; ok {
+		return c, nil
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start external backend")
+	}
+	fs.Debugf(nil, "Started external backend %q", command.String())
+	c := &conn{
+		cmd:    cmd,
+		client: jsonrpc.NewClient(readWriteCloser{Reader: stdout, Writer: stdin}),
+	}
+	conns[key] = c
+	atexit.Register(func() {
+		// Closing stdin tells the program to exit
+		_ = c.client.Close()
+		_ = c.cmd.Wait()
+	})
+	return c, nil
+}
+
+// call runs method on the program with args putting the result in
+// reply, returning early if ctx is cancelled.
+func (c *conn) call(ctx context.Context, method string, args interface{}, reply interface{}) error {
+	call := c.client.Go(serviceName+"."+method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		return decodeError(call.Error)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("external %s:%s root '%s'", f.info.Name, f.info.Root, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return f.info.Precision
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return f.hashes
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	c, err := getConn(opt.Command)
+	if err != nil {
+		return nil, err
+	}
+	f := &Fs{
+		name: name,
+		root: strings.Trim(root, "/"),
+		opt:  *opt,
+		c:    c,
+	}
+	err = c.call(ctx, "Info", &Empty{}, &f.info)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read info from external backend")
+	}
+	for _, name := range f.info.Hashes {
+		var ht hash.Type
+		if ht.Set(name) == nil {
+			f.hashes.Add(ht)
+		}
+	}
+	f.features = (&fs.Features{
+		CaseInsensitive:         f.info.CaseInsensitive,
+		CanHaveEmptyDirectories: f.info.CanHaveEmptyDirectories,
+		SlowModTime:             f.info.SlowModTime,
+		SlowHash:                f.info.SlowHash,
+	}).Fill(ctx, f)
+	if f.root != "" {
+		// Check to see if the root is actually an existing file
+		oldRoot := f.root
+		newRoot, leaf := path.Split(oldRoot)
+		f.root = strings.Trim(newRoot, "/")
+		_, err := f.NewObject(ctx, leaf)
+		if err != nil {
+			if err == fs.ErrorObjectNotFound || err == fs.ErrorNotAFile {
+				f.root = oldRoot
+				return f, nil
+			}
+			return nil, err
+		}
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// absPath returns the path of remote relative to the root of the
+// program
+func (f *Fs) absPath(remote string) string {
+	return encodePath(path.Join(f.root, remote))
+}
+
+// relPath returns the path relative to f.root of remote which is
+// relative to the root of the program
+func (f *Fs) relPath(remote string) string {
+	remote = decodePath(remote)
+	if f.root == "" {
+		return remote
+	}
+	return strings.TrimPrefix(remote, f.root+"/")
+}
+
+// newEntry makes a directory entry from e
+func (f *Fs) newEntry(e *Entry) fs.DirEntry {
+	remote := f.relPath(e.Remote)
+	if e.IsDir {
+		var modTime time.Time
+		if e.ModTime != nil {
+			modTime = *e.ModTime
+		}
+		return fs.NewDir(remote, modTime).SetSize(e.Size).SetItems(e.Items).SetID(e.ID)
+	}
+	return f.newObject(remote, e)
+}
+
+// newObject makes an Object called remote from e
+func (f *Fs) newObject(remote string, e *Entry) *Object {
+	o := &Object{
+		fs:      f,
+		remote:  remote,
+		size:    e.Size,
+		modTime: e.ModTime,
+		id:      e.ID,
+	}
+	if len(e.Hashes) > 0 {
+		o.hashes = make(map[hash.Type]string, len(e.Hashes))
+		for name, sum := range e.Hashes {
+			var ht hash.Type
+			if ht.Set(name) == nil {
+				o.hashes[ht] = sum
+			}
+		}
+	}
+	return o
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	var reply ListReply
+	err = f.c.call(ctx, "List", &PathArgs{Remote: f.absPath(dir)}, &reply)
+	if err != nil {
+		return nil, err
+	}
+	entries = make(fs.DirEntries, 0, len(reply.Entries))
+	for i := range reply.Entries {
+		entries = append(entries, f.newEntry(&reply.Entries[i]))
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	var reply Entry
+	err := f.c.call(ctx, "Stat", &PathArgs{Remote: f.absPath(remote)}, &reply)
+	if err != nil {
+		return nil, err
+	}
+	if reply.IsDir {
+		return nil, fs.ErrorNotAFile
+	}
+	return f.newObject(remote, &reply), nil
+}
+
+// upload the contents of in to remote returning the new object
+func (f *Fs) upload(ctx context.Context, in io.Reader, remote string, src fs.ObjectInfo) (*Object, error) {
+	args := CreateArgs{
+		Remote:  f.absPath(remote),
+		Size:    src.Size(),
+		ModTime: src.ModTime(ctx),
+	}
+	for _, ht := range f.hashes.Array() {
+		sum, err := src.Hash(ctx, ht)
+		if err == nil && sum != "" {
+			if args.Hashes == nil {
+				args.Hashes = make(map[string]string)
+			}
+			args.Hashes[ht.String()] = sum
+		}
+	}
+	var handle HandleReply
+	err := f.c.call(ctx, "Create", &args, &handle)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, readChunkSize)
+	for {
+		n, readErr := io.ReadFull(in, buf)
+		if n > 0 {
+			err = f.c.call(ctx, "Write", &WriteArgs{Handle: handle.Handle, Data: buf[:n]}, &Empty{})
+			if err != nil {
+				break
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		} else if readErr != nil {
+			err = readErr
+			break
+		}
+	}
+	var reply Entry
+	if err != nil {
+		_ = f.c.call(ctx, "Close", &CloseArgs{Handle: handle.Handle, Abort: true}, &reply)
+		return nil, err
+	}
+	err = f.c.call(ctx, "Close", &CloseArgs{Handle: handle.Handle}, &reply)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(remote, &reply), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o, err := f.upload(ctx, in, src.Remote(), src)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir creates the directory if it doesn't exist
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.c.call(ctx, "Mkdir", &PathArgs{Remote: f.absPath(dir)}, &Empty{})
+}
+
+// Rmdir deletes the directory if empty
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return f.c.call(ctx, "Rmdir", &PathArgs{Remote: f.absPath(dir)}, &Empty{})
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the requested hash of the object
+func (o *Object) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	if !o.fs.hashes.Contains(ht) {
+		return "", hash.ErrUnsupported
+	}
+	if sum, ok := o.hashes[ht]; ok {
+		return sum, nil
+	}
+	var sum string
+	err := o.fs.c.call(ctx, "Hash", &HashArgs{Remote: o.fs.absPath(o.remote), Hash: ht.String()}, &sum)
+	if err != nil {
+		return "", err
+	}
+	if o.hashes == nil {
+		o.hashes = make(map[hash.Type]string, 1)
+	}
+	o.hashes[ht] = sum
+	return sum, nil
+}
+
+// Size returns the size of the object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if o.modTime != nil {
+		return *o.modTime
+	}
+	var reply Entry
+	err := o.fs.c.call(ctx, "ModTime", &PathArgs{Remote: o.fs.absPath(o.remote)}, &reply)
+	if err != nil || reply.ModTime == nil {
+		fs.Debugf(o, "Failed to read modification time: %v", err)
+		return time.Now()
+	}
+	o.modTime = reply.ModTime
+	return *o.modTime
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	err := o.fs.c.call(ctx, "SetModTime", &SetModTimeArgs{Remote: o.fs.absPath(o.remote), ModTime: modTime}, &Empty{})
+	if err != nil {
+		return err
+	}
+	o.modTime = &modTime
+	return nil
+}
+
+// Storable returns a boolean showing whether this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	return o.id
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	args := OpenArgs{
+		Remote: o.fs.absPath(o.remote),
+		Limit:  -1,
+	}
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			args.Offset, args.Limit = x.Offset, -1
+		case *fs.RangeOption:
+			args.Offset, args.Limit = x.Decode(o.size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	var handle HandleReply
+	err = o.fs.c.call(ctx, "Open", &args, &handle)
+	if err != nil {
+		return nil, err
+	}
+	return &reader{ctx: ctx, c: o.fs.c, handle: handle.Handle}, nil
+}
+
+// reader reads an object opened with Open
+type reader struct {
+	ctx    context.Context
+	c      *conn
+	handle uint64
+	buf    []byte // data read but not returned yet
+	eof    bool   // set if there is no more data after buf
+}
+
+// Read data from the object
+func (r *reader) Read(p []byte) (n int, err error) {
+	for len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		var reply ReadReply
+		err = r.c.call(r.ctx, "Read", &ReadArgs{Handle: r.handle, Size: readChunkSize}, &reply)
+		if err != nil {
+			return 0, err
+		}
+		r.buf, r.eof = reply.Data, reply.EOF
+	}
+	n = copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close the object
+func (r *reader) Close() error {
+	return r.c.call(r.ctx, "Close", &CloseArgs{Handle: r.handle}, &Entry{})
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	newO, err := o.fs.upload(ctx, in, o.remote, src)
+	if err != nil {
+		return err
+	}
+	*o = *newO
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	return o.fs.c.call(ctx, "Remove", &PathArgs{Remote: o.fs.absPath(o.remote)}, &Empty{})
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+	_ fs.IDer        = (*Object)(nil)
+)
@@ -0,0 +1,59 @@
+// Test external filesystem interface
+package external
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// serveDirEnv is set to make the test binary serve a directory as an
+// external backend instead of running the tests
+const serveDirEnv = "RCLONE_EXTERNAL_TEST_SERVE_DIR"
+
+func TestMain(m *testing.M) {
+	if dir := os.Getenv(serveDirEnv); dir != "" {
+		ctx := context.Background()
+		f, err := fs.NewFs(ctx, dir)
+		if err == nil {
+			err = Serve(ctx, f, os.Stdin, os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to serve:", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// TestIntegration runs integration tests against the remote with
+// this test binary as the external program serving a local directory
+func TestIntegration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-external")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	for k, v := range map[string]string{
+		serveDirEnv:                          dir,
+		"RCLONE_CONFIG_TESTEXTERNAL_TYPE":    "external",
+		"RCLONE_CONFIG_TESTEXTERNAL_COMMAND": os.Args[0],
+	} {
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestExternal:",
+		NilObject:  (*Object)(nil),
+	})
+}
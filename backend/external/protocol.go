@@ -0,0 +1,195 @@
+package external
+
+// This file describes the protocol spoken between the external
+// backend and the program it runs.
+//
+// The program is sent requests on its stdin and writes responses to
+// its stdout using the JSON-RPC 1.0 encoding of net/rpc, so it can be
+// written in any language.  Each request is an object like
+//
+//     {"method": "Backend.List", "params": [{"remote": "dir"}], "id": 1}
+//
+// and must be answered with
+//
+//     {"result": {"entries": [...]}, "error": null, "id": 1}
+//
+// Requests may be sent before the previous ones are answered so the
+// responses may be written in any order, matched up by id.
+//
+// All the paths are relative to the root of whatever the program
+// serves.  As JSON strings can only hold valid UTF-8, any invalid
+// bytes in paths are sent as ‛ followed by the byte in hex, and ‛ is
+// sent as ‛‛.  Errors are returned as strings, and the strings of the
+// errors in errorsByText are turned back into those errors.
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/list"
+	"github.com/rclone/rclone/lib/encoder"
+)
+
+// serviceName is the prefix of all the methods
+const serviceName = "Backend"
+
+// readChunkSize is the largest read and write done in one request
+const readChunkSize = 1024 * 1024
+
+// Empty is used for requests which have no parameters or results
+type Empty struct{}
+
+// InfoReply describes the Fs the program serves
+type InfoReply struct {
+	Name                    string        `json:"name"`
+	Root                    string        `json:"root"`
+	Precision               time.Duration `json:"precision"` // in ns
+	Hashes                  []string      `json:"hashes"`    // names of the supported hashes
+	CaseInsensitive         bool          `json:"caseInsensitive"`
+	CanHaveEmptyDirectories bool          `json:"canHaveEmptyDirectories"`
+	SlowModTime             bool          `json:"slowModTime"`
+	SlowHash                bool          `json:"slowHash"`
+}
+
+// PathArgs are the parameters of requests which only need a path
+type PathArgs struct {
+	Remote string `json:"remote"`
+}
+
+// Entry is a directory entry as returned by List and Stat.
+//
+// ModTime may be missing for objects if reading it is slow, in which
+// case it is read with ModTime when needed, and Hashes may contain any
+// hashes which are known without an extra request.
+type Entry = list.EncodedEntry
+
+// ListReply is the result of List
+type ListReply struct {
+	Entries []Entry `json:"entries"`
+}
+
+// HashArgs are the parameters of Hash
+type HashArgs struct {
+	Remote string `json:"remote"`
+	Hash   string `json:"hash"`
+}
+
+// SetModTimeArgs are the parameters of SetModTime
+type SetModTimeArgs struct {
+	Remote  string    `json:"remote"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// OpenArgs are the parameters of Open
+//
+// Limit is -1 to read to the end of the file.
+type OpenArgs struct {
+	Remote string `json:"remote"`
+	Offset int64  `json:"offset"`
+	Limit  int64  `json:"limit"`
+}
+
+// HandleReply is the result of Open and Create
+type HandleReply struct {
+	Handle uint64 `json:"handle"`
+}
+
+// ReadArgs are the parameters of Read
+type ReadArgs struct {
+	Handle uint64 `json:"handle"`
+	Size   int    `json:"size"`
+}
+
+// ReadReply is the result of Read
+//
+// Data is returned as base64. EOF is set when there is no more data
+// to read after Data.
+type ReadReply struct {
+	Data []byte `json:"data"`
+	EOF  bool   `json:"eof"`
+}
+
+// CreateArgs are the parameters of Create which starts uploading
+// Remote, replacing it if it exists.
+//
+// Size is -1 if it isn't known in advance.
+type CreateArgs struct {
+	Remote  string            `json:"remote"`
+	Size    int64             `json:"size"`
+	ModTime time.Time         `json:"modTime"`
+	Hashes  map[string]string `json:"hashes,omitempty"`
+}
+
+// WriteArgs are the parameters of Write
+type WriteArgs struct {
+	Handle uint64 `json:"handle"`
+	Data   []byte `json:"data"`
+}
+
+// CloseArgs are the parameters of Close
+//
+// If Abort is set on the Close of a handle from Create the upload is
+// cancelled, otherwise it is finished and the new object is returned.
+type CloseArgs struct {
+	Handle uint64 `json:"handle"`
+	Abort  bool   `json:"abort,omitempty"`
+}
+
+// errorsByText are the errors which are sent as their text and
+// turned back into the error the other side
+var errorsByText = map[string]error{}
+
+func init() {
+	for _, err := range []error{
+		fs.ErrorObjectNotFound,
+		fs.ErrorDirNotFound,
+		fs.ErrorIsFile,
+		fs.ErrorNotAFile,
+		fs.ErrorDirectoryNotEmpty,
+		fs.ErrorDirExists,
+		fs.ErrorCantSetModTime,
+		fs.ErrorCantSetModTimeWithoutDelete,
+		fs.ErrorCantUploadEmptyFiles,
+		fs.ErrorPermissionDenied,
+	} {
+		errorsByText[err.Error()] = err
+	}
+}
+
+// encodeError returns the error to send for err
+func encodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	cause := errors.Cause(err)
+	if _, ok := errorsByText[cause.Error()]; ok {
+		return cause
+	}
+	return err
+}
+
+// decodeError turns err received from the program back into a known
+// error if possible
+func decodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if known, ok := errorsByText[err.Error()]; ok {
+		return known
+	}
+	return err
+}
+
+// pathEncoder encodes the invalid UTF-8 in paths
+const pathEncoder = encoder.MultiEncoder(encoder.EncodeInvalidUtf8)
+
+// encodePath encodes remote to send it
+func encodePath(remote string) string {
+	return pathEncoder.Encode(remote)
+}
+
+// decodePath decodes remote received from the other side
+func decodePath(remote string) string {
+	return pathEncoder.Decode(remote)
+}
@@ -0,0 +1,346 @@
+package external
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/list"
+	"github.com/rclone/rclone/fs/object"
+)
+
+// errAborted is used to cancel an upload
+var errAborted = errors.New("upload aborted")
+
+// readWriteCloser joins a reader and a writer into an io.ReadWriteCloser
+type readWriteCloser struct {
+	io.Reader
+	io.Writer
+}
+
+// Close the writer if it can be closed
+func (rwc readWriteCloser) Close() error {
+	if closer, ok := rwc.Writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Serve serves f using the protocol of the external backend, reading
+// requests from in and writing responses to out, until in is closed.
+//
+// This can be used to write a program which the external backend
+// runs from any backend written in Go, as "rclone serve backend" does.
+func Serve(ctx context.Context, f fs.Fs, in io.Reader, out io.Writer) error {
+	s := &Server{
+		ctx:     ctx,
+		f:       f,
+		handles: make(map[uint64]interface{}),
+	}
+	defer s.closeHandles()
+	server := rpc.NewServer()
+	err := server.RegisterName(serviceName, s)
+	if err != nil {
+		return err
+	}
+	server.ServeCodec(jsonrpc.NewServerCodec(readWriteCloser{Reader: in, Writer: out}))
+	return nil
+}
+
+// upload is an upload in progress started by Create
+type upload struct {
+	pw   *io.PipeWriter
+	done chan error
+	o    fs.Object
+}
+
+// Server serves an fs.Fs for the external backend.
+//
+// The exported methods are the requests it answers.
+type Server struct {
+	ctx context.Context
+	f   fs.Fs
+
+	mu      sync.Mutex
+	handles map[uint64]interface{} // io.ReadCloser or *upload
+	next    uint64
+}
+
+// addHandle stores x returning its handle
+func (s *Server) addHandle(x interface{}) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	s.handles[s.next] = x
+	return s.next
+}
+
+// getHandle returns what handle refers to
+func (s *Server) getHandle(handle uint64) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	x, ok := s.handles[handle]
+	if !ok {
+		return nil, errors.Errorf("unknown handle %d", handle)
+	}
+	return x, nil
+}
+
+// removeHandle forgets handle
+func (s *Server) removeHandle(handle uint64) {
+	s.mu.Lock()
+	delete(s.handles, handle)
+	s.mu.Unlock()
+}
+
+// closeHandles closes anything left open when the client goes away
+func (s *Server) closeHandles() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for handle, x := range s.handles {
+		switch x := x.(type) {
+		case io.ReadCloser:
+			_ = x.Close()
+		case *upload:
+			_ = x.pw.CloseWithError(errAborted)
+			<-x.done
+		}
+		delete(s.handles, handle)
+	}
+}
+
+// encodeEntry encodes entry to send it
+func (s *Server) encodeEntry(entry fs.DirEntry) (Entry, error) {
+	e, err := list.EncodeEntry(s.ctx, s.f, entry)
+	e.Remote = encodePath(e.Remote)
+	return e, err
+}
+
+// Info returns a description of the Fs
+func (s *Server) Info(args *Empty, reply *InfoReply) error {
+	features := s.f.Features()
+	*reply = InfoReply{
+		Name:                    s.f.Name(),
+		Root:                    s.f.Root(),
+		Precision:               s.f.Precision(),
+		CaseInsensitive:         features.CaseInsensitive,
+		CanHaveEmptyDirectories: features.CanHaveEmptyDirectories,
+		SlowModTime:             features.SlowModTime,
+		SlowHash:                features.SlowHash,
+	}
+	for _, ht := range s.f.Hashes().Array() {
+		reply.Hashes = append(reply.Hashes, ht.String())
+	}
+	return nil
+}
+
+// List the directory args.Remote
+func (s *Server) List(args *PathArgs, reply *ListReply) error {
+	entries, err := s.f.List(s.ctx, decodePath(args.Remote))
+	if err != nil {
+		return encodeError(err)
+	}
+	reply.Entries = make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		e, err := s.encodeEntry(entry)
+		if err != nil {
+			return err
+		}
+		reply.Entries = append(reply.Entries, e)
+	}
+	return nil
+}
+
+// Stat returns the object args.Remote
+func (s *Server) Stat(args *PathArgs, reply *Entry) (err error) {
+	o, err := s.f.NewObject(s.ctx, decodePath(args.Remote))
+	if err != nil {
+		return encodeError(err)
+	}
+	*reply, err = s.encodeEntry(o)
+	return err
+}
+
+// ModTime returns the modification time of the object args.Remote
+func (s *Server) ModTime(args *PathArgs, reply *Entry) error {
+	o, err := s.f.NewObject(s.ctx, decodePath(args.Remote))
+	if err != nil {
+		return encodeError(err)
+	}
+	modTime := o.ModTime(s.ctx)
+	*reply = Entry{
+		Remote:  encodePath(o.Remote()),
+		Size:    o.Size(),
+		ModTime: &modTime,
+	}
+	return nil
+}
+
+// Hash returns the hash args.Hash of the object args.Remote
+func (s *Server) Hash(args *HashArgs, reply *string) error {
+	var ht hash.Type
+	err := ht.Set(args.Hash)
+	if err != nil {
+		return err
+	}
+	o, err := s.f.NewObject(s.ctx, decodePath(args.Remote))
+	if err != nil {
+		return encodeError(err)
+	}
+	*reply, err = o.Hash(s.ctx, ht)
+	return encodeError(err)
+}
+
+// SetModTime sets the modification time of the object args.Remote
+func (s *Server) SetModTime(args *SetModTimeArgs, reply *Empty) error {
+	o, err := s.f.NewObject(s.ctx, decodePath(args.Remote))
+	if err != nil {
+		return encodeError(err)
+	}
+	return encodeError(o.SetModTime(s.ctx, args.ModTime))
+}
+
+// Remove the object args.Remote
+func (s *Server) Remove(args *PathArgs, reply *Empty) error {
+	o, err := s.f.NewObject(s.ctx, decodePath(args.Remote))
+	if err != nil {
+		return encodeError(err)
+	}
+	return encodeError(o.Remove(s.ctx))
+}
+
+// Mkdir makes the directory args.Remote
+func (s *Server) Mkdir(args *PathArgs, reply *Empty) error {
+	return encodeError(s.f.Mkdir(s.ctx, decodePath(args.Remote)))
+}
+
+// Rmdir removes the empty directory args.Remote
+func (s *Server) Rmdir(args *PathArgs, reply *Empty) error {
+	return encodeError(s.f.Rmdir(s.ctx, decodePath(args.Remote)))
+}
+
+// Open the object args.Remote for reading with Read
+func (s *Server) Open(args *OpenArgs, reply *HandleReply) error {
+	o, err := s.f.NewObject(s.ctx, decodePath(args.Remote))
+	if err != nil {
+		return encodeError(err)
+	}
+	var options []fs.OpenOption
+	if args.Limit >= 0 {
+		options = append(options, &fs.RangeOption{Start: args.Offset, End: args.Offset + args.Limit - 1})
+	} else if args.Offset > 0 {
+		options = append(options, &fs.SeekOption{Offset: args.Offset})
+	}
+	in, err := o.Open(s.ctx, options...)
+	if err != nil {
+		return encodeError(err)
+	}
+	reply.Handle = s.addHandle(in)
+	return nil
+}
+
+// Read up to args.Size bytes from a handle returned by Open
+func (s *Server) Read(args *ReadArgs, reply *ReadReply) error {
+	x, err := s.getHandle(args.Handle)
+	if err != nil {
+		return err
+	}
+	in, ok := x.(io.ReadCloser)
+	if !ok {
+		return errors.Errorf("handle %d not open for reading", args.Handle)
+	}
+	size := args.Size
+	if size <= 0 || size > readChunkSize {
+		size = readChunkSize
+	}
+	buf := make([]byte, size)
+	n, err := io.ReadFull(in, buf)
+	reply.Data = buf[:n]
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		reply.EOF = true
+		err = nil
+	}
+	return err
+}
+
+// Create starts an upload of args.Remote which is written with Write
+// and finished with Close
+func (s *Server) Create(args *CreateArgs, reply *HandleReply) error {
+	hashes := make(map[hash.Type]string, len(args.Hashes))
+	for name, sum := range args.Hashes {
+		var ht hash.Type
+		if ht.Set(name) == nil {
+			hashes[ht] = sum
+		}
+	}
+	src := object.NewStaticObjectInfo(decodePath(args.Remote), args.ModTime, args.Size, true, hashes, nil)
+	pr, pw := io.Pipe()
+	u := &upload{
+		pw:   pw,
+		done: make(chan error, 1),
+	}
+	go func() {
+		o, err := s.f.NewObject(s.ctx, decodePath(args.Remote))
+		if err == nil {
+			err = o.Update(s.ctx, pr, src)
+		} else if err == fs.ErrorObjectNotFound {
+			o, err = s.f.Put(s.ctx, pr, src)
+		}
+		u.o = o
+		// Make any remaining writes fail
+		_ = pr.CloseWithError(err)
+		u.done <- err
+	}()
+	reply.Handle = s.addHandle(u)
+	return nil
+}
+
+// Write args.Data to a handle returned by Create
+func (s *Server) Write(args *WriteArgs, reply *Empty) error {
+	x, err := s.getHandle(args.Handle)
+	if err != nil {
+		return err
+	}
+	u, ok := x.(*upload)
+	if !ok {
+		return errors.Errorf("handle %d not open for writing", args.Handle)
+	}
+	_, err = u.pw.Write(args.Data)
+	return err
+}
+
+// Close a handle returned by Open or Create.
+//
+// When closing an upload this returns the object uploaded.
+func (s *Server) Close(args *CloseArgs, reply *Entry) (err error) {
+	x, err := s.getHandle(args.Handle)
+	if err != nil {
+		return err
+	}
+	s.removeHandle(args.Handle)
+	switch x := x.(type) {
+	case io.ReadCloser:
+		return x.Close()
+	case *upload:
+		if args.Abort {
+			_ = x.pw.CloseWithError(errAborted)
+		} else {
+			_ = x.pw.Close()
+		}
+		err = <-x.done
+		if err != nil {
+			return encodeError(err)
+		}
+		if args.Abort {
+			return errAborted
+		}
+		*reply, err = s.encodeEntry(x.o)
+		return err
+	}
+	return errors.Errorf("unknown handle type %T", x)
+}
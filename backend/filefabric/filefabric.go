@@ -233,7 +233,7 @@ func (f *Fs) shouldRetry(ctx context.Context, resp *http.Response, err error, st
 		return false, err
 	}
 	if err != nil {
-		return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+		return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), fserrors.RateLimitedHTTP(resp, err)
 	}
 	if status != nil && !status.OK() {
 		err = status // return the error from the RPC
@@ -13,6 +13,7 @@ FIXME Patch/Delete/Get isn't working with files with spaces in - giving 404 erro
 */
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/hex"
@@ -34,12 +35,14 @@ import (
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/fshttp"
 	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fs/walk"
 	"github.com/rclone/rclone/lib/bucket"
 	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/lib/env"
 	"github.com/rclone/rclone/lib/oauthutil"
 	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/version"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/googleapi"
@@ -87,6 +90,7 @@ func init() {
 				OAuth2Config: storageConfig,
 			})
 		},
+		CommandHelp: commandHelp,
 		Options: append(oauthutil.SharedOptions, []fs.Option{{
 			Name: "project_number",
 			Help: "Project number.\nOptional - needed only for list/create/delete buckets - see your developer console.",
@@ -247,6 +251,19 @@ Docs: https://cloud.google.com/storage/docs/bucket-policy-only
 				Value: "DURABLE_REDUCED_AVAILABILITY",
 				Help:  "Durable reduced availability storage class",
 			}},
+		}, {
+			Name: "directory_markers",
+			Help: `Create and read directory markers.
+
+If set, Mkdir uploads a zero length object whose name is the directory
+with a trailing "/" and Rmdir deletes it. These markers are what the
+Google Cloud console and gsutil use to show empty folders.
+
+In listings these objects are shown as directories rather than being
+ignored, so empty directories made by rclone or these tools are
+preserved and can be synced.`,
+			Default:  false,
+			Advanced: true,
 		}, {
 			Name:     config.ConfigEncoding,
 			Help:     config.ConfigEncodingHelp,
@@ -269,6 +286,7 @@ type Options struct {
 	BucketPolicyOnly          bool                 `config:"bucket_policy_only"`
 	Location                  string               `config:"location"`
 	StorageClass              string               `config:"storage_class"`
+	DirectoryMarkers          bool                 `config:"directory_markers"`
 	Enc                       encoder.MultiEncoder `config:"encoding"`
 }
 
@@ -290,13 +308,18 @@ type Fs struct {
 //
 // Will definitely have info but maybe not meta
 type Object struct {
-	fs       *Fs       // what this object is part of
-	remote   string    // The remote path
-	url      string    // download path
-	md5sum   string    // The MD5Sum of the object
-	bytes    int64     // Bytes in the object
-	modTime  time.Time // Modified time of the object
-	mimeType string
+	fs         *Fs       // what this object is part of
+	remote     string    // The remote path
+	url        string    // download path
+	md5sum     string    // The MD5Sum of the object
+	crc32c     string    // The CRC-32C of the object
+	bytes      int64     // Bytes in the object
+	modTime    time.Time // Modified time of the object
+	mimeType   string
+	meta       map[string]string // metadata of the object
+	tier       string            // storage class of the object
+	generation int64             // generation of the old version this is or 0 for the current one
+	snapshot   bool              // set if this is an old version listed under its usual name
 }
 
 // ------------------------------------------------------------
@@ -342,10 +365,12 @@ func shouldRetry(ctx context.Context, err error) (again bool, errOut error) {
 				if gerr.Code >= 500 && gerr.Code < 600 {
 					// All 5xx errors should be retried
 					again = true
+					err = fserrors.RateLimitedStatus(gerr.Code, err)
 				} else if len(gerr.Errors) > 0 {
 					reason := gerr.Errors[0].Reason
 					if reason == "rateLimitExceeded" || reason == "userRateLimitExceeded" {
 						again = true
+						err = pacer.RateLimitedError(err)
 					}
 				}
 			}
@@ -369,7 +394,11 @@ func (f *Fs) split(rootRelativePath string) (bucketName, bucketPath string) {
 
 // split returns bucket and bucketPath from the object
 func (o *Object) split() (bucket, bucketPath string) {
-	return o.fs.split(o.remote)
+	remote := o.remote
+	if o.generation != 0 && !o.snapshot {
+		_, remote = version.Remove(remote)
+	}
+	return o.fs.split(remote)
 }
 
 func getServiceAccountClient(ctx context.Context, credentialsData []byte) (*http.Client, error) {
@@ -443,6 +472,10 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		WriteMimeType:     true,
 		BucketBased:       true,
 		BucketBasedRootOK: true,
+		SetTier:           true,
+		GetTier:           true,
+
+		CanHaveEmptyDirectories: opt.DirectoryMarkers,
 	}).Fill(ctx, f)
 
 	// Create a new authorized Drive client.
@@ -567,6 +600,16 @@ func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBuck
 			}
 			// is this a directory marker?
 			if isDirectory {
+				// When recursing there are no prefixes so
+				// return the marker as a directory unless it
+				// is the directory being listed
+				remote = strings.TrimSuffix(remote, "/")
+				if f.opt.DirectoryMarkers && recurse && object.Name != directory && remote != "" {
+					err = fn(remote, &storage.Object{Name: remote}, true)
+					if err != nil {
+						return err
+					}
+				}
 				continue // skip directory marker
 			}
 			err = fn(remote, object, false)
@@ -739,10 +782,61 @@ func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, opt
 	return f.Put(ctx, in, src, options...)
 }
 
-// Mkdir creates the bucket if it doesn't exist
+// Mkdir creates the bucket if it doesn't exist and the directory
+// marker if using directory markers
 func (f *Fs) Mkdir(ctx context.Context, dir string) (err error) {
-	bucket, _ := f.split(dir)
-	return f.makeBucket(ctx, bucket)
+	bucket, directory := f.split(dir)
+	err = f.makeBucket(ctx, bucket)
+	if err != nil || !f.opt.DirectoryMarkers || directory == "" {
+		return err
+	}
+	return f.putDirectoryMarker(ctx, bucket, directory)
+}
+
+// putDirectoryMarker uploads the zero length object marking directory
+func (f *Fs) putDirectoryMarker(ctx context.Context, bucket, directory string) error {
+	object := storage.Object{
+		Bucket:      bucket,
+		Name:        directory + "/",
+		ContentType: "application/x-directory",
+	}
+	err := f.pacer.Call(func() (bool, error) {
+		insertObject := f.svc.Objects.Insert(bucket, &object).Media(bytes.NewReader(nil), googleapi.ContentType("")).Name(object.Name)
+		if !f.opt.BucketPolicyOnly {
+			insertObject.PredefinedAcl(f.opt.ObjectACL)
+		}
+		_, err := insertObject.Context(ctx).Do()
+		return shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create directory marker")
+	}
+	fs.Debugf(f, "Created directory marker %q", object.Name)
+	return nil
+}
+
+// removeDirectoryMarker deletes the marker of directory returning an
+// error if the directory isn't empty
+func (f *Fs) removeDirectoryMarker(ctx context.Context, dir, bucket, directory string) error {
+	entries, err := f.listDir(ctx, bucket, directory, f.rootDirectory, f.rootBucket == "")
+	if err != nil {
+		return err
+	}
+	if len(entries) != 0 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	err = f.pacer.Call(func() (bool, error) {
+		err := f.svc.Objects.Delete(bucket, directory+"/").Context(ctx).Do()
+		return shouldRetry(ctx, err)
+	})
+	if gErr, ok := err.(*googleapi.Error); ok && gErr.Code == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to remove directory marker")
+	}
+	fs.Debugf(f, "Removed directory marker for %q", dir)
+	return nil
 }
 
 // makeBucket creates the bucket if it doesn't exist
@@ -792,12 +886,16 @@ func (f *Fs) makeBucket(ctx context.Context, bucket string) (err error) {
 	}, nil)
 }
 
-// Rmdir deletes the bucket if the fs is at the root
+// Rmdir deletes the bucket if the fs is at the root or the directory
+// marker if using directory markers
 //
 // Returns an error if it isn't empty: Error 409: The bucket you tried
 // to delete was not empty.
 func (f *Fs) Rmdir(ctx context.Context, dir string) (err error) {
 	bucket, directory := f.split(dir)
+	if bucket != "" && directory != "" && f.opt.DirectoryMarkers {
+		return f.removeDirectoryMarker(ctx, dir, bucket, directory)
+	}
 	if bucket == "" || directory != "" {
 		return nil
 	}
@@ -843,6 +941,9 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object,
 	}
 
 	rewriteRequest := f.svc.Objects.Rewrite(srcBucket, srcPath, dstBucket, dstPath, nil)
+	if srcObj.generation != 0 {
+		rewriteRequest.SourceGeneration(srcObj.generation)
+	}
 	if !f.opt.BucketPolicyOnly {
 		rewriteRequest.DestinationPredefinedAcl(f.opt.ObjectACL)
 	}
@@ -866,9 +967,263 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object,
 	return dstObj, nil
 }
 
+// errNotWithVersion is returned when trying to change an old version
+var errNotWithVersion = errors.New("can't modify or overwrite an old version")
+
+// ListVersions lists the old versions of the objects in dir, that
+// is the noncurrent generations, with the time they were created
+// added to their names.
+func (f *Fs) ListVersions(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	bucket, directory := f.split(dir)
+	if bucket == "" {
+		return nil, nil
+	}
+	if directory != "" {
+		directory += "/"
+	}
+	prefix := f.rootDirectory
+	if prefix != "" {
+		prefix += "/"
+	}
+	list := f.svc.Objects.List(bucket).Prefix(directory).Delimiter("/").Versions(true).MaxResults(listChunks)
+	for {
+		var objects *storage.Objects
+		err = f.pacer.Call(func() (bool, error) {
+			objects, err = list.Context(ctx).Do()
+			return shouldRetry(ctx, err)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list versions")
+		}
+		for _, object := range objects.Items {
+			// only noncurrent versions have been deleted
+			if object.TimeDeleted == "" {
+				continue
+			}
+			remote := f.opt.Enc.ToStandardPath(object.Name)
+			if !strings.HasPrefix(remote, prefix) || strings.HasSuffix(remote, "/") {
+				continue
+			}
+			remote = remote[len(prefix):]
+			if f.rootBucket == "" {
+				remote = path.Join(f.opt.Enc.ToStandardName(bucket), remote)
+			}
+			created, err := time.Parse(time.RFC3339, object.TimeCreated)
+			if err != nil {
+				fs.Debugf(remote, "Ignoring version with bad creation time %q: %v", object.TimeCreated, err)
+				continue
+			}
+			o := &Object{
+				fs:         f,
+				remote:     version.Add(remote, created),
+				generation: object.Generation,
+			}
+			o.setMetaData(object)
+			entries = append(entries, o)
+		}
+		if objects.NextPageToken == "" {
+			break
+		}
+		list.PageToken(objects.NextPageToken)
+	}
+	return entries, nil
+}
+
+// ListAt lists the objects and directories in dir as they were at t,
+// that is the generation of each object which was created at or
+// before t and not deleted until after it.
+func (f *Fs) ListAt(ctx context.Context, dir string, t time.Time) (entries fs.DirEntries, err error) {
+	bucket, directory := f.split(dir)
+	if bucket == "" {
+		return f.List(ctx, dir)
+	}
+	if directory != "" {
+		directory += "/"
+	}
+	prefix := f.rootDirectory
+	if prefix != "" {
+		prefix += "/"
+	}
+	list := f.svc.Objects.List(bucket).Prefix(directory).Delimiter("/").Versions(true).MaxResults(listChunks)
+	for {
+		var objects *storage.Objects
+		err = f.pacer.Call(func() (bool, error) {
+			objects, err = list.Context(ctx).Do()
+			return shouldRetry(ctx, err)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list versions")
+		}
+		for _, remote := range objects.Prefixes {
+			remote = f.opt.Enc.ToStandardPath(remote)
+			if !strings.HasPrefix(remote, prefix) || !strings.HasSuffix(remote, "/") {
+				continue
+			}
+			remote = remote[len(prefix) : len(remote)-1]
+			if f.rootBucket == "" {
+				remote = path.Join(f.opt.Enc.ToStandardName(bucket), remote)
+			}
+			entries = append(entries, fs.NewDir(remote, time.Time{}))
+		}
+		for _, object := range objects.Items {
+			remote := f.opt.Enc.ToStandardPath(object.Name)
+			if !strings.HasPrefix(remote, prefix) || strings.HasSuffix(remote, "/") {
+				continue
+			}
+			remote = remote[len(prefix):]
+			if f.rootBucket == "" {
+				remote = path.Join(f.opt.Enc.ToStandardName(bucket), remote)
+			}
+			created, err := time.Parse(time.RFC3339, object.TimeCreated)
+			if err != nil {
+				fs.Debugf(remote, "Ignoring version with bad creation time %q: %v", object.TimeCreated, err)
+				continue
+			}
+			if created.After(t) {
+				continue
+			}
+			o := &Object{
+				fs:     f,
+				remote: remote,
+			}
+			if object.TimeDeleted != "" {
+				deleted, err := time.Parse(time.RFC3339, object.TimeDeleted)
+				if err != nil {
+					fs.Debugf(remote, "Ignoring version with bad deletion time %q: %v", object.TimeDeleted, err)
+					continue
+				}
+				if !deleted.After(t) {
+					continue
+				}
+				o.generation = object.Generation
+				o.snapshot = true
+			}
+			o.setMetaData(object)
+			entries = append(entries, o)
+		}
+		if objects.NextPageToken == "" {
+			break
+		}
+		list.PageToken(objects.NextPageToken)
+	}
+	return entries, nil
+}
+
+// maxComposeSources is the most objects a single compose request can
+// join
+const maxComposeSources = 32
+
+// concat joins the objects srcRemotes into a new object dstRemote
+// with server-side compose requests.
+//
+// More than maxComposeSources are joined by composing the destination
+// with the next sources repeatedly.
+func (f *Fs) concat(ctx context.Context, dstRemote string, srcRemotes []string) (fs.Object, error) {
+	dstBucket, dstPath := f.split(dstRemote)
+	var sources []*storage.ComposeRequestSourceObjects
+	contentType := ""
+	for _, srcRemote := range srcRemotes {
+		srcBucket, srcPath := f.split(srcRemote)
+		if srcBucket != dstBucket {
+			return nil, errors.Errorf("can't concatenate %q: sources must be in the same bucket as the destination", srcRemote)
+		}
+		o, err := f.NewObject(ctx, srcRemote)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find source %q", srcRemote)
+		}
+		if contentType == "" {
+			contentType = o.(*Object).mimeType
+		}
+		sources = append(sources, &storage.ComposeRequestSourceObjects{Name: srcPath})
+	}
+	if operations.SkipDestructive(ctx, dstRemote, "concatenate") {
+		return nil, nil
+	}
+	dstObj := &Object{
+		fs:     f,
+		remote: dstRemote,
+	}
+	for len(sources) > 0 {
+		n := len(sources)
+		if n > maxComposeSources {
+			n = maxComposeSources
+		}
+		req := storage.ComposeRequest{
+			Destination: &storage.Object{
+				Bucket:      dstBucket,
+				Name:        dstPath,
+				ContentType: contentType,
+				Metadata:    metadataFromModTime(time.Now()),
+			},
+			SourceObjects: sources[:n],
+		}
+		sources = sources[n:]
+		if len(sources) > 0 {
+			// Carry on from the part joined so far
+			sources = append([]*storage.ComposeRequestSourceObjects{{Name: dstPath}}, sources...)
+		}
+		var newObject *storage.Object
+		err := f.pacer.Call(func() (bool, error) {
+			composeCall := f.svc.Objects.Compose(dstBucket, dstPath, &req)
+			if !f.opt.BucketPolicyOnly {
+				composeCall.DestinationPredefinedAcl(f.opt.ObjectACL)
+			}
+			var err error
+			newObject, err = composeCall.Context(ctx).Do()
+			return shouldRetry(ctx, err)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "compose failed")
+		}
+		dstObj.setMetaData(newObject)
+	}
+	return dstObj, nil
+}
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "concat",
+	Short: "Join objects into a new object server-side",
+	Long: `This command joins the source objects, in order, into a new
+destination object without downloading them, using the GCS compose
+API. All the paths are relative to the remote and must be in the same
+bucket.
+
+    rclone backend concat gcs:bucket/logs all.log 01.log 02.log 03.log
+
+This makes gcs:bucket/logs/all.log from the three logs. The sources
+are left as they are and the destination is overwritten if it exists.
+Any number of sources can be given - they are joined 32 at a time.
+
+Note that you can use -i/--dry-run with this command to see what it
+would do.
+`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "concat":
+		if len(arg) < 2 {
+			return nil, errors.New("need a destination and at least one source")
+		}
+		_, err = f.concat(ctx, arg[0], arg[1:])
+		return nil, err
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
-	return hash.Set(hash.MD5)
+	return hash.NewHashSet(hash.MD5, hash.CRC32C)
 }
 
 // ------------------------------------------------------------
@@ -891,12 +1246,16 @@ func (o *Object) Remote() string {
 	return o.remote
 }
 
-// Hash returns the Md5sum of an object returning a lowercase hex string
+// Hash returns the Md5sum or CRC-32C of an object returning a
+// lowercase hex string
 func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
-	if t != hash.MD5 {
-		return "", hash.ErrUnsupported
+	switch t {
+	case hash.MD5:
+		return o.md5sum, nil
+	case hash.CRC32C:
+		return o.crc32c, nil
 	}
-	return o.md5sum, nil
+	return "", hash.ErrUnsupported
 }
 
 // Size returns the size of an object in bytes
@@ -909,6 +1268,8 @@ func (o *Object) setMetaData(info *storage.Object) {
 	o.url = info.MediaLink
 	o.bytes = int64(info.Size)
 	o.mimeType = info.ContentType
+	o.meta = info.Metadata
+	o.tier = info.StorageClass
 
 	// Read md5sum
 	md5sumData, err := base64.StdEncoding.DecodeString(info.Md5Hash)
@@ -918,6 +1279,14 @@ func (o *Object) setMetaData(info *storage.Object) {
 		o.md5sum = hex.EncodeToString(md5sumData)
 	}
 
+	// Read crc32c, which is big endian
+	crc32cData, err := base64.StdEncoding.DecodeString(info.Crc32c)
+	if err != nil {
+		fs.Logf(o, "Bad CRC-32C decode: %v", err)
+	} else {
+		o.crc32c = hex.EncodeToString(crc32cData)
+	}
+
 	// read mtime out of metadata if available
 	mtimeString, ok := info.Metadata[metaMtime]
 	if ok {
@@ -953,7 +1322,11 @@ func (o *Object) setMetaData(info *storage.Object) {
 func (o *Object) readObjectInfo(ctx context.Context) (object *storage.Object, err error) {
 	bucket, bucketPath := o.split()
 	err = o.fs.pacer.Call(func() (bool, error) {
-		object, err = o.fs.svc.Objects.Get(bucket, bucketPath).Context(ctx).Do()
+		getRequest := o.fs.svc.Objects.Get(bucket, bucketPath)
+		if o.generation != 0 {
+			getRequest.Generation(o.generation)
+		}
+		object, err = getRequest.Context(ctx).Do()
 		return shouldRetry(ctx, err)
 	})
 	if err != nil {
@@ -1005,6 +1378,9 @@ func metadataFromModTime(modTime time.Time) map[string]string {
 
 // SetModTime sets the modification time of the local fs object
 func (o *Object) SetModTime(ctx context.Context, modTime time.Time) (err error) {
+	if o.generation != 0 {
+		return errNotWithVersion
+	}
 	// read the complete existing object first
 	object, err := o.readObjectInfo(ctx)
 	if err != nil {
@@ -1016,6 +1392,12 @@ func (o *Object) SetModTime(ctx context.Context, modTime time.Time) (err error)
 	}
 	object.Metadata[metaMtime] = modTime.Format(timeFormat)
 	object.Metadata[metaMtimeGsutil] = strconv.FormatInt(modTime.Unix(), 10)
+	return o.replaceMetadata(ctx, object)
+}
+
+// replaceMetadata copies the object to itself to replace its metadata
+// with that in object
+func (o *Object) replaceMetadata(ctx context.Context, object *storage.Object) (err error) {
 	// Copy the object to itself to update the metadata
 	// Using PATCH requires too many permissions
 	bucket, bucketPath := o.split()
@@ -1035,6 +1417,76 @@ func (o *Object) SetModTime(ctx context.Context, modTime time.Time) (err error)
 	return nil
 }
 
+// SetTier changes the storage class of the object by copying it to
+// itself
+func (o *Object) SetTier(tier string) error {
+	if o.generation != 0 {
+		return errNotWithVersion
+	}
+	ctx := context.TODO()
+	tier = strings.ToUpper(tier)
+	object, err := o.readObjectInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if object.StorageClass == tier {
+		return nil
+	}
+	object.StorageClass = tier
+	return o.replaceMetadata(ctx, object)
+}
+
+// GetTier returns the storage class of the object
+func (o *Object) GetTier() string {
+	return o.tier
+}
+
+// isFsMetaKey returns true if key is one of the fs.Metadata keys
+// which are stored in the object's metadata as they are
+func isFsMetaKey(key string) bool {
+	switch key {
+	case fs.MetadataMode, fs.MetadataUID, fs.MetadataGID:
+		return true
+	}
+	return strings.HasPrefix(key, fs.MetadataXattrPrefix)
+}
+
+// Metadata returns the mode, owner and extended attributes stored in
+// the object's metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	err := o.readMetaData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m := fs.Metadata{}
+	for key, value := range o.meta {
+		if isFsMetaKey(key) {
+			m[key] = value
+		}
+	}
+	return m, nil
+}
+
+// SetMetadata stores the keys in m in the object's metadata leaving
+// the others unchanged
+func (o *Object) SetMetadata(ctx context.Context, m fs.Metadata) (err error) {
+	if o.generation != 0 {
+		return errNotWithVersion
+	}
+	// read the complete existing object first
+	object, err := o.readObjectInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if object.Metadata == nil {
+		object.Metadata = make(map[string]string, len(m))
+	}
+	for key, value := range m {
+		object.Metadata[key] = value
+	}
+	return o.replaceMetadata(ctx, object)
+}
+
 // Storable returns a boolean as to whether this object is storable
 func (o *Object) Storable() bool {
 	return true
@@ -1074,6 +1526,9 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.Read
 //
 // The new object may have been created if an error is returned
 func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	if o.generation != 0 {
+		return errNotWithVersion
+	}
 	bucket, bucketPath := o.split()
 	err := o.fs.makeBucket(ctx, bucket)
 	if err != nil {
@@ -1087,6 +1542,18 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		ContentType: fs.MimeType(ctx, src),
 		Metadata:    metadataFromModTime(modTime),
 	}
+	// Set the mode, owner and xattrs from src if preserving metadata
+	if fs.GetConfig(ctx).Metadata {
+		srcMeta, err := fs.GetMetadata(ctx, src)
+		if err != nil {
+			fs.Debugf(o, "Failed to read source metadata: %v", err)
+		}
+		for key, value := range srcMeta {
+			if isFsMetaKey(key) {
+				object.Metadata[key] = value
+			}
+		}
+	}
 	// Apply upload options
 	for _, option := range options {
 		key, value := option.Header()
@@ -1137,7 +1604,11 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 func (o *Object) Remove(ctx context.Context) (err error) {
 	bucket, bucketPath := o.split()
 	err = o.fs.pacer.Call(func() (bool, error) {
-		err = o.fs.svc.Objects.Delete(bucket, bucketPath).Context(ctx).Do()
+		deleteRequest := o.fs.svc.Objects.Delete(bucket, bucketPath)
+		if o.generation != 0 {
+			deleteRequest.Generation(o.generation)
+		}
+		err = deleteRequest.Context(ctx).Do()
 		return shouldRetry(ctx, err)
 	})
 	return err
@@ -1150,10 +1621,17 @@ func (o *Object) MimeType(ctx context.Context) string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Copier      = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.ListRer     = &Fs{}
-	_ fs.Object      = &Object{}
-	_ fs.MimeTyper   = &Object{}
+	_ fs.Fs             = &Fs{}
+	_ fs.Copier         = &Fs{}
+	_ fs.PutStreamer    = &Fs{}
+	_ fs.ListRer        = &Fs{}
+	_ fs.Commander      = &Fs{}
+	_ fs.VersionLister  = &Fs{}
+	_ fs.SnapshotLister = &Fs{}
+	_ fs.Object         = &Object{}
+	_ fs.MimeTyper      = &Object{}
+	_ fs.Metadataer     = &Object{}
+	_ fs.SetMetadataer  = &Object{}
+	_ fs.SetTierer      = &Object{}
+	_ fs.GetTierer      = &Object{}
 )
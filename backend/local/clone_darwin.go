@@ -0,0 +1,15 @@
+// +build darwin
+
+package local
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile makes dst a copy of src which shares its data blocks
+// using clonefile(2). This works on APFS.
+//
+// dst must not exist.
+func cloneFile(src, dst string) error {
+	return unix.Clonefile(src, dst, unix.CLONE_NOFOLLOW)
+}
@@ -0,0 +1,10 @@
+// +build !linux,!darwin
+
+package local
+
+import "errors"
+
+// cloneFile isn't supported on this OS
+func cloneFile(src, dst string) error {
+	return errors.New("cloning files not supported on this OS")
+}
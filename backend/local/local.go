@@ -26,6 +26,7 @@ import (
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/lib/file"
+	"github.com/rclone/rclone/lib/random"
 	"github.com/rclone/rclone/lib/readers"
 	"golang.org/x/text/unicode/norm"
 )
@@ -135,8 +136,16 @@ time we:
 			Default:  false,
 			Advanced: true,
 		}, {
-			Name:     "one_file_system",
-			Help:     "Don't cross filesystem boundaries (unix/macOS only).",
+			Name: "one_file_system",
+			Help: `Don't cross filesystem boundaries.
+
+On Unix based systems directories on a different device to the root
+are skipped. On Windows directories on a different volume are
+skipped, so volumes mounted in a folder, and junctions or symlinks
+followed with --copy-links which point to a different volume, aren't
+recursed into.
+
+This can be set in the config file for each local remote.`,
 			Default:  false,
 			NoPrefix: true,
 			ShortOpt: "x",
@@ -172,12 +181,30 @@ Use this flag to disable preallocation.`,
 			Advanced: true,
 		}, {
 			Name: "no_sparse",
-			Help: `Disable sparse files for multi-thread downloads
+			Help: `Disable sparse files
 
 On Windows platforms rclone will make sparse files when doing
 multi-thread downloads. This avoids long pauses on large files where
-the OS zeros the file. However sparse files may be undesirable as they
-cause disk fragmentation and can be slow to work with.`,
+the OS zeros the file.
+
+When copying a sparse file from the local disk (one with holes in,
+such as a disk image) rclone leaves holes in the copy where the data
+is zero so it doesn't take up more space than the original.
+
+However sparse files may be undesirable as they cause disk
+fragmentation and can be slow to work with. This flag disables both.`,
+			Default:  false,
+			Advanced: true,
+		}, {
+			Name: "sparse_downloads",
+			Help: `Write downloaded files as sparse files
+
+Normally only copies of sparse local files are written as sparse files.
+If this flag is set then any blocks of zeros in files downloaded from
+remotes are left as holes too.
+
+This is useful when downloading disk images or other files with large
+runs of zeros. It has no effect if --local-no-sparse is set.`,
 			Default:  false,
 			Advanced: true,
 		}, {
@@ -191,6 +218,20 @@ when copying to a CIFS mount owned by another user. If this option is
 enabled, rclone will no longer update the modtime after copying a file.`,
 			Default:  false,
 			Advanced: true,
+		}, {
+			Name: "no_clone",
+			Help: `Disable cloning files when copying within the local disk
+
+Normally when copying a file to somewhere else on the same filesystem
+rclone asks the filesystem to clone it, so the copy shares its data
+with the original until one of them is changed. This is nearly
+instantaneous however big the file is. This works on APFS on macOS
+(clonefile) and on btrfs and XFS on Linux (reflinks). On filesystems
+which don't support it rclone copies the data as normal.
+
+If this flag is set, rclone will always copy the data.`,
+			Default:  false,
+			Advanced: true,
 		}, {
 			Name:     config.ConfigEncoding,
 			Help:     config.ConfigEncodingHelp,
@@ -214,7 +255,9 @@ type Options struct {
 	CaseInsensitive   bool                 `config:"case_insensitive"`
 	NoPreAllocate     bool                 `config:"no_preallocate"`
 	NoSparse          bool                 `config:"no_sparse"`
+	SparseDownloads   bool                 `config:"sparse_downloads"`
 	NoSetModTime      bool                 `config:"no_set_modtime"`
+	NoClone           bool                 `config:"no_clone"`
 	Enc               encoder.MultiEncoder `config:"encoding"`
 }
 
@@ -286,7 +329,7 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	// Check to see if this points to a file
 	fi, err := f.lstat(f.root)
 	if err == nil {
-		f.dev = readDevice(fi, f.opt.OneFileSystem)
+		f.dev = readDevice(f.root, fi, f.opt.OneFileSystem)
 	}
 	if err == nil && f.isRegular(fi.Mode()) {
 		// It is a file, so use the parent as the root
@@ -422,10 +465,29 @@ func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 // This should return ErrDirNotFound if the directory isn't
 // found.
 func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	err = f.ListP(ctx, dir, func(page fs.DirEntries) error {
+		entries = append(entries, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ListP lists the objects and directories in dir calling callback
+// with each page of entries as it is read.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
 	fsDirPath := f.localPath(dir)
 	_, err = os.Stat(fsDirPath)
 	if err != nil {
-		return nil, fs.ErrorDirNotFound
+		return fs.ErrorDirNotFound
 	}
 
 	fd, err := os.Open(fsDirPath)
@@ -437,7 +499,7 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 			_ = accounting.Stats(ctx).Error(fserrors.NoRetryError(err))
 			err = nil // ignore error but fail sync
 		}
-		return nil, err
+		return err
 	}
 	defer func() {
 		cerr := fd.Close()
@@ -482,9 +544,10 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 			}
 		}
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to read directory entry")
+			return errors.Wrap(err, "failed to read directory entry")
 		}
 
+		var entries fs.DirEntries
 		for _, fi := range fis {
 			name := fi.Name()
 			mode := fi.Mode()
@@ -501,14 +564,14 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 					continue
 				}
 				if err != nil {
-					return nil, err
+					return err
 				}
 				mode = fi.Mode()
 			}
 			if fi.IsDir() {
 				// Ignore directories which are symlinks.  These are junction points under windows which
 				// are kind of a souped up symlink. Unix doesn't have directories which are symlinks.
-				if (mode&os.ModeSymlink) == 0 && f.dev == readDevice(fi, f.opt.OneFileSystem) {
+				if (mode&os.ModeSymlink) == 0 && f.dev == readDevice(filepath.Join(fsDirPath, name), fi, f.opt.OneFileSystem) {
 					d := fs.NewDir(newRemote, fi.ModTime())
 					entries = append(entries, d)
 				}
@@ -519,15 +582,21 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 				}
 				fso, err := f.newObjectWithInfo(newRemote, fi)
 				if err != nil {
-					return nil, err
+					return err
 				}
 				if fso.Storable() {
 					entries = append(entries, fso)
 				}
 			}
 		}
+		if len(entries) > 0 {
+			err = callback(entries)
+			if err != nil {
+				return err
+			}
+		}
 	}
-	return entries, nil
+	return nil
 }
 
 func (f *Fs) cleanRemote(dir, filename string) (remote string) {
@@ -580,7 +649,7 @@ func (f *Fs) Mkdir(ctx context.Context, dir string) error {
 		if err != nil {
 			return err
 		}
-		f.dev = readDevice(fi, f.opt.OneFileSystem)
+		f.dev = readDevice(localPath, fi, f.opt.OneFileSystem)
 	}
 	return nil
 }
@@ -740,6 +809,83 @@ func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object,
 	return dstObj, nil
 }
 
+// Copy src to this remote using server-side copy operations.
+//
+// This clones the file if the filesystem supports it, so the copy
+// shares its data with the original.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	if f.opt.NoClone || srcObj.fs.opt.NoClone {
+		return nil, fs.ErrorCantCopy
+	}
+	if srcObj.translatedLink {
+		fs.Debugf(src, "Can't clone translated link")
+		return nil, fs.ErrorCantCopy
+	}
+
+	// Temporary Object under construction
+	dstObj := f.newObject(remote)
+
+	// Check it is a file if it exists
+	err := dstObj.lstat()
+	dstObj.fs.objectMetaMu.RLock()
+	dstObjMode := dstObj.mode
+	dstObj.fs.objectMetaMu.RUnlock()
+	if os.IsNotExist(err) {
+		// OK
+	} else if err != nil {
+		return nil, err
+	} else if !dstObj.fs.isRegular(dstObjMode) {
+		// It isn't a file
+		return nil, errors.New("can't copy file onto non-file")
+	}
+
+	// Create destination
+	err = dstObj.mkdirAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// Clone to a temporary name then rename it over the destination
+	// so the destination is left alone if cloning fails
+	tmpPath := dstObj.path + "." + random.String(8) + ".clone"
+	err = cloneFile(srcObj.path, tmpPath)
+	if err != nil {
+		// probably not supported by the filesystem or trying
+		// to clone across file system boundaries.
+		fs.Debugf(src, "Can't clone: %v: copying instead", err)
+		return nil, fs.ErrorCantCopy
+	}
+	err = os.Rename(tmpPath, dstObj.path)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+
+	// Set the modification time and update the info
+	err = dstObj.SetModTime(ctx, src.ModTime(ctx))
+	if err != nil {
+		return nil, err
+	}
+	err = dstObj.lstat()
+	if err != nil {
+		return nil, err
+	}
+	return dstObj, nil
+}
+
 // DirMove moves src, srcRemote to this remote at dstRemote
 // using server-side move operations.
 //
@@ -1152,7 +1298,8 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 				return err
 			}
 		}
-		if !o.fs.opt.NoPreAllocate {
+		sparse := o.writeSparse(src)
+		if !o.fs.opt.NoPreAllocate && !sparse {
 			// Pre-allocate the file for performance reasons
 			err = file.PreAllocate(src.Size(), f)
 			if err != nil {
@@ -1163,7 +1310,18 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 				}
 			}
 		}
-		out = f
+		if sparse {
+			fs.Debugf(o, "Writing sparse file")
+			if file.SetSparseImplemented {
+				err = file.SetSparse(f)
+				if err != nil {
+					fs.Errorf(o, "Failed to set sparse: %v", err)
+				}
+			}
+			out = newSparseWriter(f)
+		} else {
+			out = f
+		}
 	} else {
 		out = nopWriterCloser{&symlinkData}
 	}
@@ -1343,9 +1501,11 @@ var (
 	_ fs.Fs             = &Fs{}
 	_ fs.Purger         = &Fs{}
 	_ fs.PutStreamer    = &Fs{}
+	_ fs.Copier         = &Fs{}
 	_ fs.Mover          = &Fs{}
 	_ fs.DirMover       = &Fs{}
 	_ fs.Commander      = &Fs{}
 	_ fs.OpenWriterAter = &Fs{}
+	_ fs.ListPer        = &Fs{}
 	_ fs.Object         = &Object{}
 )
@@ -1,17 +1,21 @@
 package local
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/lib/file"
 	"github.com/rclone/rclone/lib/readers"
@@ -166,3 +170,188 @@ func TestSymlinkError(t *testing.T) {
 	_, err := NewFs(context.Background(), "local", "/", m)
 	assert.Equal(t, errLinksAndCopyLinks, err)
 }
+
+func TestOneFileSystemConfig(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "windows" && runtime.GOOS != "darwin" {
+		t.Skip("device numbers not supported")
+	}
+	dir, err := ioutil.TempDir("", "rclone-one-file-system")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	for _, oneFileSystem := range []bool{false, true} {
+		m := configmap.Simple{
+			"one_file_system": fmt.Sprint(oneFileSystem),
+		}
+		f, err := NewFs(context.Background(), "local", dir, m)
+		require.NoError(t, err)
+		assert.Equal(t, oneFileSystem, f.(*Fs).dev != devUnset, oneFileSystem)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no POSIX permissions on Windows")
+	}
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("file.txt", "hello", time.Now())
+	o, err := r.Flocal.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+	obj := o.(*Object)
+
+	err = obj.SetMetadata(ctx, fs.Metadata{"mode": "640"})
+	require.NoError(t, err)
+	m, err := obj.Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "640", m["mode"])
+	uid, ok := m.ID(fs.MetadataUID)
+	require.True(t, ok)
+	assert.Equal(t, uint32(os.Getuid()), uid)
+	gid, ok := m.ID(fs.MetadataGID)
+	require.True(t, ok)
+
+	// Setting the owner to the current owner always works
+	err = obj.SetMetadata(ctx, fs.Metadata{"uid": m["uid"], "gid": m["gid"]})
+	require.NoError(t, err)
+	m, err = obj.Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprint(gid), m["gid"])
+
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		return
+	}
+	err = obj.SetMetadata(ctx, fs.Metadata{"xattr-user.rclone-test": "potato"})
+	if err != nil {
+		t.Skipf("extended attributes not supported: %v", err)
+	}
+	m, err = obj.Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "potato", m["xattr-user.rclone-test"])
+	assert.Equal(t, "potato", m.Xattrs()["user.rclone-test"])
+}
+
+func TestCopyClone(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	f := r.Flocal.(*Fs)
+	modTime := fstest.Time("2001-02-03T04:05:10.123123123Z")
+	file1 := r.WriteFile("file.txt", "hello", modTime)
+	src, err := f.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+
+	// Disabled with --local-no-clone
+	f.opt.NoClone = true
+	_, err = f.Copy(ctx, src, "copy.txt")
+	assert.Equal(t, fs.ErrorCantCopy, err)
+	f.opt.NoClone = false
+
+	dst, err := f.Copy(ctx, src, "dir/copy.txt")
+	if err == fs.ErrorCantCopy {
+		fstest.CheckItems(t, r.Flocal, file1)
+		t.Skip("filesystem doesn't support cloning")
+	}
+	require.NoError(t, err)
+	assert.Equal(t, "dir/copy.txt", dst.Remote())
+	file2 := fstest.NewItem("dir/copy.txt", "hello", modTime)
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+
+	// Copying over an existing file replaces it
+	file3 := r.WriteFile("file3.txt", "potato", modTime)
+	src, err = f.NewObject(ctx, file3.Path)
+	require.NoError(t, err)
+	_, err = f.Copy(ctx, src, "dir/copy.txt")
+	require.NoError(t, err)
+	file2 = fstest.NewItem("dir/copy.txt", "potato", modTime)
+	fstest.CheckItems(t, r.Flocal, file1, file2, file3)
+}
+
+func TestSparseWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-sparse-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	// data with a hole in the middle and at the end, written in
+	// chunks which don't line up with the blocks
+	data := make([]byte, 10*sparseBlockSize+100)
+	copy(data, "start")
+	copy(data[5*sparseBlockSize+10:], "middle")
+	for _, chunk := range []int{1, 1000, sparseBlockSize, 3 * sparseBlockSize} {
+		name := filepath.Join(dir, fmt.Sprintf("file%d", chunk))
+		f, err := os.Create(name)
+		require.NoError(t, err)
+		w := newSparseWriter(f)
+		for p := data; len(p) > 0; {
+			n := chunk
+			if n > len(p) {
+				n = len(p)
+			}
+			written, err := w.Write(p[:n])
+			require.NoError(t, err)
+			assert.Equal(t, n, written)
+			p = p[n:]
+		}
+		require.NoError(t, w.Close())
+		got, err := ioutil.ReadFile(name)
+		require.NoError(t, err)
+		assert.Equal(t, data, got, chunk)
+	}
+}
+
+func TestSparseCopy(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	f := r.Flocal.(*Fs)
+	f.opt.NoClone = true
+	require.NoError(t, os.MkdirAll(f.root, 0777))
+
+	// Make a sparse file
+	const size = 1024 * 1024
+	srcPath := filepath.Join(f.root, "sparse.img")
+	out, err := os.Create(srcPath)
+	require.NoError(t, err)
+	_, err = out.WriteAt([]byte("hello"), size/2)
+	require.NoError(t, err)
+	require.NoError(t, out.Truncate(size))
+	require.NoError(t, out.Close())
+	src, err := f.NewObject(ctx, "sparse.img")
+	require.NoError(t, err)
+	if !src.(*Object).isSparse() {
+		t.Skip("filesystem doesn't support finding holes")
+	}
+
+	for _, noSparse := range []bool{false, true} {
+		f.opt.NoSparse = noSparse
+		dst := f.newObject("copy.img")
+		in, err := src.Open(ctx)
+		require.NoError(t, err)
+		require.NoError(t, dst.Update(ctx, in, src))
+		require.NoError(t, in.Close())
+		assert.Equal(t, !noSparse, dst.isSparse(), noSparse)
+		assert.Equal(t, int64(size), dst.Size())
+		want, err := ioutil.ReadFile(srcPath)
+		require.NoError(t, err)
+		got, err := ioutil.ReadFile(dst.path)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	f.opt.NoSparse = false
+
+	// Downloads are only sparse with --local-sparse-downloads
+	data := make([]byte, size)
+	for _, sparseDownloads := range []bool{false, true} {
+		f.opt.SparseDownloads = sparseDownloads
+		dst := f.newObject("download.img")
+		src := object.NewStaticObjectInfo("download.img", time.Now(), size, true, nil, nil)
+		require.NoError(t, dst.Update(ctx, bytes.NewReader(data), src))
+		assert.Equal(t, sparseDownloads, dst.isSparse(), sparseDownloads)
+		assert.Equal(t, int64(size), dst.Size())
+	}
+	f.opt.SparseDownloads = false
+}
@@ -0,0 +1,72 @@
+package local
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+)
+
+// Metadata returns the mode, owner and extended attributes of the
+// file
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	info, err := o.fs.lstat(o.path)
+	if err != nil {
+		return nil, err
+	}
+	m := fs.Metadata{}
+	m.SetMode(info.Mode())
+	readOwner(info, m)
+	if !o.translatedLink {
+		err = readXattrs(o.path, m)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read extended attributes")
+		}
+	}
+	return m, nil
+}
+
+// SetMetadata sets the mode, owner and extended attributes in m on
+// the file
+func (o *Object) SetMetadata(ctx context.Context, m fs.Metadata) error {
+	if o.translatedLink {
+		return errors.New("can't set metadata on a translated link")
+	}
+	if mode, ok := m.Mode(); ok {
+		err := os.Chmod(o.path, mode)
+		if err != nil {
+			return errors.Wrap(err, "failed to set mode")
+		}
+	}
+	uid, uidOK := m.ID(fs.MetadataUID)
+	gid, gidOK := m.ID(fs.MetadataGID)
+	if uidOK || gidOK {
+		// -1 leaves the id unchanged
+		newUID, newGID := -1, -1
+		if uidOK {
+			newUID = int(uid)
+		}
+		if gidOK {
+			newGID = int(gid)
+		}
+		err := os.Chown(o.path, newUID, newGID)
+		if err != nil {
+			return errors.Wrap(err, "failed to set owner")
+		}
+	}
+	if xattrs := m.Xattrs(); len(xattrs) > 0 {
+		err := writeXattrs(o.path, xattrs)
+		if err != nil {
+			return errors.Wrap(err, "failed to set extended attributes")
+		}
+	}
+	// Re-read metadata
+	return o.lstat()
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Metadataer    = &Object{}
+	_ fs.SetMetadataer = &Object{}
+)
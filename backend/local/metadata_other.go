@@ -0,0 +1,12 @@
+// +build windows plan9 js
+
+package local
+
+import (
+	"os"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// readOwner does nothing as this OS doesn't have numeric owners
+func readOwner(info os.FileInfo, m fs.Metadata) {}
@@ -0,0 +1,18 @@
+// +build !windows,!plan9,!js
+
+package local
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// readOwner reads the uid and gid from info into m
+func readOwner(info os.FileInfo, m fs.Metadata) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		m.SetID(fs.MetadataUID, st.Uid)
+		m.SetID(fs.MetadataGID, st.Gid)
+	}
+}
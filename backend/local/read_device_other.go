@@ -1,13 +1,13 @@
 // Device reading functions
 
-// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris,!windows
 
 package local
 
 import "os"
 
-// readDevice turns a valid os.FileInfo into a device number,
+// readDevice turns a valid os.FileInfo for path into a device number,
 // returning devUnset if it fails.
-func readDevice(fi os.FileInfo, oneFileSystem bool) uint64 {
+func readDevice(path string, fi os.FileInfo, oneFileSystem bool) uint64 {
 	return devUnset
 }
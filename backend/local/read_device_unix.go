@@ -11,9 +11,9 @@ import (
 	"github.com/rclone/rclone/fs"
 )
 
-// readDevice turns a valid os.FileInfo into a device number,
+// readDevice turns a valid os.FileInfo for path into a device number,
 // returning devUnset if it fails.
-func readDevice(fi os.FileInfo, oneFileSystem bool) uint64 {
+func readDevice(path string, fi os.FileInfo, oneFileSystem bool) uint64 {
 	if !oneFileSystem {
 		return devUnset
 	}
@@ -0,0 +1,45 @@
+// Device reading functions
+
+// +build windows
+
+package local
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// readDevice turns a valid os.FileInfo for path into a device number,
+// returning devUnset if it fails.
+//
+// On Windows this is the serial number of the volume path is on, so
+// volumes mounted in a folder and junctions to other volumes are
+// detected.
+func readDevice(path string, fi os.FileInfo, oneFileSystem bool) uint64 {
+	if !oneFileSystem {
+		return devUnset
+	}
+	pathp, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		fs.Debugf(path, "Failed to read device: %v", err)
+		return devUnset
+	}
+	// FILE_FLAG_BACKUP_SEMANTICS is needed to open directories
+	h, err := syscall.CreateFile(pathp, 0, syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE, nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		fs.Debugf(path, "Failed to read device: %v", err)
+		return devUnset
+	}
+	defer func() {
+		_ = syscall.CloseHandle(h)
+	}()
+	var info syscall.ByHandleFileInformation
+	err = syscall.GetFileInformationByHandle(h, &info)
+	if err != nil {
+		fs.Debugf(path, "Failed to read device: %v", err)
+		return devUnset
+	}
+	return uint64(info.VolumeSerialNumber)
+}
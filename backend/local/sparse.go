@@ -0,0 +1,105 @@
+// Writing sparse files
+
+package local
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// sparseBlockSize is the size of the blocks which are left as holes
+// if they are all zeros
+const sparseBlockSize = 4096
+
+var zeroBlock [sparseBlockSize]byte
+
+// sparseWriter writes to a file leaving holes where whole blocks of
+// the data are zero
+type sparseWriter struct {
+	f      *os.File
+	offset int64 // offset of the next write
+	hole   bool  // set if the last block was skipped
+}
+
+// newSparseWriter makes a sparseWriter writing to f which should be
+// empty
+func newSparseWriter(f *os.File) *sparseWriter {
+	return &sparseWriter{f: f}
+}
+
+// Write p to the file seeking over runs of zero blocks
+func (w *sparseWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		// Find a run of blocks which are all zero or all not
+		zero, size := false, 0
+		for size < len(p) {
+			blockSize := sparseBlockSize - int((w.offset+int64(size))%sparseBlockSize)
+			if size+blockSize > len(p) {
+				blockSize = len(p) - size
+			}
+			blockZero := blockSize == sparseBlockSize && bytes.Equal(p[size:size+blockSize], zeroBlock[:])
+			if size == 0 {
+				zero = blockZero
+			} else if blockZero != zero {
+				break
+			}
+			size += blockSize
+		}
+		if zero {
+			_, err = w.f.Seek(int64(size), io.SeekCurrent)
+		} else {
+			_, err = w.f.Write(p[:size])
+		}
+		if err != nil {
+			return n, err
+		}
+		w.offset += int64(size)
+		w.hole = zero
+		n += size
+		p = p[size:]
+	}
+	return n, nil
+}
+
+// Close the file making sure it is the right size if it ends in a
+// hole
+func (w *sparseWriter) Close() error {
+	if w.hole {
+		err := w.f.Truncate(w.offset)
+		if err != nil {
+			_ = w.f.Close()
+			return err
+		}
+	}
+	return w.f.Close()
+}
+
+// isSparse returns true if the object is a file with holes in
+func (o *Object) isSparse() bool {
+	if o.translatedLink {
+		return false
+	}
+	f, err := os.Open(o.path)
+	if err != nil {
+		return false
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return hasHoles(f, o.Size())
+}
+
+// writeSparse returns true if the object should be written from src
+// as a sparse file
+func (o *Object) writeSparse(src fs.ObjectInfo) bool {
+	if o.fs.opt.NoSparse {
+		return false
+	}
+	if srcObj, ok := fs.UnWrapObjectInfo(src).(*Object); ok {
+		return srcObj.isSparse()
+	}
+	return o.fs.opt.SparseDownloads
+}
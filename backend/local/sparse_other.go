@@ -0,0 +1,12 @@
+// +build !linux,!darwin,!freebsd
+
+package local
+
+import (
+	"os"
+)
+
+// hasHoles can't find holes on this OS
+func hasHoles(f *os.File, size int64) bool {
+	return false
+}
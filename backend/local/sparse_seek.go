@@ -0,0 +1,6 @@
+// +build linux freebsd
+
+package local
+
+// whence for lseek(2) to find the next hole
+const seekHole = 4
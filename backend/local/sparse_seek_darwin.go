@@ -0,0 +1,4 @@
+package local
+
+// whence for lseek(2) to find the next hole
+const seekHole = 3
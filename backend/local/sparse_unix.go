@@ -0,0 +1,19 @@
+// +build linux darwin freebsd
+
+package local
+
+import (
+	"os"
+)
+
+// hasHoles returns true if f of size has holes in it before the end
+func hasHoles(f *os.File, size int64) bool {
+	// There is always a virtual hole at the end of the file so if
+	// the first is before that the file is sparse. Filesystems
+	// which don't support SEEK_HOLE return the end of the file.
+	hole, err := f.Seek(0, seekHole)
+	if err != nil {
+		return false
+	}
+	return hole < size
+}
@@ -0,0 +1,20 @@
+// +build !linux,!darwin
+
+package local
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+)
+
+// readXattrs does nothing as extended attributes aren't supported
+// on this OS
+func readXattrs(path string, m fs.Metadata) error {
+	return nil
+}
+
+// writeXattrs returns an error as extended attributes aren't
+// supported on this OS
+func writeXattrs(path string, xattrs map[string]string) error {
+	return errors.New("extended attributes not supported on this OS")
+}
@@ -0,0 +1,71 @@
+// +build linux darwin
+
+package local
+
+import (
+	"bytes"
+
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/sys/unix"
+)
+
+// readXattrs reads the extended attributes of the file at path into m
+func readXattrs(path string, m fs.Metadata) error {
+	names, err := xattrBuf(func(buf []byte) (int, error) {
+		return unix.Listxattr(path, buf)
+	})
+	if err == unix.ENOTSUP {
+		// file system doesn't support extended attributes
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, name := range bytes.Split(names, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		value, err := xattrBuf(func(buf []byte) (int, error) {
+			return unix.Getxattr(path, string(name), buf)
+		})
+		if err != nil {
+			return err
+		}
+		m[fs.MetadataXattrPrefix+string(name)] = string(value)
+	}
+	return nil
+}
+
+// xattrBuf calls fn with a buffer big enough for the result
+func xattrBuf(fn func(buf []byte) (int, error)) ([]byte, error) {
+	for {
+		// find the size needed
+		size, err := fn(nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size)
+		n, err := fn(buf)
+		if err == unix.ERANGE {
+			// it grew in the meantime
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
+
+// writeXattrs sets the extended attributes in xattrs on the file at
+// path
+func writeXattrs(path string, xattrs map[string]string) error {
+	for name, value := range xattrs {
+		err := unix.Setxattr(path, name, []byte(value), 0)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
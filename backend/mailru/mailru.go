@@ -242,7 +242,7 @@ func shouldRetry(ctx context.Context, res *http.Response, err error, f *Fs, opts
 		reAuthErr := f.reAuthorize(opts, err)
 		return reAuthErr == nil, err // return an original error
 	}
-	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(res, retryErrorCodes), err
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(res, retryErrorCodes), fserrors.RateLimitedHTTP(res, err)
 }
 
 // errorHandler parses a non 2xx error response into an error
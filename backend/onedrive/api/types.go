@@ -259,19 +259,37 @@ type CreateShareLinkRequest struct {
 	Expiry   *time.Time `json:"expirationDateTime,omitempty"` // A String with format of yyyy-MM-ddTHH:mm:ssZ of DateTime indicates the expiration time of the permission.
 }
 
+// SharingLinkType describes a sharing link
+type SharingLinkType struct {
+	Type        string `json:"type"`
+	Scope       string `json:"scope"`
+	WebURL      string `json:"webUrl"`
+	Application struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"displayName"`
+	} `json:"application"`
+}
+
 //CreateShareLinkResponse is the response from CreateShareLinkRequest
 type CreateShareLinkResponse struct {
-	ID    string   `json:"id"`
-	Roles []string `json:"roles"`
-	Link  struct {
-		Type        string `json:"type"`
-		Scope       string `json:"scope"`
-		WebURL      string `json:"webUrl"`
-		Application struct {
-			ID          string `json:"id"`
-			DisplayName string `json:"displayName"`
-		} `json:"application"`
-	} `json:"link"`
+	ID    string          `json:"id"`
+	Roles []string        `json:"roles"`
+	Link  SharingLinkType `json:"link"`
+}
+
+// PermissionsType is a permission on an item - it is a sharing link
+// if Link is set
+type PermissionsType struct {
+	ID          string           `json:"id"`
+	Roles       []string         `json:"roles"`
+	Link        *SharingLinkType `json:"link,omitempty"`
+	Expiry      *time.Time       `json:"expirationDateTime,omitempty"`
+	HasPassword bool             `json:"hasPassword,omitempty"`
+}
+
+// PermissionsResponse is the response to the list permissions request
+type PermissionsResponse struct {
+	Value []PermissionsType `json:"value"`
 }
 
 // AsyncOperationStatus provides information on the status of an asynchronous job progress.
@@ -0,0 +1,311 @@
+// Recursive listings and change notifications using delta queries
+
+package onedrive
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/backend/onedrive/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/walk"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+// errDeltaExpired is returned when a delta link can no longer be used
+var errDeltaExpired = errors.New("delta link has expired")
+
+// parentID returns the normalized ID of the parent of item or "" if
+// it doesn't have one
+func parentID(item *api.Item) string {
+	ref := item.ParentReference
+	if ref == nil || ref.ID == "" {
+		return ""
+	}
+	return ref.DriveID + "#" + ref.ID
+}
+
+// deltaKey returns the ID of item in the drive it is listed in which,
+// unlike GetID, is different for a shared folder and its target
+func deltaKey(item *api.Item) string {
+	ref := item.ParentReference
+	if ref == nil {
+		return strings.ToLower(item.ID)
+	}
+	return strings.ToLower(ref.DriveID + "#" + item.ID)
+}
+
+// newDeltaOpts returns the options to start a delta query with the
+// query parameters given of the directory with normalized ID
+// directoryID
+//
+// OneDrive for Business and SharePoint only support delta queries of
+// the root of a drive so the whole drive is read.
+func (f *Fs) newDeltaOpts(directoryID string, query string) rest.Opts {
+	route := "/delta?" + query
+	if f.driveType == driveTypePersonal {
+		return f.newOptsCall(directoryID, "GET", route)
+	}
+	_, drive, rootURL := f.parseNormalizedID(directoryID)
+	if drive != "" {
+		return rest.Opts{
+			Method:  "GET",
+			RootURL: rootURL,
+			Path:    "/" + drive + "/root" + route,
+		}
+	}
+	return rest.Opts{
+		Method: "GET",
+		Path:   "/root" + route,
+	}
+}
+
+// readDelta reads the pages of the delta query opts calling fn for
+// each item and returns the delta link to read the later changes with
+func (f *Fs) readDelta(ctx context.Context, opts rest.Opts, fn func(item *api.Item) error) (deltaLink string, err error) {
+	for {
+		var result api.ViewDeltaResponse
+		var resp *http.Response
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.srv.CallJSON(ctx, &opts, nil, &result)
+			return shouldRetry(ctx, resp, err)
+		})
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusGone {
+				return "", errDeltaExpired
+			}
+			return "", errors.Wrap(err, "couldn't read delta")
+		}
+		for i := range result.Value {
+			err = fn(&result.Value[i])
+			if err != nil {
+				return "", err
+			}
+		}
+		if result.NextLink == "" {
+			return result.DeltaLink, nil
+		}
+		opts = rest.Opts{
+			Method:  "GET",
+			RootURL: result.NextLink,
+		}
+	}
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// It should call callback for each tranche of entries read.
+// These need not be returned in any particular order.  If
+// callback returns an error then the listing will stop
+// immediately.
+//
+// Don't implement this unless you have a more efficient way
+// of listing recursively than doing a directory traversal.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	directoryID, err := f.dirCache.FindDir(ctx, dir, false)
+	if err != nil {
+		return err
+	}
+	list := walk.NewListRHelper(callback)
+	err = f.listDelta(ctx, dir, directoryID, list.Add)
+	if err != nil {
+		return err
+	}
+	return list.Flush()
+}
+
+// listDelta reads everything under the directory dir with normalized
+// ID directoryID with a delta query passing the entries to add
+func (f *Fs) listDelta(ctx context.Context, dir, directoryID string, add func(fs.DirEntry) error) error {
+	items := make(map[string]*api.Item)
+	_, err := f.readDelta(ctx, f.newDeltaOpts(directoryID, fmt.Sprintf("$top=%d", f.opt.ListChunk)), func(item *api.Item) error {
+		// The same item may appear more than once so keep the last
+		if item.Deleted != nil {
+			delete(items, deltaKey(item))
+		} else {
+			items[deltaKey(item)] = item
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Index the items by parent
+	children := make(map[string][]*api.Item, len(items))
+	for _, item := range items {
+		if parent := parentID(item); parent != "" {
+			parent = strings.ToLower(parent)
+			children[parent] = append(children[parent], item)
+		}
+	}
+
+	var walkDir func(dirID, dirPath string) error
+	walkDir = func(dirID, dirPath string) error {
+		for _, item := range children[dirID] {
+			if !f.opt.ExposeOneNoteFiles && item.GetPackageType() == api.PackageTypeOneNote {
+				fs.Debugf(item.Name, "OneNote file not shown in directory listing")
+				continue
+			}
+			remote := path.Join(dirPath, f.opt.Enc.ToStandardName(item.GetName()))
+			folder := item.GetFolder()
+			if folder == nil {
+				o, err := f.newObjectWithInfo(ctx, remote, item)
+				if err != nil {
+					return err
+				}
+				err = add(o)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			// cache the directory ID for later lookups
+			id := item.GetID()
+			f.dirCache.Put(remote, id)
+			d := fs.NewDir(remote, time.Time(item.GetLastModifiedDateTime())).SetID(id)
+			d.SetItems(folder.ChildCount)
+			err := add(d)
+			if err != nil {
+				return err
+			}
+			if item.IsRemote() {
+				// Shared folders are in another drive so need their own query
+				err = f.listDelta(ctx, remote, id, add)
+			} else {
+				err = walkDir(deltaKey(item), remote)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walkDir(strings.ToLower(directoryID), dir)
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+// If the implementation uses polling, it should adhere to the given interval.
+//
+// Automatically restarts itself in case of unexpected behavior of the remote.
+//
+// Close the returned channel to stop being notified.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	go func() {
+		// get the delta link early so all changes from now on get processed
+		deltaLink, err := f.changeNotifyDeltaLink(ctx)
+		if err != nil {
+			fs.Infof(f, "Failed to get delta link: %s", err)
+		}
+		var ticker *time.Ticker
+		var tickerC <-chan time.Time
+		for {
+			select {
+			case pollInterval, ok := <-pollIntervalChan:
+				if !ok {
+					if ticker != nil {
+						ticker.Stop()
+					}
+					return
+				}
+				if ticker != nil {
+					ticker.Stop()
+					ticker, tickerC = nil, nil
+				}
+				if pollInterval != 0 {
+					ticker = time.NewTicker(pollInterval)
+					tickerC = ticker.C
+				}
+			case <-tickerC:
+				if deltaLink == "" {
+					deltaLink, err = f.changeNotifyDeltaLink(ctx)
+					if err != nil {
+						fs.Infof(f, "Failed to get delta link: %s", err)
+						continue
+					}
+				}
+				fs.Debugf(f, "Checking for changes on remote")
+				newDeltaLink, err := f.changeNotifyRunner(ctx, notifyFunc, deltaLink)
+				if err == errDeltaExpired {
+					// Changes have been lost so start again from now
+					fs.Infof(f, "Change notify delta link expired - restarting")
+					deltaLink = ""
+				} else if err != nil {
+					fs.Infof(f, "Change notify listener failure: %s", err)
+				} else {
+					deltaLink = newDeltaLink
+				}
+			}
+		}
+	}()
+}
+
+// changeNotifyDeltaLink returns a delta link to read the changes
+// made from now on
+func (f *Fs) changeNotifyDeltaLink(ctx context.Context) (deltaLink string, err error) {
+	rootID, err := f.dirCache.RootID(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	return f.readDelta(ctx, f.newDeltaOpts(rootID, "token=latest"), func(item *api.Item) error {
+		return nil
+	})
+}
+
+// changeNotifyRunner reads the changes since deltaLink and calls
+// notifyFunc with their paths, returning the delta link for the next
+// changes
+func (f *Fs) changeNotifyRunner(ctx context.Context, notifyFunc func(string, fs.EntryType), deltaLink string) (newDeltaLink string, err error) {
+	type entryType struct {
+		path      string
+		entryType fs.EntryType
+	}
+	var pathsToClear []entryType
+	opts := rest.Opts{
+		Method:  "GET",
+		RootURL: deltaLink,
+	}
+	newDeltaLink, err = f.readDelta(ctx, opts, func(item *api.Item) error {
+		changeType := fs.EntryObject
+		if item.GetFolder() != nil {
+			changeType = fs.EntryDirectory
+		}
+
+		// find the previous path
+		if path, ok := f.dirCache.GetInv(item.GetID()); ok {
+			pathsToClear = append(pathsToClear, entryType{path: path, entryType: fs.EntryDirectory})
+		}
+
+		// find the new path by translating the parent dir of this object
+		if parentPath, ok := f.dirCache.GetInv(parentID(item)); ok && item.GetName() != "" {
+			newPath := path.Join(parentPath, f.opt.Enc.ToStandardName(item.GetName()))
+			pathsToClear = append(pathsToClear, entryType{path: newPath, entryType: changeType})
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	visitedPaths := make(map[string]struct{})
+	for _, entry := range pathsToClear {
+		if _, ok := visitedPaths[entry.path]; ok {
+			continue
+		}
+		visitedPaths[entry.path] = struct{}{}
+		notifyFunc(entry.path, entry.entryType)
+	}
+	return newDeltaLink, nil
+}
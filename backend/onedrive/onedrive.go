@@ -37,6 +37,7 @@ import (
 	"github.com/rclone/rclone/lib/pacer"
 	"github.com/rclone/rclone/lib/readers"
 	"github.com/rclone/rclone/lib/rest"
+	"github.com/rclone/rclone/lib/version"
 	"golang.org/x/oauth2"
 )
 
@@ -162,6 +163,21 @@ fall back to normal copy (which will be slightly slower).`,
 			Help:     "Size of listing chunk.",
 			Default:  1000,
 			Advanced: true,
+		}, {
+			Name:    "delta",
+			Default: false,
+			Help: `Use delta queries for recursive listings
+
+If this is set then fast list (--fast-list) reads everything under
+the directory with a single delta query instead of listing each
+directory.
+
+OneDrive for Business and SharePoint only support delta queries of the
+root of a drive so this reads the whole drive even when listing a
+small directory in it. It is best used when syncing the whole drive
+or most of it.
+`,
+			Advanced: true,
 		}, {
 			Name:    "no_versions",
 			Default: false,
@@ -530,6 +546,7 @@ type Options struct {
 	ExposeOneNoteFiles      bool                 `config:"expose_onenote_files"`
 	ServerSideAcrossConfigs bool                 `config:"server_side_across_configs"`
 	ListChunk               int64                `config:"list_chunk"`
+	Delta                   bool                 `config:"delta"`
 	NoVersions              bool                 `config:"no_versions"`
 	LinkScope               string               `config:"link_scope"`
 	LinkType                string               `config:"link_type"`
@@ -568,6 +585,12 @@ type Object struct {
 	mimeType      string    // Content-Type of object from server (may not be as uploaded)
 }
 
+// versionObject describes an old version of a file
+type versionObject struct {
+	Object
+	versionID string // ID of the version
+}
+
 // ------------------------------------------------------------
 
 // Name of the remote (as passed into NewFs)
@@ -647,7 +670,7 @@ func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, err
 			return false, fserrors.FatalError(err)
 		}
 	}
-	return retry || fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+	return retry || fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), fserrors.RateLimitedHTTP(resp, err)
 }
 
 // readMetaDataForPathRelativeToID reads the metadata for a path relative to an item that is addressed by its normalized ID.
@@ -817,6 +840,9 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		CanHaveEmptyDirectories: true,
 		ServerSideAcrossConfigs: opt.ServerSideAcrossConfigs,
 	}).Fill(ctx, f)
+	if !opt.Delta {
+		f.features.ListR = nil
+	}
 	f.srv.SetErrorHandler(errorHandler)
 
 	// Renew the token in the background
@@ -1055,6 +1081,58 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 	return entries, nil
 }
 
+// ListVersions lists the old versions of the files in dir with the
+// time they were modified added to their names.
+func (f *Fs) ListVersions(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	directoryID, err := f.dirCache.FindDir(ctx, dir, false)
+	if err != nil {
+		return nil, err
+	}
+	var objects []*Object
+	var iErr error
+	_, err = f.listAll(ctx, directoryID, false, true, func(info *api.Item) bool {
+		if info.GetPackageType() == api.PackageTypeOneNote {
+			return false
+		}
+		o, err := f.newObjectWithInfo(ctx, path.Join(dir, info.GetName()), info)
+		if err != nil {
+			iErr = err
+			return true
+		}
+		objects = append(objects, o.(*Object))
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if iErr != nil {
+		return nil, iErr
+	}
+	for _, o := range objects {
+		versions, err := o.listVersions(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list versions of %q", o.remote)
+		}
+		if len(versions) < 2 {
+			continue
+		}
+		for _, v := range versions[1:] {
+			vo := &versionObject{
+				Object:    *o,
+				versionID: v.ID,
+			}
+			vo.remote = version.Add(o.remote, v.LastModifiedDateTime)
+			vo.size = int64(v.Size)
+			vo.modTime = v.LastModifiedDateTime
+			// the hashes of old versions aren't known
+			vo.sha1 = ""
+			vo.quickxorhash = ""
+			entries = append(entries, vo)
+		}
+	}
+	return entries, nil
+}
+
 // Creates from the parameters passed in a half finished Object which
 // must have setMetaData called on it
 //
@@ -1476,6 +1554,89 @@ func (f *Fs) Hashes() hash.Set {
 
 // PublicLink returns a link for downloading without account.
 func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (link string, err error) {
+	if unlink {
+		return "", f.unlink(ctx, remote)
+	}
+	return f.publicLink(ctx, remote, expire, f.opt.LinkPassword)
+}
+
+// PublicLinkWithPassword makes a link to the given file or folder
+// which needs password to use it. This is OneDrive Personal only.
+func (f *Fs) PublicLinkWithPassword(ctx context.Context, remote string, expire fs.Duration, password string) (link string, err error) {
+	return f.publicLink(ctx, remote, expire, password)
+}
+
+// listLinkPermissions returns the ID of remote and the permissions
+// on it which are sharing links
+func (f *Fs) listLinkPermissions(ctx context.Context, remote string) (id string, perms []api.PermissionsType, err error) {
+	info, _, err := f.readMetaDataForPath(ctx, f.rootPath(remote))
+	if err != nil {
+		return "", nil, err
+	}
+	opts := f.newOptsCall(info.GetID(), "GET", "/permissions")
+	var resp *http.Response
+	var result api.PermissionsResponse
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.srv.CallJSON(ctx, &opts, nil, &result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to list permissions")
+	}
+	for _, perm := range result.Value {
+		if perm.Link != nil {
+			perms = append(perms, perm)
+		}
+	}
+	return info.GetID(), perms, nil
+}
+
+// unlink removes the sharing links from remote
+func (f *Fs) unlink(ctx context.Context, remote string) error {
+	id, perms, err := f.listLinkPermissions(ctx, remote)
+	if err != nil {
+		return err
+	}
+	if len(perms) == 0 {
+		fs.Debugf(f, "%q has no sharing links to remove", remote)
+	}
+	for _, perm := range perms {
+		opts := f.newOptsCall(id, "DELETE", "/permissions/"+perm.ID)
+		opts.NoResponse = true
+		var resp *http.Response
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.srv.Call(ctx, &opts)
+			return shouldRetry(ctx, resp, err)
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to remove sharing link")
+		}
+		fs.Debugf(f, "removed sharing link %s from %q", perm.Link.WebURL, remote)
+	}
+	return nil
+}
+
+// ListPublicLinks returns the sharing links to remote.
+func (f *Fs) ListPublicLinks(ctx context.Context, remote string) (links []fs.PublicLinkInfo, err error) {
+	_, perms, err := f.listLinkPermissions(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	links = []fs.PublicLinkInfo{}
+	for _, perm := range perms {
+		links = append(links, fs.PublicLinkInfo{
+			Path:     remote,
+			URL:      perm.Link.WebURL,
+			Expires:  perm.Expiry,
+			Password: perm.HasPassword,
+		})
+	}
+	return links, nil
+}
+
+// publicLink makes a sharing link to remote which needs password to
+// use it if it is set
+func (f *Fs) publicLink(ctx context.Context, remote string, expire fs.Duration, password string) (link string, err error) {
 	info, _, err := f.readMetaDataForPath(ctx, f.rootPath(remote))
 	if err != nil {
 		return "", err
@@ -1485,7 +1646,7 @@ func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration,
 	share := api.CreateShareLinkRequest{
 		Type:     f.opt.LinkType,
 		Scope:    f.opt.LinkScope,
-		Password: f.opt.LinkPassword,
+		Password: password,
 	}
 
 	if expire < fs.DurationOff {
@@ -1609,21 +1770,30 @@ func (f *Fs) CleanUp(ctx context.Context) error {
 	return err
 }
 
-// Finds and removes any old versions for o
-func (o *Object) deleteVersions(ctx context.Context) error {
+// listVersions lists the versions of o, the current one first
+func (o *Object) listVersions(ctx context.Context) ([]api.Version, error) {
 	opts := o.fs.newOptsCall(o.id, "GET", "/versions")
 	var versions api.VersionsResponse
 	err := o.fs.pacer.Call(func() (bool, error) {
 		resp, err := o.fs.srv.CallJSON(ctx, &opts, nil, &versions)
 		return shouldRetry(ctx, resp, err)
 	})
+	if err != nil {
+		return nil, err
+	}
+	return versions.Versions, nil
+}
+
+// Finds and removes any old versions for o
+func (o *Object) deleteVersions(ctx context.Context) error {
+	versions, err := o.listVersions(ctx)
 	if err != nil {
 		return err
 	}
-	if len(versions.Versions) < 2 {
+	if len(versions) < 2 {
 		return nil
 	}
-	for _, version := range versions.Versions[1:] {
+	for _, version := range versions[1:] {
 		err = o.deleteVersion(ctx, version.ID)
 		if err != nil {
 			return err
@@ -2092,6 +2262,40 @@ func (o *Object) Remove(ctx context.Context) error {
 	return o.fs.deleteObject(ctx, o.id)
 }
 
+// errNotWithVersion is returned when trying to change an old version
+var errNotWithVersion = errors.New("can't modify or overwrite an old version")
+
+// Open the old version for read
+func (o *versionObject) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	fs.FixRangeOption(options, o.size)
+	var resp *http.Response
+	opts := o.fs.newOptsCall(o.id, "GET", "/versions/"+o.versionID+"/content")
+	opts.Options = options
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// SetModTime can't be done on old versions
+func (o *versionObject) SetModTime(ctx context.Context, modTime time.Time) error {
+	return errNotWithVersion
+}
+
+// Update can't be done on old versions
+func (o *versionObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return errNotWithVersion
+}
+
+// Remove deletes the old version
+func (o *versionObject) Remove(ctx context.Context) error {
+	return o.deleteVersion(ctx, o.versionID)
+}
+
 // MimeType of an Object if known, "" otherwise
 func (o *Object) MimeType(ctx context.Context) string {
 	return o.mimeType
@@ -2280,16 +2484,22 @@ func withTrailingColon(remotePath string) string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs              = (*Fs)(nil)
-	_ fs.Purger          = (*Fs)(nil)
-	_ fs.Copier          = (*Fs)(nil)
-	_ fs.Mover           = (*Fs)(nil)
-	_ fs.DirMover        = (*Fs)(nil)
-	_ fs.DirCacheFlusher = (*Fs)(nil)
-	_ fs.Abouter         = (*Fs)(nil)
-	_ fs.PublicLinker    = (*Fs)(nil)
-	_ fs.CleanUpper      = (*Fs)(nil)
-	_ fs.Object          = (*Object)(nil)
-	_ fs.MimeTyper       = &Object{}
-	_ fs.IDer            = &Object{}
+	_ fs.Fs                   = (*Fs)(nil)
+	_ fs.Purger               = (*Fs)(nil)
+	_ fs.Copier               = (*Fs)(nil)
+	_ fs.Mover                = (*Fs)(nil)
+	_ fs.DirMover             = (*Fs)(nil)
+	_ fs.DirCacheFlusher      = (*Fs)(nil)
+	_ fs.Abouter              = (*Fs)(nil)
+	_ fs.PublicLinker         = (*Fs)(nil)
+	_ fs.PublicLinkPassworder = (*Fs)(nil)
+	_ fs.PublicLinkLister     = (*Fs)(nil)
+	_ fs.CleanUpper           = (*Fs)(nil)
+	_ fs.VersionLister        = (*Fs)(nil)
+	_ fs.ListRer              = (*Fs)(nil)
+	_ fs.ChangeNotifier       = (*Fs)(nil)
+	_ fs.Object               = (*Object)(nil)
+	_ fs.MimeTyper            = &Object{}
+	_ fs.IDer                 = &Object{}
+	_ fs.Object               = &versionObject{}
 )
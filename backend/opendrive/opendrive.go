@@ -663,7 +663,7 @@ func (f *Fs) shouldRetry(ctx context.Context, resp *http.Response, err error) (b
 	if fserrors.ContextError(ctx, &err) {
 		return false, err
 	}
-	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), fserrors.RateLimitedHTTP(resp, err)
 }
 
 // DirCacher methods
@@ -231,7 +231,7 @@ func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, err
 		doRetry = true
 		fs.Debugf(nil, "Should retry: %v", err)
 	}
-	return doRetry || fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+	return doRetry || fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), fserrors.RateLimitedHTTP(resp, err)
 }
 
 // readMetaDataForPath reads the metadata from the path
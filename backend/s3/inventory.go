@@ -0,0 +1,237 @@
+// Recursive listings from S3 Inventory reports
+
+package s3
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/bucket"
+)
+
+// inventoryManifest is the manifest.json of an S3 Inventory report
+//
+// See: https://docs.aws.amazon.com/AmazonS3/latest/userguide/storage-inventory-location.html
+type inventoryManifest struct {
+	SourceBucket      string `json:"sourceBucket"`
+	DestinationBucket string `json:"destinationBucket"`
+	FileFormat        string `json:"fileFormat"`
+	FileSchema        string `json:"fileSchema"`
+	Files             []struct {
+		Key string `json:"key"`
+	} `json:"files"`
+}
+
+// inventorySchema holds the column of each field of the inventory
+// which is -1 if it isn't present
+type inventorySchema struct {
+	bucket, key, size, lastModified, eTag, storageClass, isLatest, isDeleteMarker int
+}
+
+// parseInventorySchema parses the fileSchema of the manifest
+func parseInventorySchema(fileSchema string) (schema inventorySchema, err error) {
+	schema = inventorySchema{-1, -1, -1, -1, -1, -1, -1, -1}
+	for i, field := range strings.Split(fileSchema, ",") {
+		switch strings.TrimSpace(field) {
+		case "Bucket":
+			schema.bucket = i
+		case "Key":
+			schema.key = i
+		case "Size":
+			schema.size = i
+		case "LastModifiedDate":
+			schema.lastModified = i
+		case "ETag":
+			schema.eTag = i
+		case "StorageClass":
+			schema.storageClass = i
+		case "IsLatest":
+			schema.isLatest = i
+		case "IsDeleteMarker":
+			schema.isDeleteMarker = i
+		}
+	}
+	if schema.bucket < 0 || schema.key < 0 || schema.size < 0 || schema.lastModified < 0 {
+		return schema, errors.Errorf("inventory must include Bucket, Key, Size and LastModifiedDate but has %q", fileSchema)
+	}
+	return schema, nil
+}
+
+// inventoryItem is an object read from an inventory file
+type inventoryItem struct {
+	bucket string
+	key    string
+	object *s3.Object
+}
+
+// readInventoryCSV reads the CSV inventory file in calling fn for
+// each current object in it
+func readInventoryCSV(in io.Reader, schema inventorySchema, fn func(item *inventoryItem) error) error {
+	r := csv.NewReader(in)
+	r.ReuseRecord = true
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read inventory")
+		}
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return record[i]
+		}
+		if field(schema.isLatest) == "false" || field(schema.isDeleteMarker) == "true" {
+			continue
+		}
+		// Keys are URL encoded in CSV inventories
+		key, err := url.QueryUnescape(field(schema.key))
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode key %q in inventory", field(schema.key))
+		}
+		size, err := strconv.ParseInt(field(schema.size), 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "bad size for %q in inventory", key)
+		}
+		lastModified, err := time.Parse(time.RFC3339Nano, field(schema.lastModified))
+		if err != nil {
+			return errors.Wrapf(err, "bad last modified date for %q in inventory", key)
+		}
+		object := &s3.Object{
+			Key:          aws.String(key),
+			Size:         aws.Int64(size),
+			LastModified: aws.Time(lastModified),
+		}
+		if eTag := field(schema.eTag); eTag != "" {
+			object.ETag = aws.String(`"` + eTag + `"`)
+		}
+		if storageClass := field(schema.storageClass); storageClass != "" {
+			object.StorageClass = aws.String(storageClass)
+		}
+		err = fn(&inventoryItem{
+			bucket: field(schema.bucket),
+			key:    key,
+			object: object,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// getObject opens key in bucket for reading, decompressing it if it
+// ends in .gz
+func (f *Fs) getObject(ctx context.Context, bucketName, key string) (in io.ReadCloser, err error) {
+	req := s3.GetObjectInput{
+		Bucket: &bucketName,
+		Key:    &key,
+	}
+	if f.opt.RequesterPays {
+		req.RequestPayer = aws.String(s3.RequestPayerRequester)
+	}
+	var resp *s3.GetObjectOutput
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.c.GetObjectWithContext(ctx, &req)
+		return f.shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %q", bucketName+"/"+key)
+	}
+	if !strings.HasSuffix(key, ".gz") {
+		return resp.Body, nil
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, errors.Wrapf(err, "failed to decompress %q", bucketName+"/"+key)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, resp.Body}, nil
+}
+
+// readInventoryManifest reads the manifest set with the inventory
+// option
+func (f *Fs) readInventoryManifest(ctx context.Context) (manifest *inventoryManifest, err error) {
+	manifestBucket, manifestPath := bucket.Split(strings.TrimPrefix(f.opt.Inventory, "s3://"))
+	if manifestBucket == "" || manifestPath == "" {
+		return nil, errors.Errorf("inventory %q must be bucket/path/to/manifest.json", f.opt.Inventory)
+	}
+	in, err := f.getObject(ctx, manifestBucket, manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	manifest = new(inventoryManifest)
+	err = json.NewDecoder(in).Decode(manifest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode inventory manifest")
+	}
+	return manifest, nil
+}
+
+// listInventory lists the objects in directory recursively from the
+// inventory calling fn for each one as list does.
+//
+// It returns false if the inventory isn't for bucket and the listing
+// should be done normally.
+func (f *Fs) listInventory(ctx context.Context, bucketName, directory, prefix string, addBucket bool, fn listFn) (ok bool, err error) {
+	manifest, err := f.readInventoryManifest(ctx)
+	if err != nil {
+		return false, err
+	}
+	if manifest.SourceBucket != bucketName {
+		fs.Debugf(f, "Inventory is for bucket %q not %q - listing normally", manifest.SourceBucket, bucketName)
+		return false, nil
+	}
+	if !strings.EqualFold(manifest.FileFormat, "CSV") {
+		return false, errors.Errorf("inventory format %q is not supported - only CSV is", manifest.FileFormat)
+	}
+	schema, err := parseInventorySchema(manifest.FileSchema)
+	if err != nil {
+		return false, err
+	}
+	// The destination bucket is an ARN like arn:aws:s3:::bucket
+	inventoryBucket := manifest.DestinationBucket
+	if i := strings.LastIndex(inventoryBucket, ":"); i >= 0 {
+		inventoryBucket = inventoryBucket[i+1:]
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+	if directory != "" {
+		directory += "/"
+	}
+	fs.Debugf(f, "Listing %q from inventory of %d files", bucketName+"/"+directory, len(manifest.Files))
+	for _, file := range manifest.Files {
+		in, err := f.getObject(ctx, inventoryBucket, file.Key)
+		if err != nil {
+			return true, err
+		}
+		err = readInventoryCSV(in, schema, func(item *inventoryItem) error {
+			if item.bucket != bucketName || !strings.HasPrefix(item.key, directory) {
+				return nil
+			}
+			return f.listObject(bucketName, directory, prefix, addBucket, true, item.key, item.object, fn)
+		})
+		fs.CheckClose(in, &err)
+		if err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
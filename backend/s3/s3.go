@@ -5,7 +5,6 @@ import (
 	"bytes"
 	"context"
 	"crypto/md5"
-	"crypto/tls"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/xml"
@@ -51,6 +50,7 @@ import (
 	"github.com/rclone/rclone/lib/readers"
 	"github.com/rclone/rclone/lib/rest"
 	"github.com/rclone/rclone/lib/structs"
+	"github.com/rclone/rclone/lib/version"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -926,6 +926,19 @@ isn't set then "acl" is used instead.`,
 				Value: "",
 				Help:  "None",
 			}},
+		}, {
+			Name: "sse_customer_key_base64",
+			Help: `If using SSE-C you may provide the secret encryption key encoded in base64 instead of sse_customer_key.
+
+This allows keys containing arbitrary bytes, such as 32 random bytes
+for AES256, to be used. Only one of this and sse_customer_key may be
+set.`,
+			Provider: "AWS,Ceph,Minio",
+			Advanced: true,
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "None",
+			}},
 		}, {
 			Name: "sse_customer_key_md5",
 			Help: `If using SSE-C you may provide the secret encryption key MD5 checksum (optional).
@@ -1232,6 +1245,38 @@ very small even with this flag.
 			Help:     `If set, don't HEAD objects`,
 			Default:  false,
 			Advanced: true,
+		}, {
+			Name: "directory_markers",
+			Help: `Create and read directory markers.
+
+If set, Mkdir uploads a zero length object whose name is the directory
+with a trailing "/" and Rmdir deletes it. These markers are what the
+AWS console, Hadoop s3a and some other tools use to show empty
+directories.
+
+In listings zero length objects ending in "/" are shown as directories
+rather than being ignored, so empty directories made by rclone or
+these tools are preserved and can be synced.`,
+			Default:  false,
+			Advanced: true,
+		}, {
+			Name: "inventory",
+			Help: `Path of an S3 Inventory manifest to use for recursive listings.
+
+Set this to the manifest.json of a CSV S3 Inventory report of the
+bucket, e.g. "inventory-bucket/source-bucket/config-id/2021-01-01T00-00Z/manifest.json",
+and rclone will read the objects from the inventory files instead of
+listing the bucket when doing recursive listings, such as when using
+--fast-list. This is much quicker for buckets with many millions of
+objects.
+
+The inventory is only as up to date as the report, so objects changed
+since it was made will be missed or out of date. Only use this when
+the bucket isn't being modified or this doesn't matter.
+
+Listings which aren't recursive and listings of other buckets are
+done as normal.`,
+			Advanced: true,
 		}, {
 			Name:     config.ConfigEncoding,
 			Help:     config.ConfigEncodingHelp,
@@ -1274,6 +1319,28 @@ disabled here.  When the issue is solved this flag will be removed.
 See: https://github.com/rclone/rclone/issues/4673, https://github.com/rclone/rclone/issues/3631
 
 `,
+		}, {
+			Name:     "ca_cert",
+			Advanced: true,
+			Help: `CA certificate file to verify the endpoint with
+
+Set this to the PEM encoded certificate of the CA which signed the
+certificate of a private endpoint. It is used for this remote instead
+of the system CA certificates and --ca-cert.`,
+		}, {
+			Name:     "client_cert",
+			Advanced: true,
+			Help: `Client SSL certificate (PEM) for mutual TLS auth
+
+If this is set then client_key must be set too. It is used for this
+remote instead of --client-cert.`,
+		}, {
+			Name:     "client_key",
+			Advanced: true,
+			Help: `Client SSL private key (PEM) for mutual TLS auth
+
+If this is set then client_cert must be set too. It is used for this
+remote instead of --client-key.`,
 		},
 		}})
 }
@@ -1282,6 +1349,9 @@ See: https://github.com/rclone/rclone/issues/4673, https://github.com/rclone/rcl
 const (
 	metaMtime   = "Mtime"     // the meta key to store mtime in - e.g. X-Amz-Meta-Mtime
 	metaMD5Hash = "Md5chksum" // the meta key to store md5hash in
+	metaMode    = "Mode"      // the meta key to store the mode in
+	metaUID     = "Uid"       // the meta key to store the uid in
+	metaGID     = "Gid"       // the meta key to store the gid in
 	// The maximum size of object we can COPY - this should be 5 GiB but is < 5 GB for b2 compatibility
 	// See https://forum.rclone.org/t/copying-files-within-a-b2-bucket/16680/76
 	maxSizeForCopy      = 4768 * 1024 * 1024
@@ -1312,6 +1382,7 @@ type Options struct {
 	SSEKMSKeyID           string               `config:"sse_kms_key_id"`
 	SSECustomerAlgorithm  string               `config:"sse_customer_algorithm"`
 	SSECustomerKey        string               `config:"sse_customer_key"`
+	SSECustomerKeyBase64  string               `config:"sse_customer_key_base64"`
 	SSECustomerKeyMD5     string               `config:"sse_customer_key_md5"`
 	StorageClass          string               `config:"storage_class"`
 	UploadCutoff          fs.SizeSuffix        `config:"upload_cutoff"`
@@ -1331,10 +1402,15 @@ type Options struct {
 	NoCheckBucket         bool                 `config:"no_check_bucket"`
 	NoHead                bool                 `config:"no_head"`
 	NoHeadObject          bool                 `config:"no_head_object"`
+	DirectoryMarkers      bool                 `config:"directory_markers"`
+	Inventory             string               `config:"inventory"`
 	Enc                   encoder.MultiEncoder `config:"encoding"`
 	MemoryPoolFlushTime   fs.Duration          `config:"memory_pool_flush_time"`
 	MemoryPoolUseMmap     bool                 `config:"memory_pool_use_mmap"`
 	DisableHTTP2          bool                 `config:"disable_http2"`
+	CaCert                string               `config:"ca_cert"`
+	ClientCert            string               `config:"client_cert"`
+	ClientKey             string               `config:"client_key"`
 }
 
 // Fs represents a remote s3 server
@@ -1370,6 +1446,8 @@ type Object struct {
 	meta         map[string]*string // The object metadata if known - may be nil
 	mimeType     string             // MimeType of object - may be ""
 	storageClass string             // e.g. GLACIER
+	versionID    *string            // ID of the old version this is or nil for the current one
+	snapshot     bool               // set if this is an old version listed under its usual name
 }
 
 // ------------------------------------------------------------
@@ -1436,7 +1514,7 @@ func (f *Fs) shouldRetry(ctx context.Context, err error) (bool, error) {
 			}
 			for _, e := range retryErrorCodes {
 				if reqErr.StatusCode() == e {
-					return true, err
+					return true, fserrors.RateLimitedStatus(e, err)
 				}
 			}
 		}
@@ -1460,20 +1538,28 @@ func (f *Fs) split(rootRelativePath string) (bucketName, bucketPath string) {
 
 // split returns bucket and bucketPath from the object
 func (o *Object) split() (bucket, bucketPath string) {
-	return o.fs.split(o.remote)
+	remote := o.remote
+	if o.versionID != nil && !o.snapshot {
+		_, remote = version.Remove(remote)
+	}
+	return o.fs.split(remote)
 }
 
 // getClient makes an http client according to the options
-func getClient(ctx context.Context, opt *Options) *http.Client {
+func getClient(ctx context.Context, opt *Options) (*http.Client, error) {
 	// TODO: Do we need cookies too?
-	t := fshttp.NewTransportCustom(ctx, func(t *http.Transport) {
-		if opt.DisableHTTP2 {
-			t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
-		}
-	})
+	t, err := fshttp.NewTransportWithOptions(ctx, fshttp.TransportOptions{
+		CaCert:       opt.CaCert,
+		ClientCert:   opt.ClientCert,
+		ClientKey:    opt.ClientKey,
+		DisableHTTP2: opt.DisableHTTP2,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
 	return &http.Client{
 		Transport: t,
-	}
+	}, nil
 }
 
 // s3Connection makes a connection to s3
@@ -1623,6 +1709,41 @@ func (f *Fs) setUploadCutoff(cs fs.SizeSuffix) (old fs.SizeSuffix, err error) {
 	return
 }
 
+// checkSSE checks the server-side encryption options are consistent,
+// decoding sse_customer_key_base64 into SSECustomerKey and filling in
+// SSECustomerKeyMD5 if not supplied.
+func checkSSE(opt *Options) error {
+	if opt.SSECustomerKeyBase64 != "" {
+		if opt.SSECustomerKey != "" {
+			return errors.New("sse_customer_key and sse_customer_key_base64 can't both be set")
+		}
+		key, err := base64.StdEncoding.DecodeString(opt.SSECustomerKeyBase64)
+		if err != nil {
+			return errors.Wrap(err, "failed to decode sse_customer_key_base64")
+		}
+		opt.SSECustomerKey = string(key)
+	}
+	if opt.SSECustomerKey != "" {
+		if opt.SSECustomerAlgorithm == "" {
+			return errors.New("sse_customer_algorithm must be set when using sse_customer_key")
+		}
+		if opt.SSECustomerKeyMD5 == "" {
+			// calculate CustomerKeyMD5 if not supplied
+			md5sumBinary := md5.Sum([]byte(opt.SSECustomerKey))
+			opt.SSECustomerKeyMD5 = base64.StdEncoding.EncodeToString(md5sumBinary[:])
+		}
+	} else if opt.SSECustomerAlgorithm != "" {
+		return errors.New("sse_customer_key must be set when using sse_customer_algorithm")
+	}
+	if opt.SSECustomerAlgorithm != "" && opt.ServerSideEncryption != "" {
+		return errors.New("server_side_encryption can't be used with sse_customer_algorithm")
+	}
+	if opt.SSEKMSKeyID != "" && opt.ServerSideEncryption != "aws:kms" {
+		return errors.New("server_side_encryption must be aws:kms when using sse_kms_key_id")
+	}
+	return nil
+}
+
 // setRoot changes the root of the Fs
 func (f *Fs) setRoot(root string) {
 	f.root = parsePath(root)
@@ -1651,12 +1772,14 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	if opt.BucketACL == "" {
 		opt.BucketACL = opt.ACL
 	}
-	if opt.SSECustomerKey != "" && opt.SSECustomerKeyMD5 == "" {
-		// calculate CustomerKeyMD5 if not supplied
-		md5sumBinary := md5.Sum([]byte(opt.SSECustomerKey))
-		opt.SSECustomerKeyMD5 = base64.StdEncoding.EncodeToString(md5sumBinary[:])
+	err = checkSSE(opt)
+	if err != nil {
+		return nil, errors.Wrap(err, "s3")
+	}
+	srv, err := getClient(ctx, opt)
+	if err != nil {
+		return nil, errors.Wrap(err, "s3")
 	}
-	srv := getClient(ctx, opt)
 	c, ses, err := s3Connection(ctx, opt, srv)
 	if err != nil {
 		return nil, err
@@ -1699,6 +1822,8 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		SetTier:           true,
 		GetTier:           true,
 		SlowModTime:       true,
+
+		CanHaveEmptyDirectories: opt.DirectoryMarkers,
 	}).Fill(ctx, f)
 	if f.rootBucket != "" && f.rootDirectory != "" && !opt.NoHeadObject && !strings.HasSuffix(root, "/") {
 		// Check to see if the (bucket,directory) is actually an existing file
@@ -1928,21 +2053,7 @@ func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBuck
 					continue
 				}
 			}
-			remote = f.opt.Enc.ToStandardPath(remote)
-			if !strings.HasPrefix(remote, prefix) {
-				fs.Logf(f, "Odd name received %q", remote)
-				continue
-			}
-			remote = remote[len(prefix):]
-			isDirectory := remote == "" || strings.HasSuffix(remote, "/")
-			if addBucket {
-				remote = path.Join(bucket, remote)
-			}
-			// is this a directory marker?
-			if isDirectory && object.Size != nil && *object.Size == 0 {
-				continue // skip directory marker
-			}
-			err = fn(remote, object, false)
+			err = f.listObject(bucket, directory, prefix, addBucket, recurse, remote, object, fn)
 			if err != nil {
 				return err
 			}
@@ -1969,6 +2080,36 @@ func (f *Fs) list(ctx context.Context, bucket, directory, prefix string, addBuck
 	return nil
 }
 
+// listObject calls fn for the object called remote, the decoded key,
+// found when listing directory.
+//
+// prefix and directory should have a trailing "/" if not empty.
+func (f *Fs) listObject(bucket, directory, prefix string, addBucket bool, recurse bool, remote string, object *s3.Object, fn listFn) error {
+	isListedDir := remote == directory
+	remote = f.opt.Enc.ToStandardPath(remote)
+	if !strings.HasPrefix(remote, prefix) {
+		fs.Logf(f, "Odd name received %q", remote)
+		return nil
+	}
+	remote = remote[len(prefix):]
+	isDirectory := remote == "" || strings.HasSuffix(remote, "/")
+	if addBucket {
+		remote = path.Join(bucket, remote)
+	}
+	// is this a directory marker?
+	if isDirectory && object.Size != nil && *object.Size == 0 {
+		// When recursing there are no common prefixes so
+		// return the marker as a directory unless it is
+		// the directory being listed
+		if f.opt.DirectoryMarkers && recurse && !isListedDir {
+			remote = strings.TrimSuffix(remote, "/")
+			return fn(remote, &s3.Object{Key: &remote}, true)
+		}
+		return nil // skip directory marker
+	}
+	return fn(remote, object, false)
+}
+
 // Convert a list item into a DirEntry
 func (f *Fs) itemToDirEntry(ctx context.Context, remote string, object *s3.Object, isDirectory bool) (fs.DirEntry, error) {
 	if isDirectory {
@@ -2067,13 +2208,20 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 	bucket, directory := f.split(dir)
 	list := walk.NewListRHelper(callback)
 	listR := func(bucket, directory, prefix string, addBucket bool) error {
-		return f.list(ctx, bucket, directory, prefix, addBucket, true, func(remote string, object *s3.Object, isDirectory bool) error {
+		fn := func(remote string, object *s3.Object, isDirectory bool) error {
 			entry, err := f.itemToDirEntry(ctx, remote, object, isDirectory)
 			if err != nil {
 				return err
 			}
 			return list.Add(entry)
-		})
+		}
+		if f.opt.Inventory != "" {
+			ok, err := f.listInventory(ctx, bucket, directory, prefix, addBucket, fn)
+			if ok || err != nil {
+				return err
+			}
+		}
+		return f.list(ctx, bucket, directory, prefix, addBucket, true, fn)
 	}
 	if bucket == "" {
 		entries, err := f.listBuckets(ctx)
@@ -2141,10 +2289,79 @@ func (f *Fs) bucketExists(ctx context.Context, bucket string) (bool, error) {
 	return false, err
 }
 
-// Mkdir creates the bucket if it doesn't exist
+// Mkdir creates the bucket if it doesn't exist and the directory
+// marker if using directory markers
 func (f *Fs) Mkdir(ctx context.Context, dir string) error {
-	bucket, _ := f.split(dir)
-	return f.makeBucket(ctx, bucket)
+	bucket, directory := f.split(dir)
+	err := f.makeBucket(ctx, bucket)
+	if err != nil || !f.opt.DirectoryMarkers || directory == "" {
+		return err
+	}
+	return f.putDirectoryMarker(ctx, bucket, directory)
+}
+
+// putDirectoryMarker uploads the zero length object marking directory
+func (f *Fs) putDirectoryMarker(ctx context.Context, bucket, directory string) error {
+	key := directory + "/"
+	req := s3.PutObjectInput{
+		Bucket:      &bucket,
+		ACL:         &f.opt.ACL,
+		Key:         &key,
+		Body:        bytes.NewReader(nil),
+		ContentType: aws.String("application/x-directory"),
+	}
+	if f.opt.RequesterPays {
+		req.RequestPayer = aws.String(s3.RequestPayerRequester)
+	}
+	if f.opt.ServerSideEncryption != "" {
+		req.ServerSideEncryption = &f.opt.ServerSideEncryption
+	}
+	if f.opt.SSECustomerAlgorithm != "" {
+		req.SSECustomerAlgorithm = &f.opt.SSECustomerAlgorithm
+		req.SSECustomerKey = &f.opt.SSECustomerKey
+		req.SSECustomerKeyMD5 = &f.opt.SSECustomerKeyMD5
+	}
+	if f.opt.SSEKMSKeyID != "" {
+		req.SSEKMSKeyId = &f.opt.SSEKMSKeyID
+	}
+	err := f.pacer.Call(func() (bool, error) {
+		_, err := f.c.PutObjectWithContext(ctx, &req)
+		return f.shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create directory marker")
+	}
+	fs.Debugf(f, "Created directory marker %q", key)
+	return nil
+}
+
+// removeDirectoryMarker deletes the marker of directory returning an
+// error if the directory isn't empty
+func (f *Fs) removeDirectoryMarker(ctx context.Context, dir, bucket, directory string) error {
+	entries, err := f.listDir(ctx, bucket, directory, f.rootDirectory, f.rootBucket == "")
+	if err != nil {
+		return err
+	}
+	if len(entries) != 0 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	key := directory + "/"
+	req := s3.DeleteObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	}
+	if f.opt.RequesterPays {
+		req.RequestPayer = aws.String(s3.RequestPayerRequester)
+	}
+	err = f.pacer.Call(func() (bool, error) {
+		_, err := f.c.DeleteObjectWithContext(ctx, &req)
+		return f.shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to remove directory marker")
+	}
+	fs.Debugf(f, "Removed directory marker for %q", dir)
+	return nil
 }
 
 // makeBucket creates the bucket if it doesn't exist
@@ -2180,11 +2397,15 @@ func (f *Fs) makeBucket(ctx context.Context, bucket string) error {
 	})
 }
 
-// Rmdir deletes the bucket if the fs is at the root
+// Rmdir deletes the bucket if the fs is at the root or the directory
+// marker if using directory markers
 //
 // Returns an error if it isn't empty
 func (f *Fs) Rmdir(ctx context.Context, dir string) error {
 	bucket, directory := f.split(dir)
+	if bucket != "" && directory != "" && f.opt.DirectoryMarkers {
+		return f.removeDirectoryMarker(ctx, dir, bucket, directory)
+	}
 	if bucket == "" || directory != "" {
 		return nil
 	}
@@ -2223,6 +2444,9 @@ func (f *Fs) copy(ctx context.Context, req *s3.CopyObjectInput, dstBucket, dstPa
 	req.ACL = &f.opt.ACL
 	req.Key = &dstPath
 	source := pathEscape(path.Join(srcBucket, srcPath))
+	if src.versionID != nil {
+		source += "?versionId=" + url.QueryEscape(*src.versionID)
+	}
 	req.CopySource = &source
 	if f.opt.RequesterPays {
 		req.RequestPayer = aws.String(s3.RequestPayerRequester)
@@ -2258,6 +2482,184 @@ func (f *Fs) copy(ctx context.Context, req *s3.CopyObjectInput, dstBucket, dstPa
 	})
 }
 
+// concatPart is a part of a concatenation made from the bytes start
+// to end inclusive of source src
+type concatPart struct {
+	src        int
+	start, end int64
+}
+
+// concatParts plans the parts to copy to join sources of sizes with
+// parts of at most partSize.
+//
+// Large sources are split into equal parts. Every part except the
+// last must be at least minChunkSize. Empty sources are skipped.
+func concatParts(sizes []int64, partSize int64) (parts []concatPart, err error) {
+	last := len(sizes) - 1
+	for last >= 0 && sizes[last] == 0 {
+		last--
+	}
+	for src, size := range sizes {
+		if size == 0 {
+			continue
+		}
+		numParts := (size + partSize - 1) / partSize
+		piece := size / numParts
+		if src != last && piece < int64(minChunkSize) {
+			return nil, errors.Errorf("source %d is %v which is too small - all but the last must be at least %v", src+1, fs.SizeSuffix(size), minChunkSize)
+		}
+		for i := int64(0); i < numParts; i++ {
+			end := (i+1)*piece - 1
+			if i == numParts-1 {
+				end = size - 1
+			}
+			parts = append(parts, concatPart{src: src, start: i * piece, end: end})
+		}
+	}
+	if len(parts) == 0 {
+		return nil, errors.New("nothing to concatenate - all the sources are empty")
+	}
+	if len(parts) > maxUploadParts {
+		return nil, errors.Errorf("too many parts %d - the maximum is %d", len(parts), maxUploadParts)
+	}
+	return parts, nil
+}
+
+// concat joins the objects srcRemotes into a new object dstRemote
+// with a multipart upload copying each source server-side
+func (f *Fs) concat(ctx context.Context, dstRemote string, srcRemotes []string) (_ fs.Object, err error) {
+	srcs := make([]*Object, len(srcRemotes))
+	sizes := make([]int64, len(srcRemotes))
+	for i, srcRemote := range srcRemotes {
+		o, err := f.NewObject(ctx, srcRemote)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find source %q", srcRemote)
+		}
+		srcs[i] = o.(*Object)
+		sizes[i] = srcs[i].bytes
+	}
+	partSize := int64(f.opt.CopyCutoff)
+	if partSize > maxSizeForCopy {
+		partSize = maxSizeForCopy
+	}
+	plan, err := concatParts(sizes, partSize)
+	if err != nil {
+		return nil, err
+	}
+	if operations.SkipDestructive(ctx, dstRemote, "concatenate") {
+		return nil, nil
+	}
+	dstBucket, dstPath := f.split(dstRemote)
+	req := &s3.CreateMultipartUploadInput{
+		Bucket:      &dstBucket,
+		Key:         &dstPath,
+		ACL:         &f.opt.ACL,
+		ContentType: aws.String(srcs[0].MimeType(ctx)),
+		Metadata: map[string]*string{
+			metaMtime: aws.String(swift.TimeToFloatString(time.Now())),
+		},
+	}
+	if f.opt.RequesterPays {
+		req.RequestPayer = aws.String(s3.RequestPayerRequester)
+	}
+	if f.opt.ServerSideEncryption != "" {
+		req.ServerSideEncryption = &f.opt.ServerSideEncryption
+	}
+	if f.opt.SSECustomerAlgorithm != "" {
+		req.SSECustomerAlgorithm = &f.opt.SSECustomerAlgorithm
+		req.SSECustomerKey = &f.opt.SSECustomerKey
+		req.SSECustomerKeyMD5 = &f.opt.SSECustomerKeyMD5
+	}
+	if f.opt.SSEKMSKeyID != "" {
+		req.SSEKMSKeyId = &f.opt.SSEKMSKeyID
+	}
+	if f.opt.StorageClass != "" {
+		req.StorageClass = &f.opt.StorageClass
+	}
+	var cout *s3.CreateMultipartUploadOutput
+	err = f.pacer.Call(func() (bool, error) {
+		var err error
+		cout, err = f.c.CreateMultipartUploadWithContext(ctx, req)
+		return f.shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "concatenate failed to initialise")
+	}
+	uid := cout.UploadId
+
+	defer atexit.OnError(&err, func() {
+		// Try to abort the upload, but ignore the error.
+		fs.Debugf(f, "Cancelling concatenation of %q", dstRemote)
+		_ = f.pacer.Call(func() (bool, error) {
+			_, err := f.c.AbortMultipartUploadWithContext(context.Background(), &s3.AbortMultipartUploadInput{
+				Bucket:       &dstBucket,
+				Key:          &dstPath,
+				UploadId:     uid,
+				RequestPayer: req.RequestPayer,
+			})
+			return f.shouldRetry(ctx, err)
+		})
+	})()
+
+	fs.Debugf(f, "Concatenating %d sources into %q with %d parts", len(srcs), dstRemote, len(plan))
+	parts := make([]*s3.CompletedPart, 0, len(plan))
+	for i, part := range plan {
+		partNum := int64(i + 1)
+		srcBucket, srcPath := srcs[part.src].split()
+		source := pathEscape(path.Join(srcBucket, srcPath))
+		if versionID := srcs[part.src].versionID; versionID != nil {
+			source += "?versionId=" + url.QueryEscape(*versionID)
+		}
+		uploadPartReq := &s3.UploadPartCopyInput{
+			Bucket:               &dstBucket,
+			Key:                  &dstPath,
+			PartNumber:           &partNum,
+			UploadId:             uid,
+			CopySource:           &source,
+			CopySourceRange:      aws.String(fmt.Sprintf("bytes=%d-%d", part.start, part.end)),
+			RequestPayer:         req.RequestPayer,
+			SSECustomerAlgorithm: req.SSECustomerAlgorithm,
+			SSECustomerKey:       req.SSECustomerKey,
+			SSECustomerKeyMD5:    req.SSECustomerKeyMD5,
+		}
+		if f.opt.SSECustomerAlgorithm != "" {
+			uploadPartReq.CopySourceSSECustomerAlgorithm = &f.opt.SSECustomerAlgorithm
+			uploadPartReq.CopySourceSSECustomerKey = &f.opt.SSECustomerKey
+			uploadPartReq.CopySourceSSECustomerKeyMD5 = &f.opt.SSECustomerKeyMD5
+		}
+		var uout *s3.UploadPartCopyOutput
+		err = f.pacer.Call(func() (bool, error) {
+			var err error
+			uout, err = f.c.UploadPartCopyWithContext(ctx, uploadPartReq)
+			return f.shouldRetry(ctx, err)
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to copy part %d from %q", partNum, srcs[part.src].remote)
+		}
+		parts = append(parts, &s3.CompletedPart{
+			PartNumber: &partNum,
+			ETag:       uout.CopyPartResult.ETag,
+		})
+	}
+
+	err = f.pacer.Call(func() (bool, error) {
+		_, err := f.c.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket: &dstBucket,
+			Key:    &dstPath,
+			MultipartUpload: &s3.CompletedMultipartUpload{
+				Parts: parts,
+			},
+			RequestPayer: req.RequestPayer,
+			UploadId:     uid,
+		})
+		return f.shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "concatenate failed to finalise")
+	}
+	return f.NewObject(ctx, dstRemote)
+}
+
 func calculateRange(partSize, partIndex, numParts, totalSize int64) string {
 	start := partIndex * partSize
 	var ends string
@@ -2508,6 +2910,26 @@ a bucket or with a bucket and path.
       "rclone-dst": []
     }
 
+`,
+}, {
+	Name:  "concat",
+	Short: "Join objects into a new object server-side",
+	Long: `This command joins the source objects, in order, into a new
+destination object without downloading them, by making a multipart
+upload with each source copied server-side as one or more parts. All
+the paths are relative to the remote.
+
+    rclone backend concat s3:bucket/logs all.log 01.log 02.log 03.log
+
+This makes s3:bucket/logs/all.log from the three logs. The sources
+are left as they are and the destination is overwritten if it exists.
+
+S3 requires each part except the last to be at least 5 MiB so all
+the sources except the last must be at least this big. Sources larger
+than --s3-copy-cutoff are split into several parts.
+
+Note that you can use -i/--dry-run with this command to see what it
+would do.
 `,
 }, {
 	Name:  "cleanup",
@@ -2602,6 +3024,12 @@ func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[str
 		return out, nil
 	case "list-multipart-uploads":
 		return f.listMultipartUploadsAll(ctx)
+	case "concat":
+		if len(arg) < 2 {
+			return nil, errors.New("need a destination and at least one source")
+		}
+		_, err = f.concat(ctx, arg[0], arg[1:])
+		return nil, err
 	case "cleanup":
 		maxAge := 24 * time.Hour
 		if opt["max-age"] != "" {
@@ -2731,6 +3159,274 @@ func (f *Fs) CleanUp(ctx context.Context) (err error) {
 	return f.cleanUp(ctx, 24*time.Hour)
 }
 
+// ListTrash lists the objects in dir and its subdirectories which
+// have been deleted, that is whose latest version is a delete marker.
+//
+// This only finds deleted objects in buckets with versioning enabled.
+func (f *Fs) ListTrash(ctx context.Context, dir string) (entries []fs.TrashEntry, err error) {
+	bucket, directory := f.split(dir)
+	if bucket == "" {
+		return nil, errors.New("can't list deleted files without a bucket")
+	}
+	if directory != "" {
+		directory += "/"
+	}
+	prefix := f.rootDirectory
+	if prefix != "" {
+		prefix += "/"
+	}
+	type version struct {
+		modTime time.Time
+		size    int64
+	}
+	// newest version of each object which isn't a delete marker
+	versions := map[string]version{}
+	markers := map[string]*s3.DeleteMarkerEntry{}
+	req := s3.ListObjectVersionsInput{
+		Bucket: &bucket,
+		Prefix: &directory,
+	}
+	for {
+		var resp *s3.ListObjectVersionsOutput
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.c.ListObjectVersionsWithContext(ctx, &req)
+			return f.shouldRetry(ctx, err)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list object versions")
+		}
+		for _, marker := range resp.DeleteMarkers {
+			if aws.BoolValue(marker.IsLatest) {
+				markers[aws.StringValue(marker.Key)] = marker
+			}
+		}
+		for _, v := range resp.Versions {
+			key := aws.StringValue(v.Key)
+			modTime := aws.TimeValue(v.LastModified)
+			if old, ok := versions[key]; !ok || modTime.After(old.modTime) {
+				versions[key] = version{modTime: modTime, size: aws.Int64Value(v.Size)}
+			}
+		}
+		if !aws.BoolValue(resp.IsTruncated) {
+			break
+		}
+		req.KeyMarker = resp.NextKeyMarker
+		req.VersionIdMarker = resp.NextVersionIdMarker
+	}
+	for key, marker := range markers {
+		remote := f.opt.Enc.ToStandardPath(key)
+		if !strings.HasPrefix(remote, prefix) || strings.HasSuffix(remote, "/") {
+			continue
+		}
+		remote = remote[len(prefix):]
+		if f.rootBucket == "" {
+			remote = path.Join(f.opt.Enc.ToStandardName(bucket), remote)
+		}
+		size := int64(-1)
+		if v, ok := versions[key]; ok {
+			size = v.size
+		}
+		entries = append(entries, fs.TrashEntry{
+			Remote:  remote,
+			Size:    size,
+			Deleted: aws.TimeValue(marker.LastModified),
+			ID:      aws.StringValue(marker.VersionId),
+		})
+	}
+	return entries, nil
+}
+
+// Undelete restores a deleted object by removing its delete marker
+func (f *Fs) Undelete(ctx context.Context, entry fs.TrashEntry) (fs.Object, error) {
+	bucket, bucketPath := f.split(entry.Remote)
+	req := s3.DeleteObjectInput{
+		Bucket:    &bucket,
+		Key:       &bucketPath,
+		VersionId: &entry.ID,
+	}
+	if f.opt.RequesterPays {
+		req.RequestPayer = aws.String(s3.RequestPayerRequester)
+	}
+	err := f.pacer.Call(func() (bool, error) {
+		_, err := f.c.DeleteObjectWithContext(ctx, &req)
+		return f.shouldRetry(ctx, err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to remove delete marker")
+	}
+	return f.NewObject(ctx, entry.Remote)
+}
+
+// errNotWithVersion is returned when trying to change an old version
+var errNotWithVersion = errors.New("can't modify or overwrite an old version")
+
+// ListVersions lists the old versions of the objects in dir, that
+// is the versions which aren't the latest, with the time they were
+// uploaded added to their names.
+func (f *Fs) ListVersions(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	bucket, directory := f.split(dir)
+	if bucket == "" {
+		return nil, nil
+	}
+	if directory != "" {
+		directory += "/"
+	}
+	prefix := f.rootDirectory
+	if prefix != "" {
+		prefix += "/"
+	}
+	delimiter := "/"
+	req := s3.ListObjectVersionsInput{
+		Bucket:    &bucket,
+		Prefix:    &directory,
+		Delimiter: &delimiter,
+	}
+	for {
+		var resp *s3.ListObjectVersionsOutput
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.c.ListObjectVersionsWithContext(ctx, &req)
+			return f.shouldRetry(ctx, err)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list object versions")
+		}
+		for _, v := range resp.Versions {
+			if aws.BoolValue(v.IsLatest) {
+				continue
+			}
+			remote := f.opt.Enc.ToStandardPath(aws.StringValue(v.Key))
+			if !strings.HasPrefix(remote, prefix) || strings.HasSuffix(remote, "/") {
+				continue
+			}
+			remote = remote[len(prefix):]
+			if f.rootBucket == "" {
+				remote = path.Join(f.opt.Enc.ToStandardName(bucket), remote)
+			}
+			o := &Object{
+				fs:           f,
+				remote:       version.Add(remote, aws.TimeValue(v.LastModified)),
+				bytes:        aws.Int64Value(v.Size),
+				lastModified: aws.TimeValue(v.LastModified),
+				storageClass: aws.StringValue(v.StorageClass),
+				versionID:    v.VersionId,
+			}
+			o.setMD5FromEtag(aws.StringValue(v.ETag))
+			entries = append(entries, o)
+		}
+		if !aws.BoolValue(resp.IsTruncated) {
+			break
+		}
+		req.KeyMarker = resp.NextKeyMarker
+		req.VersionIdMarker = resp.NextVersionIdMarker
+	}
+	return entries, nil
+}
+
+// ListAt lists the objects and directories in dir as they were at t,
+// that is the newest version of each object uploaded at or before t
+// unless that is a delete marker.
+func (f *Fs) ListAt(ctx context.Context, dir string, t time.Time) (entries fs.DirEntries, err error) {
+	bucket, directory := f.split(dir)
+	if bucket == "" {
+		return f.List(ctx, dir)
+	}
+	if directory != "" {
+		directory += "/"
+	}
+	prefix := f.rootDirectory
+	if prefix != "" {
+		prefix += "/"
+	}
+	// newest version and delete marker of each key at or before t
+	versions := map[string]*s3.ObjectVersion{}
+	markers := map[string]time.Time{}
+	var keys []string
+	delimiter := "/"
+	req := s3.ListObjectVersionsInput{
+		Bucket:    &bucket,
+		Prefix:    &directory,
+		Delimiter: &delimiter,
+	}
+	for {
+		var resp *s3.ListObjectVersionsOutput
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.c.ListObjectVersionsWithContext(ctx, &req)
+			return f.shouldRetry(ctx, err)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list object versions")
+		}
+		for _, commonPrefix := range resp.CommonPrefixes {
+			remote := f.opt.Enc.ToStandardPath(aws.StringValue(commonPrefix.Prefix))
+			if !strings.HasPrefix(remote, prefix) {
+				continue
+			}
+			remote = strings.TrimSuffix(remote[len(prefix):], "/")
+			if f.rootBucket == "" {
+				remote = path.Join(f.opt.Enc.ToStandardName(bucket), remote)
+			}
+			entries = append(entries, fs.NewDir(remote, time.Time{}))
+		}
+		for _, marker := range resp.DeleteMarkers {
+			key := aws.StringValue(marker.Key)
+			modTime := aws.TimeValue(marker.LastModified)
+			if modTime.After(t) {
+				continue
+			}
+			if old, ok := markers[key]; !ok || modTime.After(old) {
+				markers[key] = modTime
+			}
+		}
+		for _, v := range resp.Versions {
+			key := aws.StringValue(v.Key)
+			modTime := aws.TimeValue(v.LastModified)
+			if modTime.After(t) {
+				continue
+			}
+			old, ok := versions[key]
+			if !ok {
+				keys = append(keys, key)
+			}
+			if !ok || modTime.After(aws.TimeValue(old.LastModified)) {
+				versions[key] = v
+			}
+		}
+		if !aws.BoolValue(resp.IsTruncated) {
+			break
+		}
+		req.KeyMarker = resp.NextKeyMarker
+		req.VersionIdMarker = resp.NextVersionIdMarker
+	}
+	for _, key := range keys {
+		v := versions[key]
+		if deleted, ok := markers[key]; ok && !deleted.Before(aws.TimeValue(v.LastModified)) {
+			continue
+		}
+		remote := f.opt.Enc.ToStandardPath(key)
+		if !strings.HasPrefix(remote, prefix) || strings.HasSuffix(remote, "/") {
+			continue
+		}
+		remote = remote[len(prefix):]
+		if f.rootBucket == "" {
+			remote = path.Join(f.opt.Enc.ToStandardName(bucket), remote)
+		}
+		o := &Object{
+			fs:           f,
+			remote:       remote,
+			bytes:        aws.Int64Value(v.Size),
+			lastModified: aws.TimeValue(v.LastModified),
+			storageClass: aws.StringValue(v.StorageClass),
+		}
+		if !aws.BoolValue(v.IsLatest) {
+			o.versionID = v.VersionId
+			o.snapshot = true
+		}
+		o.setMD5FromEtag(aws.StringValue(v.ETag))
+		entries = append(entries, o)
+	}
+	return entries, nil
+}
+
 // ------------------------------------------------------------
 
 // Fs returns the parent Fs
@@ -2795,8 +3491,9 @@ func (o *Object) Size() int64 {
 func (o *Object) headObject(ctx context.Context) (resp *s3.HeadObjectOutput, err error) {
 	bucket, bucketPath := o.split()
 	req := s3.HeadObjectInput{
-		Bucket: &bucket,
-		Key:    &bucketPath,
+		Bucket:    &bucket,
+		Key:       &bucketPath,
+		VersionId: o.versionID,
 	}
 	if o.fs.opt.RequesterPays {
 		req.RequestPayer = aws.String(s3.RequestPayerRequester)
@@ -2907,6 +3604,9 @@ func (o *Object) ModTime(ctx context.Context) time.Time {
 
 // SetModTime sets the modification time of the local fs object
 func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	if o.versionID != nil {
+		return errNotWithVersion
+	}
 	err := o.readMetaData(ctx)
 	if err != nil {
 		return err
@@ -2917,8 +3617,75 @@ func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
 	if o.storageClass == "GLACIER" || o.storageClass == "DEEP_ARCHIVE" {
 		return fs.ErrorCantSetModTime
 	}
+	return o.replaceMetaData(ctx)
+}
+
+// fsMetaKeys maps the fs.Metadata keys to the meta keys they are
+// stored in
+var fsMetaKeys = map[string]string{
+	fs.MetadataMode: metaMode,
+	fs.MetadataUID:  metaUID,
+	fs.MetadataGID:  metaGID,
+}
+
+// Metadata returns the mode and owner stored in the object's metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	err := o.readMetaData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m := fs.Metadata{}
+	for key, metaKey := range fsMetaKeys {
+		if value, ok := o.meta[metaKey]; ok && value != nil {
+			m[key] = *value
+		}
+	}
+	return m, nil
+}
 
-	// Copy the object to itself to update the metadata
+// SetMetadata stores the mode and owner in m in the object's metadata
+//
+// Extended attributes aren't supported so if there are any in m the
+// rest of the keys are stored and an error wrapping
+// fs.ErrorCantSetMetadata is returned.
+func (o *Object) SetMetadata(ctx context.Context, m fs.Metadata) error {
+	if o.versionID != nil {
+		return errNotWithVersion
+	}
+	err := o.readMetaData(ctx)
+	if err != nil {
+		return err
+	}
+	var unsupported []string
+	changed := false
+	for key, value := range m {
+		metaKey, ok := fsMetaKeys[key]
+		if !ok {
+			unsupported = append(unsupported, key)
+			continue
+		}
+		o.meta[metaKey] = aws.String(value)
+		changed = true
+	}
+	if changed {
+		if o.storageClass == "GLACIER" || o.storageClass == "DEEP_ARCHIVE" {
+			return errors.New("can't update metadata of objects in GLACIER or DEEP_ARCHIVE")
+		}
+		err = o.replaceMetaData(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return errors.Wrapf(fs.ErrorCantSetMetadata, "can't store %q", unsupported)
+	}
+	return nil
+}
+
+// replaceMetaData copies the object to itself to replace its
+// metadata with o.meta
+func (o *Object) replaceMetaData(ctx context.Context) error {
 	bucket, bucketPath := o.split()
 	req := s3.CopyObjectInput{
 		ContentType:       aws.String(fs.MimeType(ctx, o)), // Guess the content type
@@ -2940,8 +3707,9 @@ func (o *Object) Storable() bool {
 func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
 	bucket, bucketPath := o.split()
 	req := s3.GetObjectInput{
-		Bucket: &bucket,
-		Key:    &bucketPath,
+		Bucket:    &bucket,
+		Key:       &bucketPath,
+		VersionId: o.versionID,
 	}
 	if o.fs.opt.RequesterPays {
 		req.RequestPayer = aws.String(s3.RequestPayerRequester)
@@ -3199,6 +3967,9 @@ func (o *Object) uploadMultipart(ctx context.Context, req *s3.PutObjectInput, si
 
 // Update the Object from in with modTime and size
 func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	if o.versionID != nil {
+		return errNotWithVersion
+	}
 	bucket, bucketPath := o.split()
 	err := o.fs.makeBucket(ctx, bucket)
 	if err != nil {
@@ -3214,6 +3985,19 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		metaMtime: aws.String(swift.TimeToFloatString(modTime)),
 	}
 
+	// Set the mode and owner from src if preserving metadata
+	if fs.GetConfig(ctx).Metadata {
+		srcMeta, err := fs.GetMetadata(ctx, src)
+		if err != nil {
+			fs.Debugf(o, "Failed to read source metadata: %v", err)
+		}
+		for key, metaKey := range fsMetaKeys {
+			if value, ok := srcMeta[key]; ok {
+				metadata[metaKey] = aws.String(value)
+			}
+		}
+	}
+
 	// read the md5sum if available
 	// - for non multipart
 	//    - so we can add a ContentMD5
@@ -3354,7 +4138,7 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 				return false, nil
 			}
 			err = errors.Errorf("s3 upload: %s: %s", resp.Status, body)
-			return fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+			return fserrors.ShouldRetryHTTP(resp, retryErrorCodes), fserrors.RateLimitedHTTP(resp, err)
 		})
 		if err != nil {
 			return err
@@ -3391,8 +4175,9 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 func (o *Object) Remove(ctx context.Context) error {
 	bucket, bucketPath := o.split()
 	req := s3.DeleteObjectInput{
-		Bucket: &bucket,
-		Key:    &bucketPath,
+		Bucket:    &bucket,
+		Key:       &bucketPath,
+		VersionId: o.versionID,
 	}
 	if o.fs.opt.RequesterPays {
 		req.RequestPayer = aws.String(s3.RequestPayerRequester)
@@ -3416,6 +4201,9 @@ func (o *Object) MimeType(ctx context.Context) string {
 
 // SetTier performs changing storage class
 func (o *Object) SetTier(tier string) (err error) {
+	if o.versionID != nil {
+		return errNotWithVersion
+	}
 	ctx := context.TODO()
 	tier = strings.ToUpper(tier)
 	bucket, bucketPath := o.split()
@@ -3441,14 +4229,20 @@ func (o *Object) GetTier() string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Copier      = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.ListRer     = &Fs{}
-	_ fs.Commander   = &Fs{}
-	_ fs.CleanUpper  = &Fs{}
-	_ fs.Object      = &Object{}
-	_ fs.MimeTyper   = &Object{}
-	_ fs.GetTierer   = &Object{}
-	_ fs.SetTierer   = &Object{}
+	_ fs.Fs             = &Fs{}
+	_ fs.Copier         = &Fs{}
+	_ fs.PutStreamer    = &Fs{}
+	_ fs.ListRer        = &Fs{}
+	_ fs.Commander      = &Fs{}
+	_ fs.CleanUpper     = &Fs{}
+	_ fs.TrashLister    = &Fs{}
+	_ fs.Undeleter      = &Fs{}
+	_ fs.VersionLister  = &Fs{}
+	_ fs.SnapshotLister = &Fs{}
+	_ fs.Object         = &Object{}
+	_ fs.MimeTyper      = &Object{}
+	_ fs.GetTierer      = &Object{}
+	_ fs.SetTierer      = &Object{}
+	_ fs.Metadataer     = &Object{}
+	_ fs.SetMetadataer  = &Object{}
 )
@@ -0,0 +1,187 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSSE(t *testing.T) {
+	key := "01234567890123456789012345678901"
+	md5sum := md5.Sum([]byte(key))
+	keyMD5 := base64.StdEncoding.EncodeToString(md5sum[:])
+	for _, test := range []struct {
+		name    string
+		opt     Options
+		wantKey string
+		wantMD5 string
+		wantErr string
+	}{{
+		name: "none",
+	}, {
+		name: "SSE-S3",
+		opt:  Options{ServerSideEncryption: "AES256"},
+	}, {
+		name: "SSE-KMS",
+		opt:  Options{ServerSideEncryption: "aws:kms", SSEKMSKeyID: "arn:aws:kms:us-east-1:key"},
+	}, {
+		name:    "SSE-KMS without aws:kms",
+		opt:     Options{SSEKMSKeyID: "arn:aws:kms:us-east-1:key"},
+		wantErr: "server_side_encryption must be aws:kms when using sse_kms_key_id",
+	}, {
+		name:    "SSE-C",
+		opt:     Options{SSECustomerAlgorithm: "AES256", SSECustomerKey: key},
+		wantKey: key,
+		wantMD5: keyMD5,
+	}, {
+		name:    "SSE-C with MD5",
+		opt:     Options{SSECustomerAlgorithm: "AES256", SSECustomerKey: key, SSECustomerKeyMD5: "potato"},
+		wantKey: key,
+		wantMD5: "potato",
+	}, {
+		name:    "SSE-C base64",
+		opt:     Options{SSECustomerAlgorithm: "AES256", SSECustomerKeyBase64: base64.StdEncoding.EncodeToString([]byte(key))},
+		wantKey: key,
+		wantMD5: keyMD5,
+	}, {
+		name:    "SSE-C bad base64",
+		opt:     Options{SSECustomerAlgorithm: "AES256", SSECustomerKeyBase64: "!!!"},
+		wantErr: "failed to decode sse_customer_key_base64",
+	}, {
+		name:    "SSE-C both keys",
+		opt:     Options{SSECustomerAlgorithm: "AES256", SSECustomerKey: key, SSECustomerKeyBase64: "YQ=="},
+		wantErr: "sse_customer_key and sse_customer_key_base64 can't both be set",
+	}, {
+		name:    "SSE-C without algorithm",
+		opt:     Options{SSECustomerKey: key},
+		wantErr: "sse_customer_algorithm must be set when using sse_customer_key",
+	}, {
+		name:    "SSE-C without key",
+		opt:     Options{SSECustomerAlgorithm: "AES256"},
+		wantErr: "sse_customer_key must be set when using sse_customer_algorithm",
+	}, {
+		name:    "SSE-C with SSE",
+		opt:     Options{SSECustomerAlgorithm: "AES256", SSECustomerKey: key, ServerSideEncryption: "AES256"},
+		wantErr: "server_side_encryption can't be used with sse_customer_algorithm",
+	}} {
+		t.Run(test.name, func(t *testing.T) {
+			opt := test.opt
+			err := checkSSE(&opt)
+			if test.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.wantKey, opt.SSECustomerKey)
+			assert.Equal(t, test.wantMD5, opt.SSECustomerKeyMD5)
+		})
+	}
+}
+
+func TestParseInventorySchema(t *testing.T) {
+	schema, err := parseInventorySchema("Bucket, Key, VersionId, IsLatest, IsDeleteMarker, Size, LastModifiedDate, ETag, StorageClass")
+	require.NoError(t, err)
+	assert.Equal(t, inventorySchema{
+		bucket:         0,
+		key:            1,
+		isLatest:       3,
+		isDeleteMarker: 4,
+		size:           5,
+		lastModified:   6,
+		eTag:           7,
+		storageClass:   8,
+	}, schema)
+
+	_, err = parseInventorySchema("Bucket, Key, ETag")
+	assert.Error(t, err)
+}
+
+func TestReadInventoryCSV(t *testing.T) {
+	schema, err := parseInventorySchema("Bucket, Key, IsLatest, IsDeleteMarker, Size, LastModifiedDate, ETag")
+	require.NoError(t, err)
+	in := strings.NewReader(`"bucket","dir/file+one.txt","true","false","10","2021-01-02T03:04:05.000Z","0123456789abcdef0123456789abcdef"
+"bucket","dir/old.txt","false","false","5","2021-01-02T03:04:05.000Z",""
+"bucket","dir/deleted.txt","true","true","","",""
+"bucket","dir/%E2%82%AC.txt","true","false","0","2021-01-02T03:04:05Z",""
+`)
+	var items []*inventoryItem
+	err = readInventoryCSV(in, schema, func(item *inventoryItem) error {
+		items = append(items, item)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	assert.Equal(t, "bucket", items[0].bucket)
+	assert.Equal(t, "dir/file one.txt", items[0].key)
+	assert.Equal(t, int64(10), *items[0].object.Size)
+	assert.Equal(t, time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC), *items[0].object.LastModified)
+	assert.Equal(t, `"0123456789abcdef0123456789abcdef"`, *items[0].object.ETag)
+
+	assert.Equal(t, "dir/€.txt", items[1].key)
+	assert.Nil(t, items[1].object.ETag)
+
+	// Bad size
+	err = readInventoryCSV(strings.NewReader(`"bucket","file","true","false","potato","2021-01-02T03:04:05Z",""`), schema, func(item *inventoryItem) error {
+		return nil
+	})
+	assert.Error(t, err)
+}
+
+func TestConcatParts(t *testing.T) {
+	const M = int64(minChunkSize)
+	for _, test := range []struct {
+		name     string
+		sizes    []int64
+		partSize int64
+		want     []concatPart
+		wantErr  string
+	}{{
+		name:     "simple",
+		sizes:    []int64{M, 2 * M, 1},
+		partSize: 10 * M,
+		want:     []concatPart{{0, 0, M - 1}, {1, 0, 2*M - 1}, {2, 0, 0}},
+	}, {
+		name:     "split",
+		sizes:    []int64{25 * M, 3},
+		partSize: 10 * M,
+		want:     []concatPart{{0, 0, 25*M/3 - 1}, {0, 25 * M / 3, 2*(25*M/3) - 1}, {0, 2 * (25 * M / 3), 25*M - 1}, {1, 0, 2}},
+	}, {
+		name:     "empty sources skipped",
+		sizes:    []int64{0, M, 10, 0},
+		partSize: 10 * M,
+		want:     []concatPart{{1, 0, M - 1}, {2, 0, 9}},
+	}, {
+		name:     "too small",
+		sizes:    []int64{M - 1, M},
+		partSize: 10 * M,
+		wantErr:  "source 1 is",
+	}, {
+		name:     "all empty",
+		sizes:    []int64{0, 0},
+		partSize: 10 * M,
+		wantErr:  "nothing to concatenate",
+	}, {
+		name:     "too many parts",
+		sizes:    []int64{(maxUploadParts + 1) * M},
+		partSize: M,
+		wantErr:  "too many parts",
+	}} {
+		t.Run(test.name, func(t *testing.T) {
+			parts, err := concatParts(test.sizes, test.partSize)
+			if test.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, parts)
+		})
+	}
+}
@@ -426,7 +426,7 @@ func (f *Fs) shouldRetry(ctx context.Context, resp *http.Response, err error) (b
 		}
 		return true, pacer.RetryAfterError(err, time.Duration(retryAfter)*time.Second)
 	}
-	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), fserrors.RateLimitedHTTP(resp, err)
 }
 
 func (f *Fs) shouldRetryUpload(ctx context.Context, resp *http.Response, err error) (bool, error) {
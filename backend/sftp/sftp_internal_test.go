@@ -3,10 +3,19 @@
 package sftp
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/pkg/sftp"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestShellEscape(t *testing.T) {
@@ -36,6 +45,19 @@ func TestParseHash(t *testing.T) {
 	}
 }
 
+func TestHashCommands(t *testing.T) {
+	f := &Fs{}
+	for _, hc := range hashCommands {
+		// Check the hash of no data each command must print
+		hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(hc.hashType))
+		require.NoError(t, err)
+		sums := hasher.Sums()
+		assert.Equal(t, sums[hc.hashType], hc.empty, hc.hashType.String())
+		assert.NotNil(t, f.hashCommandOpt(hc.hashType), hc.hashType.String())
+	}
+	assert.Nil(t, f.hashCommandOpt(hash.CRC32))
+}
+
 func TestParseUsage(t *testing.T) {
 	for i, test := range []struct {
 		sshOutput string
@@ -49,3 +71,94 @@ func TestParseUsage(t *testing.T) {
 		assert.Equal(t, test.usage, [3]int64{gotSpaceTotal, gotSpaceUsed, gotSpaceAvail}, fmt.Sprintf("Test %d sshOutput = %q", i, test.sshOutput))
 	}
 }
+
+func TestUsageFromStatVFS(t *testing.T) {
+	usage := usageFromStatVFS(&sftp.StatVFS{
+		Bsize:  4096,
+		Frsize: 1024,
+		Blocks: 1000,
+		Bfree:  300,
+		Bavail: 200,
+	})
+	assert.Equal(t, int64(1024000), *usage.Total)
+	assert.Equal(t, int64(716800), *usage.Used)
+	assert.Equal(t, int64(204800), *usage.Free)
+	assert.Nil(t, usage.Trashed)
+
+	// Frsize not set
+	usage = usageFromStatVFS(&sftp.StatVFS{
+		Bsize:  4096,
+		Blocks: 10,
+		Bfree:  5,
+		Bavail: 5,
+	})
+	assert.Equal(t, int64(40960), *usage.Total)
+}
+
+// fakeKey is an ssh.PublicKey with the given type
+type fakeKey struct {
+	ssh.PublicKey
+	keyType string
+}
+
+func (k fakeKey) Type() string { return k.keyType }
+
+func TestIsSecurityKey(t *testing.T) {
+	for _, test := range []struct {
+		keyType string
+		want    bool
+	}{
+		{ssh.KeyAlgoED25519, false},
+		{ssh.KeyAlgoRSA, false},
+		{ssh.CertAlgoED25519v01, false},
+		{ssh.KeyAlgoSKECDSA256, true},
+		{ssh.KeyAlgoSKED25519, true},
+		{ssh.CertAlgoSKECDSA256v01, true},
+		{ssh.CertAlgoSKED25519v01, true},
+	} {
+		assert.Equal(t, test.want, isSecurityKey(fakeKey{keyType: test.keyType}), test.keyType)
+	}
+}
+
+func TestReadCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-sftp-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	newSigner := func() ssh.Signer {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		signer, err := ssh.NewSignerFromKey(priv)
+		require.NoError(t, err)
+		return signer
+	}
+	ca, user := newSigner(), newSigner()
+	cert := &ssh.Certificate{
+		Key:             user.PublicKey(),
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{"user"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	require.NoError(t, cert.SignCert(rand.Reader, ca))
+
+	certFile := filepath.Join(dir, "id_ed25519-cert.pub")
+	require.NoError(t, ioutil.WriteFile(certFile, ssh.MarshalAuthorizedKey(cert), 0600))
+	pubFile := filepath.Join(dir, "id_ed25519.pub")
+	require.NoError(t, ioutil.WriteFile(pubFile, ssh.MarshalAuthorizedKey(user.PublicKey()), 0600))
+
+	got, err := readCertificate(certFile)
+	require.NoError(t, err)
+	assert.Equal(t, cert.Marshal(), got.Marshal())
+
+	pub, err := readPublicKey(pubFile)
+	require.NoError(t, err)
+	assert.Equal(t, user.PublicKey().Marshal(), pub.Marshal())
+
+	_, err = readCertificate(pubFile)
+	assert.EqualError(t, err, "public key file is not a certificate file: "+pubFile)
+
+	_, err = readCertificate(filepath.Join(dir, "notfound"))
+	assert.Error(t, err)
+}
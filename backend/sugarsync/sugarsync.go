@@ -264,7 +264,7 @@ func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, err
 	if fserrors.ContextError(ctx, &err) {
 		return false, err
 	}
-	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), fserrors.RateLimitedHTTP(resp, err)
 }
 
 // readMetaDataForPath reads the metadata from the path
@@ -1076,6 +1076,22 @@ func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration,
 	return info.PublicLink.URL, err
 }
 
+// About gets quota information
+func (f *Fs) About(ctx context.Context) (usage *fs.Usage, err error) {
+	user, err := f.getUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usage = &fs.Usage{
+		Used: fs.NewUsageValue(user.Quota.Usage), // bytes in use
+	}
+	if user.Quota.Limit > 0 {
+		usage.Total = fs.NewUsageValue(user.Quota.Limit)                   // quota of bytes that can be used
+		usage.Free = fs.NewUsageValue(user.Quota.Limit - user.Quota.Usage) // bytes which can be uploaded before reaching the quota
+	}
+	return usage, nil
+}
+
 // DirCacheFlush resets the directory cache - used in testing as an
 // optional interface
 func (f *Fs) DirCacheFlush() {
@@ -1307,6 +1323,7 @@ var (
 	_ fs.DirMover        = (*Fs)(nil)
 	_ fs.DirCacheFlusher = (*Fs)(nil)
 	_ fs.PublicLinker    = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
 	_ fs.Object          = (*Object)(nil)
 	_ fs.IDer            = (*Object)(nil)
 )
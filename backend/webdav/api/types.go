@@ -21,6 +21,9 @@ const (
 	noZerosRFC1123 = "Mon, _2 Jan 2006 15:04:05 MST"
 )
 
+// RcloneNamespace is the XML namespace of the properties rclone stores
+const RcloneNamespace = "http://rclone.org/ns/"
+
 // Multistatus contains responses returned from an HTTP 207 return code
 type Multistatus struct {
 	Responses []Response `xml:"response"`
@@ -73,6 +76,7 @@ type Prop struct {
 	Size         int64     `xml:"DAV: prop>getcontentlength,omitempty"`
 	Modified     Time      `xml:"DAV: prop>getlastmodified,omitempty"`
 	Checksums    []string  `xml:"prop>checksums>checksum,omitempty"`
+	ModTime      string    `xml:"http://rclone.org/ns/ prop>mtime,omitempty"`
 }
 
 // Parse a status of the form "HTTP/1.1 200 OK" or "HTTP/1.1 200"
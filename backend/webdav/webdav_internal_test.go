@@ -7,13 +7,16 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rclone/rclone/backend/webdav"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/configfile"
 	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/object"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	xwebdav "golang.org/x/net/webdav"
 )
 
 var (
@@ -72,3 +75,52 @@ func TestHeaders(t *testing.T) {
 	_, err := f.Features().About(context.Background())
 	require.NoError(t, err)
 }
+
+// TestModTimeProp checks the mtime is stored in and read from a dead
+// property
+func TestModTimeProp(t *testing.T) {
+	ctx := context.Background()
+	ts := httptest.NewServer(&xwebdav.Handler{
+		FileSystem: xwebdav.NewMemFS(),
+		LockSystem: xwebdav.NewMemLS(),
+	})
+	defer ts.Close()
+	configfile.Install()
+
+	m := configmap.Simple{
+		"type":         "webdav",
+		"url":          ts.URL,
+		"modtime_prop": "true",
+	}
+	f, err := webdav.NewFs(ctx, remoteName, "", m)
+	require.NoError(t, err)
+	assert.Equal(t, time.Nanosecond, f.Precision())
+
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 123456789, time.UTC)
+	src := object.NewStaticObjectInfo("file.txt", modTime, 5, true, nil, nil)
+	o, err := f.Put(ctx, strings.NewReader("hello"), src)
+	require.NoError(t, err)
+	assert.True(t, modTime.Equal(o.ModTime(ctx)), o.ModTime(ctx))
+
+	// Read it back from a listing
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.True(t, modTime.Equal(entries[0].ModTime(ctx)), entries[0].ModTime(ctx))
+
+	// Change it
+	newModTime := modTime.Add(time.Hour)
+	require.NoError(t, o.SetModTime(ctx, newModTime))
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.True(t, newModTime.Equal(o.ModTime(ctx)), o.ModTime(ctx))
+
+	// Without the option the mtime can't be set
+	m["modtime_prop"] = "false"
+	f, err = webdav.NewFs(ctx, remoteName, "", m)
+	require.NoError(t, err)
+	assert.Equal(t, fs.ModTimeNotSupported, f.Precision())
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, fs.ErrorCantSetModTime, o.SetModTime(ctx, modTime))
+}
@@ -38,6 +38,7 @@ docs = [
     "compress.md",
     "dropbox.md",
     "filefabric.md",
+    "external.md",
     "ftp.md",
     "googlecloudstorage.md",
     "drive.md",
@@ -67,6 +67,11 @@ Where the fields are:
 Not all backends print all fields. Information is not included if it is not
 provided by a backend. Where the value is unlimited it is omitted.
 
+Some backends (e.g. local and sftp) report the usage of the file system
+the path is on, so ` + "`rclone about remote:path`" + ` can give different
+results for different paths if they are on different disks. Most
+backends report the usage of the whole account whatever path is given.
+
 Applying a ` + "`--full`" + ` flag to the command prints the bytes in full, e.g.
 
     Total:   18253611008
@@ -85,6 +90,11 @@ A ` + "`--json`" + ` flag generates conveniently computer readable output, e.g.
         "free": 1411001220
     }
 
+The JSON keys are always the ones above (plus "objects") and are the
+same for all backends. All the values are in bytes, except "objects"
+which is a count. Keys for information the backend doesn't provide, or
+which is unlimited, are left out.
+
 Not all backends support the ` + "`rclone about`" + ` command.
 
 See [List of backends that do not support about](https://rclone.org/overview/#optional-features)
@@ -0,0 +1,345 @@
+// Package backup provides the backup command.
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/sync"
+	"github.com/spf13/cobra"
+)
+
+var (
+	planPath = ""
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.StringVarP(cmdFlags, &planPath, "plan", "", planPath, "Path to the backup plan (default \"backup.yaml\" in the config directory)")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "backup [job]*",
+	Short: `Run the backup jobs described in a backup plan.`,
+	Long: `
+Run the backup jobs in a declarative backup plan, pruning old
+snapshots according to each job's retention policy, then report an
+aggregate result.
+
+The plan is read from "backup.yaml" in the same directory as the
+config file unless --plan is given.  If job names are given as
+arguments then only those jobs are run, otherwise all the jobs in the
+plan are run in the order they are listed.
+
+An example plan
+
+    log_dir: /var/log/rclone-backup
+    jobs:
+      - name: photos
+        source: /home/user/Photos
+        destinations:
+          - gdrive:backup/photos
+          - s3:bucket/photos
+        snapshots: true
+        filters:
+          - "- *.tmp"
+          - "- .thumbnails/**"
+        retention:
+          keep_last: 7
+          max_age: 90d
+      - name: documents
+        source: /home/user/Documents
+        destinations:
+          - onedrive:backup/documents
+        mode: copy
+
+Each job has these keys
+
+- name - the name of the job (required) - this is used in the log file
+  name so must not contain "/" or "\" or be "." or ".."
+- source - the path to back up (required)
+- destinations - a list of paths to back up to (required)
+- mode - "sync" (the default) to make the destination identical to the
+  source or "copy" to never delete files on the destination
+- snapshots - if true then each run is written into a new directory
+  named after the time of the run, e.g. "20210501T120000Z", within each
+  destination.  Unchanged files are server-side copied from the
+  previous snapshot where the destination supports it, as with
+  --copy-dest.  All the destinations of a job use the same snapshot
+  name for a run.
+- filters - a list of filter rules as used by --filter.  These replace
+  any filters given on the command line for the job.
+- filter_from - a list of files to read filter rules from as used by
+  --filter-from
+- create_empty_src_dirs - create empty source directories on the
+  destination
+- retention - only valid with snapshots.  "keep_last" keeps that many
+  of the most recent snapshots and "max_age" removes snapshots older
+  than the duration given.  The newest snapshot is never removed.
+
+If log_dir is set then the log output of each job is also written to a
+file named after the job and the time of the run in that directory.
+
+All the other rclone flags, e.g. --dry-run, --transfers and
+--bwlimit, apply to every job.
+
+If any job fails then the remaining jobs are still run and rclone
+exits with an error once they have finished.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 1e6, command, args)
+		path := planPath
+		if path == "" {
+			path = filepath.Join(filepath.Dir(config.GetConfigPath()), "backup.yaml")
+		}
+		cmd.Run(false, true, command, func() error {
+			plan, err := LoadPlan(path)
+			if err != nil {
+				return err
+			}
+			jobs, err := plan.Select(args)
+			if err != nil {
+				return err
+			}
+			results := plan.Run(context.Background(), jobs)
+			printResults(results)
+			return resultsError(results)
+		})
+	},
+}
+
+// Result is the result of backing up a job to one destination
+type Result struct {
+	Job         string
+	Destination string
+	Snapshot    string
+	Duration    time.Duration
+	Transfers   int64
+	Bytes       int64
+	Errors      int64
+	Pruned      int
+	Err         error
+}
+
+// counters reads the global transfer counters
+func counters() (transfers, bytes, errs int64) {
+	stats, _ := accounting.GlobalStats().RemoteStats()
+	transfers, _ = stats["transfers"].(int64)
+	bytes, _ = stats["bytes"].(int64)
+	errs, _ = stats["errors"].(int64)
+	return transfers, bytes, errs
+}
+
+// Run runs the jobs given returning a result for each destination
+func (plan *Plan) Run(ctx context.Context, jobs []Job) (results []Result) {
+	for _, job := range jobs {
+		results = append(results, plan.runJob(ctx, job)...)
+	}
+	return results
+}
+
+// runJob runs a single job with its own log file if configured
+func (plan *Plan) runJob(ctx context.Context, job Job) (results []Result) {
+	now := time.Now()
+	snapshot := ""
+	if job.Snapshots {
+		snapshot = snapshotName(now)
+	}
+	if plan.LogDir != "" {
+		stop, err := startJobLog(plan.LogDir, job.Name, now)
+		if err != nil {
+			fs.Errorf(nil, "backup %q: %v", job.Name, err)
+		} else {
+			defer stop()
+		}
+	}
+	fs.Logf(nil, "backup %q: starting", job.Name)
+
+	ctx, err := job.addFilters(ctx)
+	if err != nil {
+		for _, dest := range job.Destinations {
+			results = append(results, Result{Job: job.Name, Destination: dest, Err: err})
+		}
+		return results
+	}
+	for _, dest := range job.Destinations {
+		result := Result{
+			Job:         job.Name,
+			Destination: dest,
+			Snapshot:    snapshot,
+		}
+		startTransfers, startBytes, startErrors := counters()
+		start := time.Now()
+		result.Pruned, result.Err = job.backup(ctx, dest, snapshot, now)
+		result.Duration = time.Since(start)
+		endTransfers, endBytes, endErrors := counters()
+		result.Transfers = endTransfers - startTransfers
+		result.Bytes = endBytes - startBytes
+		result.Errors = endErrors - startErrors
+		if result.Err != nil {
+			fs.Errorf(nil, "backup %q to %q: failed: %v", job.Name, dest, result.Err)
+		} else {
+			fs.Logf(nil, "backup %q to %q: done in %v", job.Name, dest, result.Duration.Truncate(time.Millisecond))
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// addFilters returns a context with the job's filters if it has any
+func (job *Job) addFilters(ctx context.Context) (context.Context, error) {
+	if len(job.Filters) == 0 && len(job.FilterFrom) == 0 {
+		return ctx, nil
+	}
+	opt := filter.DefaultOpt
+	opt.FilterRule = job.Filters
+	opt.FilterFrom = job.FilterFrom
+	fi, err := filter.NewFilter(&opt)
+	if err != nil {
+		return ctx, errors.Wrap(err, "bad filters")
+	}
+	return filter.ReplaceConfig(ctx, fi), nil
+}
+
+// backup runs the job for a single destination returning the number
+// of snapshots pruned
+func (job *Job) backup(ctx context.Context, dest, snapshot string, now time.Time) (pruned int, err error) {
+	fsrc, err := cache.Get(ctx, job.Source)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to make source")
+	}
+	if snapshot == "" {
+		fdst, err := cache.Get(ctx, dest)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to make destination")
+		}
+		if job.Mode == modeCopy {
+			return 0, sync.CopyDir(ctx, fdst, fsrc, job.CreateEmptySrcDirs)
+		}
+		return 0, sync.Sync(ctx, fdst, fsrc, job.CreateEmptySrcDirs)
+	}
+
+	// Find the existing snapshots
+	froot, err := cache.Get(ctx, dest)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to make destination")
+	}
+	names, err := snapshotDirs(ctx, froot)
+	if err != nil {
+		return 0, err
+	}
+
+	// Copy into the new snapshot using the previous one as
+	// --copy-dest if the destination can server-side copy
+	fdst, err := cache.Get(ctx, fspath.JoinRootPath(dest, snapshot))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to make snapshot destination")
+	}
+	if previous, _ := parseSnapshots(names); len(previous) > 0 && fdst.Features().Copy != nil {
+		var ci *fs.ConfigInfo
+		ctx, ci = fs.AddConfig(ctx)
+		ci.CopyDest = []string{fspath.JoinRootPath(dest, previous[0])}
+		fs.Infof(nil, "backup %q: using %q as previous snapshot", job.Name, ci.CopyDest[0])
+	}
+	err = sync.CopyDir(ctx, fdst, fsrc, job.CreateEmptySrcDirs)
+	if err != nil {
+		return 0, err
+	}
+
+	// Prune old snapshots only if the backup succeeded making sure
+	// no filters are active so whole snapshots are removed
+	noFilter, err := filter.NewFilter(nil)
+	if err != nil {
+		return 0, err
+	}
+	ctx = filter.ReplaceConfig(ctx, noFilter)
+	for _, name := range job.Retention.prune(append(names, snapshot), now) {
+		fs.Infof(nil, "backup %q: removing old snapshot %q from %q", job.Name, name, dest)
+		err = operations.Purge(ctx, froot, name)
+		if err != nil {
+			return pruned, errors.Wrapf(err, "failed to remove old snapshot %q", name)
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// snapshotDirs returns the names of the directories in the root of f
+func snapshotDirs(ctx context.Context, f fs.Fs) (names []string, err error) {
+	entries, err := f.List(ctx, "")
+	if err == fs.ErrorDirNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to list snapshots")
+	}
+	for _, entry := range entries {
+		if _, ok := entry.(fs.Directory); ok {
+			names = append(names, entry.Remote())
+		}
+	}
+	return names, nil
+}
+
+// startJobLog copies the log output to a file for the job until the
+// returned function is called
+func startJobLog(dir, name string, now time.Time) (stop func(), err error) {
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make log directory")
+	}
+	logPath := filepath.Join(dir, fmt.Sprintf("%s-%s.log", name, snapshotName(now)))
+	out, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open log file")
+	}
+	oldLogPrint := fs.LogPrint
+	fs.LogPrint = func(level fs.LogLevel, text string) {
+		oldLogPrint(level, text)
+		_, _ = fmt.Fprintf(out, "%s %-6s: %s\n", time.Now().Format("2006/01/02 15:04:05"), level, text)
+	}
+	return func() {
+		fs.LogPrint = oldLogPrint
+		_ = out.Close()
+	}, nil
+}
+
+// printResults prints a summary of the results
+func printResults(results []Result) {
+	fmt.Printf("%-20s %-40s %-8s %10s %10s %6s %6s %s\n", "JOB", "DESTINATION", "STATUS", "TRANSFERS", "BYTES", "ERRORS", "PRUNED", "DURATION")
+	for _, r := range results {
+		status := "OK"
+		if r.Err != nil {
+			status = "FAILED"
+		}
+		fmt.Printf("%-20s %-40s %-8s %10d %10s %6d %6d %v\n", r.Job, r.Destination, status, r.Transfers, fs.SizeSuffix(r.Bytes), r.Errors, r.Pruned, r.Duration.Truncate(time.Millisecond))
+	}
+}
+
+// resultsError returns an error summarising any failures in results
+func resultsError(results []Result) error {
+	var failed []string
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s to %s", r.Job, r.Destination))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return errors.Errorf("%d of %d backups failed: %s", len(failed), len(results), strings.Join(failed, ", "))
+}
@@ -0,0 +1,181 @@
+package backup
+
+import (
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"gopkg.in/yaml.v2"
+)
+
+// snapshotFormat is the time format used to name snapshot directories
+//
+// It sorts lexically in time order
+const snapshotFormat = "20060102T150405Z"
+
+// Plan is a declarative backup plan as read from YAML
+type Plan struct {
+	LogDir string `yaml:"log_dir"` // directory for per job logs - if not set no logs are written
+	Jobs   []Job  `yaml:"jobs"`
+}
+
+// Job is a single backup job in the plan
+type Job struct {
+	Name               string    `yaml:"name"`
+	Source             string    `yaml:"source"`
+	Destinations       []string  `yaml:"destinations"`
+	Mode               string    `yaml:"mode"`      // sync (default) or copy
+	Snapshots          bool      `yaml:"snapshots"` // write each run into a new timestamped directory
+	Filters            []string  `yaml:"filters"`   // filter rules, e.g. "- *.tmp"
+	FilterFrom         []string  `yaml:"filter_from"`
+	CreateEmptySrcDirs bool      `yaml:"create_empty_src_dirs"`
+	Retention          Retention `yaml:"retention"`
+}
+
+// Retention controls which snapshots are kept
+type Retention struct {
+	KeepLast int    `yaml:"keep_last"` // keep this many most recent snapshots - 0 for all
+	MaxAge   string `yaml:"max_age"`   // remove snapshots older than this, e.g. "30d"
+
+	maxAge time.Duration
+}
+
+// Job modes
+const (
+	modeSync = "sync"
+	modeCopy = "copy"
+)
+
+// LoadPlan reads and validates the plan in path
+func LoadPlan(path string) (*Plan, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read backup plan")
+	}
+	return ParsePlan(data)
+}
+
+// ParsePlan parses and validates the YAML plan in data
+func ParsePlan(data []byte) (*Plan, error) {
+	plan := new(Plan)
+	err := yaml.UnmarshalStrict(data, plan)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse backup plan")
+	}
+	if len(plan.Jobs) == 0 {
+		return nil, errors.New("backup plan has no jobs")
+	}
+	seen := map[string]bool{}
+	for i := range plan.Jobs {
+		job := &plan.Jobs[i]
+		if job.Name == "" {
+			return nil, errors.Errorf("backup job %d has no name", i+1)
+		}
+		// the name is used in the log file name so mustn't be a path
+		if job.Name == "." || job.Name == ".." || strings.ContainsAny(job.Name, `/\`) {
+			return nil, errors.Errorf("backup job name %q must not contain / or \\ or be . or ..", job.Name)
+		}
+		if seen[job.Name] {
+			return nil, errors.Errorf("duplicate backup job name %q", job.Name)
+		}
+		seen[job.Name] = true
+		if err := job.validate(); err != nil {
+			return nil, errors.Wrapf(err, "backup job %q", job.Name)
+		}
+	}
+	return plan, nil
+}
+
+// validate checks the job and fills in defaults
+func (job *Job) validate() error {
+	if job.Source == "" {
+		return errors.New("no source")
+	}
+	if len(job.Destinations) == 0 {
+		return errors.New("no destinations")
+	}
+	switch job.Mode {
+	case "":
+		job.Mode = modeSync
+	case modeSync, modeCopy:
+	default:
+		return errors.Errorf("unknown mode %q - must be %q or %q", job.Mode, modeSync, modeCopy)
+	}
+	if job.Retention.KeepLast < 0 {
+		return errors.New("keep_last must not be negative")
+	}
+	if job.Retention.MaxAge != "" {
+		d, err := fs.ParseDuration(job.Retention.MaxAge)
+		if err != nil {
+			return errors.Wrap(err, "bad max_age")
+		}
+		job.Retention.maxAge = d
+	}
+	if (job.Retention.KeepLast != 0 || job.Retention.maxAge != 0) && !job.Snapshots {
+		return errors.New("retention needs snapshots to be enabled")
+	}
+	return nil
+}
+
+// Select returns the jobs with the names given, or all the jobs if
+// no names are given.
+func (plan *Plan) Select(names []string) ([]Job, error) {
+	if len(names) == 0 {
+		return plan.Jobs, nil
+	}
+	var jobs []Job
+	for _, name := range names {
+		found := false
+		for _, job := range plan.Jobs {
+			if job.Name == name {
+				jobs = append(jobs, job)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("backup job %q not found in plan", name)
+		}
+	}
+	return jobs, nil
+}
+
+// snapshotName returns the name of the snapshot directory for t
+func snapshotName(t time.Time) string {
+	return t.UTC().Format(snapshotFormat)
+}
+
+// parseSnapshots returns the directory names which are snapshots
+// sorted newest first along with their times.
+func parseSnapshots(names []string) (snapshots []string, times []time.Time) {
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	for _, name := range names {
+		t, err := time.Parse(snapshotFormat, name)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, name)
+		times = append(times, t)
+	}
+	return snapshots, times
+}
+
+// prune returns the snapshots in names which the retention policy
+// says should be removed at time now.
+//
+// The newest snapshot is never removed.
+func (r *Retention) prune(names []string, now time.Time) (remove []string) {
+	snapshots, times := parseSnapshots(names)
+	for i, name := range snapshots {
+		if i == 0 {
+			continue
+		}
+		if (r.KeepLast > 0 && i >= r.KeepLast) || (r.maxAge > 0 && now.Sub(times[i]) > r.maxAge) {
+			remove = append(remove, name)
+		}
+	}
+	return remove
+}
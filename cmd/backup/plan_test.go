@@ -0,0 +1,156 @@
+package backup
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlan(t *testing.T) {
+	plan, err := ParsePlan([]byte(`
+log_dir: /tmp/logs
+jobs:
+  - name: photos
+    source: /photos
+    destinations: [remote:photos, other:photos]
+    snapshots: true
+    filters: ["- *.tmp"]
+    retention:
+      keep_last: 3
+      max_age: 30d
+  - name: docs
+    source: /docs
+    destinations: [remote:docs]
+    mode: copy
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/logs", plan.LogDir)
+	require.Len(t, plan.Jobs, 2)
+	assert.Equal(t, modeSync, plan.Jobs[0].Mode)
+	assert.Equal(t, []string{"remote:photos", "other:photos"}, plan.Jobs[0].Destinations)
+	assert.Equal(t, 30*24*time.Hour, plan.Jobs[0].Retention.maxAge)
+	assert.Equal(t, modeCopy, plan.Jobs[1].Mode)
+
+	jobs, err := plan.Select([]string{"docs"})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "docs", jobs[0].Name)
+	_, err = plan.Select([]string{"potato"})
+	assert.Error(t, err)
+	jobs, err = plan.Select(nil)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 2)
+}
+
+func TestParsePlanErrors(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"", "no jobs"},
+		{"jobs:\n  - source: /a\n    destinations: [b:]\n", "no name"},
+		{"jobs:\n  - name: a\n    destinations: [b:]\n", "no source"},
+		{"jobs:\n  - name: a\n    source: /a\n", "no destinations"},
+		{"jobs:\n  - name: a\n    source: /a\n    destinations: [b:]\n    mode: potato\n", "unknown mode"},
+		{"jobs:\n  - name: a\n    source: /a\n    destinations: [b:]\n    retention:\n      keep_last: 2\n", "needs snapshots"},
+		{"jobs:\n  - name: a\n    source: /a\n    destinations: [b:]\n    snapshots: true\n    retention:\n      max_age: potato\n", "bad max_age"},
+		{"jobs:\n  - name: a\n    source: /a\n    destinations: [b:]\n  - name: a\n    source: /a\n    destinations: [b:]\n", "duplicate"},
+		{"jobs:\n  - name: a\n    unknown: key\n", "failed to parse"},
+		{"jobs:\n  - name: ../a\n    source: /a\n    destinations: [b:]\n", "must not contain"},
+		{"jobs:\n  - name: a\\b\n    source: /a\n    destinations: [b:]\n", "must not contain"},
+		{"jobs:\n  - name: ..\n    source: /a\n    destinations: [b:]\n", "must not contain"},
+	} {
+		_, err := ParsePlan([]byte(test.in))
+		require.Error(t, err, test.in)
+		assert.Contains(t, err.Error(), test.want, test.in)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2021, 5, 10, 12, 0, 0, 0, time.UTC)
+	names := []string{
+		"20210510T110000Z",
+		"20210509T120000Z",
+		"not-a-snapshot",
+		"20210501T120000Z",
+		"20210301T120000Z",
+	}
+	r := Retention{}
+	assert.Nil(t, r.prune(names, now))
+
+	r = Retention{KeepLast: 2}
+	assert.Equal(t, []string{"20210501T120000Z", "20210301T120000Z"}, r.prune(names, now))
+
+	r = Retention{maxAge: 7 * 24 * time.Hour}
+	assert.Equal(t, []string{"20210501T120000Z", "20210301T120000Z"}, r.prune(names, now))
+
+	r = Retention{KeepLast: 3, maxAge: 30 * 24 * time.Hour}
+	assert.Equal(t, []string{"20210301T120000Z"}, r.prune(names, now))
+
+	// never removes the newest
+	r = Retention{maxAge: time.Minute}
+	assert.Equal(t, []string{"20210509T120000Z", "20210501T120000Z", "20210301T120000Z"}, r.prune(names, now))
+}
+
+func TestBackupSnapshots(t *testing.T) {
+	fstest.Initialise()
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "rclone-backup-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	require.NoError(t, os.MkdirAll(src, 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(src, "file.txt"), []byte("hello"), 0666))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(src, "file.tmp"), []byte("temporary"), 0666))
+
+	job := Job{
+		Name:         "test",
+		Source:       src,
+		Destinations: []string{dst},
+		Snapshots:    true,
+		Filters:      []string{"- *.tmp"},
+		Retention:    Retention{KeepLast: 2},
+	}
+	require.NoError(t, job.validate())
+	ctx, err = job.addFilters(ctx)
+	require.NoError(t, err)
+
+	now := time.Date(2021, 5, 10, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		snapshot := snapshotName(now.Add(time.Duration(i) * time.Hour))
+		pruned, err := job.backup(ctx, dst, snapshot, now)
+		require.NoError(t, err)
+		assert.Equal(t, i/2, pruned)
+		data, err := ioutil.ReadFile(filepath.Join(dst, snapshot, "file.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(data))
+		_, err = os.Stat(filepath.Join(dst, snapshot, "file.tmp"))
+		assert.True(t, os.IsNotExist(err))
+	}
+
+	entries, err := ioutil.ReadDir(dst)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"20210510T130000Z", "20210510T140000Z"}, names)
+}
+
+func TestResultsError(t *testing.T) {
+	assert.NoError(t, resultsError([]Result{{Job: "a", Destination: "b:"}}))
+	err := resultsError([]Result{{Job: "a", Destination: "b:"}, {Job: "a", Destination: "c:", Err: assert.AnError}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 2 backups failed: a to c:")
+}
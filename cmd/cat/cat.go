@@ -2,13 +2,17 @@ package cat
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/flags"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/spf13/cobra"
@@ -16,11 +20,13 @@ import (
 
 // Globals
 var (
-	head    = int64(0)
-	tail    = int64(0)
-	offset  = int64(0)
-	count   = int64(-1)
-	discard = false
+	head      = int64(0)
+	tail      = int64(0)
+	offset    = int64(0)
+	count     = int64(-1)
+	discard   = false
+	streams   = 1
+	benchmark = false
 )
 
 func init() {
@@ -31,6 +37,8 @@ func init() {
 	flags.Int64VarP(cmdFlags, &offset, "offset", "", offset, "Start printing at offset N (or from end if -ve).")
 	flags.Int64VarP(cmdFlags, &count, "count", "", count, "Only print N characters.")
 	flags.BoolVarP(cmdFlags, &discard, "discard", "", discard, "Discard the output instead of printing.")
+	flags.IntVarP(cmdFlags, &streams, "streams", "", streams, "Read each file with this many range requests at once.")
+	flags.BoolVarP(cmdFlags, &benchmark, "benchmark", "", benchmark, "Read the files with --streams streams discarding the data and print the speed of each.")
 }
 
 var commandDefinition = &cobra.Command{
@@ -56,6 +64,18 @@ Use the |--head| flag to print characters only at the start, |--tail| for
 the end and |--offset| and |--count| to print a section in the middle.
 Note that if offset is negative it will count from the end, so
 |--offset -1 --count 1| is equivalent to |--tail 1|.
+
+Use |--streams N| to read each file with N range requests at once
+which can be much quicker on backends which limit the speed of each
+connection. The parts are fetched in chunks and output in order so
+rclone needs memory for N chunks of 4 MiB.
+
+Use |--discard| to read the files without printing them, and
+|--benchmark| to read each file split into |--streams| equal parts at
+once and print the speed of each stream, which is useful for
+diagnosing slow backends, eg
+
+    rclone cat --benchmark --streams 4 --count 100M remote:path/to/file
 `, "|", "`"),
 	Run: func(command *cobra.Command, args []string) {
 		usedOffset := offset != 0 || count >= 0
@@ -78,8 +98,39 @@ Note that if offset is negative it will count from the end, so
 		if discard {
 			w = ioutil.Discard
 		}
+		if benchmark {
+			cmd.Run(false, false, command, func() error {
+				return runBenchmark(context.Background(), fsrc)
+			})
+			return
+		}
 		cmd.Run(false, false, command, func() error {
-			return operations.Cat(context.Background(), fsrc, w, offset, count)
+			return operations.CatStreams(context.Background(), fsrc, w, offset, count, streams)
 		})
 	},
 }
+
+// runBenchmark reads each file in fsrc printing the speed of each stream
+func runBenchmark(ctx context.Context, fsrc fs.Fs) error {
+	var mu sync.Mutex
+	return operations.ListFn(ctx, fsrc, func(o fs.Object) {
+		mu.Lock()
+		defer mu.Unlock()
+		start := time.Now()
+		stats, err := operations.BenchmarkRead(ctx, o, offset, count, streams)
+		if err != nil {
+			err = fs.CountError(err)
+			fs.Errorf(o, "Failed to benchmark: %v", err)
+			return
+		}
+		elapsed := time.Since(start)
+		var total int64
+		fmt.Printf("%s\n", o.Remote())
+		for _, stat := range stats {
+			total += stat.Bytes
+			fmt.Printf("  stream %2d: %10v in %-12v %v\n", stat.Stream, fs.SizeSuffix(stat.Bytes).ByteUnit(), stat.Duration.Truncate(time.Millisecond), fs.SizeSuffix(stat.Rate()).ByteRateUnit())
+		}
+		rate := float64(total) / elapsed.Seconds()
+		fmt.Printf("  total    : %10v in %-12v %v\n", fs.SizeSuffix(total).ByteUnit(), elapsed.Truncate(time.Millisecond), fs.SizeSuffix(rate).ByteRateUnit())
+	})
+}
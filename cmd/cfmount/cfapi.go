@@ -0,0 +1,249 @@
+// Bindings for the parts of the Windows Cloud Files API (cldapi.dll)
+// which cfmount uses. The structures follow cfapi.h for 64 bit
+// Windows with the padding C puts before each union made explicit.
+
+// +build windows,amd64
+
+package cfmount
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	cldapi               = windows.NewLazySystemDLL("cldapi.dll")
+	cfRegisterSyncRoot   = cldapi.NewProc("CfRegisterSyncRoot")
+	cfUnregisterSyncRoot = cldapi.NewProc("CfUnregisterSyncRoot")
+	cfConnectSyncRoot    = cldapi.NewProc("CfConnectSyncRoot")
+	cfDisconnectSyncRoot = cldapi.NewProc("CfDisconnectSyncRoot")
+	cfExecute            = cldapi.NewProc("CfExecute")
+	errNoCloudFilesAPI   = errors.New("the Windows Cloud Files API isn't available - it needs Windows 10 version 1709 or later")
+)
+
+// providerID identifies rclone as the sync provider
+var providerID = windows.GUID{Data1: 0x2d4f6a1e, Data2: 0x7c9b, Data3: 0x4e35, Data4: [8]byte{0x9b, 0x1a, 0x5c, 0x3e, 0x8d, 0x0f, 0x6a, 0x27}}
+
+// CF_CALLBACK_TYPE
+const (
+	cfCallbackTypeFetchData                = 0
+	cfCallbackTypeFetchPlaceholders        = 3
+	cfCallbackTypeNotifyDelete             = 9
+	cfCallbackTypeNotifyRename             = 11
+	cfCallbackTypeNone              uint32 = 0xffffffff
+)
+
+// CF_OPERATION_TYPE
+const (
+	cfOperationTypeTransferData         = 0
+	cfOperationTypeTransferPlaceholders = 4
+	cfOperationTypeAckDelete            = 6
+	cfOperationTypeAckRename            = 7
+)
+
+// Policies and flags
+const (
+	cfHydrationPolicyProgressive                      = 1
+	cfPopulationPolicyFull                            = 2
+	cfInSyncPolicyNone                                = 0
+	cfRegisterFlagUpdate                              = 0x1
+	cfRegisterFlagMarkInSyncOnRoot                    = 0x4
+	cfConnectFlagRequireFullFilePath                  = 0x4
+	cfPlaceholderCreateFlagMarkInSync                 = 0x2
+	cfTransferPlaceholdersFlagDisableOnDemandPopulate = 0x2
+	cfRenameFlagTargetInScope                         = 0x4
+)
+
+// NTSTATUS values to complete operations with
+const (
+	statusSuccess      = 0
+	statusUnsuccessful = 0xC0000001
+	statusAccessDenied = 0xC0000022
+)
+
+// CF_SYNC_REGISTRATION
+type cfSyncRegistration struct {
+	StructSize             uint32
+	ProviderName           *uint16
+	ProviderVersion        *uint16
+	SyncRootIdentity       uintptr
+	SyncRootIdentityLength uint32
+	FileIdentity           uintptr
+	FileIdentityLength     uint32
+	ProviderID             windows.GUID
+}
+
+// CF_SYNC_POLICIES
+type cfSyncPolicies struct {
+	StructSize            uint32
+	HydrationPrimary      uint16
+	HydrationModifier     uint16
+	PopulationPrimary     uint16
+	PopulationModifier    uint16
+	InSync                uint32
+	HardLink              uint32
+	PlaceholderManagement uint32
+}
+
+// CF_CALLBACK_REGISTRATION
+type cfCallbackRegistration struct {
+	Type     uint32
+	Callback uintptr
+}
+
+// CF_CALLBACK_INFO
+type cfCallbackInfo struct {
+	StructSize             uint32
+	ConnectionKey          int64
+	CallbackContext        uintptr
+	VolumeGUIDName         *uint16
+	VolumeDosName          *uint16
+	VolumeSerialNumber     uint32
+	SyncRootFileID         int64
+	SyncRootIdentity       uintptr
+	SyncRootIdentityLength uint32
+	FileID                 int64
+	FileSize               int64
+	FileIdentity           uintptr
+	FileIdentityLength     uint32
+	NormalizedPath         *uint16
+	TransferKey            int64
+	PriorityHint           uint8
+	CorrelationVector      uintptr
+	ProcessInfo            uintptr
+	RequestKey             int64
+}
+
+// CF_CALLBACK_PARAMETERS for CF_CALLBACK_TYPE_FETCH_DATA
+type cfFetchDataParams struct {
+	ParamSize          uint32
+	_                  uint32
+	Flags              uint32
+	RequiredFileOffset int64
+	RequiredLength     int64
+	OptionalFileOffset int64
+	OptionalLength     int64
+}
+
+// CF_CALLBACK_PARAMETERS for CF_CALLBACK_TYPE_NOTIFY_RENAME
+type cfRenameParams struct {
+	ParamSize  uint32
+	_          uint32
+	Flags      uint32
+	TargetPath *uint16
+}
+
+// CF_OPERATION_INFO
+type cfOperationInfo struct {
+	StructSize        uint32
+	Type              uint32
+	ConnectionKey     int64
+	TransferKey       int64
+	CorrelationVector uintptr
+	SyncStatus        uintptr
+	RequestKey        int64
+}
+
+// CF_OPERATION_PARAMETERS for CF_OPERATION_TYPE_TRANSFER_DATA
+type cfTransferDataParams struct {
+	ParamSize        uint32
+	_                uint32
+	Flags            uint32
+	CompletionStatus uint32
+	Buffer           uintptr
+	Offset           int64
+	Length           int64
+}
+
+// CF_OPERATION_PARAMETERS for CF_OPERATION_TYPE_TRANSFER_PLACEHOLDERS
+type cfTransferPlaceholdersParams struct {
+	ParamSize             uint32
+	_                     uint32
+	Flags                 uint32
+	CompletionStatus      uint32
+	PlaceholderTotalCount int64
+	PlaceholderArray      *cfPlaceholderCreateInfo
+	PlaceholderCount      uint32
+	EntriesProcessed      uint32
+}
+
+// CF_OPERATION_PARAMETERS for CF_OPERATION_TYPE_ACK_DELETE and
+// CF_OPERATION_TYPE_ACK_RENAME
+type cfAckParams struct {
+	ParamSize        uint32
+	_                uint32
+	Flags            uint32
+	CompletionStatus uint32
+}
+
+// CF_PLACEHOLDER_CREATE_INFO
+type cfPlaceholderCreateInfo struct {
+	RelativeFileName   *uint16
+	CreationTime       int64
+	LastAccessTime     int64
+	LastWriteTime      int64
+	ChangeTime         int64
+	FileAttributes     uint32
+	FileSize           int64
+	FileIdentity       uintptr
+	FileIdentityLength uint32
+	Flags              uint32
+	Result             int32
+	CreateUsn          int64
+}
+
+// hresultError turns the HRESULT r returned by the function called
+// name into an error or nil if it succeeded
+func hresultError(name string, r uintptr) error {
+	hr := uint32(r)
+	if int32(hr) >= 0 {
+		return nil
+	}
+	// HRESULT_FROM_WIN32
+	if hr&0xffff0000 == 0x80070000 {
+		return errors.Wrap(windows.Errno(hr&0xffff), name)
+	}
+	return errors.Errorf("%s failed: HRESULT 0x%08X", name, hr)
+}
+
+// loadCloudFilesAPI checks the Cloud Files API is available
+func loadCloudFilesAPI() error {
+	if cldapi.Load() != nil || cfExecute.Find() != nil {
+		return errNoCloudFilesAPI
+	}
+	return nil
+}
+
+// execute calls CfExecute for the operation opType on the file
+// described by key with the parameters in params which must point to
+// one of the cf*Params structures
+func execute(key *operationKey, opType uint32, params unsafe.Pointer) error {
+	info := cfOperationInfo{
+		Type:          opType,
+		ConnectionKey: key.connectionKey,
+		TransferKey:   key.transferKey,
+		RequestKey:    key.requestKey,
+	}
+	info.StructSize = uint32(unsafe.Sizeof(info))
+	r, _, _ := cfExecute.Call(uintptr(unsafe.Pointer(&info)), uintptr(params))
+	return hresultError("CfExecute", r)
+}
+
+// operationKey identifies the request a callback is for so an
+// operation can be executed for it after the callback has returned
+type operationKey struct {
+	connectionKey int64
+	transferKey   int64
+	requestKey    int64
+}
+
+// newOperationKey copies the keys out of info
+func newOperationKey(info *cfCallbackInfo) *operationKey {
+	return &operationKey{
+		connectionKey: info.ConnectionKey,
+		transferKey:   info.TransferKey,
+		requestKey:    info.RequestKey,
+	}
+}
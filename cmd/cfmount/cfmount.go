@@ -0,0 +1,480 @@
+// Package cfmount implements a mount for rclone remotes using the
+// Windows Cloud Files API, the same API OneDrive's Files On-Demand
+// uses.
+//
+// Rather than serving a file system like the FUSE mounts do, this
+// makes the mountpoint a sync root where each file is a placeholder
+// which Windows hydrates by calling back into rclone for the data.
+
+// +build windows,amd64
+
+package cfmount
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd/mountlib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+	"golang.org/x/sys/windows"
+)
+
+func init() {
+	cmd := mountlib.NewMountCommand("cfmount", false, mount)
+	cmd.Short = `Mount the remote as placeholder files using the Windows Cloud Files API.`
+	cmd.Long = strings.Replace(longHelp, "|", "`", -1) + cmd.Long
+	mountlib.AddRc("cfmount", mount)
+}
+
+const longHelp = `
+rclone cfmount makes the mountpoint a sync root of the Windows Cloud
+Files API, the API OneDrive uses for Files On-Demand, rather than a
+file system served through WinFsp. It needs Windows 10 version 1709 or
+later and a 64 bit build of rclone. The mountpoint must be a directory
+on an NTFS volume and will be made if it doesn't exist.
+
+    rclone cfmount remote:path/to/files C:\Users\user\remote
+
+Each file and directory on the remote appears as a placeholder which
+takes no space until it is opened. Windows then asks rclone for the
+data, which is read through the VFS, and the file stays hydrated
+locally afterwards. Explorer can be used to free up the space again
+with "Free up space". Directories are listed from the remote the first
+time they are opened.
+
+Deleting or renaming placeholders deletes or renames the files on the
+remote, unless |--read-only| is set in which case it is refused.
+Files which are created or modified locally are not uploaded, so use
+|rclone mount| if the remote needs to be written to.
+
+When rclone cfmount stops the sync root is unregistered. Files which
+were hydrated stay in the directory but placeholders which weren't
+can no longer be opened.
+
+The rest of this help is for |rclone mount|. The VFS flags apply to
+cfmount but the flags for FUSE and WinFsp are ignored.
+`
+
+// chunkSize is the most data transferred in one go when hydrating
+const chunkSize = 1024 * 1024
+
+// syncRoot is a directory registered with the Cloud Files API and
+// connected to a VFS
+type syncRoot struct {
+	VFS           *vfs.VFS
+	path          string // absolute path of the sync root
+	volumePath    string // path of the sync root within its volume
+	id            uintptr
+	connectionKey int64
+}
+
+var (
+	rootsMu   sync.Mutex
+	roots     = map[uintptr]*syncRoot{}
+	lastID    uintptr
+	callbacks []cfCallbackRegistration
+)
+
+// newSyncRoot makes a syncRoot for mountpoint, creating the directory
+// if necessary
+func newSyncRoot(VFS *vfs.VFS, mountpoint string) (*syncRoot, error) {
+	absPath, err := filepath.Abs(mountpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find mountpoint")
+	}
+	err = os.MkdirAll(absPath, 0777)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make mountpoint")
+	}
+	return &syncRoot{
+		VFS:        VFS,
+		path:       absPath,
+		volumePath: strings.TrimRight(absPath[len(filepath.VolumeName(absPath)):], `\`),
+	}, nil
+}
+
+// register registers the directory as a sync root, updating any
+// earlier registration
+func (r *syncRoot) register() error {
+	identity := []byte(fs.ConfigString(r.VFS.Fs()))
+	reg := cfSyncRegistration{
+		ProviderName:           windows.StringToUTF16Ptr("rclone"),
+		ProviderVersion:        windows.StringToUTF16Ptr(fs.Version),
+		SyncRootIdentity:       uintptr(unsafe.Pointer(&identity[0])),
+		SyncRootIdentityLength: uint32(len(identity)),
+		ProviderID:             providerID,
+	}
+	reg.StructSize = uint32(unsafe.Sizeof(reg))
+	policies := cfSyncPolicies{
+		HydrationPrimary:  cfHydrationPolicyProgressive,
+		PopulationPrimary: cfPopulationPolicyFull,
+		InSync:            cfInSyncPolicyNone,
+	}
+	policies.StructSize = uint32(unsafe.Sizeof(policies))
+	r0, _, _ := cfRegisterSyncRoot.Call(
+		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(r.path))),
+		uintptr(unsafe.Pointer(&reg)),
+		uintptr(unsafe.Pointer(&policies)),
+		cfRegisterFlagUpdate|cfRegisterFlagMarkInSyncOnRoot,
+	)
+	runtime.KeepAlive(identity)
+	return hresultError("CfRegisterSyncRoot", r0)
+}
+
+// unregister removes the sync root registration
+func (r *syncRoot) unregister() error {
+	r0, _, _ := cfUnregisterSyncRoot.Call(uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(r.path))))
+	return hresultError("CfUnregisterSyncRoot", r0)
+}
+
+// connect starts receiving the callbacks for the sync root
+func (r *syncRoot) connect() error {
+	rootsMu.Lock()
+	if callbacks == nil {
+		callbacks = []cfCallbackRegistration{
+			{Type: cfCallbackTypeFetchData, Callback: windows.NewCallback(onFetchData)},
+			{Type: cfCallbackTypeFetchPlaceholders, Callback: windows.NewCallback(onFetchPlaceholders)},
+			{Type: cfCallbackTypeNotifyDelete, Callback: windows.NewCallback(onNotifyDelete)},
+			{Type: cfCallbackTypeNotifyRename, Callback: windows.NewCallback(onNotifyRename)},
+			{Type: cfCallbackTypeNone},
+		}
+	}
+	lastID++
+	r.id = lastID
+	roots[r.id] = r
+	rootsMu.Unlock()
+	r0, _, _ := cfConnectSyncRoot.Call(
+		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(r.path))),
+		uintptr(unsafe.Pointer(&callbacks[0])),
+		r.id,
+		cfConnectFlagRequireFullFilePath,
+		uintptr(unsafe.Pointer(&r.connectionKey)),
+	)
+	err := hresultError("CfConnectSyncRoot", r0)
+	if err != nil {
+		r.forget()
+	}
+	return err
+}
+
+// disconnect stops receiving the callbacks for the sync root
+func (r *syncRoot) disconnect() error {
+	r0, _, _ := cfDisconnectSyncRoot.Call(uintptr(r.connectionKey))
+	r.forget()
+	return hresultError("CfDisconnectSyncRoot", r0)
+}
+
+// forget removes the sync root from the callback lookup
+func (r *syncRoot) forget() {
+	rootsMu.Lock()
+	delete(roots, r.id)
+	rootsMu.Unlock()
+}
+
+// lookup finds the sync root and the path within it of the file a
+// callback is for
+//
+// It returns a nil syncRoot if the callback should be ignored.
+func lookup(info *cfCallbackInfo) (r *syncRoot, path string) {
+	rootsMu.Lock()
+	r = roots[info.CallbackContext]
+	rootsMu.Unlock()
+	if r == nil {
+		return nil, ""
+	}
+	path, ok := r.relative(windows.UTF16PtrToString(info.NormalizedPath))
+	if !ok {
+		fs.Errorf(r.path, "Callback for file outside the sync root: %q", windows.UTF16PtrToString(info.NormalizedPath))
+		return nil, ""
+	}
+	return r, path
+}
+
+// relative returns the VFS path for volumePath, a path relative to
+// the root of the volume, and whether it is in the sync root
+func (r *syncRoot) relative(volumePath string) (path string, ok bool) {
+	if len(volumePath) < len(r.volumePath) || !strings.EqualFold(volumePath[:len(r.volumePath)], r.volumePath) {
+		return "", false
+	}
+	rest := volumePath[len(r.volumePath):]
+	if rest != "" && rest[0] != '\\' {
+		return "", false
+	}
+	return strings.Trim(filepath.ToSlash(rest), "/"), true
+}
+
+// onFetchData is called when Windows needs the data for a placeholder
+func onFetchData(info *cfCallbackInfo, params *cfFetchDataParams) uintptr {
+	r, path := lookup(info)
+	if r != nil {
+		go r.fetchData(newOperationKey(info), path, params.RequiredFileOffset, params.RequiredLength)
+	}
+	return 0
+}
+
+// onFetchPlaceholders is called when a directory is opened which
+// hasn't been listed yet
+func onFetchPlaceholders(info *cfCallbackInfo, params uintptr) uintptr {
+	r, path := lookup(info)
+	if r != nil {
+		go r.fetchPlaceholders(newOperationKey(info), path)
+	}
+	return 0
+}
+
+// onNotifyDelete is called before a placeholder is deleted
+func onNotifyDelete(info *cfCallbackInfo, params uintptr) uintptr {
+	r, path := lookup(info)
+	if r != nil {
+		go r.notifyDelete(newOperationKey(info), path)
+	}
+	return 0
+}
+
+// onNotifyRename is called before a placeholder is renamed or moved
+func onNotifyRename(info *cfCallbackInfo, params *cfRenameParams) uintptr {
+	r, path := lookup(info)
+	if r != nil {
+		newPath, ok := r.relative(windows.UTF16PtrToString(params.TargetPath))
+		if params.Flags&cfRenameFlagTargetInScope == 0 {
+			ok = false
+		}
+		go r.notifyRename(newOperationKey(info), path, newPath, ok)
+	}
+	return 0
+}
+
+// transferData sends length bytes at offset in buf to Windows, or
+// fails the range if status isn't statusSuccess
+func transferData(key *operationKey, buf []byte, offset, length int64, status uint32) error {
+	params := cfTransferDataParams{
+		CompletionStatus: status,
+		Offset:           offset,
+		Length:           length,
+	}
+	if len(buf) > 0 {
+		params.Buffer = uintptr(unsafe.Pointer(&buf[0]))
+	}
+	params.ParamSize = uint32(unsafe.Sizeof(params))
+	err := execute(key, cfOperationTypeTransferData, unsafe.Pointer(&params))
+	runtime.KeepAlive(buf)
+	return err
+}
+
+// fetchData reads length bytes at offset from the file at path and
+// transfers them to Windows
+func (r *syncRoot) fetchData(key *operationKey, path string, offset, length int64) {
+	fs.Debugf(path, "cfmount: hydrate %d bytes at %d", length, offset)
+	err := r.readData(key, path, offset, length)
+	if err != nil {
+		fs.Errorf(path, "cfmount: failed to hydrate: %v", err)
+	}
+}
+
+// readData does the work for fetchData, failing the rest of the range
+// if there is an error reading it
+func (r *syncRoot) readData(key *operationKey, path string, offset, length int64) (err error) {
+	end := offset + length
+	defer func() {
+		if err != nil && offset < end {
+			_ = transferData(key, nil, offset, end-offset, statusUnsuccessful)
+		}
+	}()
+	handle, err := r.VFS.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(handle, &err)
+	buf := make([]byte, chunkSize)
+	for offset < end {
+		n := int64(len(buf))
+		if end-offset < n {
+			n = end - offset
+		}
+		read, readErr := handle.ReadAt(buf[:n], offset)
+		if read > 0 {
+			err = transferData(key, buf[:read], offset, int64(read), statusSuccess)
+			if err != nil {
+				return err
+			}
+			offset += int64(read)
+		}
+		if readErr == io.EOF {
+			if offset < end {
+				return errors.Errorf("file is %d bytes shorter than expected", end-offset)
+			}
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	return nil
+}
+
+// fileTime converts t into a FILETIME
+func fileTime(t time.Time) int64 {
+	ft := windows.NsecToFiletime(t.UnixNano())
+	return int64(ft.HighDateTime)<<32 | int64(ft.LowDateTime)
+}
+
+// fetchPlaceholders lists the directory at path and transfers a
+// placeholder for each item in it to Windows
+func (r *syncRoot) fetchPlaceholders(key *operationKey, path string) {
+	fs.Debugf(path, "cfmount: list directory")
+	var items vfs.Nodes
+	node, err := r.VFS.Stat(path)
+	if err == nil {
+		dir, ok := node.(*vfs.Dir)
+		if !ok {
+			err = errors.Errorf("%q is not a directory", path)
+		} else {
+			items, err = dir.ReadDirAll()
+		}
+	}
+	params := cfTransferPlaceholdersParams{
+		Flags: cfTransferPlaceholdersFlagDisableOnDemandPopulate,
+	}
+	params.ParamSize = uint32(unsafe.Sizeof(params))
+	if err != nil {
+		fs.Errorf(path, "cfmount: failed to list directory: %v", err)
+		params.CompletionStatus = statusUnsuccessful
+		items = nil
+	}
+	var (
+		infos      = make([]cfPlaceholderCreateInfo, len(items))
+		identities = make([][]byte, len(items))
+	)
+	for i, item := range items {
+		info := &infos[i]
+		modTime := fileTime(item.ModTime())
+		identities[i] = []byte(item.Path())
+		info.RelativeFileName = windows.StringToUTF16Ptr(item.Name())
+		info.CreationTime = modTime
+		info.LastAccessTime = modTime
+		info.LastWriteTime = modTime
+		info.ChangeTime = modTime
+		info.FileIdentity = uintptr(unsafe.Pointer(&identities[i][0]))
+		info.FileIdentityLength = uint32(len(identities[i]))
+		info.Flags = cfPlaceholderCreateFlagMarkInSync
+		if item.IsDir() {
+			info.FileAttributes = windows.FILE_ATTRIBUTE_DIRECTORY
+		} else {
+			info.FileAttributes = windows.FILE_ATTRIBUTE_NORMAL
+			info.FileSize = item.Size()
+		}
+		if r.VFS.Opt.ReadOnly {
+			info.FileAttributes |= windows.FILE_ATTRIBUTE_READONLY
+		}
+	}
+	if len(infos) > 0 {
+		params.PlaceholderArray = &infos[0]
+	}
+	params.PlaceholderTotalCount = int64(len(infos))
+	params.PlaceholderCount = uint32(len(infos))
+	err = execute(key, cfOperationTypeTransferPlaceholders, unsafe.Pointer(&params))
+	runtime.KeepAlive(identities)
+	if err != nil {
+		fs.Errorf(path, "cfmount: failed to transfer placeholders: %v", err)
+		return
+	}
+	for i := range infos {
+		if infos[i].Result < 0 {
+			fs.Errorf(items[i].Path(), "cfmount: failed to create placeholder: %v", hresultError("CfExecute", uintptr(uint32(infos[i].Result))))
+		}
+	}
+}
+
+// ack acknowledges the delete or rename of the file at path,
+// refusing it if err is set
+func ack(key *operationKey, opType uint32, path string, err error) {
+	params := cfAckParams{}
+	params.ParamSize = uint32(unsafe.Sizeof(params))
+	if err != nil {
+		fs.Errorf(path, "cfmount: %v", err)
+		params.CompletionStatus = statusUnsuccessful
+		if err == vfs.EROFS {
+			params.CompletionStatus = statusAccessDenied
+		}
+	}
+	err = execute(key, opType, unsafe.Pointer(&params))
+	if err != nil {
+		fs.Errorf(path, "cfmount: failed to acknowledge: %v", err)
+	}
+}
+
+// remove removes the file or empty directory at path from the VFS
+func (r *syncRoot) remove(path string) error {
+	if r.VFS.Opt.ReadOnly {
+		return vfs.EROFS
+	}
+	err := r.VFS.Remove(path)
+	if err == vfs.ENOENT {
+		return nil
+	}
+	return err
+}
+
+// notifyDelete deletes the file at path from the remote
+func (r *syncRoot) notifyDelete(key *operationKey, path string) {
+	fs.Debugf(path, "cfmount: delete")
+	ack(key, cfOperationTypeAckDelete, path, r.remove(path))
+}
+
+// notifyRename renames the file at path to newPath on the remote, or
+// removes it if it is being moved out of the sync root
+func (r *syncRoot) notifyRename(key *operationKey, path, newPath string, inScope bool) {
+	var err error
+	switch {
+	case !inScope:
+		fs.Debugf(path, "cfmount: moved out of the sync root")
+		err = r.remove(path)
+	case r.VFS.Opt.ReadOnly:
+		err = vfs.EROFS
+	default:
+		fs.Debugf(path, "cfmount: rename to %q", newPath)
+		err = r.VFS.Rename(path, newPath)
+	}
+	ack(key, cfOperationTypeAckRename, path, err)
+}
+
+// mount the VFS on mountpoint, returning a channel which is never
+// written to as the sync root stays until it is unmounted
+func mount(VFS *vfs.VFS, mountpoint string, opt *mountlib.Options) (<-chan error, func() error, error) {
+	err := loadCloudFilesAPI()
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := newSyncRoot(VFS, mountpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	fs.Debugf(VFS.Fs(), "Registering sync root %q", r.path)
+	err = r.register()
+	if err != nil {
+		return nil, nil, err
+	}
+	err = r.connect()
+	if err != nil {
+		_ = r.unregister()
+		return nil, nil, err
+	}
+	fs.Debugf(VFS.Fs(), "Sync root connected")
+	unmount := func() error {
+		err := r.disconnect()
+		if unregisterErr := r.unregister(); err == nil {
+			err = unregisterErr
+		}
+		VFS.Shutdown()
+		return err
+	}
+	return make(chan error), unmount, nil
+}
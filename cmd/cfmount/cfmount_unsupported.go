@@ -0,0 +1,6 @@
+// Build for cfmount for unsupported platforms to stop go complaining
+// about "no buildable Go source files "
+
+// +build !windows !amd64
+
+package cfmount
@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/flags"
@@ -27,6 +28,7 @@ var (
 	differ            = ""
 	errFile           = ""
 	checkFileHashType = ""
+	checkSample       = fs.SizeSuffix(0)
 )
 
 func init() {
@@ -34,6 +36,7 @@ func init() {
 	cmdFlags := commandDefinition.Flags()
 	flags.BoolVarP(cmdFlags, &download, "download", "", download, "Check by downloading rather than with hash.")
 	flags.StringVarP(cmdFlags, &checkFileHashType, "checkfile", "C", checkFileHashType, "Treat source:path as a SUM file with hashes of given type")
+	flags.FVarP(cmdFlags, &checkSample, "check-sample", "", "Check by downloading this many bytes from the start, end and middle of files without a common hash")
 	AddFlags(cmdFlags)
 }
 
@@ -147,6 +150,14 @@ both remotes and check them against each other on the fly.  This can
 be useful for remotes that don't support hashes or if you really want
 to check all the data.
 
+If you supply the |--check-sample N| flag then files which can't be
+compared with a common hash are checked by downloading N bytes from
+the start, the end and a random place in the middle of both copies and
+comparing those. This catches most corruption without downloading the
+whole of each file so is useful for verifying large amounts of data
+between remotes without a common hash. Files smaller than 3*N are
+downloaded in full. It can't be used with |--download|.
+
 If you supply the |--checkfile HASH| flag with a valid hash name,
 the |source:path| must point to a text file in the SUM format.
 `, "|", "`") + FlagsHelp,
@@ -180,8 +191,14 @@ the |source:path| must point to a text file in the SUM format.
 			}
 
 			if download {
+				if checkSample > 0 {
+					return errors.New("can't use --check-sample with --download")
+				}
 				return operations.CheckDownload(context.Background(), opt)
 			}
+			if checkSample > 0 {
+				return operations.CheckSample(context.Background(), opt, int64(checkSample))
+			}
 			hashType := fsrc.Hashes().Overlap(fdst.Hashes()).GetOne()
 			if hashType == hash.None {
 				fs.Errorf(nil, "No common hash found - not using a hash for checks")
@@ -23,25 +23,38 @@ func init() {
 }
 
 var commandDefinition = &cobra.Command{
-	Use:   "checksum <hash> sumfile src:path",
+	Use:   "checksum [<hash>] sumfile src:path",
 	Short: `Checks the files in the source against a SUM file.`,
 	Long: strings.ReplaceAll(`
 Checks that hashsums of source files match the SUM file.
 It compares hashes (MD5, SHA1, etc) and logs a report of files which
 don't match.  It doesn't alter the file system.
 
+The SUM file is in the format made by md5sum, sha1sum or |rclone hashsum|.
+If the hash isn't given it is worked out from the length of the sums
+in the file, preferring MD5, SHA-1 and SHA-256, e.g.
+
+    rclone checksum files.md5 remote:path
+
+The hashes the remote stores are used where possible.  If the remote
+doesn't support the hash, or doesn't have it for a file, then the file
+is downloaded and hashed instead.
+
 If you supply the |--download| flag, it will download the data from remote
-and calculate the contents hash on the fly.  This can be useful for remotes
-that don't support hashes or if you really want to check all the data.
+and calculate the contents hash on the fly for all the files.  This can be
+useful if you really want to check all the data.
 `, "|", "`") + check.FlagsHelp,
 	RunE: func(command *cobra.Command, args []string) error {
-		cmd.CheckArgs(3, 3, command, args)
+		cmd.CheckArgs(2, 3, command, args)
 		var hashType hash.Type
-		if err := hashType.Set(args[0]); err != nil {
-			fmt.Println(hash.HelpString(0))
-			return err
+		if len(args) == 3 {
+			if err := hashType.Set(args[0]); err != nil {
+				fmt.Println(hash.HelpString(0))
+				return err
+			}
+			args = args[1:]
 		}
-		fsum, sumFile, fsrc := cmd.NewFsSrcFileDst(args[1:])
+		fsum, sumFile, fsrc := cmd.NewFsSrcFileDst(args)
 
 		cmd.Run(false, true, command, func() error {
 			opt, close, err := check.GetCheckOpt(nil, fsrc)
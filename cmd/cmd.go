@@ -7,8 +7,10 @@ package cmd
 // would probably mean bringing all the flags in to here? Or define some flagsets in fs...
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
@@ -54,6 +56,7 @@ var (
 	version         bool
 	retries         = flags.IntP("retries", "", 3, "Retry operations this many times if they fail")
 	retriesInterval = flags.DurationP("retries-sleep", "", 0, "Interval between retrying operations if they fail, e.g 500ms, 60s, 5m. (0 to disable)")
+	retryFailedTo   = flags.StringP("retry-failed-to", "", "", "Write the files which failed to transfer to this file for use with --files-from")
 	// Errors
 	errorCommandNotFound    = errors.New("command not found")
 	errorUncategorized      = errors.New("uncategorized error")
@@ -83,6 +86,15 @@ func ShowVersion() {
 	fmt.Printf("- go/tags: %s\n", tagString)
 }
 
+// setRemoteFlags sets any global flags from the config of remote
+func setRemoteFlags(remote string) {
+	err := configflags.SetRemoteFlags(context.Background(), remote)
+	if err != nil {
+		err = fs.CountError(err)
+		log.Fatalf("Failed to set flags for %q: %v", remote, err)
+	}
+}
+
 // NewFsFile creates an Fs from a name but may point to a file.
 //
 // It returns a string with the file name if points to a file
@@ -93,6 +105,7 @@ func NewFsFile(remote string) (fs.Fs, string) {
 		err = fs.CountError(err)
 		log.Fatalf("Failed to create file system for %q: %v", remote, err)
 	}
+	setRemoteFlags(remote)
 	f, err := cache.Get(context.Background(), remote)
 	switch err {
 	case fs.ErrorIsFile:
@@ -144,6 +157,7 @@ func NewFsSrc(args []string) fs.Fs {
 //
 // This must point to a directory
 func newFsDir(remote string) fs.Fs {
+	setRemoteFlags(remote)
 	f, err := cache.Get(context.Background(), remote)
 	if err != nil {
 		err = fs.CountError(err)
@@ -177,6 +191,54 @@ func NewFsSrcFileDst(args []string) (fsrc fs.Fs, srcFileName string, fdst fs.Fs)
 	return fsrc, srcFileName, fdst
 }
 
+// mergePolicies maps the --merge-policy values onto the union
+// backend search policy which implements them
+//
+// The union backend lists and finds objects in all the upstreams and
+// ff then picks the entry from the first upstream in the order given,
+// so "first" follows the order of the sources.
+var mergePolicies = map[string]string{
+	"first":  "ff",
+	"newest": "newest",
+}
+
+// NewFsSrcsFileDst creates a new src and dst fs from the arguments
+// where all but the last are sources.
+//
+// If there is one source it works like NewFsSrcFileDst. Otherwise
+// the sources are merged into one read only union Fs and for files
+// with the same path in more than one source the one chosen by policy
+// is used. The sources must either all be directories or all be
+// files with the same name, in which case the union is of their
+// parent directories and the file name is returned.
+func NewFsSrcsFileDst(args []string, policy string) (fsrc fs.Fs, srcFileName string, fdst fs.Fs) {
+	if len(args) <= 2 {
+		return NewFsSrcFileDst(args)
+	}
+	searchPolicy, ok := mergePolicies[policy]
+	if !ok {
+		log.Fatalf("Unknown --merge-policy %q: must be first or newest", policy)
+	}
+	srcs := args[:len(args)-1]
+	upstreams := make(fs.SpaceSepList, len(srcs))
+	for i, src := range srcs {
+		upstream, fileName := NewFsFile(src)
+		if i > 0 && fileName != srcFileName {
+			log.Fatalf("Sources must all be directories or all be files with the same name: %q doesn't match %q", src, srcs[0])
+		}
+		srcFileName = fileName
+		upstreams[i] = src
+		if fileName != "" {
+			upstreams[i] = fs.ConfigString(upstream)
+		}
+		upstreams[i] += ":ro"
+	}
+	quoted := strings.Replace(upstreams.String(), "'", "''", -1)
+	fsrc = newFsDir(fmt.Sprintf(":union,upstreams='%s',search_policy=%s:", quoted, searchPolicy))
+	fdst = newFsDir(args[len(args)-1])
+	return fsrc, srcFileName, fdst
+}
+
 // NewFsSrcDstFiles creates a new src and dst fs from the arguments
 // If src is a file then srcFileName and dstFileName will be non-empty
 func NewFsSrcDstFiles(args []string) (fsrc fs.Fs, srcFileName string, fdst fs.Fs, dstFileName string) {
@@ -237,6 +299,25 @@ func ShowStats() bool {
 	return statsIntervalFlag != nil && statsIntervalFlag.Changed
 }
 
+// writeFailedTransfers writes the files which failed to transfer to
+// path one per line so they can be retried with --files-from
+func writeFailedTransfers(path string) {
+	failed := accounting.GlobalStats().FailedTransfers()
+	var buf bytes.Buffer
+	for _, remote := range failed {
+		buf.WriteString(remote)
+		buf.WriteByte('\n')
+	}
+	err := ioutil.WriteFile(path, buf.Bytes(), 0666)
+	if err != nil {
+		fs.Errorf(nil, "Failed to write --retry-failed-to file: %v", err)
+		return
+	}
+	if len(failed) > 0 {
+		fs.Logf(nil, "Wrote %d failed transfers to %q", len(failed), path)
+	}
+}
+
 // Run the function with stats and retries if required
 func Run(Retry bool, showStats bool, cmd *cobra.Command, f func() error) {
 	ci := fs.GetConfig(context.Background())
@@ -260,7 +341,7 @@ func Run(Retry bool, showStats bool, cmd *cobra.Command, f func() error) {
 		}
 		if !Retry || !accounting.GlobalStats().Errored() {
 			if try > 1 {
-				fs.Errorf(nil, "Attempt %d/%d succeeded", try, *retries)
+				fs.Errorf(nil, "Attempt %d/%d succeeded%v", try, *retries, fs.LogValueHide("attempt", try))
 			}
 			break
 		}
@@ -280,9 +361,9 @@ func Run(Retry bool, showStats bool, cmd *cobra.Command, f func() error) {
 			}
 		}
 		if lastErr != nil {
-			fs.Errorf(nil, "Attempt %d/%d failed with %d errors and: %v", try, *retries, accounting.GlobalStats().GetErrors(), lastErr)
+			fs.Errorf(nil, "Attempt %d/%d failed with %d errors and: %v%v", try, *retries, accounting.GlobalStats().GetErrors(), lastErr, fs.LogValueHide("attempt", try))
 		} else {
-			fs.Errorf(nil, "Attempt %d/%d failed with %d errors", try, *retries, accounting.GlobalStats().GetErrors())
+			fs.Errorf(nil, "Attempt %d/%d failed with %d errors%v", try, *retries, accounting.GlobalStats().GetErrors(), fs.LogValueHide("attempt", try))
 		}
 		if try < *retries {
 			accounting.GlobalStats().ResetErrors()
@@ -291,10 +372,16 @@ func Run(Retry bool, showStats bool, cmd *cobra.Command, f func() error) {
 			time.Sleep(*retriesInterval)
 		}
 	}
+	if *retryFailedTo != "" {
+		writeFailedTransfers(*retryFailedTo)
+	}
 	stopStats()
 	if showStats && (accounting.GlobalStats().Errored() || *statsInterval > 0) {
 		accounting.GlobalStats().Log()
 	}
+	if ci.DryRun && ci.Estimate {
+		fs.Logf(nil, "Estimated: %s", accounting.GlobalStats().EstimateString())
+	}
 	fs.Debugf(nil, "%d go routines active\n", runtime.NumGoroutine())
 
 	if ci.Progress && ci.ProgressTerminalTitle {
@@ -384,12 +471,12 @@ func initConfig() {
 	// Start the logger
 	fslog.InitLogging()
 
+	// Install the config file handler first as --profile reads it
+	configfile.Install()
+
 	// Finish parsing any command line flags
 	configflags.SetFlags(ci)
 
-	// Load the config
-	configfile.Install()
-
 	// Start accounting
 	accounting.Start(ctx)
 
@@ -418,6 +505,13 @@ func initConfig() {
 		log.Fatalf("Failed to start remote control: %v", err)
 	}
 
+	// Let transfers in progress finish on exit if required
+	if ci.ShutdownTimeout > 0 {
+		atexit.SetDrain(func() {
+			accounting.Drain(context.Background(), ci.ShutdownTimeout)
+		})
+	}
+
 	// Setup CPU profiling if desired
 	if *cpuProfile != "" {
 		fs.Infof(nil, "Creating CPU profile %q\n", *cpuProfile)
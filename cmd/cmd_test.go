@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	_ "github.com/rclone/rclone/backend/union"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/sync"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile writes contents to name in dir with the modification time
+// modTime
+func writeFile(t *testing.T, dir, name, contents string, modTime time.Time) {
+	filePath := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(contents), 0600))
+	require.NoError(t, os.Chtimes(filePath, modTime, modTime))
+}
+
+// readFile returns the contents of name in dir
+func readFile(t *testing.T, dir, name string) string {
+	contents, err := ioutil.ReadFile(filepath.Join(dir, name))
+	require.NoError(t, err)
+	return string(contents)
+}
+
+func TestNewFsSrcsFileDst(t *testing.T) {
+	fstest.Initialise()
+	ctx := context.Background()
+	base, err := ioutil.TempDir("", "rclone-merge-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(base))
+	}()
+	old := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	newer := old.Add(time.Hour)
+
+	// older has the older copy of file.txt and newer the newer
+	older, newest := filepath.Join(base, "older"), filepath.Join(base, "newer")
+	for _, dir := range []string{older, newest} {
+		require.NoError(t, os.Mkdir(dir, 0700))
+	}
+	writeFile(t, older, "file.txt", "older", old)
+	writeFile(t, newest, "file.txt", "newer", newer)
+	writeFile(t, older, "only-older.txt", "only in older", old)
+
+	n := 0
+	for _, test := range []struct {
+		name   string
+		srcs   []string
+		policy string
+		want   string
+	}{
+		{"first", []string{older, newest}, "first", "older"},
+		{"first reversed", []string{newest, older}, "first", "newer"},
+		{"newest", []string{older, newest}, "newest", "newer"},
+		{"newest reversed", []string{newest, older}, "newest", "newer"},
+		{"first files", []string{filepath.Join(older, "file.txt"), filepath.Join(newest, "file.txt")}, "first", "older"},
+		{"first files reversed", []string{filepath.Join(newest, "file.txt"), filepath.Join(older, "file.txt")}, "first", "newer"},
+		{"newest files", []string{filepath.Join(older, "file.txt"), filepath.Join(newest, "file.txt")}, "newest", "newer"},
+		{"single file", []string{filepath.Join(older, "file.txt")}, "first", "older"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, doSync := range []bool{false, true} {
+				n++
+				dst := filepath.Join(base, "dst", strconv.Itoa(n))
+				require.NoError(t, os.MkdirAll(dst, 0700))
+				writeFile(t, dst, "extra.txt", "only in dst", old)
+
+				fsrc, srcFileName, fdst := NewFsSrcsFileDst(append(test.srcs, dst), test.policy)
+				if srcFileName != "" {
+					assert.Equal(t, "file.txt", srcFileName)
+					require.NoError(t, operations.CopyFile(ctx, fdst, fsrc, srcFileName, srcFileName))
+				} else if doSync {
+					require.NoError(t, sync.Sync(ctx, fdst, fsrc, false))
+				} else {
+					require.NoError(t, sync.CopyDir(ctx, fdst, fsrc, false))
+				}
+				assert.Equal(t, test.want, readFile(t, dst, "file.txt"))
+
+				// only a sync of directories removes the extra file
+				_, err := os.Stat(filepath.Join(dst, "extra.txt"))
+				if doSync && srcFileName == "" {
+					assert.True(t, os.IsNotExist(err), "extra.txt should be deleted")
+				} else {
+					assert.NoError(t, err)
+				}
+				if srcFileName == "" {
+					assert.Equal(t, "only in older", readFile(t, dst, "only-older.txt"))
+				}
+			}
+		})
+	}
+}
@@ -156,6 +156,8 @@ func (fsys *FS) stat(node vfs.Node, stat *fuse.Stat_t) (errc int) {
 	Mode := node.Mode().Perm()
 	if node.IsDir() {
 		Mode |= fuse.S_IFDIR
+	} else if node.Mode()&os.ModeSymlink != 0 {
+		Mode |= fuse.S_IFLNK
 	} else {
 		Mode |= fuse.S_IFREG
 	}
@@ -165,6 +167,9 @@ func (fsys *FS) stat(node vfs.Node, stat *fuse.Stat_t) (errc int) {
 	stat.Nlink = 1
 	stat.Uid = fsys.VFS.Opt.UID
 	stat.Gid = fsys.VFS.Opt.GID
+	if file, ok := node.(*vfs.File); ok {
+		stat.Uid, stat.Gid = file.Owner()
+	}
 	//stat.Rdev
 	stat.Size = int64(Size)
 	t := fuse.NewTimespec(modTime)
@@ -506,27 +511,59 @@ func (fsys *FS) Link(oldpath string, newpath string) (errc int) {
 // Symlink creates a symbolic link.
 func (fsys *FS) Symlink(target string, newpath string) (errc int) {
 	defer log.Trace(target, "newpath=%q", newpath)("errc=%d", &errc)
-	return -fuse.ENOSYS
+	return translateError(fsys.VFS.Symlink(target, newpath))
 }
 
 // Readlink reads the target of a symbolic link.
 func (fsys *FS) Readlink(path string) (errc int, linkPath string) {
 	defer log.Trace(path, "")("linkPath=%q, errc=%d", &linkPath, &errc)
-	return -fuse.ENOSYS, ""
+	linkPath, err := fsys.VFS.Readlink(path)
+	return translateError(err), linkPath
 }
 
 // Chmod changes the permission bits of a file.
 func (fsys *FS) Chmod(path string, mode uint32) (errc int) {
 	defer log.Trace(path, "mode=0%o", mode)("errc=%d", &errc)
-	// This is a no-op for rclone
-	return 0
+	file, errc := fsys.metadataFile(path)
+	if file == nil {
+		return errc
+	}
+	return translateError(file.Chmod(os.FileMode(mode) & os.ModePerm))
 }
 
 // Chown changes the owner and group of a file.
 func (fsys *FS) Chown(path string, uid uint32, gid uint32) (errc int) {
 	defer log.Trace(path, "uid=%d, gid=%d", uid, gid)("errc=%d", &errc)
-	// This is a no-op for rclone
-	return 0
+	file, errc := fsys.metadataFile(path)
+	if file == nil {
+		return errc
+	}
+	// ^uint32(0) is -1 which means leave unchanged
+	newUID, newGID := -1, -1
+	if uid != ^uint32(0) {
+		newUID = int(uid)
+	}
+	if gid != ^uint32(0) {
+		newGID = int(gid)
+	}
+	return translateError(file.Chown(newUID, newGID))
+}
+
+// metadataFile returns the file at path if --vfs-metadata is in use.
+//
+// If it returns a nil file then errc should be returned - this is 0
+// for directories and without --vfs-metadata as the change is
+// ignored.
+func (fsys *FS) metadataFile(path string) (file *vfs.File, errc int) {
+	if !fsys.VFS.Opt.Metadata {
+		return nil, 0
+	}
+	node, errc := fsys.lookupNode(path)
+	if errc != 0 {
+		return nil, errc
+	}
+	file, _ = node.(*vfs.File)
+	return file, 0
 }
 
 // Access checks file access permissions.
@@ -545,12 +582,36 @@ func (fsys *FS) Fsyncdir(path string, datasync bool, fh uint64) (errc int) {
 
 // Setxattr sets extended attributes.
 func (fsys *FS) Setxattr(path string, name string, value []byte, flags int) (errc int) {
-	return -fuse.ENOSYS
+	if !fsys.VFS.Opt.Metadata {
+		return -fuse.ENOSYS
+	}
+	file, errc := fsys.metadataFile(path)
+	if file == nil {
+		if errc == 0 {
+			errc = -fuse.ENOTSUP
+		}
+		return errc
+	}
+	return translateError(file.SetXattr(name, string(value)))
 }
 
 // Getxattr gets extended attributes.
 func (fsys *FS) Getxattr(path string, name string) (errc int, value []byte) {
-	return -fuse.ENOSYS, nil
+	if !fsys.VFS.Opt.Metadata {
+		return -fuse.ENOSYS, nil
+	}
+	file, errc := fsys.metadataFile(path)
+	if file == nil {
+		if errc == 0 {
+			errc = -fuse.ENOATTR
+		}
+		return errc, nil
+	}
+	xattr, ok := file.Xattrs()[name]
+	if !ok {
+		return -fuse.ENOATTR, nil
+	}
+	return 0, []byte(xattr)
 }
 
 // Removexattr removes extended attributes.
@@ -560,7 +621,19 @@ func (fsys *FS) Removexattr(path string, name string) (errc int) {
 
 // Listxattr lists extended attributes.
 func (fsys *FS) Listxattr(path string, fill func(name string) bool) (errc int) {
-	return -fuse.ENOSYS
+	if !fsys.VFS.Opt.Metadata {
+		return -fuse.ENOSYS
+	}
+	file, errc := fsys.metadataFile(path)
+	if file == nil {
+		return errc
+	}
+	for name := range file.Xattrs() {
+		if !fill(name) {
+			return -fuse.ERANGE
+		}
+	}
+	return 0
 }
 
 // Translate errors from mountlib
@@ -589,6 +662,8 @@ func translateError(err error) (errc int) {
 		return -fuse.EROFS
 	case vfs.ENOSYS, fs.ErrorNotImplemented:
 		return -fuse.ENOSYS
+	case vfs.ENOTSUP:
+		return -fuse.ENOTSUP
 	case vfs.EINVAL:
 		return -fuse.EINVAL
 	}
@@ -39,6 +39,10 @@ func init() {
 	buildinfo.Tags = append(buildinfo.Tags, "cmount")
 }
 
+// globalMountPrefix is put in front of a drive letter on Windows to
+// make it visible to all users
+const globalMountPrefix = `\\.\`
+
 // Find the option string in the current options
 func findOption(name string, options []string) (found bool) {
 	for _, option := range options {
@@ -73,6 +77,9 @@ func mountOptions(VFS *vfs.VFS, device string, mountpoint string, opt *mountlib.
 				options = append(options, "-o", "volname="+opt.VolumeName)
 			}
 		}
+		if opt.VolumeSerial != "" {
+			options = append(options, "-o", "VolumeSerialNumber="+opt.VolumeSerial)
+		}
 	} else {
 		options = append(options, "-o", "fsname="+device)
 		options = append(options, "-o", "subtype=rclone")
@@ -152,11 +159,19 @@ func waitFor(fn func() bool) (ok bool) {
 // returns an error, and an error channel for the serve process to
 // report an error when fusermount is called.
 func mount(VFS *vfs.VFS, mountPath string, opt *mountlib.Options) (<-chan error, func() error, error) {
+	// Check the FUSE library is available before doing anything else
+	err := checkWinFsp()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Get mountpoint using OS specific logic
 	mountpoint, err := getMountpoint(mountPath, opt)
 	if err != nil {
 		return nil, nil, err
 	}
+	// The path the mount appears at, without the global prefix
+	mountedPath := strings.TrimPrefix(mountpoint, globalMountPrefix)
 	fs.Debugf(nil, "Mounting on %q (%q)", mountpoint, opt.VolumeName)
 
 	// Create underlying FS
@@ -207,7 +222,7 @@ func mount(VFS *vfs.VFS, mountPath string, opt *mountlib.Options) (<-chan error,
 			fs.Debugf(nil, "Unmounted successfully")
 			if runtime.GOOS == "windows" {
 				if !waitFor(func() bool {
-					_, err := os.Stat(mountpoint)
+					_, err := os.Stat(mountedPath)
 					return err != nil
 				}) {
 					fs.Errorf(nil, "mountpoint %q didn't disappear after unmount - continuing anyway", mountpoint)
@@ -232,7 +247,7 @@ func mount(VFS *vfs.VFS, mountPath string, opt *mountlib.Options) (<-chan error,
 	// On Windows the Init signal comes slightly before the mount is ready
 	if runtime.GOOS == "windows" {
 		if !waitFor(func() bool {
-			_, err := os.Stat(mountpoint)
+			_, err := os.Stat(mountedPath)
 			return err == nil
 		}) {
 			fs.Errorf(nil, "mountpoint %q didn't became available on mount - continuing anyway", mountpoint)
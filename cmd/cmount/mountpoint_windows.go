@@ -19,6 +19,7 @@ var isDriveRegex = regexp.MustCompile(`^[a-zA-Z]\:$`)
 var isDriveRootPathRegex = regexp.MustCompile(`^[a-zA-Z]\:\\$`)
 var isDriveOrRootPathRegex = regexp.MustCompile(`^[a-zA-Z]\:\\?$`)
 var isNetworkSharePathRegex = regexp.MustCompile(`^\\\\[^\\]+\\[^\\]`)
+var isVolumeSerialRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}$`)
 
 // isNetworkSharePath returns true if the given string is a valid network share path,
 // in the basic UNC format "\\Server\Share\Path", where the first two path components
@@ -156,6 +157,18 @@ func handleVolumeName(opt *mountlib.Options, volumeName string) {
 	}
 }
 
+// checkVolumeSerial checks the volume serial number option is 8 hex
+// digits if set.
+func checkVolumeSerial(serial string) error {
+	if serial == "" {
+		return nil
+	}
+	if !isVolumeSerialRegex.MatchString(serial) {
+		return errors.Errorf("invalid --volume-serial %q: must be 8 hex digits, e.g. 1A2B3C4D", serial)
+	}
+	return nil
+}
+
 // getMountpoint handles mounting details on Windows,
 // where disk and network based file systems are treated different.
 func getMountpoint(mountpath string, opt *mountlib.Options) (mountpoint string, err error) {
@@ -178,6 +191,19 @@ func getMountpoint(mountpath string, opt *mountlib.Options) (mountpoint string,
 	// Second handle volume name
 	handleVolumeName(opt, volumeName)
 
+	// Then the volume serial and system mounts
+	if err == nil {
+		err = checkVolumeSerial(opt.VolumeSerial)
+	}
+	if err == nil && opt.SystemMount {
+		if !isDrive(mountpoint) {
+			err = errors.New("--system-mount needs a drive letter mountpoint, not " + mountpoint)
+		} else {
+			fs.Debugf(nil, "Mounting %q for all users", mountpoint)
+			mountpoint = globalMountPrefix + mountpoint
+		}
+	}
+
 	// Done, return mountpoint to be used, together with updated mount options.
 	if opt.NetworkMode {
 		fs.Debugf(nil, "Network mode mounting is enabled")
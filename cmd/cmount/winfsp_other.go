@@ -0,0 +1,10 @@
+// +build cmount
+// +build cgo
+// +build !windows
+
+package cmount
+
+// checkWinFsp is only needed on Windows
+func checkWinFsp() error {
+	return nil
+}
@@ -0,0 +1,56 @@
+// +build cmount
+// +build cgo
+// +build windows
+
+package cmount
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/sys/windows/registry"
+)
+
+// errWinFspNotFound is returned when WinFsp isn't installed
+var errWinFspNotFound = errors.New("WinFsp is not installed or could not be found - it is needed to mount on Windows, install it from https://winfsp.dev/rel/ and try again")
+
+// winFspDLL returns the name of the WinFsp DLL cgofuse loads for this
+// architecture
+func winFspDLL() string {
+	switch runtime.GOARCH {
+	case "386":
+		return "winfsp-x86.dll"
+	case "arm64":
+		return "winfsp-a64.dll"
+	}
+	return "winfsp-x64.dll"
+}
+
+// checkWinFsp checks WinFsp is installed in the place cgofuse looks
+// for it so we can return a helpful error rather than the mount
+// failing part way through.
+func checkWinFsp() error {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\WinFsp`, registry.QUERY_VALUE|registry.WOW64_32KEY)
+	if err != nil {
+		fs.Debugf(nil, "Failed to open WinFsp registry key: %v", err)
+		return errWinFspNotFound
+	}
+	defer func() {
+		_ = key.Close()
+	}()
+	installDir, _, err := key.GetStringValue("InstallDir")
+	if err != nil {
+		fs.Debugf(nil, "Failed to read WinFsp InstallDir: %v", err)
+		return errWinFspNotFound
+	}
+	dll := filepath.Join(installDir, "bin", winFspDLL())
+	if _, err := os.Stat(dll); err != nil {
+		fs.Debugf(nil, "Failed to find WinFsp DLL: %v", err)
+		return errWinFspNotFound
+	}
+	fs.Debugf(nil, "Found WinFsp at %q", dll)
+	return nil
+}
@@ -13,16 +13,18 @@ import (
 
 var (
 	createEmptySrcDirs = false
+	mergePolicy        = "first"
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefinition)
 	cmdFlags := commandDefinition.Flags()
 	flags.BoolVarP(cmdFlags, &createEmptySrcDirs, "create-empty-src-dirs", "", createEmptySrcDirs, "Create empty source dirs on destination after copy")
+	flags.StringVarP(cmdFlags, &mergePolicy, "merge-policy", "", mergePolicy, "Which file to copy if it is in more than one source: first|newest")
 }
 
 var commandDefinition = &cobra.Command{
-	Use:   "copy source:path dest:path",
+	Use:   "copy source:path [source:path...] dest:path",
 	Short: `Copy files from source to dest, skipping already copied.`,
 	// Note: "|" will be replaced by backticks below
 	Long: strings.ReplaceAll(`
@@ -73,13 +75,26 @@ recently very efficiently like this:
 
     rclone copy --max-age 24h --no-traverse /path/to/src remote:
 
+If more than one source is given then they are merged and the result
+copied to the destination, for example to consolidate several old
+accounts into one with a single command
+
+    rclone copy old1: old2: old3: new:
+
+By default if a file is in more than one source the copy from the
+first source it is in is used. Use |--merge-policy newest| to use
+the one with the newest modification time instead. The sources must
+either all be directories or all be files with the same name, and as
+the merged files are read through rclone they are never copied
+server-side.
+
 **Note**: Use the |-P|/|--progress| flag to view real-time transfer statistics.
 
 **Note**: Use the |--dry-run| or the |--interactive|/|-i| flag to test without copying anything.
 `, "|", "`"),
 	Run: func(command *cobra.Command, args []string) {
-		cmd.CheckArgs(2, 2, command, args)
-		fsrc, srcFileName, fdst := cmd.NewFsSrcFileDst(args)
+		cmd.CheckArgs(2, 256, command, args)
+		fsrc, srcFileName, fdst := cmd.NewFsSrcsFileDst(args, mergePolicy)
 		cmd.Run(true, true, command, func() error {
 			if srcFileName == "" {
 				return sync.CopyDir(context.Background(), fdst, fsrc, createEmptySrcDirs)
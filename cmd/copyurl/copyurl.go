@@ -18,6 +18,7 @@ var (
 	printFilename = false
 	stdout        = false
 	noClobber     = false
+	urlList       = ""
 )
 
 func init() {
@@ -27,10 +28,11 @@ func init() {
 	flags.BoolVarP(cmdFlags, &printFilename, "print-filename", "p", printFilename, "Print the resulting name from --auto-filename")
 	flags.BoolVarP(cmdFlags, &noClobber, "no-clobber", "", noClobber, "Prevent overwriting file with same name")
 	flags.BoolVarP(cmdFlags, &stdout, "stdout", "", stdout, "Write the output to stdout rather than a file")
+	flags.StringVarP(cmdFlags, &urlList, "urls", "", urlList, "Read the URLs to copy from this file, one per line (use - for stdin)")
 }
 
 var commandDefinition = &cobra.Command{
-	Use:   "copyurl https://example.com dest:path",
+	Use:   "copyurl https://example.com dest:path | copyurl --urls FILE dest:path",
 	Short: `Copy url content to dest.`,
 	Long: `
 Download a URL's content and copy it to the destination without saving
@@ -46,8 +48,44 @@ destination if there is one with the same name.
 
 Setting ` + "`--stdout`" + ` or making the output file name ` + "`-`" + `
 will cause the output to be written to standard output.
+
+Setting ` + "`--urls FILE`" + ` reads a list of URLs from FILE, or standard
+input if FILE is ` + "`-`" + `, one per line, and copies them into the
+destination directory. Blank lines and lines starting with ` + "`#`" + ` are
+ignored. The URLs are downloaded ` + "`--transfers`" + ` at a time and named
+as with ` + "`--auto-filename`" + `. Failing URLs are logged and the rest
+carry on, so the whole list is retried with ` + "`--retries`" + ` if any fail -
+use ` + "`--no-clobber`" + ` to skip the ones already copied.
+
+    rclone copyurl --urls urls.txt remote:downloads
+
+With ` + "`--auto-filename`" + ` or ` + "`--urls`" + ` the file name is taken from
+the Content-Disposition header if the server sends one, otherwise from
+the last part of the URL.
 `,
 	RunE: func(command *cobra.Command, args []string) (err error) {
+		if urlList != "" {
+			cmd.CheckArgs(1, 1, command, args)
+			fsdst := cmd.NewFsDir(args)
+			cmd.Run(true, true, command, func() (err error) {
+				in := os.Stdin
+				if urlList != "-" {
+					in, err = os.Open(urlList)
+					if err != nil {
+						return err
+					}
+					defer fs.CheckClose(in, &err)
+				}
+				var printFn func(fs.Object)
+				if printFilename {
+					printFn = func(dst fs.Object) {
+						fmt.Println(dst.Remote())
+					}
+				}
+				return operations.CopyURLs(context.Background(), fsdst, in, noClobber, printFn)
+			})
+			return nil
+		}
 		cmd.CheckArgs(1, 2, command, args)
 
 		var dstFileName string
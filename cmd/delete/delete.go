@@ -12,12 +12,14 @@ import (
 
 var (
 	rmdirs = false
+	opt    operations.DeleteOpt
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefinition)
 	cmdFlags := commandDefinition.Flags()
 	flags.BoolVarP(cmdFlags, &rmdirs, "rmdirs", "", rmdirs, "rmdirs removes empty directories but leaves root intact")
+	flags.StringVarP(cmdFlags, &opt.Checkpoint, "checkpoint", "", opt.Checkpoint, "Record the files deleted in this file so an interrupted delete can be continued")
 }
 
 var commandDefinition = &cobra.Command{
@@ -49,6 +51,14 @@ Then proceed with the actual delete:
 That reads "delete everything with a minimum size of 100 MiB", hence
 delete all files bigger than 100 MiB.
 
+The files are deleted as they are found, |--transfers| at a time, and
+the progress is shown in the stats. When deleting a lot of files use
+|--checkpoint FILE| to record the files deleted. If the delete is
+interrupted then run it again with the same checkpoint to continue
+without trying to delete the files already deleted, which avoids
+errors from remotes whose listings lag behind deletions. The
+checkpoint is removed when the delete finishes successfully.
+
 **Important**: Since this can cause data loss, test first with the
 |--dry-run| or the |--interactive|/|-i| flag.
 `, "|", "`"),
@@ -56,7 +66,7 @@ delete all files bigger than 100 MiB.
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc := cmd.NewFsSrc(args)
 		cmd.Run(true, false, command, func() error {
-			if err := operations.Delete(context.Background(), fsrc); err != nil {
+			if err := operations.DeleteWithOpt(context.Background(), fsrc, &opt); err != nil {
 				return err
 			}
 			if rmdirs {
@@ -0,0 +1,258 @@
+// Package du provides the du command.
+package du
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/walk"
+	"github.com/spf13/cobra"
+)
+
+var (
+	jsonOutput bool
+	diffFile   string
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.BoolVarP(cmdFlags, &jsonOutput, "json", "", false, "Format output as JSON")
+	flags.StringVarP(cmdFlags, &diffFile, "diff", "", "", "Compare with a scan previously saved with --json")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "du remote:path [remote2:path2]",
+	Short: `Show the size and number of objects in each directory of remote:path.`,
+	Long: `
+Scans remote:path and prints the total size and number of objects in
+each directory, including everything in the directories below it.
+
+It uses ListR to do the scan if the remote supports it, so is much
+quicker than ncdu on bucket based remotes.
+
+    $ rclone du remote:path
+     1.500Gi     1234 .
+       512Mi      800 photos
+         1Gi      434 videos
+
+Use --json to output the scan as JSON.  This can be saved and compared
+with a later scan using --diff to see which directories have changed,
+eg
+
+    rclone du --json remote:path > monday.json
+    rclone du --diff monday.json remote:path
+
+When comparing, only the directories which have changed are shown with
+the change in size and number of objects.  If two remotes are given
+then they are scanned and compared directly, which is useful for
+seeing where a sync is different.
+
+    rclone du remote:path remote2:path2
+
+The --max-depth flag limits how far down the scan goes, and the
+filters can be used to control what is counted.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 2, command, args)
+		fsrc := cmd.NewFsSrc(args[:1])
+		var fdst fs.Fs
+		if len(args) == 2 {
+			fdst = cmd.NewFsSrc(args[1:])
+		}
+		cmd.Run(false, false, command, func() error {
+			ctx := context.Background()
+			var old Usage
+			if diffFile != "" && fdst != nil {
+				return errors.New("can't use --diff when comparing two remotes")
+			} else if diffFile != "" {
+				var err error
+				old, err = Load(diffFile)
+				if err != nil {
+					return err
+				}
+			} else if fdst != nil {
+				var err error
+				old, err = Scan(ctx, fsrc)
+				if err != nil {
+					return err
+				}
+				fsrc = fdst
+			}
+			usage, err := Scan(ctx, fsrc)
+			if err != nil {
+				return err
+			}
+			if old != nil {
+				usage = Diff(old, usage)
+			}
+			if jsonOutput {
+				return usage.WriteJSON(os.Stdout)
+			}
+			return usage.Write(os.Stdout, old != nil)
+		})
+	},
+}
+
+// DirUsage is the total size and number of objects in a directory
+// and the directories below it
+type DirUsage struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+	Count int64  `json:"count"`
+}
+
+// Usage is the usage of each directory sorted by path
+type Usage []DirUsage
+
+// parentDir returns the directory above dir or "" for the root
+func parentDir(dir string) string {
+	dir = path.Dir(dir)
+	if dir == "." || dir == "/" {
+		dir = ""
+	}
+	return dir
+}
+
+// Scan the directories of f returning the usage of each one
+func Scan(ctx context.Context, f fs.Fs) (Usage, error) {
+	ci := fs.GetConfig(ctx)
+	var mu sync.Mutex
+	dirs := map[string]*DirUsage{
+		"": {Path: ""},
+	}
+	// find the usage of dir creating it and its parents if needed
+	var find func(dir string) *DirUsage
+	find = func(dir string) *DirUsage {
+		d, ok := dirs[dir]
+		if !ok {
+			d = &DirUsage{Path: dir}
+			dirs[dir] = d
+			find(parentDir(dir))
+		}
+		return d
+	}
+	err := walk.ListR(ctx, f, "", false, ci.MaxDepth, walk.ListAll, func(entries fs.DirEntries) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, entry := range entries {
+			switch x := entry.(type) {
+			case fs.Directory:
+				find(x.Remote())
+			case fs.Object:
+				size := x.Size()
+				if size < 0 {
+					size = 0
+				}
+				for dir := parentDir(x.Remote()); ; dir = parentDir(dir) {
+					d := find(dir)
+					d.Bytes += size
+					d.Count++
+					if dir == "" {
+						break
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "du listing failed")
+	}
+	usage := make(Usage, 0, len(dirs))
+	for _, d := range dirs {
+		usage = append(usage, *d)
+	}
+	usage.sort()
+	return usage, nil
+}
+
+// sort the usage by path
+func (u Usage) sort() {
+	sort.Slice(u, func(i, j int) bool {
+		return u[i].Path < u[j].Path
+	})
+}
+
+// Load reads a scan saved with WriteJSON from the file name
+func Load(name string) (u Usage, err error) {
+	in, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open scan")
+	}
+	defer fs.CheckClose(in, &err)
+	err = json.NewDecoder(in).Decode(&u)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read scan from %q", name)
+	}
+	u.sort()
+	return u, nil
+}
+
+// Diff returns the directories which are different between old and
+// current with the change in their size and number of objects.
+func Diff(old, current Usage) Usage {
+	changes := map[string]*DirUsage{}
+	for _, d := range current {
+		changes[d.Path] = &DirUsage{Path: d.Path, Bytes: d.Bytes, Count: d.Count}
+	}
+	for _, d := range old {
+		c, ok := changes[d.Path]
+		if !ok {
+			c = &DirUsage{Path: d.Path}
+			changes[d.Path] = c
+		}
+		c.Bytes -= d.Bytes
+		c.Count -= d.Count
+	}
+	var diff = Usage{}
+	for _, c := range changes {
+		if c.Bytes != 0 || c.Count != 0 {
+			diff = append(diff, *c)
+		}
+	}
+	diff.sort()
+	return diff
+}
+
+// WriteJSON writes the usage to out as JSON
+func (u Usage) WriteJSON(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "\t")
+	return enc.Encode(u)
+}
+
+// Write writes the usage to out one directory per line. If signed is
+// set the sizes are shown with a + or -.
+func (u Usage) Write(out io.Writer, signed bool) error {
+	for _, d := range u {
+		dir := d.Path
+		if dir == "" {
+			dir = "."
+		}
+		var err error
+		if signed {
+			sign, bytes := "+", d.Bytes
+			if bytes < 0 {
+				sign, bytes = "-", -bytes
+			}
+			_, err = fmt.Fprintf(out, "%9s %+8d %s\n", sign+fs.SizeSuffix(bytes).String(), d.Count, dir)
+		} else {
+			_, err = fmt.Fprintf(out, "%8s %8d %s\n", fs.SizeSuffix(d.Bytes).String(), d.Count, dir)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
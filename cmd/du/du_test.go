@@ -0,0 +1,114 @@
+package du
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeTree makes files of the sizes given in a temporary directory
+func makeTree(t *testing.T, files map[string]int) (dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "rclone-du-test")
+	require.NoError(t, err)
+	for name, size := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0777))
+		require.NoError(t, ioutil.WriteFile(name, []byte(strings.Repeat("x", size)), 0666))
+	}
+	return dir, func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+func TestScan(t *testing.T) {
+	fstest.Initialise()
+	ctx := context.Background()
+	dir, cleanup := makeTree(t, map[string]int{
+		"file1":           1,
+		"sub/file2":       10,
+		"sub/file3":       100,
+		"sub/deeper/file": 1000,
+		"other/file4":     10000,
+	})
+	defer cleanup()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "empty"), 0777))
+	f, err := fs.NewFs(ctx, dir)
+	require.NoError(t, err)
+
+	usage, err := Scan(ctx, f)
+	require.NoError(t, err)
+	assert.Equal(t, Usage{
+		{Path: "", Bytes: 11111, Count: 5},
+		{Path: "empty", Bytes: 0, Count: 0},
+		{Path: "other", Bytes: 10000, Count: 1},
+		{Path: "sub", Bytes: 1110, Count: 3},
+		{Path: "sub/deeper", Bytes: 1000, Count: 1},
+	}, usage)
+
+	var buf bytes.Buffer
+	require.NoError(t, usage.Write(&buf, false))
+	assert.Equal(t, `10.851Ki        5 .
+       0        0 empty
+ 9.766Ki        1 other
+ 1.084Ki        3 sub
+    1000        1 sub/deeper
+`, buf.String())
+}
+
+func TestDiff(t *testing.T) {
+	old := Usage{
+		{Path: "", Bytes: 300, Count: 3},
+		{Path: "a", Bytes: 100, Count: 1},
+		{Path: "b", Bytes: 200, Count: 2},
+	}
+	current := Usage{
+		{Path: "", Bytes: 250, Count: 3},
+		{Path: "b", Bytes: 200, Count: 2},
+		{Path: "c", Bytes: 50, Count: 1},
+	}
+	diff := Diff(old, current)
+	assert.Equal(t, Usage{
+		{Path: "", Bytes: -50, Count: 0},
+		{Path: "a", Bytes: -100, Count: -1},
+		{Path: "c", Bytes: 50, Count: 1},
+	}, diff)
+	assert.Equal(t, Usage{}, Diff(old, old))
+
+	var buf bytes.Buffer
+	require.NoError(t, diff.Write(&buf, true))
+	assert.Equal(t, `      -50       +0 .
+     -100       -1 a
+      +50       +1 c
+`, buf.String())
+}
+
+func TestLoad(t *testing.T) {
+	usage := Usage{
+		{Path: "", Bytes: 300, Count: 3},
+		{Path: "a", Bytes: 300, Count: 3},
+	}
+	out, err := ioutil.TempFile("", "rclone-du-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.Remove(out.Name()))
+	}()
+	require.NoError(t, usage.WriteJSON(out))
+	require.NoError(t, out.Close())
+
+	got, err := Load(out.Name())
+	require.NoError(t, err)
+	assert.Equal(t, usage, got)
+
+	_, err = Load(out.Name() + "-notfound")
+	assert.Error(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd"
@@ -77,6 +78,17 @@ Then
     $ rclone hashsum MD5 remote:path
 
 Note that hash names are case insensitive.
+
+Several hashes can be given separated by commas, in which case a
+column is output for each of them before the file name. With the
+download flag all of them are computed in a single pass over the
+data, e.g.
+
+    $ rclone hashsum --download MD5,SHA-256,CRC-32C remote:path
+
+**Note**: XXH3 isn't supported yet as rclone doesn't include an
+implementation of it.  Use CRC-32C or SHA-256 if you need a fast or
+a strong hash which any remote can compute with --download.
 `,
 	RunE: func(command *cobra.Command, args []string) error {
 		cmd.CheckArgs(0, 2, command, args)
@@ -86,28 +98,35 @@ Note that hash names are case insensitive.
 		} else if len(args) == 1 {
 			return errors.New("need hash type and remote")
 		}
-		var ht hash.Type
-		err := ht.Set(args[0])
-		if err != nil {
-			fmt.Println(hash.HelpString(0))
-			return err
+		var hts []hash.Type
+		for _, name := range strings.Split(args[0], ",") {
+			var ht hash.Type
+			err := ht.Set(name)
+			if err != nil {
+				fmt.Println(hash.HelpString(0))
+				return err
+			}
+			hts = append(hts, ht)
+		}
+		if ChecksumFile != "" && len(hts) > 1 {
+			return errors.New("only one hash can be used with --checkfile")
 		}
 		fsrc := cmd.NewFsSrc(args[1:])
 
 		cmd.Run(false, false, command, func() error {
 			if ChecksumFile != "" {
 				fsum, sumFile := cmd.NewFsFile(ChecksumFile)
-				return operations.CheckSum(context.Background(), fsrc, fsum, sumFile, ht, nil, DownloadFlag)
+				return operations.CheckSum(context.Background(), fsrc, fsum, sumFile, hts[0], nil, DownloadFlag)
 			}
 			if HashsumOutfile == "" {
-				return operations.HashLister(context.Background(), ht, OutputBase64, DownloadFlag, fsrc, nil)
+				return operations.HashListerTypes(context.Background(), hts, OutputBase64, DownloadFlag, fsrc, nil)
 			}
 			output, close, err := GetHashsumOutput(HashsumOutfile)
 			if err != nil {
 				return err
 			}
 			defer close()
-			return operations.HashLister(context.Background(), ht, OutputBase64, DownloadFlag, fsrc, output)
+			return operations.HashListerTypes(context.Background(), hts, OutputBase64, DownloadFlag, fsrc, output)
 		})
 		return nil
 	},
@@ -3,6 +3,7 @@ package link
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/fs"
@@ -12,8 +13,10 @@ import (
 )
 
 var (
-	expire = fs.DurationOff
-	unlink = false
+	expire   = fs.DurationOff
+	unlink   = false
+	password = ""
+	list     = false
 )
 
 func init() {
@@ -21,6 +24,8 @@ func init() {
 	cmdFlags := commandDefinition.Flags()
 	flags.FVarP(cmdFlags, &expire, "expire", "", "The amount of time that the link will be valid")
 	flags.BoolVarP(cmdFlags, &unlink, "unlink", "", unlink, "Remove existing public link to file/folder")
+	flags.StringVarP(cmdFlags, &password, "password", "", password, "Password needed to use the link")
+	flags.BoolVarP(cmdFlags, &list, "list", "", list, "List the existing public links to file/folder")
 }
 
 var commandDefinition = &cobra.Command{
@@ -33,6 +38,8 @@ file or folder.
     rclone link remote:path/to/folder/
     rclone link --unlink remote:path/to/folder/
     rclone link --expire 1d remote:path/to/file
+    rclone link --password secret remote:path/to/file
+    rclone link --list remote:path/to/folder/
 
 If you supply the --expire flag, it will set the expiration time
 otherwise it will use the default (100 years). **Note** not all
@@ -43,6 +50,16 @@ Use the --unlink flag to remove existing public links to the file or
 folder. **Note** not all backends support "--unlink" flag - those that
 don't will just ignore it.
 
+Use the --password flag to make a link which needs the password to
+use it. This is only supported by some backends (Box, Dropbox and
+OneDrive Personal) - those that don't will return an error.
+
+Use the --list flag to show the existing public links to the file or
+folder, one per line, with their expiry time and whether they need a
+password. Some backends (e.g. Dropbox) list all the links in the
+folder and its subfolders too. This is supported by Box, Dropbox,
+Google Drive and OneDrive.
+
 If successful, the last line of the output will contain the
 link. Exact capabilities depend on the remote, but the link will
 always by default be created with the least constraints – e.g. no
@@ -52,7 +69,17 @@ expiry, no password protection, accessible without account.
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc, remote := cmd.NewFsFile(args[0])
 		cmd.Run(false, false, command, func() error {
-			link, err := operations.PublicLink(context.Background(), fsrc, remote, expire, unlink)
+			ctx := context.Background()
+			if list {
+				return listLinks(ctx, fsrc, remote)
+			}
+			var link string
+			var err error
+			if password != "" && !unlink {
+				link, err = operations.PublicLinkWithPassword(ctx, fsrc, remote, expire, password)
+			} else {
+				link, err = operations.PublicLink(ctx, fsrc, remote, expire, unlink)
+			}
 			if err != nil {
 				return err
 			}
@@ -63,3 +90,22 @@ expiry, no password protection, accessible without account.
 		})
 	},
 }
+
+// listLinks prints the public links to remote in f
+func listLinks(ctx context.Context, f fs.Fs, remote string) error {
+	links, err := operations.ListPublicLinks(ctx, f, remote)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		var extra []string
+		if link.Expires != nil {
+			extra = append(extra, "expires "+link.Expires.Local().Format("2006-01-02 15:04:05"))
+		}
+		if link.Password {
+			extra = append(extra, "password")
+		}
+		fmt.Printf("%s\t%s\t%s\n", link.Path, link.URL, strings.Join(extra, ", "))
+	}
+	return nil
+}
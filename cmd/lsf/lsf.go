@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd"
@@ -26,6 +27,7 @@ var (
 	dirsOnly  bool
 	csv       bool
 	absolute  bool
+	header    bool
 )
 
 func init() {
@@ -38,6 +40,7 @@ func init() {
 	flags.BoolVarP(cmdFlags, &filesOnly, "files-only", "", false, "Only list files.")
 	flags.BoolVarP(cmdFlags, &dirsOnly, "dirs-only", "", false, "Only list directories.")
 	flags.BoolVarP(cmdFlags, &csv, "csv", "", false, "Output in CSV format.")
+	flags.BoolVarP(cmdFlags, &header, "header", "", false, "Print a line with the column names first.")
 	flags.BoolVarP(cmdFlags, &absolute, "absolute", "", false, "Put a leading / in front of path names.")
 	flags.BoolVarP(cmdFlags, &recurse, "recursive", "R", false, "Recurse into the listing.")
 }
@@ -73,6 +76,7 @@ output:
     m - MimeType of object if known
     e - encrypted name
     T - tier of storage if known, e.g. "Hot" or "Cool"
+    M{key} - the metadata value of key if known, e.g. M{mode}
 
 So if you wanted the path, size and modification time, you would use
 --format "pst", or maybe --format "tsp" to put the path last.
@@ -131,6 +135,17 @@ Eg
     test.sh,449
     "this file contains a comma, in the file name.txt",6
 
+Use the --header flag to print a line with the names of the columns
+first.  This is useful with --csv to make an inventory of the files
+which can be loaded straight into a spreadsheet or database.
+
+Eg
+
+    $ rclone lsf --csv --header --files-only --format "pstTM{mode}" remote:path
+    Path,Size,ModTime,Tier,mode
+    test.log,22355,2020-01-08 15:32:19,Hot,644
+    test.sh,449,2020-01-08 15:32:19,Hot,755
+
 Note that the --absolute parameter is useful for making lists of files
 to pass to an rclone copy with the --files-from-raw flag.
 
@@ -173,7 +188,9 @@ func Lsf(ctx context.Context, fsrc fs.Fs, out io.Writer) error {
 		Recurse:    recurse,
 	}
 
-	for _, char := range format {
+	chars := []rune(format)
+	for i := 0; i < len(chars); i++ {
+		char := chars[i]
 		switch char {
 		case 'p':
 			list.AddPath()
@@ -199,11 +216,23 @@ func Lsf(ctx context.Context, fsrc fs.Fs, out io.Writer) error {
 			opt.ShowOrigIDs = true
 		case 'T':
 			list.AddTier()
+		case 'M':
+			rest := string(chars[i+1:])
+			end := strings.IndexRune(rest, '}')
+			if !strings.HasPrefix(rest, "{") || end < 0 {
+				return errors.New("Format character 'M' must be followed by {key}")
+			}
+			list.AddMetadata(rest[1:end])
+			opt.Metadata = true
+			i += len([]rune(rest[:end+1]))
 		default:
 			return errors.Errorf("Unknown format character %q", char)
 		}
 	}
 
+	if header {
+		_, _ = fmt.Fprintln(out, list.Header())
+	}
 	return operations.ListJSON(ctx, fsrc, "", &opt, func(item *operations.ListJSONItem) error {
 		_, _ = fmt.Fprintln(out, list.Format(item))
 		return nil
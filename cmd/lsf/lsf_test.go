@@ -223,3 +223,58 @@ subdir/file3_+_111_+_`+expectedOutput[6]+`
 	recurse = false
 	dirSlash = false
 }
+
+func TestHeader(t *testing.T) {
+	fstest.Initialise()
+	f, err := fs.NewFs(context.Background(), "testfiles")
+	require.NoError(t, err)
+	format = "psM{potato}"
+	separator = ","
+	csv = true
+	header = true
+	filesOnly = true
+
+	buf := new(bytes.Buffer)
+	err = Lsf(context.Background(), f, buf)
+	require.NoError(t, err)
+	assert.Equal(t, `Path,Size,potato
+file1,0,
+file2,321,
+file3,1234,
+`, buf.String())
+
+	format = ""
+	separator = ""
+	csv = false
+	header = false
+	filesOnly = false
+}
+
+func TestMetadataFormat(t *testing.T) {
+	fstest.Initialise()
+	f, err := fs.NewFs(context.Background(), "testfiles")
+	require.NoError(t, err)
+	filesOnly = true
+
+	o, err := f.NewObject(context.Background(), "file1")
+	require.NoError(t, err)
+	meta, err := fs.GetMetadata(context.Background(), o)
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	format = "M{mode}p"
+	separator = ";"
+	err = Lsf(context.Background(), f, buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), meta["mode"]+";file1\n")
+
+	for _, bad := range []string{"M", "pM", "M{mode", "Mmode}"} {
+		format = bad
+		err = Lsf(context.Background(), f, new(bytes.Buffer))
+		assert.Error(t, err, bad)
+	}
+
+	format = ""
+	separator = ""
+	filesOnly = false
+}
@@ -4,18 +4,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/cmd/ls/lshelp"
+	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/fspath"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/spf13/cobra"
 )
 
 var (
-	opt operations.ListJSONOpt
+	opt      operations.ListJSONOpt
+	statOnly bool
+	diffFile string
 )
 
 func init() {
@@ -30,6 +36,9 @@ func init() {
 	flags.BoolVarP(cmdFlags, &opt.FilesOnly, "files-only", "", false, "Show only files in the listing.")
 	flags.BoolVarP(cmdFlags, &opt.DirsOnly, "dirs-only", "", false, "Show only directories in the listing.")
 	flags.StringArrayVarP(cmdFlags, &opt.HashTypes, "hash-type", "", nil, "Show only this hash type (may be repeated).")
+	flags.BoolVarP(cmdFlags, &opt.Metadata, "metadata", "", false, "Include the metadata in the output.")
+	flags.BoolVarP(cmdFlags, &statOnly, "stat", "", false, "Just return the info for the pointed to file.")
+	flags.StringVarP(cmdFlags, &diffFile, "diff", "", "", "Only output the changes since the listing saved in this file.")
 }
 
 var commandDefinition = &cobra.Command{
@@ -73,6 +82,9 @@ request (e.g. s3, swift).
 
 If --encrypted is not specified the Encrypted won't be emitted.
 
+If --metadata is specified then the Metadata property is emitted with
+the metadata the backend stores, for example the mode and owner.
+
 If --dirs-only is not specified files in addition to directories are
 returned
 
@@ -98,14 +110,76 @@ will be shown ("2017-05-31T16:15:57+01:00").
 
 The whole output can be processed as a JSON blob, or alternatively it
 can be processed line by line as each item is written one to a line.
+
+If --stat is set then just the single Item for remote:path is output
+on its own rather than in an array. A file is found directly so this
+doesn't list its parent directory which can be a lot quicker in
+directories with many files, for example
+
+    rclone lsjson --stat --hash remote:path/to/file.txt
+
+If remote:path points to a directory then its parent is listed to
+find it. The root of a remote is returned as a blank Item with IsDir
+set. It is an error if remote:path doesn't exist.
+
+If --diff is set to a file containing the output of a previous lsjson
+then only the items which have changed since it was saved are output,
+which gives a cheap way of finding out what has changed on remotes
+which don't notify changes. Each Item has a Change property set to
+
+  - added - the item isn't in the old listing
+  - removed - the item is only in the old listing (the Item is the old one)
+  - changed - the item is in both but is different (the old Item is in Old)
+
+A file has changed if its size, modification time or any hash shown in
+both listings differs. A directory has only changed if it is now a
+file. Use the same flags as for the saved listing, for example
+
+    rclone lsjson -R --hash remote:path > old.json
+    rclone lsjson -R --hash --diff old.json remote:path
+
+The removed items are output after the rest of the listing.
 ` + lshelp.Help,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
+		if statOnly {
+			if diffFile != "" {
+				log.Fatalf("Can't use --stat with --diff")
+			}
+			fsrc, remote := newFsStat(args[0])
+			cmd.Run(false, false, command, func() error {
+				item, err := operations.StatJSON(context.Background(), fsrc, remote, &opt)
+				if err != nil {
+					return err
+				}
+				if item == nil {
+					return errors.Errorf("%q not found", args[0])
+				}
+				out, err := json.MarshalIndent(item, "", "\t")
+				if err != nil {
+					return errors.Wrap(err, "failed to marshal list object")
+				}
+				_, err = fmt.Println(string(out))
+				if err != nil {
+					return errors.Wrap(err, "failed to write to output")
+				}
+				return nil
+			})
+			return
+		}
 		fsrc := cmd.NewFsSrc(args)
 		cmd.Run(false, false, command, func() error {
+			var old []*operations.ListJSONItem
+			if diffFile != "" {
+				var err error
+				old, err = readListing(diffFile)
+				if err != nil {
+					return err
+				}
+			}
 			fmt.Println("[")
 			first := true
-			err := operations.ListJSON(context.Background(), fsrc, "", &opt, func(item *operations.ListJSONItem) error {
+			write := func(item interface{}) error {
 				out, err := json.Marshal(item)
 				if err != nil {
 					return errors.Wrap(err, "failed to marshal list object")
@@ -120,7 +194,17 @@ can be processed line by line as each item is written one to a line.
 					return errors.Wrap(err, "failed to write to output")
 				}
 				return nil
-			})
+			}
+			var err error
+			if diffFile != "" {
+				err = operations.ListJSONDiff(context.Background(), fsrc, "", &opt, old, func(item *operations.ListJSONDiffItem) error {
+					return write(item)
+				})
+			} else {
+				err = operations.ListJSON(context.Background(), fsrc, "", &opt, func(item *operations.ListJSONItem) error {
+					return write(item)
+				})
+			}
 			if !first {
 				fmt.Println()
 			}
@@ -129,3 +213,28 @@ can be processed line by line as each item is written one to a line.
 		})
 	},
 }
+
+// readListing reads the lsjson listing saved in the file at
+// listingPath
+func readListing(listingPath string) (items []*operations.ListJSONItem, err error) {
+	in, err := os.Open(listingPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open --diff listing")
+	}
+	defer fs.CheckClose(in, &err)
+	return operations.ReadListJSON(in)
+}
+
+// newFsStat returns an Fs for the parent of remote and the leaf to
+// stat within it.
+func newFsStat(remote string) (fs.Fs, string) {
+	parent, leaf, err := fspath.Split(strings.TrimRight(remote, "/"))
+	if err != nil || leaf == "" || leaf == "." || leaf == ".." {
+		// the root of the remote
+		return cmd.NewFsDir([]string{remote}), ""
+	}
+	if parent == "" {
+		parent = "."
+	}
+	return cmd.NewFsDir([]string{parent}), leaf
+}
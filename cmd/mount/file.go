@@ -4,6 +4,8 @@ package mount
 
 import (
 	"context"
+	"os"
+	"sort"
 	"time"
 
 	"bazil.org/fuse"
@@ -28,9 +30,8 @@ func (f *File) Attr(ctx context.Context, a *fuse.Attr) (err error) {
 	modTime := f.File.ModTime()
 	Size := uint64(f.File.Size())
 	Blocks := (Size + 511) / 512
-	a.Gid = f.VFS().Opt.GID
-	a.Uid = f.VFS().Opt.UID
-	a.Mode = f.VFS().Opt.FilePerms
+	a.Uid, a.Gid = f.File.Owner()
+	a.Mode = f.File.Mode() & (os.ModePerm | os.ModeSymlink)
 	a.Size = Size
 	a.Atime = modTime
 	a.Mtime = modTime
@@ -43,9 +44,28 @@ func (f *File) Attr(ctx context.Context, a *fuse.Attr) (err error) {
 // Check interface satisfied
 var _ fusefs.NodeSetattrer = (*File)(nil)
 
-// Setattr handles attribute changes from FUSE. Currently supports ModTime and Size only
+// Setattr handles attribute changes from FUSE. Currently supports
+// ModTime and Size, and Mode, Uid and Gid with --vfs-metadata
 func (f *File) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse.SetattrResponse) (err error) {
 	defer log.Trace(f, "a=%+v", req)("err=%v", &err)
+	if f.VFS().Opt.Metadata {
+		if req.Valid.Mode() {
+			err = f.File.Chmod(req.Mode)
+		}
+		if err == nil && (req.Valid.Uid() || req.Valid.Gid()) {
+			uid, gid := -1, -1
+			if req.Valid.Uid() {
+				uid = int(req.Uid)
+			}
+			if req.Valid.Gid() {
+				gid = int(req.Gid)
+			}
+			err = f.File.Chown(uid, gid)
+		}
+		if err != nil {
+			return translateError(err)
+		}
+	}
 	if !f.VFS().Opt.NoModTime {
 		if req.Valid.Mtime() {
 			err = f.File.SetModTime(req.Mtime)
@@ -97,14 +117,32 @@ func (f *File) Fsync(ctx context.Context, req *fuse.FsyncRequest) (err error) {
 //
 // If there is no xattr by that name, returns fuse.ErrNoXattr.
 func (f *File) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) error {
-	return fuse.ENOSYS // we never implement this
+	if !f.VFS().Opt.Metadata {
+		return fuse.ENOSYS // only implemented with --vfs-metadata
+	}
+	value, ok := f.File.Xattrs()[req.Name]
+	if !ok {
+		return fuse.ErrNoXattr
+	}
+	resp.Xattr = []byte(value)
+	return nil
 }
 
 var _ fusefs.NodeGetxattrer = (*File)(nil)
 
 // Listxattr lists the extended attributes recorded for the node.
 func (f *File) Listxattr(ctx context.Context, req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) error {
-	return fuse.ENOSYS // we never implement this
+	if !f.VFS().Opt.Metadata {
+		return fuse.ENOSYS // only implemented with --vfs-metadata
+	}
+	xattrs := f.File.Xattrs()
+	names := make([]string, 0, len(xattrs))
+	for name := range xattrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	resp.Append(names...)
+	return nil
 }
 
 var _ fusefs.NodeListxattrer = (*File)(nil)
@@ -112,7 +150,10 @@ var _ fusefs.NodeListxattrer = (*File)(nil)
 // Setxattr sets an extended attribute with the given name and
 // value for the node.
 func (f *File) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) error {
-	return fuse.ENOSYS // we never implement this
+	if !f.VFS().Opt.Metadata {
+		return fuse.ENOSYS // only implemented with --vfs-metadata
+	}
+	return translateError(f.File.SetXattr(req.Name, string(req.Xattr)))
 }
 
 var _ fusefs.NodeSetxattrer = (*File)(nil)
@@ -125,3 +166,15 @@ func (f *File) Removexattr(ctx context.Context, req *fuse.RemovexattrRequest) er
 }
 
 var _ fusefs.NodeRemovexattrer = (*File)(nil)
+
+// Readlink reads a symbolic link.
+func (f *File) Readlink(ctx context.Context, req *fuse.ReadlinkRequest) (target string, err error) {
+	defer log.Trace(f, "")("target=%q, err=%v", &target, &err)
+	target, err = f.File.Readlink()
+	if err != nil {
+		return "", translateError(err)
+	}
+	return target, nil
+}
+
+var _ fusefs.NodeReadlinker = (*File)(nil)
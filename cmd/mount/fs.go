@@ -45,7 +45,37 @@ func (f *FS) Root() (node fusefs.Node, err error) {
 	if err != nil {
 		return nil, translateError(err)
 	}
-	return &Dir{root, f}, nil
+	// Cache the node so changes in the root can be invalidated
+	node, ok := root.Sys().(fusefs.Node)
+	if !ok {
+		node = &Dir{root, f}
+		root.SetSys(node)
+	}
+	return node, nil
+}
+
+// changeNotify invalidates the kernel cache of entries changed on the
+// remote so they are looked up again
+func (f *FS) changeNotify(parent *vfs.Dir, leaf string, node vfs.Node) {
+	// Do this in the background otherwise we can deadlock with
+	// the kernel if it is waiting on a request to this directory
+	go func() {
+		if dirNode, ok := parent.Sys().(fusefs.Node); ok {
+			err := f.server.InvalidateEntry(dirNode, leaf)
+			if err != nil && err != fuse.ErrNotCached {
+				fs.Debugf(parent, "Failed to invalidate %q: %v", leaf, err)
+			}
+		}
+		if node == nil {
+			return
+		}
+		if fuseNode, ok := node.Sys().(fusefs.Node); ok {
+			err := f.server.InvalidateNodeData(fuseNode)
+			if err != nil && err != fuse.ErrNotCached {
+				fs.Debugf(node, "Failed to invalidate data: %v", err)
+			}
+		}
+	}()
 }
 
 // Check interface satisfied
@@ -97,6 +127,8 @@ func translateError(err error) error {
 		return fuse.Errno(syscall.EROFS)
 	case vfs.ENOSYS, fs.ErrorNotImplemented:
 		return fuse.ENOSYS
+	case vfs.ENOTSUP:
+		return fuse.Errno(syscall.ENOTSUP)
 	case vfs.EINVAL:
 		return fuse.Errno(syscall.EINVAL)
 	}
@@ -92,6 +92,7 @@ func mount(VFS *vfs.VFS, mountpoint string, opt *mountlib.Options) (<-chan error
 
 	filesys := NewFS(VFS, opt)
 	filesys.server = fusefs.New(c, nil)
+	VFS.RegisterChangeNotify(filesys.changeNotify)
 
 	// Serve the mount point in the background returning error to errChan
 	errChan := make(chan error, 1)
@@ -149,3 +149,19 @@ func (f *FileHandle) Setattr(ctx context.Context, in *fuse.SetAttrIn, out *fuse.
 }
 
 var _ fusefs.FileSetattrer = (*FileHandle)(nil)
+
+// fallocKeepSize is FALLOC_FL_KEEP_SIZE from fallocate(2) - the other
+// modes aren't supported
+const fallocKeepSize = 0x01
+
+// Allocate preallocates space for future writes, so they will
+// never encounter ENOSPC.
+func (f *FileHandle) Allocate(ctx context.Context, off uint64, size uint64, mode uint32) (errno syscall.Errno) {
+	defer log.Trace(f, "off=%d, size=%d, mode=%#x", off, size, mode)("errno=%v", &errno)
+	if mode&^fallocKeepSize != 0 {
+		return syscall.EOPNOTSUPP
+	}
+	return translateError(f.h.Allocate(int64(off), int64(size), mode&fallocKeepSize != 0))
+}
+
+var _ fusefs.FileAllocater = (*FileHandle)(nil)
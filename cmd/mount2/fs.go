@@ -43,6 +43,29 @@ func (f *FS) Root() (node *Node, err error) {
 	return newNode(f, root), nil
 }
 
+// changeNotify invalidates the kernel cache of entries changed on the
+// remote so they are looked up again
+func (f *FS) changeNotify(parent *vfs.Dir, leaf string, node vfs.Node) {
+	// Do this in the background otherwise we can deadlock with
+	// the kernel if it is waiting on a request to this directory
+	go func() {
+		if dirNode, ok := parent.Sys().(*Node); ok && dirNode.known() {
+			if errno := dirNode.NotifyEntry(leaf); errno != 0 && errno != syscall.ENOENT {
+				fs.Debugf(parent, "Failed to invalidate %q: %v", leaf, errno)
+			}
+		}
+		if node == nil {
+			return
+		}
+		if fuseNode, ok := node.Sys().(*Node); ok && fuseNode.known() {
+			// offset 0 and length 0 means the attributes and all the data
+			if errno := fuseNode.NotifyContent(0, 0); errno != 0 && errno != syscall.ENOENT {
+				fs.Debugf(node, "Failed to invalidate data: %v", errno)
+			}
+		}
+	}()
+}
+
 // SetDebug if called, provide debug output through the log package.
 func (f *FS) SetDebug(debug bool) {
 	fs.Debugf(f.f, "SetDebug %v", debug)
@@ -53,6 +76,8 @@ func getMode(node os.FileInfo) uint32 {
 	Mode := node.Mode().Perm()
 	if node.IsDir() {
 		Mode |= fuse.S_IFDIR
+	} else if node.Mode()&os.ModeSymlink != 0 {
+		Mode |= fuse.S_IFLNK
 	} else {
 		Mode |= fuse.S_IFREG
 	}
@@ -66,9 +91,11 @@ func setAttr(node vfs.Node, attr *fuse.Attr) {
 	Blocks := (Size + BlockSize - 1) / BlockSize
 	modTime := node.ModTime()
 	// set attributes
-	vfs := node.VFS()
-	attr.Owner.Gid = vfs.Opt.GID
-	attr.Owner.Uid = vfs.Opt.UID
+	if file, ok := node.(*vfs.File); ok {
+		attr.Owner.Uid, attr.Owner.Gid = file.Owner()
+	} else {
+		attr.Owner.Uid, attr.Owner.Gid = node.VFS().Opt.UID, node.VFS().Opt.GID
+	}
 	attr.Mode = getMode(node)
 	attr.Size = Size
 	attr.Nlink = 1
@@ -124,6 +151,8 @@ func translateError(err error) syscall.Errno {
 		return syscall.EROFS
 	case vfs.ENOSYS, fs.ErrorNotImplemented:
 		return syscall.ENOSYS
+	case vfs.ENOTSUP:
+		return syscall.ENOTSUP
 	case vfs.EINVAL:
 		return syscall.EINVAL
 	}
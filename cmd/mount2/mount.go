@@ -30,7 +30,7 @@ func mountOptions(fsys *FS, f fs.Fs) (mountOpts *fuse.MountOptions) {
 		AllowOther:    fsys.opt.AllowOther,
 		FsName:        device,
 		Name:          "rclone",
-		DisableXAttrs: true,
+		DisableXAttrs: !fsys.VFS.Opt.Metadata,
 		Debug:         fsys.opt.DebugFUSE,
 		MaxReadAhead:  int(fsys.opt.MaxReadAhead),
 
@@ -183,6 +183,7 @@ func mount(VFS *vfs.VFS, mountpoint string, opt *mountlib.Options) (<-chan error
 	}
 
 	rawFS := fusefs.NewNodeFS(root, &opts)
+	fsys.VFS.RegisterChangeNotify(fsys.changeNotify)
 	server, err := fuse.NewServer(rawFS, mountpoint, &opts.MountOptions)
 	if err != nil {
 		return nil, nil, err
@@ -6,10 +6,12 @@ import (
 	"context"
 	"os"
 	"path"
+	"sort"
 	"syscall"
 
 	fusefs "github.com/hanwen/go-fuse/v2/fs"
 	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd/mountlib"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/log"
@@ -43,6 +45,12 @@ func newNode(fsys *FS, vfsNode vfs.Node) (node *Node) {
 	return node
 }
 
+// known returns true if the kernel knows about this node so it can
+// be sent notifications
+func (n *Node) known() bool {
+	return n.Operations() != nil && !n.Forgotten()
+}
+
 // String used for pretty printing.
 func (n *Node) String() string {
 	return n.node.Path()
@@ -84,17 +92,16 @@ func (n *Node) lookupVfsNodeInDir(leaf string) (vfsNode vfs.Node, errno syscall.
 // will not work.
 func (n *Node) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno {
 	defer log.Trace(n, "")("out=%+v", &out)
-	out = new(fuse.StatfsOut)
 	const blockSize = 4096
-	const fsBlocks = (1 << 50) / blockSize
-	out.Blocks = fsBlocks  // Total data blocks in file system.
-	out.Bfree = fsBlocks   // Free blocks in file system.
-	out.Bavail = fsBlocks  // Free blocks in file system if you're not root.
-	out.Files = 1e9        // Total files in file system.
-	out.Ffree = 1e9        // Free files in file system.
-	out.Bsize = blockSize  // Block size
-	out.NameLen = 255      // Maximum file name length?
-	out.Frsize = blockSize // Fragment size, smallest addressable data size in the file system.
+	total, _, free := n.fsys.VFS.Statfs()
+	out.Blocks = uint64(total) / blockSize // Total data blocks in file system.
+	out.Bfree = uint64(free) / blockSize   // Free blocks in file system.
+	out.Bavail = out.Bfree                 // Free blocks in file system if you're not root.
+	out.Files = 1e9                        // Total files in file system.
+	out.Ffree = 1e9                        // Free files in file system.
+	out.Bsize = blockSize                  // Block size
+	out.NameLen = 255                      // Maximum file name length?
+	out.Frsize = blockSize                 // Fragment size, smallest addressable data size in the file system.
 	mountlib.ClipBlocks(&out.Blocks)
 	mountlib.ClipBlocks(&out.Bfree)
 	mountlib.ClipBlocks(&out.Bavail)
@@ -139,6 +146,30 @@ func (n *Node) Setattr(ctx context.Context, f fusefs.FileHandle, in *fuse.SetAtt
 		out.Attr.Mtime = uint64(mtime.Unix())
 		out.Attr.Mtimensec = uint32(mtime.Nanosecond())
 	}
+	if file, ok := n.node.(*vfs.File); ok && n.fsys.VFS.Opt.Metadata {
+		if mode, ok := in.GetMode(); ok {
+			err = file.Chmod(os.FileMode(mode) & os.ModePerm)
+			if err != nil {
+				return translateError(err)
+			}
+		}
+		uid, uidOK := in.GetUID()
+		gid, gidOK := in.GetGID()
+		if uidOK || gidOK {
+			newUID, newGID := -1, -1
+			if uidOK {
+				newUID = int(uid)
+			}
+			if gidOK {
+				newGID = int(gid)
+			}
+			err = file.Chown(newUID, newGID)
+			if err != nil {
+				return translateError(err)
+			}
+		}
+		setAttr(n.node, &out.Attr)
+	}
 	return 0
 }
 
@@ -233,8 +264,21 @@ func (ds *dirStream) HasNext() bool {
 // indicate I/O errors
 func (ds *dirStream) Next() (de fuse.DirEntry, errno syscall.Errno) {
 	// defer log.Trace(nil, "")("de=%+v, errno=%v", &de, &errno)
-	fi := ds.nodes[ds.i]
-	de = fuse.DirEntry{
+	de = dirEntry(ds.nodes[ds.i])
+	ds.i++
+	return de, 0
+}
+
+// Close releases resources related to this directory
+// stream.
+func (ds *dirStream) Close() {
+}
+
+var _ fusefs.DirStream = (*dirStream)(nil)
+
+// dirEntry makes a fuse.DirEntry for fi
+func dirEntry(fi os.FileInfo) fuse.DirEntry {
+	return fuse.DirEntry{
 		// Mode is the file's mode. Only the high bits (e.g. S_IFDIR)
 		// are considered.
 		Mode: getMode(fi),
@@ -245,16 +289,88 @@ func (ds *dirStream) Next() (de fuse.DirEntry, errno syscall.Errno) {
 		// Ino is the inode number.
 		Ino: 0, // FIXME
 	}
-	ds.i++
+}
+
+// pagedDirStream is a DirStream which returns the entries of a
+// directory page by page as they are listed by a background
+// goroutine. This is used with --vfs-lazy-listing so the first
+// entries of very large directories can be returned quickly.
+type pagedDirStream struct {
+	pages   chan vfs.Nodes
+	quit    chan struct{}
+	done    chan struct{}
+	err     error // error from listing - read after pages is closed
+	nodes   vfs.Nodes
+	errDone bool // set when err has been returned
+}
+
+// newPagedDirStream starts listing dir in the background
+func newPagedDirStream(dir *vfs.Dir) *pagedDirStream {
+	ds := &pagedDirStream{
+		pages: make(chan vfs.Nodes),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go func() {
+		defer close(ds.done)
+		defer close(ds.pages)
+		ds.err = dir.ReadDirPaged(func(items vfs.Nodes) error {
+			select {
+			case ds.pages <- items:
+				return nil
+			case <-ds.quit:
+				return errReaddirClosed
+			}
+		})
+		if ds.err == errReaddirClosed {
+			ds.err = nil
+		}
+	}()
+	return ds
+}
+
+// errReaddirClosed is used to stop the listing if the stream is closed
+var errReaddirClosed = errors.New("directory stream closed")
+
+// HasNext indicates if there are further entries. HasNext
+// might be called on already closed streams.
+func (ds *pagedDirStream) HasNext() bool {
+	for len(ds.nodes) == 0 {
+		nodes, ok := <-ds.pages
+		if !ok {
+			// return the error, if any, from Next
+			return ds.err != nil && !ds.errDone
+		}
+		ds.nodes = nodes
+	}
+	return true
+}
+
+// Next retrieves the next entry. It is only called if HasNext
+// has previously returned true.  The Errno return may be used to
+// indicate I/O errors
+func (ds *pagedDirStream) Next() (de fuse.DirEntry, errno syscall.Errno) {
+	if len(ds.nodes) == 0 {
+		ds.errDone = true
+		return de, translateError(ds.err)
+	}
+	de = dirEntry(ds.nodes[0])
+	ds.nodes = ds.nodes[1:]
 	return de, 0
 }
 
 // Close releases resources related to this directory
 // stream.
-func (ds *dirStream) Close() {
+func (ds *pagedDirStream) Close() {
+	select {
+	case <-ds.quit:
+	default:
+		close(ds.quit)
+	}
+	<-ds.done
 }
 
-var _ fusefs.DirStream = (*dirStream)(nil)
+var _ fusefs.DirStream = (*pagedDirStream)(nil)
 
 // Readdir opens a stream of directory entries.
 //
@@ -272,6 +388,9 @@ func (n *Node) Readdir(ctx context.Context) (ds fusefs.DirStream, errno syscall.
 	if !n.node.IsDir() {
 		return nil, syscall.ENOTDIR
 	}
+	if dir, ok := n.node.(*vfs.Dir); ok && n.fsys.VFS.Opt.LazyListing {
+		return newPagedDirStream(dir), 0
+	}
 	fh, err := n.node.Open(os.O_RDONLY)
 	if err != nil {
 		return nil, translateError(err)
@@ -355,6 +474,42 @@ func (n *Node) Create(ctx context.Context, name string, flags uint32, mode uint3
 
 var _ = (fusefs.NodeCreater)((*Node)(nil))
 
+// Symlink is similar to Lookup, but must create a new symbolic
+// link entry and Inode.
+func (n *Node) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (inode *fusefs.Inode, errno syscall.Errno) {
+	defer log.Trace(n, "target=%q, name=%q", target, name)("inode=%v, errno=%v", &inode, &errno)
+	dir, ok := n.node.(*vfs.Dir)
+	if !ok {
+		return nil, syscall.ENOTDIR
+	}
+	file, err := dir.Symlink(target, name)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	newNode := newNode(n.fsys, file)
+	n.fsys.setEntryOut(newNode.node, out)
+	newInode := n.NewInode(ctx, newNode, fusefs.StableAttr{Mode: out.Attr.Mode})
+	return newInode, 0
+}
+
+var _ = (fusefs.NodeSymlinker)((*Node)(nil))
+
+// Readlink reads the content of a symlink.
+func (n *Node) Readlink(ctx context.Context) (target []byte, errno syscall.Errno) {
+	defer log.Trace(n, "")("target=%q, errno=%v", &target, &errno)
+	file, ok := n.node.(*vfs.File)
+	if !ok {
+		return nil, syscall.EINVAL
+	}
+	link, err := file.Readlink()
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return []byte(link), 0
+}
+
+var _ = (fusefs.NodeReadlinker)((*Node)(nil))
+
 // Unlink should remove a child from this directory.  If the
 // return status is OK, the Inode is removed as child in the
 // FS tree automatically. Default is to return EROFS.
@@ -404,3 +559,63 @@ func (n *Node) Rename(ctx context.Context, oldName string, newParent fusefs.Inod
 }
 
 var _ = (fusefs.NodeRenamer)((*Node)(nil))
+
+// Getxattr reads the extended attribute attr into dest returning
+// its size. It is only enabled with --vfs-metadata.
+func (n *Node) Getxattr(ctx context.Context, attr string, dest []byte) (size uint32, errno syscall.Errno) {
+	defer log.Trace(n, "attr=%q", attr)("size=%d, errno=%v", &size, &errno)
+	file, ok := n.node.(*vfs.File)
+	if !ok {
+		return 0, syscall.Errno(fuse.ENOATTR)
+	}
+	value, ok := file.Xattrs()[attr]
+	if !ok {
+		return 0, syscall.Errno(fuse.ENOATTR)
+	}
+	if len(dest) < len(value) {
+		return uint32(len(value)), syscall.ERANGE
+	}
+	return uint32(copy(dest, value)), 0
+}
+
+var _ = (fusefs.NodeGetxattrer)((*Node)(nil))
+
+// Setxattr sets the extended attribute attr to data. It is only
+// enabled with --vfs-metadata.
+func (n *Node) Setxattr(ctx context.Context, attr string, data []byte, flags uint32) (errno syscall.Errno) {
+	defer log.Trace(n, "attr=%q, flags=%#x", attr, flags)("errno=%v", &errno)
+	file, ok := n.node.(*vfs.File)
+	if !ok {
+		return syscall.ENOTSUP
+	}
+	return translateError(file.SetXattr(attr, string(data)))
+}
+
+var _ = (fusefs.NodeSetxattrer)((*Node)(nil))
+
+// Listxattr writes the null terminated names of the extended
+// attributes into dest returning their size. It is only enabled with
+// --vfs-metadata.
+func (n *Node) Listxattr(ctx context.Context, dest []byte) (size uint32, errno syscall.Errno) {
+	defer log.Trace(n, "")("size=%d, errno=%v", &size, &errno)
+	file, ok := n.node.(*vfs.File)
+	if !ok {
+		return 0, 0
+	}
+	var names []string
+	for name := range file.Xattrs() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var list []byte
+	for _, name := range names {
+		list = append(list, name...)
+		list = append(list, 0)
+	}
+	if len(dest) < len(list) {
+		return uint32(len(list)), syscall.ERANGE
+	}
+	return uint32(copy(dest, list)), 0
+}
+
+var _ = (fusefs.NodeListxattrer)((*Node)(nil))
@@ -44,16 +44,22 @@ type Options struct {
 	NoAppleXattr       bool
 	DaemonTimeout      time.Duration // OSXFUSE only
 	AsyncRead          bool
-	NetworkMode        bool // Windows only
+	NetworkMode        bool          // Windows only
+	VolumeSerial       string        // Windows only
+	SystemMount        bool          // Windows only
+	HealthCheck        time.Duration // interval between health checks, 0 for none
+	HealthCheckTimeout time.Duration // time a health check may take
+	AutoRemount        bool          // remount if the mount fails
 }
 
 // DefaultOpt is the default values for creating the mount
 var DefaultOpt = Options{
-	MaxReadAhead:  128 * 1024,
-	AttrTimeout:   1 * time.Second, // how long the kernel caches attribute for
-	NoAppleDouble: true,            // use noappledouble by default
-	NoAppleXattr:  false,           // do not use noapplexattr by default
-	AsyncRead:     true,            // do async reads by default
+	MaxReadAhead:       128 * 1024,
+	AttrTimeout:        1 * time.Second, // how long the kernel caches attribute for
+	NoAppleDouble:      true,            // use noappledouble by default
+	NoAppleXattr:       false,           // do not use noapplexattr by default
+	AsyncRead:          true,            // do async reads by default
+	HealthCheckTimeout: time.Minute,
 }
 
 type (
@@ -97,6 +103,9 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &Opt.AsyncRead, "async-read", "", Opt.AsyncRead, "Use asynchronous reads. Not supported on Windows.")
 	flags.FVarP(flagSet, &Opt.MaxReadAhead, "max-read-ahead", "", "The number of bytes that can be prefetched for sequential reads. Not supported on Windows.")
 	flags.BoolVarP(flagSet, &Opt.WritebackCache, "write-back-cache", "", Opt.WritebackCache, "Makes kernel buffer writes before sending them to rclone. Without this, writethrough caching is used. Not supported on Windows.")
+	flags.DurationVarP(flagSet, &Opt.HealthCheck, "health-check-interval", "", Opt.HealthCheck, "Check the mount point is working this often (0 to disable).")
+	flags.DurationVarP(flagSet, &Opt.HealthCheckTimeout, "health-check-timeout", "", Opt.HealthCheckTimeout, "Fail a health check which takes longer than this.")
+	flags.BoolVarP(flagSet, &Opt.AutoRemount, "auto-remount", "", Opt.AutoRemount, "Remount if the mount fails or a health check does.")
 	// Windows and OSX
 	flags.StringVarP(flagSet, &Opt.VolumeName, "volname", "", Opt.VolumeName, "Set the volume name. Supported on Windows and OSX only.")
 	// OSX only
@@ -104,6 +113,8 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &Opt.NoAppleXattr, "noapplexattr", "", Opt.NoAppleXattr, "Ignore all \"com.apple.*\" extended attributes. Supported on OSX only.")
 	// Windows only
 	flags.BoolVarP(flagSet, &Opt.NetworkMode, "network-mode", "", Opt.NetworkMode, "Mount as remote network drive, instead of fixed disk drive. Supported on Windows only")
+	flags.StringVarP(flagSet, &Opt.VolumeSerial, "volume-serial", "", Opt.VolumeSerial, "Set the volume serial number as 8 hex digits, e.g. 1A2B3C4D. Supported on Windows only")
+	flags.BoolVarP(flagSet, &Opt.SystemMount, "system-mount", "", Opt.SystemMount, "Make the drive visible to all users, not just the current one. Needs administrator rights. Supported on Windows only")
 }
 
 // Check if folder is empty
@@ -218,6 +229,11 @@ or newer on some platforms depending on the underlying FUSE library in use.
 To run rclone @ on Windows, you will need to
 download and install [WinFsp](http://www.secfs.net/winfsp/).
 
+If WinFsp can't be found then rclone @ will stop with an error saying
+so rather than failing part way through mounting. The same error is
+returned by the |mount/mount| call of the [remote control](/rc/),
+so programs mounting through it can ask the user to install WinFsp.
+
 [WinFsp](https://github.com/billziss-gh/winfsp) is an open source
 Windows File System Proxy which makes it easy to write user space file
 systems for Windows.  It provides a FUSE emulation layer which rclone
@@ -295,6 +311,12 @@ must be with just a single backslash prefix in this case.
 
 *Note:* In previous versions of rclone this was the only supported method.
 
+Windows identifies volumes by a serial number, shown by |vol X:|,
+which some programs use to recognise a drive they have seen before,
+for example to keep licences or library locations. This is normally
+different for every mount. Use |--volume-serial| to give the mount a
+fixed serial number as 8 hex digits, for example |--volume-serial 1A2B3C4D|.
+
 [Read more about drive mapping](https://en.wikipedia.org/wiki/Drive_mapping)
 
 See also [Limitations](#limitations) section below.
@@ -372,6 +394,15 @@ the configuration file from your profile unless you tell it to
 with the [|--config|](https://rclone.org/docs/#config-config-file) option.
 Read more in the [install documentation](https://rclone.org/install/).
 
+Alternatively, when mounting to a drive letter, the |--system-mount|
+flag creates the drive in the global namespace so it is visible to
+all users and sessions on the computer, rather than just to the user
+which ran the mount. This needs rclone to be run as Administrator (or
+as the SYSTEM account) and is not supported with directory
+mountpoints.
+
+    rclone @ remote:path/to/files X: --system-mount
+
 Note that mapping to a directory path, instead of a drive letter,
 does not suffer from the same limitations.
 
@@ -444,6 +475,23 @@ after the mountpoint has been successfully set up.
 Units having the rclone @ service specified as a requirement
 will see all files and folders immediately in this mode.
 
+### Health checks and remounting
+
+Use |--health-check-interval 1m| to check the mount is working every
+minute by reading the mount point through the kernel, as a program
+using the mount would. A check fails if it returns an error or takes
+longer than |--health-check-timeout| (default 1m).
+
+With |--auto-remount| a mount which fails a health check, or which the
+kernel disconnects with an error, is unmounted and mounted again,
+retrying with backoff, so that a dropped connection doesn't leave a
+dead mount point until someone notices. Without it failures are just
+logged. An unmount from outside rclone, eg with |fusermount -u|, still
+stops rclone @.
+
+The failures and the action taken are logged and can be read with the
+|mount/health| remote control command when running with |--rc|.
+
 ### chunked reading
 
 |--vfs-read-chunk-size| will enable reading the source objects in parts.
@@ -579,11 +627,18 @@ func Mount(VFS *vfs.VFS, mountpoint string, mount MountFn, opt *Options) error {
 	}
 
 	// Unmount on exit
-	var finaliseOnce sync.Once
+	var (
+		unmountMu    sync.Mutex // protect unmount and finalised
+		finalised    bool
+		finaliseOnce sync.Once
+	)
 	finalise := func() {
 		finaliseOnce.Do(func() {
 			_ = sysdnotify.Stopping()
+			unmountMu.Lock()
+			finalised = true
 			_ = unmount()
+			unmountMu.Unlock()
 		})
 	}
 	fnHandle := atexit.Register(finalise)
@@ -598,12 +653,57 @@ func Mount(VFS *vfs.VFS, mountpoint string, mount MountFn, opt *Options) error {
 	sigHup := make(chan os.Signal, 1)
 	signal.Notify(sigHup, syscall.SIGHUP)
 
+	// Check the health of the mount if required
+	var healthTick <-chan time.Time
+	if opt.HealthCheck > 0 {
+		ticker := time.NewTicker(opt.HealthCheck)
+		defer ticker.Stop()
+		healthTick = ticker.C
+	}
+	sup := newSupervisor(mountpoint)
+	defer sup.remove()
+
+	// remount unmounts the failed mount and mounts it again, retrying
+	// with backoff until it works
+	remount := func() {
+		unmountMu.Lock()
+		_ = unmount()
+		unmountMu.Unlock()
+		backoff := remountMinBackoff
+		for {
+			unmountMu.Lock()
+			if finalised {
+				unmountMu.Unlock()
+				return
+			}
+			newErrChan, newUnmount, mountErr := mount(VFS, mountpoint, opt)
+			if mountErr == nil {
+				errChan, unmount = newErrChan, newUnmount
+			}
+			unmountMu.Unlock()
+			if mountErr == nil {
+				break
+			}
+			sup.incident(mountErr, "retrying remount in "+backoff.String())
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > remountMaxBackoff {
+				backoff = remountMaxBackoff
+			}
+		}
+		sup.remounted()
+	}
+
 waitloop:
 	for {
 		select {
 		// umount triggered outside the app
 		case err = <-errChan:
-			break waitloop
+			if err == nil || !opt.AutoRemount {
+				break waitloop
+			}
+			sup.incident(err, "remounting")
+			remount()
 		// user sent SIGHUP to clear the cache
 		case <-sigHup:
 			root, err := VFS.Root()
@@ -612,6 +712,19 @@ waitloop:
 			} else {
 				root.ForgetAll()
 			}
+		// time for a health check
+		case <-healthTick:
+			err := checkHealth(mountpoint, opt.HealthCheckTimeout)
+			sup.checked(err)
+			if err == nil {
+				continue
+			}
+			if !opt.AutoRemount {
+				sup.incident(err, "no action as --auto-remount not set")
+				continue
+			}
+			sup.incident(err, "remounting")
+			remount()
 		}
 	}
 
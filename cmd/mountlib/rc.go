@@ -9,6 +9,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/vfs"
 	"github.com/rclone/rclone/vfs/vfscommon"
@@ -23,6 +24,7 @@ type MountInfo struct {
 	Fs         string    `json:"Fs"`
 	MountOpt   *Options
 	VFSOpt     *vfscommon.Options
+	FilterOpt  *filter.Opt `json:",omitempty"`
 }
 
 var (
@@ -60,21 +62,46 @@ This takes the following parameters
 - mountType: One of the values (mount, cmount, mount2) specifies the mount implementation to use
 - mountOpt: a JSON object with Mount options in.
 - vfsOpt: a JSON object with VFS options in.
+- _filter: a JSON object with filter options in to use for this mount only.
+- _config: a JSON object with global options in to use for this mount only.
+
+Each mount gets its own VFS so mounts of the same remote can use
+different vfsOpt, for example a different CacheMode, and a different
+set of filters.
 
 Eg
 
     rclone rc mount/mount fs=mydrive: mountPoint=/home/<user>/mountPoint
     rclone rc mount/mount fs=mydrive: mountPoint=/home/<user>/mountPoint mountType=mount
     rclone rc mount/mount fs=TestDrive: mountPoint=/mnt/tmp vfsOpt='{"CacheMode": 2}' mountOpt='{"AllowOther": true}'
+    rclone rc mount/mount fs=TestDrive: mountPoint=/mnt/photos _filter='{"IncludeRule": ["*.jpg"]}'
 
 The vfsOpt are as described in options/get and can be seen in the the
 "vfs" section when running and the mountOpt can be seen in the "mount" section.
+The _filter options can be seen in the "filter" section.
 
     rclone rc options/get
 `,
 	})
 }
 
+// ResolveMountMethod returns the mount function called mountType or
+// if mountType is empty the first available of mount, cmount and
+// mount2.  It returns a nil MountFn if none is found.
+func ResolveMountMethod(mountType string) (string, MountFn) {
+	mountMu.Lock()
+	defer mountMu.Unlock()
+	if mountType != "" {
+		return mountType, mountFns[mountType]
+	}
+	for _, mountType := range []string{"mount", "cmount", "mount2"} {
+		if mountFns[mountType] != nil {
+			return mountType, mountFns[mountType]
+		}
+	}
+	return "", nil
+}
+
 // mountRc allows the mount command to be run from rc
 func mountRc(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	mountPoint, err := in.GetString("mountPoint")
@@ -94,37 +121,28 @@ func mountRc(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 		return nil, err
 	}
 
-	mountType, err := in.GetString("mountType")
+	mountType, _ := in.GetString("mountType")
+	mountType, mountFn := ResolveMountMethod(mountType)
 
 	mountMu.Lock()
 	defer mountMu.Unlock()
 
-	if err != nil || mountType == "" {
-		if mountFns["mount"] != nil {
-			mountType = "mount"
-		} else if mountFns["cmount"] != nil {
-			mountType = "cmount"
-		} else if mountFns["mount2"] != nil {
-			mountType = "mount2"
-		}
-	}
-
 	// Get Fs.fs to be mounted from fs parameter in the params
 	fdst, err := rc.GetFs(ctx, in)
 	if err != nil {
 		return nil, err
 	}
 
-	if mountFns[mountType] != nil {
-		VFS := vfs.New(fdst, &vfsOpt)
-		_, unmountFn, err := mountFns[mountType](VFS, mountPoint, &mountOpt)
+	if mountFn != nil {
+		VFS := vfs.NewWithContext(ctx, fdst, &vfsOpt)
+		_, unmountFn, err := mountFn(VFS, mountPoint, &mountOpt)
 
 		if err != nil {
 			log.Printf("mount FAILED: %v", err)
 			return nil, err
 		}
 		// Add mount to list if mount point was successfully created
-		liveMounts[mountPoint] = MountInfo{
+		mountInfo := MountInfo{
 			unmountFn:  unmountFn,
 			MountedOn:  time.Now(),
 			Fs:         fdst.Name(),
@@ -132,6 +150,11 @@ func mountRc(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 			VFSOpt:     &vfsOpt,
 			MountOpt:   &mountOpt,
 		}
+		// Only show the filters if they were set for this mount
+		if fi := filter.GetConfig(ctx); fi != filter.GetConfig(context.Background()) {
+			mountInfo.FilterOpt = &fi.Opt
+		}
+		liveMounts[mountPoint] = mountInfo
 
 		fs.Debugf(nil, "Mount for %s created at %s using %s", fdst.String(), mountPoint, mountType)
 		return nil, nil
@@ -224,6 +247,10 @@ This takes no parameters and returns
 
 - mountPoints: list of current mount points
 
+Each mount point has the MountPoint, MountedOn and Fs along with the
+MountOpt and VFSOpt it was mounted with and the FilterOpt if _filter
+was passed to mount/mount.
+
 Eg
 
     rclone rc mount/listmounts
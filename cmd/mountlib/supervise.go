@@ -0,0 +1,200 @@
+// Health checks and auto-remount for mounts
+
+package mountlib
+
+import (
+	"context"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/rc"
+)
+
+const (
+	maxIncidents      = 100              // number of incidents kept for each mount
+	remountMinBackoff = time.Second      // first wait between failed remounts
+	remountMaxBackoff = 10 * time.Minute // longest wait between failed remounts
+)
+
+// Incident records a failure of a supervised mount
+type Incident struct {
+	Time   time.Time `json:"time"`
+	Error  string    `json:"error"`
+	Action string    `json:"action"`
+}
+
+// supervisor keeps the health of a mount
+type supervisor struct {
+	mu         sync.Mutex
+	mountpoint string
+	healthy    bool
+	lastCheck  time.Time
+	remounts   int
+	incidents  []Incident
+}
+
+var (
+	// mutex to protect supervisors
+	supervisorsMu sync.Mutex
+	// Map of mount point => supervisor for the mounts made by the mount commands
+	supervisors = map[string]*supervisor{}
+)
+
+// newSupervisor makes a supervisor for mountpoint and registers it
+func newSupervisor(mountpoint string) *supervisor {
+	s := &supervisor{
+		mountpoint: mountpoint,
+		healthy:    true,
+	}
+	supervisorsMu.Lock()
+	supervisors[mountpoint] = s
+	supervisorsMu.Unlock()
+	return s
+}
+
+// remove unregisters the supervisor
+func (s *supervisor) remove() {
+	supervisorsMu.Lock()
+	delete(supervisors, s.mountpoint)
+	supervisorsMu.Unlock()
+}
+
+// checked records the result of a health check
+func (s *supervisor) checked(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastCheck = time.Now()
+	s.healthy = err == nil
+}
+
+// incident logs err and the action taken about it
+func (s *supervisor) incident(err error, action string) {
+	fs.Errorf(nil, "Mount %q: %v - %s", s.mountpoint, err, action)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.healthy = false
+	s.incidents = append(s.incidents, Incident{
+		Time:   time.Now(),
+		Error:  err.Error(),
+		Action: action,
+	})
+	if len(s.incidents) > maxIncidents {
+		s.incidents = s.incidents[len(s.incidents)-maxIncidents:]
+	}
+}
+
+// remounted records a successful remount
+func (s *supervisor) remounted() {
+	fs.Logf(nil, "Mount %q: remounted", s.mountpoint)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.healthy = true
+	s.remounts++
+}
+
+// status returns the state of the mount for the rc
+func (s *supervisor) status() rc.Params {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	incidents := make([]Incident, len(s.incidents))
+	copy(incidents, s.incidents)
+	return rc.Params{
+		"mountPoint": s.mountpoint,
+		"healthy":    s.healthy,
+		"lastCheck":  s.lastCheck,
+		"remounts":   s.remounts,
+		"incidents":  incidents,
+	}
+}
+
+// probeMount stats the mountpoint and reads an entry from it through
+// the kernel which fails or hangs if the mount is dead
+func probeMount(mountpoint string) (err error) {
+	fi, err := os.Stat(mountpoint)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return errors.New("mount point is not a directory")
+	}
+	dir, err := os.Open(mountpoint)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(dir, &err)
+	_, err = dir.Readdirnames(1)
+	if err == io.EOF {
+		err = nil
+	}
+	return err
+}
+
+// checkHealth runs probeMount on mountpoint failing if it doesn't
+// return within timeout.
+//
+// A probe of a hung mount may never return so it is left running in
+// the background.
+func checkHealth(mountpoint string, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- probeMount(mountpoint)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return errors.Wrap(err, "health check failed")
+		}
+		return nil
+	case <-time.After(timeout):
+		return errors.Errorf("health check timed out after %v", timeout)
+	}
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:         "mount/health",
+		AuthRequired: true,
+		Fn:           healthRc,
+		Title:        "Show the health of the mounts",
+		Help: `This shows the state of the mounts made by the mount commands which
+are checked with --health-check-interval and remounted with
+--auto-remount.
+
+This takes no parameters and returns
+
+- mounts: list of mounts each with
+    - mountPoint: where it is mounted
+    - healthy: true if the last health check passed
+    - lastCheck: time of the last health check
+    - remounts: number of times it has been remounted
+    - incidents: list of the last 100 failures each with the time, error and action taken
+
+Eg
+
+    rclone rc mount/health
+`,
+	})
+}
+
+// healthRc returns the health of the supervised mounts
+func healthRc(_ context.Context, in rc.Params) (out rc.Params, err error) {
+	supervisorsMu.Lock()
+	mountpoints := make([]string, 0, len(supervisors))
+	for mountpoint := range supervisors {
+		mountpoints = append(mountpoints, mountpoint)
+	}
+	sort.Strings(mountpoints)
+	mounts := make([]rc.Params, 0, len(mountpoints))
+	for _, mountpoint := range mountpoints {
+		mounts = append(mounts, supervisors[mountpoint].status())
+	}
+	supervisorsMu.Unlock()
+	return rc.Params{
+		"mounts": mounts,
+	}, nil
+}
@@ -0,0 +1,103 @@
+package mountlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/vfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHealth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-mountlib-health")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// Empty and non empty directories are healthy
+	assert.NoError(t, checkHealth(dir, time.Minute))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello"), 0666))
+	assert.NoError(t, checkHealth(dir, time.Minute))
+
+	// Missing mount points and files aren't
+	assert.Error(t, checkHealth(filepath.Join(dir, "missing"), time.Minute))
+	err = checkHealth(filepath.Join(dir, "file.txt"), time.Minute)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a directory")
+}
+
+func TestSupervisor(t *testing.T) {
+	ctx := context.Background()
+	s := newSupervisor("/mnt/test")
+	defer s.remove()
+
+	s.checked(nil)
+	for i := 0; i < maxIncidents+1; i++ {
+		s.incident(fmt.Errorf("error %d", i), "remounting")
+	}
+	out, err := healthRc(ctx, nil)
+	require.NoError(t, err)
+	mounts := out["mounts"]
+	require.Len(t, mounts, 1)
+	status := mounts.([]rc.Params)[0]
+	assert.Equal(t, "/mnt/test", status["mountPoint"])
+	assert.Equal(t, false, status["healthy"])
+	incidents := status["incidents"].([]Incident)
+	require.Len(t, incidents, maxIncidents)
+	assert.Equal(t, "error 1", incidents[0].Error)
+	assert.Equal(t, "remounting", incidents[0].Action)
+
+	s.remounted()
+	status = s.status()
+	assert.Equal(t, true, status["healthy"])
+	assert.Equal(t, 1, status["remounts"])
+
+	s.checked(errors.New("failed"))
+	assert.Equal(t, false, s.status()["healthy"])
+}
+
+func TestMountAutoRemount(t *testing.T) {
+	var (
+		mounts   = 0
+		unmounts = 0
+		errChans = make(chan chan error, 10)
+	)
+	mount := func(VFS *vfs.VFS, mountpoint string, opt *Options) (<-chan error, func() error, error) {
+		mounts++
+		if mounts == 2 {
+			return nil, nil, errors.New("mount failed")
+		}
+		errChan := make(chan error, 1)
+		errChans <- errChan
+		return errChan, func() error {
+			unmounts++
+			return nil
+		}, nil
+	}
+	opt := DefaultOpt
+	opt.AutoRemount = true
+	done := make(chan error)
+	go func() {
+		done <- Mount(nil, "/mnt/test", mount, &opt)
+	}()
+
+	// A failure is remounted after the failed mount is retried
+	(<-errChans) <- errors.New("transport failed")
+	// An unmount from outside stops the mount
+	(<-errChans) <- nil
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("mount didn't finish")
+	}
+	assert.Equal(t, 3, mounts)
+	assert.Equal(t, 2, unmounts)
+}
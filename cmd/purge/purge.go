@@ -4,12 +4,20 @@ import (
 	"context"
 
 	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs/config/flags"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/spf13/cobra"
 )
 
+var (
+	opt operations.DeleteOpt
+)
+
 func init() {
 	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.BoolVarP(cmdFlags, &opt.TPSAware, "tps-aware", "", opt.TPSAware, "Delete each file through the pacer with progress rather than with the backend's purge")
+	flags.StringVarP(cmdFlags, &opt.Checkpoint, "checkpoint", "", opt.Checkpoint, "Record the files deleted in this file so an interrupted purge can be continued")
 }
 
 var commandDefinition = &cobra.Command{
@@ -21,6 +29,22 @@ include/exclude filters - everything will be removed.  Use the ` + "`delete`" +
 command if you want to selectively delete files. To delete empty directories only,
 use command ` + "`rmdir`" + ` or ` + "`rmdirs`" + `.
 
+Some backends can purge a directory with a single call, otherwise
+rclone lists the files and deletes them, running ` + "`--transfers`" + `
+deletions in parallel, and then removes the directories. Use the
+` + "`--tps-aware`" + ` flag to make rclone delete the files itself even if
+the backend can purge, so the deletions obey ` + "`--tpslimit`" + `,
+` + "`--max-delete`" + ` and ` + "`--transfers`" + ` and their progress is
+shown in the stats as they are found and deleted.
+
+When purging a lot of files use ` + "`--checkpoint FILE`" + ` to record the
+files deleted. If the purge is interrupted then run it again with the
+same checkpoint to continue without trying to delete the files already
+deleted, which avoids errors from remotes whose listings lag behind
+deletions. The checkpoint is removed when the purge finishes
+successfully. It only applies when rclone is deleting the files
+itself.
+
 **Important**: Since this can cause data loss, test first with the
 ` + "`--dry-run` or the `--interactive`/`-i`" + ` flag.
 `,
@@ -28,7 +52,7 @@ use command ` + "`rmdir`" + ` or ` + "`rmdirs`" + `.
 		cmd.CheckArgs(1, 1, command, args)
 		fdst := cmd.NewFsDir(args)
 		cmd.Run(true, false, command, func() error {
-			return operations.Purge(context.Background(), fdst, "")
+			return operations.PurgeWithOpt(context.Background(), fdst, "", &opt)
 		})
 	},
 }
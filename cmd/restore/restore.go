@@ -0,0 +1,85 @@
+package restore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/spf13/cobra"
+)
+
+var (
+	since = fs.DurationOff
+	list  = false
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.FVarP(cmdFlags, &since, "since", "", "Only restore files deleted less than this long ago, e.g. 24h")
+	flags.BoolVarP(cmdFlags, &list, "list", "", list, "List the deleted files rather than restoring them")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "restore remote:path",
+	Short: `Restore deleted files in remote:path.`,
+	Long: `
+Restores the deleted files in remote:path and its subdirectories to
+where they were deleted from, using the trash or old versions kept by
+the remote. Not supported by all remotes.
+
+If --since is given then only files deleted less than that long ago are
+restored, for example to recover from a sync which deleted files by
+mistake in the last day
+
+    rclone restore remote:path --since 24h
+
+Only the most recently deleted version of each file is restored, and
+files which exist already are left alone. Use the filters to restore
+only some files and --dry-run to see what would be restored. With
+--list the deleted files are listed instead, with when they were deleted
+and their sizes.
+
+This is supported by Google Drive (the trash), Dropbox (deleted files
+which still have revisions), B2 (hidden files) and S3 (objects with a
+delete marker in a bucket with versioning enabled).
+
+On Google Drive files in a directory which has been deleted aren't
+listed - restore the directory from the trash first.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		fsrc := cmd.NewFsSrc(args)
+		var sinceTime time.Time
+		if since != fs.DurationOff {
+			sinceTime = time.Now().Add(-time.Duration(since))
+		}
+		cmd.Run(!list, false, command, func() error {
+			ctx := context.Background()
+			if list {
+				return listTrash(ctx, fsrc, sinceTime)
+			}
+			return operations.Restore(ctx, fsrc, "", sinceTime)
+		})
+	},
+}
+
+// listTrash prints the deleted files in f
+func listTrash(ctx context.Context, f fs.Fs, since time.Time) error {
+	entries, err := operations.ListTrash(ctx, f, "", since)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		deleted := "-"
+		if !entry.Deleted.IsZero() {
+			deleted = entry.Deleted.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%s %12d %s\n", deleted, entry.Size, entry.Remote)
+	}
+	return nil
+}
@@ -24,7 +24,15 @@ var rmdirsCmd = &cobra.Command{
 This recursively removes any empty directories (including directories
 that only contain empty directories), that it finds under the path.
 The root path itself will also be removed if it is empty, unless
-you supply the ` + "`--leave-root`" + ` flag.
+you supply the ` + "`--leave-root`" + ` flag. When used on the root of a
+bucket based remote, eg ` + "`s3:`" + `, ` + "`--leave-root`" + ` leaves the
+buckets too.
+
+The directories are found with a single recursive listing, which
+uses ` + "`ListR`" + ` if the remote supports it, and then removed from the
+deepest up, with ` + "`--checkers`" + ` of the directories at the same depth
+removed in parallel. If a directory can't be removed then the
+directories containing it aren't tried.
 
 Use command ` + "`rmdir`" + ` to delete just the empty directory
 given by path, not recurse.
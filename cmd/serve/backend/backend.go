@@ -0,0 +1,41 @@
+// Package backend implements serving a remote to the external backend
+package backend
+
+import (
+	"context"
+	"os"
+
+	"github.com/rclone/rclone/backend/external"
+	"github.com/rclone/rclone/cmd"
+	"github.com/spf13/cobra"
+)
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "backend remote:path",
+	Short: `Serve remote:path to the external backend on stdin and stdout.`,
+	Long: `
+rclone serve backend serves remote:path using the protocol of the
+external backend, reading requests on stdin and writing the responses
+to stdout.  It isn't normally run directly, but as the command of an
+external remote, e.g.
+
+    [remote]
+    type = external
+    command = rclone serve backend other:path
+
+This runs the other remote in a separate process.  As the protocol is
+the same for every program, it can be used to check a program which
+implements a backend against rclone, or as an example of what a
+program needs to do.
+
+Logs are written to stderr so they don't interfere with the protocol.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		cmd.Run(false, false, command, func() error {
+			return external.Serve(context.Background(), f, os.Stdin, os.Stdout)
+		})
+	},
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/xml"
 	"fmt"
+	"html"
 	"log"
 	"mime"
 	"net/http"
@@ -12,6 +13,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/anacrolix/dms/dlna"
@@ -69,7 +71,7 @@ var mediaMimeTypeRegexp = regexp.MustCompile("^(video|audio|image)/")
 
 // Turns the given entry and DMS host into a UPnP object. A nil object is
 // returned if the entry is not of interest.
-func (cds *contentDirectoryService) cdsObjectToUpnpavObject(cdsObject object, fileInfo vfs.Node, resources vfs.Nodes, host string) (ret interface{}, err error) {
+func (cds *contentDirectoryService) cdsObjectToUpnpavObject(cdsObject object, fileInfo vfs.Node, resources mediaResources, host string) (ret interface{}, err error) {
 	obj := upnpav.Object{
 		ID:         cdsObject.ID(),
 		Restricted: 1,
@@ -113,19 +115,28 @@ func (cds *contentDirectoryService) cdsObjectToUpnpavObject(cdsObject object, fi
 		Res:    make([]upnpav.Resource, 0, 1),
 	}
 
-	item.Res = append(item.Res, upnpav.Resource{
+	md := cds.metadata.get(resources.metadata)
+	res := upnpav.Resource{
 		URL: (&url.URL{
 			Scheme: "http",
 			Host:   host,
 			Path:   path.Join(resPath, cdsObject.Path),
 		}).String(),
-		ProtocolInfo: fmt.Sprintf("http-get:*:%s:%s", mimeType, dlna.ContentFeatures{
-			SupportRange: true,
-		}.String()),
-		Size: uint64(fileInfo.Size()),
-	})
+		ProtocolInfo: fmt.Sprintf("http-get:*:%s:%s", mimeType, contentFeatures(fileInfo.Name(), mimeType, md).String()),
+		Size:         uint64(fileInfo.Size()),
+	}
+	if md != nil {
+		if md.Duration > 0 {
+			res.Duration = dlna.FormatNPTTime(md.Duration)
+		}
+		res.Bitrate = md.Bitrate
+		res.Resolution = md.Resolution()
+		res.SampleFrequency = md.SampleFrequency
+		res.NrAudioChannels = md.AudioChannels
+	}
+	item.Res = append(item.Res, res)
 
-	for _, resource := range resources {
+	for _, resource := range resources.subtitles {
 		subtitleURL := (&url.URL{
 			Scheme: "http",
 			Host:   host,
@@ -135,6 +146,10 @@ func (cds *contentDirectoryService) cdsObjectToUpnpavObject(cdsObject object, fi
 			URL:          subtitleURL,
 			ProtocolInfo: fmt.Sprintf("http-get:*:%s:*", "text/srt"),
 		})
+		// Samsung TVs only find subtitles this way
+		if item.InnerXML == "" {
+			item.InnerXML = fmt.Sprintf(`<sec:CaptionInfoEx sec:type="srt">%s</sec:CaptionInfoEx>`, html.EscapeString(subtitleURL))
+		}
 	}
 
 	ret = item
@@ -160,12 +175,12 @@ func (cds *contentDirectoryService) readContainer(o object, host string) (ret []
 		return
 	}
 
-	dirEntries, mediaResources := mediaWithResources(dirEntries)
+	dirEntries, resources := mediaWithResources(dirEntries)
 	for _, de := range dirEntries {
 		child := object{
 			path.Join(o.Path, de.Name()),
 		}
-		obj, err := cds.cdsObjectToUpnpavObject(child, de, mediaResources[de], host)
+		obj, err := cds.cdsObjectToUpnpavObject(child, de, resources[de], host)
 		if err != nil {
 			fs.Errorf(cds, "error with %s: %s", child.FilePath(), err)
 			continue
@@ -180,23 +195,36 @@ func (cds *contentDirectoryService) readContainer(o object, host string) (ret []
 	return
 }
 
-// Given a list of nodes, separate them into potential media items and any associated resources (external subtitles,
-// for example.)
+// mediaResources are the files associated with a media file
+type mediaResources struct {
+	subtitles vfs.Nodes // external subtitles
+	metadata  vfs.Node  // metadata sidecar or nil
+}
+
+// Given a list of nodes, separate them into potential media items and any associated resources (external subtitles
+// and metadata sidecars, for example.)
 //
 // The result is a slice of potential media nodes (in their original order) and a map containing associated
-// resources nodes of each media node, if any.
-func mediaWithResources(nodes vfs.Nodes) (vfs.Nodes, map[vfs.Node]vfs.Nodes) {
-	media, mediaResources := vfs.Nodes{}, make(map[vfs.Node]vfs.Nodes)
+// resources of each media node, if any.
+func mediaWithResources(nodes vfs.Nodes) (vfs.Nodes, map[vfs.Node]mediaResources) {
+	media, resources := vfs.Nodes{}, make(map[vfs.Node]mediaResources)
 
-	// First, separate out the subtitles and media into maps, keyed by their lowercase base names.
+	// First, separate out the subtitles, sidecars and media into maps, keyed by their lowercase base names.
 	mediaByName, subtitlesByName := make(map[string]vfs.Nodes), make(map[string]vfs.Node)
+	mediaByFullName, sidecarsByName := make(map[string]vfs.Node), make(map[string]vfs.Node)
 	for _, node := range nodes {
-		baseName, ext := splitExt(strings.ToLower(node.Name()))
+		lowerName := strings.ToLower(node.Name())
+		if strings.HasSuffix(lowerName, sidecarSuffix) {
+			sidecarsByName[strings.TrimSuffix(lowerName, sidecarSuffix)] = node
+			continue
+		}
+		baseName, ext := splitExt(lowerName)
 		switch ext {
 		case ".srt":
 			subtitlesByName[baseName] = node
 		default:
 			mediaByName[baseName] = append(mediaByName[baseName], node)
+			mediaByFullName[lowerName] = node
 			media = append(media, node)
 		}
 	}
@@ -220,11 +248,57 @@ func mediaWithResources(nodes vfs.Nodes) (vfs.Nodes, map[vfs.Node]vfs.Nodes) {
 		// Associate with all potential media nodes
 		fs.Debugf(mediaNodes, "associating subtitle: %s", node.Name())
 		for _, mediaNode := range mediaNodes {
-			mediaResources[mediaNode] = append(mediaResources[mediaNode], node)
+			r := resources[mediaNode]
+			r.subtitles = append(r.subtitles, node)
+			resources[mediaNode] = r
 		}
 	}
 
-	return media, mediaResources
+	// Find the media file for each sidecar (video.mp4 for video.mp4.ffprobe.json)
+	for name, node := range sidecarsByName {
+		mediaNode, found := mediaByFullName[name]
+		if !found {
+			fs.Infof(node, "could not find associated media for metadata: %s", node.Name())
+			continue
+		}
+		r := resources[mediaNode]
+		r.metadata = node
+		resources[mediaNode] = r
+	}
+
+	// Sort the subtitles so the results are stable
+	for mediaNode, r := range resources {
+		sort.Slice(r.subtitles, func(i, j int) bool {
+			return r.subtitles[i].Name() < r.subtitles[j].Name()
+		})
+		resources[mediaNode] = r
+	}
+
+	return media, resources
+}
+
+// resourcesFor returns the resources associated with the media file
+// at the path given by listing its directory.
+func (s *server) resourcesFor(nodePath string) (r mediaResources) {
+	node, err := s.vfs.Stat(path.Dir(nodePath))
+	if err != nil {
+		return r
+	}
+	dir, ok := node.(*vfs.Dir)
+	if !ok {
+		return r
+	}
+	dirEntries, err := dir.ReadDirAll()
+	if err != nil {
+		return r
+	}
+	_, resources := mediaWithResources(dirEntries)
+	for mediaNode, r := range resources {
+		if mediaNode.Name() == path.Base(nodePath) {
+			return r
+		}
+	}
+	return r
 }
 
 type browse struct {
@@ -305,8 +379,11 @@ func (cds *contentDirectoryService) Handle(action string, argsXML []byte, r *htt
 			if err != nil {
 				return nil, err
 			}
-			// TODO: External subtitles won't appear in the metadata here, but probably should.
-			upnpObject, err := cds.cdsObjectToUpnpavObject(obj, node, vfs.Nodes{}, host)
+			var resources mediaResources
+			if !node.IsDir() {
+				resources = cds.resourcesFor(obj.Path)
+			}
+			upnpObject, err := cds.cdsObjectToUpnpavObject(obj, node, resources, host)
 			if err != nil {
 				return nil, err
 			}
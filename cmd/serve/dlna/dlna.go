@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -16,6 +17,7 @@ import (
 	"github.com/anacrolix/dms/soap"
 	"github.com/anacrolix/dms/ssdp"
 	"github.com/anacrolix/dms/upnp"
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/cmd/serve/dlna/data"
 	"github.com/rclone/rclone/cmd/serve/dlna/dlnaflags"
@@ -43,6 +45,19 @@ Rclone will list all files present in the remote, without filtering based on med
 file extensions. Additionally, there is no media transcoding support. This means that some
 players might show files that they are not able to play back correctly.
 
+External subtitles are served alongside the media they belong to. A subtitle file is associated
+with a media file if it has the same base name, e.g. "video.srt" or "video.en.srt" for
+"video.mp4".
+
+Rclone doesn't read the media files to find their duration, resolution and so on, but it will
+use the output of ffprobe if it is stored next to the media file with ".ffprobe.json" appended
+to its name, e.g. "video.mp4.ffprobe.json" made with
+
+    ffprobe -v quiet -print_format json -show_format -show_streams video.mp4 > video.mp4.ffprobe.json
+
+This lets players show the duration, choose the right DLNA profile and seek by time as well as
+by bytes.
+
 ` + dlnaflags.Help + vfs.Help,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
@@ -88,6 +103,9 @@ type server struct {
 
 	f   fs.Fs
 	vfs *vfs.VFS
+
+	// Parsed metadata sidecars
+	metadata *metadataCache
 }
 
 func newServer(f fs.Fs, opt *dlnaflags.Options) *server {
@@ -104,8 +122,9 @@ func newServer(f fs.Fs, opt *dlnaflags.Options) *server {
 
 		httpListenAddr: opt.ListenAddr,
 
-		f:   f,
-		vfs: vfs.New(f, &vfsflags.Opt),
+		f:        f,
+		vfs:      vfs.New(f, &vfsflags.Opt),
+		metadata: newMetadataCache(),
 	}
 
 	s.services = map[string]UPnPService{
@@ -223,6 +242,81 @@ func (s *server) soapActionResponse(sa upnp.SoapAction, actionRequestXML []byte,
 	return service.Handle(sa.Action, actionRequestXML, r)
 }
 
+// contentFeatures returns the DLNA content features for a media file
+func contentFeatures(name, mimeType string, md *mediaMetadata) dms_dlna.ContentFeatures {
+	return dms_dlna.ContentFeatures{
+		ProfileName:     dlnaProfileName(name, mimeType, md),
+		SupportRange:    true,
+		SupportTimeSeek: md != nil && md.Duration > 0,
+	}
+}
+
+// parseNPT parses a normal play time which is either seconds, e.g.
+// "12.5", or hours, minutes and seconds, e.g. "0:00:12.500"
+func parseNPT(s string) (time.Duration, error) {
+	if strings.Contains(s, ":") {
+		return dms_dlna.ParseNPTTime(s)
+	}
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil || seconds < 0 {
+		return -1, errors.Errorf("invalid npt time: %q", s)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// formatNPT formats d as seconds for the TimeSeekRange header
+func formatNPT(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// timeSeekRange converts a TimeSeekRange.dlna.org header for a file
+// of size bytes with the duration given into an approximate byte
+// range, returning the Range header to use and the
+// TimeSeekRange.dlna.org header to reply with.
+func timeSeekRange(header string, size int64, duration time.Duration) (rangeHeader, reply string, err error) {
+	spec := strings.TrimSpace(header)
+	if !strings.HasPrefix(spec, "npt=") {
+		return "", "", errors.Errorf("unsupported time seek range %q", header)
+	}
+	spec = strings.TrimPrefix(spec, "npt=")
+	// ignore any bytes= part the client sent
+	if i := strings.IndexByte(spec, ' '); i >= 0 {
+		spec = spec[:i]
+	}
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return "", "", errors.Errorf("bad time seek range %q", header)
+	}
+	start, err := parseNPT(parts[0])
+	if err != nil {
+		return "", "", err
+	}
+	end := duration
+	if parts[1] != "" {
+		end, err = parseNPT(parts[1])
+		if err != nil {
+			return "", "", err
+		}
+		if end > duration {
+			end = duration
+		}
+	}
+	if start >= duration || end < start || size <= 0 {
+		return "", "", errors.Errorf("time seek range %q out of range", header)
+	}
+	startByte := int64(float64(size) * float64(start) / float64(duration))
+	endByte := int64(float64(size)*float64(end)/float64(duration)) - 1
+	if end == duration || endByte >= size {
+		endByte = size - 1
+	}
+	if endByte < startByte {
+		endByte = startByte
+	}
+	rangeHeader = fmt.Sprintf("bytes=%d-%d", startByte, endByte)
+	reply = fmt.Sprintf("npt=%s-%s/%s bytes=%d-%d/%d", formatNPT(start), formatNPT(end), formatNPT(duration), startByte, endByte, size)
+	return rangeHeader, reply, nil
+}
+
 // Serves actual resources (media files).
 func (s *server) resourceHandler(w http.ResponseWriter, r *http.Request) {
 	remotePath := r.URL.Path
@@ -231,18 +325,64 @@ func (s *server) resourceHandler(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	file, ok := node.(*vfs.File)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
 
-	w.Header().Set("Content-Length", strconv.FormatInt(node.Size(), 10))
+	var mimeType string
+	if o, ok := node.DirEntry().(fs.Object); ok {
+		mimeType = fs.MimeType(r.Context(), o)
+	} else {
+		mimeType = fs.MimeTypeFromName(node.Name())
+	}
+	var (
+		resources mediaResources
+		md        *mediaMetadata
+	)
+	isMedia := mediaMimeTypeRegexp.MatchString(mimeType)
+	if isMedia {
+		resources = s.resourcesFor(path.Join("/", remotePath))
+		md = s.metadata.get(resources.metadata)
+	}
 
 	// add some DLNA specific headers
 	if r.Header.Get("getContentFeatures.dlna.org") != "" {
-		w.Header().Set("contentFeatures.dlna.org", dms_dlna.ContentFeatures{
-			SupportRange: true,
-		}.String())
+		w.Header().Set(dms_dlna.ContentFeaturesDomain, contentFeatures(node.Name(), mimeType, md).String())
+	}
+	transferMode := r.Header.Get(dms_dlna.TransferModeDomain)
+	if transferMode == "" {
+		transferMode = "Interactive"
+		if strings.HasPrefix(mimeType, "video/") || strings.HasPrefix(mimeType, "audio/") {
+			transferMode = "Streaming"
+		}
+	}
+	w.Header().Set(dms_dlna.TransferModeDomain, transferMode)
+	if r.Header.Get("getCaptionInfo.sec") != "" && len(resources.subtitles) > 0 {
+		w.Header().Set("CaptionInfo.sec", (&url.URL{
+			Scheme: "http",
+			Host:   r.Host,
+			Path:   path.Join(resPath, resources.subtitles[0].Path()),
+		}).String())
+	}
+
+	// convert a time seek into a byte range
+	if timeSeek := r.Header.Get(dms_dlna.TimeSeekRangeDomain); timeSeek != "" {
+		if md == nil || md.Duration <= 0 {
+			http.Error(w, "time seek not supported for this resource", http.StatusNotAcceptable)
+			return
+		}
+		rangeHeader, reply, err := timeSeekRange(timeSeek, node.Size(), md.Duration)
+		if err != nil {
+			fs.Debugf(node, "bad time seek: %v", err)
+			http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		r.Header.Set("Range", rangeHeader)
+		w.Header().Set(dms_dlna.TimeSeekRangeDomain, reply)
 	}
-	w.Header().Set("transferMode.dlna.org", "Streaming")
 
-	file := node.(*vfs.File)
 	in, err := file.Open(os.O_RDONLY)
 	if err != nil {
 		serveError(node, w, "Could not open resource", err)
@@ -250,6 +390,7 @@ func (s *server) resourceHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer fs.CheckClose(in, &err)
 
+	w.Header().Set("Content-Type", mimeType)
 	http.ServeContent(w, r, remotePath, node.ModTime(), in)
 }
 
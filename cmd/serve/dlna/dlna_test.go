@@ -10,6 +10,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/anacrolix/dms/soap"
 
@@ -203,3 +204,164 @@ func TestContentDirectoryBrowseDirectChildren(t *testing.T) {
 	require.Contains(t, string(body), "/r/subdir/video.mp4")
 	require.Contains(t, string(body), "/r/subdir/video.srt")
 }
+
+// doBrowse runs a ContentDirectory#Browse returning the body
+func doBrowse(t *testing.T, objectID, browseFlag string) string {
+	req, err := http.NewRequest("POST", baseURL+serviceControlURL, strings.NewReader(`
+<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"
+            s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+    <s:Body>
+        <u:Browse xmlns:u="urn:schemas-upnp-org:service:ContentDirectory:1">
+            <ObjectID>`+objectID+`</ObjectID>
+            <BrowseFlag>`+browseFlag+`</BrowseFlag>
+            <Filter>*</Filter>
+            <StartingIndex>0</StartingIndex>
+            <RequestedCount>0</RequestedCount>
+            <SortCriteria></SortCriteria>
+        </u:Browse>
+    </s:Body>
+</s:Envelope>`))
+	require.NoError(t, err)
+	req.Header.Set("SOAPACTION", `"urn:schemas-upnp-org:service:ContentDirectory:1#Browse"`)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return html.UnescapeString(string(body))
+}
+
+// Check that metadata from the sidecar and subtitles are in the DIDL.
+func TestContentDirectoryBrowseMediaMetadata(t *testing.T) {
+	body := doBrowse(t, "0", "BrowseDirectChildren")
+	assert.Contains(t, body, `duration="00:00:10.000"`)
+	assert.Contains(t, body, `resolution="1280x720"`)
+	assert.Contains(t, body, `bitrate="100000"`)
+	assert.Contains(t, body, `nrAudioChannels="2"`)
+	assert.Contains(t, body, "DLNA.ORG_PN=AVC_MP4_MP_HD_720p_AAC;DLNA.ORG_OP=11")
+	assert.Contains(t, body, `<sec:CaptionInfoEx sec:type="srt">`)
+	// the sidecar isn't listed itself
+	assert.NotContains(t, body, "ffprobe.json")
+
+	// BrowseMetadata on the item includes the subtitles too
+	body = doBrowse(t, "%2Fvideo.mp4", "BrowseMetadata")
+	assert.Contains(t, body, "/r/video.mp4")
+	assert.Contains(t, body, "/r/video.srt")
+	assert.Contains(t, body, "/r/video.en.srt")
+	assert.Contains(t, body, `duration="00:00:10.000"`)
+
+	// no sidecar in subdir so no time seek
+	body = doBrowse(t, "%2Fsubdir", "BrowseDirectChildren")
+	assert.Contains(t, body, "DLNA.ORG_OP=01")
+	assert.NotContains(t, body, "duration=")
+}
+
+// Check the DLNA headers and time based seeking.
+func TestServeContentSeek(t *testing.T) {
+	req, err := http.NewRequest("GET", baseURL+resPath+"video.mp4", nil)
+	require.NoError(t, err)
+	req.Header.Set("getContentFeatures.dlna.org", "1")
+	req.Header.Set("getCaptionInfo.sec", "1")
+	req.Header.Set("TimeSeekRange.dlna.org", "npt=5.0-")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "DLNA.ORG_PN=AVC_MP4_MP_HD_720p_AAC;DLNA.ORG_OP=11;DLNA.ORG_CI=0", resp.Header.Get("contentFeatures.dlna.org"))
+	assert.Equal(t, "Streaming", resp.Header.Get("transferMode.dlna.org"))
+	assert.Equal(t, "npt=5.000-10.000/10.000 bytes=131-261/262", resp.Header.Get("TimeSeekRange.dlna.org"))
+	assert.Contains(t, resp.Header.Get("CaptionInfo.sec"), "/r/video.en.srt")
+	assert.Len(t, body, 131)
+
+	// byte seek still works
+	req, err = http.NewRequest("GET", baseURL+resPath+"video.mp4", nil)
+	require.NoError(t, err)
+	req.Header.Set("Range", "bytes=10-19")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, err = ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Len(t, body, 10)
+
+	// no time seek without a duration
+	req, err = http.NewRequest("GET", baseURL+resPath+"subdir/video.mp4", nil)
+	require.NoError(t, err)
+	req.Header.Set("TimeSeekRange.dlna.org", "npt=5.0-")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+
+	// subtitles are served too
+	resp, err = http.Get(baseURL + resPath + "video.srt")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/srt")
+	assert.Equal(t, "Interactive", resp.Header.Get("transferMode.dlna.org"))
+}
+
+func TestTimeSeekRange(t *testing.T) {
+	for _, test := range []struct {
+		in        string
+		wantRange string
+		wantReply string
+		wantErr   bool
+	}{
+		{"npt=0-", "bytes=0-999", "npt=0.000-100.000/100.000 bytes=0-999/1000", false},
+		{"npt=25.5-50", "bytes=255-499", "npt=25.500-50.000/100.000 bytes=255-499/1000", false},
+		{"npt=00:00:10.000-", "bytes=100-999", "npt=10.000-100.000/100.000 bytes=100-999/1000", false},
+		{"npt=10-200", "bytes=100-999", "npt=10.000-100.000/100.000 bytes=100-999/1000", false},
+		{"npt=200-", "", "", true},
+		{"npt=50-10", "", "", true},
+		{"bytes=0-", "", "", true},
+		{"npt=potato-", "", "", true},
+	} {
+		gotRange, gotReply, err := timeSeekRange(test.in, 1000, 100*time.Second)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.wantRange, gotRange, test.in)
+		assert.Equal(t, test.wantReply, gotReply, test.in)
+	}
+}
+
+func TestDLNAProfileName(t *testing.T) {
+	assert.Equal(t, "MP3", dlnaProfileName("song.MP3", "audio/mpeg", nil))
+	assert.Equal(t, "PNG_LRG", dlnaProfileName("pic.png", "image/png", nil))
+	assert.Equal(t, "", dlnaProfileName("pic.jpg", "image/jpeg", nil))
+	assert.Equal(t, "JPEG_SM", dlnaProfileName("pic.jpg", "image/jpeg", &mediaMetadata{Width: 640, Height: 480}))
+	assert.Equal(t, "JPEG_LRG", dlnaProfileName("pic.jpg", "image/jpeg", &mediaMetadata{Width: 4000, Height: 3000}))
+	assert.Equal(t, "AAC_ISO_320", dlnaProfileName("song.m4a", "audio/mpeg", &mediaMetadata{AudioCodec: "aac"}))
+	assert.Equal(t, "AVC_MP4_HP_HD_AAC", dlnaProfileName("film.mp4", "video/mp4", &mediaMetadata{VideoCodec: "h264", AudioCodec: "aac", Height: 1080}))
+	assert.Equal(t, "MPEG_PS_PAL", dlnaProfileName("film.mpg", "video/mpeg", &mediaMetadata{VideoCodec: "mpeg2video", Height: 576}))
+	assert.Equal(t, "", dlnaProfileName("film.mkv", "video/x-matroska", &mediaMetadata{VideoCodec: "hevc"}))
+}
+
+func TestParseFFProbe(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/files/video.mp4.ffprobe.json")
+	require.NoError(t, err)
+	md, err := parseFFProbe(data)
+	require.NoError(t, err)
+	assert.Equal(t, &mediaMetadata{
+		Duration:        10 * time.Second,
+		Bitrate:         100000,
+		Width:           1280,
+		Height:          720,
+		VideoCodec:      "h264",
+		AudioCodec:      "aac",
+		SampleFrequency: 48000,
+		AudioChannels:   2,
+	}, md)
+	assert.Equal(t, "1280x720", md.Resolution())
+
+	_, err = parseFFProbe([]byte("potato"))
+	assert.Error(t, err)
+}
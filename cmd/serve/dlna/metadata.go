@@ -0,0 +1,202 @@
+package dlna
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+)
+
+// sidecarSuffix is appended to the name of a media file to find the
+// file holding its metadata, as written by
+//
+//	ffprobe -v quiet -print_format json -show_format -show_streams video.mp4 > video.mp4.ffprobe.json
+const sidecarSuffix = ".ffprobe.json"
+
+// maxSidecarSize is the largest sidecar file we will read
+const maxSidecarSize = 1024 * 1024
+
+// mediaMetadata is the metadata of a media file read from its sidecar
+type mediaMetadata struct {
+	Duration        time.Duration
+	Bitrate         uint // bytes per second as DIDL-Lite wants
+	Width           int
+	Height          int
+	VideoCodec      string
+	AudioCodec      string
+	SampleFrequency uint
+	AudioChannels   uint
+}
+
+// Resolution returns the resolution as WxH or "" if unknown
+func (md *mediaMetadata) Resolution() string {
+	if md.Width <= 0 || md.Height <= 0 {
+		return ""
+	}
+	return strconv.Itoa(md.Width) + "x" + strconv.Itoa(md.Height)
+}
+
+// ffprobeOutput is the part of ffprobe's JSON output we use
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType  string `json:"codec_type"`
+		CodecName  string `json:"codec_name"`
+		Width      int    `json:"width"`
+		Height     int    `json:"height"`
+		SampleRate string `json:"sample_rate"`
+		Channels   uint   `json:"channels"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+		BitRate  string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+// parseFFProbe parses the JSON output of ffprobe
+func parseFFProbe(data []byte) (*mediaMetadata, error) {
+	var out ffprobeOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, errors.Wrap(err, "failed to parse ffprobe output")
+	}
+	md := &mediaMetadata{}
+	if seconds, err := strconv.ParseFloat(out.Format.Duration, 64); err == nil && seconds > 0 {
+		md.Duration = time.Duration(seconds * float64(time.Second))
+	}
+	if bitsPerSecond, err := strconv.ParseUint(out.Format.BitRate, 10, 64); err == nil {
+		md.Bitrate = uint(bitsPerSecond / 8)
+	}
+	for _, stream := range out.Streams {
+		switch stream.CodecType {
+		case "video":
+			if md.VideoCodec == "" {
+				md.VideoCodec = stream.CodecName
+				md.Width = stream.Width
+				md.Height = stream.Height
+			}
+		case "audio":
+			if md.AudioCodec == "" {
+				md.AudioCodec = stream.CodecName
+				md.AudioChannels = stream.Channels
+				if rate, err := strconv.ParseUint(stream.SampleRate, 10, 64); err == nil {
+					md.SampleFrequency = uint(rate)
+				}
+			}
+		}
+	}
+	return md, nil
+}
+
+// dlnaProfileName returns the DLNA.ORG_PN profile for a media file or
+// "" if it isn't known.  md may be nil if there is no metadata.
+func dlnaProfileName(name, mimeType string, md *mediaMetadata) string {
+	ext := strings.ToLower(path.Ext(name))
+	switch {
+	case ext == ".mp3":
+		return "MP3"
+	case mimeType == "image/png":
+		return "PNG_LRG"
+	case md == nil:
+		return ""
+	case mimeType == "image/jpeg":
+		switch {
+		case md.Width <= 0 || md.Height <= 0:
+			return ""
+		case md.Width <= 640 && md.Height <= 480:
+			return "JPEG_SM"
+		case md.Width <= 1024 && md.Height <= 768:
+			return "JPEG_MED"
+		case md.Width <= 4096 && md.Height <= 4096:
+			return "JPEG_LRG"
+		}
+	case md.VideoCodec == "" && md.AudioCodec == "aac":
+		return "AAC_ISO_320"
+	case mimeType == "video/mp4" && md.VideoCodec == "h264" && md.AudioCodec == "aac":
+		switch {
+		case md.Height <= 576:
+			return "AVC_MP4_MP_SD_AAC_MULT5"
+		case md.Height <= 720:
+			return "AVC_MP4_MP_HD_720p_AAC"
+		default:
+			return "AVC_MP4_HP_HD_AAC"
+		}
+	case mimeType == "video/mpeg" && md.VideoCodec == "mpeg2video":
+		if md.Height == 576 {
+			return "MPEG_PS_PAL"
+		}
+		return "MPEG_PS_NTSC"
+	}
+	return ""
+}
+
+// metadataCache caches the parsed sidecars
+type metadataCache struct {
+	mu      sync.Mutex
+	entries map[string]metadataCacheEntry
+}
+
+// metadataCacheEntry is a parsed sidecar and what it was parsed from
+type metadataCacheEntry struct {
+	modTime time.Time
+	size    int64
+	md      *mediaMetadata
+}
+
+// newMetadataCache makes an empty cache
+func newMetadataCache() *metadataCache {
+	return &metadataCache{
+		entries: map[string]metadataCacheEntry{},
+	}
+}
+
+// get returns the metadata in the sidecar node or nil if it can't be
+// read.  The sidecar is only read again if it changes.
+func (mc *metadataCache) get(sidecar vfs.Node) *mediaMetadata {
+	if sidecar == nil {
+		return nil
+	}
+	key := sidecar.Path()
+	modTime, size := sidecar.ModTime(), sidecar.Size()
+	mc.mu.Lock()
+	entry, ok := mc.entries[key]
+	mc.mu.Unlock()
+	if ok && entry.modTime.Equal(modTime) && entry.size == size {
+		return entry.md
+	}
+	md, err := readSidecar(sidecar)
+	if err != nil {
+		fs.Debugf(sidecar, "failed to read media metadata: %v", err)
+	}
+	mc.mu.Lock()
+	mc.entries[key] = metadataCacheEntry{modTime: modTime, size: size, md: md}
+	mc.mu.Unlock()
+	return md
+}
+
+// readSidecar reads and parses the sidecar node
+func readSidecar(sidecar vfs.Node) (md *mediaMetadata, err error) {
+	if sidecar.Size() > maxSidecarSize {
+		return nil, errors.New("sidecar too large")
+	}
+	file, ok := sidecar.(*vfs.File)
+	if !ok {
+		return nil, errors.New("sidecar is not a file")
+	}
+	in, err := file.Open(os.O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	return parseFFProbe(data)
+}
@@ -19,6 +19,9 @@ type Resource struct {
 	Bitrate      uint     `xml:"bitrate,attr,omitempty"`
 	Duration     string   `xml:"duration,attr,omitempty"`
 	Resolution   string   `xml:"resolution,attr,omitempty"`
+	// SampleFrequency is in Hz
+	SampleFrequency uint `xml:"sampleFrequency,attr,omitempty"`
+	NrAudioChannels uint `xml:"nrAudioChannels,attr,omitempty"`
 }
 
 // Container description
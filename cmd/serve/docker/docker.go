@@ -0,0 +1,317 @@
+// Package docker serves a Docker volume plugin API backed by rclone mounts
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/cmd/mountlib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/lib/atexit"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Options contains options for the docker volume plugin
+type Options struct {
+	SocketAddr  string // unix socket path or host:port to listen on
+	BaseDir     string // directory the volumes are mounted under
+	StateFile   string // file to save the volumes in
+	ForgetState bool   // don't save or restore the volumes
+	MountType   string // mount implementation to use
+}
+
+// DefaultOpt is the default values used for Options
+var DefaultOpt = Options{
+	SocketAddr: "/run/docker/plugins/rclone.sock",
+	BaseDir:    "/var/lib/docker-volumes/rclone",
+}
+
+// Opt is options set by command line flags
+var Opt = DefaultOpt
+
+// AddFlags adds flags for docker
+func AddFlags(flagSet *pflag.FlagSet) {
+	rc.AddOption("docker", &Opt)
+	flags.StringVarP(flagSet, &Opt.SocketAddr, "socket-addr", "", Opt.SocketAddr, "Path of the unix socket or IPaddress:Port to listen on.")
+	flags.StringVarP(flagSet, &Opt.BaseDir, "base-dir", "", Opt.BaseDir, "Directory to mount the volumes under.")
+	flags.StringVarP(flagSet, &Opt.StateFile, "state-file", "", Opt.StateFile, "File to save the volumes in (default \"docker-plugin.state\" in the cache directory).")
+	flags.BoolVarP(flagSet, &Opt.ForgetState, "forget-state", "", Opt.ForgetState, "Don't save or restore the volumes.")
+	flags.StringVarP(flagSet, &Opt.MountType, "mount-type", "", Opt.MountType, "Mount implementation to use: mount, cmount or mount2 (default first available).")
+}
+
+func init() {
+	AddFlags(Command.Flags())
+	volumeFlags.VisitAll(func(flag *pflag.Flag) {
+		Command.Flags().AddFlag(flag)
+	})
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "docker",
+	Short: `Serve any remote on docker's volume plugin API.`,
+	Long: `
+This command implements the Docker volume plugin API allowing docker
+to use rclone as a data storage mechanism for named volumes, e.g.
+
+    sudo rclone serve docker
+
+    docker volume create photos -d rclone -o remote=gdrive:photos -o vfs-cache-mode=writes
+    docker run --rm -it -v photos:/photos alpine ls /photos
+
+Docker finds the plugin by the unix socket it listens on, which is
+"/run/docker/plugins/rclone.sock" by default, so the driver name is
+"rclone".  Use --socket-addr to change it.  If --socket-addr is an
+IPaddress:Port then the plugin listens on TCP instead and you must
+tell docker where it is with a spec file, e.g.
+"/etc/docker/plugins/rclone.spec" containing "tcp://localhost:8787".
+
+Each volume is mounted with rclone mount in its own directory under
+--base-dir when the first container using it starts and unmounted
+when the last one stops.
+
+### Volume options
+
+The "remote" option is required and is the remote path to mount.  It
+may be a connection string if the remote isn't in the config file,
+e.g.
+
+    docker volume create pics -d rclone -o remote=:sftp,host=example.com:pics
+
+All the other options are the names of the VFS and mount flags
+without the leading "--", e.g. "-o vfs-cache-mode=full", "-o
+read-only" or "-o allow-other".  Flags not given for a volume take the
+value given on the command line.
+
+### State
+
+The volumes and their options are saved in --state-file so they are
+restored when the plugin restarts.  Use --forget-state to start with
+no volumes and not save them.
+
+The plugin must run as root or a user who can mount FUSE file systems
+and write to --base-dir and the socket directory.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 0, command, args)
+		cmd.Run(false, false, command, func() error {
+			opt := Opt
+			if opt.StateFile == "" {
+				opt.StateFile = filepath.Join(config.CacheDir, "docker-plugin.state")
+			}
+			mountType, mountFn := mountlib.ResolveMountMethod(opt.MountType)
+			drv, err := newDriver(context.Background(), &opt, mountType, mountFn)
+			if err != nil {
+				return err
+			}
+			s, err := newServer(drv, &opt)
+			if err != nil {
+				return err
+			}
+			atexit.Register(func() {
+				_ = s.Close()
+				drv.unmountAll()
+			})
+			return s.Serve()
+		})
+	},
+}
+
+// pluginContentType is the content type of plugin API responses
+const pluginContentType = "application/vnd.docker.plugins.v1.1+json"
+
+// server serves the plugin API
+type server struct {
+	drv      *driver
+	listener net.Listener
+	srv      *http.Server
+	socket   string // path of the unix socket if using one
+}
+
+// newServer makes the plugin API server listening on opt.SocketAddr
+func newServer(drv *driver, opt *Options) (*server, error) {
+	s := &server{drv: drv}
+	var err error
+	if isTCPAddr(opt.SocketAddr) {
+		s.listener, err = net.Listen("tcp", opt.SocketAddr)
+	} else {
+		s.socket = opt.SocketAddr
+		if err = os.MkdirAll(filepath.Dir(s.socket), 0755); err != nil {
+			return nil, errors.Wrap(err, "failed to make socket directory")
+		}
+		// remove a socket left over from a previous run
+		_ = os.Remove(s.socket)
+		s.listener, err = net.Listen("unix", s.socket)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to listen")
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/Plugin.Activate", s.activate)
+	mux.HandleFunc("/VolumeDriver.Create", s.create)
+	mux.HandleFunc("/VolumeDriver.Remove", s.remove)
+	mux.HandleFunc("/VolumeDriver.Mount", s.mount)
+	mux.HandleFunc("/VolumeDriver.Unmount", s.unmount)
+	mux.HandleFunc("/VolumeDriver.Path", s.path)
+	mux.HandleFunc("/VolumeDriver.Get", s.get)
+	mux.HandleFunc("/VolumeDriver.List", s.list)
+	mux.HandleFunc("/VolumeDriver.Capabilities", s.capabilities)
+	s.srv = &http.Server{Handler: mux}
+	return s, nil
+}
+
+// isTCPAddr returns true if addr looks like host:port rather than a path
+func isTCPAddr(addr string) bool {
+	if strings.ContainsAny(addr, `/\`) {
+		return false
+	}
+	_, _, err := net.SplitHostPort(addr)
+	return err == nil
+}
+
+// Addr returns the address the server is listening on
+func (s *server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Serve serves the plugin API until Close is called
+func (s *server) Serve() error {
+	fs.Logf(nil, "Serving docker volume plugin on %v", s.Addr())
+	err := s.srv.Serve(s.listener)
+	if err == http.ErrServerClosed {
+		err = nil
+	}
+	return err
+}
+
+// Close stops the server
+func (s *server) Close() error {
+	err := s.srv.Close()
+	if s.socket != "" {
+		_ = os.Remove(s.socket)
+	}
+	return err
+}
+
+// request is the union of the plugin API request bodies
+type request struct {
+	Name string
+	Opts map[string]string
+	ID   string
+}
+
+// response is the union of the plugin API response bodies
+type response struct {
+	Err          string
+	Mountpoint   string        `json:",omitempty"`
+	Volume       *volumeInfo   `json:",omitempty"`
+	Volumes      []*volumeInfo `json:",omitempty"`
+	Capabilities *capabilities `json:",omitempty"`
+}
+
+// capabilities describes the driver capabilities
+type capabilities struct {
+	Scope string
+}
+
+// readRequest decodes the request body - docker may send an empty body
+func readRequest(r *http.Request) (req request, err error) {
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err == io.EOF {
+		err = nil
+	}
+	return req, err
+}
+
+// writeJSON writes out as the response with the status given
+func writeJSON(w http.ResponseWriter, status int, out interface{}) {
+	w.Header().Set("Content-Type", pluginContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		fs.Errorf(nil, "docker: failed to write response: %v", err)
+	}
+}
+
+// serve decodes the request, calls fn and writes the response
+func (s *server) serve(w http.ResponseWriter, r *http.Request, fn func(req *request, resp *response) error) {
+	resp := &response{}
+	req, err := readRequest(r)
+	if err == nil {
+		err = fn(&req, resp)
+	}
+	if err != nil {
+		fs.Errorf(nil, "docker: %s %q: %v", strings.TrimPrefix(r.URL.Path, "/"), req.Name, err)
+		writeJSON(w, http.StatusInternalServerError, &response{Err: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *server) activate(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]string{"Implements": {"VolumeDriver"}})
+}
+
+func (s *server) create(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, func(req *request, resp *response) error {
+		return s.drv.Create(req.Name, req.Opts)
+	})
+}
+
+func (s *server) remove(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, func(req *request, resp *response) error {
+		return s.drv.Remove(req.Name)
+	})
+}
+
+func (s *server) mount(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, func(req *request, resp *response) (err error) {
+		resp.Mountpoint, err = s.drv.Mount(req.Name, req.ID)
+		return err
+	})
+}
+
+func (s *server) unmount(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, func(req *request, resp *response) error {
+		return s.drv.Unmount(req.Name, req.ID)
+	})
+}
+
+func (s *server) path(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, func(req *request, resp *response) (err error) {
+		resp.Mountpoint, err = s.drv.Path(req.Name)
+		return err
+	})
+}
+
+func (s *server) get(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, func(req *request, resp *response) (err error) {
+		resp.Volume, err = s.drv.Get(req.Name)
+		return err
+	})
+}
+
+func (s *server) list(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, func(req *request, resp *response) error {
+		resp.Volumes = s.drv.List()
+		return nil
+	})
+}
+
+func (s *server) capabilities(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, func(req *request, resp *response) error {
+		resp.Capabilities = &capabilities{Scope: "local"}
+		return nil
+	})
+}
@@ -0,0 +1,190 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/cmd/mountlib"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/rclone/rclone/vfs/vfsflags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVolumeOptions(t *testing.T) {
+	remote, vfsOpt, mountOpt, err := parseVolumeOptions(map[string]string{
+		"remote":         "remote:path",
+		"vfs-cache-mode": "writes",
+		"read-only":      "",
+		"allow-other":    "true",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "remote:path", remote)
+	assert.Equal(t, vfscommon.CacheModeWrites, vfsOpt.CacheMode)
+	assert.True(t, vfsOpt.ReadOnly)
+	assert.True(t, mountOpt.AllowOther)
+
+	// the globals are left alone
+	assert.Equal(t, vfscommon.DefaultOpt.CacheMode, vfsflags.Opt.CacheMode)
+	assert.False(t, vfsflags.Opt.ReadOnly)
+	assert.False(t, mountlib.Opt.AllowOther)
+
+	_, _, _, err = parseVolumeOptions(map[string]string{})
+	assert.Equal(t, errNoRemote, err)
+	_, _, _, err = parseVolumeOptions(map[string]string{"remote": "a:", "potato": "1"})
+	assert.EqualError(t, err, `unknown option "potato"`)
+	_, _, _, err = parseVolumeOptions(map[string]string{"remote": "a:", "vfs-cache-mode": "potato"})
+	assert.Error(t, err)
+}
+
+func TestIsTCPAddr(t *testing.T) {
+	assert.True(t, isTCPAddr("localhost:8787"))
+	assert.True(t, isTCPAddr(":8787"))
+	assert.False(t, isTCPAddr("/run/docker/plugins/rclone.sock"))
+	assert.False(t, isTCPAddr("rclone.sock"))
+}
+
+// fakeMount records mounts without needing FUSE
+type fakeMount struct {
+	mounted map[string]*vfs.VFS
+}
+
+func (fm *fakeMount) mount(VFS *vfs.VFS, mountpoint string, opt *mountlib.Options) (<-chan error, func() error, error) {
+	fm.mounted[mountpoint] = VFS
+	return make(chan error), func() error {
+		delete(fm.mounted, mountpoint)
+		return nil
+	}, nil
+}
+
+// call calls the plugin API method with in returning the response
+func call(t *testing.T, client *http.Client, method string, in interface{}) (resp response, status int) {
+	body, err := json.Marshal(in)
+	require.NoError(t, err)
+	res, err := client.Post("http://plugin/"+method, pluginContentType, bytes.NewReader(body))
+	require.NoError(t, err)
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	assert.Equal(t, pluginContentType, res.Header.Get("Content-Type"))
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+	return resp, res.StatusCode
+}
+
+func TestServer(t *testing.T) {
+	fstest.Initialise()
+	dir, err := ioutil.TempDir("", "rclone-docker-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	remoteDir := filepath.Join(dir, "remote")
+	require.NoError(t, os.Mkdir(remoteDir, 0777))
+
+	opt := DefaultOpt
+	opt.SocketAddr = filepath.Join(dir, "run", "rclone.sock")
+	opt.BaseDir = filepath.Join(dir, "volumes")
+	opt.StateFile = filepath.Join(dir, "state", "docker-plugin.state")
+	fm := &fakeMount{mounted: map[string]*vfs.VFS{}}
+	drv, err := newDriver(context.Background(), &opt, "fake", fm.mount)
+	require.NoError(t, err)
+	s, err := newServer(drv, &opt)
+	require.NoError(t, err)
+	go func() {
+		assert.NoError(t, s.Serve())
+	}()
+	defer func() {
+		assert.NoError(t, s.Close())
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return net.Dial("unix", opt.SocketAddr)
+			},
+		},
+	}
+
+	res, err := client.Post("http://plugin/Plugin.Activate", pluginContentType, nil)
+	require.NoError(t, err)
+	var activate map[string][]string
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&activate))
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, []string{"VolumeDriver"}, activate["Implements"])
+
+	resp, _ := call(t, client, "VolumeDriver.Capabilities", nil)
+	assert.Equal(t, "local", resp.Capabilities.Scope)
+
+	// create
+	resp, status := call(t, client, "VolumeDriver.Create", request{Name: "vol", Opts: map[string]string{"remote": remoteDir, "read-only": ""}})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "", resp.Err)
+	resp, status = call(t, client, "VolumeDriver.Create", request{Name: "vol", Opts: map[string]string{"remote": remoteDir}})
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, errVolumeExists.Error(), resp.Err)
+	resp, _ = call(t, client, "VolumeDriver.Create", request{Name: "bad", Opts: map[string]string{}})
+	assert.Equal(t, errNoRemote.Error(), resp.Err)
+	resp, _ = call(t, client, "VolumeDriver.Create", request{Name: "../bad", Opts: map[string]string{"remote": remoteDir}})
+	assert.Equal(t, errBadVolumeName.Error(), resp.Err)
+
+	// list and get
+	resp, _ = call(t, client, "VolumeDriver.List", nil)
+	require.Len(t, resp.Volumes, 1)
+	assert.Equal(t, "vol", resp.Volumes[0].Name)
+	assert.Equal(t, "", resp.Volumes[0].Mountpoint)
+	resp, _ = call(t, client, "VolumeDriver.Get", request{Name: "potato"})
+	assert.Equal(t, errVolumeNotFound.Error(), resp.Err)
+
+	// mount twice
+	mountpoint := filepath.Join(opt.BaseDir, "vol")
+	resp, _ = call(t, client, "VolumeDriver.Mount", request{Name: "vol", ID: "one"})
+	assert.Equal(t, "", resp.Err)
+	assert.Equal(t, mountpoint, resp.Mountpoint)
+	resp, _ = call(t, client, "VolumeDriver.Mount", request{Name: "vol", ID: "two"})
+	assert.Equal(t, mountpoint, resp.Mountpoint)
+	require.Len(t, fm.mounted, 1)
+	assert.True(t, fm.mounted[mountpoint].Opt.ReadOnly)
+	resp, _ = call(t, client, "VolumeDriver.Path", request{Name: "vol"})
+	assert.Equal(t, mountpoint, resp.Mountpoint)
+	resp, _ = call(t, client, "VolumeDriver.Get", request{Name: "vol"})
+	assert.Equal(t, mountpoint, resp.Volume.Mountpoint)
+	assert.Equal(t, float64(2), resp.Volume.Status["Users"])
+
+	// can't remove while in use
+	resp, _ = call(t, client, "VolumeDriver.Remove", request{Name: "vol"})
+	assert.Equal(t, errVolumeInUse.Error(), resp.Err)
+
+	// unmount when the last user goes
+	call(t, client, "VolumeDriver.Unmount", request{Name: "vol", ID: "one"})
+	assert.Len(t, fm.mounted, 1)
+	call(t, client, "VolumeDriver.Unmount", request{Name: "vol", ID: "two"})
+	assert.Len(t, fm.mounted, 0)
+	resp, _ = call(t, client, "VolumeDriver.Path", request{Name: "vol"})
+	assert.Equal(t, "", resp.Mountpoint)
+
+	// the volume is restored by a new driver
+	drv2, err := newDriver(context.Background(), &opt, "fake", fm.mount)
+	require.NoError(t, err)
+	infos := drv2.List()
+	require.Len(t, infos, 1)
+	assert.Equal(t, "vol", infos[0].Name)
+
+	// remove
+	resp, _ = call(t, client, "VolumeDriver.Remove", request{Name: "vol"})
+	assert.Equal(t, "", resp.Err)
+	resp, _ = call(t, client, "VolumeDriver.List", nil)
+	assert.Len(t, resp.Volumes, 0)
+	drv3, err := newDriver(context.Background(), &opt, "fake", fm.mount)
+	require.NoError(t, err)
+	assert.Len(t, drv3.List(), 0)
+}
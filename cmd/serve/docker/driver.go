@@ -0,0 +1,392 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd/mountlib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/rclone/rclone/vfs/vfsflags"
+	"github.com/spf13/pflag"
+)
+
+// Errors returned by the driver
+var (
+	errVolumeNotFound = errors.New("volume not found")
+	errVolumeExists   = errors.New("volume already exists")
+	errVolumeInUse    = errors.New("volume is in use")
+	errNoRemote       = errors.New("the \"remote\" option is required")
+	errBadVolumeName  = errors.New("bad volume name")
+)
+
+// volumeNameRe matches the volume names docker allows
+var volumeNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// volumeFlags are the VFS and mount flags which can be set per volume.
+//
+// They are bound to the global options so parsing must be done with
+// optMu held.
+var (
+	volumeFlags = pflag.NewFlagSet("volume", pflag.ContinueOnError)
+	optMu       sync.Mutex
+)
+
+func init() {
+	vfsflags.AddFlags(volumeFlags)
+	mountlib.AddFlags(volumeFlags)
+}
+
+// parseVolumeOptions turns the options given to docker volume create
+// into a remote and VFS and mount options.  Options not given take
+// their values from the command line.
+func parseVolumeOptions(opts map[string]string) (remote string, vfsOpt vfscommon.Options, mountOpt mountlib.Options, err error) {
+	optMu.Lock()
+	defer optMu.Unlock()
+	oldVFSOpt, oldMountOpt := vfsflags.Opt, mountlib.Opt
+	defer func() {
+		vfsflags.Opt, mountlib.Opt = oldVFSOpt, oldMountOpt
+	}()
+	// sort the keys so errors are deterministic
+	keys := make([]string, 0, len(opts))
+	for key := range opts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := opts[key]
+		if key == "remote" {
+			remote = value
+			continue
+		}
+		flag := volumeFlags.Lookup(key)
+		if flag == nil {
+			return "", vfsOpt, mountOpt, errors.Errorf("unknown option %q", key)
+		}
+		if value == "" && flag.NoOptDefVal != "" {
+			value = flag.NoOptDefVal
+		}
+		if err = flag.Value.Set(value); err != nil {
+			return "", vfsOpt, mountOpt, errors.Wrapf(err, "bad value for option %q", key)
+		}
+	}
+	if remote == "" {
+		return "", vfsOpt, mountOpt, errNoRemote
+	}
+	return remote, vfsflags.Opt, mountlib.Opt, nil
+}
+
+// volume is a docker volume backed by an rclone mount
+type volume struct {
+	Name      string            `json:"name"`
+	Options   map[string]string `json:"options"`
+	CreatedAt time.Time         `json:"createdAt"`
+
+	remote     string
+	mountpoint string
+	vfsOpt     vfscommon.Options
+	mountOpt   mountlib.Options
+	mountIDs   map[string]struct{} // containers using the mount
+	vfs        *vfs.VFS
+	unmountFn  mountlib.UnmountFn
+}
+
+// mounted returns true if the volume is mounted
+func (vol *volume) mounted() bool {
+	return vol.unmountFn != nil
+}
+
+// driver manages the volumes
+type driver struct {
+	mu        sync.Mutex
+	ctx       context.Context
+	opt       Options
+	mountType string
+	mountFn   mountlib.MountFn
+	volumes   map[string]*volume
+}
+
+// newDriver makes a new driver restoring any saved volumes
+func newDriver(ctx context.Context, opt *Options, mountType string, mountFn mountlib.MountFn) (*driver, error) {
+	if mountFn == nil {
+		return nil, errors.New("no mount implementation is available")
+	}
+	drv := &driver{
+		ctx:       ctx,
+		opt:       *opt,
+		mountType: mountType,
+		mountFn:   mountFn,
+		volumes:   map[string]*volume{},
+	}
+	if err := os.MkdirAll(drv.opt.BaseDir, 0700); err != nil {
+		return nil, errors.Wrap(err, "failed to make base directory")
+	}
+	if drv.opt.ForgetState {
+		return drv, nil
+	}
+	if err := drv.restoreState(); err != nil {
+		return nil, err
+	}
+	return drv, nil
+}
+
+// newVolume makes a volume from its name and options
+func (drv *driver) newVolume(name string, opts map[string]string, createdAt time.Time) (*volume, error) {
+	if !volumeNameRe.MatchString(name) {
+		return nil, errBadVolumeName
+	}
+	remote, vfsOpt, mountOpt, err := parseVolumeOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if mountOpt.VolumeName == "" {
+		mountOpt.VolumeName = name
+	}
+	return &volume{
+		Name:       name,
+		Options:    opts,
+		CreatedAt:  createdAt,
+		remote:     remote,
+		mountpoint: filepath.Join(drv.opt.BaseDir, name),
+		vfsOpt:     vfsOpt,
+		mountOpt:   mountOpt,
+		mountIDs:   map[string]struct{}{},
+	}, nil
+}
+
+// restoreState reads the saved volumes
+func (drv *driver) restoreState() error {
+	data, err := ioutil.ReadFile(drv.opt.StateFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "failed to read state")
+	}
+	var saved []*volume
+	if err = json.Unmarshal(data, &saved); err != nil {
+		return errors.Wrap(err, "failed to parse state")
+	}
+	for _, s := range saved {
+		vol, err := drv.newVolume(s.Name, s.Options, s.CreatedAt)
+		if err != nil {
+			fs.Errorf(nil, "docker: dropping saved volume %q: %v", s.Name, err)
+			continue
+		}
+		drv.volumes[vol.Name] = vol
+	}
+	fs.Infof(nil, "docker: restored %d volumes", len(drv.volumes))
+	return nil
+}
+
+// saveState writes the volumes to the state file - call with mu held
+func (drv *driver) saveState() error {
+	if drv.opt.ForgetState {
+		return nil
+	}
+	saved := make([]*volume, 0, len(drv.volumes))
+	for _, vol := range drv.volumes {
+		saved = append(saved, vol)
+	}
+	sort.Slice(saved, func(i, j int) bool {
+		return saved[i].Name < saved[j].Name
+	})
+	data, err := json.MarshalIndent(saved, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode state")
+	}
+	if err = os.MkdirAll(filepath.Dir(drv.opt.StateFile), 0700); err != nil {
+		return errors.Wrap(err, "failed to make state directory")
+	}
+	tmp := drv.opt.StateFile + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrap(err, "failed to write state")
+	}
+	if err = os.Rename(tmp, drv.opt.StateFile); err != nil {
+		return errors.Wrap(err, "failed to write state")
+	}
+	return nil
+}
+
+// Create makes a new volume
+func (drv *driver) Create(name string, opts map[string]string) error {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	if _, ok := drv.volumes[name]; ok {
+		return errVolumeExists
+	}
+	vol, err := drv.newVolume(name, opts, time.Now())
+	if err != nil {
+		return err
+	}
+	drv.volumes[name] = vol
+	fs.Infof(nil, "docker: created volume %q for %q", name, vol.remote)
+	return drv.saveState()
+}
+
+// Remove deletes a volume which isn't in use
+func (drv *driver) Remove(name string) error {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	vol, ok := drv.volumes[name]
+	if !ok {
+		return errVolumeNotFound
+	}
+	if vol.mounted() {
+		return errVolumeInUse
+	}
+	delete(drv.volumes, name)
+	if err := os.Remove(vol.mountpoint); err != nil && !os.IsNotExist(err) {
+		fs.Errorf(nil, "docker: failed to remove mountpoint %q: %v", vol.mountpoint, err)
+	}
+	fs.Infof(nil, "docker: removed volume %q", name)
+	return drv.saveState()
+}
+
+// Mount mounts the volume for the container id returning the
+// mountpoint.  The volume is only mounted once however many
+// containers use it.
+func (drv *driver) Mount(name, id string) (string, error) {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	vol, ok := drv.volumes[name]
+	if !ok {
+		return "", errVolumeNotFound
+	}
+	if !vol.mounted() {
+		f, err := fs.NewFs(drv.ctx, vol.remote)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to make remote")
+		}
+		if err = os.MkdirAll(vol.mountpoint, 0700); err != nil {
+			return "", errors.Wrap(err, "failed to make mountpoint")
+		}
+		VFS := vfs.New(f, &vol.vfsOpt)
+		_, unmountFn, err := drv.mountFn(VFS, vol.mountpoint, &vol.mountOpt)
+		if err != nil {
+			VFS.Shutdown()
+			return "", errors.Wrap(err, "failed to mount")
+		}
+		vol.vfs = VFS
+		vol.unmountFn = unmountFn
+		fs.Infof(nil, "docker: mounted volume %q on %q with %s", name, vol.mountpoint, drv.mountType)
+	}
+	vol.mountIDs[id] = struct{}{}
+	return vol.mountpoint, nil
+}
+
+// Unmount releases the volume for the container id unmounting it when
+// no containers are using it.
+func (drv *driver) Unmount(name, id string) error {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	vol, ok := drv.volumes[name]
+	if !ok {
+		return errVolumeNotFound
+	}
+	delete(vol.mountIDs, id)
+	if len(vol.mountIDs) > 0 || !vol.mounted() {
+		return nil
+	}
+	return vol.unmount()
+}
+
+// unmount unmounts the volume - call with the driver lock held
+func (vol *volume) unmount() error {
+	err := vol.unmountFn()
+	if err != nil {
+		return errors.Wrap(err, "failed to unmount")
+	}
+	vol.vfs.WaitForWriters(time.Minute)
+	vol.vfs.Shutdown()
+	vol.vfs = nil
+	vol.unmountFn = nil
+	vol.mountIDs = map[string]struct{}{}
+	fs.Infof(nil, "docker: unmounted volume %q", vol.Name)
+	return nil
+}
+
+// Path returns the mountpoint of the volume or "" if not mounted
+func (drv *driver) Path(name string) (string, error) {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	vol, ok := drv.volumes[name]
+	if !ok {
+		return "", errVolumeNotFound
+	}
+	if !vol.mounted() {
+		return "", nil
+	}
+	return vol.mountpoint, nil
+}
+
+// volumeInfo describes a volume in the plugin API
+type volumeInfo struct {
+	Name       string
+	Mountpoint string `json:",omitempty"`
+	CreatedAt  string `json:",omitempty"`
+	Status     map[string]interface{}
+}
+
+// info returns the API description of the volume - call with mu held
+func (vol *volume) info() *volumeInfo {
+	info := &volumeInfo{
+		Name:      vol.Name,
+		CreatedAt: vol.CreatedAt.Format(time.RFC3339),
+		Status: map[string]interface{}{
+			"Remote":  vol.remote,
+			"Mounted": vol.mounted(),
+			"Users":   len(vol.mountIDs),
+		},
+	}
+	if vol.mounted() {
+		info.Mountpoint = vol.mountpoint
+	}
+	return info
+}
+
+// Get returns the description of a volume
+func (drv *driver) Get(name string) (*volumeInfo, error) {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	vol, ok := drv.volumes[name]
+	if !ok {
+		return nil, errVolumeNotFound
+	}
+	return vol.info(), nil
+}
+
+// List returns the descriptions of all the volumes sorted by name
+func (drv *driver) List() []*volumeInfo {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	infos := make([]*volumeInfo, 0, len(drv.volumes))
+	for _, vol := range drv.volumes {
+		infos = append(infos, vol.info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// unmountAll unmounts all the mounted volumes
+func (drv *driver) unmountAll() {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+	for _, vol := range drv.volumes {
+		if vol.mounted() {
+			if err := vol.unmount(); err != nil {
+				fs.Errorf(nil, "docker: volume %q: %v", vol.Name, err)
+			}
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"os/user"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -34,12 +35,14 @@ import (
 type Options struct {
 	//TODO add more options
 	ListenAddr   string // Port to listen on
-	PublicIP     string // Passive ports range
+	PublicIP     string // Public IP address to advertise for passive connections
 	PassivePorts string // Passive ports range
 	BasicUser    string // single username for basic auth if not using Htpasswd
 	BasicPass    string // password for BasicUser
 	TLSCert      string // TLS PEM key (concatenation of certificate and CA certificate)
 	TLSKey       string // TLS PEM Private key
+	ExplicitTLS  bool   // use explicit FTPS (AUTH TLS) rather than implicit
+	ForceTLS     bool   // refuse clients which don't upgrade with AUTH TLS
 }
 
 // DefaultOpt is the default values used for Options
@@ -64,6 +67,8 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.StringVarP(flagSet, &Opt.BasicPass, "pass", "", Opt.BasicPass, "Password for authentication. (empty value allow every password)")
 	flags.StringVarP(flagSet, &Opt.TLSCert, "cert", "", Opt.TLSCert, "TLS PEM key (concatenation of certificate and CA certificate)")
 	flags.StringVarP(flagSet, &Opt.TLSKey, "key", "", Opt.TLSKey, "TLS PEM Private key")
+	flags.BoolVarP(flagSet, &Opt.ExplicitTLS, "explicit-tls", "", Opt.ExplicitTLS, "Use explicit FTPS (AUTH TLS) instead of implicit FTPS.")
+	flags.BoolVarP(flagSet, &Opt.ForceTLS, "force-tls", "", Opt.ForceTLS, "Refuse clients which don't upgrade to TLS with --explicit-tls.")
 }
 
 func init() {
@@ -96,6 +101,37 @@ then using Authentication is advised - see the next section for info.
 By default this will serve files without needing a login.
 
 You can set a single username and password with the --user and --pass flags.
+
+#### TLS
+
+Use --cert and --key to enable FTPS. Both must be supplied and should
+be PEM encoded.
+
+By default this runs implicit FTPS, where the client must start a TLS
+session as soon as it connects, usually on port 990.
+
+Use --explicit-tls to run explicit FTPS instead (RFC 4217). Clients
+connect with plain FTP, as they would normally on port 21, then
+upgrade the connection with AUTH TLS. Most clients call this "FTP
+over TLS" or "FTPES". The data connections are encrypted if the
+client asks for it with PROT P.
+
+With --explicit-tls, clients may carry on without encryption unless
+--force-tls is given, in which case every command other than AUTH TLS
+is refused until the connection has been upgraded.
+
+#### Passive mode and NAT
+
+The data for file transfers and listings is sent over a separate
+connection which the client opens to the server (passive mode). Use
+--passive-port to set the range of ports the server listens on for
+these, e.g. --passive-port 30000-30100. The range must contain at
+least two ports.
+
+If the server is behind NAT or a firewall then these ports need to be
+forwarded along with the --addr port, and the server needs to tell
+the client its public IPv4 address rather than the one it is listening
+on. Set this with --public-ip, e.g. --public-ip 203.0.113.10.
 ` + vfs.Help + proxy.Help,
 	Run: func(command *cobra.Command, args []string) {
 		var f fs.Fs
@@ -147,6 +183,18 @@ func newServer(ctx context.Context, f fs.Fs, opt *Options) (*server, error) {
 	} else {
 		s.vfs = vfs.New(f, &vfsflags.Opt)
 	}
+	if err := checkTLSOptions(opt); err != nil {
+		return nil, err
+	}
+	if err := checkPassivePorts(opt.PassivePorts); err != nil {
+		return nil, err
+	}
+	if opt.PublicIP != "" {
+		ip := net.ParseIP(opt.PublicIP)
+		if ip == nil || ip.To4() == nil {
+			return nil, errors.Errorf("invalid --public-ip %q: must be an IPv4 address", opt.PublicIP)
+		}
+	}
 	s.useTLS = s.opt.TLSKey != ""
 
 	ftpopt := &ftp.ServerOpts{
@@ -162,15 +210,69 @@ func newServer(ctx context.Context, f fs.Fs, opt *Options) (*server, error) {
 		TLS:            s.useTLS,
 		CertFile:       s.opt.TLSCert,
 		KeyFile:        s.opt.TLSKey,
+		ExplicitFTPS:   s.opt.ExplicitTLS,
 		//TODO implement a maximum of https://godoc.org/goftp.io/server#ServerOpts
 	}
 	s.srv = ftp.NewServer(ftpopt)
+	// NewServer doesn't copy ForceTLS so set it here
+	s.srv.ForceTLS = s.opt.ForceTLS
 	return s, nil
 }
 
+// checkTLSOptions checks the TLS options are consistent
+func checkTLSOptions(opt *Options) error {
+	if (opt.TLSCert == "") != (opt.TLSKey == "") {
+		return errors.New("need both --cert and --key to use TLS")
+	}
+	if opt.ExplicitTLS && opt.TLSKey == "" {
+		return errors.New("--explicit-tls needs --cert and --key")
+	}
+	if opt.ForceTLS && !opt.ExplicitTLS {
+		return errors.New("--force-tls needs --explicit-tls")
+	}
+	return nil
+}
+
+// checkPassivePorts checks the passive port range is of the form
+// start-end
+func checkPassivePorts(portRange string) error {
+	if portRange == "" {
+		return nil
+	}
+	parts := strings.Split(portRange, "-")
+	if len(parts) != 2 {
+		return errors.Errorf("invalid --passive-port %q: must be start-end", portRange)
+	}
+	var ports [2]int
+	for i, part := range parts {
+		port, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || port <= 0 || port > 65535 {
+			return errors.Errorf("invalid --passive-port %q: bad port %q", portRange, part)
+		}
+		ports[i] = port
+	}
+	if ports[0] >= ports[1] {
+		return errors.Errorf("invalid --passive-port %q: start must be less than end", portRange)
+	}
+	return nil
+}
+
+// tlsMode describes the TLS mode for the logs
+func (s *server) tlsMode() string {
+	switch {
+	case !s.useTLS:
+		return "without TLS"
+	case s.opt.ForceTLS:
+		return "with explicit TLS required"
+	case s.opt.ExplicitTLS:
+		return "with explicit TLS"
+	}
+	return "with implicit TLS"
+}
+
 // serve runs the ftp server
 func (s *server) serve() error {
-	fs.Logf(s.f, "Serving FTP on %s", s.srv.Hostname+":"+strconv.Itoa(s.srv.Port))
+	fs.Logf(s.f, "Serving FTP on %s %s", s.srv.Hostname+":"+strconv.Itoa(s.srv.Port), s.tlsMode())
 	return s.srv.ListenAndServe()
 }
 
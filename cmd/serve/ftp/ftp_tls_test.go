@@ -0,0 +1,160 @@
+//+build !plan9
+
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ftpclient "github.com/jlaffaye/ftp"
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ftp "goftp.io/server/core"
+)
+
+func TestCheckOptions(t *testing.T) {
+	for _, test := range []struct {
+		opt     Options
+		wantErr bool
+	}{
+		{Options{}, false},
+		{Options{TLSCert: "cert", TLSKey: "key"}, false},
+		{Options{TLSCert: "cert"}, true},
+		{Options{TLSKey: "key"}, true},
+		{Options{TLSCert: "cert", TLSKey: "key", ExplicitTLS: true}, false},
+		{Options{TLSCert: "cert", TLSKey: "key", ExplicitTLS: true, ForceTLS: true}, false},
+		{Options{ExplicitTLS: true}, true},
+		{Options{TLSCert: "cert", TLSKey: "key", ForceTLS: true}, true},
+	} {
+		err := checkTLSOptions(&test.opt)
+		assert.Equal(t, test.wantErr, err != nil, "%+v: %v", test.opt, err)
+	}
+
+	for _, test := range []struct {
+		ports   string
+		wantErr bool
+	}{
+		{"", false},
+		{"30000-32000", false},
+		{" 30000 - 30001 ", false},
+		{"30000", true},
+		{"30000-30000", true},
+		{"32000-30000", true},
+		{"0-100", true},
+		{"1-65536", true},
+		{"a-b", true},
+		{"1-2-3", true},
+	} {
+		err := checkPassivePorts(test.ports)
+		assert.Equal(t, test.wantErr, err != nil, "%q: %v", test.ports, err)
+	}
+}
+
+// writeCert writes a self signed certificate and key for localhost
+// into dir returning their paths
+func writeCert(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600))
+	return certFile, keyFile
+}
+
+// TestExplicitTLS checks a client can upgrade with AUTH TLS and
+// that --force-tls refuses clients which don't
+func TestExplicitTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-serve-ftp-tls")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.Mkdir(root, 0777))
+	f, err := fs.NewFs(context.Background(), root)
+	require.NoError(t, err)
+	certFile, keyFile := writeCert(t, dir)
+
+	const addr = "127.0.0.1:51781"
+	opt := DefaultOpt
+	opt.ListenAddr = addr
+	opt.PassivePorts = "30000-32000"
+	opt.BasicUser = testUSER
+	opt.BasicPass = testPASS
+	opt.TLSCert = certFile
+	opt.TLSKey = keyFile
+	opt.ExplicitTLS = true
+	opt.ForceTLS = true
+
+	s, err := newServer(context.Background(), f, &opt)
+	require.NoError(t, err)
+	assert.Equal(t, "with explicit TLS required", s.tlsMode())
+	quit := make(chan struct{})
+	go func() {
+		err := s.serve()
+		close(quit)
+		if err != ftp.ErrServerClosed {
+			assert.NoError(t, err)
+		}
+	}()
+	defer func() {
+		assert.NoError(t, s.close())
+		<-quit
+	}()
+
+	// wait for the server to start
+	var c *ftpclient.ServerConn
+	for i := 0; i < 50; i++ {
+		c, err = ftpclient.Dial(addr, ftpclient.DialWithTimeout(time.Second), ftpclient.DialWithExplicitTLS(&tls.Config{
+			InsecureSkipVerify: true,
+		}))
+		if err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	require.NoError(t, c.Login(testUSER, testPASS))
+	require.NoError(t, c.Stor("hello.txt", bytes.NewBufferString("hello")))
+	names, err := c.NameList("/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello.txt"}, names)
+	require.NoError(t, c.Quit())
+	data, err := ioutil.ReadFile(filepath.Join(root, "hello.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	// a plain client can't log in
+	c, err = ftpclient.Dial(addr, ftpclient.DialWithTimeout(time.Second))
+	require.NoError(t, err)
+	assert.Error(t, c.Login(testUSER, testPASS))
+	_ = c.Quit()
+}
@@ -38,6 +38,14 @@ to be used within the template to server pages:
 |-- .IsDir    | Boolean for if an entry is a directory or not. |
 |-- .Size     | Size in Bytes of the entry. |
 |-- .ModTime  | The UTC timestamp of an entry. |
+
+The following functions are available in the template as well as the
+standard Go template functions:
+
+| Function    | Description |
+| :---------- | :---------- |
+| afterEpoch  | True if the time is set, e.g. {{if afterEpoch .ModTime}} |
+| humanSize   | The size with a suffix, e.g. {{humanSize .Size}} gives "1.500 MiByte" |
 `
 
 // Options for the templating functionality
@@ -55,6 +63,11 @@ func AfterEpoch(t time.Time) bool {
 	return t.After(time.Time{})
 }
 
+// HumanSize returns the size in bytes with a suffix
+func HumanSize(size int64) string {
+	return fs.SizeSuffix(size).ByteUnit()
+}
+
 // GetTemplate returns the HTML template for serving directories via HTTP/Webdav
 func GetTemplate(tmpl string) (tpl *template.Template, err error) {
 	var templateString string
@@ -84,6 +97,7 @@ func GetTemplate(tmpl string) (tpl *template.Template, err error) {
 
 	funcMap := template.FuncMap{
 		"afterEpoch": AfterEpoch,
+		"humanSize":  HumanSize,
 	}
 	tpl, err = template.New("index").Funcs(funcMap).Parse(templateString)
 	if err != nil {
@@ -1,9 +1,10 @@
 package http
 
 import (
+	"context"
+	"fmt"
 	"html/template"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"path"
@@ -13,36 +14,84 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/cmd/serve/http/data"
+	"github.com/rclone/rclone/cmd/serve/proxy"
+	"github.com/rclone/rclone/cmd/serve/proxy/proxyflags"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/hash"
 	httplib "github.com/rclone/rclone/lib/http"
 	"github.com/rclone/rclone/lib/http/auth"
 	"github.com/rclone/rclone/lib/http/serve"
 	"github.com/rclone/rclone/vfs"
 	"github.com/rclone/rclone/vfs/vfsflags"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // Options required for http server
 type Options struct {
 	data.Options
+	ETagHash     string        // hash to use for the ETag, "auto" or "" for none
+	AllowOrigins []string      // origins allowed to make CORS requests
+	AllowHeaders []string      // request headers allowed in CORS requests
+	CORSMaxAge   time.Duration // how long CORS preflight responses may be cached
 }
 
 // DefaultOpt is the default values used for Options
-var DefaultOpt = Options{}
+var DefaultOpt = Options{
+	AllowHeaders: []string{"Range", "If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since", "Authorization"},
+	CORSMaxAge:   time.Hour,
+}
 
 // Opt is options set by command line flags
 var Opt = DefaultOpt
 
 func init() {
 	data.AddFlags(Command.Flags(), "", &Opt.Options)
+	AddFlags(Command.Flags(), &Opt)
 	httplib.AddFlags(Command.Flags())
 	auth.AddFlags(Command.Flags())
 	vfsflags.AddFlags(Command.Flags())
+	proxyflags.AddFlags(Command.Flags())
+}
+
+// AddFlags adds the flags for serving http
+func AddFlags(flagSet *pflag.FlagSet, Opt *Options) {
+	flags.StringVarP(flagSet, &Opt.ETagHash, "etag-hash", "", Opt.ETagHash, "Which hash to use for the ETag, or auto or blank for modtime and size")
+	flags.StringArrayVarP(flagSet, &Opt.AllowOrigins, "allow-origin", "", Opt.AllowOrigins, "Origin allowed to read the files with CORS or * for any (can be repeated)")
+	flags.StringArrayVarP(flagSet, &Opt.AllowHeaders, "allow-header", "", Opt.AllowHeaders, "Request header allowed in CORS requests (can be repeated)")
+	flags.DurationVarP(flagSet, &Opt.CORSMaxAge, "cors-max-age", "", Opt.CORSMaxAge, "How long browsers may cache CORS preflight responses")
 }
 
+// Help describes the options specific to serve http
+var Help = `
+### Caching and conditional requests
+
+Files are served with Last-Modified and ETag headers so browsers and
+caches can make conditional requests with If-Modified-Since,
+If-None-Match, If-Match and If-Unmodified-Since.  Range requests and
+If-Range are supported too.
+
+By default the ETag is made from the modification time and size of
+the file.  Use --etag-hash to use a hash of the file instead, either
+"auto" for the first hash the remote supports or a named hash such as
+"MD5" or "SHA-1".  Use "rclone hashsum" to see the full list.
+
+### CORS
+
+To let web pages on other sites fetch the files, list the origins
+allowed with --allow-origin, e.g. --allow-origin https://example.com,
+or use --allow-origin '*' to allow any.  Preflight OPTIONS requests
+are answered allowing GET and HEAD with the request headers given by
+--allow-header and cached for --cors-max-age.  The Content-Length,
+Content-Range, ETag, Last-Modified and Accept-Ranges headers are
+exposed to scripts.
+`
+
 // Command definition for cobra
 var Command = &cobra.Command{
 	Use:   "http remote:path",
@@ -58,17 +107,26 @@ The server will log errors.  Use -v to see access logs.
 
 --bwlimit will be respected for file transfers.  Use --stats to
 control the stats printing.
-` + httplib.Help + data.Help + auth.Help + vfs.Help,
+` + Help + httplib.Help + data.Help + auth.Help + vfs.Help + proxy.Help,
 	Run: func(command *cobra.Command, args []string) {
-		cmd.CheckArgs(1, 1, command, args)
-		f := cmd.NewFsSrc(args)
+		var f fs.Fs
+		if proxyflags.Opt.AuthProxy == "" {
+			cmd.CheckArgs(1, 1, command, args)
+			f = cmd.NewFsSrc(args)
+		} else {
+			cmd.CheckArgs(0, 0, command, args)
+		}
 		cmd.Run(false, true, command, func() error {
-			s := newServer(f, Opt.Template)
+			s, err := newServer(context.Background(), f, &Opt)
+			if err != nil {
+				return err
+			}
 			router, err := httplib.Router()
 			if err != nil {
 				return err
 			}
 			s.Bind(router)
+			httplib.Wait()
 			return nil
 		})
 	},
@@ -77,21 +135,43 @@ control the stats printing.
 // server contains everything to run the server
 type server struct {
 	f            fs.Fs
-	vfs          *vfs.VFS
+	_vfs         *vfs.VFS // don't use directly, use getVFS
+	proxy        *proxy.Proxy
+	opt          Options
+	hashType     hash.Type          // hash to use for the ETag
 	HTMLTemplate *template.Template // HTML template for web interface
 }
 
-func newServer(f fs.Fs, templatePath string) *server {
-	htmlTemplate, templateErr := data.GetTemplate(templatePath)
-	if templateErr != nil {
-		log.Fatalf(templateErr.Error())
+func newServer(ctx context.Context, f fs.Fs, opt *Options) (*server, error) {
+	htmlTemplate, err := data.GetTemplate(opt.Template)
+	if err != nil {
+		return nil, err
 	}
 	s := &server{
 		f:            f,
-		vfs:          vfs.New(f, &vfsflags.Opt),
+		opt:          *opt,
+		hashType:     hash.None,
 		HTMLTemplate: htmlTemplate,
 	}
-	return s
+	if proxyflags.Opt.AuthProxy != "" {
+		s.proxy = proxy.New(ctx, &proxyflags.Opt)
+	} else {
+		s._vfs = vfs.New(f, &vfsflags.Opt)
+	}
+	if opt.ETagHash == "auto" {
+		// with the proxy this is found for each user's backend
+		if f != nil {
+			s.hashType = f.Hashes().GetOne()
+		}
+	} else if opt.ETagHash != "" {
+		if err := s.hashType.Set(opt.ETagHash); err != nil {
+			return nil, err
+		}
+	}
+	if s.hashType != hash.None {
+		fs.Debugf(f, "Using hash %v for ETag", s.hashType)
+	}
+	return s, nil
 }
 
 func (s *server) Bind(router chi.Router) {
@@ -99,25 +179,131 @@ func (s *server) Bind(router chi.Router) {
 		middleware.SetHeader("Accept-Ranges", "bytes"),
 		middleware.SetHeader("Server", "rclone/"+fs.Version),
 	)
-	router.Get("/*", s.handler)
-	router.Head("/*", s.handler)
+	if len(s.opt.AllowOrigins) > 0 {
+		router.Use(s.cors)
+		router.Options("/*", s.preflight)
+	}
+	authOpt := auth.Opt
+	if s.proxy != nil {
+		authOpt.Auth = s.auth
+	}
+	router.Group(func(router chi.Router) {
+		if authMiddleware := auth.Auth(authOpt); authMiddleware != nil {
+			router.Use(authMiddleware)
+		}
+		router.Get("/*", s.handler)
+		router.Head("/*", s.handler)
+	})
+}
+
+// auth does proxy authorization
+func (s *server) auth(user, pass string) (value interface{}, err error) {
+	VFS, _, err := s.proxy.Call(user, pass, false)
+	if err != nil {
+		return nil, err
+	}
+	return VFS, err
+}
+
+// getVFS returns the VFS in use for this request
+func (s *server) getVFS(ctx context.Context) (VFS *vfs.VFS, err error) {
+	if s._vfs != nil {
+		return s._vfs, nil
+	}
+	value := ctx.Value(auth.ContextAuthKey)
+	if value == nil {
+		return nil, errors.New("no VFS found in context")
+	}
+	VFS, ok := value.(*vfs.VFS)
+	if !ok {
+		return nil, errors.Errorf("context value is not VFS: %#v", value)
+	}
+	return VFS, nil
+}
+
+// allowedOrigin returns the value for the Access-Control-Allow-Origin
+// header for the request or "" if its origin isn't allowed
+func (s *server) allowedOrigin(r *http.Request) string {
+	origin := r.Header.Get("Origin")
+	for _, allowed := range s.opt.AllowOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+		if origin != "" && allowed == origin {
+			return origin
+		}
+	}
+	return ""
+}
+
+// cors is middleware to add the CORS headers to responses
+func (s *server) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		if origin := s.allowedOrigin(r); origin != "" {
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Set("Access-Control-Expose-Headers", "Content-Length, Content-Range, ETag, Last-Modified, Accept-Ranges")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// preflight answers CORS preflight requests
+func (s *server) preflight(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Set("Allow", "GET, HEAD, OPTIONS")
+	if s.allowedOrigin(r) != "" && r.Header.Get("Access-Control-Request-Method") != "" {
+		h.Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+		if len(s.opt.AllowHeaders) > 0 {
+			h.Set("Access-Control-Allow-Headers", strings.Join(s.opt.AllowHeaders, ", "))
+		}
+		if s.opt.CORSMaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(int(s.opt.CORSMaxAge/time.Second)))
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// etag returns the ETag for obj or "" if there isn't one
+func (s *server) etag(ctx context.Context, obj fs.Object, file *vfs.File) string {
+	hashType := s.hashType
+	if s.proxy != nil && s.opt.ETagHash == "auto" {
+		hashType = obj.Fs().Hashes().GetOne()
+	}
+	if hashType != hash.None {
+		sum, err := obj.Hash(ctx, hashType)
+		if err == nil && sum != "" {
+			return `"` + sum + `"`
+		}
+		return ""
+	}
+	if obj.Size() < 0 {
+		return ""
+	}
+	return fmt.Sprintf(`"%x-%x"`, file.ModTime().UnixNano(), obj.Size())
 }
 
 // handler reads incoming requests and dispatches them
 func (s *server) handler(w http.ResponseWriter, r *http.Request) {
 	isDir := strings.HasSuffix(r.URL.Path, "/")
 	remote := strings.Trim(r.URL.Path, "/")
+	VFS, err := s.getVFS(r.Context())
+	if err != nil {
+		serve.Error(remote, w, "Failed to get VFS", err)
+		return
+	}
 	if isDir {
-		s.serveDir(w, r, remote)
+		s.serveDir(w, r, VFS, remote)
 	} else {
-		s.serveFile(w, r, remote)
+		s.serveFile(w, r, VFS, remote)
 	}
 }
 
 // serveDir serves a directory index at dirRemote
-func (s *server) serveDir(w http.ResponseWriter, r *http.Request, dirRemote string) {
+func (s *server) serveDir(w http.ResponseWriter, r *http.Request, VFS *vfs.VFS, dirRemote string) {
 	// List the directory
-	node, err := s.vfs.Stat(dirRemote)
+	node, err := VFS.Stat(dirRemote)
 	if err == vfs.ENOENT {
 		http.Error(w, "Directory not found", http.StatusNotFound)
 		return
@@ -157,8 +343,8 @@ func (s *server) serveDir(w http.ResponseWriter, r *http.Request, dirRemote stri
 }
 
 // serveFile serves a file object at remote
-func (s *server) serveFile(w http.ResponseWriter, r *http.Request, remote string) {
-	node, err := s.vfs.Stat(remote)
+func (s *server) serveFile(w http.ResponseWriter, r *http.Request, VFS *vfs.VFS, remote string) {
+	node, err := VFS.Stat(remote)
 	if err == vfs.ENOENT {
 		fs.Infof(remote, "%s: File not found", r.RemoteAddr)
 		http.Error(w, "File not found", http.StatusNotFound)
@@ -193,8 +379,18 @@ func (s *server) serveFile(w http.ResponseWriter, r *http.Request, remote string
 		w.Header().Set("Content-Type", mimeType)
 	}
 
-	// Set the Last-Modified header to the timestamp
-	w.Header().Set("Last-Modified", file.ModTime().UTC().Format(http.TimeFormat))
+	// Set the Last-Modified and ETag headers
+	modTime := file.ModTime()
+	w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	etag := s.etag(r.Context(), obj, file)
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+
+	// Answer conditional requests without opening the object
+	if serve.CheckPreconditions(w, r, modTime, etag) {
+		return
+	}
 
 	// If HEAD no need to read the object since we have set the headers
 	if r.Method == "HEAD" {
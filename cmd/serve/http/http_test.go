@@ -27,12 +27,18 @@ var (
 const (
 	testBindAddress = "localhost:0"
 	testTemplate    = "testdata/golden/testindex.html"
+	testOrigin      = "https://example.com"
 )
 
 func startServer(t *testing.T, f fs.Fs) {
 	opt := httplib.DefaultOpt
 	opt.ListenAddr = testBindAddress
-	httpServer = newServer(f, testTemplate)
+	serverOpt := DefaultOpt
+	serverOpt.Template = testTemplate
+	serverOpt.AllowOrigins = []string{testOrigin}
+	var err error
+	httpServer, err = newServer(context.Background(), f, &serverOpt)
+	require.NoError(t, err)
 	router, err := httplib.Router()
 	if err != nil {
 		t.Fatal(err.Error())
@@ -229,6 +235,73 @@ func TestGET(t *testing.T) {
 	}
 }
 
+// do does a request returning the response with the body read
+func do(t *testing.T, method, path string, headers map[string]string) *http.Response {
+	req, err := http.NewRequest(method, testURL+path, nil)
+	require.NoError(t, err)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	return resp
+}
+
+func TestConditional(t *testing.T) {
+	resp := do(t, "GET", datedObject, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	assert.NotEqual(t, "", etag)
+	lastModified := resp.Header.Get("Last-Modified")
+
+	for _, method := range []string{"GET", "HEAD"} {
+		resp = do(t, method, datedObject, map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode, method)
+		assert.Equal(t, etag, resp.Header.Get("ETag"), method)
+
+		resp = do(t, method, datedObject, map[string]string{"If-Modified-Since": lastModified})
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode, method)
+
+		resp = do(t, method, datedObject, map[string]string{"If-Modified-Since": expectedTime.Add(-time.Hour).Format(http.TimeFormat)})
+		assert.Equal(t, http.StatusOK, resp.StatusCode, method)
+
+		resp = do(t, method, datedObject, map[string]string{"If-None-Match": `"potato"`})
+		assert.Equal(t, http.StatusOK, resp.StatusCode, method)
+
+		resp = do(t, method, datedObject, map[string]string{"If-Match": `"potato"`})
+		assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode, method)
+	}
+
+	// If-Range with the current ETag gets the range
+	resp = do(t, "GET", datedObject, map[string]string{"Range": "bytes=2-5", "If-Range": etag})
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	resp = do(t, "GET", datedObject, map[string]string{"Range": "bytes=2-5", "If-Range": `"potato"`})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCORS(t *testing.T) {
+	resp := do(t, "GET", datedObject, map[string]string{"Origin": testOrigin})
+	assert.Equal(t, testOrigin, resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, resp.Header.Get("Access-Control-Expose-Headers"), "ETag")
+
+	resp = do(t, "GET", datedObject, map[string]string{"Origin": "https://evil.example.com"})
+	assert.Equal(t, "", resp.Header.Get("Access-Control-Allow-Origin"))
+
+	resp = do(t, "OPTIONS", datedObject, map[string]string{
+		"Origin":                         testOrigin,
+		"Access-Control-Request-Method":  "GET",
+		"Access-Control-Request-Headers": "Range",
+	})
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, testOrigin, resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, HEAD, OPTIONS", resp.Header.Get("Access-Control-Allow-Methods"))
+	assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "Range")
+	assert.Equal(t, "3600", resp.Header.Get("Access-Control-Max-Age"))
+}
+
 func TestFinalise(t *testing.T) {
 	_ = httplib.Shutdown()
 }
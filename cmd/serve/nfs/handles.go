@@ -0,0 +1,263 @@
+package nfs
+
+import (
+	"encoding/binary"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// handleSize is the size of the file handles we issue
+const handleSize = 16
+
+// rootID is the id of the root directory
+const rootID = 1
+
+// handles maps NFS file handles to paths in the VFS
+//
+// A handle is the server epoch followed by an id, both 64 bit.  The
+// epoch changes each time the server is started so clients get
+// NFS3ERR_STALE for handles from a previous run rather than the
+// wrong file.
+type handles struct {
+	mu     sync.Mutex
+	epoch  uint64
+	nextID uint64
+	paths  map[uint64]string
+	ids    map[string]uint64
+}
+
+// newHandles makes a new handle table
+func newHandles() *handles {
+	h := &handles{
+		epoch:  uint64(time.Now().UnixNano()),
+		nextID: rootID + 1,
+		paths:  map[uint64]string{rootID: ""},
+		ids:    map[string]uint64{"": rootID},
+	}
+	return h
+}
+
+// encode makes the file handle for id
+func (h *handles) encode(id uint64) []byte {
+	b := make([]byte, handleSize)
+	binary.BigEndian.PutUint64(b, h.epoch)
+	binary.BigEndian.PutUint64(b[8:], id)
+	return b
+}
+
+// toHandle returns the file handle for the path, allocating one if
+// necessary
+func (h *handles) toHandle(p string) []byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	id, ok := h.ids[p]
+	if !ok {
+		id = h.nextID
+		h.nextID++
+		h.ids[p] = id
+		h.paths[id] = p
+	}
+	return h.encode(id)
+}
+
+// fromHandle returns the id and path of the file handle
+func (h *handles) fromHandle(b []byte) (id uint64, p string, status uint32) {
+	if len(b) != handleSize {
+		return 0, "", nfs3ErrBadHandle
+	}
+	if binary.BigEndian.Uint64(b) != h.epoch {
+		return 0, "", nfs3ErrStale
+	}
+	id = binary.BigEndian.Uint64(b[8:])
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	p, ok := h.paths[id]
+	if !ok {
+		return 0, "", nfs3ErrStale
+	}
+	return id, p, nfs3OK
+}
+
+// remove forgets the handle for path
+func (h *handles) remove(p string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if id, ok := h.ids[p]; ok && id != rootID {
+		delete(h.ids, p)
+		delete(h.paths, id)
+	}
+}
+
+// rename updates the handles for oldPath and anything below it to
+// newPath so handles held by clients stay valid
+func (h *handles) rename(oldPath, newPath string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// anything already at the destination is replaced
+	if id, ok := h.ids[newPath]; ok {
+		delete(h.ids, newPath)
+		delete(h.paths, id)
+	}
+	prefix := oldPath + "/"
+	for p, id := range h.ids {
+		var renamed string
+		switch {
+		case p == oldPath:
+			renamed = newPath
+		case strings.HasPrefix(p, prefix):
+			renamed = newPath + "/" + p[len(prefix):]
+		default:
+			continue
+		}
+		delete(h.ids, p)
+		h.ids[renamed] = id
+		h.paths[id] = renamed
+	}
+}
+
+// joinPath joins a directory path and a leaf name
+func joinPath(dir, leaf string) string {
+	if dir == "" {
+		return leaf
+	}
+	return dir + "/" + leaf
+}
+
+// parentPath returns the path of the parent of p
+func parentPath(p string) string {
+	parent := path.Dir(p)
+	if parent == "." || parent == "/" {
+		return ""
+	}
+	return parent
+}
+
+// openFile is an open VFS handle kept between NFS calls
+type openFile struct {
+	mu       sync.Mutex // held while the handle is in use
+	h        vfs.Handle
+	write    bool
+	lastUsed time.Time // protected by openFiles.mu
+}
+
+// openFiles keeps VFS handles open between NFS calls since NFS is
+// stateless but the VFS needs sequential access through a single
+// handle when not caching.
+type openFiles struct {
+	mu    sync.Mutex
+	vfs   *vfs.VFS
+	files map[uint64]*openFile
+}
+
+// newOpenFiles makes a new open file cache
+func newOpenFiles(VFS *vfs.VFS) *openFiles {
+	return &openFiles{
+		vfs:   VFS,
+		files: map[uint64]*openFile{},
+	}
+}
+
+// get returns an open handle for id suitable for reading or writing.
+//
+// The openFile is returned locked and must be unlocked by the caller.
+func (of *openFiles) get(id uint64, p string, write bool, offset int64) (*openFile, error) {
+	of.mu.Lock()
+	f := of.files[id]
+	if f != nil && write && !f.write {
+		// need to reopen a read handle for writing
+		delete(of.files, id)
+		of.mu.Unlock()
+		f.mu.Lock()
+		f.close(p)
+		f.mu.Unlock()
+		return of.get(id, p, write, offset)
+	}
+	if f == nil {
+		flags := os.O_RDONLY
+		if write {
+			flags = os.O_WRONLY
+			if of.vfs.Opt.CacheMode >= vfscommon.CacheModeWrites {
+				flags = os.O_RDWR
+			} else if offset == 0 {
+				// without a cache the file can only be
+				// rewritten from the start
+				flags |= os.O_TRUNC
+			}
+		}
+		h, err := of.vfs.OpenFile(p, flags, 0777)
+		if err != nil {
+			of.mu.Unlock()
+			return nil, err
+		}
+		f = &openFile{h: h, write: write}
+		of.files[id] = f
+	}
+	f.lastUsed = time.Now()
+	of.mu.Unlock()
+	f.mu.Lock()
+	return f, nil
+}
+
+// add stores an already open handle for id
+func (of *openFiles) add(id uint64, h vfs.Handle) {
+	of.mu.Lock()
+	old := of.files[id]
+	of.files[id] = &openFile{h: h, write: true, lastUsed: time.Now()}
+	of.mu.Unlock()
+	if old != nil {
+		old.mu.Lock()
+		old.close(h.Node().Path())
+		old.mu.Unlock()
+	}
+}
+
+// release closes any handle open for id returning the close error
+func (of *openFiles) release(id uint64) error {
+	of.mu.Lock()
+	f := of.files[id]
+	delete(of.files, id)
+	of.mu.Unlock()
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.h.Close()
+}
+
+// close closes the handle logging any error - call with f.mu held
+func (f *openFile) close(p string) {
+	if err := f.h.Close(); err != nil {
+		fs.Errorf(p, "nfs: failed to close file: %v", err)
+	}
+}
+
+// expire closes handles which haven't been used since before cutoff
+func (of *openFiles) expire(cutoff time.Time) {
+	of.mu.Lock()
+	var expired []*openFile
+	for id, f := range of.files {
+		if f.lastUsed.Before(cutoff) {
+			expired = append(expired, f)
+			delete(of.files, id)
+		}
+	}
+	of.mu.Unlock()
+	for _, f := range expired {
+		f.mu.Lock()
+		f.close(f.h.Node().Path())
+		f.mu.Unlock()
+	}
+}
+
+// closeAll closes all the open handles
+func (of *openFiles) closeAll() {
+	of.expire(time.Now().Add(time.Hour))
+}
@@ -0,0 +1,97 @@
+package nfs
+
+// MOUNT and PORTMAP program constants from RFC 1813 and RFC 1833
+const (
+	mountProgram = 100005
+	mountVersion = 3
+
+	mountOK    = 0
+	mountNoEnt = 2
+
+	pmapProgram = 100000
+	pmapVersion = 2
+
+	ipProtoTCP = 6
+
+	// exportPath is the only path we export
+	exportPath = "/"
+)
+
+// mountProcs are the MOUNT v3 procedures indexed by procedure number
+var mountProcs = map[uint32]struct {
+	name string
+	fn   func(s *server, r *xdrReader, w *xdrWriter) error
+}{
+	0: {"NULL", nfsNull},
+	1: {"MNT", mountMnt},
+	2: {"DUMP", mountDump},
+	3: {"UMNT", mountUmnt},
+	4: {"UMNTALL", nfsNull},
+	5: {"EXPORT", mountExport},
+}
+
+// pmapProcs are the PORTMAP v2 procedures indexed by procedure number
+//
+// Only enough is implemented for clients to discover that the MOUNT
+// and NFS programs are available on our port.
+var pmapProcs = map[uint32]struct {
+	name string
+	fn   func(s *server, r *xdrReader, w *xdrWriter) error
+}{
+	0: {"NULL", nfsNull},
+	3: {"GETPORT", pmapGetPort},
+}
+
+func mountMnt(s *server, r *xdrReader, w *xdrWriter) error {
+	dir := r.string()
+	if r.err != nil {
+		return errGarbage
+	}
+	if dir != exportPath && dir != "" {
+		w.uint32(mountNoEnt)
+		return nil
+	}
+	w.uint32(mountOK)
+	w.opaque(s.handles.toHandle(""))
+	w.uint32(2) // auth flavors
+	w.uint32(authUnix)
+	w.uint32(authNull)
+	return nil
+}
+
+func mountDump(s *server, r *xdrReader, w *xdrWriter) error {
+	w.bool(false) // no mount list entries
+	return nil
+}
+
+func mountUmnt(s *server, r *xdrReader, w *xdrWriter) error {
+	r.string()
+	if r.err != nil {
+		return errGarbage
+	}
+	return nil
+}
+
+func mountExport(s *server, r *xdrReader, w *xdrWriter) error {
+	w.bool(true) // an export follows
+	w.string(exportPath)
+	w.bool(false) // no groups
+	w.bool(false) // no more exports
+	return nil
+}
+
+func pmapGetPort(s *server, r *xdrReader, w *xdrWriter) error {
+	prog := r.uint32()
+	vers := r.uint32()
+	prot := r.uint32()
+	r.uint32() // port
+	if r.err != nil {
+		return errGarbage
+	}
+	port := uint32(0)
+	if prot == ipProtoTCP && ((prog == nfsProgram && vers == nfsVersion) || (prog == mountProgram && vers == mountVersion)) {
+		port = uint32(s.port)
+	}
+	w.uint32(port)
+	return nil
+}
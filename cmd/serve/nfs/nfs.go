@@ -0,0 +1,330 @@
+// Package nfs implements an NFS version 3 server for rclone
+package nfs
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfsflags"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Options contains options for the NFS Server
+type Options struct {
+	ListenAddr     string        // Port to listen on
+	HandleTimeout  time.Duration // how long to keep idle files open
+	MaxConcurrency int           // number of requests to process at once per connection
+}
+
+// DefaultOpt is the default values used for Options
+var DefaultOpt = Options{
+	ListenAddr:     "localhost:2049",
+	HandleTimeout:  5 * time.Second,
+	MaxConcurrency: 16,
+}
+
+// Opt is options set by command line flags
+var Opt = DefaultOpt
+
+// AddFlags adds flags for nfs
+func AddFlags(flagSet *pflag.FlagSet) {
+	rc.AddOption("nfs", &Opt)
+	flags.StringVarP(flagSet, &Opt.ListenAddr, "addr", "", Opt.ListenAddr, "IPaddress:Port or :Port to bind server to.")
+	flags.DurationVarP(flagSet, &Opt.HandleTimeout, "handle-timeout", "", Opt.HandleTimeout, "Time to keep idle files open between NFS calls.")
+	flags.IntVarP(flagSet, &Opt.MaxConcurrency, "max-concurrency", "", Opt.MaxConcurrency, "Max number of requests to process at once per connection.")
+}
+
+func init() {
+	vfsflags.AddFlags(Command.Flags())
+	AddFlags(Command.Flags())
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "nfs remote:path",
+	Short: `Serve remote:path over NFS.`,
+	Long: `
+rclone serve nfs implements an NFS version 3 server to serve the
+remote so it can be mounted with the operating system's NFS client
+without needing FUSE.
+
+The server provides the PORTMAP, MOUNT and NFS programs all on the
+same TCP port so the client should be told not to use the portmapper
+and not to use NFS locking, e.g. on Linux
+
+    mount -t nfs -o port=2049,mountport=2049,tcp,nolock,vers=3 localhost:/ /mnt/point
+
+and on macOS
+
+    mount -t nfs -o port=2049,mountport=2049,tcp,nolocks,vers=3 localhost:/ /mnt/point
+
+The remote is exported as "/".
+
+NFS clients read and write files at arbitrary offsets, so it is
+strongly recommended to use --vfs-cache-mode writes or full.  Without
+the cache files can only be written sequentially from the start.
+
+### Server options
+
+Use --addr to specify which IP address and port the server should
+listen on, e.g. --addr 1.2.3.4:2049 or --addr :2049 to listen to all
+IPs.  By default it only listens on localhost.  You can use port
+:0 to let the OS choose an available port.
+
+There is no authentication: the server accepts AUTH_NULL and
+AUTH_UNIX credentials without checking them, and all files appear to
+be owned by --uid and --gid.  Don't listen on a public IP address.
+
+NFS has no open or close, so files are kept open between calls and
+closed (and uploaded) once they have been idle for --handle-timeout
+or when the client sends a COMMIT.
+
+--max-concurrency controls how many requests from a single
+connection are processed at once.
+` + vfs.Help,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		cmd.Run(false, false, command, func() error {
+			s, err := newServer(context.Background(), f, &Opt)
+			if err != nil {
+				return err
+			}
+			if err = s.Serve(); err != nil {
+				return err
+			}
+			s.Wait()
+			return nil
+		})
+	},
+}
+
+// server contains everything to run the server
+type server struct {
+	f         fs.Fs
+	opt       Options
+	vfs       *vfs.VFS
+	listener  net.Listener
+	port      int
+	fsid      uint64
+	writeVerf [8]byte
+	handles   *handles
+	open      *openFiles
+	mu        sync.Mutex
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+	quit      chan struct{}
+}
+
+// Make a new NFS server to serve the remote
+func newServer(ctx context.Context, f fs.Fs, opt *Options) (*server, error) {
+	s := &server{
+		f:       f,
+		opt:     *opt,
+		vfs:     vfs.New(f, &vfsflags.Opt),
+		handles: newHandles(),
+		conns:   map[net.Conn]struct{}{},
+		quit:    make(chan struct{}),
+	}
+	if s.opt.MaxConcurrency < 1 {
+		s.opt.MaxConcurrency = 1
+	}
+	s.open = newOpenFiles(s.vfs)
+	if _, err := rand.Read(s.writeVerf[:]); err != nil {
+		return nil, errors.Wrap(err, "failed to make write verifier")
+	}
+	s.fsid = binary.BigEndian.Uint64(s.writeVerf[:])
+	var err error
+	s.listener, err = net.Listen("tcp", s.opt.ListenAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open listener")
+	}
+	s.port = s.listener.Addr().(*net.TCPAddr).Port
+	return s, nil
+}
+
+// Addr returns the address the server is listening on
+func (s *server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Serve starts the server in the background
+func (s *server) Serve() error {
+	fs.Logf(s.f, "NFS server listening on %v", s.Addr())
+	s.wg.Add(2)
+	go s.acceptConnections()
+	go s.expireHandles()
+	return nil
+}
+
+// Wait blocks until the server is closed
+func (s *server) Wait() {
+	s.wg.Wait()
+}
+
+// Close shuts the server down closing any open files
+func (s *server) Close() error {
+	close(s.quit)
+	err := s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	s.open.closeAll()
+	return err
+}
+
+// expireHandles closes idle open files periodically
+func (s *server) expireHandles() {
+	defer s.wg.Done()
+	interval := s.opt.HandleTimeout / 2
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.open.expire(time.Now().Add(-s.opt.HandleTimeout))
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// acceptConnections accepts connections until the listener is closed
+func (s *server) acceptConnections() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.quit:
+			default:
+				fs.Errorf(nil, "NFS: failed to accept connection: %v", err)
+			}
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.handleConnection(conn)
+	}
+}
+
+// handleConnection reads RPC calls from conn and dispatches them
+func (s *server) handleConnection(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+	fs.Debugf(nil, "NFS: connection from %v", conn.RemoteAddr())
+	var (
+		in       = bufio.NewReader(conn)
+		writeMu  sync.Mutex
+		requests sync.WaitGroup
+		tokens   = make(chan struct{}, s.opt.MaxConcurrency)
+	)
+	defer requests.Wait()
+	for {
+		msg, err := readRecord(in)
+		if err != nil {
+			if err != io.EOF && !isClosed(err) {
+				fs.Errorf(nil, "NFS: failed to read from %v: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		tokens <- struct{}{}
+		requests.Add(1)
+		go func() {
+			defer func() {
+				<-tokens
+				requests.Done()
+			}()
+			reply := s.handleCall(msg)
+			if reply == nil {
+				return
+			}
+			writeMu.Lock()
+			err := writeRecord(conn, reply)
+			writeMu.Unlock()
+			if err != nil && !isClosed(err) {
+				fs.Errorf(nil, "NFS: failed to write to %v: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// isClosed returns true if err is from a closed connection
+func isClosed(err error) bool {
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
+
+// handleCall decodes and runs the RPC call in msg returning the
+// reply or nil if no reply should be sent
+func (s *server) handleCall(msg []byte) []byte {
+	call, err := parseCall(msg)
+	if err != nil {
+		fs.Debugf(nil, "NFS: dropping bad RPC call: %v", err)
+		return nil
+	}
+	if call.rpcVers != rpcVersion {
+		return rpcMismatchReply(call.xid).Bytes()
+	}
+	var procs map[uint32]struct {
+		name string
+		fn   func(s *server, r *xdrReader, w *xdrWriter) error
+	}
+	var progName string
+	switch call.prog {
+	case nfsProgram:
+		if call.vers != nfsVersion {
+			return progMismatchReply(call.xid, nfsVersion, nfsVersion).Bytes()
+		}
+		procs, progName = nfsProcs, "NFS"
+	case mountProgram:
+		if call.vers != mountVersion {
+			return progMismatchReply(call.xid, mountVersion, mountVersion).Bytes()
+		}
+		procs, progName = mountProcs, "MOUNT"
+	case pmapProgram:
+		if call.vers != pmapVersion {
+			return progMismatchReply(call.xid, pmapVersion, pmapVersion).Bytes()
+		}
+		procs, progName = pmapProcs, "PORTMAP"
+	default:
+		return acceptedReply(call.xid, acceptProgUnavail).Bytes()
+	}
+	proc, ok := procs[call.proc]
+	if !ok {
+		return acceptedReply(call.xid, acceptProcUnavail).Bytes()
+	}
+	fs.Debugf(nil, "NFS: %s %s", progName, proc.name)
+	w := acceptedReply(call.xid, acceptSuccess)
+	if err := proc.fn(s, call.args, w); err != nil {
+		fs.Debugf(nil, "NFS: %s %s: %v", progName, proc.name, err)
+		return acceptedReply(call.xid, acceptGarbageArgs).Bytes()
+	}
+	return w.Bytes()
+}
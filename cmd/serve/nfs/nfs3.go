@@ -0,0 +1,830 @@
+package nfs
+
+import (
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+)
+
+// NFS version 3 constants from RFC 1813
+const (
+	nfsProgram = 100003
+	nfsVersion = 3
+
+	nfs3OK             = 0
+	nfs3ErrPerm        = 1
+	nfs3ErrNoEnt       = 2
+	nfs3ErrIO          = 5
+	nfs3ErrAcces       = 13
+	nfs3ErrExist       = 17
+	nfs3ErrNotDir      = 20
+	nfs3ErrIsDir       = 21
+	nfs3ErrInval       = 22
+	nfs3ErrRoFs        = 30
+	nfs3ErrNameTooLong = 63
+	nfs3ErrNotEmpty    = 66
+	nfs3ErrStale       = 70
+	nfs3ErrBadHandle   = 10001
+	nfs3ErrNotSupp     = 10004
+	nfs3ErrServerFault = 10006
+
+	nf3Reg = 1
+	nf3Dir = 2
+
+	access3Read    = 0x0001
+	access3Lookup  = 0x0002
+	access3Modify  = 0x0004
+	access3Extend  = 0x0008
+	access3Delete  = 0x0010
+	access3Execute = 0x0020
+
+	timeDontChange   = 0
+	timeServerTime   = 1
+	timeClientTime   = 2
+	createUnchecked  = 0
+	createGuarded    = 1
+	createExclusive  = 2
+	stableFileSync   = 2
+	fsfHomogeneous   = 0x0008
+	fsfCanSetTime    = 0x0010
+	maxNameLen       = 255
+	maxTransferSize  = 1024 * 1024
+	dirEntryOverhead = 24 // approximate XDR size of a directory entry without the name
+	attrSize         = 88 // XDR size of post_op_attr with fattr3
+)
+
+// nfsProcs are the NFS v3 procedures indexed by procedure number
+var nfsProcs = map[uint32]struct {
+	name string
+	fn   func(s *server, r *xdrReader, w *xdrWriter) error
+}{
+	0:  {"NULL", nfsNull},
+	1:  {"GETATTR", nfsGetAttr},
+	2:  {"SETATTR", nfsSetAttr},
+	3:  {"LOOKUP", nfsLookup},
+	4:  {"ACCESS", nfsAccess},
+	5:  {"READLINK", nfsReadLink},
+	6:  {"READ", nfsRead},
+	7:  {"WRITE", nfsWrite},
+	8:  {"CREATE", nfsCreate},
+	9:  {"MKDIR", nfsMkdir},
+	10: {"SYMLINK", nfsNotSuppWcc},
+	11: {"MKNOD", nfsNotSuppWcc},
+	12: {"REMOVE", nfsRemove},
+	13: {"RMDIR", nfsRemove},
+	14: {"RENAME", nfsRename},
+	15: {"LINK", nfsLink},
+	16: {"READDIR", nfsReadDir},
+	17: {"READDIRPLUS", nfsReadDirPlus},
+	18: {"FSSTAT", nfsFsStat},
+	19: {"FSINFO", nfsFsInfo},
+	20: {"PATHCONF", nfsPathConf},
+	21: {"COMMIT", nfsCommit},
+}
+
+// translateError converts a VFS error into an NFS status
+func translateError(err error) uint32 {
+	if err == nil {
+		return nfs3OK
+	}
+	cause := errors.Cause(err)
+	switch {
+	case cause == vfs.ENOENT || os.IsNotExist(cause) || cause == fs.ErrorDirNotFound || cause == fs.ErrorObjectNotFound:
+		return nfs3ErrNoEnt
+	case cause == vfs.EEXIST || os.IsExist(cause):
+		return nfs3ErrExist
+	case cause == vfs.EPERM || os.IsPermission(cause):
+		return nfs3ErrPerm
+	case cause == vfs.EINVAL:
+		return nfs3ErrInval
+	case cause == vfs.ENOTEMPTY || cause == fs.ErrorDirectoryNotEmpty:
+		return nfs3ErrNotEmpty
+	case cause == vfs.EROFS:
+		return nfs3ErrRoFs
+	case cause == vfs.ENOSYS:
+		return nfs3ErrNotSupp
+	case cause == vfs.EBADF:
+		return nfs3ErrBadHandle
+	}
+	return nfs3ErrIO
+}
+
+// writeTime writes an nfstime3
+func writeTime(w *xdrWriter, t time.Time) {
+	w.uint32(uint32(t.Unix()))
+	w.uint32(uint32(t.Nanosecond()))
+}
+
+// writeFattr writes the fattr3 for node
+func (s *server) writeFattr(w *xdrWriter, node vfs.Node) {
+	size := uint64(0)
+	if node.IsDir() {
+		w.uint32(nf3Dir)
+		w.uint32(uint32(s.vfs.Opt.DirPerms.Perm()))
+		w.uint32(2)
+	} else {
+		w.uint32(nf3Reg)
+		w.uint32(uint32(s.vfs.Opt.FilePerms.Perm()))
+		w.uint32(1)
+		if node.Size() > 0 {
+			size = uint64(node.Size())
+		}
+	}
+	w.uint32(s.vfs.Opt.UID)
+	w.uint32(s.vfs.Opt.GID)
+	w.uint64(size) // size
+	w.uint64(size) // used
+	w.uint32(0)    // rdev
+	w.uint32(0)
+	w.uint64(s.fsid)
+	w.uint64(node.Inode())
+	modTime := node.ModTime()
+	writeTime(w, modTime) // atime
+	writeTime(w, modTime) // mtime
+	writeTime(w, modTime) // ctime
+}
+
+// writePostOpAttr writes the post_op_attr for the path
+func (s *server) writePostOpAttr(w *xdrWriter, p string) {
+	node, err := s.vfs.Stat(p)
+	if err != nil {
+		w.bool(false)
+		return
+	}
+	w.bool(true)
+	s.writeFattr(w, node)
+}
+
+// writeWcc writes wcc_data with no pre operation attributes
+func (s *server) writeWcc(w *xdrWriter, p string) {
+	w.bool(false)
+	s.writePostOpAttr(w, p)
+}
+
+// writeEmptyWcc writes wcc_data with no attributes
+func writeEmptyWcc(w *xdrWriter) {
+	w.bool(false)
+	w.bool(false)
+}
+
+// sattr3 holds the attributes to set from a SETATTR, CREATE or MKDIR
+type sattr3 struct {
+	setSize  bool
+	size     uint64
+	mtimeHow uint32
+	mtime    time.Time
+}
+
+// readTimeHow reads a set_atime or set_mtime
+func readTimeHow(r *xdrReader) (how uint32, t time.Time) {
+	how = r.uint32()
+	switch how {
+	case timeServerTime:
+		t = time.Now()
+	case timeClientTime:
+		sec := r.uint32()
+		nsec := r.uint32()
+		t = time.Unix(int64(sec), int64(nsec))
+	}
+	return how, t
+}
+
+// readSattr reads a sattr3 - mode, uid and gid are ignored
+func readSattr(r *xdrReader) (attr sattr3) {
+	if r.bool() { // mode
+		r.uint32()
+	}
+	if r.bool() { // uid
+		r.uint32()
+	}
+	if r.bool() { // gid
+		r.uint32()
+	}
+	attr.setSize = r.bool()
+	if attr.setSize {
+		attr.size = r.uint64()
+	}
+	readTimeHow(r) // atime is not supported
+	attr.mtimeHow, attr.mtime = readTimeHow(r)
+	return attr
+}
+
+// setAttr applies attr to the node at p
+func (s *server) setAttr(id uint64, p string, attr sattr3) error {
+	node, err := s.vfs.Stat(p)
+	if err != nil {
+		return err
+	}
+	if attr.setSize {
+		if node.IsDir() {
+			return vfs.EINVAL
+		}
+		// truncate through any open handle so it stays consistent
+		if f, err := s.open.get(id, p, true, int64(attr.size)); err == nil {
+			err = f.h.Truncate(int64(attr.size))
+			f.mu.Unlock()
+			if err != nil {
+				return err
+			}
+		} else if err = node.Truncate(int64(attr.size)); err != nil {
+			return err
+		}
+	}
+	if attr.mtimeHow != timeDontChange {
+		if err = node.SetModTime(attr.mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readHandle reads a file handle and checks it is known
+func (s *server) readHandle(r *xdrReader) (id uint64, p string, status uint32) {
+	fh := r.opaque()
+	if r.err != nil {
+		return 0, "", nfs3ErrBadHandle
+	}
+	return s.handles.fromHandle(fh)
+}
+
+// checkName checks a file name is valid for creating or looking up
+func checkName(name string) uint32 {
+	if len(name) > maxNameLen {
+		return nfs3ErrNameTooLong
+	}
+	if name == "" || name == "." || name == ".." {
+		return nfs3ErrInval
+	}
+	for _, c := range name {
+		if c == '/' {
+			return nfs3ErrInval
+		}
+	}
+	return nfs3OK
+}
+
+func nfsNull(s *server, r *xdrReader, w *xdrWriter) error {
+	return nil
+}
+
+func nfsGetAttr(s *server, r *xdrReader, w *xdrWriter) error {
+	_, p, status := s.readHandle(r)
+	if r.err != nil {
+		return errGarbage
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		return nil
+	}
+	node, err := s.vfs.Stat(p)
+	if err != nil {
+		w.uint32(translateError(err))
+		return nil
+	}
+	w.uint32(nfs3OK)
+	s.writeFattr(w, node)
+	return nil
+}
+
+func nfsSetAttr(s *server, r *xdrReader, w *xdrWriter) error {
+	id, p, status := s.readHandle(r)
+	attr := readSattr(r)
+	if r.bool() { // guard - not supported
+		r.uint32()
+		r.uint32()
+	}
+	if r.err != nil {
+		return errGarbage
+	}
+	if status == nfs3OK {
+		status = translateError(s.setAttr(id, p, attr))
+	}
+	w.uint32(status)
+	if status == nfs3ErrStale || status == nfs3ErrBadHandle {
+		writeEmptyWcc(w)
+	} else {
+		s.writeWcc(w, p)
+	}
+	return nil
+}
+
+func nfsLookup(s *server, r *xdrReader, w *xdrWriter) error {
+	_, dir, status := s.readHandle(r)
+	name := r.string()
+	if r.err != nil {
+		return errGarbage
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		w.bool(false)
+		return nil
+	}
+	var p string
+	switch name {
+	case ".":
+		p = dir
+	case "..":
+		p = parentPath(dir)
+	default:
+		if status = checkName(name); status != nfs3OK {
+			w.uint32(status)
+			s.writePostOpAttr(w, dir)
+			return nil
+		}
+		p = joinPath(dir, name)
+	}
+	node, err := s.vfs.Stat(p)
+	if err != nil {
+		w.uint32(translateError(err))
+		s.writePostOpAttr(w, dir)
+		return nil
+	}
+	w.uint32(nfs3OK)
+	w.opaque(s.handles.toHandle(p))
+	w.bool(true)
+	s.writeFattr(w, node)
+	s.writePostOpAttr(w, dir)
+	return nil
+}
+
+func nfsAccess(s *server, r *xdrReader, w *xdrWriter) error {
+	_, p, status := s.readHandle(r)
+	access := r.uint32()
+	if r.err != nil {
+		return errGarbage
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		w.bool(false)
+		return nil
+	}
+	node, err := s.vfs.Stat(p)
+	if err != nil {
+		w.uint32(translateError(err))
+		w.bool(false)
+		return nil
+	}
+	if s.vfs.Opt.ReadOnly {
+		access &^= access3Modify | access3Extend | access3Delete
+	}
+	if !node.IsDir() {
+		access &^= access3Lookup
+	}
+	w.uint32(nfs3OK)
+	w.bool(true)
+	s.writeFattr(w, node)
+	w.uint32(access)
+	return nil
+}
+
+func nfsReadLink(s *server, r *xdrReader, w *xdrWriter) error {
+	_, p, status := s.readHandle(r)
+	if r.err != nil {
+		return errGarbage
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		w.bool(false)
+		return nil
+	}
+	w.uint32(nfs3ErrNotSupp)
+	s.writePostOpAttr(w, p)
+	return nil
+}
+
+func nfsRead(s *server, r *xdrReader, w *xdrWriter) error {
+	id, p, status := s.readHandle(r)
+	offset := r.uint64()
+	count := r.uint32()
+	if r.err != nil {
+		return errGarbage
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		w.bool(false)
+		return nil
+	}
+	if count > maxTransferSize {
+		count = maxTransferSize
+	}
+	node, err := s.vfs.Stat(p)
+	if err == nil && node.IsDir() {
+		w.uint32(nfs3ErrIsDir)
+		s.writePostOpAttr(w, p)
+		return nil
+	}
+	var (
+		buf = make([]byte, count)
+		n   int
+		eof bool
+	)
+	if err == nil {
+		var f *openFile
+		f, err = s.open.get(id, p, false, int64(offset))
+		if err == nil {
+			n, err = f.h.ReadAt(buf, int64(offset))
+			f.mu.Unlock()
+			if err == io.EOF {
+				eof = true
+				err = nil
+			}
+		}
+	}
+	if err != nil {
+		fs.Errorf(p, "nfs: read failed: %v", err)
+		w.uint32(translateError(err))
+		s.writePostOpAttr(w, p)
+		return nil
+	}
+	if size := node.Size(); size >= 0 && int64(offset)+int64(n) >= size {
+		eof = true
+	}
+	w.uint32(nfs3OK)
+	s.writePostOpAttr(w, p)
+	w.uint32(uint32(n))
+	w.bool(eof)
+	w.opaque(buf[:n])
+	return nil
+}
+
+func nfsWrite(s *server, r *xdrReader, w *xdrWriter) error {
+	id, p, status := s.readHandle(r)
+	offset := r.uint64()
+	r.uint32() // count
+	r.uint32() // stable - we always reply FILE_SYNC
+	data := r.opaque()
+	if r.err != nil {
+		return errGarbage
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		writeEmptyWcc(w)
+		return nil
+	}
+	f, err := s.open.get(id, p, true, int64(offset))
+	if err == nil {
+		_, err = f.h.WriteAt(data, int64(offset))
+		f.mu.Unlock()
+	}
+	if err != nil {
+		fs.Errorf(p, "nfs: write failed: %v", err)
+		w.uint32(translateError(err))
+		s.writeWcc(w, p)
+		return nil
+	}
+	w.uint32(nfs3OK)
+	s.writeWcc(w, p)
+	w.uint32(uint32(len(data)))
+	w.uint32(stableFileSync)
+	w.fixed(s.writeVerf[:])
+	return nil
+}
+
+// writeCreateResult writes the result of CREATE or MKDIR
+func (s *server) writeCreateResult(w *xdrWriter, dir, p string, err error) {
+	if err != nil {
+		w.uint32(translateError(err))
+		s.writeWcc(w, dir)
+		return
+	}
+	w.uint32(nfs3OK)
+	w.bool(true)
+	w.opaque(s.handles.toHandle(p))
+	s.writePostOpAttr(w, p)
+	s.writeWcc(w, dir)
+}
+
+func nfsCreate(s *server, r *xdrReader, w *xdrWriter) error {
+	_, dir, status := s.readHandle(r)
+	name := r.string()
+	how := r.uint32()
+	var attr sattr3
+	switch how {
+	case createUnchecked, createGuarded:
+		attr = readSattr(r)
+	case createExclusive:
+		r.fixed(8) // verifier
+	}
+	if r.err != nil {
+		return errGarbage
+	}
+	if status == nfs3OK {
+		status = checkName(name)
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		writeEmptyWcc(w)
+		return nil
+	}
+	p := joinPath(dir, name)
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if how != createUnchecked {
+		flags |= os.O_EXCL
+	}
+	h, err := s.vfs.OpenFile(p, flags, 0777)
+	if err == nil {
+		// keep the handle open for the WRITEs which follow
+		fh := s.handles.toHandle(p)
+		id, _, _ := s.handles.fromHandle(fh)
+		s.open.add(id, h)
+		if attr.mtimeHow != timeDontChange {
+			err = s.setAttr(id, p, sattr3{mtimeHow: attr.mtimeHow, mtime: attr.mtime})
+		}
+	}
+	s.writeCreateResult(w, dir, p, err)
+	return nil
+}
+
+func nfsMkdir(s *server, r *xdrReader, w *xdrWriter) error {
+	_, dir, status := s.readHandle(r)
+	name := r.string()
+	readSattr(r)
+	if r.err != nil {
+		return errGarbage
+	}
+	if status == nfs3OK {
+		status = checkName(name)
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		writeEmptyWcc(w)
+		return nil
+	}
+	p := joinPath(dir, name)
+	err := s.vfs.Mkdir(p, 0777)
+	s.writeCreateResult(w, dir, p, err)
+	return nil
+}
+
+// nfsNotSuppWcc replies not supported to calls returning wcc_data
+func nfsNotSuppWcc(s *server, r *xdrReader, w *xdrWriter) error {
+	w.uint32(nfs3ErrNotSupp)
+	writeEmptyWcc(w)
+	return nil
+}
+
+func nfsRemove(s *server, r *xdrReader, w *xdrWriter) error {
+	_, dir, status := s.readHandle(r)
+	name := r.string()
+	if r.err != nil {
+		return errGarbage
+	}
+	if status == nfs3OK {
+		status = checkName(name)
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		writeEmptyWcc(w)
+		return nil
+	}
+	p := joinPath(dir, name)
+	if id, _, idStatus := s.handles.fromHandle(s.handles.toHandle(p)); idStatus == nfs3OK {
+		_ = s.open.release(id)
+	}
+	err := s.vfs.Remove(p)
+	if err == nil {
+		s.handles.remove(p)
+	}
+	w.uint32(translateError(err))
+	s.writeWcc(w, dir)
+	return nil
+}
+
+func nfsRename(s *server, r *xdrReader, w *xdrWriter) error {
+	_, fromDir, fromStatus := s.readHandle(r)
+	fromName := r.string()
+	_, toDir, toStatus := s.readHandle(r)
+	toName := r.string()
+	if r.err != nil {
+		return errGarbage
+	}
+	status := fromStatus
+	if status == nfs3OK {
+		status = toStatus
+	}
+	if status == nfs3OK {
+		status = checkName(fromName)
+	}
+	if status == nfs3OK {
+		status = checkName(toName)
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		writeEmptyWcc(w)
+		writeEmptyWcc(w)
+		return nil
+	}
+	fromPath := joinPath(fromDir, fromName)
+	toPath := joinPath(toDir, toName)
+	// flush any open handle so the file is complete before the rename
+	if id, _, idStatus := s.handles.fromHandle(s.handles.toHandle(fromPath)); idStatus == nfs3OK {
+		_ = s.open.release(id)
+	}
+	err := s.vfs.Rename(fromPath, toPath)
+	if err == nil {
+		s.handles.rename(fromPath, toPath)
+	}
+	w.uint32(translateError(err))
+	s.writeWcc(w, fromDir)
+	s.writeWcc(w, toDir)
+	return nil
+}
+
+func nfsLink(s *server, r *xdrReader, w *xdrWriter) error {
+	w.uint32(nfs3ErrNotSupp)
+	w.bool(false)
+	writeEmptyWcc(w)
+	return nil
+}
+
+// readDir returns the sorted directory entries of the directory p
+func (s *server) readDir(p string) ([]os.FileInfo, error) {
+	entries, err := s.vfs.ReadDir(p)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// readDirCommon implements READDIR and READDIRPLUS
+func readDirCommon(s *server, r *xdrReader, w *xdrWriter, plus bool) error {
+	_, p, status := s.readHandle(r)
+	cookie := r.uint64()
+	r.fixed(8) // cookie verifier - not checked
+	count := r.uint32()
+	if plus {
+		count = r.uint32() // maxcount limits the whole reply
+	}
+	if r.err != nil {
+		return errGarbage
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		w.bool(false)
+		return nil
+	}
+	entries, err := s.readDir(p)
+	if err != nil {
+		w.uint32(translateError(err))
+		s.writePostOpAttr(w, p)
+		return nil
+	}
+	if cookie > uint64(len(entries)) {
+		w.uint32(nfs3ErrInval)
+		s.writePostOpAttr(w, p)
+		return nil
+	}
+	w.uint32(nfs3OK)
+	s.writePostOpAttr(w, p)
+	w.fixed(make([]byte, 8)) // cookie verifier
+	size := w.Len() + 8
+	eof := true
+	for i := int(cookie); i < len(entries); i++ {
+		name := entries[i].Name()
+		entrySize := dirEntryOverhead + len(name) + pad(len(name))
+		if plus {
+			entrySize += attrSize + 4 + 4 + handleSize
+		}
+		if size+entrySize > int(count) {
+			eof = false
+			break
+		}
+		size += entrySize
+		entryPath := joinPath(p, name)
+		node, ok := entries[i].(vfs.Node)
+		if !ok {
+			continue
+		}
+		w.bool(true) // another entry follows
+		w.uint64(node.Inode())
+		w.string(name)
+		w.uint64(uint64(i + 1))
+		if plus {
+			w.bool(true)
+			s.writeFattr(w, node)
+			w.bool(true)
+			w.opaque(s.handles.toHandle(entryPath))
+		}
+	}
+	w.bool(false) // no more entries
+	w.bool(eof)
+	return nil
+}
+
+func nfsReadDir(s *server, r *xdrReader, w *xdrWriter) error {
+	return readDirCommon(s, r, w, false)
+}
+
+func nfsReadDirPlus(s *server, r *xdrReader, w *xdrWriter) error {
+	return readDirCommon(s, r, w, true)
+}
+
+func nfsFsStat(s *server, r *xdrReader, w *xdrWriter) error {
+	_, p, status := s.readHandle(r)
+	if r.err != nil {
+		return errGarbage
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		w.bool(false)
+		return nil
+	}
+	const unknownSize = 1 << 50
+	total, _, free := s.vfs.Statfs()
+	if total < 0 {
+		total = unknownSize
+	}
+	if free < 0 {
+		free = unknownSize
+	}
+	w.uint32(nfs3OK)
+	s.writePostOpAttr(w, p)
+	w.uint64(uint64(total)) // tbytes
+	w.uint64(uint64(free))  // fbytes
+	w.uint64(uint64(free))  // abytes
+	w.uint64(1 << 32)       // tfiles
+	w.uint64(1 << 32)       // ffiles
+	w.uint64(1 << 32)       // afiles
+	w.uint32(0)             // invarsec
+	return nil
+}
+
+func nfsFsInfo(s *server, r *xdrReader, w *xdrWriter) error {
+	_, p, status := s.readHandle(r)
+	if r.err != nil {
+		return errGarbage
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		w.bool(false)
+		return nil
+	}
+	w.uint32(nfs3OK)
+	s.writePostOpAttr(w, p)
+	w.uint32(maxTransferSize) // rtmax
+	w.uint32(maxTransferSize) // rtpref
+	w.uint32(4096)            // rtmult
+	w.uint32(maxTransferSize) // wtmax
+	w.uint32(maxTransferSize) // wtpref
+	w.uint32(4096)            // wtmult
+	w.uint32(64 * 1024)       // dtpref
+	w.uint64(1<<63 - 1)       // maxfilesize
+	w.uint32(0)               // time_delta
+	w.uint32(1)
+	w.uint32(fsfHomogeneous | fsfCanSetTime)
+	return nil
+}
+
+func nfsPathConf(s *server, r *xdrReader, w *xdrWriter) error {
+	_, p, status := s.readHandle(r)
+	if r.err != nil {
+		return errGarbage
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		w.bool(false)
+		return nil
+	}
+	w.uint32(nfs3OK)
+	s.writePostOpAttr(w, p)
+	w.uint32(1)          // linkmax
+	w.uint32(maxNameLen) // name_max
+	w.bool(true)         // no_trunc
+	w.bool(true)         // chown_restricted
+	w.bool(s.vfs.Opt.CaseInsensitive)
+	w.bool(true) // case_preserving
+	return nil
+}
+
+func nfsCommit(s *server, r *xdrReader, w *xdrWriter) error {
+	id, p, status := s.readHandle(r)
+	r.uint64() // offset
+	r.uint32() // count
+	if r.err != nil {
+		return errGarbage
+	}
+	if status != nfs3OK {
+		w.uint32(status)
+		writeEmptyWcc(w)
+		return nil
+	}
+	err := s.open.release(id)
+	if err != nil {
+		fs.Errorf(p, "nfs: commit failed: %v", err)
+		w.uint32(translateError(err))
+		s.writeWcc(w, p)
+		return nil
+	}
+	w.uint32(nfs3OK)
+	s.writeWcc(w, p)
+	w.fixed(s.writeVerf[:])
+	return nil
+}
@@ -0,0 +1,332 @@
+package nfs
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/rclone/rclone/vfs/vfsflags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXDR(t *testing.T) {
+	w := new(xdrWriter)
+	w.uint32(42)
+	w.uint64(1 << 40)
+	w.bool(true)
+	w.string("hello")
+	w.opaque(nil)
+	w.fixed([]byte{1, 2})
+	assert.Equal(t, 0, w.Len()%4)
+
+	r := newXDRReader(w.Bytes())
+	assert.Equal(t, uint32(42), r.uint32())
+	assert.Equal(t, uint64(1<<40), r.uint64())
+	assert.True(t, r.bool())
+	assert.Equal(t, "hello", r.string())
+	assert.Equal(t, "", r.string())
+	assert.Equal(t, []byte{1, 2}, r.fixed(2))
+	require.NoError(t, r.err)
+
+	// reading past the end is sticky
+	assert.Equal(t, uint32(0), r.uint32())
+	assert.Equal(t, errGarbage, r.err)
+	assert.Equal(t, "", r.string())
+}
+
+func TestHandles(t *testing.T) {
+	h := newHandles()
+	root := h.toHandle("")
+	id, p, status := h.fromHandle(root)
+	assert.Equal(t, uint32(nfs3OK), status)
+	assert.Equal(t, uint64(rootID), id)
+	assert.Equal(t, "", p)
+
+	fh := h.toHandle("dir/file")
+	assert.Equal(t, fh, h.toHandle("dir/file"))
+	h.toHandle("dir")
+	h.rename("dir", "newdir")
+	_, p, status = h.fromHandle(fh)
+	assert.Equal(t, uint32(nfs3OK), status)
+	assert.Equal(t, "newdir/file", p)
+
+	h.remove("newdir/file")
+	_, _, status = h.fromHandle(fh)
+	assert.Equal(t, uint32(nfs3ErrStale), status)
+
+	_, _, status = h.fromHandle([]byte{1, 2, 3})
+	assert.Equal(t, uint32(nfs3ErrBadHandle), status)
+
+	other := newHandles()
+	other.epoch++
+	_, _, status = other.fromHandle(root)
+	assert.Equal(t, uint32(nfs3ErrStale), status)
+}
+
+// rpcClient is a minimal ONC RPC client for testing
+type rpcClient struct {
+	t    *testing.T
+	conn net.Conn
+	in   *bufio.Reader
+	xid  uint32
+}
+
+// call calls prog.proc with args returning a reader for the results
+func (c *rpcClient) call(prog, vers, proc uint32, args func(w *xdrWriter)) *xdrReader {
+	c.xid++
+	w := new(xdrWriter)
+	w.uint32(c.xid)
+	w.uint32(msgCall)
+	w.uint32(rpcVersion)
+	w.uint32(prog)
+	w.uint32(vers)
+	w.uint32(proc)
+	w.uint32(authNull)
+	w.opaque(nil)
+	w.uint32(authNull)
+	w.opaque(nil)
+	if args != nil {
+		args(w)
+	}
+	require.NoError(c.t, writeRecord(c.conn, w.Bytes()))
+	msg, err := readRecord(c.in)
+	require.NoError(c.t, err)
+	r := newXDRReader(msg)
+	assert.Equal(c.t, c.xid, r.uint32())
+	assert.Equal(c.t, uint32(msgReply), r.uint32())
+	assert.Equal(c.t, uint32(replyAccepted), r.uint32())
+	r.uint32() // verifier
+	r.opaque()
+	require.Equal(c.t, uint32(acceptSuccess), r.uint32())
+	return r
+}
+
+// nfs calls an NFS procedure checking the status returned
+func (c *rpcClient) nfs(proc uint32, wantStatus uint32, args func(w *xdrWriter)) *xdrReader {
+	r := c.call(nfsProgram, nfsVersion, proc, args)
+	require.Equal(c.t, wantStatus, r.uint32(), "NFS proc %d", proc)
+	return r
+}
+
+// skipPostOpAttr skips a post_op_attr
+func skipPostOpAttr(r *xdrReader) {
+	if r.bool() {
+		r.fixed(84)
+	}
+}
+
+// skipWcc skips wcc_data
+func skipWcc(r *xdrReader) {
+	if r.bool() {
+		r.fixed(24)
+	}
+	skipPostOpAttr(r)
+}
+
+func TestServer(t *testing.T) {
+	fstest.Initialise()
+	f, _, clean, err := fstest.RandomRemote()
+	require.NoError(t, err)
+	defer clean()
+
+	oldCacheMode := vfsflags.Opt.CacheMode
+	vfsflags.Opt.CacheMode = vfscommon.CacheModeWrites
+	defer func() {
+		vfsflags.Opt.CacheMode = oldCacheMode
+	}()
+
+	opt := DefaultOpt
+	opt.ListenAddr = "localhost:0"
+	s, err := newServer(context.Background(), f, &opt)
+	require.NoError(t, err)
+	require.NoError(t, s.Serve())
+	defer func() {
+		assert.NoError(t, s.Close())
+	}()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer func() {
+		_ = conn.Close()
+	}()
+	c := &rpcClient{t: t, conn: conn, in: bufio.NewReader(conn)}
+
+	// portmapper points at ourselves
+	r := c.call(pmapProgram, pmapVersion, 3, func(w *xdrWriter) {
+		w.uint32(mountProgram)
+		w.uint32(mountVersion)
+		w.uint32(ipProtoTCP)
+		w.uint32(0)
+	})
+	assert.Equal(t, uint32(s.port), r.uint32())
+
+	// mount
+	r = c.call(mountProgram, mountVersion, 1, func(w *xdrWriter) {
+		w.string("/")
+	})
+	require.Equal(t, uint32(mountOK), r.uint32())
+	root := r.opaque()
+	require.NoError(t, r.err)
+
+	// create a file
+	r = c.nfs(8, nfs3OK, func(w *xdrWriter) {
+		w.opaque(root)
+		w.string("hello.txt")
+		w.uint32(createGuarded)
+		for i := 0; i < 6; i++ {
+			w.bool(false)
+		}
+	})
+	require.True(t, r.bool())
+	fh := r.opaque()
+
+	// creating again fails when guarded
+	c.nfs(8, nfs3ErrExist, func(w *xdrWriter) {
+		w.opaque(root)
+		w.string("hello.txt")
+		w.uint32(createGuarded)
+		for i := 0; i < 6; i++ {
+			w.bool(false)
+		}
+	})
+
+	// write to it out of order
+	for _, part := range []struct {
+		offset uint64
+		data   string
+	}{
+		{6, "world"},
+		{0, "hello "},
+	} {
+		r = c.nfs(7, nfs3OK, func(w *xdrWriter) {
+			w.opaque(fh)
+			w.uint64(part.offset)
+			w.uint32(uint32(len(part.data)))
+			w.uint32(stableFileSync)
+			w.string(part.data)
+		})
+		skipWcc(r)
+		assert.Equal(t, uint32(len(part.data)), r.uint32())
+	}
+
+	// commit so it is uploaded
+	c.nfs(21, nfs3OK, func(w *xdrWriter) {
+		w.opaque(fh)
+		w.uint64(0)
+		w.uint32(0)
+	})
+
+	// lookup
+	r = c.nfs(3, nfs3OK, func(w *xdrWriter) {
+		w.opaque(root)
+		w.string("hello.txt")
+	})
+	assert.Equal(t, fh, r.opaque())
+	c.nfs(3, nfs3ErrNoEnt, func(w *xdrWriter) {
+		w.opaque(root)
+		w.string("potato")
+	})
+
+	// getattr
+	r = c.nfs(1, nfs3OK, func(w *xdrWriter) {
+		w.opaque(fh)
+	})
+	assert.Equal(t, uint32(nf3Reg), r.uint32())
+	r.uint32() // mode
+	r.uint32() // nlink
+	r.uint32() // uid
+	r.uint32() // gid
+	assert.Equal(t, uint64(11), r.uint64())
+
+	// read it back
+	r = c.nfs(6, nfs3OK, func(w *xdrWriter) {
+		w.opaque(fh)
+		w.uint64(0)
+		w.uint32(100)
+	})
+	skipPostOpAttr(r)
+	assert.Equal(t, uint32(11), r.uint32())
+	assert.True(t, r.bool())
+	assert.Equal(t, "hello world", r.string())
+
+	// make a directory
+	r = c.nfs(9, nfs3OK, func(w *xdrWriter) {
+		w.opaque(root)
+		w.string("dir")
+		for i := 0; i < 6; i++ {
+			w.bool(false)
+		}
+	})
+	require.True(t, r.bool())
+
+	// rename the file
+	c.nfs(14, nfs3OK, func(w *xdrWriter) {
+		w.opaque(root)
+		w.string("hello.txt")
+		w.opaque(root)
+		w.string("moved.txt")
+	})
+
+	// the old handle follows the rename
+	r = c.nfs(1, nfs3OK, func(w *xdrWriter) {
+		w.opaque(fh)
+	})
+	assert.Equal(t, uint32(nf3Reg), r.uint32())
+
+	// list the root
+	r = c.nfs(16, nfs3OK, func(w *xdrWriter) {
+		w.opaque(root)
+		w.uint64(0)
+		w.fixed(make([]byte, 8))
+		w.uint32(4096)
+	})
+	skipPostOpAttr(r)
+	r.fixed(8)
+	var names []string
+	for r.bool() {
+		r.uint64()
+		names = append(names, r.string())
+		r.uint64()
+	}
+	assert.True(t, r.bool())
+	require.NoError(t, r.err)
+	assert.Equal(t, []string{"dir", "moved.txt"}, names)
+
+	// remove the directory and file
+	c.nfs(13, nfs3OK, func(w *xdrWriter) {
+		w.opaque(root)
+		w.string("dir")
+	})
+	c.nfs(12, nfs3OK, func(w *xdrWriter) {
+		w.opaque(root)
+		w.string("moved.txt")
+	})
+	c.nfs(1, nfs3ErrStale, func(w *xdrWriter) {
+		w.opaque(fh)
+	})
+
+	// unknown program
+	c.xid++
+	w := new(xdrWriter)
+	w.uint32(c.xid)
+	w.uint32(msgCall)
+	w.uint32(rpcVersion)
+	w.uint32(99)
+	w.uint32(1)
+	w.uint32(0)
+	w.uint32(authNull)
+	w.opaque(nil)
+	w.uint32(authNull)
+	w.opaque(nil)
+	require.NoError(t, writeRecord(conn, w.Bytes()))
+	msg, err := readRecord(c.in)
+	require.NoError(t, err)
+	r = newXDRReader(msg)
+	r.fixed(20)
+	assert.Equal(t, uint32(acceptProgUnavail), r.uint32())
+}
@@ -0,0 +1,258 @@
+package nfs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// errGarbage is returned when the arguments can't be decoded
+var errGarbage = errors.New("couldn't decode XDR arguments")
+
+// maxRecordSize is the largest RPC record we will accept
+const maxRecordSize = 4 * 1024 * 1024
+
+// xdrReader decodes XDR data from a buffer
+//
+// After an error all further reads return zero values and the error
+// is available in err.
+type xdrReader struct {
+	buf []byte
+	err error
+}
+
+// newXDRReader makes a reader for buf
+func newXDRReader(buf []byte) *xdrReader {
+	return &xdrReader{buf: buf}
+}
+
+// next returns the next n bytes or nil on error
+func (r *xdrReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.buf) {
+		r.err = errGarbage
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *xdrReader) uint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *xdrReader) uint64() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (r *xdrReader) bool() bool {
+	return r.uint32() != 0
+}
+
+// fixed reads a fixed length opaque of n bytes
+func (r *xdrReader) fixed(n int) []byte {
+	b := r.next(n)
+	r.next(pad(n))
+	return b
+}
+
+// opaque reads a variable length opaque
+func (r *xdrReader) opaque() []byte {
+	n := r.uint32()
+	if n > maxRecordSize {
+		r.err = errGarbage
+		return nil
+	}
+	return r.fixed(int(n))
+}
+
+func (r *xdrReader) string() string {
+	return string(r.opaque())
+}
+
+// pad returns the number of padding bytes needed after n bytes
+func pad(n int) int {
+	return (4 - n%4) % 4
+}
+
+// xdrWriter encodes XDR data into a buffer
+type xdrWriter struct {
+	bytes.Buffer
+}
+
+func (w *xdrWriter) uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	_, _ = w.Write(b[:])
+}
+
+func (w *xdrWriter) uint64(v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	_, _ = w.Write(b[:])
+}
+
+func (w *xdrWriter) bool(v bool) {
+	if v {
+		w.uint32(1)
+	} else {
+		w.uint32(0)
+	}
+}
+
+// fixed writes a fixed length opaque
+func (w *xdrWriter) fixed(b []byte) {
+	_, _ = w.Write(b)
+	var zero [3]byte
+	_, _ = w.Write(zero[:pad(len(b))])
+}
+
+// opaque writes a variable length opaque
+func (w *xdrWriter) opaque(b []byte) {
+	w.uint32(uint32(len(b)))
+	w.fixed(b)
+}
+
+func (w *xdrWriter) string(s string) {
+	w.opaque([]byte(s))
+}
+
+// RPC message constants from RFC 5531
+const (
+	rpcVersion = 2
+
+	msgCall  = 0
+	msgReply = 1
+
+	replyAccepted = 0
+	replyDenied   = 1
+
+	acceptSuccess      = 0
+	acceptProgUnavail  = 1
+	acceptProgMismatch = 2
+	acceptProcUnavail  = 3
+	acceptGarbageArgs  = 4
+	acceptSystemErr    = 5
+
+	rejectRPCMismatch = 0
+
+	authNull = 0
+	authUnix = 1
+
+	lastFragment = 1 << 31
+)
+
+// rpcCall is a decoded RPC call header
+type rpcCall struct {
+	xid     uint32
+	prog    uint32
+	vers    uint32
+	proc    uint32
+	args    *xdrReader // the procedure arguments
+	rpcVers uint32
+}
+
+// parseCall decodes the RPC call in msg
+func parseCall(msg []byte) (*rpcCall, error) {
+	r := newXDRReader(msg)
+	call := &rpcCall{}
+	call.xid = r.uint32()
+	if msgType := r.uint32(); r.err == nil && msgType != msgCall {
+		return nil, errors.Errorf("expecting RPC call but got message type %d", msgType)
+	}
+	call.rpcVers = r.uint32()
+	call.prog = r.uint32()
+	call.vers = r.uint32()
+	call.proc = r.uint32()
+	// credentials and verifier - accepted without checking
+	r.uint32()
+	r.opaque()
+	r.uint32()
+	r.opaque()
+	if r.err != nil {
+		return nil, r.err
+	}
+	call.args = r
+	return call, nil
+}
+
+// acceptedReply starts a reply to call with the accept status given
+func acceptedReply(xid uint32, stat uint32) *xdrWriter {
+	w := new(xdrWriter)
+	w.uint32(xid)
+	w.uint32(msgReply)
+	w.uint32(replyAccepted)
+	w.uint32(authNull) // verifier
+	w.opaque(nil)
+	w.uint32(stat)
+	return w
+}
+
+// rpcMismatchReply makes a reply rejecting the RPC version
+func rpcMismatchReply(xid uint32) *xdrWriter {
+	w := new(xdrWriter)
+	w.uint32(xid)
+	w.uint32(msgReply)
+	w.uint32(replyDenied)
+	w.uint32(rejectRPCMismatch)
+	w.uint32(rpcVersion)
+	w.uint32(rpcVersion)
+	return w
+}
+
+// progMismatchReply makes a reply rejecting the program version
+func progMismatchReply(xid uint32, low, high uint32) *xdrWriter {
+	w := acceptedReply(xid, acceptProgMismatch)
+	w.uint32(low)
+	w.uint32(high)
+	return w
+}
+
+// readRecord reads a record marked RPC message
+func readRecord(in *bufio.Reader) ([]byte, error) {
+	var msg []byte
+	for {
+		var header [4]byte
+		_, err := io.ReadFull(in, header[:])
+		if err != nil {
+			return nil, err
+		}
+		fragment := binary.BigEndian.Uint32(header[:])
+		size := int(fragment &^ lastFragment)
+		if len(msg)+size > maxRecordSize {
+			return nil, errors.Errorf("RPC record too large (%d bytes)", len(msg)+size)
+		}
+		start := len(msg)
+		msg = append(msg, make([]byte, size)...)
+		_, err = io.ReadFull(in, msg[start:])
+		if err != nil {
+			return nil, err
+		}
+		if fragment&lastFragment != 0 {
+			return msg, nil
+		}
+	}
+}
+
+// writeRecord writes msg as a single record marked fragment
+func writeRecord(out io.Writer, msg []byte) error {
+	buf := make([]byte, 4+len(msg))
+	binary.BigEndian.PutUint32(buf, uint32(len(msg))|lastFragment)
+	copy(buf[4:], msg)
+	_, err := out.Write(buf)
+	return err
+}
@@ -7,6 +7,8 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/json"
+	"io/ioutil"
+	"net/http"
 	"os/exec"
 	"strings"
 	"time"
@@ -16,6 +18,7 @@ import (
 	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fshttp"
 	libcache "github.com/rclone/rclone/lib/cache"
 	"github.com/rclone/rclone/vfs"
 	"github.com/rclone/rclone/vfs/vfsflags"
@@ -30,6 +33,11 @@ rclone will use that program to generate backends on the fly which
 then are used to authenticate incoming requests.  This uses a simple
 JSON based protocol with input on STDIN and output on STDOUT.
 
+If the parameter is a URL, eg |--auth-proxy https://auth.example.com/rclone|,
+then rclone will POST the input to it as JSON instead and read the
+output from the body of the response.  A response which isn't a 2xx
+status fails the login.
+
 **PLEASE NOTE:** |--auth-proxy| and |--authorized-keys| cannot be used
 together, if |--auth-proxy| is set the authorized keys option will be
 ignored.
@@ -139,27 +147,71 @@ func New(ctx context.Context, opt *Options) *Proxy {
 	}
 }
 
-// run the proxy command returning a config map
-func (p *Proxy) run(in map[string]string) (config configmap.Simple, err error) {
+// isURL returns true if the auth proxy is an HTTP endpoint
+func (p *Proxy) isURL() bool {
+	return strings.HasPrefix(p.Opt.AuthProxy, "http://") || strings.HasPrefix(p.Opt.AuthProxy, "https://")
+}
+
+// runCommand runs the proxy command with inBytes on STDIN returning
+// its STDOUT
+func (p *Proxy) runCommand(inBytes []byte) ([]byte, error) {
 	cmd := exec.Command(p.cmdLine[0], p.cmdLine[1:]...)
-	inBytes, err := json.MarshalIndent(in, "", "\t")
-	if err != nil {
-		return nil, errors.Wrap(err, "Proxy.Call failed to marshal input: %v")
-	}
 	var stdout, stderr bytes.Buffer
 	cmd.Stdin = bytes.NewBuffer(inBytes)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
-	start := time.Now()
-	err = cmd.Run()
 	fs.Debugf(nil, "Calling proxy %v", p.cmdLine)
-	duration := time.Since(start)
+	err := cmd.Run()
 	if err != nil {
 		return nil, errors.Wrapf(err, "proxy: failed on %v: %q", p.cmdLine, strings.TrimSpace(string(stderr.Bytes())))
 	}
-	err = json.Unmarshal(stdout.Bytes(), &config)
+	return stdout.Bytes(), nil
+}
+
+// runURL POSTs inBytes to the proxy endpoint returning the body of
+// the response
+func (p *Proxy) runURL(inBytes []byte) (out []byte, err error) {
+	fs.Debugf(nil, "Calling proxy %q", p.Opt.AuthProxy)
+	req, err := http.NewRequestWithContext(p.ctx, "POST", p.Opt.AuthProxy, bytes.NewBuffer(inBytes))
+	if err != nil {
+		return nil, errors.Wrap(err, "proxy")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := fshttp.NewClient(p.ctx).Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "proxy: failed on %q", p.Opt.AuthProxy)
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	out, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "proxy: failed to read response from %q", p.Opt.AuthProxy)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, errors.Errorf("proxy: failed on %q: %s: %q", p.Opt.AuthProxy, resp.Status, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// run the proxy command or endpoint returning a config map
+func (p *Proxy) run(in map[string]string) (config configmap.Simple, err error) {
+	inBytes, err := json.MarshalIndent(in, "", "\t")
+	if err != nil {
+		return nil, errors.Wrap(err, "Proxy.Call failed to marshal input: %v")
+	}
+	start := time.Now()
+	var out []byte
+	if p.isURL() {
+		out, err = p.runURL(inBytes)
+	} else {
+		out, err = p.runCommand(inBytes)
+	}
+	duration := time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(out, &config)
 	if err != nil {
-		return nil, errors.Wrapf(err, "proxy: failed to read output: %q", string(stdout.Bytes()))
+		return nil, errors.Wrapf(err, "proxy: failed to read output: %q", string(out))
 	}
 	fs.Debugf(nil, "Proxy returned in %v", duration)
 
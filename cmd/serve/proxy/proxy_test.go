@@ -6,7 +6,10 @@ import (
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -233,3 +236,47 @@ func TestRun(t *testing.T) {
 		assert.Equal(t, 1, p.vfsCache.Entries())
 	})
 }
+
+func TestRunURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var in map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		if in["pass"] != "pass" {
+			http.Error(w, "bad password for "+in["user"], http.StatusForbidden)
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]string{
+			"type":  "local",
+			"_root": "/" + in["user"],
+		}))
+	}))
+	defer ts.Close()
+	opt := DefaultOpt
+	opt.AuthProxy = ts.URL
+	p := New(context.Background(), &opt)
+
+	t.Run("Normal", func(t *testing.T) {
+		config, err := p.run(map[string]string{
+			"user": "me",
+			"pass": "pass",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, configmap.Simple{
+			"type":  "local",
+			"_root": "/me",
+		}, config)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		config, err := p.run(map[string]string{
+			"user": "me",
+			"pass": "wrong",
+		})
+		assert.Nil(t, config)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "403 Forbidden")
+		assert.Contains(t, err.Error(), "bad password for me")
+	})
+}
@@ -0,0 +1,144 @@
+package restic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/walk"
+)
+
+// errQuotaExceeded is returned when an upload would take a repository
+// over its quota
+var errQuotaExceeded = errors.New("repository quota exceeded")
+
+// resticTypes are the directories and files at the top of a repository
+var resticTypes = map[string]bool{
+	"config":    true,
+	"data":      true,
+	"index":     true,
+	"keys":      true,
+	"locks":     true,
+	"snapshots": true,
+}
+
+// repoRemote returns the remote of the repository the object at the
+// URL path is in, for example "user/repo" for
+// "/user/repo/data/2159dd48".  ok is false if path isn't an object in
+// a repository.
+func repoRemote(path string) (repo string, ok bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	n := len(parts)
+	switch {
+	case parts[n-1] == "config":
+		parts = parts[:n-1]
+	case n >= 2 && resticTypes[parts[n-2]] && parts[n-2] != "config":
+		parts = parts[:n-2]
+	default:
+		return "", false
+	}
+	return strings.Join(parts, "/"), true
+}
+
+// quota keeps track of how much each repository is using
+type quota struct {
+	f       fs.Fs
+	maxSize int64
+
+	mu   sync.Mutex
+	used map[string]int64 // bytes used by each repository which has been measured
+}
+
+// newQuota makes a quota allowing each repository maxSize bytes
+func newQuota(f fs.Fs, maxSize int64) *quota {
+	return &quota{
+		f:       f,
+		maxSize: maxSize,
+		used:    map[string]int64{},
+	}
+}
+
+// measure returns the bytes used by repo, listing it the first time.
+//
+// Call with the lock held.
+func (q *quota) measure(ctx context.Context, repo string) (used int64, err error) {
+	used, ok := q.used[repo]
+	if ok {
+		return used, nil
+	}
+	err = walk.ListR(ctx, q.f, repo, true, -1, walk.ListObjects, func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			if o, ok := entry.(fs.Object); ok && o.Size() > 0 {
+				used += o.Size()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_, err = fserrors.Cause(err)
+		if err != fs.ErrorDirNotFound {
+			return 0, err
+		}
+	}
+	fs.Debugf(repo, "Repository is using %v of quota %v", fs.SizeSuffix(used), fs.SizeSuffix(q.maxSize))
+	q.used[repo] = used
+	return used, nil
+}
+
+// reserve reserves size bytes in repo returning errQuotaExceeded if
+// there isn't room once freed bytes, the size of a file being
+// overwritten, have been given back
+func (q *quota) reserve(ctx context.Context, repo string, size, freed int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	used, err := q.measure(ctx, repo)
+	if err != nil {
+		return err
+	}
+	used -= freed
+	if size > 0 && used+size > q.maxSize {
+		return errQuotaExceeded
+	}
+	q.used[repo] = used + size
+	return nil
+}
+
+// release gives back size bytes reserved in repo or freed by a delete
+func (q *quota) release(repo string, size int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if used, ok := q.used[repo]; ok {
+		used -= size
+		if used < 0 {
+			used = 0
+		}
+		q.used[repo] = used
+	}
+}
+
+// quotaReader reserves space in the repository as it is read so
+// uploads of unknown size can be stopped when the quota is reached
+type quotaReader struct {
+	in       io.Reader
+	ctx      context.Context
+	q        *quota
+	repo     string
+	reserved int64 // bytes reserved so far
+	freed    int64 // bytes given back for the file being overwritten
+}
+
+// Read reads from the upload reserving the bytes read
+func (qr *quotaReader) Read(p []byte) (n int, err error) {
+	n, err = qr.in.Read(p)
+	if n > 0 {
+		if reserveErr := qr.q.reserve(qr.ctx, qr.repo, int64(n), 0); reserveErr != nil {
+			return 0, reserveErr
+		}
+		qr.reserved += int64(n)
+	}
+	return n, err
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path"
@@ -32,6 +34,7 @@ var (
 	appendOnly   bool
 	privateRepos bool
 	cacheObjects bool
+	maxRepoSize  = fs.SizeSuffix(-1)
 )
 
 func init() {
@@ -41,6 +44,7 @@ func init() {
 	flags.BoolVarP(flagSet, &appendOnly, "append-only", "", false, "disallow deletion of repository data")
 	flags.BoolVarP(flagSet, &privateRepos, "private-repos", "", false, "users can only access their private repo")
 	flags.BoolVarP(flagSet, &cacheObjects, "cache-objects", "", true, "cache listed objects")
+	flags.FVarP(flagSet, &maxRepoSize, "max-repo-size", "", "maximum size of each repository, e.g. 100G (default off)")
 }
 
 // Command definition for cobra
@@ -128,6 +132,23 @@ these **must** end with /.  Eg
 
 The "--private-repos" flag can be used to limit users to repositories starting
 with a path of ` + "`/<username>/`" + `.
+
+#### Hardening ####
+
+The "--append-only" flag stops clients deleting or overwriting
+anything in a repository apart from lock files, so a compromised
+client can't destroy existing backups.  Use "restic forget" and
+"restic prune" from a trusted machine with direct access to the remote
+to remove old snapshots.
+
+The "--max-repo-size" flag limits the total size of each repository,
+e.g. "--max-repo-size 100G".  Uploads which would take a repository
+over the limit fail with "413 Request Entity Too Large".  Each
+repository is listed the first time it is written to, to find out how
+big it is, and is then tracked as files are uploaded and deleted
+through the server, so changes made to the remote by other means
+aren't noticed until the server is restarted.  With "--private-repos"
+this gives each user a quota.
 ` + httplib.Help,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
@@ -170,6 +191,7 @@ type Server struct {
 	*httplib.Server
 	f     fs.Fs
 	cache *cache
+	quota *quota // nil if unlimited
 }
 
 // NewServer returns an HTTP server that speaks the rest protocol
@@ -180,6 +202,9 @@ func NewServer(f fs.Fs, opt *httplib.Options) *Server {
 		f:      f,
 		cache:  newCache(),
 	}
+	if maxRepoSize >= 0 {
+		s.quota = newQuota(f, int64(maxRepoSize))
+	}
 	mux.HandleFunc(s.Opt.BaseURL+"/", s.ServeHTTP)
 	return s
 }
@@ -248,9 +273,9 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		case "GET", "HEAD":
 			s.serveObject(w, r, remote)
 		case "POST":
-			s.postObject(w, r, remote)
+			s.postObject(w, r, path, remote)
 		case "DELETE":
-			s.deleteObject(w, r, remote)
+			s.deleteObject(w, r, path, remote)
 		default:
 			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 		}
@@ -284,7 +309,7 @@ func (s *Server) serveObject(w http.ResponseWriter, r *http.Request, remote stri
 }
 
 // postObject posts an object to the repository
-func (s *Server) postObject(w http.ResponseWriter, r *http.Request, remote string) {
+func (s *Server) postObject(w http.ResponseWriter, r *http.Request, path, remote string) {
 	if appendOnly {
 		// make sure the file does not exist yet
 		_, err := s.newObject(r.Context(), remote)
@@ -296,8 +321,37 @@ func (s *Server) postObject(w http.ResponseWriter, r *http.Request, remote strin
 		}
 	}
 
-	o, err := operations.RcatSize(r.Context(), s.f, remote, r.Body, r.ContentLength, time.Now())
+	// reserve the space for the upload in the repository quota,
+	// giving back the space of any file it overwrites first
+	var in io.Reader = r.Body
+	var qr *quotaReader
+	repo, inRepo := repoRemote(path)
+	if s.quota != nil && inRepo {
+		qr = &quotaReader{in: r.Body, ctx: r.Context(), q: s.quota, repo: repo}
+		if old, err := s.newObject(r.Context(), remote); err == nil {
+			qr.freed = old.Size()
+		}
+		size := r.ContentLength
+		if size < 0 {
+			size = 0
+			in = qr
+		}
+		if err := s.quota.reserve(r.Context(), repo, size, qr.freed); err != nil {
+			s.quotaError(w, remote, err)
+			return
+		}
+		qr.reserved = size
+	}
+
+	o, err := operations.RcatSize(r.Context(), s.f, remote, ioutil.NopCloser(in), r.ContentLength, time.Now())
 	if err != nil {
+		if qr != nil {
+			s.quota.release(repo, qr.reserved-qr.freed)
+			if errors.Is(err, errQuotaExceeded) {
+				s.quotaError(w, remote, err)
+				return
+			}
+		}
 		err = accounting.Stats(r.Context()).Error(err)
 		fs.Errorf(remote, "Post request rcat error: %v", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -305,12 +359,28 @@ func (s *Server) postObject(w http.ResponseWriter, r *http.Request, remote strin
 		return
 	}
 
+	// correct the quota for the actual size
+	if qr != nil {
+		s.quota.release(repo, qr.reserved-o.Size())
+	}
+
 	// if successfully uploaded add to cache
 	s.cache.add(remote, o)
 }
 
+// quotaError reports an error reserving space in the repository quota
+func (s *Server) quotaError(w http.ResponseWriter, remote string, err error) {
+	if errors.Is(err, errQuotaExceeded) {
+		fs.Errorf(remote, "Post request: refusing upload: %v", err)
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	fs.Errorf(remote, "Post request: failed to measure repository: %v", err)
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
 // delete the remote
-func (s *Server) deleteObject(w http.ResponseWriter, r *http.Request, remote string) {
+func (s *Server) deleteObject(w http.ResponseWriter, r *http.Request, path, remote string) {
 	if appendOnly {
 		parts := strings.Split(r.URL.Path, "/")
 
@@ -340,6 +410,11 @@ func (s *Server) deleteObject(w http.ResponseWriter, r *http.Request, remote str
 
 	// remove object from cache
 	s.cache.remove(remote)
+
+	// give the space back to the repository quota
+	if repo, ok := repoRemote(path); s.quota != nil && ok {
+		s.quota.release(repo, o.Size())
+	}
 }
 
 // listItem is an element returned for the restic v2 list response
@@ -0,0 +1,105 @@
+package restic
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/cmd/serve/httplib/httpflags"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepoRemote(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		repo string
+		ok   bool
+	}{
+		{"/config", "", true},
+		{"/data/2159dd48", "", true},
+		{"/keys/2159dd48", "", true},
+		{"/user/repo/config", "user/repo", true},
+		{"/user/repo/data/2159dd48", "user/repo", true},
+		{"/user/repo/snapshots/2159dd48", "user/repo", true},
+		{"/user/repo/potato/2159dd48", "", false},
+		{"/user/config/2159dd48", "", false},
+		{"/potato", "", false},
+	} {
+		repo, ok := repoRemote(test.path)
+		assert.Equal(t, test.ok, ok, test.path)
+		assert.Equal(t, test.repo, repo, test.path)
+	}
+}
+
+// TestResticQuota checks the repository quotas are enforced
+func TestResticQuota(t *testing.T) {
+	configfile.Install()
+
+	tempdir, err := ioutil.TempDir("", "rclone-restic-test-")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tempdir))
+	}()
+
+	prev := maxRepoSize
+	maxRepoSize = fs.SizeSuffix(100)
+	defer func() {
+		maxRepoSize = prev
+	}()
+
+	f := cmd.NewFsSrc([]string{tempdir})
+	srv := NewServer(f, &httpflags.Opt)
+
+	fill := func(n int) *strings.Reader {
+		return strings.NewReader(strings.Repeat("x", n))
+	}
+	// unknownLength makes a request whose body has an unknown length
+	unknownLength := func(method, path string, n int) *http.Request {
+		req := newRequest(t, method, path, ioutil.NopCloser(fill(n)))
+		req.ContentLength = -1
+		return req
+	}
+
+	for i, test := range []TestRequest{
+		{newRequest(t, "POST", "/one/?create=true", nil), []wantFunc{wantCode(http.StatusOK)}},
+		{newRequest(t, "POST", "/two/?create=true", nil), []wantFunc{wantCode(http.StatusOK)}},
+		{newRequest(t, "POST", "/one/config", fill(10)), []wantFunc{wantCode(http.StatusOK)}},
+		{newRequest(t, "POST", "/one/data/aa01", fill(80)), []wantFunc{wantCode(http.StatusOK)}},
+		// too big for the space left
+		{newRequest(t, "POST", "/one/data/aa02", fill(20)), []wantFunc{wantCode(http.StatusRequestEntityTooLarge)}},
+		{unknownLength("POST", "/one/data/aa02", 20), []wantFunc{wantCode(http.StatusRequestEntityTooLarge)}},
+		{newRequest(t, "GET", "/one/data/aa02", nil), []wantFunc{wantCode(http.StatusNotFound)}},
+		// but there is room for this
+		{unknownLength("POST", "/one/data/aa03", 10), []wantFunc{wantCode(http.StatusOK)}},
+		// the other repository has its own quota
+		{newRequest(t, "POST", "/two/data/aa01", fill(90)), []wantFunc{wantCode(http.StatusOK)}},
+		// deleting frees up space
+		{newRequest(t, "DELETE", "/one/data/aa01", nil), []wantFunc{wantCode(http.StatusOK)}},
+		{newRequest(t, "POST", "/one/data/aa02", fill(20)), []wantFunc{wantCode(http.StatusOK)}},
+		// overwriting only counts the difference
+		{newRequest(t, "POST", "/one/data/aa02", fill(30)), []wantFunc{wantCode(http.StatusOK)}},
+	} {
+		t.Logf("request %v: %v %v", i, test.req.Method, test.req.URL.Path)
+		checkRequest(t, srv.ServeHTTP, test.req, test.want)
+	}
+	assert.Equal(t, int64(50), srv.quota.used["one"])
+	assert.Equal(t, int64(90), srv.quota.used["two"])
+
+	// a new server measures the existing repositories
+	srv = NewServer(f, &httpflags.Opt)
+	checkRequest(t, srv.ServeHTTP, newRequest(t, "POST", "/two/data/aa02", fill(20)), []wantFunc{wantCode(http.StatusRequestEntityTooLarge)})
+	checkRequest(t, srv.ServeHTTP, newRequest(t, "POST", "/two/data/aa02", fill(10)), []wantFunc{wantCode(http.StatusOK)})
+
+	// a full repository can still overwrite a file with one no bigger
+	checkRequest(t, srv.ServeHTTP, newRequest(t, "POST", "/two/data/aa01", fill(90)), []wantFunc{wantCode(http.StatusOK)})
+	checkRequest(t, srv.ServeHTTP, unknownLength("POST", "/two/data/aa01", 85), []wantFunc{wantCode(http.StatusOK)})
+	checkRequest(t, srv.ServeHTTP, newRequest(t, "POST", "/two/data/aa01", fill(95)), []wantFunc{wantCode(http.StatusRequestEntityTooLarge)})
+	checkRequest(t, srv.ServeHTTP, unknownLength("POST", "/two/data/aa01", 95), []wantFunc{wantCode(http.StatusRequestEntityTooLarge)})
+	assert.Equal(t, int64(95), srv.quota.used["two"])
+}
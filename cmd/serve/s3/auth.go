@@ -0,0 +1,297 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	signV4Algorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat   = "20060102T150405Z"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	maxClockSkew    = 15 * time.Minute
+)
+
+// signature describes the parts of a v4 signature sent by the client
+type signature struct {
+	accessKey     string
+	scope         string // date/region/service/aws4_request
+	date          string // yyyymmdd
+	region        string
+	service       string
+	signedHeaders []string
+	signature     string
+	amzDate       string
+	payloadHash   string
+	expires       time.Duration // for presigned URLs only
+}
+
+// parseAuthHeader parses a v4 signature from the Authorization header
+func parseAuthHeader(r *http.Request) (sig signature, err error) {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, signV4Algorithm+" ") {
+		return sig, errors.New("only AWS signature version 4 is supported")
+	}
+	for _, field := range strings.Split(strings.TrimPrefix(authHeader, signV4Algorithm+" "), ",") {
+		field = strings.TrimSpace(field)
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return sig, errors.Errorf("bad field %q in Authorization header", field)
+		}
+		switch kv[0] {
+		case "Credential":
+			err = sig.parseCredential(kv[1])
+			if err != nil {
+				return sig, err
+			}
+		case "SignedHeaders":
+			sig.signedHeaders = strings.Split(kv[1], ";")
+		case "Signature":
+			sig.signature = kv[1]
+		}
+	}
+	sig.amzDate = r.Header.Get("X-Amz-Date")
+	if sig.amzDate == "" {
+		sig.amzDate = r.Header.Get("Date")
+	}
+	sig.payloadHash = r.Header.Get("X-Amz-Content-Sha256")
+	if sig.payloadHash == "" {
+		sig.payloadHash = unsignedPayload
+	}
+	return sig, nil
+}
+
+// parseQuery parses a v4 signature from a presigned URL
+func parseQuery(r *http.Request) (sig signature, err error) {
+	query := r.URL.Query()
+	if query.Get("X-Amz-Algorithm") != signV4Algorithm {
+		return sig, errors.New("only AWS signature version 4 is supported")
+	}
+	err = sig.parseCredential(query.Get("X-Amz-Credential"))
+	if err != nil {
+		return sig, err
+	}
+	sig.signedHeaders = strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+	sig.signature = query.Get("X-Amz-Signature")
+	sig.amzDate = query.Get("X-Amz-Date")
+	sig.payloadHash = unsignedPayload
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil {
+		return sig, errors.Wrap(err, "bad X-Amz-Expires")
+	}
+	sig.expires = time.Duration(expires) * time.Second
+	return sig, nil
+}
+
+// parseCredential parses accessKey/date/region/service/aws4_request
+func (sig *signature) parseCredential(credential string) error {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[4] != "aws4_request" {
+		return errors.Errorf("bad credential %q", credential)
+	}
+	sig.accessKey = parts[0]
+	sig.date, sig.region, sig.service = parts[1], parts[2], parts[3]
+	sig.scope = strings.Join(parts[1:], "/")
+	return nil
+}
+
+// checkAuth checks the request is signed with one of the configured
+// keys.  If no keys are configured then all requests are allowed.
+func (s *server) checkAuth(r *http.Request) error {
+	if len(s.keys) == 0 {
+		return nil
+	}
+	var (
+		sig signature
+		err error
+	)
+	if r.URL.Query().Get("X-Amz-Signature") != "" {
+		sig, err = parseQuery(r)
+	} else {
+		sig, err = parseAuthHeader(r)
+	}
+	if err != nil {
+		return err
+	}
+	if s.opt.Region != "" && sig.region != s.opt.Region {
+		return errors.Errorf("request signed for region %q but serving %q", sig.region, s.opt.Region)
+	}
+	secretKey, ok := s.keys[sig.accessKey]
+	if !ok {
+		return errors.Errorf("unknown access key %q", sig.accessKey)
+	}
+	t, err := time.Parse(amzDateFormat, sig.amzDate)
+	if err != nil {
+		return errors.Wrap(err, "bad request date")
+	}
+	now := time.Now()
+	if sig.expires > 0 {
+		if now.After(t.Add(sig.expires)) {
+			return errors.New("presigned URL has expired")
+		}
+	} else if now.Sub(t) > maxClockSkew || t.Sub(now) > maxClockSkew {
+		return errors.New("request date too far from server time")
+	}
+	want := sig.calculate(r, secretKey)
+	if !hmac.Equal([]byte(want), []byte(sig.signature)) {
+		return errSignatureMismatch
+	}
+	return nil
+}
+
+// checkPayload arranges for the request body to be checked against
+// the X-Amz-Content-Sha256 header as it is read.  Reading to the end of
+// a body which doesn't match returns errContentSHA256Mismatch.
+// Unsigned and aws-chunked streaming payloads aren't checked.
+func checkPayload(r *http.Request) *s3Error {
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" || payloadHash == unsignedPayload || strings.HasPrefix(payloadHash, "STREAMING-") {
+		return nil
+	}
+	want, err := hex.DecodeString(payloadHash)
+	if err != nil || len(want) != sha256.Size {
+		return errInvalidArgument
+	}
+	r.Body = &payloadReader{
+		in:   r.Body,
+		hash: sha256.New(),
+		want: want,
+	}
+	return nil
+}
+
+// payloadReader checks the SHA-256 of the data read through it
+type payloadReader struct {
+	in   io.ReadCloser
+	hash hash.Hash
+	want []byte
+}
+
+// Read satisfies the io.Reader interface
+func (pr *payloadReader) Read(b []byte) (n int, err error) {
+	n, err = pr.in.Read(b)
+	_, _ = pr.hash.Write(b[:n])
+	if err == io.EOF && !bytes.Equal(pr.hash.Sum(nil), pr.want) {
+		err = errContentSHA256Mismatch
+	}
+	return n, err
+}
+
+// Close satisfies the io.Closer interface
+func (pr *payloadReader) Close() error {
+	return pr.in.Close()
+}
+
+// calculate computes the signature the request should have
+func (sig *signature) calculate(r *http.Request, secretKey string) string {
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		awsEscape(r.URL.Path, false),
+		canonicalQuery(r),
+		canonicalHeaders(r, sig.signedHeaders),
+		strings.Join(sig.signedHeaders, ";"),
+		sig.payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		signV4Algorithm,
+		sig.amzDate,
+		sig.scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+secretKey), sig.date)
+	key = hmacSHA256(key, sig.region)
+	key = hmacSHA256(key, sig.service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalQuery returns the sorted, encoded query string without
+// the signature
+func canonicalQuery(r *http.Request) string {
+	query := r.URL.Query()
+	query.Del("X-Amz-Signature")
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var out []string
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			out = append(out, awsEscape(k, true)+"="+awsEscape(v, true))
+		}
+	}
+	return strings.Join(out, "&")
+}
+
+// canonicalHeaders returns the signed headers in canonical form
+func canonicalHeaders(r *http.Request, signedHeaders []string) string {
+	var b strings.Builder
+	for _, name := range signedHeaders {
+		var value string
+		switch name {
+		case "host":
+			value = r.Host
+		case "content-length":
+			value = r.Header.Get("Content-Length")
+			if value == "" && r.ContentLength >= 0 {
+				value = strconv.FormatInt(r.ContentLength, 10)
+			}
+		default:
+			values := r.Header.Values(name)
+			for i := range values {
+				values[i] = strings.Join(strings.Fields(values[i]), " ")
+			}
+			value = strings.Join(values, ",")
+		}
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(value)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// awsEscape escapes s as required by the v4 signing process.
+//
+// If encodeSlash is false then "/" is left alone.
+func awsEscape(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&15])
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	h := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,514 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/walk"
+	"github.com/rclone/rclone/lib/http/serve"
+)
+
+// emptyMD5 is the MD5 of no data
+const emptyMD5 = "d41d8cd98f00b204e9800998ecf8427e"
+
+// defaultMaxKeys is the maximum number of keys returned in a listing
+const defaultMaxKeys = 1000
+
+// validName returns true if a bucket name or key can be mapped
+// safely onto a remote path
+func validName(name string) bool {
+	for _, segment := range strings.Split(strings.TrimSuffix(name, "/"), "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// objectRemote returns the remote path for bucket and key
+func objectRemote(bucket, key string) string {
+	return bucket + "/" + key
+}
+
+// bucketExists returns an error if the bucket doesn't exist
+func (s *server) bucketExists(r *http.Request, bucket string) *s3Error {
+	if !validName(bucket) {
+		return errInvalidBucketName
+	}
+	_, err := s.f.List(r.Context(), bucket)
+	if err == fs.ErrorDirNotFound {
+		return errNoSuchBucket
+	} else if err != nil {
+		fs.Errorf(bucket, "s3: failed to read bucket: %v", err)
+		return errInternalError
+	}
+	return nil
+}
+
+// etag returns the quoted ETag for o or "" if not available
+func etag(r *http.Request, o fs.Object) string {
+	md5sum, err := o.Hash(r.Context(), hash.MD5)
+	if err != nil || md5sum == "" {
+		return ""
+	}
+	return `"` + md5sum + `"`
+}
+
+// modTimeFromHeader reads the modification time from the
+// X-Amz-Meta-Mtime header as used by rclone's s3 backend, falling
+// back to the current time.
+func modTimeFromHeader(r *http.Request) time.Time {
+	if mtime := r.Header.Get("X-Amz-Meta-Mtime"); mtime != "" {
+		seconds, err := strconv.ParseFloat(mtime, 64)
+		if err == nil {
+			return time.Unix(0, int64(seconds*1e9))
+		}
+	}
+	return time.Now()
+}
+
+// listBuckets lists the top level directories as buckets
+func (s *server) listBuckets(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.f.List(r.Context(), "")
+	if err != nil {
+		fs.Errorf(s.f, "s3: failed to list buckets: %v", err)
+		writeError(w, r, errInternalError)
+		return
+	}
+	result := listBucketsResult{
+		Xmlns:   xmlns,
+		Owner:   defaultOwner,
+		Buckets: []bucketInfo{},
+	}
+	for _, entry := range entries {
+		if dir, ok := entry.(fs.Directory); ok {
+			result.Buckets = append(result.Buckets, bucketInfo{
+				Name:         dir.Remote(),
+				CreationDate: formatTime(dir.ModTime(r.Context())),
+			})
+		}
+	}
+	writeXML(w, result)
+}
+
+// headBucket checks a bucket exists
+func (s *server) headBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	if err := s.bucketExists(r, bucket); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// createBucket makes a new bucket
+func (s *server) createBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	err := s.bucketExists(r, bucket)
+	if err == nil {
+		writeError(w, r, errBucketAlreadyOwned)
+		return
+	} else if err != errNoSuchBucket {
+		writeError(w, r, err)
+		return
+	}
+	if mkdirErr := s.f.Mkdir(r.Context(), bucket); mkdirErr != nil {
+		fs.Errorf(bucket, "s3: failed to create bucket: %v", mkdirErr)
+		writeError(w, r, errInternalError)
+		return
+	}
+	w.Header().Set("Location", "/"+bucket)
+	w.WriteHeader(http.StatusOK)
+}
+
+// deleteBucket removes an empty bucket
+func (s *server) deleteBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	if !validName(bucket) {
+		writeError(w, r, errInvalidBucketName)
+		return
+	}
+	entries, err := s.f.List(r.Context(), bucket)
+	if err == fs.ErrorDirNotFound {
+		writeError(w, r, errNoSuchBucket)
+		return
+	} else if err != nil {
+		fs.Errorf(bucket, "s3: failed to read bucket: %v", err)
+		writeError(w, r, errInternalError)
+		return
+	}
+	if len(entries) != 0 {
+		writeError(w, r, errBucketNotEmpty)
+		return
+	}
+	if err := s.f.Rmdir(r.Context(), bucket); err != nil {
+		fs.Errorf(bucket, "s3: failed to delete bucket: %v", err)
+		writeError(w, r, errInternalError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listEntry is an object or common prefix found while listing
+type listEntry struct {
+	key string
+	o   fs.Object // nil for a common prefix
+}
+
+// listObjects implements ListObjects and ListObjectsV2
+func (s *server) listObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	if err := s.bucketExists(r, bucket); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	query := r.URL.Query()
+	v2 := query.Get("list-type") == "2"
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	maxKeys := defaultMaxKeys
+	if value := query.Get("max-keys"); value != "" {
+		var err error
+		maxKeys, err = strconv.Atoi(value)
+		if err != nil || maxKeys < 0 {
+			writeError(w, r, errInvalidArgument)
+			return
+		}
+		if maxKeys > defaultMaxKeys {
+			maxKeys = defaultMaxKeys
+		}
+	}
+
+	// work out where to start from
+	var marker string
+	if v2 {
+		marker = query.Get("start-after")
+		if token := query.Get("continuation-token"); token != "" {
+			decoded, err := base64.StdEncoding.DecodeString(token)
+			if err != nil {
+				writeError(w, r, errInvalidArgument)
+				return
+			}
+			marker = string(decoded)
+		}
+	} else {
+		marker = query.Get("marker")
+	}
+
+	entries, err := s.listEntries(r, bucket, prefix, delimiter)
+	if err != nil {
+		fs.Errorf(bucket, "s3: failed to list: %v", err)
+		writeError(w, r, errInternalError)
+		return
+	}
+
+	result := listBucketResult{
+		Xmlns:          xmlns,
+		Name:           bucket,
+		Prefix:         prefix,
+		Delimiter:      delimiter,
+		MaxKeys:        maxKeys,
+		Contents:       []objectInfo{},
+		CommonPrefixes: []commonPrefix{},
+	}
+	if v2 {
+		result.ContinuationToken = query.Get("continuation-token")
+		result.StartAfter = query.Get("start-after")
+	} else {
+		result.Marker = &marker
+	}
+	start := sort.Search(len(entries), func(i int) bool {
+		return entries[i].key > marker
+	})
+	entries = entries[start:]
+	if len(entries) > maxKeys {
+		entries = entries[:maxKeys]
+		result.IsTruncated = true
+	}
+	for _, entry := range entries {
+		if entry.o == nil {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: entry.key})
+			continue
+		}
+		result.Contents = append(result.Contents, objectInfo{
+			Key:          entry.key,
+			LastModified: formatTime(entry.o.ModTime(r.Context())),
+			ETag:         etag(r, entry.o),
+			Size:         entry.o.Size(),
+			StorageClass: "STANDARD",
+		})
+	}
+	if result.IsTruncated && len(entries) > 0 {
+		last := entries[len(entries)-1].key
+		if v2 {
+			result.NextContinuationToken = base64.StdEncoding.EncodeToString([]byte(last))
+		} else {
+			result.NextMarker = last
+		}
+	}
+	if v2 {
+		keyCount := len(entries)
+		result.KeyCount = &keyCount
+	}
+	writeXML(w, result)
+}
+
+// listEntries returns the sorted objects and common prefixes in
+// bucket matching prefix.
+//
+// If delimiter is "/" then only a single directory is listed,
+// otherwise the listing is recursive.
+func (s *server) listEntries(r *http.Request, bucket, prefix, delimiter string) (entries []listEntry, err error) {
+	// find the directory the prefix refers to
+	dir := prefix
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir)
+		if dir == "." {
+			dir = ""
+		}
+	}
+	dir = strings.TrimSuffix(dir, "/")
+	if dir != "" && !validName(dir) {
+		return nil, nil
+	}
+	root := bucket
+	if dir != "" {
+		root = objectRemote(bucket, dir)
+	}
+	trim := bucket + "/"
+	add := func(dirEntries fs.DirEntries) {
+		for _, entry := range dirEntries {
+			key := strings.TrimPrefix(entry.Remote(), trim)
+			switch x := entry.(type) {
+			case fs.Object:
+				if strings.HasPrefix(key, prefix) {
+					entries = append(entries, listEntry{key: key, o: x})
+				}
+			case fs.Directory:
+				key += "/"
+				if strings.HasPrefix(key, prefix) {
+					entries = append(entries, listEntry{key: key})
+				}
+			}
+		}
+	}
+	if delimiter == "/" {
+		dirEntries, err := s.f.List(r.Context(), root)
+		if err == fs.ErrorDirNotFound {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		add(dirEntries)
+	} else {
+		err = walk.ListR(r.Context(), s.f, root, true, -1, walk.ListObjects, func(dirEntries fs.DirEntries) error {
+			add(dirEntries)
+			return nil
+		})
+		if err == fs.ErrorDirNotFound {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	return entries, nil
+}
+
+// getObject implements GetObject and HeadObject
+func (s *server) getObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	if !validName(bucket) || !validName(key) || strings.HasSuffix(key, "/") {
+		writeError(w, r, errNoSuchKey)
+		return
+	}
+	o, err := s.f.NewObject(r.Context(), objectRemote(bucket, key))
+	if err != nil {
+		if bucketErr := s.bucketExists(r, bucket); bucketErr != nil {
+			writeError(w, r, bucketErr)
+			return
+		}
+		writeError(w, r, errNoSuchKey)
+		return
+	}
+	modTime := o.ModTime(r.Context())
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modTime.Truncate(time.Second).After(since) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if tag := etag(r, o); tag != "" {
+		if match := r.Header.Get("If-None-Match"); match != "" && strings.Trim(match, `"`) == strings.Trim(tag, `"`) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", tag)
+	}
+	w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	w.Header().Set("X-Amz-Meta-Mtime", strconv.FormatFloat(float64(modTime.UnixNano())/1e9, 'f', -1, 64))
+	serve.Object(w, r, o)
+}
+
+// putObject implements PutObject
+func (s *server) putObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	if !validName(key) {
+		writeError(w, r, errInvalidArgument)
+		return
+	}
+	if err := s.bucketExists(r, bucket); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	remote := objectRemote(bucket, key)
+
+	// Keys ending in / are directory markers
+	if strings.HasSuffix(key, "/") {
+		if err := s.f.Mkdir(r.Context(), strings.TrimSuffix(remote, "/")); err != nil {
+			fs.Errorf(remote, "s3: failed to make directory: %v", err)
+			writeError(w, r, errInternalError)
+			return
+		}
+		w.Header().Set("ETag", `"`+emptyMD5+`"`)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	in, size := requestBody(r)
+	hasher := md5.New()
+	in = io.TeeReader(in, hasher)
+	o, err := operations.RcatSize(r.Context(), s.f, remote, ioutil.NopCloser(in), size, modTimeFromHeader(r))
+	if err != nil {
+		err = accounting.Stats(r.Context()).Error(err)
+		fs.Errorf(remote, "s3: put failed: %v", err)
+		writeError(w, r, bodyError(err))
+		return
+	}
+	sum := hasher.Sum(nil)
+	if contentMD5 := r.Header.Get("Content-Md5"); contentMD5 != "" && contentMD5 != base64.StdEncoding.EncodeToString(sum) {
+		fs.Errorf(remote, "s3: Content-MD5 mismatch - removing upload")
+		if err := o.Remove(r.Context()); err != nil {
+			fs.Errorf(remote, "s3: failed to remove corrupted upload: %v", err)
+		}
+		writeError(w, r, errBadDigest)
+		return
+	}
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum)+`"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+// copyObject implements CopyObject
+func (s *server) copyObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	if !validName(key) {
+		writeError(w, r, errInvalidArgument)
+		return
+	}
+	if err := s.bucketExists(r, bucket); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	source, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		writeError(w, r, errInvalidArgument)
+		return
+	}
+	// strip any version ID
+	if i := strings.IndexRune(source, '?'); i >= 0 {
+		source = source[:i]
+	}
+	srcBucket, srcKey := splitPath(source)
+	if !validName(srcBucket) || !validName(srcKey) {
+		writeError(w, r, errInvalidArgument)
+		return
+	}
+	srcObj, err := s.f.NewObject(r.Context(), objectRemote(srcBucket, srcKey))
+	if err != nil {
+		writeError(w, r, errNoSuchKey)
+		return
+	}
+	dst, err := operations.Copy(r.Context(), s.f, nil, objectRemote(bucket, key), srcObj)
+	if err != nil {
+		fs.Errorf(srcObj, "s3: copy failed: %v", err)
+		writeError(w, r, errInternalError)
+		return
+	}
+	writeXML(w, copyObjectResult{
+		Xmlns:        xmlns,
+		LastModified: formatTime(dst.ModTime(r.Context())),
+		ETag:         etag(r, dst),
+	})
+}
+
+// removeObject removes the object at bucket/key, ignoring it if it
+// doesn't exist as S3 does
+func (s *server) removeObject(r *http.Request, bucket, key string) error {
+	if !validName(key) {
+		return nil
+	}
+	remote := objectRemote(bucket, key)
+	if strings.HasSuffix(key, "/") {
+		err := s.f.Rmdir(r.Context(), strings.TrimSuffix(remote, "/"))
+		if err == fs.ErrorDirNotFound {
+			err = nil
+		}
+		return err
+	}
+	o, err := s.f.NewObject(r.Context(), remote)
+	if err == fs.ErrorObjectNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return operations.DeleteFile(r.Context(), o)
+}
+
+// deleteObject implements DeleteObject
+func (s *server) deleteObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	if err := s.bucketExists(r, bucket); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := s.removeObject(r, bucket, key); err != nil {
+		fs.Errorf(objectRemote(bucket, key), "s3: delete failed: %v", err)
+		writeError(w, r, errInternalError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteObjects implements DeleteObjects
+func (s *server) deleteObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	if err := s.bucketExists(r, bucket); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var req deleteRequest
+	if s3Err := readXML(r, &req); s3Err != nil {
+		writeError(w, r, s3Err)
+		return
+	}
+	result := deleteResult{Xmlns: xmlns}
+	for _, object := range req.Objects {
+		err := s.removeObject(r, bucket, object.Key)
+		if err != nil {
+			fs.Errorf(objectRemote(bucket, object.Key), "s3: delete failed: %v", err)
+			result.Errors = append(result.Errors, deleteError{
+				Key:     object.Key,
+				Code:    errInternalError.Code,
+				Message: err.Error(),
+			})
+		} else if !req.Quiet {
+			result.Deleted = append(result.Deleted, deletedObject{Key: object.Key})
+		}
+	}
+	writeXML(w, result)
+}
@@ -0,0 +1,461 @@
+package s3
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/random"
+)
+
+// maxPartNumber is the largest part number S3 allows
+const maxPartNumber = 10000
+
+// part is an uploaded part of a multipart upload
+type part struct {
+	number  int
+	size    int64
+	etag    string // unquoted hex MD5
+	path    string // local file holding the data
+	modTime time.Time
+}
+
+// upload is a multipart upload in progress
+type upload struct {
+	mu        sync.Mutex
+	id        string
+	bucket    string
+	key       string
+	dir       string // local directory holding the parts
+	initiated time.Time
+	modTime   time.Time
+	parts     map[int]*part
+}
+
+// uploads holds all the multipart uploads in progress
+type uploads struct {
+	mu      sync.Mutex
+	uploads map[string]*upload
+}
+
+// newUploads makes a new empty set of uploads
+func newUploads() *uploads {
+	return &uploads{
+		uploads: map[string]*upload{},
+	}
+}
+
+// new starts a new upload for bucket/key
+func (us *uploads) new(bucket, key string, modTime time.Time) (*upload, error) {
+	dir, err := ioutil.TempDir("", "rclone-serve-s3-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make temporary directory for upload")
+	}
+	u := &upload{
+		id:        random.String(32),
+		bucket:    bucket,
+		key:       key,
+		dir:       dir,
+		initiated: time.Now(),
+		modTime:   modTime,
+		parts:     map[int]*part{},
+	}
+	us.mu.Lock()
+	us.uploads[u.id] = u
+	us.mu.Unlock()
+	return u, nil
+}
+
+// get finds the upload with id for bucket/key
+func (us *uploads) get(bucket, key, id string) *upload {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	u := us.uploads[id]
+	if u == nil || u.bucket != bucket || u.key != key {
+		return nil
+	}
+	return u
+}
+
+// remove removes the upload with id and its local data
+func (us *uploads) remove(id string) {
+	us.mu.Lock()
+	u := us.uploads[id]
+	delete(us.uploads, id)
+	us.mu.Unlock()
+	if u != nil {
+		u.removeData()
+	}
+}
+
+// list returns the uploads in progress for bucket sorted by key
+func (us *uploads) list(bucket string) (out []*upload) {
+	us.mu.Lock()
+	for _, u := range us.uploads {
+		if u.bucket == bucket {
+			out = append(out, u)
+		}
+	}
+	us.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].key < out[j].key
+	})
+	return out
+}
+
+// abortAll removes all uploads in progress
+func (us *uploads) abortAll() {
+	us.mu.Lock()
+	ids := make([]string, 0, len(us.uploads))
+	for id := range us.uploads {
+		ids = append(ids, id)
+	}
+	us.mu.Unlock()
+	for _, id := range ids {
+		us.remove(id)
+	}
+}
+
+// removeData removes the local data for the upload
+func (u *upload) removeData() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := os.RemoveAll(u.dir); err != nil {
+		fs.Errorf(u.key, "s3: failed to remove multipart upload data: %v", err)
+	}
+}
+
+// sortedParts returns the uploaded parts in order
+func (u *upload) sortedParts() []*part {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	parts := make([]*part, 0, len(u.parts))
+	for _, p := range u.parts {
+		parts = append(parts, p)
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].number < parts[j].number
+	})
+	return parts
+}
+
+// writePart stores the data in in as part number, replacing any
+// part already uploaded with that number
+func (u *upload) writePart(number int, in io.Reader) (*part, error) {
+	p := &part{
+		number:  number,
+		path:    filepath.Join(u.dir, fmt.Sprintf("%05d-%s", number, random.String(8))),
+		modTime: time.Now(),
+	}
+	out, err := os.Create(p.path)
+	if err != nil {
+		return nil, err
+	}
+	hasher := md5.New()
+	p.size, err = io.Copy(io.MultiWriter(out, hasher), in)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(p.path)
+		return nil, err
+	}
+	p.etag = hex.EncodeToString(hasher.Sum(nil))
+	u.mu.Lock()
+	old := u.parts[number]
+	u.parts[number] = p
+	u.mu.Unlock()
+	if old != nil {
+		_ = os.Remove(old.path)
+	}
+	return p, nil
+}
+
+// partsReader reads the concatenation of parts
+type partsReader struct {
+	parts []*part
+	cur   *os.File
+}
+
+// Read satisfies the io.Reader interface
+func (pr *partsReader) Read(b []byte) (n int, err error) {
+	for {
+		if pr.cur == nil {
+			if len(pr.parts) == 0 {
+				return 0, io.EOF
+			}
+			pr.cur, err = os.Open(pr.parts[0].path)
+			if err != nil {
+				return 0, err
+			}
+			pr.parts = pr.parts[1:]
+		}
+		n, err = pr.cur.Read(b)
+		if err == io.EOF {
+			_ = pr.cur.Close()
+			pr.cur = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+// Close satisfies the io.Closer interface
+func (pr *partsReader) Close() error {
+	if pr.cur != nil {
+		return pr.cur.Close()
+	}
+	return nil
+}
+
+// createMultipartUpload implements CreateMultipartUpload
+func (s *server) createMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	if !validName(key) || strings.HasSuffix(key, "/") {
+		writeError(w, r, errInvalidArgument)
+		return
+	}
+	if err := s.bucketExists(r, bucket); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	u, err := s.uploads.new(bucket, key, modTimeFromHeader(r))
+	if err != nil {
+		fs.Errorf(objectRemote(bucket, key), "s3: %v", err)
+		writeError(w, r, errInternalError)
+		return
+	}
+	writeXML(w, initiateMultipartUploadResult{
+		Xmlns:    xmlns,
+		Bucket:   bucket,
+		Key:      key,
+		UploadID: u.id,
+	})
+}
+
+// uploadPart implements UploadPart
+func (s *server) uploadPart(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string) {
+	u := s.uploads.get(bucket, key, uploadID)
+	if u == nil {
+		writeError(w, r, errNoSuchUpload)
+		return
+	}
+	number, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil || number < 1 || number > maxPartNumber {
+		writeError(w, r, errInvalidArgument)
+		return
+	}
+	in, _ := requestBody(r)
+	p, err := u.writePart(number, in)
+	if err != nil {
+		fs.Errorf(objectRemote(bucket, key), "s3: failed to write part %d: %v", number, err)
+		writeError(w, r, bodyError(err))
+		return
+	}
+	w.Header().Set("ETag", `"`+p.etag+`"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+// completeMultipartUpload implements CompleteMultipartUpload
+func (s *server) completeMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string) {
+	u := s.uploads.get(bucket, key, uploadID)
+	if u == nil {
+		writeError(w, r, errNoSuchUpload)
+		return
+	}
+	var req completeMultipartUpload
+	if s3Err := readXML(r, &req); s3Err != nil {
+		writeError(w, r, s3Err)
+		return
+	}
+	if len(req.Parts) == 0 {
+		writeError(w, r, errMalformedXML)
+		return
+	}
+
+	// Check the requested parts against the uploaded ones
+	u.mu.Lock()
+	parts := make([]*part, 0, len(req.Parts))
+	var size int64
+	md5s := md5.New()
+	var s3Err *s3Error
+	for i, reqPart := range req.Parts {
+		if i > 0 && reqPart.PartNumber <= req.Parts[i-1].PartNumber {
+			s3Err = errInvalidPartOrder
+			break
+		}
+		p := u.parts[reqPart.PartNumber]
+		if p == nil || strings.Trim(reqPart.ETag, `"`) != p.etag {
+			s3Err = errInvalidPart
+			break
+		}
+		sum, _ := hex.DecodeString(p.etag)
+		_, _ = md5s.Write(sum)
+		size += p.size
+		parts = append(parts, p)
+	}
+	u.mu.Unlock()
+	if s3Err != nil {
+		writeError(w, r, s3Err)
+		return
+	}
+
+	remote := objectRemote(bucket, key)
+	in := &partsReader{parts: parts}
+	_, err := operations.RcatSize(r.Context(), s.f, remote, in, size, u.modTime)
+	_ = in.Close()
+	if err != nil {
+		err = accounting.Stats(r.Context()).Error(err)
+		fs.Errorf(remote, "s3: failed to complete multipart upload: %v", err)
+		writeError(w, r, errInternalError)
+		return
+	}
+	s.uploads.remove(uploadID)
+	writeXML(w, completeMultipartUploadResult{
+		Xmlns:    xmlns,
+		Location: "/" + remote,
+		Bucket:   bucket,
+		Key:      key,
+		ETag:     fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(md5s.Sum(nil)), len(parts)),
+	})
+}
+
+// abortMultipartUpload implements AbortMultipartUpload
+func (s *server) abortMultipartUpload(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string) {
+	if s.uploads.get(bucket, key, uploadID) == nil {
+		writeError(w, r, errNoSuchUpload)
+		return
+	}
+	s.uploads.remove(uploadID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listParts implements ListParts
+func (s *server) listParts(w http.ResponseWriter, r *http.Request, bucket, key, uploadID string) {
+	u := s.uploads.get(bucket, key, uploadID)
+	if u == nil {
+		writeError(w, r, errNoSuchUpload)
+		return
+	}
+	result := listPartsResult{
+		Xmlns:    xmlns,
+		Bucket:   bucket,
+		Key:      key,
+		UploadID: uploadID,
+		Parts:    []partInfo{},
+	}
+	for _, p := range u.sortedParts() {
+		result.Parts = append(result.Parts, partInfo{
+			PartNumber:   p.number,
+			LastModified: formatTime(p.modTime),
+			ETag:         `"` + p.etag + `"`,
+			Size:         p.size,
+		})
+	}
+	writeXML(w, result)
+}
+
+// listMultipartUploads implements ListMultipartUploads
+func (s *server) listMultipartUploads(w http.ResponseWriter, r *http.Request, bucket string) {
+	if err := s.bucketExists(r, bucket); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	result := listMultipartUploadsResult{
+		Xmlns:   xmlns,
+		Bucket:  bucket,
+		Uploads: []uploadInfo{},
+	}
+	for _, u := range s.uploads.list(bucket) {
+		result.Uploads = append(result.Uploads, uploadInfo{
+			Key:       u.key,
+			UploadID:  u.id,
+			Initiated: formatTime(u.initiated),
+		})
+	}
+	writeXML(w, result)
+}
+
+// requestBody returns the body of the request and its size, decoding
+// aws-chunked streaming uploads if necessary.  The size is -1 if not
+// known.
+func requestBody(r *http.Request) (io.Reader, int64) {
+	if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		size, err := strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil {
+			size = -1
+		}
+		return &chunkedReader{in: bufio.NewReader(r.Body)}, size
+	}
+	return r.Body, r.ContentLength
+}
+
+// chunkedReader decodes the aws-chunked transfer encoding
+//
+// Each chunk is "hex-size;chunk-signature=sig\r\n" followed by the
+// data and "\r\n".  The stream ends with a zero sized chunk.  The
+// chunk signatures are not checked.
+type chunkedReader struct {
+	in    *bufio.Reader
+	left  int64 // bytes left in the current chunk
+	done  bool
+	first bool // set once the first chunk header has been read
+}
+
+// Read satisfies the io.Reader interface
+func (cr *chunkedReader) Read(b []byte) (n int, err error) {
+	for cr.left == 0 {
+		if cr.done {
+			return 0, io.EOF
+		}
+		if cr.first {
+			// read the \r\n after the previous chunk's data
+			if _, err = cr.in.Discard(2); err != nil {
+				return 0, err
+			}
+		}
+		cr.first = true
+		line, err := cr.in.ReadString('\n')
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to read chunk header")
+		}
+		line = strings.TrimSpace(line)
+		if i := strings.IndexRune(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		cr.left, err = strconv.ParseInt(line, 16, 64)
+		if err != nil || cr.left < 0 {
+			return 0, errors.Errorf("bad chunk size %q", line)
+		}
+		if cr.left == 0 {
+			cr.done = true
+		}
+	}
+	if int64(len(b)) > cr.left {
+		b = b[:cr.left]
+	}
+	n, err = cr.in.Read(b)
+	cr.left -= int64(n)
+	if err == io.EOF && cr.left > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
@@ -0,0 +1,263 @@
+// Package s3 implements a subset of the S3 protocol in front of any remote
+package s3
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/cmd/serve/httplib"
+	"github.com/rclone/rclone/cmd/serve/httplib/httpflags"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/spf13/cobra"
+)
+
+// Options contains options for the S3 server
+type Options struct {
+	AuthKeys []string // list of accessKey,secretKey pairs
+	Region   string   // region to report and check signatures against
+}
+
+// DefaultOpt is the default values used for Options
+var DefaultOpt = Options{
+	Region: "us-east-1",
+}
+
+// Opt is options set by command line flags
+var Opt = DefaultOpt
+
+func init() {
+	flagSet := Command.Flags()
+	httpflags.AddFlags(flagSet)
+	flags.StringArrayVarP(flagSet, &Opt.AuthKeys, "auth-key", "", Opt.AuthKeys, "Set key pair for v4 authorization, split by comma")
+	flags.StringVarP(flagSet, &Opt.Region, "region", "", Opt.Region, "Region to report to clients and check signatures against")
+}
+
+// Command definition for cobra
+var Command = &cobra.Command{
+	Use:   "s3 remote:path",
+	Short: `Serve remote:path over S3.`,
+	Long: `rclone serve s3 implements a basic S3 server that serves a remote
+via the S3 protocol.  This can be viewed with an S3 client, or you
+can make a [remote of type s3](/s3) to read and write it.
+
+This allows applications which only speak S3 to use any storage that
+rclone supports, e.g. Google Drive, OneDrive or SFTP.
+
+The top level directories of remote:path are presented as buckets and
+the files and directories beneath them as objects.  Path style
+addressing is used, so clients need to be configured to use it, e.g.
+with ` + "`force_path_style = true`" + ` in rclone or
+` + "`--s3-force-path-style`" + ` in other tools.
+
+The following S3 operations are supported
+
+- ListBuckets, CreateBucket, HeadBucket, DeleteBucket
+- ListObjects and ListObjectsV2 (with prefix, delimiter and paging)
+- GetObject (including Range requests), HeadObject, PutObject,
+  CopyObject and DeleteObject
+- CreateMultipartUpload, UploadPart, CompleteMultipartUpload,
+  AbortMultipartUpload and ListParts
+
+Parts of multipart uploads are buffered in the local temporary
+directory until the upload is completed, at which point they are
+streamed to the remote as a single object.
+
+#### Authentication
+
+By default the server accepts any request, and logs a warning saying
+so when it starts.  Use --auth-key accessKey,secretKey to require AWS
+signature version 4 signed requests.  This flag may be repeated to
+allow several key pairs.  Use --region to set the region used when
+checking signatures (default us-east-1).
+
+If the client sends the SHA-256 of the request body in the
+x-amz-content-sha256 header then the body is checked against it and
+the request fails if it doesn't match.  This is done whether or not
+--auth-key is set.  Bodies sent as UNSIGNED-PAYLOAD or with aws-chunked
+streaming are not checked.
+
+Note that the --user/--pass and --htpasswd flags use HTTP basic
+authentication which S3 clients do not support, so --auth-key should
+be used instead.
+
+--bwlimit will be respected for file transfers.  Use --stats to
+control the stats printing.
+` + httplib.Help,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		cmd.Run(false, true, command, func() error {
+			s, err := newServer(context.Background(), f, &Opt, &httpflags.Opt)
+			if err != nil {
+				return err
+			}
+			err = s.Serve()
+			if err != nil {
+				return err
+			}
+			s.Wait()
+			return nil
+		})
+	},
+}
+
+// server contains everything to run the server
+type server struct {
+	*httplib.Server
+	f       fs.Fs
+	opt     Options
+	keys    map[string]string // access key to secret key
+	uploads *uploads
+}
+
+// newServer makes a new S3 server serving f
+func newServer(ctx context.Context, f fs.Fs, opt *Options, httpOpt *httplib.Options) (*server, error) {
+	mux := http.NewServeMux()
+	s := &server{
+		Server:  httplib.NewServer(mux, httpOpt),
+		f:       f,
+		opt:     *opt,
+		keys:    map[string]string{},
+		uploads: newUploads(),
+	}
+	for _, pair := range opt.AuthKeys {
+		parts := strings.SplitN(pair, ",", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("auth key %q must be in the form accessKey,secretKey", pair)
+		}
+		s.keys[parts[0]] = parts[1]
+	}
+	mux.HandleFunc(s.Opt.BaseURL+"/", s.handler)
+	return s, nil
+}
+
+// Serve runs the http server in the background.
+//
+// Use s.Close() and s.Wait() to shutdown server
+func (s *server) Serve() error {
+	err := s.Server.Serve()
+	if err != nil {
+		return err
+	}
+	fs.Logf(s.f, "Starting s3 server on %s", s.URL())
+	if len(s.keys) == 0 {
+		fs.Logf(s.f, "No --auth-key set so serving without authentication - anyone who can connect can read and write the remote")
+	}
+	return nil
+}
+
+// Close shuts the server down and removes any pending uploads
+func (s *server) Close() {
+	s.Server.Close()
+	s.uploads.abortAll()
+}
+
+// splitPath splits a URL path into bucket and key
+func splitPath(urlPath string) (bucket, key string) {
+	urlPath = strings.TrimPrefix(urlPath, "/")
+	i := strings.IndexRune(urlPath, '/')
+	if i < 0 {
+		return urlPath, ""
+	}
+	return urlPath[:i], urlPath[i+1:]
+}
+
+// handler reads incoming requests and dispatches them
+func (s *server) handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Server", "rclone/"+fs.Version)
+
+	urlPath, ok := s.Path(w, r)
+	if !ok {
+		return
+	}
+	bucket, key := splitPath(urlPath)
+	fs.Debugf(s.f, "%s bucket=%q key=%q", r.Method, bucket, key)
+
+	if s3Err := checkPayload(r); s3Err != nil {
+		writeError(w, r, s3Err)
+		return
+	}
+	if err := s.checkAuth(r); err != nil {
+		fs.Infof(urlPath, "%s: request not authorized: %v", r.RemoteAddr, err)
+		if s3Err, ok := err.(*s3Error); ok {
+			writeError(w, r, s3Err)
+		} else {
+			writeError(w, r, errAccessDenied)
+		}
+		return
+	}
+
+	query := r.URL.Query()
+	switch {
+	case bucket == "":
+		switch r.Method {
+		case "GET":
+			s.listBuckets(w, r)
+		default:
+			writeError(w, r, errMethodNotAllowed)
+		}
+	case key == "":
+		switch r.Method {
+		case "GET":
+			if _, ok := query["uploads"]; ok {
+				s.listMultipartUploads(w, r, bucket)
+			} else {
+				s.listObjects(w, r, bucket)
+			}
+		case "HEAD":
+			s.headBucket(w, r, bucket)
+		case "PUT":
+			s.createBucket(w, r, bucket)
+		case "DELETE":
+			s.deleteBucket(w, r, bucket)
+		case "POST":
+			if _, ok := query["delete"]; ok {
+				s.deleteObjects(w, r, bucket)
+			} else {
+				writeError(w, r, errNotImplemented)
+			}
+		default:
+			writeError(w, r, errMethodNotAllowed)
+		}
+	default:
+		uploadID := query.Get("uploadId")
+		switch r.Method {
+		case "GET":
+			if uploadID != "" {
+				s.listParts(w, r, bucket, key, uploadID)
+			} else {
+				s.getObject(w, r, bucket, key)
+			}
+		case "HEAD":
+			s.getObject(w, r, bucket, key)
+		case "PUT":
+			if uploadID != "" {
+				s.uploadPart(w, r, bucket, key, uploadID)
+			} else if r.Header.Get("x-amz-copy-source") != "" {
+				s.copyObject(w, r, bucket, key)
+			} else {
+				s.putObject(w, r, bucket, key)
+			}
+		case "POST":
+			if _, ok := query["uploads"]; ok {
+				s.createMultipartUpload(w, r, bucket, key)
+			} else if uploadID != "" {
+				s.completeMultipartUpload(w, r, bucket, key, uploadID)
+			} else {
+				writeError(w, r, errNotImplemented)
+			}
+		case "DELETE":
+			if uploadID != "" {
+				s.abortMultipartUpload(w, r, bucket, key, uploadID)
+			} else {
+				s.deleteObject(w, r, bucket, key)
+			}
+		default:
+			writeError(w, r, errMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/cmd/serve/httplib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testBindAddress = "localhost:0"
+	testAccessKey   = "access"
+	testSecretKey   = "secret"
+)
+
+// start a server on a random remote returning an S3 client for it
+func startServer(t *testing.T, accessKey, secretKey string) (fs.Fs, *awss3.S3, func()) {
+	fstest.Initialise()
+	fremote, _, clean, err := fstest.RandomRemote()
+	require.NoError(t, err)
+	require.NoError(t, fremote.Mkdir(context.Background(), ""))
+
+	httpOpt := httplib.DefaultOpt
+	httpOpt.ListenAddr = testBindAddress
+	opt := DefaultOpt
+	opt.AuthKeys = []string{testAccessKey + "," + testSecretKey}
+	s, err := newServer(context.Background(), fremote, &opt, &httpOpt)
+	require.NoError(t, err)
+	require.NoError(t, s.Serve())
+
+	sess, err := session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials(accessKey, secretKey, ""),
+		Endpoint:         aws.String(s.URL()),
+		Region:           aws.String(opt.Region),
+		S3ForcePathStyle: aws.Bool(true),
+		DisableSSL:       aws.Bool(true),
+	})
+	require.NoError(t, err)
+	return fremote, awss3.New(sess), func() {
+		s.Close()
+		s.Wait()
+		clean()
+	}
+}
+
+func TestSplitPath(t *testing.T) {
+	for _, test := range []struct {
+		in     string
+		bucket string
+		key    string
+	}{
+		{"", "", ""},
+		{"/", "", ""},
+		{"/bucket", "bucket", ""},
+		{"/bucket/", "bucket", ""},
+		{"/bucket/key", "bucket", "key"},
+		{"/bucket/dir/key", "bucket", "dir/key"},
+		{"/bucket/dir/", "bucket", "dir/"},
+	} {
+		bucket, key := splitPath(test.in)
+		assert.Equal(t, test.bucket, bucket, test.in)
+		assert.Equal(t, test.key, key, test.in)
+	}
+}
+
+func TestValidName(t *testing.T) {
+	assert.True(t, validName("bucket"))
+	assert.True(t, validName("dir/file.txt"))
+	assert.True(t, validName("dir/"))
+	assert.False(t, validName(""))
+	assert.False(t, validName("../file"))
+	assert.False(t, validName("dir/./file"))
+	assert.False(t, validName("dir//file"))
+}
+
+func TestChunkedReader(t *testing.T) {
+	in := "5;chunk-signature=aaaa\r\nhello\r\n6;chunk-signature=bbbb\r\n world\r\n0;chunk-signature=cccc\r\n\r\n"
+	cr := &chunkedReader{in: bufio.NewReader(strings.NewReader(in))}
+	out, err := ioutil.ReadAll(cr)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(out))
+}
+
+func TestPayloadReader(t *testing.T) {
+	for _, test := range []struct {
+		body    string
+		hash    string
+		wantErr error
+	}{
+		{"hello", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", nil},
+		{"hellO", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", errContentSHA256Mismatch},
+		{"hello", unsignedPayload, nil},
+		{"hello", "", nil},
+	} {
+		r := httptest.NewRequest("PUT", "/bucket/key", strings.NewReader(test.body))
+		if test.hash != "" {
+			r.Header.Set("X-Amz-Content-Sha256", test.hash)
+		}
+		require.Nil(t, checkPayload(r))
+		out, err := ioutil.ReadAll(r.Body)
+		assert.Equal(t, test.wantErr, err, test.body)
+		assert.Equal(t, test.body, string(out))
+	}
+
+	r := httptest.NewRequest("PUT", "/bucket/key", strings.NewReader("hello"))
+	r.Header.Set("X-Amz-Content-Sha256", "potato")
+	assert.Equal(t, errInvalidArgument, checkPayload(r))
+}
+
+func TestPayloadMismatch(t *testing.T) {
+	fstest.Initialise()
+	fremote, _, clean, err := fstest.RandomRemote()
+	require.NoError(t, err)
+	defer clean()
+	ctx := context.Background()
+	require.NoError(t, fremote.Mkdir(ctx, "bucket"))
+
+	// no --auth-key so the payload hash is the only check
+	httpOpt := httplib.DefaultOpt
+	s, err := newServer(ctx, fremote, &DefaultOpt, &httpOpt)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("PUT", "/bucket/file.txt", strings.NewReader("tampered"))
+	r.Header.Set("X-Amz-Content-Sha256", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	w := httptest.NewRecorder()
+	s.handler(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "XAmzContentSHA256Mismatch")
+	_, err = fremote.NewObject(ctx, "bucket/file.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	r = httptest.NewRequest("POST", "/bucket?delete", strings.NewReader("<Delete><Object><Key>a</Key></Object></Delete>"))
+	r.Header.Set("X-Amz-Content-Sha256", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	w = httptest.NewRecorder()
+	s.handler(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "XAmzContentSHA256Mismatch")
+
+	r = httptest.NewRequest("PUT", "/bucket/file.txt", strings.NewReader("hello"))
+	r.Header.Set("X-Amz-Content-Sha256", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	w = httptest.NewRecorder()
+	s.handler(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, err = fremote.NewObject(ctx, "bucket/file.txt")
+	assert.NoError(t, err)
+}
+
+func TestAuth(t *testing.T) {
+	_, client, cleanup := startServer(t, testAccessKey, "wrong")
+	defer cleanup()
+
+	_, err := client.ListBuckets(&awss3.ListBucketsInput{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "SignatureDoesNotMatch")
+}
+
+func TestServer(t *testing.T) {
+	fremote, client, cleanup := startServer(t, testAccessKey, testSecretKey)
+	defer cleanup()
+	ctx := context.Background()
+	bucket := aws.String("bucket")
+
+	// Buckets
+	_, err := client.CreateBucket(&awss3.CreateBucketInput{Bucket: bucket})
+	require.NoError(t, err)
+	_, err = client.HeadBucket(&awss3.HeadBucketInput{Bucket: bucket})
+	require.NoError(t, err)
+	_, err = client.HeadBucket(&awss3.HeadBucketInput{Bucket: aws.String("missing")})
+	require.Error(t, err)
+	buckets, err := client.ListBuckets(&awss3.ListBucketsInput{})
+	require.NoError(t, err)
+	require.Len(t, buckets.Buckets, 1)
+	assert.Equal(t, "bucket", *buckets.Buckets[0].Name)
+
+	// Put and Get
+	for _, key := range []string{"a.txt", "dir/b.txt", "dir/sub/c.txt", "e f+g.txt"} {
+		_, err = client.PutObject(&awss3.PutObjectInput{
+			Bucket: bucket,
+			Key:    aws.String(key),
+			Body:   bytes.NewReader([]byte("contents of " + key)),
+		})
+		require.NoError(t, err, key)
+	}
+	obj, err := client.GetObject(&awss3.GetObjectInput{Bucket: bucket, Key: aws.String("dir/b.txt")})
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(obj.Body)
+	require.NoError(t, err)
+	require.NoError(t, obj.Body.Close())
+	assert.Equal(t, "contents of dir/b.txt", string(data))
+
+	obj, err = client.GetObject(&awss3.GetObjectInput{Bucket: bucket, Key: aws.String("a.txt"), Range: aws.String("bytes=9-10")})
+	require.NoError(t, err)
+	data, err = ioutil.ReadAll(obj.Body)
+	require.NoError(t, err)
+	require.NoError(t, obj.Body.Close())
+	assert.Equal(t, "of", string(data))
+
+	head, err := client.HeadObject(&awss3.HeadObjectInput{Bucket: bucket, Key: aws.String("e f+g.txt")})
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("contents of e f+g.txt")), *head.ContentLength)
+
+	_, err = client.GetObject(&awss3.GetObjectInput{Bucket: bucket, Key: aws.String("missing")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "NoSuchKey")
+
+	// List recursively
+	list, err := client.ListObjectsV2(&awss3.ListObjectsV2Input{Bucket: bucket})
+	require.NoError(t, err)
+	var keys []string
+	for _, o := range list.Contents {
+		keys = append(keys, *o.Key)
+	}
+	assert.Equal(t, []string{"a.txt", "dir/b.txt", "dir/sub/c.txt", "e f+g.txt"}, keys)
+
+	// List with delimiter
+	list, err = client.ListObjectsV2(&awss3.ListObjectsV2Input{Bucket: bucket, Prefix: aws.String("dir/"), Delimiter: aws.String("/")})
+	require.NoError(t, err)
+	require.Len(t, list.Contents, 1)
+	assert.Equal(t, "dir/b.txt", *list.Contents[0].Key)
+	require.Len(t, list.CommonPrefixes, 1)
+	assert.Equal(t, "dir/sub/", *list.CommonPrefixes[0].Prefix)
+
+	// List with paging
+	keys = nil
+	err = client.ListObjectsV2Pages(&awss3.ListObjectsV2Input{Bucket: bucket, MaxKeys: aws.Int64(1)}, func(page *awss3.ListObjectsV2Output, lastPage bool) bool {
+		for _, o := range page.Contents {
+			keys = append(keys, *o.Key)
+		}
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "dir/b.txt", "dir/sub/c.txt", "e f+g.txt"}, keys)
+
+	// Copy
+	_, err = client.CopyObject(&awss3.CopyObjectInput{Bucket: bucket, Key: aws.String("copy.txt"), CopySource: aws.String("bucket/a.txt")})
+	require.NoError(t, err)
+	o, err := fremote.NewObject(ctx, "bucket/copy.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("contents of a.txt")), o.Size())
+
+	// Multipart upload
+	create, err := client.CreateMultipartUpload(&awss3.CreateMultipartUploadInput{Bucket: bucket, Key: aws.String("multi.txt")})
+	require.NoError(t, err)
+	var completed []*awss3.CompletedPart
+	for i, chunk := range []string{"part one,", "part two"} {
+		part, err := client.UploadPart(&awss3.UploadPartInput{
+			Bucket:     bucket,
+			Key:        aws.String("multi.txt"),
+			UploadId:   create.UploadId,
+			PartNumber: aws.Int64(int64(i + 1)),
+			Body:       strings.NewReader(chunk),
+		})
+		require.NoError(t, err)
+		completed = append(completed, &awss3.CompletedPart{ETag: part.ETag, PartNumber: aws.Int64(int64(i + 1))})
+	}
+	parts, err := client.ListParts(&awss3.ListPartsInput{Bucket: bucket, Key: aws.String("multi.txt"), UploadId: create.UploadId})
+	require.NoError(t, err)
+	assert.Len(t, parts.Parts, 2)
+	_, err = client.CompleteMultipartUpload(&awss3.CompleteMultipartUploadInput{
+		Bucket:          bucket,
+		Key:             aws.String("multi.txt"),
+		UploadId:        create.UploadId,
+		MultipartUpload: &awss3.CompletedMultipartUpload{Parts: completed},
+	})
+	require.NoError(t, err)
+	obj, err = client.GetObject(&awss3.GetObjectInput{Bucket: bucket, Key: aws.String("multi.txt")})
+	require.NoError(t, err)
+	data, err = ioutil.ReadAll(obj.Body)
+	require.NoError(t, err)
+	require.NoError(t, obj.Body.Close())
+	assert.Equal(t, "part one,part two", string(data))
+
+	// Abort multipart upload
+	create, err = client.CreateMultipartUpload(&awss3.CreateMultipartUploadInput{Bucket: bucket, Key: aws.String("aborted.txt")})
+	require.NoError(t, err)
+	_, err = client.AbortMultipartUpload(&awss3.AbortMultipartUploadInput{Bucket: bucket, Key: aws.String("aborted.txt"), UploadId: create.UploadId})
+	require.NoError(t, err)
+	_, err = client.ListParts(&awss3.ListPartsInput{Bucket: bucket, Key: aws.String("aborted.txt"), UploadId: create.UploadId})
+	require.Error(t, err)
+
+	// Delete
+	_, err = client.DeleteBucket(&awss3.DeleteBucketInput{Bucket: bucket})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "BucketNotEmpty")
+	_, err = client.DeleteObject(&awss3.DeleteObjectInput{Bucket: bucket, Key: aws.String("a.txt")})
+	require.NoError(t, err)
+	_, err = fremote.NewObject(ctx, "bucket/a.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	deleted, err := client.DeleteObjects(&awss3.DeleteObjectsInput{Bucket: bucket, Delete: &awss3.Delete{Objects: []*awss3.ObjectIdentifier{
+		{Key: aws.String("copy.txt")},
+		{Key: aws.String("multi.txt")},
+	}}})
+	require.NoError(t, err)
+	assert.Len(t, deleted.Deleted, 2)
+}
@@ -0,0 +1,277 @@
+package s3
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+)
+
+// xmlns is the namespace used in S3 responses
+const xmlns = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// timeFormat is the time format used in S3 XML responses
+const timeFormat = "2006-01-02T15:04:05.000Z"
+
+// s3Error describes an error returned to the client
+type s3Error struct {
+	Code       string
+	Message    string
+	StatusCode int
+}
+
+// Error satisfies the error interface
+func (e *s3Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Errors returned by the server
+var (
+	errAccessDenied          = &s3Error{"AccessDenied", "Access Denied", http.StatusForbidden}
+	errBucketNotEmpty        = &s3Error{"BucketNotEmpty", "The bucket you tried to delete is not empty", http.StatusConflict}
+	errBadDigest             = &s3Error{"BadDigest", "The Content-MD5 you specified did not match what we received", http.StatusBadRequest}
+	errInternalError         = &s3Error{"InternalError", "We encountered an internal error, please try again", http.StatusInternalServerError}
+	errInvalidArgument       = &s3Error{"InvalidArgument", "Invalid Argument", http.StatusBadRequest}
+	errInvalidBucketName     = &s3Error{"InvalidBucketName", "The specified bucket is not valid", http.StatusBadRequest}
+	errInvalidPart           = &s3Error{"InvalidPart", "One or more of the specified parts could not be found", http.StatusBadRequest}
+	errInvalidPartOrder      = &s3Error{"InvalidPartOrder", "The list of parts was not in ascending order", http.StatusBadRequest}
+	errMalformedXML          = &s3Error{"MalformedXML", "The XML you provided was not well-formed", http.StatusBadRequest}
+	errMethodNotAllowed      = &s3Error{"MethodNotAllowed", "The specified method is not allowed against this resource", http.StatusMethodNotAllowed}
+	errNoSuchBucket          = &s3Error{"NoSuchBucket", "The specified bucket does not exist", http.StatusNotFound}
+	errNoSuchKey             = &s3Error{"NoSuchKey", "The specified key does not exist", http.StatusNotFound}
+	errNoSuchUpload          = &s3Error{"NoSuchUpload", "The specified multipart upload does not exist", http.StatusNotFound}
+	errNotImplemented        = &s3Error{"NotImplemented", "A header you provided implies functionality that is not implemented", http.StatusNotImplemented}
+	errSignatureMismatch     = &s3Error{"SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided", http.StatusForbidden}
+	errBucketAlreadyOwned    = &s3Error{"BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it", http.StatusConflict}
+	errContentSHA256Mismatch = &s3Error{"XAmzContentSHA256Mismatch", "The provided 'x-amz-content-sha256' header does not match what was computed", http.StatusBadRequest}
+)
+
+// errorResponse is the XML body of an error
+type errorResponse struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string
+	Message  string
+	Resource string
+}
+
+// writeError writes err to the client as an S3 XML error
+func writeError(w http.ResponseWriter, r *http.Request, err *s3Error) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(err.StatusCode)
+	// HEAD responses don't have a body
+	if r.Method == "HEAD" {
+		return
+	}
+	_, _ = w.Write([]byte(xml.Header))
+	encodeErr := xml.NewEncoder(w).Encode(errorResponse{
+		Code:     err.Code,
+		Message:  err.Message,
+		Resource: r.URL.Path,
+	})
+	if encodeErr != nil {
+		fs.Errorf(nil, "s3: failed to write error response: %v", encodeErr)
+	}
+}
+
+// bodyError returns the error to send to the client when reading the
+// request body failed with err
+func bodyError(err error) *s3Error {
+	if errors.Cause(err) == errContentSHA256Mismatch {
+		return errContentSHA256Mismatch
+	}
+	return errInternalError
+}
+
+// readXML reads the whole request body and decodes it into v
+func readXML(r *http.Request, v interface{}) *s3Error {
+	data, err := ioutil.ReadAll(r.Body)
+	if errors.Cause(err) == errContentSHA256Mismatch {
+		return errContentSHA256Mismatch
+	}
+	if err != nil {
+		return errMalformedXML
+	}
+	if err = xml.Unmarshal(data, v); err != nil {
+		return errMalformedXML
+	}
+	return nil
+}
+
+// writeXML writes v to the client as an XML document
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(xml.Header))
+	err := xml.NewEncoder(w).Encode(v)
+	if err != nil {
+		fs.Errorf(nil, "s3: failed to write response: %v", err)
+	}
+}
+
+// formatTime formats t for use in XML responses
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+// owner describes the owner of a bucket or object
+type owner struct {
+	ID          string
+	DisplayName string
+}
+
+// defaultOwner is returned as the owner of everything
+var defaultOwner = owner{ID: "rclone", DisplayName: "rclone"}
+
+// bucketInfo describes a bucket in a ListBuckets response
+type bucketInfo struct {
+	Name         string
+	CreationDate string
+}
+
+// listBucketsResult is the response to ListBuckets
+type listBucketsResult struct {
+	XMLName xml.Name     `xml:"ListAllMyBucketsResult"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Owner   owner        `xml:"Owner"`
+	Buckets []bucketInfo `xml:"Buckets>Bucket"`
+}
+
+// objectInfo describes an object in a listing
+type objectInfo struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int64
+	StorageClass string
+	Owner        *owner `xml:",omitempty"`
+}
+
+// commonPrefix describes a pseudo directory in a listing
+type commonPrefix struct {
+	Prefix string
+}
+
+// listBucketResult is the response to ListObjects and ListObjectsV2
+type listBucketResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Xmlns                 string   `xml:"xmlns,attr"`
+	Name                  string
+	Prefix                string
+	Delimiter             string `xml:",omitempty"`
+	MaxKeys               int
+	IsTruncated           bool
+	Marker                *string        `xml:",omitempty"`
+	NextMarker            string         `xml:",omitempty"`
+	ContinuationToken     string         `xml:",omitempty"`
+	NextContinuationToken string         `xml:",omitempty"`
+	StartAfter            string         `xml:",omitempty"`
+	KeyCount              *int           `xml:",omitempty"`
+	Contents              []objectInfo   `xml:"Contents"`
+	CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+}
+
+// copyObjectResult is the response to CopyObject
+type copyObjectResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	LastModified string
+	ETag         string
+}
+
+// deleteRequest is the body of a DeleteObjects request
+type deleteRequest struct {
+	XMLName xml.Name `xml:"Delete"`
+	Quiet   bool
+	Objects []struct {
+		Key string
+	} `xml:"Object"`
+}
+
+// deletedObject describes a successfully deleted object
+type deletedObject struct {
+	Key string
+}
+
+// deleteError describes an object which failed to delete
+type deleteError struct {
+	Key     string
+	Code    string
+	Message string
+}
+
+// deleteResult is the response to DeleteObjects
+type deleteResult struct {
+	XMLName xml.Name        `xml:"DeleteResult"`
+	Xmlns   string          `xml:"xmlns,attr"`
+	Deleted []deletedObject `xml:"Deleted"`
+	Errors  []deleteError   `xml:"Error"`
+}
+
+// initiateMultipartUploadResult is the response to CreateMultipartUpload
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Bucket   string
+	Key      string
+	UploadID string `xml:"UploadId"`
+}
+
+// completedPart is a part in a CompleteMultipartUpload request
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// completeMultipartUpload is the body of a CompleteMultipartUpload request
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// completeMultipartUploadResult is the response to CompleteMultipartUpload
+type completeMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string
+	Bucket   string
+	Key      string
+	ETag     string
+}
+
+// partInfo describes an uploaded part in a ListParts response
+type partInfo struct {
+	PartNumber   int
+	LastModified string
+	ETag         string
+	Size         int64
+}
+
+// listPartsResult is the response to ListParts
+type listPartsResult struct {
+	XMLName     xml.Name `xml:"ListPartsResult"`
+	Xmlns       string   `xml:"xmlns,attr"`
+	Bucket      string
+	Key         string
+	UploadID    string `xml:"UploadId"`
+	IsTruncated bool
+	Parts       []partInfo `xml:"Part"`
+}
+
+// uploadInfo describes an in progress upload in a ListMultipartUploads response
+type uploadInfo struct {
+	Key       string
+	UploadID  string `xml:"UploadId"`
+	Initiated string
+}
+
+// listMultipartUploadsResult is the response to ListMultipartUploads
+type listMultipartUploadsResult struct {
+	XMLName     xml.Name `xml:"ListMultipartUploadsResult"`
+	Xmlns       string   `xml:"xmlns,attr"`
+	Bucket      string
+	IsTruncated bool
+	Uploads     []uploadInfo `xml:"Upload"`
+}
@@ -4,10 +4,14 @@ import (
 	"errors"
 
 	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/cmd/serve/backend"
 	"github.com/rclone/rclone/cmd/serve/dlna"
+	"github.com/rclone/rclone/cmd/serve/docker"
 	"github.com/rclone/rclone/cmd/serve/ftp"
 	"github.com/rclone/rclone/cmd/serve/http"
+	"github.com/rclone/rclone/cmd/serve/nfs"
 	"github.com/rclone/rclone/cmd/serve/restic"
+	"github.com/rclone/rclone/cmd/serve/s3"
 	"github.com/rclone/rclone/cmd/serve/sftp"
 	"github.com/rclone/rclone/cmd/serve/webdav"
 	"github.com/spf13/cobra"
@@ -30,6 +34,16 @@ func init() {
 	if sftp.Command != nil {
 		Command.AddCommand(sftp.Command)
 	}
+	if s3.Command != nil {
+		Command.AddCommand(s3.Command)
+	}
+	if nfs.Command != nil {
+		Command.AddCommand(nfs.Command)
+	}
+	if docker.Command != nil {
+		Command.AddCommand(docker.Command)
+	}
+	Command.AddCommand(backend.Command)
 	cmd.Root.AddCommand(Command)
 }
 
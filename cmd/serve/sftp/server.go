@@ -39,6 +39,7 @@ type server struct {
 	listener net.Listener
 	waitChan chan struct{} // for waiting on the listener to close
 	proxy    *proxy.Proxy
+	users    *userDB // set if using --users-file
 }
 
 func newServer(ctx context.Context, f fs.Fs, opt *Options) *server {
@@ -50,21 +51,35 @@ func newServer(ctx context.Context, f fs.Fs, opt *Options) *server {
 	}
 	if proxyflags.Opt.AuthProxy != "" {
 		s.proxy = proxy.New(ctx, &proxyflags.Opt)
-	} else {
+	} else if opt.UsersFile == "" {
+		// with --users-file the VFSes are made per user on login
 		s.vfs = vfs.New(f, &vfsflags.Opt)
 	}
 	return s
 }
 
-// getVFS gets the vfs from s or the proxy
+// getVFS gets the vfs from s, the user or the proxy
 func (s *server) getVFS(what string, sshConn *ssh.ServerConn) (VFS *vfs.VFS) {
-	if s.proxy == nil {
+	if s.proxy == nil && s.users == nil {
 		return s.vfs
 	}
-	if sshConn.Permissions == nil && sshConn.Permissions.Extensions == nil {
+	if sshConn.Permissions == nil || sshConn.Permissions.Extensions == nil {
 		fs.Infof(what, "SSH Permissions Extensions not found")
 		return nil
 	}
+	if s.users != nil {
+		u := s.users.users[sshConn.Permissions.Extensions["_user"]]
+		if u == nil {
+			fs.Infof(what, "user not found")
+			return nil
+		}
+		VFS, err := u.getVFS(s.ctx)
+		if err != nil {
+			fs.Errorf(what, "%v", err)
+			return nil
+		}
+		return VFS
+	}
 	key := sshConn.Permissions.Extensions["_vfsKey"]
 	if key == "" {
 		fs.Infof(what, "VFS key not found")
@@ -134,8 +149,29 @@ func (s *server) serve() (err error) {
 		return errors.New("--auth-proxy and --authorized-keys cannot be used at the same time")
 	}
 
+	// Load the users and their keys
+	if s.opt.UsersFile != "" {
+		switch {
+		case proxyflags.Opt.AuthProxy != "":
+			return errors.New("--users-file and --auth-proxy cannot be used at the same time")
+		case s.opt.User != "" || s.opt.Pass != "":
+			return errors.New("--users-file and --user/--pass cannot be used at the same time")
+		case s.opt.AuthorizedKeys != "" && s.opt.AuthorizedKeys != DefaultOpt.AuthorizedKeys:
+			return errors.New("--users-file and --authorized-keys cannot be used at the same time")
+		case s.opt.NoAuth:
+			return errors.New("--users-file and --no-auth cannot be used at the same time")
+		}
+		s.users, err = loadUsers(s.opt.UsersFile, s.f, s.opt.HtPasswd)
+		if err != nil {
+			return err
+		}
+		fs.Logf(nil, "Loaded %d users from %q", len(s.users.users), s.opt.UsersFile)
+	} else if s.opt.HtPasswd != "" {
+		return errors.New("--htpasswd needs --users-file")
+	}
+
 	// Load the authorized keys
-	if s.opt.AuthorizedKeys != "" && proxyflags.Opt.AuthProxy == "" {
+	if s.opt.AuthorizedKeys != "" && proxyflags.Opt.AuthProxy == "" && s.users == nil {
 		authKeysFile := env.ShellExpand(s.opt.AuthorizedKeys)
 		authorizedKeysMap, err = loadAuthorizedKeys(authKeysFile)
 		// If user set the flag away from the default then report an error
@@ -145,7 +181,7 @@ func (s *server) serve() (err error) {
 		fs.Logf(nil, "Loaded %d authorized keys from %q", len(authorizedKeysMap), authKeysFile)
 	}
 
-	if !s.opt.NoAuth && len(authorizedKeysMap) == 0 && s.opt.User == "" && s.opt.Pass == "" && s.proxy == nil {
+	if !s.opt.NoAuth && len(authorizedKeysMap) == 0 && s.opt.User == "" && s.opt.Pass == "" && s.proxy == nil && s.users == nil {
 		return errors.New("no authorization found, use --user/--pass or --authorized-keys or --no-auth or --auth-proxy")
 	}
 
@@ -167,6 +203,14 @@ func (s *server) serve() (err error) {
 						"_vfsKey": vfsKey,
 					},
 				}, nil
+			} else if s.users != nil {
+				if u := s.users.checkPassword(c.User(), pass); u != nil {
+					return &ssh.Permissions{
+						Extensions: map[string]string{
+							"_user": u.name,
+						},
+					}, nil
+				}
 			} else if s.opt.User != "" && s.opt.Pass != "" {
 				userOK := subtle.ConstantTimeCompare([]byte(c.User()), []byte(s.opt.User))
 				passOK := subtle.ConstantTimeCompare(pass, []byte(s.opt.Pass))
@@ -195,6 +239,17 @@ func (s *server) serve() (err error) {
 					},
 				}, nil
 			}
+			if s.users != nil {
+				if u := s.users.checkPublicKey(c.User(), pubKey.Marshal()); u != nil {
+					return &ssh.Permissions{
+						Extensions: map[string]string{
+							"_user":     u.name,
+							"pubkey-fp": ssh.FingerprintSHA256(pubKey),
+						},
+					}, nil
+				}
+				return nil, fmt.Errorf("unknown public key for %q", c.User())
+			}
 			if _, ok := authorizedKeysMap[string(pubKey.Marshal())]; ok {
 				return &ssh.Permissions{
 					// Record the public key used for authentication.
@@ -206,11 +261,15 @@ func (s *server) serve() (err error) {
 			return nil, fmt.Errorf("unknown public key for %q", c.User())
 		},
 		AuthLogCallback: func(conn ssh.ConnMetadata, method string, err error) {
-			status := "OK"
-			if err != nil {
-				status = err.Error()
+			switch {
+			case err == nil:
+				fs.Infof(describeConn(conn), "ssh auth %q for user %q from %q: OK", method, conn.User(), conn.ClientVersion())
+			case method == "none":
+				// clients try this first to find out which methods are allowed
+				fs.Debugf(describeConn(conn), "ssh auth %q for user %q from %q: %v", method, conn.User(), conn.ClientVersion(), err)
+			default:
+				fs.Logf(describeConn(conn), "ssh auth %q for user %q from %q failed: %v", method, conn.User(), conn.ClientVersion(), err)
 			}
-			fs.Debugf(describeConn(conn), "ssh auth %q from %q: %s", method, conn.ClientVersion(), status)
 		},
 		NoClientAuth: s.opt.NoAuth,
 	}
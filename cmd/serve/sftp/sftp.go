@@ -7,6 +7,7 @@ package sftp
 import (
 	"context"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/cmd/serve/proxy"
 	"github.com/rclone/rclone/cmd/serve/proxy/proxyflags"
@@ -24,6 +25,8 @@ type Options struct {
 	ListenAddr     string   // Port to listen on
 	HostKeys       []string // Paths to private host keys
 	AuthorizedKeys string   // Path to authorized keys file
+	UsersFile      string   // Path to file mapping users to roots
+	HtPasswd       string   // Path to htpasswd file for the users file
 	User           string   // single username
 	Pass           string   // password for user
 	NoAuth         bool     // allow no authentication on connections
@@ -45,6 +48,8 @@ func AddFlags(flagSet *pflag.FlagSet, Opt *Options) {
 	flags.StringVarP(flagSet, &Opt.ListenAddr, "addr", "", Opt.ListenAddr, "IPaddress:Port or :Port to bind server to.")
 	flags.StringArrayVarP(flagSet, &Opt.HostKeys, "key", "", Opt.HostKeys, "SSH private host key file (Can be multi-valued, leave blank to auto generate)")
 	flags.StringVarP(flagSet, &Opt.AuthorizedKeys, "authorized-keys", "", Opt.AuthorizedKeys, "Authorized keys file")
+	flags.StringVarP(flagSet, &Opt.UsersFile, "users-file", "", Opt.UsersFile, "File mapping user names to their root directories and authorized keys")
+	flags.StringVarP(flagSet, &Opt.HtPasswd, "htpasswd", "", Opt.HtPasswd, "htpasswd file with the passwords of the users in --users-file")
 	flags.StringVarP(flagSet, &Opt.User, "user", "", Opt.User, "User name for authentication.")
 	flags.StringVarP(flagSet, &Opt.Pass, "pass", "", Opt.Pass, "Password for authentication.")
 	flags.BoolVarP(flagSet, &Opt.NoAuth, "no-auth", "", Opt.NoAuth, "Allow connections with no authentication if set.")
@@ -78,6 +83,37 @@ an authorized keys file (specify location with --authorized-keys - the
 default is the same as ssh), an --auth-proxy, or set the --no-auth flag for no
 authentication when logging in.
 
+### Multiple users
+
+Use --users-file to give each user their own root, so different
+users see different directories or remotes.  Each line of the file
+is a user name, the root for that user and optionally an
+authorized_keys file for them, separated by spaces, e.g.
+
+    # user  root          authorized_keys
+    alice   alice         /home/alice/.ssh/authorized_keys
+    bob     backup:bob
+
+A root without a ":" is a directory of the remote given on the
+command line, so with "rclone serve sftp --users-file users
+remote:home" alice gets "remote:home/alice".  Otherwise it is a
+remote in its own right and the remote on the command line may be
+left out.
+
+Users may only log in with the keys in their own authorized_keys file
+or with the password for them in the file given with --htpasswd.  This
+uses the same format as the --htpasswd flag of "rclone serve http", so
+make it with, e.g.
+
+    htpasswd -B -c htpasswd alice
+
+--users-file can't be used with --user, --pass, --authorized-keys,
+--no-auth or --auth-proxy.
+
+Successful logins are logged at INFO level (use -v to see them) and
+failed logins at NOTICE level with the user name and remote address,
+so they can be fed to tools like fail2ban.
+
 Note that this also implements a small number of shell commands so
 that it can provide md5sum/sha1sum/df information for the rclone sftp
 backend.  This means that is can support SHA1SUMs, MD5SUMs and the
@@ -100,14 +136,22 @@ be used with sshd via ~/.ssh/authorized_keys, for example:
 ` + vfs.Help + proxy.Help,
 	Run: func(command *cobra.Command, args []string) {
 		var f fs.Fs
-		if proxyflags.Opt.AuthProxy == "" {
+		if proxyflags.Opt.AuthProxy != "" {
+			cmd.CheckArgs(0, 0, command, args)
+		} else if Opt.UsersFile != "" {
+			cmd.CheckArgs(0, 1, command, args)
+			if len(args) > 0 {
+				f = cmd.NewFsSrc(args)
+			}
+		} else {
 			cmd.CheckArgs(1, 1, command, args)
 			f = cmd.NewFsSrc(args)
-		} else {
-			cmd.CheckArgs(0, 0, command, args)
 		}
 		cmd.Run(false, true, command, func() error {
 			if Opt.Stdio {
+				if Opt.UsersFile != "" {
+					return errors.New("--users-file can't be used with --stdio")
+				}
 				return serveStdio(f)
 			}
 			s := newServer(context.Background(), f, &Opt)
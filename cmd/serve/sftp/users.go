@@ -0,0 +1,150 @@
+// +build !plan9
+
+package sftp
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	auth "github.com/abbot/go-http-auth"
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/lib/env"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfsflags"
+)
+
+// sftpUser is a user read from the --users-file
+type sftpUser struct {
+	name           string
+	root           string              // remote the user is confined to
+	authorizedKeys map[string]struct{} // public keys the user may log in with
+
+	mu  sync.Mutex
+	vfs *vfs.VFS // made on first login
+}
+
+// getVFS returns the VFS for the user's root, making it if necessary
+func (u *sftpUser) getVFS(ctx context.Context) (*vfs.VFS, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.vfs != nil {
+		return u.vfs, nil
+	}
+	f, err := cache.Get(ctx, u.root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make root %q for user %q", u.root, u.name)
+	}
+	u.vfs = vfs.New(f, &vfsflags.Opt)
+	return u.vfs, nil
+}
+
+// userDB is the users read from the --users-file along with their
+// passwords from the --htpasswd file
+type userDB struct {
+	users    map[string]*sftpUser
+	htpasswd *auth.BasicAuth // nil if there are no passwords
+}
+
+// loadUsers reads the users file
+//
+// Each line is a user name, the root they are given and optionally
+// an authorized keys file for them, separated by white space, e.g.
+//
+//	alice  alice  /home/alice/.ssh/authorized_keys
+//	bob    backup:bob
+//
+// A root without a ":" is a directory in f which must not be nil.
+// Blank lines and lines starting with "#" are ignored.
+func loadUsers(usersPath string, f fs.Fs, htpasswd string) (db *userDB, err error) {
+	in, err := os.Open(usersPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open users file")
+	}
+	defer fs.CheckClose(in, &err)
+	db = &userDB{
+		users: map[string]*sftpUser{},
+	}
+	scanner := bufio.NewScanner(in)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, errors.Errorf("%s:%d: expecting \"user root [authorized_keys]\"", usersPath, lineNumber)
+		}
+		u := &sftpUser{
+			name: fields[0],
+			root: fields[1],
+		}
+		if strings.Contains(u.name, ":") {
+			return nil, errors.Errorf("%s:%d: user name %q must not contain \":\"", usersPath, lineNumber, u.name)
+		}
+		if _, found := db.users[u.name]; found {
+			return nil, errors.Errorf("%s:%d: duplicate user %q", usersPath, lineNumber, u.name)
+		}
+		if !strings.Contains(u.root, ":") {
+			if f == nil {
+				return nil, errors.Errorf("%s:%d: root %q for user %q needs a remote to be relative to", usersPath, lineNumber, u.root, u.name)
+			}
+			u.root = fspath.JoinRootPath(fs.ConfigString(f), u.root)
+		}
+		if len(fields) == 3 {
+			u.authorizedKeys, err = loadAuthorizedKeys(env.ShellExpand(fields[2]))
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s:%d", usersPath, lineNumber)
+			}
+		}
+		db.users[u.name] = u
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read users file")
+	}
+	if htpasswd != "" {
+		// the provider panics if the file is missing so check first
+		if _, err := os.Stat(htpasswd); err != nil {
+			return nil, errors.Wrap(err, "failed to read htpasswd file")
+		}
+		db.htpasswd = auth.NewBasicAuthenticator("", auth.HtpasswdFileProvider(htpasswd))
+	}
+	return db, nil
+}
+
+// checkPassword returns the user if the password is correct for them
+func (db *userDB) checkPassword(user string, pass []byte) *sftpUser {
+	u := db.users[user]
+	if u == nil || db.htpasswd == nil {
+		return nil
+	}
+	// use the authenticator to check the password against the
+	// htpasswd file so all its hash types are supported
+	r := &http.Request{Header: http.Header{}}
+	r.SetBasicAuth(user, string(pass))
+	if db.htpasswd.CheckAuth(r) != user {
+		return nil
+	}
+	return u
+}
+
+// checkPublicKey returns the user if the marshalled public key is
+// authorized for them
+func (db *userDB) checkPublicKey(user string, pubKey []byte) *sftpUser {
+	u := db.users[user]
+	if u == nil {
+		return nil
+	}
+	if _, ok := u.authorizedKeys[string(pubKey)]; !ok {
+		return nil
+	}
+	return u
+}
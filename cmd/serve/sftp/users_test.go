@@ -0,0 +1,206 @@
+// +build !plan9
+
+package sftp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/pkg/sftp"
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// writeFile writes contents to name in dir returning its path
+func writeFile(t *testing.T, dir, name, contents string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0777))
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+// htpasswdSHA returns an htpasswd line for user and pass
+func htpasswdSHA(user, pass string) string {
+	sum := sha1.Sum([]byte(pass))
+	return user + ":{SHA}" + base64.StdEncoding.EncodeToString(sum[:]) + "\n"
+}
+
+func TestLoadUsers(t *testing.T) {
+	fstest.Initialise()
+	dir, err := ioutil.TempDir("", "rclone-sftp-users")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	f, err := fs.NewFs(context.Background(), dir)
+	require.NoError(t, err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	pub, err := ssh.NewPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	keysPath := writeFile(t, dir, "alice_keys", string(ssh.MarshalAuthorizedKey(pub)))
+	htpasswd := writeFile(t, dir, "htpasswd", htpasswdSHA("bob", "secret"))
+	usersPath := writeFile(t, dir, "users", `
+# comment
+alice  alice  `+keysPath+`
+bob    `+filepath.Join(dir, "elsewhere")+`
+carol  :memory:carol
+`)
+
+	db, err := loadUsers(usersPath, f, htpasswd)
+	require.NoError(t, err)
+	var names []string
+	for name := range db.users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"alice", "bob", "carol"}, names)
+	assert.Equal(t, fs.ConfigString(f)+"/alice", db.users["alice"].root)
+	assert.Equal(t, ":memory:carol", db.users["carol"].root)
+
+	// passwords
+	assert.Equal(t, db.users["bob"], db.checkPassword("bob", []byte("secret")))
+	assert.Nil(t, db.checkPassword("bob", []byte("potato")))
+	assert.Nil(t, db.checkPassword("alice", []byte("secret")))
+	assert.Nil(t, db.checkPassword("nobody", []byte("secret")))
+
+	// public keys are only accepted for their user
+	assert.Equal(t, db.users["alice"], db.checkPublicKey("alice", pub.Marshal()))
+	assert.Nil(t, db.checkPublicKey("bob", pub.Marshal()))
+	assert.Nil(t, db.checkPublicKey("nobody", pub.Marshal()))
+
+	// errors
+	for _, test := range []struct {
+		users string
+		f     fs.Fs
+		want  string
+	}{
+		{users: "alice\n", f: f, want: `:1: expecting "user root [authorized_keys]"`},
+		{users: "alice a b c\n", f: f, want: `:1: expecting "user root [authorized_keys]"`},
+		{users: "alice a\nalice b\n", f: f, want: `:2: duplicate user "alice"`},
+		{users: "a:b root\n", f: f, want: `:1: user name "a:b" must not contain ":"`},
+		{users: "alice alice\n", f: nil, want: `:1: root "alice" for user "alice" needs a remote to be relative to`},
+	} {
+		badPath := writeFile(t, dir, "bad_users", test.users)
+		_, err := loadUsers(badPath, test.f, "")
+		require.Error(t, err, test.users)
+		assert.Contains(t, err.Error(), test.want, test.users)
+	}
+	_, err = loadUsers(filepath.Join(dir, "notfound"), f, "")
+	assert.Error(t, err)
+	_, err = loadUsers(usersPath, f, filepath.Join(dir, "notfound"))
+	assert.Error(t, err)
+}
+
+func TestServeUsers(t *testing.T) {
+	fstest.Initialise()
+	dir, err := ioutil.TempDir("", "rclone-sftp-users")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	root := filepath.Join(dir, "root")
+	writeFile(t, root, "alice/alice.txt", "alice")
+	writeFile(t, root, "bob/bob.txt", "bob")
+	f, err := fs.NewFs(context.Background(), root)
+	require.NoError(t, err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+	keysPath := writeFile(t, dir, "alice_keys", string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+
+	opt := DefaultOpt
+	opt.ListenAddr = testBindAddress
+	opt.UsersFile = writeFile(t, dir, "users", "alice alice "+keysPath+"\nbob bob\n")
+	opt.HtPasswd = writeFile(t, dir, "htpasswd", htpasswdSHA("bob", "secret"))
+	s := newServer(context.Background(), f, &opt)
+	require.NoError(t, s.serve())
+	defer func() {
+		s.Close()
+		s.Wait()
+	}()
+
+	// list returns the names in the root of the user's sftp session
+	list := func(user string, auth ssh.AuthMethod) ([]string, error) {
+		sshClient, err := ssh.Dial("tcp", s.Addr(), &ssh.ClientConfig{
+			User:            user,
+			Auth:            []ssh.AuthMethod{auth},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = sshClient.Close()
+		}()
+		client, err := sftp.NewClient(sshClient)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = client.Close()
+		}()
+		infos, err := client.ReadDir("/")
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		return names, nil
+	}
+
+	names, err := list("alice", ssh.PublicKeys(signer))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice.txt"}, names)
+
+	names, err = list("bob", ssh.Password("secret"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bob.txt"}, names)
+
+	_, err = list("bob", ssh.PublicKeys(signer))
+	assert.Error(t, err)
+	_, err = list("bob", ssh.Password("potato"))
+	assert.Error(t, err)
+	_, err = list("alice", ssh.Password("secret"))
+	assert.Error(t, err)
+}
+
+func TestServeUsersConflictingFlags(t *testing.T) {
+	for _, modify := range []func(opt *Options){
+		func(opt *Options) { opt.User = "user" },
+		func(opt *Options) { opt.AuthorizedKeys = "keys" },
+		func(opt *Options) { opt.NoAuth = true },
+	} {
+		opt := DefaultOpt
+		opt.UsersFile = "users"
+		modify(&opt)
+		s := newServer(context.Background(), nil, &opt)
+		err := s.serve()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "--users-file")
+	}
+	fstest.Initialise()
+	f, err := fs.NewFs(context.Background(), os.TempDir())
+	require.NoError(t, err)
+	opt := DefaultOpt
+	opt.HtPasswd = "htpasswd"
+	s := newServer(context.Background(), f, &opt)
+	assert.EqualError(t, s.serve(), "--htpasswd needs --users-file")
+}
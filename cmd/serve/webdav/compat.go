@@ -0,0 +1,204 @@
+package webdav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/errors"
+	"github.com/rclone/rclone/vfs"
+	"golang.org/x/net/webdav"
+)
+
+// win32Namespace is the XML namespace of the properties Windows
+// Explorer reads and writes
+const win32Namespace = "urn:schemas-microsoft-com:"
+
+// Windows file attributes as used in the Win32FileAttributes property
+const (
+	win32AttributeReadOnly  = 0x01
+	win32AttributeDirectory = 0x10
+	win32AttributeArchive   = 0x20
+)
+
+var win32LastModifiedTime = xml.Name{Space: win32Namespace, Local: "Win32LastModifiedTime"}
+
+// compatHandle is a Handle which also has the properties that
+// Windows Explorer and macOS Finder expect for use with --compat
+type compatHandle struct {
+	Handle
+	vfs *vfs.VFS
+}
+
+// check interface
+var _ webdav.DeadPropsHolder = compatHandle{}
+
+// DeadProps returns the extra properties of the handle
+func (h compatHandle) DeadProps() (map[xml.Name]webdav.Property, error) {
+	props := map[xml.Name]webdav.Property{}
+	add := func(space, local, value string) {
+		name := xml.Name{Space: space, Local: local}
+		props[name] = webdav.Property{XMLName: name, InnerXML: []byte(value)}
+	}
+	node := h.Node()
+	modTime := node.ModTime().UTC().Format(http.TimeFormat)
+	add(win32Namespace, "Win32CreationTime", modTime)
+	add(win32Namespace, "Win32LastAccessTime", modTime)
+	add(win32Namespace, "Win32LastModifiedTime", modTime)
+	attributes := win32AttributeArchive
+	if node.IsDir() {
+		attributes = win32AttributeDirectory
+	}
+	if h.vfs.Opt.ReadOnly {
+		attributes |= win32AttributeReadOnly
+	}
+	add(win32Namespace, "Win32FileAttributes", fmt.Sprintf("%08X", attributes))
+	if node.IsDir() {
+		// RFC 4331 quotas which Finder uses to show the free space
+		_, used, free := h.vfs.Statfs()
+		if free >= 0 {
+			add("DAV:", "quota-available-bytes", strconv.FormatInt(free, 10))
+		}
+		if used >= 0 {
+			add("DAV:", "quota-used-bytes", strconv.FormatInt(used, 10))
+		}
+	}
+	return props, nil
+}
+
+// Patch accepts all the property changes so clients don't give up
+// but only Win32LastModifiedTime is stored, as the modification time.
+func (h compatHandle) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	pstatOK := webdav.Propstat{Status: http.StatusOK}
+	pstatForbidden := webdav.Propstat{Status: http.StatusForbidden}
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			prop := webdav.Property{XMLName: p.XMLName}
+			if patch.Remove || p.XMLName != win32LastModifiedTime {
+				pstatOK.Props = append(pstatOK.Props, prop)
+				continue
+			}
+			node := h.Node()
+			modTime, err := http.ParseTime(strings.TrimSpace(string(p.InnerXML)))
+			if err == nil {
+				err = node.SetModTime(modTime)
+			}
+			if err != nil {
+				fs.Debugf(node.Path(), "Failed to set %s: %v", p.XMLName.Local, err)
+				pstatForbidden.Props = append(pstatForbidden.Props, prop)
+				continue
+			}
+			pstatOK.Props = append(pstatOK.Props, prop)
+		}
+	}
+	var pstats []webdav.Propstat
+	for _, pstat := range []webdav.Propstat{pstatOK, pstatForbidden} {
+		if len(pstat.Props) > 0 {
+			pstats = append(pstats, pstat)
+		}
+	}
+	return pstats, nil
+}
+
+// bufferedResponseWriter holds the response so it can be sent with a
+// Content-Length as the Windows WebDAV client doesn't cope well with
+// chunked multistatus responses
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+// WriteHeader saves the status until flush is called
+func (bw *bufferedResponseWriter) WriteHeader(status int) {
+	if bw.status == 0 {
+		bw.status = status
+	}
+}
+
+// Write saves the data until flush is called
+func (bw *bufferedResponseWriter) Write(p []byte) (int, error) {
+	bw.WriteHeader(http.StatusOK)
+	return bw.buf.Write(p)
+}
+
+// flush sends the response
+func (bw *bufferedResponseWriter) flush() {
+	bw.WriteHeader(http.StatusOK)
+	bw.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(bw.buf.Len()))
+	bw.ResponseWriter.WriteHeader(bw.status)
+	_, err := bw.buf.WriteTo(bw.ResponseWriter)
+	if err != nil {
+		fs.Debugf(nil, "Failed to write response: %v", err)
+	}
+}
+
+// expectedLengthReader checks a body is as long as promised in the
+// X-Expected-Entity-Length header which Finder sends instead of a
+// Content-Length with its chunked uploads
+type expectedLengthReader struct {
+	io.ReadCloser
+	remaining int64
+	err       error // set if the body was the wrong length
+}
+
+// Read reads from the body returning an error if it is the wrong length
+func (er *expectedLengthReader) Read(p []byte) (n int, err error) {
+	n, err = er.ReadCloser.Read(p)
+	er.remaining -= int64(n)
+	if er.remaining < 0 {
+		er.err = errors.New("body longer than X-Expected-Entity-Length")
+	} else if err == io.EOF && er.remaining > 0 {
+		er.err = io.ErrUnexpectedEOF
+	}
+	if er.err != nil {
+		return n, er.err
+	}
+	return n, err
+}
+
+// expectedLength returns a reader checking the length of the body of
+// r if the client sent X-Expected-Entity-Length or nil if not
+func expectedLength(r *http.Request) *expectedLengthReader {
+	if r.ContentLength >= 0 {
+		return nil
+	}
+	expected, err := strconv.ParseInt(r.Header.Get("X-Expected-Entity-Length"), 10, 64)
+	if err != nil || expected < 0 {
+		return nil
+	}
+	return &expectedLengthReader{ReadCloser: r.Body, remaining: expected}
+}
+
+// limitLockTimeout returns the Timeout header of a LOCK request with
+// the timeout limited to maxTimeout so the locks of clients which go
+// away expire
+func limitLockTimeout(header string, maxTimeout time.Duration) string {
+	if maxTimeout <= 0 {
+		return header
+	}
+	limited := "Second-" + strconv.FormatInt(int64(maxTimeout/time.Second), 10)
+	// only the first timeout is used
+	first := strings.TrimSpace(strings.Split(header, ",")[0])
+	if first == "" || first == "Infinite" {
+		return limited
+	}
+	if !strings.HasPrefix(first, "Second-") {
+		return header
+	}
+	seconds, err := strconv.ParseInt(first[len("Second-"):], 10, 64)
+	if err != nil {
+		// let the webdav handler report the error
+		return header
+	}
+	if seconds > int64(maxTimeout/time.Second) {
+		return limited
+	}
+	return header
+}
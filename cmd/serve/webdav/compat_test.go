@@ -0,0 +1,172 @@
+package webdav
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/cmd/serve/httplib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitLockTimeout(t *testing.T) {
+	for _, test := range []struct {
+		header string
+		max    time.Duration
+		want   string
+	}{
+		{"", time.Hour, "Second-3600"},
+		{"Infinite", time.Hour, "Second-3600"},
+		{"Infinite, Second-4100000000", time.Hour, "Second-3600"},
+		{"Second-600", time.Hour, "Second-600"},
+		{"Second-7200", time.Hour, "Second-3600"},
+		{"Second-99999999999999999999", time.Hour, "Second-99999999999999999999"},
+		{"Potato", time.Hour, "Potato"},
+		{"Infinite", 0, "Infinite"},
+		{"", 0, ""},
+	} {
+		assert.Equal(t, test.want, limitLockTimeout(test.header, test.max), test.header)
+	}
+}
+
+func TestExpectedLength(t *testing.T) {
+	newRequest := func(body string, expected string) *http.Request {
+		r, err := http.NewRequest("PUT", "http://example.com/file", ioutil.NopCloser(strings.NewReader(body)))
+		require.NoError(t, err)
+		r.ContentLength = -1
+		if expected != "" {
+			r.Header.Set("X-Expected-Entity-Length", expected)
+		}
+		return r
+	}
+	assert.Nil(t, expectedLength(newRequest("hello", "")))
+	assert.Nil(t, expectedLength(newRequest("hello", "potato")))
+
+	er := expectedLength(newRequest("hello", "5"))
+	require.NotNil(t, er)
+	data, err := ioutil.ReadAll(er)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.NoError(t, er.err)
+
+	er = expectedLength(newRequest("hel", "5"))
+	_, err = ioutil.ReadAll(er)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	er = expectedLength(newRequest("hello world", "5"))
+	_, err = ioutil.ReadAll(er)
+	assert.Error(t, err)
+}
+
+func TestCompat(t *testing.T) {
+	fstest.Initialise()
+	oldCompat := compat
+	compat = true
+	defer func() {
+		compat = oldCompat
+	}()
+	dir, err := ioutil.TempDir("", "rclone-webdav-compat")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello"), 0666))
+	f, err := fs.NewFs(context.Background(), dir)
+	require.NoError(t, err)
+
+	opt := httplib.DefaultOpt
+	opt.ListenAddr = testBindAddress
+	w := newWebDAV(context.Background(), f, &opt)
+	require.NoError(t, w.serve())
+	defer func() {
+		w.Close()
+		w.Wait()
+	}()
+	testURL := w.Server.URL()
+
+	do := func(method, path, body string, headers map[string]string) (*http.Response, string) {
+		req, err := http.NewRequest(method, testURL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp, string(data)
+	}
+
+	// allprop includes the Windows and Finder properties
+	resp, body := do("PROPFIND", "", `<?xml version="1.0"?><D:propfind xmlns:D="DAV:"><D:allprop/></D:propfind>`, map[string]string{"Depth": "1"})
+	assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+	assert.NotEqual(t, int64(-1), resp.ContentLength)
+	assert.Contains(t, body, "Win32FileAttributes")
+	assert.Contains(t, body, ">00000010<")
+	assert.Contains(t, body, ">00000020<")
+	assert.Contains(t, body, "quota-available-bytes")
+
+	// Windows setting the modification time
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	resp, body = do("PROPPATCH", "file.txt", `<?xml version="1.0"?>
+<D:propertyupdate xmlns:D="DAV:" xmlns:Z="urn:schemas-microsoft-com:"><D:set><D:prop>
+<Z:Win32LastModifiedTime>`+modTime.Format(http.TimeFormat)+`</Z:Win32LastModifiedTime>
+<Z:Win32FileAttributes>00000020</Z:Win32FileAttributes>
+</D:prop></D:set></D:propertyupdate>`, nil)
+	assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+	assert.Contains(t, body, "200 OK")
+	assert.NotContains(t, body, "403")
+	fi, err := os.Stat(filepath.Join(dir, "file.txt"))
+	require.NoError(t, err)
+	assert.True(t, modTime.Equal(fi.ModTime()), fi.ModTime())
+	// and the file wasn't truncated by the patch
+	assert.Equal(t, int64(5), fi.Size())
+
+	// Infinite locks are limited
+	resp, body = do("LOCK", "file.txt", `<?xml version="1.0"?>
+<D:lockinfo xmlns:D="DAV:"><D:lockscope><D:exclusive/></D:lockscope><D:locktype><D:write/></D:locktype></D:lockinfo>`, map[string]string{"Timeout": "Infinite"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "Second-3600")
+	token := resp.Header.Get("Lock-Token")
+	require.NotEqual(t, "", token)
+	resp, _ = do("PUT", "file.txt", "potato", nil)
+	assert.Equal(t, http.StatusLocked, resp.StatusCode)
+	resp, _ = do("UNLOCK", "file.txt", "", map[string]string{"Lock-Token": token})
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// Finder uploads which are cut short are removed
+	req, err := http.NewRequest("PUT", testURL+"short.txt", ioutil.NopCloser(strings.NewReader("hel")))
+	require.NoError(t, err)
+	req.ContentLength = -1
+	req.Header.Set("X-Expected-Entity-Length", "5")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.NotEqual(t, http.StatusCreated, resp.StatusCode)
+	_, err = os.Stat(filepath.Join(dir, "short.txt"))
+	assert.True(t, os.IsNotExist(err), err)
+
+	// and complete ones are kept
+	req, err = http.NewRequest("PUT", testURL+"full.txt", ioutil.NopCloser(strings.NewReader("hello")))
+	require.NoError(t, err)
+	req.ContentLength = -1
+	req.Header.Set("X-Expected-Entity-Length", "5")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "full.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+}
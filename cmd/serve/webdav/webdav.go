@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rclone/rclone/cmd"
@@ -25,9 +26,11 @@ import (
 )
 
 var (
-	hashName      string
-	hashType      = hash.None
-	disableGETDir = false
+	hashName       string
+	hashType       = hash.None
+	disableGETDir  = false
+	compat         = false
+	maxLockTimeout = time.Hour
 )
 
 func init() {
@@ -37,6 +40,8 @@ func init() {
 	proxyflags.AddFlags(flagSet)
 	flags.StringVarP(flagSet, &hashName, "etag-hash", "", "", "Which hash to use for the ETag, or auto or blank for off")
 	flags.BoolVarP(flagSet, &disableGETDir, "disable-dir-list", "", false, "Disable HTML directory list on GET request for a directory")
+	flags.BoolVarP(flagSet, &compat, "compat", "", false, "Work around the quirks of the Windows Explorer and macOS Finder clients")
+	flags.DurationVarP(flagSet, &maxLockTimeout, "max-lock-timeout", "", maxLockTimeout, "Maximum time a lock is held without being refreshed, 0 for unlimited")
 }
 
 // Command definition for cobra
@@ -62,6 +67,39 @@ supported hash on the backend or you can use a named hash such as
 
 Use "rclone hashsum" to see the full list.
 
+#### --compat
+
+Windows Explorer ("Map network drive") and macOS Finder ("Connect to
+Server") are fussy WebDAV clients.  Set this flag to work around their
+quirks so they can mount the server read-write.  It
+
+- returns the Win32 properties Windows Explorer asks for and accepts
+  its PROPPATCH requests, setting the modification time from
+  Win32LastModifiedTime and ignoring the other properties
+- returns the quota-available-bytes and quota-used-bytes properties
+  Finder uses to show the free space
+- sends PROPFIND, PROPPATCH and LOCK responses with a Content-Length
+  rather than chunked
+- checks Finder's chunked uploads against their
+  X-Expected-Entity-Length header and removes uploads which are cut
+  short rather than leaving a truncated file
+
+Note that Windows only allows basic authentication over https by
+default.
+
+#### Locking
+
+The server supports class 2 WebDAV locking (LOCK and UNLOCK) which
+Finder needs to mount read-write and which stops clients overwriting
+each other's changes.  Locks are held in memory so are lost when the
+server restarts.  When using --auth-proxy each user has their own
+locks.
+
+Clients which go away without unlocking would keep locks, in
+particular "Infinite" ones, forever, so locks expire if they aren't
+refreshed within --max-lock-timeout (default 1h).  Set it to 0 to
+allow any timeout the client asks for.
+
 ` + httplib.Help + vfs.Help + proxy.Help,
 	RunE: func(command *cobra.Command, args []string) error {
 		var f fs.Fs
@@ -115,6 +153,9 @@ type WebDAV struct {
 	webdavhandler *webdav.Handler
 	proxy         *proxy.Proxy
 	ctx           context.Context // for global config
+
+	lockSystemsMu sync.Mutex
+	lockSystems   map[string]webdav.LockSystem // per user lock systems for the proxy
 }
 
 // check interface
@@ -123,8 +164,9 @@ var _ webdav.FileSystem = (*WebDAV)(nil)
 // Make a new WebDAV to serve the remote
 func newWebDAV(ctx context.Context, f fs.Fs, opt *httplib.Options) *WebDAV {
 	w := &WebDAV{
-		f:   f,
-		ctx: ctx,
+		f:           f,
+		ctx:         ctx,
+		lockSystems: map[string]webdav.LockSystem{},
 	}
 	if proxyflags.Opt.AuthProxy != "" {
 		w.proxy = proxy.New(ctx, &proxyflags.Opt)
@@ -146,6 +188,27 @@ func newWebDAV(ctx context.Context, f fs.Fs, opt *httplib.Options) *WebDAV {
 	return w
 }
 
+// getHandler returns the webdav handler for this request
+//
+// When using the proxy each user gets their own locks as they may
+// each see different files under the same names.
+func (w *WebDAV) getHandler(r *http.Request) *webdav.Handler {
+	if w.proxy == nil {
+		return w.webdavhandler
+	}
+	user, _ := r.Context().Value(httplib.ContextUserKey).(string)
+	w.lockSystemsMu.Lock()
+	lockSystem, ok := w.lockSystems[user]
+	if !ok {
+		lockSystem = webdav.NewMemLS()
+		w.lockSystems[user] = lockSystem
+	}
+	w.lockSystemsMu.Unlock()
+	handler := *w.webdavhandler
+	handler.LockSystem = lockSystem
+	return &handler
+}
+
 // Gets the VFS in use for this request
 func (w *WebDAV) getVFS(ctx context.Context) (VFS *vfs.VFS, err error) {
 	if w._vfs != nil {
@@ -182,7 +245,38 @@ func (w *WebDAV) handler(rw http.ResponseWriter, r *http.Request) {
 		w.serveDir(rw, r, remote)
 		return
 	}
-	w.webdavhandler.ServeHTTP(rw, r)
+	if r.Method == "LOCK" {
+		if timeout := limitLockTimeout(r.Header.Get("Timeout"), maxLockTimeout); timeout != "" {
+			r.Header.Set("Timeout", timeout)
+		}
+	}
+	handler := w.getHandler(r)
+	if !compat {
+		handler.ServeHTTP(rw, r)
+		return
+	}
+	switch r.Method {
+	case "PROPFIND", "PROPPATCH", "LOCK":
+		bw := &bufferedResponseWriter{ResponseWriter: rw}
+		handler.ServeHTTP(bw, r)
+		bw.flush()
+	case "PUT":
+		er := expectedLength(r)
+		if er == nil {
+			handler.ServeHTTP(rw, r)
+			return
+		}
+		r.Body = er
+		handler.ServeHTTP(rw, r)
+		if er.err != nil {
+			fs.Errorf(remote, "Removing incomplete upload: %v", er.err)
+			if err := w.RemoveAll(r.Context(), remote); err != nil {
+				fs.Errorf(remote, "Failed to remove incomplete upload: %v", err)
+			}
+		}
+	default:
+		handler.ServeHTTP(rw, r)
+	}
 }
 
 // serveDir serves a directory index at dirRemote
@@ -271,10 +365,18 @@ func (w *WebDAV) OpenFile(ctx context.Context, name string, flags int, perm os.F
 	if err != nil {
 		return nil, err
 	}
+	// The webdav handler only opens with exactly O_RDWR to patch
+	// properties so open read only to avoid truncating the file.
+	if flags == os.O_RDWR {
+		flags = os.O_RDONLY
+	}
 	f, err := VFS.OpenFile(name, flags, perm)
 	if err != nil {
 		return nil, err
 	}
+	if compat {
+		return compatHandle{Handle: Handle{f}, vfs: VFS}, nil
+	}
 	return Handle{f}, nil
 }
 
@@ -2,15 +2,23 @@ package settier
 
 import (
 	"context"
+	"encoding/json"
+	"os"
+	"sort"
 
-	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/spf13/cobra"
 )
 
+var jsonOutput bool
+
 func init() {
 	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.BoolVarP(cmdFlags, &jsonOutput, "json", "", false, "Write the number of objects in each tier to stdout as JSON")
 }
 
 var commandDefinition = &cobra.Command{
@@ -25,7 +33,7 @@ Google Cloud Storage, Regional Storage, Nearline, Coldline etc.
 Note that, certain tier changes make objects not available to access immediately.
 For example tiering to archive in azure blob storage makes objects in frozen state,
 user can restore by setting tier to Hot/Cool, similarly S3 to Glacier makes object
-inaccessible.true
+inaccessible.
 
 You can use it to tier single object
 
@@ -35,9 +43,20 @@ Or use rclone filters to set tier on only specific files
 
 	rclone --include "*.txt" settier Hot remote:path/dir
 
-Or just provide remote directory and all files in directory will be tiered
+Or just provide remote directory and all files in directory and its
+subdirectories will be tiered
 
     rclone settier tier remote:path/dir
+
+Objects already in the tier are left alone. Combined with the filters
+this can be used to move old objects to a colder tier, for example to
+move everything older than 90 days to Glacier on S3
+
+    rclone settier --min-age 90d GLACIER s3:bucket/path
+
+It changes --checkers objects at once and obeys --dry-run. When it is
+finished it logs the number of objects and bytes in each tier before
+and after, or writes them to stdout with --json.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(2, 2, command, args)
@@ -45,12 +64,40 @@ Or just provide remote directory and all files in directory will be tiered
 		input := args[1:]
 		fsrc := cmd.NewFsSrc(input)
 		cmd.Run(false, false, command, func() error {
-			isSupported := fsrc.Features().SetTier
-			if !isSupported {
-				return errors.Errorf("Remote %s does not support settier", fsrc.Name())
+			result, err := operations.SetTier(context.Background(), fsrc, tier)
+			if result != nil {
+				if jsonOutput {
+					out := json.NewEncoder(os.Stdout)
+					out.SetIndent("", "\t")
+					if jsonErr := out.Encode(result); err == nil {
+						err = jsonErr
+					}
+				} else {
+					logResult(result)
+				}
 			}
-
-			return operations.SetTier(context.Background(), fsrc, tier)
+			return err
 		})
 	},
 }
+
+// logResult logs the objects in each tier
+func logResult(result *operations.SetTierResult) {
+	logTiers := func(when string, tiers map[string]*operations.TierStats) {
+		var names []string
+		for name := range tiers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			ts := tiers[name]
+			if name == "" {
+				name = "unknown"
+			}
+			fs.Logf(nil, "%-6s %-20s %8d objects %v", when, name, ts.Count, fs.SizeSuffix(ts.Bytes).ByteUnit())
+		}
+	}
+	logTiers("Before", result.Before)
+	logTiers("After", result.After)
+	fs.Logf(nil, "Changed %d objects %v, %d errors", result.Changed.Count, fs.SizeSuffix(result.Changed.Bytes).ByteUnit(), result.Errors.Count)
+}
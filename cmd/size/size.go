@@ -4,7 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/bits"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/fs"
@@ -13,28 +17,45 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var jsonOutput bool
+var (
+	jsonOutput   bool
+	perDirectory bool
+	histogram    bool
+)
 
 func init() {
 	cmd.Root.AddCommand(commandDefinition)
 	cmdFlags := commandDefinition.Flags()
 	flags.BoolVarP(cmdFlags, &jsonOutput, "json", "", false, "format output as JSON")
+	flags.BoolVarP(cmdFlags, &perDirectory, "per-directory", "", false, "Show the totals for each top level directory too")
+	flags.BoolVarP(cmdFlags, &histogram, "histogram", "", false, "Show a histogram of the object sizes")
 }
 
 var commandDefinition = &cobra.Command{
 	Use:   "size remote:path",
 	Short: `Prints the total size and number of objects in remote:path.`,
+	Long: `
+Prints the total size and number of objects in remote:path.
+
+If --per-directory is given then the totals for each top level
+directory are shown as well. Objects in the root of remote:path are
+counted under the directory "" (shown as "." in the text output).
+
+If --histogram is given then the objects are counted in buckets by
+size, each bucket twice the size of the one before. Objects with an
+unknown size are counted in the totals but not in the histogram.
+
+These are useful for planning the capacity needed before migrating
+remote:path. With --json all the results are written as JSON, for
+example
+
+    rclone size --json --per-directory --histogram remote:path
+`,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc := cmd.NewFsSrc(args)
 		cmd.Run(false, false, command, func() error {
-			var err error
-			var results struct {
-				Count int64 `json:"count"`
-				Bytes int64 `json:"bytes"`
-			}
-
-			results.Count, results.Bytes, err = operations.Count(context.Background(), fsrc)
+			results, err := count(context.Background(), fsrc)
 			if err != nil {
 				return err
 			}
@@ -45,8 +66,139 @@ var commandDefinition = &cobra.Command{
 
 			fmt.Printf("Total objects: %d\n", results.Count)
 			fmt.Printf("Total size: %s (%d bytes)\n", fs.SizeSuffix(results.Bytes).ByteUnit(), results.Bytes)
+			if results.Directories != nil {
+				fmt.Printf("\nDirectories:\n")
+				for _, dir := range results.Directories {
+					name := dir.Name
+					if name == "" {
+						name = "."
+					}
+					fmt.Printf("%10d %16s %s\n", dir.Count, fs.SizeSuffix(dir.Bytes).ByteUnit(), name)
+				}
+			}
+			if results.Histogram != nil {
+				fmt.Printf("\nHistogram:\n")
+				for _, bucket := range results.Histogram {
+					fmt.Printf(">= %-12s %10d %16s\n", fs.SizeSuffix(bucket.Min).ByteUnit(), bucket.Count, fs.SizeSuffix(bucket.Bytes).ByteUnit())
+				}
+			}
 
 			return nil
 		})
 	},
 }
+
+// Directory is the totals for one top level directory
+type Directory struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+	Bytes int64  `json:"bytes"`
+}
+
+// Bucket is the number of objects with sizes from Min to Max inclusive
+type Bucket struct {
+	Min   int64 `json:"min"`
+	Max   int64 `json:"max"`
+	Count int64 `json:"count"`
+	Bytes int64 `json:"bytes"`
+}
+
+// Results is the output of the size command
+type Results struct {
+	Count       int64       `json:"count"`
+	Bytes       int64       `json:"bytes"`
+	Directories []Directory `json:"directories,omitempty"`
+	Histogram   []Bucket    `json:"histogram,omitempty"`
+}
+
+// tally accumulates the Results
+type tally struct {
+	mu        sync.Mutex
+	results   Results
+	dirs      map[string]*Directory // nil unless counting per directory
+	histogram bool
+	buckets   [65]Bucket // bucket i holds sizes with bit length i
+}
+
+// newTally makes a tally counting per directory and making a
+// histogram as requested
+func newTally(perDirectory, histogram bool) *tally {
+	t := &tally{histogram: histogram}
+	if perDirectory {
+		t.dirs = map[string]*Directory{}
+	}
+	return t
+}
+
+// bucketRange returns the smallest and largest sizes in bucket i
+func bucketRange(i int) (min, max int64) {
+	if i == 0 {
+		return 0, 0
+	}
+	return 1 << (i - 1), 1<<i - 1
+}
+
+// add o to the tally
+func (t *tally) add(o fs.Object) {
+	size := o.Size()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.results.Count++
+	if size > 0 {
+		t.results.Bytes += size
+	}
+	if t.dirs != nil {
+		name := ""
+		if i := strings.IndexRune(o.Remote(), '/'); i >= 0 {
+			name = o.Remote()[:i]
+		}
+		dir := t.dirs[name]
+		if dir == nil {
+			dir = &Directory{Name: name}
+			t.dirs[name] = dir
+		}
+		dir.Count++
+		if size > 0 {
+			dir.Bytes += size
+		}
+	}
+	if t.histogram && size >= 0 {
+		bucket := &t.buckets[bits.Len64(uint64(size))]
+		bucket.Count++
+		bucket.Bytes += size
+	}
+}
+
+// finish returns the Results
+func (t *tally) finish() *Results {
+	if t.dirs != nil {
+		t.results.Directories = []Directory{}
+		for _, dir := range t.dirs {
+			t.results.Directories = append(t.results.Directories, *dir)
+		}
+		sort.Slice(t.results.Directories, func(i, j int) bool {
+			return t.results.Directories[i].Name < t.results.Directories[j].Name
+		})
+	}
+	if t.histogram {
+		t.results.Histogram = []Bucket{}
+		for i, bucket := range t.buckets {
+			if bucket.Count == 0 {
+				continue
+			}
+			bucket.Min, bucket.Max = bucketRange(i)
+			t.results.Histogram = append(t.results.Histogram, bucket)
+		}
+	}
+	return &t.results
+}
+
+// count the objects in f
+func count(ctx context.Context, f fs.Fs) (*Results, error) {
+	t := newTally(perDirectory, histogram)
+	err := operations.ListFn(ctx, f, t.add)
+	if err != nil {
+		return nil, err
+	}
+	return t.finish(), nil
+}
@@ -0,0 +1,63 @@
+package size
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketRange(t *testing.T) {
+	for _, test := range []struct {
+		i        int
+		min, max int64
+	}{
+		{0, 0, 0},
+		{1, 1, 1},
+		{2, 2, 3},
+		{11, 1024, 2047},
+		{63, 1 << 62, 1<<63 - 1},
+	} {
+		min, max := bucketRange(test.i)
+		assert.Equal(t, test.min, min, test.i)
+		assert.Equal(t, test.max, max, test.i)
+	}
+}
+
+func TestTally(t *testing.T) {
+	tl := newTally(true, true)
+	for _, o := range []struct {
+		remote  string
+		size    int
+		unknown bool
+	}{
+		{"root.txt", 0, false},
+		{"a/one.txt", 1, false},
+		{"a/b/three.txt", 3, false},
+		{"b/thousand.txt", 1000, false},
+		{"b/unknown.txt", 10, true},
+	} {
+		obj := mockobject.New(o.remote).WithContent(make([]byte, o.size), mockobject.SeekModeNone)
+		obj.SetUnknownSize(o.unknown)
+		tl.add(obj)
+	}
+	results := tl.finish()
+	assert.Equal(t, int64(5), results.Count)
+	assert.Equal(t, int64(1004), results.Bytes)
+	assert.Equal(t, []Directory{
+		{Name: "", Count: 1, Bytes: 0},
+		{Name: "a", Count: 2, Bytes: 4},
+		{Name: "b", Count: 2, Bytes: 1000},
+	}, results.Directories)
+	assert.Equal(t, []Bucket{
+		{Min: 0, Max: 0, Count: 1, Bytes: 0},
+		{Min: 1, Max: 1, Count: 1, Bytes: 1},
+		{Min: 2, Max: 3, Count: 1, Bytes: 3},
+		{Min: 512, Max: 1023, Count: 1, Bytes: 1000},
+	}, results.Histogram)
+
+	plain := newTally(false, false)
+	plain.add(mockobject.New("a/file.txt").WithContent([]byte("hello"), mockobject.SeekModeNone))
+	results = plain.finish()
+	assert.Equal(t, &Results{Count: 1, Bytes: 5}, results)
+}
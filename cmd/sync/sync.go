@@ -3,6 +3,7 @@ package sync
 import (
 	"context"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/fs/config/flags"
 	"github.com/rclone/rclone/fs/operations"
@@ -12,16 +13,24 @@ import (
 
 var (
 	createEmptySrcDirs = false
+	journal            = ""
+	resumeJournal      = ""
+	rollback           = false
+	mergePolicy        = "first"
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefinition)
 	cmdFlags := commandDefinition.Flags()
 	flags.BoolVarP(cmdFlags, &createEmptySrcDirs, "create-empty-src-dirs", "", createEmptySrcDirs, "Create empty source dirs on destination after sync")
+	flags.StringVarP(cmdFlags, &journal, "journal", "", journal, "Write a journal of the changes made to the destination to this file")
+	flags.StringVarP(cmdFlags, &resumeJournal, "resume-journal", "", resumeJournal, "Continue the interrupted sync recorded in this journal")
+	flags.BoolVarP(cmdFlags, &rollback, "rollback", "", rollback, "With --resume-journal undo the changes of the interrupted sync instead")
+	flags.StringVarP(cmdFlags, &mergePolicy, "merge-policy", "", mergePolicy, "Which file to sync if it is in more than one source: first|newest")
 }
 
 var commandDefinition = &cobra.Command{
-	Use:   "sync source:path dest:path",
+	Use:   "sync source:path [source:path...] dest:path",
 	Short: `Make source and dest identical, modifying destination only.`,
 	Long: `
 Sync the source to the destination, changing the destination
@@ -49,16 +58,58 @@ go there.
 
 **Note**: Use the ` + "`-P`" + `/` + "`--progress`" + ` flag to view real-time transfer statistics
 
+**Multiple sources**: If more than one source is given then the
+destination is made identical to all of them merged together, for
+example
+
+    rclone sync old1: old2: old3: new:
+
+If a file is in more than one source the copy from the first source it
+is in is used, or use ` + "`--merge-policy newest`" + ` to use the one with
+the newest modification time. See the ` + "`copy`" + ` command for more.
+
+**Journal**: Use the ` + "`--journal FILE`" + ` flag to record each
+change to the destination in FILE before it is made.  If the sync is
+interrupted, for example by a crash or a power cut, then
+
+    rclone sync --resume-journal FILE
+
+runs the same sync again to complete it, and
+
+    rclone sync --resume-journal FILE --rollback
+
+undoes the changes the interrupted sync made instead.  Files which
+were created are deleted and files which were overwritten or deleted
+are moved back from the ` + "`--backup-dir`" + ` if one was in use, so use
+` + "`--backup-dir`" + ` if you need to be able to roll back completely.  Use
+the same flags when resuming as for the original sync.
+
 **Note**: Use the ` + "`rclone dedupe`" + ` command to deal with "Duplicate object/directory found in source/destination - ignoring" errors.
 See [this forum post](https://forum.rclone.org/t/sync-not-clearing-duplicates/14372) for more info.
 `,
 	Run: func(command *cobra.Command, args []string) {
-		cmd.CheckArgs(2, 2, command, args)
-		fsrc, srcFileName, fdst := cmd.NewFsSrcFileDst(args)
+		if resumeJournal != "" {
+			cmd.CheckArgs(0, 0, command, args)
+			cmd.Run(true, true, command, func() error {
+				return sync.ResumeJournal(context.Background(), resumeJournal, rollback)
+			})
+			return
+		}
+		cmd.CheckArgs(2, 256, command, args)
+		fsrc, srcFileName, fdst := cmd.NewFsSrcsFileDst(args, mergePolicy)
 		cmd.Run(true, true, command, func() error {
+			if rollback {
+				return errors.New("can't use --rollback without --resume-journal")
+			}
 			if srcFileName == "" {
+				if journal != "" {
+					return sync.SyncJournal(context.Background(), fdst, fsrc, createEmptySrcDirs, journal)
+				}
 				return sync.Sync(context.Background(), fdst, fsrc, createEmptySrcDirs)
 			}
+			if journal != "" {
+				return errors.New("can't use --journal when syncing a single file")
+			}
 			return operations.CopyFile(context.Background(), fdst, fsrc, srcFileName, srcFileName)
 		})
 	},
@@ -0,0 +1,297 @@
+// Package bench measures the upload, download, listing and delete
+// performance of a remote.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/cmd/test"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	// Flags
+	numberOfFiles = 10
+	fileSize      = fs.SizeSuffix(10 * 1024 * 1024)
+	seed          = int64(1)
+	jsonOutput    = false
+	keep          = false
+)
+
+func init() {
+	test.Command.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.IntVarP(cmdFlags, &numberOfFiles, "files", "", numberOfFiles, "Number of files to upload and download")
+	flags.FVarP(cmdFlags, &fileSize, "size", "", "Size of each file")
+	flags.Int64VarP(cmdFlags, &seed, "seed", "", seed, "Seed for the random file contents")
+	flags.BoolVarP(cmdFlags, &jsonOutput, "json", "", jsonOutput, "Write the report as JSON to stdout")
+	flags.BoolVarP(cmdFlags, &keep, "keep", "", keep, "Don't delete the test files at the end")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "bench remote:path",
+	Short: `Benchmark uploads, downloads, listings and deletes on remote:path.`,
+	Long: `This uploads --files files of --size bytes each to a new directory
+in remote:path, lists them, downloads them and then deletes them,
+timing each part.
+
+--transfers files are uploaded and downloaded at once.
+
+The file contents are random but depend only on --seed so runs with
+the same flags are repeatable. They aren't compressible so remotes
+which compress data don't distort the results.
+
+The report includes the rclone version and the flags used and can be
+written as JSON with --json which makes it suitable for attaching to
+bug reports, for example
+
+    rclone test bench --files 20 --size 100M --transfers 8 --json remote:bench > report.json
+
+**NB** This makes real transfers so may cost money on remotes which
+charge for them.
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(1, 1, command, args)
+		fsInfo, _, _, _, err := fs.ParseRemote(args[0])
+		if err != nil {
+			return err
+		}
+		f := cmd.NewFsSrc(args)
+		ctx := context.Background()
+		r, err := run(ctx, f)
+		if err != nil {
+			return err
+		}
+		r.Backend = fsInfo.Name
+		if jsonOutput {
+			out := json.NewEncoder(os.Stdout)
+			out.SetIndent("", "\t")
+			return out.Encode(r)
+		}
+		r.log()
+		return nil
+	},
+}
+
+// Result is the timing of one part of the benchmark
+type Result struct {
+	Duration      time.Duration `json:"duration"`
+	Seconds       float64       `json:"seconds"`
+	BytesPerSec   int64         `json:"bytesPerSecond,omitempty"`
+	FilesPerSec   float64       `json:"filesPerSecond"`
+	Files         int           `json:"files"`
+	Bytes         int64         `json:"bytes,omitempty"`
+	FirstByteTime time.Duration `json:"firstByteTime,omitempty"` // average time to the first byte read
+}
+
+// newResult makes a Result for files and bytes done in dt
+func newResult(dt time.Duration, files int, bytes int64) Result {
+	r := Result{
+		Duration: dt,
+		Seconds:  dt.Seconds(),
+		Files:    files,
+		Bytes:    bytes,
+	}
+	if dt > 0 {
+		r.FilesPerSec = float64(files) / dt.Seconds()
+		r.BytesPerSec = int64(float64(bytes) / dt.Seconds())
+	}
+	return r
+}
+
+// Report is the result of the benchmark
+type Report struct {
+	Version   string    `json:"version"`
+	GoVersion string    `json:"goVersion"`
+	OS        string    `json:"os"`
+	Arch      string    `json:"arch"`
+	Backend   string    `json:"backend"`
+	Remote    string    `json:"remote"`
+	Start     time.Time `json:"start"`
+	Files     int       `json:"files"`
+	Size      int64     `json:"size"`
+	Transfers int       `json:"transfers"`
+	Seed      int64     `json:"seed"`
+	Upload    Result    `json:"upload"`
+	List      Result    `json:"list"`
+	Download  Result    `json:"download"`
+	Delete    *Result   `json:"delete,omitempty"`
+}
+
+// log writes the report to the log
+func (r *Report) log() {
+	fs.Logf(nil, "rclone %s (%s %s/%s) benchmark of %s (%s)", r.Version, r.GoVersion, r.OS, r.Arch, r.Remote, r.Backend)
+	fs.Logf(nil, "%d files of %v with %d transfers", r.Files, fs.SizeSuffix(r.Size).ByteUnit(), r.Transfers)
+	logResult := func(name string, res Result) {
+		if res.Bytes > 0 {
+			fs.Logf(nil, "%-8s %10v %10.2f files/s %v", name, res.Duration.Round(time.Millisecond), res.FilesPerSec, fs.SizeSuffix(res.BytesPerSec).ByteRateUnit())
+		} else {
+			fs.Logf(nil, "%-8s %10v %10.2f files/s", name, res.Duration.Round(time.Millisecond), res.FilesPerSec)
+		}
+	}
+	logResult("Upload", r.Upload)
+	logResult("List", r.List)
+	logResult("Download", r.Download)
+	if r.Download.FirstByteTime > 0 {
+		fs.Logf(nil, "%-8s %10v average time to first byte", "", r.Download.FirstByteTime.Round(time.Millisecond))
+	}
+	if r.Delete != nil {
+		logResult("Delete", *r.Delete)
+	}
+}
+
+// fileReader returns the contents of file i
+func fileReader(i int) io.Reader {
+	return io.LimitReader(rand.New(rand.NewSource(seed+int64(i))), int64(fileSize))
+}
+
+// run the benchmark on f
+func run(ctx context.Context, f fs.Fs) (r *Report, err error) {
+	ci := fs.GetConfig(ctx)
+	if numberOfFiles <= 0 {
+		return nil, errors.New("--files must be at least 1")
+	}
+	r = &Report{
+		Version:   fs.Version,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Remote:    fs.ConfigString(f),
+		Start:     time.Now(),
+		Files:     numberOfFiles,
+		Size:      int64(fileSize),
+		Transfers: ci.Transfers,
+		Seed:      seed,
+	}
+	dir := "rclone-bench-" + random.String(8)
+	fs.Infof(f, "Benchmarking in %q", dir)
+	defer func() {
+		if keep {
+			return
+		}
+		start := time.Now()
+		purgeErr := operations.Purge(ctx, f, dir)
+		if purgeErr != nil {
+			fs.Errorf(f, "Failed to remove %q: %v", dir, purgeErr)
+			if err == nil {
+				err = purgeErr
+			}
+			return
+		}
+		res := newResult(time.Since(start), numberOfFiles, 0)
+		r.Delete = &res
+	}()
+	remote := func(i int) string {
+		return path.Join(dir, fmt.Sprintf("file%06d", i))
+	}
+
+	// forEach runs fn for each file with --transfers at once
+	forEach := func(fn func(i int) error) error {
+		g, gCtx := errgroup.WithContext(ctx)
+		next := int32(-1)
+		for t := 0; t < ci.Transfers; t++ {
+			g.Go(func() error {
+				for {
+					i := int(atomic.AddInt32(&next, 1))
+					if i >= numberOfFiles || gCtx.Err() != nil {
+						return nil
+					}
+					err := fn(i)
+					if err != nil {
+						return err
+					}
+				}
+			})
+		}
+		return g.Wait()
+	}
+
+	// Upload
+	var total int64
+	start := time.Now()
+	err = forEach(func(i int) error {
+		info := object.NewStaticObjectInfo(remote(i), time.Now(), int64(fileSize), true, nil, f)
+		o, err := f.Put(ctx, fileReader(i), info)
+		if err != nil {
+			return errors.Wrapf(err, "failed to upload %q", info.Remote())
+		}
+		atomic.AddInt64(&total, o.Size())
+		return nil
+	})
+	if err != nil {
+		return r, err
+	}
+	r.Upload = newResult(time.Since(start), numberOfFiles, total)
+
+	// List
+	start = time.Now()
+	var objects []fs.Object
+	entries, err := f.List(ctx, dir)
+	if err != nil {
+		return r, errors.Wrap(err, "failed to list")
+	}
+	entries.ForObject(func(o fs.Object) {
+		objects = append(objects, o)
+	})
+	r.List = newResult(time.Since(start), len(objects), 0)
+	if len(objects) != numberOfFiles {
+		return r, errors.Errorf("expecting %d files in listing but found %d", numberOfFiles, len(objects))
+	}
+
+	// Download
+	total = 0
+	var firstByte int64
+	start = time.Now()
+	err = forEach(func(i int) error {
+		o := objects[i]
+		openStart := time.Now()
+		in, err := o.Open(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "failed to open %q", o.Remote())
+		}
+		// Time the first byte separately as it shows the latency
+		var buf [1]byte
+		n, err := io.ReadFull(in, buf[:])
+		atomic.AddInt64(&firstByte, int64(time.Since(openStart)))
+		if err == io.EOF {
+			err = nil // empty file
+		} else if err == nil {
+			var m int64
+			m, err = io.Copy(ioutil.Discard, in)
+			n += int(m)
+		}
+		closeErr := in.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to download %q", o.Remote())
+		}
+		atomic.AddInt64(&total, int64(n))
+		return nil
+	})
+	if err != nil {
+		return r, err
+	}
+	r.Download = newResult(time.Since(start), numberOfFiles, total)
+	r.Download.FirstByteTime = time.Duration(firstByte / int64(numberOfFiles))
+	return r, nil
+}
@@ -2,49 +2,145 @@
 package changenotify
 
 import (
+	"bytes"
 	"context"
-	"errors"
+	"io/ioutil"
+	"path"
+	"strings"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/cmd/test"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/random"
 	"github.com/spf13/cobra"
 )
 
 var (
 	pollInterval = 10 * time.Second
+	makeChanges  = false
+	timeout      = 5 * time.Minute
 )
 
 func init() {
 	test.Command.AddCommand(commandDefinition)
 	cmdFlags := commandDefinition.Flags()
 	flags.DurationVarP(cmdFlags, &pollInterval, "poll-interval", "", pollInterval, "Time to wait between polling for changes.")
+	flags.BoolVarP(cmdFlags, &makeChanges, "make-changes", "", makeChanges, "Make changes on the remote and time how long they take to be notified.")
+	flags.DurationVarP(cmdFlags, &timeout, "timeout", "", timeout, "Time to wait for each change to be notified with --make-changes.")
 }
 
 var commandDefinition = &cobra.Command{
 	Use:   "changenotify remote:",
 	Short: `Log any change notify requests for the remote passed in.`,
+	Long: `This polls the remote for changes and logs them until interrupted.
+
+If --make-changes is set then it creates, updates and deletes a file
+in a new directory on the remote instead, reporting how long each
+change took to be notified, and then exits. This checks that change
+notifications work on a remote and how far behind they are.
+`,
 	RunE: func(command *cobra.Command, args []string) error {
 		cmd.CheckArgs(1, 1, command, args)
 		f := cmd.NewFsSrc(args)
 		ctx := context.Background()
 
+		notifyFunc := changeNotify
+		var notifications chan string
+		if makeChanges {
+			notifications = make(chan string, 100)
+			notifyFunc = func(relativePath string, entryType fs.EntryType) {
+				changeNotify(relativePath, entryType)
+				select {
+				case notifications <- relativePath:
+				default:
+				}
+			}
+		}
+
 		// Start polling function
 		features := f.Features()
 		if do := features.ChangeNotify; do != nil {
 			pollChan := make(chan time.Duration)
-			do(ctx, changeNotify, pollChan)
+			do(ctx, notifyFunc, pollChan)
 			pollChan <- pollInterval
 			fs.Logf(nil, "Waiting for changes, polling every %v", pollInterval)
 		} else {
 			return errors.New("poll-interval is not supported by this remote")
 		}
+		if makeChanges {
+			return testChanges(ctx, f, notifications)
+		}
 		select {}
 	},
 }
 
+// testChanges makes changes on f and times how long it takes for
+// them to arrive on notifications
+func testChanges(ctx context.Context, f fs.Fs, notifications <-chan string) (err error) {
+	dir := "rclone-changenotify-test-" + random.String(8)
+	remote := path.Join(dir, "file.txt")
+	defer func() {
+		purgeErr := operations.Purge(ctx, f, dir)
+		if err == nil && purgeErr != nil && purgeErr != fs.ErrorDirNotFound {
+			err = errors.Wrap(purgeErr, "failed to remove test directory")
+		}
+	}()
+
+	// wait for a notification of remote or any of its parents after
+	// running action
+	wait := func(name string, action func() error) error {
+		// discard any notifications from earlier changes
+		for len(notifications) > 0 {
+			<-notifications
+		}
+		start := time.Now()
+		err := action()
+		if err != nil {
+			return errors.Wrapf(err, "%s failed", name)
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		for {
+			select {
+			case changed := <-notifications:
+				if changed == remote || changed == dir || changed == "" || strings.HasPrefix(remote, changed+"/") {
+					fs.Logf(nil, "%s: notified after %v", name, time.Since(start).Round(time.Millisecond))
+					return nil
+				}
+			case <-timer.C:
+				return errors.Errorf("%s: not notified within %v", name, timeout)
+			}
+		}
+	}
+	put := func(contents string) func() error {
+		return func() error {
+			in := ioutil.NopCloser(bytes.NewBufferString(contents))
+			_, err := operations.Rcat(ctx, f, remote, in, time.Now())
+			return err
+		}
+	}
+
+	err = wait("create", put("hello"))
+	if err != nil {
+		return err
+	}
+	err = wait("update", put("hello again"))
+	if err != nil {
+		return err
+	}
+	return wait("delete", func() error {
+		o, err := f.NewObject(ctx, remote)
+		if err != nil {
+			return err
+		}
+		return operations.DeleteFile(ctx, o)
+	})
+}
+
 // changeNotify invalidates the directory cache for the relativePath
 // passed in.
 //
@@ -0,0 +1,465 @@
+// Package conformance checks the optional features of a remote work
+// as rclone expects.
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/cmd/test"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/spf13/cobra"
+)
+
+var (
+	writeJSON string
+	keep      = false
+)
+
+func init() {
+	test.Command.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.StringVarP(cmdFlags, &writeJSON, "write-json", "", "", "Write the results to this file as JSON")
+	flags.BoolVarP(cmdFlags, &keep, "keep", "", keep, "Don't delete the test directory at the end")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "backend-conformance remote:path",
+	Short: `Check the features of a remote work as rclone expects.`,
+	Long: `This runs a set of checks against remote:path to see whether the
+basic operations and the optional features of the remote behave as
+rclone expects. It is useful for validating self hosted servers, for
+example S3 or WebDAV servers, before relying on them.
+
+It makes a new directory in remote:path and deletes it at the end
+unless --keep is given.
+
+Each check is one of
+
+- PASS - the feature works
+- FAIL - the feature is claimed but doesn't work
+- SKIP - the remote doesn't support the feature
+- the checks which depend on a failed check are skipped too
+
+It prints a table of the results and can write them as JSON with
+--write-json. It exits with an error if any check fails.
+
+    rclone test backend-conformance --write-json results.json minio:bucket/path
+
+These checks are smaller than the integration tests rclone's
+developers run but need no Go toolchain.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsDir(args)
+		cmd.Run(false, false, command, func() error {
+			return run(context.Background(), f)
+		})
+	},
+}
+
+// Status of a check
+type Status string
+
+// The possible Status of a check
+const (
+	StatusPass Status = "PASS"
+	StatusFail Status = "FAIL"
+	StatusSkip Status = "SKIP"
+)
+
+// Result of one check
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// errSkip is returned by a check which can't be run
+type errSkip string
+
+func (e errSkip) Error() string { return string(e) }
+
+// checker runs the checks on f keeping the state they share
+type checker struct {
+	ctx       context.Context
+	f         fs.Fs
+	dir       string
+	contents  []byte
+	modTime   time.Time
+	o         fs.Object // the object uploaded by the Put check
+	results   []Result
+	failed    map[string]bool
+	anyFailed bool
+}
+
+// check runs fn as the check called name unless one of the checks
+// in needs failed
+func (c *checker) check(name string, needs []string, fn func() error) {
+	res := Result{Name: name, Status: StatusPass}
+	for _, need := range needs {
+		if c.failed[need] {
+			res.Status = StatusSkip
+			res.Message = need + " failed"
+		}
+	}
+	if res.Status == StatusPass {
+		err := fn()
+		if skip, ok := err.(errSkip); ok {
+			res.Status = StatusSkip
+			res.Message = string(skip)
+		} else if err != nil {
+			res.Status = StatusFail
+			res.Message = err.Error()
+		}
+	}
+	if res.Status != StatusPass {
+		c.failed[name] = true
+	}
+	if res.Status == StatusFail {
+		c.anyFailed = true
+	}
+	fs.Debugf(c.f, "%s: %s %s", name, res.Status, res.Message)
+	c.results = append(c.results, res)
+}
+
+// remote returns the path of name in the test directory
+func (c *checker) remote(name string) string {
+	return path.Join(c.dir, name)
+}
+
+// readAll reads o with the options checking it has the contents want
+func (c *checker) readAll(o fs.Object, want []byte, options ...fs.OpenOption) error {
+	in, err := o.Open(c.ctx, options...)
+	if err != nil {
+		return errors.Wrap(err, "open failed")
+	}
+	got, err := ioutil.ReadAll(in)
+	closeErr := in.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "read failed")
+	}
+	if !bytes.Equal(got, want) {
+		return errors.Errorf("read %d bytes which don't match the %d expected", len(got), len(want))
+	}
+	return nil
+}
+
+// put uploads the test contents as name
+func (c *checker) put(name string) (fs.Object, error) {
+	info := object.NewStaticObjectInfo(c.remote(name), c.modTime, int64(len(c.contents)), true, nil, c.f)
+	return c.f.Put(c.ctx, bytes.NewReader(c.contents), info)
+}
+
+// find checks the object at remote exists with the right size
+func (c *checker) find(remote string) (fs.Object, error) {
+	o, err := c.f.NewObject(c.ctx, remote)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't find %q", remote)
+	}
+	if o.Size() != int64(len(c.contents)) {
+		return nil, errors.Errorf("%q has size %d but expected %d", remote, o.Size(), len(c.contents))
+	}
+	return o, nil
+}
+
+// gone checks the object at remote doesn't exist
+func (c *checker) gone(remote string) error {
+	_, err := c.f.NewObject(c.ctx, remote)
+	if err == nil {
+		return errors.Errorf("%q still exists", remote)
+	}
+	if err != fs.ErrorObjectNotFound {
+		return errors.Wrapf(err, "unexpected error looking for %q", remote)
+	}
+	return nil
+}
+
+// run the checks on f
+func run(ctx context.Context, f fs.Fs) (err error) {
+	c := &checker{
+		ctx:      ctx,
+		f:        f,
+		dir:      "rclone-conformance-" + random.String(8),
+		contents: []byte(random.String(1000)),
+		modTime:  time.Date(2001, 2, 3, 4, 5, 6, 123456789, time.UTC),
+		failed:   map[string]bool{},
+	}
+	features := f.Features()
+	if !keep {
+		defer func() {
+			purgeErr := operations.Purge(ctx, f, c.dir)
+			if purgeErr != nil && purgeErr != fs.ErrorDirNotFound {
+				fs.Errorf(f, "Failed to remove %q: %v", c.dir, purgeErr)
+			}
+		}()
+	}
+
+	c.check("Mkdir", nil, func() error {
+		return f.Mkdir(ctx, c.dir)
+	})
+	c.check("Put", []string{"Mkdir"}, func() (err error) {
+		_, err = c.put("file.txt")
+		if err != nil {
+			return err
+		}
+		c.o, err = c.find(c.remote("file.txt"))
+		return err
+	})
+	c.check("Open", []string{"Put"}, func() error {
+		return c.readAll(c.o, c.contents)
+	})
+	c.check("Open range", []string{"Put"}, func() error {
+		return c.readAll(c.o, c.contents[100:200], &fs.RangeOption{Start: 100, End: 199})
+	})
+	c.check("List", []string{"Put"}, func() error {
+		entries, err := f.List(ctx, c.dir)
+		if err != nil {
+			return err
+		}
+		if len(entries) != 1 || entries[0].Remote() != c.remote("file.txt") {
+			return errors.Errorf("expecting just %q but got %v", c.remote("file.txt"), entries)
+		}
+		return nil
+	})
+	c.check("ListR", []string{"Put"}, func() error {
+		if features.ListR == nil {
+			return errSkip("not supported")
+		}
+		var found []string
+		err := features.ListR(ctx, c.dir, func(entries fs.DirEntries) error {
+			for _, entry := range entries {
+				found = append(found, entry.Remote())
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(found) != 1 || found[0] != c.remote("file.txt") {
+			return errors.Errorf("expecting just %q but got %q", c.remote("file.txt"), found)
+		}
+		return nil
+	})
+	for _, ht := range f.Hashes().Array() {
+		ht := ht
+		c.check("Hash "+ht.String(), []string{"Put"}, func() error {
+			got, err := c.o.Hash(ctx, ht)
+			if err != nil {
+				return err
+			}
+			if got == "" {
+				return errSkip("hash not available for this object")
+			}
+			sums, err := hash.StreamTypes(bytes.NewReader(c.contents), hash.NewHashSet(ht))
+			if err != nil {
+				return err
+			}
+			if want := sums[ht]; !hash.Equals(got, want) {
+				return errors.Errorf("got %q but expected %q", got, want)
+			}
+			return nil
+		})
+	}
+	precision := f.Precision()
+	c.check("ModTime", []string{"Put"}, func() error {
+		if precision == fs.ModTimeNotSupported {
+			return errSkip("not supported")
+		}
+		return c.checkModTime(c.o, c.modTime, precision)
+	})
+	c.check("SetModTime", []string{"Put"}, func() error {
+		if precision == fs.ModTimeNotSupported {
+			return errSkip("not supported")
+		}
+		newTime := c.modTime.Add(36 * time.Hour)
+		err := c.o.SetModTime(ctx, newTime)
+		if err == fs.ErrorCantSetModTime || err == fs.ErrorCantSetModTimeWithoutDelete {
+			return errSkip(err.Error())
+		}
+		if err != nil {
+			return err
+		}
+		o, err := c.find(c.remote("file.txt"))
+		if err != nil {
+			return err
+		}
+		return c.checkModTime(o, newTime, precision)
+	})
+	c.check("PutStream", []string{"Mkdir"}, func() error {
+		if features.PutStream == nil {
+			return errSkip("not supported")
+		}
+		info := object.NewStaticObjectInfo(c.remote("stream.txt"), c.modTime, -1, true, nil, f)
+		_, err := features.PutStream(ctx, bytes.NewReader(c.contents), info)
+		if err != nil {
+			return err
+		}
+		o, err := c.find(c.remote("stream.txt"))
+		if err != nil {
+			return err
+		}
+		return c.readAll(o, c.contents)
+	})
+	c.check("Copy", []string{"Put"}, func() error {
+		if features.Copy == nil {
+			return errSkip("not supported")
+		}
+		dst, err := features.Copy(ctx, c.o, c.remote("copy.txt"))
+		if err == fs.ErrorCantCopy {
+			return errSkip(err.Error())
+		}
+		if err != nil {
+			return err
+		}
+		if dst.Remote() != c.remote("copy.txt") {
+			return errors.Errorf("copy has name %q", dst.Remote())
+		}
+		o, err := c.find(c.remote("copy.txt"))
+		if err != nil {
+			return err
+		}
+		return c.readAll(o, c.contents)
+	})
+	c.check("Move", []string{"Put"}, func() error {
+		if features.Move == nil {
+			return errSkip("not supported")
+		}
+		src, err := c.put("move.txt")
+		if err != nil {
+			return errors.Wrap(err, "failed to upload file to move")
+		}
+		_, err = features.Move(ctx, src, c.remote("moved.txt"))
+		if err == fs.ErrorCantMove {
+			return errSkip(err.Error())
+		}
+		if err != nil {
+			return err
+		}
+		o, err := c.find(c.remote("moved.txt"))
+		if err != nil {
+			return err
+		}
+		err = c.readAll(o, c.contents)
+		if err != nil {
+			return err
+		}
+		return c.gone(c.remote("move.txt"))
+	})
+	c.check("DirMove", []string{"Put"}, func() error {
+		if features.DirMove == nil {
+			return errSkip("not supported")
+		}
+		_, err := c.put("dir/file.txt")
+		if err != nil {
+			return errors.Wrap(err, "failed to upload file to move")
+		}
+		err = features.DirMove(ctx, f, c.remote("dir"), c.remote("dir2"))
+		if err == fs.ErrorCantDirMove {
+			return errSkip(err.Error())
+		}
+		if err != nil {
+			return err
+		}
+		o, err := c.find(c.remote("dir2/file.txt"))
+		if err != nil {
+			return err
+		}
+		err = c.readAll(o, c.contents)
+		if err != nil {
+			return err
+		}
+		return c.gone(c.remote("dir/file.txt"))
+	})
+	c.check("About", nil, func() error {
+		if features.About == nil {
+			return errSkip("not supported")
+		}
+		usage, err := features.About(ctx)
+		if err != nil {
+			return err
+		}
+		if usage == nil {
+			return errors.New("returned no usage")
+		}
+		return nil
+	})
+	c.check("Remove", []string{"Put"}, func() error {
+		o, err := c.put("remove.txt")
+		if err != nil {
+			return errors.Wrap(err, "failed to upload file to remove")
+		}
+		err = o.Remove(ctx)
+		if err != nil {
+			return err
+		}
+		return c.gone(c.remote("remove.txt"))
+	})
+	c.check("Purge", []string{"Put"}, func() error {
+		if features.Purge == nil {
+			return errSkip("not supported")
+		}
+		_, err := c.put("purge/file.txt")
+		if err != nil {
+			return errors.Wrap(err, "failed to upload file to purge")
+		}
+		err = features.Purge(ctx, c.remote("purge"))
+		if err != nil {
+			return err
+		}
+		return c.gone(c.remote("purge/file.txt"))
+	})
+
+	c.print()
+	if writeJSON != "" {
+		data, err := json.MarshalIndent(c.results, "", "\t")
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(writeJSON, append(data, '\n'), 0666)
+		if err != nil {
+			return errors.Wrap(err, "failed to write results")
+		}
+	}
+	if c.anyFailed {
+		return errors.New("some checks failed")
+	}
+	return nil
+}
+
+// checkModTime checks the modification time of o is want within precision
+func (c *checker) checkModTime(o fs.Object, want time.Time, precision time.Duration) error {
+	got := o.ModTime(c.ctx)
+	dt := got.Sub(want)
+	if dt < 0 {
+		dt = -dt
+	}
+	if dt > precision {
+		return errors.Errorf("got %v but expected %v within %v", got, want, precision)
+	}
+	return nil
+}
+
+// print the results as a table
+func (c *checker) print() {
+	fmt.Fprintf(os.Stdout, "%-16s %-6s %s\n", "Check", "Status", "Message")
+	for _, res := range c.results {
+		fmt.Fprintf(os.Stdout, "%-16s %-6s %s\n", res.Name, res.Status, res.Message)
+	}
+}
@@ -5,11 +5,15 @@ package makefiles
 import (
 	"io"
 	"log"
+	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/cmd/test"
 	"github.com/rclone/rclone/fs"
@@ -28,11 +32,13 @@ var (
 	minFileNameLength        = 4
 	maxFileNameLength        = 12
 	seed                     = int64(1)
+	distribution             = "uniform"
 
 	// Globals
 	randSource          *rand.Rand
 	directoriesToCreate int
 	totalDirectories    int
+	fileSize            func() int64            // chooses the size of the next file
 	fileNames           = map[string]struct{}{} // keep a note of which file name we've used already
 )
 
@@ -47,13 +53,39 @@ func init() {
 	flags.IntVarP(cmdFlags, &minFileNameLength, "min-name-length", "", minFileNameLength, "Minimum size of file names")
 	flags.IntVarP(cmdFlags, &maxFileNameLength, "max-name-length", "", maxFileNameLength, "Maximum size of file names")
 	flags.Int64VarP(cmdFlags, &seed, "seed", "", seed, "Seed for the random number generator (0 for random)")
+	flags.StringVarP(cmdFlags, &distribution, "size-distribution", "", distribution, "Distribution of file sizes: uniform, log or a list of size=weight")
 }
 
 var commandDefinition = &cobra.Command{
 	Use:   "makefiles <dir>",
 	Short: `Make a random file hierarchy in <dir>`,
+	Long: `This makes a random hierarchy of directories and files in <dir>
+which is useful for making reproducible tests and benchmarks.
+
+The same --seed always makes the same files, so a report can say
+exactly which files were used.
+
+The sizes of the files are chosen between --min-file-size and
+--max-file-size with the --size-distribution which can be
+
+- uniform - every size is equally likely (the default)
+- log - small files are much more likely than big ones, with each
+  power of 2 being equally likely, which is more like real data
+- a list of size=weight, e.g. "4k=70,1M=25,100M=5" to make 70% of the
+  files 4 KiB, 25% 1 MiB and 5% 100 MiB. --min-file-size and
+  --max-file-size are ignored.
+
+For example
+
+    rclone test makefiles --files 10000 --max-file-size 10M --size-distribution log /tmp/files
+`,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
+		var err error
+		fileSize, err = parseDistribution(distribution)
+		if err != nil {
+			log.Fatalf("Bad --size-distribution: %v", err)
+		}
 		if seed == 0 {
 			seed = time.Now().UnixNano()
 			fs.Logf(nil, "Using random seed = %d", seed)
@@ -61,9 +93,8 @@ var commandDefinition = &cobra.Command{
 		randSource = rand.New(rand.NewSource(seed))
 		outputDirectory := args[0]
 		directoriesToCreate = numberOfFiles / averageFilesPerDirectory
-		averageSize := (minFileSize + maxFileSize) / 2
 		start := time.Now()
-		fs.Logf(nil, "Creating %d files of average size %v in %d directories in %q.", numberOfFiles, averageSize, directoriesToCreate, outputDirectory)
+		fs.Logf(nil, "Creating %d files with a %s size distribution in %d directories in %q.", numberOfFiles, distribution, directoriesToCreate, outputDirectory)
 		root := &dir{name: outputDirectory, depth: 1}
 		for totalDirectories < directoriesToCreate {
 			root.createDirectories()
@@ -75,7 +106,7 @@ var commandDefinition = &cobra.Command{
 			totalBytes += writeFile(dir, fileName())
 		}
 		dt := time.Since(start)
-		fs.Logf(nil, "Written %viB in %v at %viB/s.", fs.SizeSuffix(totalBytes), dt.Round(time.Millisecond), fs.SizeSuffix((totalBytes*int64(time.Second))/int64(dt)))
+		fs.Logf(nil, "Written %v in %v at %v.", fs.SizeSuffix(totalBytes).ByteUnit(), dt.Round(time.Millisecond), fs.SizeSuffix((totalBytes*int64(time.Second))/int64(dt)).ByteRateUnit())
 	},
 }
 
@@ -143,7 +174,7 @@ func writeFile(dir, name string) int64 {
 	if err != nil {
 		log.Fatalf("Failed to open file %q: %v", path, err)
 	}
-	size := randSource.Int63n(int64(maxFileSize-minFileSize)) + int64(minFileSize)
+	size := fileSize()
 	_, err = io.CopyN(fd, randSource, size)
 	if err != nil {
 		log.Fatalf("Failed to write %v bytes to file %q: %v", size, path, err)
@@ -155,3 +186,64 @@ func writeFile(dir, name string) int64 {
 	fs.Infof(path, "Written file size %v", fs.SizeSuffix(size))
 	return size
 }
+
+// parseDistribution returns a function to choose the file sizes
+// with the distribution described by dist
+func parseDistribution(dist string) (func() int64, error) {
+	min, max := int64(minFileSize), int64(maxFileSize)
+	if !strings.Contains(dist, "=") && max < min {
+		return nil, errors.New("--max-file-size must be at least --min-file-size")
+	}
+	switch dist {
+	case "uniform":
+		return func() int64 {
+			if max <= min {
+				return min
+			}
+			return randSource.Int63n(max-min) + min
+		}, nil
+	case "log":
+		logMin, logMax := math.Log2(float64(min+1)), math.Log2(float64(max+1))
+		return func() int64 {
+			size := int64(math.Exp2(logMin+randSource.Float64()*(logMax-logMin))) - 1
+			if size < min {
+				size = min
+			} else if size > max {
+				size = max
+			}
+			return size
+		}, nil
+	}
+	var (
+		sizes      []int64
+		cumulative []int64
+		total      int64
+	)
+	for _, item := range strings.Split(dist, ",") {
+		equals := strings.IndexRune(item, '=')
+		if equals < 0 {
+			return nil, errors.Errorf("expecting uniform, log or size=weight but got %q", item)
+		}
+		var size fs.SizeSuffix
+		err := size.Set(strings.TrimSpace(item[:equals]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad size in %q", item)
+		}
+		weight, err := strconv.ParseInt(strings.TrimSpace(item[equals+1:]), 10, 64)
+		if err != nil || weight <= 0 {
+			return nil, errors.Errorf("bad weight in %q", item)
+		}
+		total += weight
+		sizes = append(sizes, int64(size))
+		cumulative = append(cumulative, total)
+	}
+	return func() int64 {
+		n := randSource.Int63n(total)
+		for i, c := range cumulative {
+			if n < c {
+				return sizes[i]
+			}
+		}
+		return sizes[len(sizes)-1]
+	}, nil
+}
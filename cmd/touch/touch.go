@@ -3,13 +3,20 @@ package touch
 import (
 	"bytes"
 	"context"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/march"
 	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +24,8 @@ var (
 	notCreateNewFile bool
 	timeAsArgument   string
 	localTime        bool
+	recursive        bool
+	reference        string
 )
 
 const (
@@ -31,6 +40,8 @@ func init() {
 	flags.BoolVarP(cmdFlags, &notCreateNewFile, "no-create", "C", false, "Do not create the file if it does not exist.")
 	flags.StringVarP(cmdFlags, &timeAsArgument, "timestamp", "t", "", "Use specified time instead of the current time of day.")
 	flags.BoolVarP(cmdFlags, &localTime, "localtime", "", false, "Use localtime for timestamp, not UTC.")
+	flags.BoolVarP(cmdFlags, &recursive, "recursive", "R", false, "Touch recursively all the existing files in remote:path.")
+	flags.StringVarP(cmdFlags, &reference, "reference", "", "", "Use the modification times from this remote:path instead.")
 }
 
 var commandDefinition = &cobra.Command{
@@ -49,12 +60,43 @@ time instead of the current time. Times may be specified as one of:
 - 'YYMMDD' - e.g. 17.10.30
 - 'YYYY-MM-DDTHH:MM:SS' - e.g. 2006-01-02T15:04:05
 - 'YYYY-MM-DDTHH:MM:SS.SSS' - e.g. 2006-01-02T15:04:05.123456789
+- 'YYYY-MM-DDTHH:MM:SSZ07:00' - e.g. 2006-01-02T15:04:05+07:00
+- 'now-DURATION' or 'now+DURATION' - e.g. now-2d or now+1h30m
 
 Note that --timestamp is in UTC if you want local time then add the
 --localtime flag.
+
+If --reference is used then the modification time is copied from the
+file it points to instead.
+
+If --recursive is used then remote:path should be a directory and the
+modification time of all the existing files in it, and in its
+subdirectories, is set. No files are created and the filters may be
+used to choose the files. Combined with --reference pointing to a
+directory this sets the modification time of each file to that of the
+file with the same path in the reference, without copying any data.
+This can be used to repair a copy whose modification times were lost,
+for example
+
+    rclone touch -R --reference /path/to/originals remote:copy
+
+Files which are missing from the reference are left alone, as are
+files which already have the right modification time. Use --dry-run
+to see what would be changed.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
+		if recursive {
+			fdst := cmd.NewFsSrc(args)
+			var fref fs.Fs
+			if reference != "" {
+				fref = cmd.NewFsDir([]string{reference})
+			}
+			cmd.Run(true, false, command, func() error {
+				return TouchRecursive(context.Background(), fdst, fref)
+			})
+			return
+		}
 		fsrc, srcFileName := cmd.NewFsDstFile(args)
 		cmd.Run(true, false, command, func() error {
 			return Touch(context.Background(), fsrc, srcFileName)
@@ -62,26 +104,79 @@ Note that --timestamp is in UTC if you want local time then add the
 	},
 }
 
-//Touch create new file or change file modification time.
-func Touch(ctx context.Context, fsrc fs.Fs, srcFileName string) (err error) {
-	timeAtr := time.Now()
-	if timeAsArgument != "" {
-		layout := defaultLayout
-		if len(timeAsArgument) == len(layoutDateWithTime) {
-			layout = layoutDateWithTime
-		} else if len(timeAsArgument) > len(layoutDateWithTime) {
-			layout = layoutDateWithTimeNano
+// parseTimeArgument parses the --timestamp flag returning the
+// current time if it isn't set
+func parseTimeArgument() (time.Time, error) {
+	now := time.Now()
+	if timeAsArgument == "" {
+		return now, nil
+	}
+	if strings.HasPrefix(timeAsArgument, "now") {
+		offset := timeAsArgument[3:]
+		if offset == "" {
+			return now, nil
 		}
-		var timeAtrFromFlags time.Time
-		if localTime {
-			timeAtrFromFlags, err = time.ParseInLocation(layout, timeAsArgument, time.Local)
-		} else {
-			timeAtrFromFlags, err = time.Parse(layout, timeAsArgument)
+		d, err := fs.ParseDuration(strings.TrimLeft(offset, "+-"))
+		if err != nil || (offset[0] != '-' && offset[0] != '+') {
+			return now, errors.Errorf("failed to parse date/time argument: bad offset %q", offset)
 		}
-		if err != nil {
-			return errors.Wrap(err, "failed to parse date/time argument")
+		if offset[0] == '-' {
+			d = -d
+		}
+		return now.Add(d), nil
+	}
+	layout := defaultLayout
+	if len(timeAsArgument) == len(layoutDateWithTime) {
+		layout = layoutDateWithTime
+	} else if len(timeAsArgument) > len(layoutDateWithTime) {
+		layout = layoutDateWithTimeNano
+		if t, err := time.Parse(time.RFC3339Nano, timeAsArgument); err == nil {
+			return t, nil
 		}
-		timeAtr = timeAtrFromFlags
+	}
+	var t time.Time
+	var err error
+	if localTime {
+		t, err = time.ParseInLocation(layout, timeAsArgument, time.Local)
+	} else {
+		t, err = time.Parse(layout, timeAsArgument)
+	}
+	if err != nil {
+		return now, errors.Wrap(err, "failed to parse date/time argument")
+	}
+	return t, nil
+}
+
+// referenceTime returns the modification time of the file --reference
+// points to
+func referenceTime(ctx context.Context) (time.Time, error) {
+	f, err := cache.Get(ctx, reference)
+	if err == nil {
+		return time.Time{}, errors.Errorf("--reference %q is a directory: use --recursive to touch from a directory", reference)
+	} else if err != fs.ErrorIsFile {
+		return time.Time{}, errors.Wrap(err, "failed to read --reference")
+	}
+	_, leaf, err := fspath.Split(reference)
+	if err != nil {
+		return time.Time{}, err
+	}
+	o, err := f.NewObject(ctx, leaf)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "failed to read --reference")
+	}
+	return o.ModTime(ctx), nil
+}
+
+//Touch create new file or change file modification time.
+func Touch(ctx context.Context, fsrc fs.Fs, srcFileName string) (err error) {
+	var timeAtr time.Time
+	if reference != "" {
+		timeAtr, err = referenceTime(ctx)
+	} else {
+		timeAtr, err = parseTimeArgument()
+	}
+	if err != nil {
+		return err
 	}
 	file, err := fsrc.NewObject(ctx, srcFileName)
 	if err != nil {
@@ -101,3 +196,123 @@ func Touch(ctx context.Context, fsrc fs.Fs, srcFileName string) (err error) {
 	}
 	return nil
 }
+
+// touchMarch sets the modification times of the objects in the
+// destination from those in the reference
+type touchMarch struct {
+	fdst    fs.Fs
+	fref    fs.Fs
+	changed int64
+	missing int64
+	errors  int64
+}
+
+// SrcOnly is called for objects only in the reference
+func (tm *touchMarch) SrcOnly(src fs.DirEntry) (recurse bool) {
+	return false
+}
+
+// DstOnly is called for objects which aren't in the reference
+func (tm *touchMarch) DstOnly(dst fs.DirEntry) (recurse bool) {
+	switch dst.(type) {
+	case fs.Object:
+		fs.Debugf(dst, "Not touching as not found in reference")
+		atomic.AddInt64(&tm.missing, 1)
+	case fs.Directory:
+		return true
+	}
+	return false
+}
+
+// Match is called for objects in the destination and the reference
+func (tm *touchMarch) Match(ctx context.Context, dst, src fs.DirEntry) (recurse bool) {
+	switch dstX := dst.(type) {
+	case fs.Object:
+		srcX, ok := src.(fs.Object)
+		if !ok {
+			fs.Errorf(dst, "Not touching as reference is a directory")
+			atomic.AddInt64(&tm.errors, 1)
+			return false
+		}
+		tm.touch(ctx, dstX, srcX.ModTime(ctx))
+	case fs.Directory:
+		_, ok := src.(fs.Directory)
+		return ok
+	}
+	return false
+}
+
+// touch sets the modification time of o to modTime if it differs
+func (tm *touchMarch) touch(ctx context.Context, o fs.Object, modTime time.Time) {
+	var err error
+	tr := accounting.Stats(ctx).NewCheckingTransfer(o)
+	defer func() {
+		tr.Done(ctx, err)
+	}()
+	var window time.Duration
+	if tm.fref != nil {
+		window = fs.GetModifyWindow(ctx, tm.fdst, tm.fref)
+	} else {
+		window = fs.GetModifyWindow(ctx, tm.fdst)
+	}
+	dt := o.ModTime(ctx).Sub(modTime)
+	if dt >= -window && dt <= window {
+		fs.Debugf(o, "Modification time already correct")
+		return
+	}
+	atomic.AddInt64(&tm.changed, 1)
+	if operations.SkipDestructive(ctx, o, "touch") {
+		return
+	}
+	err = o.SetModTime(ctx, modTime)
+	if err != nil {
+		err = fs.CountError(err)
+		fs.Errorf(o, "Failed to set modification time: %v", err)
+		atomic.AddInt64(&tm.errors, 1)
+		return
+	}
+	fs.Infof(o, "Set modification time to %v", modTime)
+}
+
+// TouchRecursive sets the modification time of all the existing
+// objects in fdst.
+//
+// If fref is set then the modification time of each object is copied
+// from the object with the same path in fref, otherwise it is set to
+// the --timestamp.
+func TouchRecursive(ctx context.Context, fdst fs.Fs, fref fs.Fs) (err error) {
+	tm := &touchMarch{
+		fdst: fdst,
+		fref: fref,
+	}
+	if fref != nil {
+		m := &march.March{
+			Ctx:      ctx,
+			Fdst:     fdst,
+			Fsrc:     fref,
+			Dir:      "",
+			Callback: tm,
+		}
+		err = m.Run(ctx)
+	} else {
+		var modTime time.Time
+		modTime, err = parseTimeArgument()
+		if err != nil {
+			return err
+		}
+		err = operations.ListFn(ctx, fdst, func(o fs.Object) {
+			tm.touch(ctx, o, modTime)
+		})
+	}
+	if tm.missing > 0 {
+		fs.Logf(fdst, "%d files not found in reference so not touched", tm.missing)
+	}
+	fs.Infof(fdst, "Touched %d files", tm.changed)
+	if err != nil {
+		return err
+	}
+	if tm.errors > 0 {
+		return errors.Errorf("failed to touch %d files", tm.errors)
+	}
+	return nil
+}
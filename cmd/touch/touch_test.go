@@ -2,12 +2,14 @@ package touch
 
 import (
 	"context"
+	"path"
 	"testing"
 	"time"
 
 	_ "github.com/rclone/rclone/backend/local"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -117,3 +119,99 @@ func TestTouchCreateMultipleDirAndFile(t *testing.T) {
 	file1 := fstest.NewItem("a/b/c.txt", "", t1)
 	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1}, []string{"a", "a/b"}, fs.ModTimeNotSupported)
 }
+
+func TestParseTimeArgument(t *testing.T) {
+	defer func() { timeAsArgument = "" }()
+	for _, test := range []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"171030", time.Date(2017, 10, 30, 0, 0, 0, 0, time.UTC), false},
+		{"2006-01-02T15:04:05", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), false},
+		{"2006-01-02T15:04:05.123456789", time.Date(2006, 1, 2, 15, 4, 5, 123456789, time.UTC), false},
+		{"2006-01-02T15:04:05+07:00", time.Date(2006, 1, 2, 8, 4, 5, 0, time.UTC), false},
+		{"now-2d", time.Now().Add(-48 * time.Hour), false},
+		{"now+1h30m", time.Now().Add(90 * time.Minute), false},
+		{"now", time.Now(), false},
+		{"now2d", time.Time{}, true},
+		{"potato", time.Time{}, true},
+	} {
+		timeAsArgument = test.in
+		got, err := parseTimeArgument()
+		if test.wantErr {
+			require.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.WithinDuration(t, test.want, got, time.Second, test.in)
+	}
+}
+
+func TestTouchRecursive(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	ctx := context.Background()
+
+	file1 := r.WriteObject(ctx, "a", "aaa", t1)
+	file2 := r.WriteObject(ctx, "sub/b", "bbb", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	timeAsArgument = "2006-01-02T15:04:05"
+	defer func() { timeAsArgument = "" }()
+	err := TouchRecursive(ctx, r.Fremote, nil)
+	require.NoError(t, err)
+	t2 := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	file1.ModTime = t2
+	file2.ModTime = t2
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
+
+func TestTouchRecursiveReference(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	ctx := context.Background()
+
+	t2 := fstest.Time("2011-12-25T12:59:59.123456789Z")
+	t3 := fstest.Time("2001-02-03T04:05:06.499999999Z")
+	file1 := r.WriteObject(ctx, "a", "aaa", t1)
+	file2 := r.WriteObject(ctx, "sub/b", "bbb", t1)
+	file3 := r.WriteObject(ctx, "c", "ccc", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	// the reference has different times and is missing c
+	ref1 := r.WriteFile("a", "different", t2)
+	ref2 := r.WriteFile("sub/b", "bbb", t3)
+	ref4 := r.WriteFile("d", "ddd", t3)
+	fstest.CheckItems(t, r.Flocal, ref1, ref2, ref4)
+
+	err := TouchRecursive(ctx, r.Fremote, r.Flocal)
+	require.NoError(t, err)
+	file1.ModTime = t2
+	file2.ModTime = t3
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+}
+
+func TestTouchReference(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	ctx := context.Background()
+
+	t2 := fstest.Time("2011-12-25T12:59:59.123456789Z")
+	r.WriteFile("ref", "reference", t2)
+	file1 := r.WriteObject(ctx, "a", "aaa", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	reference = path.Join(r.LocalName, "ref")
+	defer func() { reference = "" }()
+	err := Touch(ctx, r.Fremote, "a")
+	require.NoError(t, err)
+	file1.ModTime = t2
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// a directory isn't allowed without --recursive
+	reference = r.LocalName
+	err = Touch(ctx, r.Fremote, "a")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "use --recursive")
+}
@@ -60,6 +60,7 @@ type Account struct {
 	closed  bool          // set if the file is closed
 	exit    chan struct{} // channel that will be closed when transfer is finished
 	withBuf bool          // is using a buffered in
+	maxBuf  int64         // if set the buffer starts small and can Grow to this size
 
 	tokenBucket buckets // per file bandwidth limiter (may be nil)
 
@@ -119,15 +120,19 @@ func (acc *Account) WithBuffer() *Account {
 		return acc
 	}
 	acc.withBuf = true
+	bufferSize, initial := int64(acc.ci.BufferSize), 0
+	if acc.maxBuf > 0 {
+		bufferSize, initial = acc.maxBuf, 1
+	}
 	var buffers int
-	if acc.size >= int64(acc.ci.BufferSize) || acc.size == -1 {
-		buffers = int(int64(acc.ci.BufferSize) / asyncreader.BufferSize)
+	if acc.size >= bufferSize || acc.size == -1 {
+		buffers = int(bufferSize / asyncreader.BufferSize)
 	} else {
 		buffers = int(acc.size / asyncreader.BufferSize)
 	}
 	// On big files add a buffer
 	if buffers > 0 {
-		rc, err := asyncreader.New(acc.ctx, acc.origIn, buffers)
+		rc, err := asyncreader.NewWindow(acc.ctx, acc.origIn, initial, buffers)
 		if err != nil {
 			fs.Errorf(acc.name, "Failed to make buffer: %v", err)
 		} else {
@@ -138,6 +143,24 @@ func (acc *Account) WithBuffer() *Account {
 	return acc
 }
 
+// WithReadAhead is like WithBuffer except that the buffer starts off
+// reading ahead a single block and can be made bigger, up to maxBuf
+// bytes, with GrowBuffer.
+//
+// The buffer starts off small again after UpdateReader.
+func (acc *Account) WithReadAhead(maxBuf int64) *Account {
+	acc.maxBuf = maxBuf
+	return acc.WithBuffer()
+}
+
+// GrowBuffer increases the read ahead of the buffer added by
+// WithReadAhead to size bytes. It does nothing if there is no buffer.
+func (acc *Account) GrowBuffer(size int64) {
+	if asyncIn := acc.GetAsyncReader(); asyncIn != nil {
+		asyncIn.Grow(int(size / asyncreader.BufferSize))
+	}
+}
+
 // HasBuffer - returns true if this Account has an AsyncReader with a buffer
 func (acc *Account) HasBuffer() bool {
 	acc.mu.Lock()
@@ -326,6 +349,7 @@ func (acc *Account) accountRead(n int) {
 
 	TokenBucket.LimitBandwidth(TokenBucketSlotAccounting, n)
 	acc.limitPerFileBandwidth(n)
+	transferGate.waitRead(acc.ctx)
 }
 
 // read bytes from the io.Reader passed in and account them
@@ -64,6 +64,29 @@ func TestAccountWithBuffer(t *testing.T) {
 	assert.NoError(t, acc.Close())
 }
 
+func TestAccountWithReadAhead(t *testing.T) {
+	ctx := context.Background()
+	in := ioutil.NopCloser(bytes.NewBuffer([]byte{1}))
+
+	stats := NewStats(ctx)
+	acc := newAccountSizeName(ctx, stats, in, -1, "test")
+	acc.WithReadAhead(4 * asyncreader.BufferSize)
+	ar := acc.GetAsyncReader()
+	require.NotNil(t, ar)
+	assert.Equal(t, 1, ar.ReadAhead())
+
+	acc.GrowBuffer(2 * asyncreader.BufferSize)
+	assert.Equal(t, 2, ar.ReadAhead())
+
+	// UpdateReader starts with a small buffer again
+	in2 := ioutil.NopCloser(bytes.NewBuffer([]byte{1}))
+	acc.UpdateReader(ctx, in2)
+	ar = acc.GetAsyncReader()
+	require.NotNil(t, ar)
+	assert.Equal(t, 1, ar.ReadAhead())
+	assert.NoError(t, acc.Close())
+}
+
 func TestAccountGetUpdateReader(t *testing.T) {
 	ctx := context.Background()
 	test := func(doClose bool) func(t *testing.T) {
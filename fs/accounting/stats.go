@@ -46,7 +46,17 @@ type StatsInfo struct {
 	renameQueue       int
 	renameQueueSize   int64
 	deletes           int64
+	deleteQueue       int64
 	deletedDirs       int64
+	verifies          int64
+	verifyFailures    int64
+	flagged           int64
+	estStreams        int64
+	estStreamBytes    int64
+	estServerSide     int64
+	estServerBytes    int64
+	apiCalls          map[string]int64    // number of calls to each API endpoint
+	failed            map[string]struct{} // remotes of the transfers which failed
 	inProgress        *inProgress
 	startedTransfers  []*Transfer   // currently active transfers
 	oldTimeRanges     timeRanges    // a merged list of time ranges for the transfers
@@ -103,8 +113,26 @@ func (s *StatsInfo) RemoteStats() (out rc.Params, err error) {
 	out["checks"] = s.checks
 	out["transfers"] = s.transfers
 	out["deletes"] = s.deletes
+	out["totalDeletes"] = s.deleteQueue
 	out["deletedDirs"] = s.deletedDirs
 	out["renames"] = s.renames
+	out["verifies"] = s.verifies
+	out["verifyFailures"] = s.verifyFailures
+	out["flagged"] = s.flagged
+	if s.estStreams != 0 || s.estServerSide != 0 {
+		out["estimatedStreams"] = s.estStreams
+		out["estimatedStreamBytes"] = s.estStreamBytes
+		out["estimatedServerSide"] = s.estServerSide
+		out["estimatedServerSideBytes"] = s.estServerBytes
+		if d, ok := s.estimatedTime(); ok {
+			out["estimatedTime"] = d.Seconds()
+		} else {
+			out["estimatedTime"] = nil
+		}
+	}
+	if len(s.apiCalls) > 0 {
+		out["apiCalls"] = s.copyAPICalls()
+	}
 	out["elapsedTime"] = time.Since(s.startTime).Seconds()
 	eta, etaOK := eta(s.bytes, ts.totalBytes, ts.speed)
 	if etaOK {
@@ -427,16 +455,31 @@ func (s *StatsInfo) String() string {
 			_, _ = fmt.Fprintf(buf, "Checks:        %10d / %d, %s\n",
 				s.checks, ts.totalChecks, percent(s.checks, ts.totalChecks))
 		}
-		if s.deletes != 0 || s.deletedDirs != 0 {
+		if s.deleteQueue != 0 {
+			_, _ = fmt.Fprintf(buf, "Deleted:       %10d / %d (files), %d (dirs), %s\n",
+				s.deletes, s.deleteQueue, s.deletedDirs, percent(s.deletes, s.deleteQueue))
+		} else if s.deletes != 0 || s.deletedDirs != 0 {
 			_, _ = fmt.Fprintf(buf, "Deleted:       %10d (files), %d (dirs)\n", s.deletes, s.deletedDirs)
 		}
 		if s.renames != 0 {
 			_, _ = fmt.Fprintf(buf, "Renamed:       %10d\n", s.renames)
 		}
+		if s.verifies != 0 || s.verifyFailures != 0 {
+			_, _ = fmt.Fprintf(buf, "Verified:      %10d (files), %d (mismatched)\n", s.verifies, s.verifyFailures)
+		}
+		if s.flagged != 0 {
+			_, _ = fmt.Fprintf(buf, "Flagged:       %10d (by scanner)\n", s.flagged)
+		}
+		if s.estStreams != 0 || s.estServerSide != 0 {
+			_, _ = fmt.Fprintf(buf, "Estimated:     %s\n", s.estimateString())
+		}
 		if s.transfers != 0 || ts.totalTransfers != 0 {
 			_, _ = fmt.Fprintf(buf, "Transferred:   %10d / %d, %s\n",
 				s.transfers, ts.totalTransfers, percent(s.transfers, ts.totalTransfers))
 		}
+		if len(s.apiCalls) > 0 {
+			_, _ = fmt.Fprintf(buf, "API calls:     %10d (%s)\n", s.totalAPICalls(), s.topAPICalls(maxAPICallsShown))
+		}
 		_, _ = fmt.Fprintf(buf, "Elapsed time:  %10ss\n", strings.TrimRight(elapsedTime.Truncate(time.Minute).String(), "0s")+fmt.Sprintf("%.1f", elapsedTimeSecondsOnly.Seconds()))
 	}
 
@@ -582,6 +625,15 @@ func (s *StatsInfo) Deletes(deletes int64) int64 {
 	return s.deletes
 }
 
+// DeleteQueue updates the stats for the files found by delete and
+// purge which are to be deleted
+func (s *StatsInfo) DeleteQueue(deleteQueue int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteQueue += deleteQueue
+	return s.deleteQueue
+}
+
 // DeletedDirs updates the stats for deletedDirs
 func (s *StatsInfo) DeletedDirs(deletedDirs int64) int64 {
 	s.mu.Lock()
@@ -598,7 +650,174 @@ func (s *StatsInfo) Renames(renames int64) int64 {
 	return s.renames
 }
 
-// ResetCounters sets the counters (bytes, checks, errors, transfers, deletes, renames) to 0 and resets lastError, fatalError and retryError
+// Verifies updates the stats for files verified with --verify
+func (s *StatsInfo) Verifies(verifies int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.verifies += verifies
+	return s.verifies
+}
+
+// VerifyFailures updates the stats for files which failed --verify
+func (s *StatsInfo) VerifyFailures(verifyFailures int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.verifyFailures += verifyFailures
+	return s.verifyFailures
+}
+
+// Flagged updates the stats for files flagged by the content scanner
+func (s *StatsInfo) Flagged(flagged int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flagged += flagged
+	return s.flagged
+}
+
+// Estimated updates the --estimate stats for a file of size bytes
+// which a --dry-run would have transferred
+func (s *StatsInfo) Estimated(serverSide bool, size int64) {
+	if size < 0 {
+		size = 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if serverSide {
+		s.estServerSide++
+		s.estServerBytes += size
+	} else {
+		s.estStreams++
+		s.estStreamBytes += size
+	}
+}
+
+// estimateBandwidth returns the bandwidth in bytes/s to estimate the
+// time taken with, --estimate-bandwidth if set or the current
+// --bwlimit, or 0 if neither is set
+func (s *StatsInfo) estimateBandwidth() int64 {
+	if s.ci.EstimateBandwidth > 0 {
+		return int64(s.ci.EstimateBandwidth)
+	}
+	limit := s.ci.BwLimit.LimitAt(time.Now()).Bandwidth
+	for _, bw := range []fs.SizeSuffix{limit.Tx, limit.Rx} {
+		if bw > 0 {
+			return int64(bw)
+		}
+	}
+	return 0
+}
+
+// estimatedTime returns the time --estimate expects streaming the
+// files to take and whether it could be estimated
+//
+// Call with lock held
+func (s *StatsInfo) estimatedTime() (time.Duration, bool) {
+	bw := s.estimateBandwidth()
+	if bw <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(s.estStreamBytes) / float64(bw) * float64(time.Second)), true
+}
+
+// estimateString describes the --estimate stats
+//
+// Call with lock held
+func (s *StatsInfo) estimateString() string {
+	timeString := "set --estimate-bandwidth to estimate the time"
+	if d, ok := s.estimatedTime(); ok {
+		timeString = fmt.Sprintf("%s at %s", fs.Duration(d).ReadableString(), fs.SizeSuffix(s.estimateBandwidth()).ByteRateUnit())
+	}
+	return fmt.Sprintf("%s streamed (%d files), %s server-side (%d files), %s",
+		fs.SizeSuffix(s.estStreamBytes).ByteUnit(), s.estStreams,
+		fs.SizeSuffix(s.estServerBytes).ByteUnit(), s.estServerSide,
+		timeString)
+}
+
+// EstimateString describes the bytes, operations and time a --dry-run
+// with --estimate expects the transfers to take
+func (s *StatsInfo) EstimateString() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.estimateString()
+}
+
+const (
+	// maxAPIEndpoints is the number of different endpoints counted
+	// before the rest are counted as "other"
+	maxAPIEndpoints = 1000
+	// maxAPICallsShown is the number of endpoints shown in the stats
+	maxAPICallsShown = 5
+)
+
+// APICall counts a call to the API endpoint
+func (s *StatsInfo) APICall(endpoint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addAPICalls(endpoint, 1)
+}
+
+// addAPICalls adds n calls of endpoint - call with the lock held
+func (s *StatsInfo) addAPICalls(endpoint string, n int64) {
+	if s.apiCalls == nil {
+		s.apiCalls = make(map[string]int64)
+	}
+	if _, found := s.apiCalls[endpoint]; !found && len(s.apiCalls) >= maxAPIEndpoints {
+		endpoint = "other"
+	}
+	s.apiCalls[endpoint] += n
+}
+
+// GetAPICalls returns the number of calls made to each API endpoint
+func (s *StatsInfo) GetAPICalls() map[string]int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.copyAPICalls()
+}
+
+// copyAPICalls returns a copy of apiCalls - call with the lock held
+func (s *StatsInfo) copyAPICalls() map[string]int64 {
+	calls := make(map[string]int64, len(s.apiCalls))
+	for endpoint, n := range s.apiCalls {
+		calls[endpoint] = n
+	}
+	return calls
+}
+
+// totalAPICalls returns the total number of API calls - call with the
+// lock held
+func (s *StatsInfo) totalAPICalls() (total int64) {
+	for _, n := range s.apiCalls {
+		total += n
+	}
+	return total
+}
+
+// topAPICalls returns the n most called endpoints as a string - call
+// with the lock held
+func (s *StatsInfo) topAPICalls(n int) string {
+	endpoints := make([]string, 0, len(s.apiCalls))
+	for endpoint := range s.apiCalls {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		a, b := endpoints[i], endpoints[j]
+		if s.apiCalls[a] != s.apiCalls[b] {
+			return s.apiCalls[a] > s.apiCalls[b]
+		}
+		return a < b
+	})
+	var out []string
+	for i, endpoint := range endpoints {
+		if i >= n {
+			out = append(out, "...")
+			break
+		}
+		out = append(out, fmt.Sprintf("%s %d", endpoint, s.apiCalls[endpoint]))
+	}
+	return strings.Join(out, ", ")
+}
+
+// ResetCounters sets the counters (bytes, checks, errors, transfers, deletes, renames, verifies, flagged, estimates) to 0 and resets lastError, fatalError and retryError
 func (s *StatsInfo) ResetCounters() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -611,8 +830,18 @@ func (s *StatsInfo) ResetCounters() {
 	s.checks = 0
 	s.transfers = 0
 	s.deletes = 0
+	s.deleteQueue = 0
 	s.deletedDirs = 0
 	s.renames = 0
+	s.verifies = 0
+	s.verifyFailures = 0
+	s.flagged = 0
+	s.estStreams = 0
+	s.estStreamBytes = 0
+	s.estServerSide = 0
+	s.estServerBytes = 0
+	s.apiCalls = nil
+	s.failed = nil
 	s.startedTransfers = nil
 	s.oldDuration = 0
 
@@ -620,7 +849,7 @@ func (s *StatsInfo) ResetCounters() {
 	s.average = averageValues{stop: make(chan bool)}
 }
 
-// ResetErrors sets the errors count to 0 and resets lastError, fatalError and retryError
+// ResetErrors sets the errors count to 0 and resets lastError, fatalError, retryError and the failed transfers
 func (s *StatsInfo) ResetErrors() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -629,6 +858,36 @@ func (s *StatsInfo) ResetErrors() {
 	s.fatalError = false
 	s.retryError = false
 	s.retryAfter = time.Time{}
+	s.failed = nil
+}
+
+// UncountError takes an error which is being retried off the errors
+// count, resetting lastError, retryError and retryAfter if there are
+// none left
+func (s *StatsInfo) UncountError() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.errors > 0 {
+		s.errors--
+	}
+	if s.errors == 0 && !s.fatalError {
+		s.lastError = nil
+		s.retryError = false
+		s.retryAfter = time.Time{}
+	}
+}
+
+// FailedTransfers returns the sorted remotes of the transfers which
+// failed and haven't since succeeded
+func (s *StatsInfo) FailedTransfers() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	remotes := make([]string, 0, len(s.failed))
+	for remote := range s.failed {
+		remotes = append(remotes, remote)
+	}
+	sort.Strings(remotes)
+	return remotes
 }
 
 // Errored returns whether there have been any errors
@@ -674,6 +933,7 @@ func (s *StatsInfo) RetryAfter() time.Time {
 
 // NewCheckingTransfer adds a checking transfer to the stats, from the object.
 func (s *StatsInfo) NewCheckingTransfer(obj fs.Object) *Transfer {
+	transferGate.waitStart(s.ctx)
 	tr := newCheckingTransfer(s, obj)
 	s.checking.add(tr)
 	return tr
@@ -696,6 +956,7 @@ func (s *StatsInfo) GetTransfers() int64 {
 
 // NewTransfer adds a transfer to the stats from the object.
 func (s *StatsInfo) NewTransfer(obj fs.Object) *Transfer {
+	transferGate.waitStart(s.ctx)
 	tr := newTransfer(s, obj)
 	s.transferring.add(tr)
 	s.startAverageLoop()
@@ -704,6 +965,7 @@ func (s *StatsInfo) NewTransfer(obj fs.Object) *Transfer {
 
 // NewTransferRemoteSize adds a transfer to the stats based on remote and size.
 func (s *StatsInfo) NewTransferRemoteSize(remote string, size int64) *Transfer {
+	transferGate.waitStart(s.ctx)
 	tr := newTransferRemoteSize(s, remote, size, false)
 	s.transferring.add(tr)
 	s.startAverageLoop()
@@ -715,11 +977,17 @@ func (s *StatsInfo) NewTransferRemoteSize(remote string, size int64) *Transfer {
 // if ok is true then it increments the transfers count
 func (s *StatsInfo) DoneTransferring(remote string, ok bool) {
 	s.transferring.del(remote)
+	s.mu.Lock()
 	if ok {
-		s.mu.Lock()
 		s.transfers++
-		s.mu.Unlock()
+		delete(s.failed, remote)
+	} else {
+		if s.failed == nil {
+			s.failed = make(map[string]struct{})
+		}
+		s.failed[remote] = struct{}{}
 	}
+	s.mu.Unlock()
 	if s.transferring.empty() {
 		time.AfterFunc(averageStopAfter, s.stopAverageLoop)
 	}
@@ -91,17 +91,30 @@ Returns the following values:
 	"deletes" : number of files deleted,
 	"elapsedTime": time in floating point seconds since rclone was started,
 	"errors": number of errors,
+	"estimatedServerSide": number of files --estimate expects to copy server-side (with --estimate only),
+	"estimatedServerSideBytes": bytes --estimate expects to copy server-side (with --estimate only),
+	"estimatedStreamBytes": bytes --estimate expects to upload or download (with --estimate only),
+	"estimatedStreams": number of files --estimate expects to upload or download (with --estimate only),
+	"estimatedTime": time in seconds --estimate expects the streams to take or null if unknown (with --estimate only),
 	"eta": estimated time in seconds until the group completes,
 	"fatalError": boolean whether there has been at least one fatal error,
+	"flagged": number of files flagged by the content scanner,
 	"lastError": last error string,
 	"renames" : number of files renamed,
 	"retryError": boolean showing whether there has been at least one non-NoRetryError,
 	"speed": average speed in bytes per second since start of the group,
 	"totalBytes": total number of bytes in the group,
 	"totalChecks": total number of checks in the group,
+	"totalDeletes": total number of files found to delete by delete and purge,
 	"totalTransfers": total number of transfers in the group,
 	"transferTime" : total time spent on running jobs,
 	"transfers": number of transferred files,
+	"verifies": number of files verified with --verify,
+	"verifyFailures": number of files which didn't match when verified,
+	"apiCalls": a map of the number of HTTP calls to each API endpoint
+		{
+			"www.googleapis.com: GET /drive/v3/files": 1200
+		},
 	"transferring": an array of currently active file transfers:
 		[
 			{
@@ -118,7 +131,7 @@ Returns the following values:
 		[]
 }
 ` + "```" + `
-Values for "transferring", "checking" and "lastError" are only assigned if data is available.
+Values for "transferring", "checking", "apiCalls" and "lastError" are only assigned if data is available.
 The value for "eta" is null if an eta cannot be determined.
 `,
 	})
@@ -364,8 +377,19 @@ func (sg *statsGroups) sum(ctx context.Context) *StatsInfo {
 			sum.checks += stats.checks
 			sum.transfers += stats.transfers
 			sum.deletes += stats.deletes
+			sum.deleteQueue += stats.deleteQueue
 			sum.deletedDirs += stats.deletedDirs
 			sum.renames += stats.renames
+			sum.verifies += stats.verifies
+			sum.verifyFailures += stats.verifyFailures
+			sum.flagged += stats.flagged
+			sum.estStreams += stats.estStreams
+			sum.estStreamBytes += stats.estStreamBytes
+			sum.estServerSide += stats.estServerSide
+			sum.estServerBytes += stats.estServerBytes
+			for endpoint, n := range stats.apiCalls {
+				sum.addAPICalls(endpoint, n)
+			}
 			sum.checking.merge(stats.checking)
 			sum.transferring.merge(stats.transferring)
 			sum.inProgress.merge(stats.inProgress)
@@ -436,3 +436,94 @@ func TestPruneTransfers(t *testing.T) {
 		})
 	}
 }
+
+func TestStatsAPICalls(t *testing.T) {
+	ctx := context.Background()
+	s := NewStats(ctx)
+	assert.NotContains(t, s.String(), "API calls")
+	out, err := s.RemoteStats()
+	require.NoError(t, err)
+	assert.NotContains(t, out, "apiCalls")
+
+	for i := 0; i < 3; i++ {
+		s.APICall("host: GET /files")
+	}
+	s.APICall("host: POST /files")
+	assert.Equal(t, map[string]int64{"host: GET /files": 3, "host: POST /files": 1}, s.GetAPICalls())
+	assert.Contains(t, s.String(), "API calls:              4 (host: GET /files 3, host: POST /files 1)\n")
+	out, err = s.RemoteStats()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"host: GET /files": 3, "host: POST /files": 1}, out["apiCalls"])
+
+	// Too many endpoints are counted as other
+	for i := 0; i < maxAPIEndpoints; i++ {
+		s.APICall(fmt.Sprintf("host: GET /%d", i))
+	}
+	calls := s.GetAPICalls()
+	assert.Equal(t, maxAPIEndpoints+1, len(calls))
+	assert.Equal(t, int64(2), calls["other"])
+	assert.Contains(t, s.String(), ", ...)")
+
+	s.ResetCounters()
+	assert.Equal(t, map[string]int64{}, s.GetAPICalls())
+}
+
+func TestStatsFailedTransfers(t *testing.T) {
+	ctx := context.Background()
+	s := NewStats(ctx)
+	s.DoneTransferring("b", false)
+	s.DoneTransferring("a", false)
+	s.DoneTransferring("c", true)
+	assert.Equal(t, []string{"a", "b"}, s.FailedTransfers())
+
+	// Succeeding on a retry takes it off the list
+	s.DoneTransferring("b", true)
+	assert.Equal(t, []string{"a"}, s.FailedTransfers())
+
+	s.ResetErrors()
+	assert.Equal(t, []string{}, s.FailedTransfers())
+}
+
+func TestStatsUncountError(t *testing.T) {
+	ctx := context.Background()
+	s := NewStats(ctx)
+	s.Error(fserrors.RetryErrorf("retry 1"))
+	s.Error(fserrors.RetryErrorf("retry 2"))
+	s.UncountError()
+	assert.Equal(t, int64(1), s.GetErrors())
+	assert.True(t, s.HadRetryError())
+	s.UncountError()
+	assert.Equal(t, int64(0), s.GetErrors())
+	assert.False(t, s.Errored())
+	assert.False(t, s.HadRetryError())
+	assert.Nil(t, s.GetLastError())
+}
+
+func TestStatsEstimated(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.Estimate = true
+	s := NewStats(ctx)
+	assert.NotContains(t, s.String(), "Estimated")
+
+	s.Estimated(false, 10*1024*1024)
+	s.Estimated(false, 20*1024*1024)
+	s.Estimated(true, 1024*1024*1024)
+	assert.Contains(t, s.String(), "Estimated:     30 MiByte streamed (2 files), 1 GiByte server-side (1 files), set --estimate-bandwidth to estimate the time\n")
+	out, err := s.RemoteStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), out["estimatedStreams"])
+	assert.Equal(t, int64(30*1024*1024), out["estimatedStreamBytes"])
+	assert.Equal(t, int64(1), out["estimatedServerSide"])
+	assert.Equal(t, int64(1024*1024*1024), out["estimatedServerSideBytes"])
+	assert.Nil(t, out["estimatedTime"])
+
+	ci.EstimateBandwidth = fs.SizeSuffix(1024 * 1024)
+	assert.Equal(t, "30 MiByte streamed (2 files), 1 GiByte server-side (1 files), 30s at 1 MiByte/s", s.EstimateString())
+	out, err = s.RemoteStats()
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, out["estimatedTime"])
+
+	s.ResetCounters()
+	assert.NotContains(t, s.String(), "Estimated")
+}
@@ -0,0 +1,172 @@
+// Suspend and resume transfers and drain them on shutdown
+
+package accounting
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/rc"
+)
+
+// gate stops transfers starting or moving data
+type gate struct {
+	closed    int32 // non zero if suspended or draining - read with atomic
+	mu        sync.Mutex
+	suspended bool          // set if transfers are suspended
+	draining  bool          // set if no new transfers may start
+	changed   chan struct{} // closed and remade when the state changes
+}
+
+var transferGate = gate{changed: make(chan struct{})}
+
+// set changes the state with f - call without the lock held
+func (g *gate) set(f func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	f()
+	closed := int32(0)
+	if g.suspended || g.draining {
+		closed = 1
+	}
+	atomic.StoreInt32(&g.closed, closed)
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// wait waits until blocked returns false or ctx is cancelled
+//
+// blocked is only called, with the lock held, if the gate is
+// suspended or draining so the gate is cheap to pass otherwise.
+func (g *gate) wait(ctx context.Context, blocked func() bool) {
+	if atomic.LoadInt32(&g.closed) == 0 {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for {
+		g.mu.Lock()
+		isBlocked, changed := blocked(), g.changed
+		g.mu.Unlock()
+		if !isBlocked {
+			return
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// waitStart waits until new transfers and checks may start
+func (g *gate) waitStart(ctx context.Context) {
+	g.wait(ctx, func() bool {
+		return g.suspended || g.draining
+	})
+}
+
+// waitRead waits until transfers in progress may move data
+func (g *gate) waitRead(ctx context.Context) {
+	g.wait(ctx, func() bool {
+		return g.suspended && !g.draining
+	})
+}
+
+// Suspend stops new transfers and checks starting and pauses the
+// transfers in progress until Resume is called.
+func Suspend() {
+	transferGate.set(func() {
+		transferGate.suspended = true
+	})
+	fs.Logf(nil, "Transfers suspended")
+}
+
+// Resume restarts the transfers stopped by Suspend
+func Resume() {
+	transferGate.set(func() {
+		transferGate.suspended = false
+	})
+	fs.Logf(nil, "Transfers resumed")
+}
+
+// Suspended returns true if transfers are suspended
+func Suspended() bool {
+	transferGate.mu.Lock()
+	defer transferGate.mu.Unlock()
+	return transferGate.suspended
+}
+
+// Drain stops new transfers and checks starting, even if resumed, and
+// waits up to timeout for the transfers in progress to finish.
+//
+// It is used to shut down gracefully. Suspended transfers are
+// restarted so they can finish.
+func Drain(ctx context.Context, timeout time.Duration) {
+	transferGate.set(func() {
+		transferGate.draining = true
+	})
+	fs.Logf(nil, "Waiting up to %v for transfers in progress to finish", timeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+	for {
+		if groups.sum(ctx).transferring.empty() {
+			fs.Infof(nil, "Transfers in progress have finished")
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			fs.Logf(nil, "Timed out waiting for transfers to finish")
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// rcSuspend suspends the transfers
+func rcSuspend(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	Suspend()
+	return rc.Params{"suspended": Suspended()}, nil
+}
+
+// rcResume resumes the transfers
+func rcResume(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	Resume()
+	return rc.Params{"suspended": Suspended()}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "core/suspend",
+		Fn:    rcSuspend,
+		Title: "Suspend all transfers.",
+		Help: `
+This stops new transfers and checks starting and pauses the data of
+the transfers in progress until core/resume is called. This can be
+used to pause a long sync without losing the progress made so far.
+
+Note that connections paused for longer than the --timeout may be
+dropped by the provider, in which case the transfer is retried from
+the start when resumed.
+
+Returns
+- suspended - true
+`,
+	})
+	rc.Add(rc.Call{
+		Path:  "core/resume",
+		Fn:    rcResume,
+		Title: "Resume transfers stopped with core/suspend.",
+		Help: `
+Returns
+- suspended - false
+`,
+	})
+}
@@ -0,0 +1,117 @@
+package accounting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// started returns a channel which is closed when fn returns
+func started(fn func()) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+	return done
+}
+
+// finishes returns true if done is closed within a second
+func finishes(done chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+// blocks returns true if done isn't closed within 100ms
+func blocks(done chan struct{}) bool {
+	select {
+	case <-done:
+		return false
+	case <-time.After(100 * time.Millisecond):
+		return true
+	}
+}
+
+func TestSuspendResume(t *testing.T) {
+	ctx := context.Background()
+	s := NewStats(ctx)
+	assert.False(t, Suspended())
+
+	Suspend()
+	assert.True(t, Suspended())
+	transfer := started(func() {
+		s.NewTransferRemoteSize("file", 1).Done(ctx, nil)
+	})
+	read := started(func() {
+		transferGate.waitRead(ctx)
+	})
+	assert.True(t, blocks(transfer))
+	assert.True(t, blocks(read))
+
+	// Cancelling the context stops the wait
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancelled := started(func() {
+		transferGate.waitStart(cancelCtx)
+	})
+	cancel()
+	assert.True(t, finishes(cancelled))
+
+	Resume()
+	assert.False(t, Suspended())
+	assert.True(t, finishes(transfer))
+	assert.True(t, finishes(read))
+	assert.Equal(t, int64(1), s.GetTransfers())
+}
+
+func TestGateOpenDoesntLock(t *testing.T) {
+	ctx := context.Background()
+	transferGate.mu.Lock()
+	read := started(func() {
+		transferGate.waitRead(ctx)
+	})
+	assert.True(t, finishes(read))
+	transferGate.mu.Unlock()
+}
+
+func TestDrain(t *testing.T) {
+	ctx := context.Background()
+	defer transferGate.set(func() {
+		transferGate.draining = false
+	})
+	s := NewStatsGroup(ctx, "drain-test")
+	defer groups.delete("drain-test")
+	tr := s.NewTransferRemoteSize("file", 1)
+
+	// Draining times out if the transfers don't finish
+	start := time.Now()
+	Drain(ctx, 200*time.Millisecond)
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+
+	// Suspended transfers in progress carry on while draining
+	Suspend()
+	defer Resume()
+	drained := started(func() {
+		Drain(ctx, time.Minute)
+	})
+	read := started(func() {
+		transferGate.waitRead(ctx)
+	})
+	assert.True(t, finishes(read))
+
+	// New transfers don't start
+	transfer := started(func() {
+		s.NewTransferRemoteSize("file2", 1).Done(ctx, nil)
+	})
+	assert.True(t, blocks(transfer))
+	assert.True(t, blocks(drained))
+
+	// Draining finishes when the transfers in progress do
+	tr.Done(ctx, nil)
+	assert.True(t, finishes(drained))
+}
@@ -35,6 +35,7 @@ type AsyncReader struct {
 	token   chan struct{}  // Tokens which allow a buffer to be taken
 	exit    chan struct{}  // Closes when finished
 	buffers int            // Number of buffers
+	active  int            // Number of buffers currently in use for read ahead
 	err     error          // If an error has occurred it is here
 	cur     *buffer        // Current buffer being served
 	exited  chan struct{}  // Channel is closed been the async reader shuts down
@@ -51,31 +52,42 @@ type AsyncReader struct {
 // The input can be read from the returned reader.
 // When done use Close to release the buffers and close the supplied input.
 func New(ctx context.Context, rd io.ReadCloser, buffers int) (*AsyncReader, error) {
+	return NewWindow(ctx, rd, buffers, buffers)
+}
+
+// NewWindow returns a reader like New which starts by reading ahead
+// only initial buffers. The read ahead can be increased up to buffers
+// with Grow.
+func NewWindow(ctx context.Context, rd io.ReadCloser, initial, buffers int) (*AsyncReader, error) {
 	if buffers <= 0 {
 		return nil, errors.New("number of buffers too small")
 	}
 	if rd == nil {
 		return nil, errors.New("nil reader supplied")
 	}
+	if initial <= 0 || initial > buffers {
+		initial = buffers
+	}
 	a := &AsyncReader{
 		ci: fs.GetConfig(ctx),
 	}
-	a.init(rd, buffers)
+	a.init(rd, initial, buffers)
 	return a, nil
 }
 
-func (a *AsyncReader) init(rd io.ReadCloser, buffers int) {
+func (a *AsyncReader) init(rd io.ReadCloser, active, buffers int) {
 	a.in = rd
 	a.ready = make(chan *buffer, buffers)
 	a.token = make(chan struct{}, buffers)
 	a.exit = make(chan struct{}, 0)
 	a.exited = make(chan struct{}, 0)
 	a.buffers = buffers
+	a.active = active
 	a.cur = nil
 	a.size = softStartInitial
 
 	// Create tokens
-	for i := 0; i < buffers; i++ {
+	for i := 0; i < active; i++ {
 		a.token <- struct{}{}
 	}
 
@@ -202,6 +214,30 @@ func (a *AsyncReader) WriteTo(w io.Writer) (n int64, err error) {
 	}
 }
 
+// Grow increases the number of buffers used for reading ahead to n.
+//
+// It can't increase it beyond the number of buffers the AsyncReader
+// was created with, and it never decreases it.
+func (a *AsyncReader) Grow(n int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if n > a.buffers {
+		n = a.buffers
+	}
+	// There are never more than a.active tokens so this can't block
+	for ; a.active < n; a.active++ {
+		a.token <- struct{}{}
+	}
+}
+
+// ReadAhead returns the number of buffers currently used for reading
+// ahead.
+func (a *AsyncReader) ReadAhead() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.active
+}
+
 // SkipBytes will try to seek 'skip' bytes relative to the current position.
 // On success it returns true. If 'skip' is outside the current buffer data or
 // an error occurs, Abandon is called and false is returned.
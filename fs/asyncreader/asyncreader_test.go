@@ -378,3 +378,35 @@ func TestAsyncReaderSkipBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestAsyncReaderGrow(t *testing.T) {
+	ctx := context.Background()
+
+	data := make([]byte, 3*BufferSize+100)
+	_, _ = rand.New(rand.NewSource(42)).Read(data)
+	ar, err := NewWindow(ctx, ioutil.NopCloser(bytes.NewReader(data)), 1, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 1, ar.ReadAhead())
+
+	ar.Grow(3)
+	assert.Equal(t, 3, ar.ReadAhead())
+
+	// Can't grow beyond the number of buffers
+	ar.Grow(10)
+	assert.Equal(t, 4, ar.ReadAhead())
+
+	// Or shrink
+	ar.Grow(2)
+	assert.Equal(t, 4, ar.ReadAhead())
+
+	got, err := ioutil.ReadAll(ar)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+	require.NoError(t, ar.Close())
+
+	// A bad initial value means use all the buffers
+	ar, err = NewWindow(ctx, ioutil.NopCloser(bytes.NewReader(data)), 0, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 4, ar.ReadAhead())
+	require.NoError(t, ar.Close())
+}
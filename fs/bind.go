@@ -0,0 +1,98 @@
+// Parsing of the network address options
+
+package fs
+
+import (
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ParseBindAddr parses the value of --bind or the bind config
+// parameter of a remote which is a comma separated list of IP
+// addresses, network interface names or host names.
+//
+// If an interface has more than one address its first global IPv4
+// address is used, or failing that its first global IPv6 address.
+func ParseBindAddr(bind string) (addrs []net.IP, err error) {
+	for _, item := range strings.Split(bind, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		addr, err := parseBindItem(item)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return nil, errors.Errorf("no addresses found in %q", bind)
+	}
+	return addrs, nil
+}
+
+// parseBindItem parses a single address, interface or host name
+func parseBindItem(item string) (net.IP, error) {
+	if ip := net.ParseIP(item); ip != nil {
+		return ip, nil
+	}
+	if iface, err := net.InterfaceByName(item); err == nil {
+		ifaceAddrs, err := iface.Addrs()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read addresses of interface %q", item)
+		}
+		var ipv6 net.IP
+		for _, ifaceAddr := range ifaceAddrs {
+			ipNet, ok := ifaceAddr.(*net.IPNet)
+			if !ok || !ipNet.IP.IsGlobalUnicast() {
+				continue
+			}
+			if ipNet.IP.To4() != nil {
+				return ipNet.IP, nil
+			}
+			if ipv6 == nil {
+				ipv6 = ipNet.IP
+			}
+		}
+		if ipv6 == nil {
+			return nil, errors.Errorf("interface %q has no usable addresses", item)
+		}
+		return ipv6, nil
+	}
+	addrs, err := net.LookupIP(item)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q as IP address", item)
+	}
+	if len(addrs) != 1 {
+		return nil, errors.Errorf("expecting 1 IP address for %q but got %d", item, len(addrs))
+	}
+	return addrs[0], nil
+}
+
+// ParseDNSOverride parses the values of --dns-override or the
+// dns_override config parameter of a remote. Each value is a comma
+// separated list of host=IP items.
+func ParseDNSOverride(values []string) (map[string]net.IP, error) {
+	overrides := make(map[string]net.IP)
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			equals := strings.IndexRune(item, '=')
+			if equals < 0 {
+				return nil, errors.Errorf("expecting host=IP but got %q", item)
+			}
+			host := strings.ToLower(strings.TrimSpace(item[:equals]))
+			ip := net.ParseIP(strings.TrimSpace(item[equals+1:]))
+			if host == "" || ip == nil {
+				return nil, errors.Errorf("expecting host=IP but got %q", item)
+			}
+			overrides[host] = ip
+		}
+	}
+	return overrides, nil
+}
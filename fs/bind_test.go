@@ -0,0 +1,48 @@
+package fs
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBindAddr(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    []net.IP
+		wantErr bool
+	}{
+		{"127.0.0.1", []net.IP{net.ParseIP("127.0.0.1")}, false},
+		{"::1", []net.IP{net.ParseIP("::1")}, false},
+		{"192.168.1.2, 192.168.2.2", []net.IP{net.ParseIP("192.168.1.2"), net.ParseIP("192.168.2.2")}, false},
+		{"10.0.0.1,,", []net.IP{net.ParseIP("10.0.0.1")}, false},
+		{"", nil, true},
+		{" , ", nil, true},
+		{"10.0.0.1,not.a.host.invalid", nil, true},
+	} {
+		got, err := ParseBindAddr(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestParseDNSOverride(t *testing.T) {
+	got, err := ParseDNSOverride([]string{"s3.Example.com=192.0.2.1", " a.example.com = 2001:db8::1 , b.example.com=192.0.2.2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]net.IP{
+		"s3.example.com": net.ParseIP("192.0.2.1"),
+		"a.example.com":  net.ParseIP("2001:db8::1"),
+		"b.example.com":  net.ParseIP("192.0.2.2"),
+	}, got)
+
+	for _, in := range []string{"example.com", "example.com=", "=192.0.2.1", "example.com=example.org"} {
+		_, err = ParseDNSOverride([]string{in})
+		assert.Error(t, err, in)
+	}
+}
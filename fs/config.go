@@ -49,6 +49,8 @@ type ConfigInfo struct {
 	StatsLogLevel          LogLevel
 	UseJSONLog             bool
 	DryRun                 bool
+	Estimate               bool
+	EstimateBandwidth      SizeSuffix
 	Interactive            bool
 	CheckSum               bool
 	SizeOnly               bool
@@ -57,17 +59,24 @@ type ConfigInfo struct {
 	IgnoreErrors           bool
 	ModifyWindow           time.Duration
 	Checkers               int
+	ListParallelism        int
+	ListCutoff             int
 	Transfers              int
 	ConnectTimeout         time.Duration // Connect timeout
 	Timeout                time.Duration // Data channel timeout
 	ExpectContinueTimeout  time.Duration
 	Dump                   DumpFlags
+	DumpHAR                string
+	DumpHARBody            SizeSuffix
 	InsecureSkipVerify     bool // Skip server certificate verification
 	DeleteMode             DeleteMode
 	MaxDelete              int64
 	TrackRenames           bool   // Track file renames.
 	TrackRenamesStrategy   string // Comma separated list of strategies used to track renames
 	LowLevelRetries        int
+	AdaptivePacer          bool
+	TransferRetries        int
+	TransferRetriesSleep   time.Duration
 	UpdateOlder            bool // Skip files that are newer on the destination
 	NoGzip                 bool // Disable compression
 	MaxDepth               int
@@ -85,13 +94,26 @@ type ConfigInfo struct {
 	BackupDir              string
 	Suffix                 string
 	SuffixKeepExtension    bool
+	AtomicUpload           bool   // upload to a temporary name and rename when complete
+	PartialSuffix          string // suffix of the temporary names for AtomicUpload
+	PreTransferCmd         SpaceSepList
+	PostTransferCmd        SpaceSepList
+	PostTransferURL        string
+	TransferFilterCmd      SpaceSepList
+	ScanClamd              string
+	ScanICAP               string
+	ScanQuarantine         string
+	Report                 string
 	UseListR               bool
 	BufferSize             SizeSuffix
 	BwLimit                BwTimetable
 	BwLimitFile            BwTimetable
 	TPSLimit               float64
 	TPSLimitBurst          int
-	BindAddr               net.IP
+	BindAddr               []net.IP // local addresses to use in turn for outgoing connections
+	IPv4Only               bool
+	IPv6Only               bool
+	DNSOverride            map[string]net.IP // IP addresses to use for host names instead of resolving them
 	DisableFeatures        []string
 	UserAgent              string
 	Immutable              bool
@@ -100,10 +122,14 @@ type ConfigInfo struct {
 	StatsFileNameLength    int
 	AskPassword            bool
 	PasswordCommand        SpaceSepList
+	ConfigRecipients       []string
+	ConfigIdentities       []string
 	UseServerModTime       bool
+	SnapshotTime           time.Time // show versioned remotes as they were at this time if set
 	MaxTransfer            SizeSuffix
 	MaxDuration            time.Duration
 	CutoffMode             CutoffMode
+	ShutdownTimeout        time.Duration
 	MaxBacklog             int
 	MaxStatsGroups         int
 	StatsOneLine           bool
@@ -125,11 +151,16 @@ type ConfigInfo struct {
 	DownloadHeaders        []*HTTPOption
 	Headers                []*HTTPOption
 	RefreshTimes           bool
+	RefreshMetadata        bool
+	Metadata               bool
+	Verify                 bool
 	NoConsole              bool
 	TrafficClass           uint8
 	FsCacheExpireDuration  time.Duration
 	FsCacheExpireInterval  time.Duration
 	DisableHTTP2           bool
+	UseListingCache        bool
+	ListingCacheMaxAge     time.Duration
 }
 
 // NewConfig creates a new config with everything set to the default
@@ -150,6 +181,8 @@ func NewConfig() *ConfigInfo {
 	c.DeleteMode = DeleteModeDefault
 	c.MaxDelete = -1
 	c.LowLevelRetries = 10
+	c.AdaptivePacer = true
+	c.TransferRetriesSleep = 10 * time.Second
 	c.MaxDepth = -1
 	c.DataRateUnit = "bytes"
 	c.BufferSize = SizeSuffix(16 << 20)
@@ -160,6 +193,7 @@ func NewConfig() *ConfigInfo {
 	c.AskPassword = true
 	c.TPSLimitBurst = 1
 	c.MaxTransfer = -1
+	c.EstimateBandwidth = -1
 	c.MaxBacklog = 10000
 	// We do not want to set the default here. We use this variable being empty as part of the fall-through of options.
 	//	c.StatsOneLineDateFormat = "2006/01/02 15:04:05 - "
@@ -169,6 +203,8 @@ func NewConfig() *ConfigInfo {
 	c.TrackRenamesStrategy = "hash"
 	c.FsCacheExpireDuration = 300 * time.Second
 	c.FsCacheExpireInterval = 60 * time.Second
+	c.ListingCacheMaxAge = 10 * time.Minute
+	c.PartialSuffix = ".partial"
 
 	// Perform a simple check for debug flags to enable debug logging during the flag initialization
 	for argIndex, arg := range os.Args {
@@ -187,6 +223,15 @@ func NewConfig() *ConfigInfo {
 	return c
 }
 
+// Listers returns the number of directories to list in parallel
+// which is ci.ListParallelism if > 0 or ci.Checkers otherwise
+func (c *ConfigInfo) Listers() int {
+	if c.ListParallelism > 0 {
+		return c.ListParallelism
+	}
+	return c.Checkers
+}
+
 // TimeoutOrInfinite returns ci.Timeout if > 0 or infinite otherwise
 func (c *ConfigInfo) TimeoutOrInfinite() time.Duration {
 	if c.Timeout > 0 {
@@ -112,9 +112,10 @@ var (
 )
 
 var (
-	configPath string
-	data       Storage
-	dataLoaded bool
+	configPath  string
+	data        Storage
+	dataLoaded  bool
+	dataLoading bool // set while data is being loaded
 )
 
 func init() {
@@ -321,6 +322,8 @@ func SetConfigPath(path string) (err error) {
 		cfgPath = ""
 	} else if filepath.Base(path) == noConfigFile {
 		cfgPath = ""
+	} else if IsStorePath(path) {
+		cfgPath = path
 	} else if err = file.IsReserved(path); err != nil {
 		return err
 	} else if cfgPath, err = filepath.Abs(path); err != nil {
@@ -343,12 +346,25 @@ func Data() Storage {
 
 // LoadedData ensures the config file storage is loaded and returns it
 func LoadedData() Storage {
+	if dataLoading {
+		// Loading from a Store may need to make a backend which
+		// reads the config, so give it an empty one rather than
+		// loading it again.
+		return newDefaultStorage()
+	}
 	if !dataLoaded {
 		// Set RCLONE_CONFIG_DIR for backend config and subprocesses
 		// If empty configPath (in-memory only) the value will be "."
-		_ = os.Setenv("RCLONE_CONFIG_DIR", filepath.Dir(configPath))
+		if IsStorePath(configPath) {
+			_ = os.Setenv("RCLONE_CONFIG_DIR", ".")
+		} else {
+			_ = os.Setenv("RCLONE_CONFIG_DIR", filepath.Dir(configPath))
+		}
 		// Load configuration from file (or initialize sensible default if no file or error)
-		if err := data.Load(); err == nil {
+		dataLoading = true
+		err := data.Load()
+		dataLoading = false
+		if err == nil {
 			fs.Debugf(nil, "Using config file from %q", configPath)
 			dataLoaded = true
 		} else if err == ErrorConfigFileNotFound {
@@ -381,6 +397,9 @@ func SaveConfig() {
 	for i := 0; i < ci.LowLevelRetries+1; i++ {
 		if err = LoadedData().Save(); err == nil {
 			return
+		} else if errors.Cause(err) == ErrorConfigReadOnly {
+			fs.Logf(nil, "Not saving config changes as %q is read only", configPath)
+			return
 		}
 		waitingTimeMs := mathrand.Intn(1000)
 		time.Sleep(time.Duration(waitingTimeMs) * time.Millisecond)
@@ -629,7 +648,7 @@ var matchEnv = regexp.MustCompile(`^RCLONE_CONFIG_(.*?)_TYPE=.*$`)
 // FileSections returns the sections in the config file
 // including any defined by environment variables.
 func FileSections() []string {
-	sections := LoadedData().GetSectionList()
+	sections := remoteSections()
 	for _, item := range os.Environ() {
 		matches := matchEnv.FindStringSubmatch(item)
 		if len(matches) == 2 {
@@ -3,6 +3,7 @@ package configfile
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -32,7 +33,7 @@ func (s *Storage) check() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if configPath := config.GetConfigPath(); configPath != "" {
+	if configPath := config.GetConfigPath(); configPath != "" && !config.IsStorePath(configPath) {
 		// Check to see if config file has changed since it was last loaded
 		fi, err := os.Stat(configPath)
 		if err == nil {
@@ -64,6 +65,14 @@ func (s *Storage) _load() (err error) {
 		return config.ErrorConfigFileNotFound
 	}
 
+	store, err := config.GetStore()
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		return s._loadStore(store)
+	}
+
 	fd, err := os.Open(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -90,6 +99,29 @@ func (s *Storage) _load() (err error) {
 	return nil
 }
 
+// _loadStore loads the config from store, decrypting if necessary
+//
+// mu must be held when calling this
+func (s *Storage) _loadStore(store config.Store) error {
+	data, err := store.Read(context.Background())
+	if err != nil {
+		return err
+	}
+
+	cryptReader, err := config.Decrypt(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	gc, err := goconfig.LoadFromReader(cryptReader)
+	if err != nil {
+		return err
+	}
+	s.gc = gc
+
+	return nil
+}
+
 // Load the config from permanent storage, decrypting if necessary
 func (s *Storage) Load() (err error) {
 	s.mu.Lock()
@@ -97,18 +129,43 @@ func (s *Storage) Load() (err error) {
 	return s._load()
 }
 
-// Save the config to permanent storage, encrypting if necessary
-func (s *Storage) Save() error {
+// saveStore saves the config to store, encrypting if necessary
+func (s *Storage) saveStore(store config.Store) error {
+	// Don't hold mu while writing as writing to the store may read
+	// the config
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	var buf, out bytes.Buffer
+	err := goconfig.SaveConfigData(s.gc, &buf)
+	if err == nil {
+		err = config.Encrypt(&buf, &out)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return errors.Wrap(err, "failed to save config")
+	}
+	return store.Write(context.Background(), out.Bytes())
+}
 
+// Save the config to permanent storage, encrypting if necessary
+func (s *Storage) Save() error {
 	configPath := config.GetConfigPath()
 	if configPath == "" {
 		return errors.Errorf("Failed to save config file: Path is empty")
 	}
 
+	store, err := config.GetStore()
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		return s.saveStore(store)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	dir, name := filepath.Split(configPath)
-	err := os.MkdirAll(dir, os.ModePerm)
+	err = os.MkdirAll(dir, os.ModePerm)
 	if err != nil {
 		return errors.Wrap(err, "failed to create config directory")
 	}
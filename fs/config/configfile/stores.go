@@ -0,0 +1,317 @@
+// Places other than local files which the config can be kept
+
+package configfile
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/object"
+)
+
+func init() {
+	config.RegisterStore("env", newEnvStore)
+	config.RegisterStore("vault", newVaultStore)
+	config.RegisterStore("awssm", newAWSStore)
+	config.RegisterStore("remote", newRemoteStore)
+}
+
+// envStore reads the config from the base64 encoded contents of an
+// environment variable, as given by --config env:NAME
+type envStore struct {
+	name string
+}
+
+func newEnvStore(name string) (config.Store, error) {
+	if name == "" {
+		return nil, errors.New("need the name of an environment variable")
+	}
+	return envStore{name: name}, nil
+}
+
+// Read the config from the environment variable
+func (s envStore) Read(ctx context.Context) ([]byte, error) {
+	value, found := os.LookupEnv(s.name)
+	if !found {
+		return nil, config.ErrorConfigFileNotFound
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode base64 config in $%s", s.name)
+	}
+	return data, nil
+}
+
+// Write is not supported as the environment can't be changed
+func (s envStore) Write(ctx context.Context, data []byte) error {
+	return config.ErrorConfigReadOnly
+}
+
+// vaultStore keeps the config in the "config" field of a secret in the
+// KV version 2 secrets engine of HashiCorp Vault, as given by
+// --config vault:MOUNT/PATH
+//
+// The server and token are read from VAULT_ADDR, VAULT_TOKEN and
+// VAULT_NAMESPACE like the vault command does.
+type vaultStore struct {
+	url       string // URL of the secret's data
+	token     string
+	namespace string
+}
+
+// vaultData is the body of the secret read and written
+type vaultData struct {
+	Data struct {
+		Config string `json:"config"`
+	} `json:"data"`
+}
+
+func newVaultStore(path string) (config.Store, error) {
+	path = strings.Trim(path, "/")
+	i := strings.IndexRune(path, '/')
+	if i <= 0 || i == len(path)-1 {
+		return nil, errors.New("need a path of the form vault:MOUNT/PATH")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return nil, errors.New("VAULT_TOKEN must be set to use a config in vault")
+	}
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		addr = "https://127.0.0.1:8200"
+	}
+	return &vaultStore{
+		url:       strings.TrimRight(addr, "/") + "/v1/" + path[:i] + "/data/" + path[i+1:],
+		token:     token,
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+	}, nil
+}
+
+// do makes a request to vault returning the response
+func (s *vaultStore) do(ctx context.Context, method string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("X-Vault-Token", s.token)
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return fshttp.NewClient(ctx).Do(req)
+}
+
+// vaultError returns an error for an unsuccessful response
+func vaultError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+	return errors.Errorf("vault returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
+
+// Read the config from vault
+func (s *vaultStore) Read(ctx context.Context) (data []byte, err error) {
+	resp, err := s.do(ctx, "GET", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config from vault")
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, config.ErrorConfigFileNotFound
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, vaultError(resp)
+	}
+	var result struct {
+		Data vaultData `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode config from vault")
+	}
+	return []byte(result.Data.Data.Config), nil
+}
+
+// Write the config to vault as a new version of the secret
+func (s *vaultStore) Write(ctx context.Context, data []byte) (err error) {
+	var secret vaultData
+	secret.Data.Config = string(data)
+	body, err := json.Marshal(&secret)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, "POST", body)
+	if err != nil {
+		return errors.Wrap(err, "failed to write config to vault")
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return vaultError(resp)
+	}
+	return nil
+}
+
+// awsStore keeps the config in the string of a secret in AWS Secrets
+// Manager, as given by --config awssm:SECRET_ID
+//
+// The credentials and region are found in the usual way for the AWS
+// SDK, eg from AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+type awsStore struct {
+	id string
+}
+
+func newAWSStore(id string) (config.Store, error) {
+	if id == "" {
+		return nil, errors.New("need the name or ARN of a secret")
+	}
+	return &awsStore{id: id}, nil
+}
+
+// client makes the Secrets Manager client
+func (s *awsStore) client() (*secretsmanager.SecretsManager, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make AWS session")
+	}
+	return secretsmanager.New(sess), nil
+}
+
+// isNotFound returns true if err means the secret doesn't exist
+func isNotFound(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == secretsmanager.ErrCodeResourceNotFoundException
+}
+
+// Read the config from Secrets Manager
+func (s *awsStore) Read(ctx context.Context) ([]byte, error) {
+	client, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(s.id),
+	})
+	if isNotFound(err) {
+		return nil, config.ErrorConfigFileNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read config from AWS Secrets Manager")
+	}
+	if out.SecretString != nil {
+		return []byte(*out.SecretString), nil
+	}
+	return out.SecretBinary, nil
+}
+
+// Write the config to Secrets Manager, creating the secret if needed
+func (s *awsStore) Write(ctx context.Context, data []byte) error {
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+	_, err = client.PutSecretValueWithContext(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(s.id),
+		SecretString: aws.String(string(data)),
+	})
+	if isNotFound(err) {
+		_, err = client.CreateSecretWithContext(ctx, &secretsmanager.CreateSecretInput{
+			Name:         aws.String(s.id),
+			SecretString: aws.String(string(data)),
+		})
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to write config to AWS Secrets Manager")
+	}
+	return nil
+}
+
+// remoteStore keeps the config in a file on a remote, as given by
+// --config remote:REMOTE:PATH
+//
+// As the config isn't loaded yet the remote must be a connection
+// string or be configured with environment variables.
+type remoteStore struct {
+	parent string
+	leaf   string
+}
+
+func newRemoteStore(remotePath string) (config.Store, error) {
+	parent, leaf, err := fspath.Split(remotePath)
+	if err != nil {
+		return nil, err
+	}
+	if leaf == "" {
+		return nil, errors.New("need the path of a file on a remote")
+	}
+	return &remoteStore{parent: parent, leaf: leaf}, nil
+}
+
+// String returns where the config is for the log
+func (s *remoteStore) String() string {
+	return fmt.Sprintf("%s%s", s.parent, s.leaf)
+}
+
+// Read the config from the remote
+func (s *remoteStore) Read(ctx context.Context) (data []byte, err error) {
+	f, err := fs.NewFs(ctx, s.parent)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make remote for config %q", s)
+	}
+	o, err := f.NewObject(ctx, s.leaf)
+	if err == fs.ErrorObjectNotFound {
+		return nil, config.ErrorConfigFileNotFound
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to find config %q", s)
+	}
+	in, err := o.Open(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open config %q", s)
+	}
+	defer fs.CheckClose(in, &err)
+	return ioutil.ReadAll(in)
+}
+
+// Write the config to the remote
+func (s *remoteStore) Write(ctx context.Context, data []byte) error {
+	f, err := fs.NewFs(ctx, s.parent)
+	if err != nil {
+		return errors.Wrapf(err, "failed to make remote for config %q", s)
+	}
+	src := object.NewStaticObjectInfo(s.leaf, time.Now(), int64(len(data)), true, nil, f)
+	o, err := f.NewObject(ctx, s.leaf)
+	if err == nil {
+		err = o.Update(ctx, bytes.NewReader(data), src)
+	} else if err == fs.ErrorObjectNotFound {
+		_, err = f.Put(ctx, bytes.NewReader(data), src)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to write config %q", s)
+	}
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ config.Store = envStore{}
+	_ config.Store = (*vaultStore)(nil)
+	_ config.Store = (*awsStore)(nil)
+	_ config.Store = (*remoteStore)(nil)
+)
@@ -0,0 +1,115 @@
+package configfile
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useConfigPath sets the config path returning a function to restore it
+func useConfigPath(t *testing.T, configPath string) func() {
+	old := config.GetConfigPath()
+	require.NoError(t, config.SetConfigPath(configPath))
+	assert.Equal(t, configPath, config.GetConfigPath())
+	return func() {
+		assert.NoError(t, config.SetConfigPath(old))
+	}
+}
+
+// testStoreRoundTrip checks that config saved in the store
+// configured can be read back
+func testStoreRoundTrip(t *testing.T) {
+	data := &Storage{}
+	require.Equal(t, config.ErrorConfigFileNotFound, data.Load())
+
+	data.SetValue("one", "fruit", "potato")
+	require.NoError(t, data.Save())
+
+	data = &Storage{}
+	require.NoError(t, data.Load())
+	value, ok := data.GetValue("one", "fruit")
+	assert.True(t, ok)
+	assert.Equal(t, "potato", value)
+}
+
+func TestStoreEnv(t *testing.T) {
+	defer useConfigPath(t, "env:RCLONE_TEST_CONFIG_BLOB")()
+	data := &Storage{}
+
+	require.Equal(t, config.ErrorConfigFileNotFound, data.Load())
+
+	require.NoError(t, os.Setenv("RCLONE_TEST_CONFIG_BLOB", base64.StdEncoding.EncodeToString([]byte(configData))))
+	defer func() {
+		_ = os.Unsetenv("RCLONE_TEST_CONFIG_BLOB")
+	}()
+	require.NoError(t, data.Load())
+	value, ok := data.GetValue("two", "topping")
+	assert.True(t, ok)
+	assert.Equal(t, "nuts", value)
+
+	assert.Equal(t, config.ErrorConfigReadOnly, data.Save())
+}
+
+func TestStoreVault(t *testing.T) {
+	var secret []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/secret/data/rclone/config", r.URL.Path)
+		assert.Equal(t, "token", r.Header.Get("X-Vault-Token"))
+		switch r.Method {
+		case "GET":
+			if secret == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]json.RawMessage{"data": secret})
+		case "POST":
+			var err error
+			secret, err = ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+		}
+	}))
+	defer server.Close()
+	require.NoError(t, os.Setenv("VAULT_ADDR", server.URL))
+	require.NoError(t, os.Setenv("VAULT_TOKEN", "token"))
+	defer func() {
+		_ = os.Unsetenv("VAULT_ADDR")
+		_ = os.Unsetenv("VAULT_TOKEN")
+	}()
+	defer useConfigPath(t, "vault:secret/rclone/config")()
+
+	testStoreRoundTrip(t)
+}
+
+func TestStoreRemote(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-config-store")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	defer useConfigPath(t, "remote::local:"+filepath.ToSlash(dir)+"/rclone.conf")()
+
+	testStoreRoundTrip(t)
+
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "rclone.conf"))
+	require.NoError(t, err)
+	assert.Contains(t, string(contents), "fruit = potato")
+
+	// Loading through the config package must not recurse as
+	// making the remote reads the config
+	oldData := config.Data()
+	defer config.SetData(oldData)
+	config.SetData(&Storage{})
+	value, ok := config.LoadedData().GetValue("one", "fruit")
+	assert.True(t, ok)
+	assert.Equal(t, "potato", value)
+}
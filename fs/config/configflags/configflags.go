@@ -4,14 +4,19 @@ package configflags
 
 // Options set by command line flags
 import (
+	"context"
+	"fmt"
 	"log"
-	"net"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fs/config"
 	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/fspath"
 	fsLog "github.com/rclone/rclone/fs/log"
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/sirupsen/logrus"
@@ -23,6 +28,7 @@ var (
 	verbose         int
 	quiet           bool
 	configPath      string
+	profile         string
 	dumpHeaders     bool
 	dumpBodies      bool
 	deleteBefore    bool
@@ -34,6 +40,8 @@ var (
 	uploadHeaders   []string
 	downloadHeaders []string
 	headers         []string
+	dnsOverride     []string
+	snapshotTime    string
 )
 
 // AddFlags adds the non filing system specific flags to the command
@@ -44,8 +52,11 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &quiet, "quiet", "q", false, "Print as little stuff as possible")
 	flags.DurationVarP(flagSet, &ci.ModifyWindow, "modify-window", "", ci.ModifyWindow, "Max time diff to be considered the same")
 	flags.IntVarP(flagSet, &ci.Checkers, "checkers", "", ci.Checkers, "Number of checkers to run in parallel.")
+	flags.IntVarP(flagSet, &ci.ListParallelism, "list-parallelism", "", ci.ListParallelism, "Number of directories to list in parallel (default same as --checkers).")
+	flags.IntVarP(flagSet, &ci.ListCutoff, "list-cutoff", "", ci.ListCutoff, "Sort directory listings bigger than this on disk to save memory (0 to disable).")
 	flags.IntVarP(flagSet, &ci.Transfers, "transfers", "", ci.Transfers, "Number of file transfers to run in parallel.")
 	flags.StringVarP(flagSet, &configPath, "config", "", config.GetConfigPath(), "Config file.")
+	flags.StringVarP(flagSet, &profile, "profile", "", "", "Use the flags in the [profile.NAME] section of the config file.")
 	flags.StringVarP(flagSet, &config.CacheDir, "cache-dir", "", config.CacheDir, "Directory rclone will use for caching.")
 	flags.BoolVarP(flagSet, &ci.CheckSum, "checksum", "c", ci.CheckSum, "Skip based on checksum (if available) & size, not mod-time & size")
 	flags.BoolVarP(flagSet, &ci.SizeOnly, "size-only", "", ci.SizeOnly, "Skip based on size only, not mod-time or checksum")
@@ -53,6 +64,8 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &ci.IgnoreExisting, "ignore-existing", "", ci.IgnoreExisting, "Skip all files that exist on destination")
 	flags.BoolVarP(flagSet, &ci.IgnoreErrors, "ignore-errors", "", ci.IgnoreErrors, "delete even if there are I/O errors")
 	flags.BoolVarP(flagSet, &ci.DryRun, "dry-run", "n", ci.DryRun, "Do a trial run with no permanent changes")
+	flags.BoolVarP(flagSet, &ci.Estimate, "estimate", "", ci.Estimate, "With --dry-run estimate the data, operations and time the transfers would take")
+	flags.FVarP(flagSet, &ci.EstimateBandwidth, "estimate-bandwidth", "", "Bandwidth in bytes/s to estimate the time for --estimate, default --bwlimit")
 	flags.BoolVarP(flagSet, &ci.Interactive, "interactive", "i", ci.Interactive, "Enable interactive mode")
 	flags.DurationVarP(flagSet, &ci.ConnectTimeout, "contimeout", "", ci.ConnectTimeout, "Connect timeout")
 	flags.DurationVarP(flagSet, &ci.Timeout, "timeout", "", ci.Timeout, "IO idle timeout")
@@ -62,6 +75,8 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &ci.InsecureSkipVerify, "no-check-certificate", "", ci.InsecureSkipVerify, "Do not verify the server SSL certificate. Insecure.")
 	flags.BoolVarP(flagSet, &ci.AskPassword, "ask-password", "", ci.AskPassword, "Allow prompt for password for encrypted configuration.")
 	flags.FVarP(flagSet, &ci.PasswordCommand, "password-command", "", "Command for supplying password for encrypted configuration.")
+	flags.StringArrayVarP(flagSet, &ci.ConfigRecipients, "config-recipient", "", nil, "Encrypt the configuration to this age recipient or GPG public key file instead of a password.")
+	flags.StringArrayVarP(flagSet, &ci.ConfigIdentities, "config-identity", "", nil, "Age identity or GPG private key file for decrypting a configuration encrypted to recipients.")
 	flags.BoolVarP(flagSet, &deleteBefore, "delete-before", "", false, "When synchronizing, delete files on destination before transferring")
 	flags.BoolVarP(flagSet, &deleteDuring, "delete-during", "", false, "When synchronizing, delete files during transfer")
 	flags.BoolVarP(flagSet, &deleteAfter, "delete-after", "", false, "When synchronizing, delete files on destination after transferring (default)")
@@ -69,8 +84,12 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &ci.TrackRenames, "track-renames", "", ci.TrackRenames, "When synchronizing, track file renames and do a server-side move if possible")
 	flags.StringVarP(flagSet, &ci.TrackRenamesStrategy, "track-renames-strategy", "", ci.TrackRenamesStrategy, "Strategies to use when synchronizing using track-renames hash|modtime|leaf")
 	flags.IntVarP(flagSet, &ci.LowLevelRetries, "low-level-retries", "", ci.LowLevelRetries, "Number of low level retries to do.")
+	flags.BoolVarP(flagSet, &ci.AdaptivePacer, "adaptive-pacer", "", ci.AdaptivePacer, "Learn the rate the API can sustain from rate limiting errors.")
+	flags.IntVarP(flagSet, &ci.TransferRetries, "transfer-retries", "", ci.TransferRetries, "Retry failed transfers this many times at the end of the sync.")
+	flags.DurationVarP(flagSet, &ci.TransferRetriesSleep, "transfer-retries-sleep", "", ci.TransferRetriesSleep, "Interval before the first retry of failed transfers, doubled each time.")
 	flags.BoolVarP(flagSet, &ci.UpdateOlder, "update", "u", ci.UpdateOlder, "Skip files that are newer on the destination.")
 	flags.BoolVarP(flagSet, &ci.UseServerModTime, "use-server-modtime", "", ci.UseServerModTime, "Use server modified time instead of object metadata")
+	flags.StringVarP(flagSet, &snapshotTime, "snapshot-time", "", "", "Show versioned remotes read only as they were at this time or this long ago, e.g. \"2021-01-02 15:04:05\" or 7d")
 	flags.BoolVarP(flagSet, &ci.NoGzip, "no-gzip-encoding", "", ci.NoGzip, "Don't set Accept-Encoding: gzip.")
 	flags.IntVarP(flagSet, &ci.MaxDepth, "max-depth", "", ci.MaxDepth, "If set limits the recursion depth to this.")
 	flags.BoolVarP(flagSet, &ci.IgnoreSize, "ignore-size", "", false, "Ignore size when skipping use mod-time or checksum.")
@@ -86,10 +105,23 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.StringVarP(flagSet, &ci.BackupDir, "backup-dir", "", ci.BackupDir, "Make backups into hierarchy based in DIR.")
 	flags.StringVarP(flagSet, &ci.Suffix, "suffix", "", ci.Suffix, "Suffix to add to changed files.")
 	flags.BoolVarP(flagSet, &ci.SuffixKeepExtension, "suffix-keep-extension", "", ci.SuffixKeepExtension, "Preserve the extension when using --suffix.")
+	flags.BoolVarP(flagSet, &ci.AtomicUpload, "atomic-upload", "", ci.AtomicUpload, "Upload to a temporary name and rename into place when complete.")
+	flags.StringVarP(flagSet, &ci.PartialSuffix, "partial-suffix", "", ci.PartialSuffix, "Suffix of the temporary names used by --atomic-upload.")
+	flags.FVarP(flagSet, &ci.PreTransferCmd, "pre-transfer-cmd", "", "Command to run before each file is transferred.")
+	flags.FVarP(flagSet, &ci.PostTransferCmd, "post-transfer-cmd", "", "Command to run after each file is transferred.")
+	flags.StringVarP(flagSet, &ci.PostTransferURL, "post-transfer-url", "", ci.PostTransferURL, "URL to POST the result of each transfer to as JSON.")
+	flags.FVarP(flagSet, &ci.TransferFilterCmd, "transfer-filter-cmd", "", "Command to transform the data of each file through when transferring.")
+	flags.StringVarP(flagSet, &ci.ScanClamd, "scan-clamd", "", ci.ScanClamd, "Scan files with the clamd at this address or unix socket before transferring them.")
+	flags.StringVarP(flagSet, &ci.ScanICAP, "scan-icap", "", ci.ScanICAP, "Scan files with the ICAP service at this icap:// URL before transferring them.")
+	flags.StringVarP(flagSet, &ci.ScanQuarantine, "scan-quarantine", "", ci.ScanQuarantine, "Copy files flagged by the scanner to this remote:path.")
+	flags.StringVarP(flagSet, &ci.Report, "report", "", ci.Report, "Write the action taken on each file to this file as JSON lines.")
 	flags.BoolVarP(flagSet, &ci.UseListR, "fast-list", "", ci.UseListR, "Use recursive list if available. Uses more memory but fewer transactions.")
 	flags.Float64VarP(flagSet, &ci.TPSLimit, "tpslimit", "", ci.TPSLimit, "Limit HTTP transactions per second to this.")
 	flags.IntVarP(flagSet, &ci.TPSLimitBurst, "tpslimit-burst", "", ci.TPSLimitBurst, "Max burst of transactions for --tpslimit.")
-	flags.StringVarP(flagSet, &bindAddr, "bind", "", "", "Local address to bind to for outgoing connections, IPv4, IPv6 or name.")
+	flags.StringVarP(flagSet, &bindAddr, "bind", "", "", "Local address to bind to for outgoing connections, IPv4, IPv6, interface or name. Use a comma separated list to use each in turn.")
+	flags.BoolVarP(flagSet, &ci.IPv4Only, "ipv4-only", "", ci.IPv4Only, "Only connect to IPv4 addresses.")
+	flags.BoolVarP(flagSet, &ci.IPv6Only, "ipv6-only", "", ci.IPv6Only, "Only connect to IPv6 addresses.")
+	flags.StringArrayVarP(flagSet, &dnsOverride, "dns-override", "", nil, "Connect to IP instead of resolving host, as host=IP.")
 	flags.StringVarP(flagSet, &disableFeatures, "disable", "", "", "Disable a comma separated list of features.  Use --disable help to see a list.")
 	flags.StringVarP(flagSet, &ci.UserAgent, "user-agent", "", ci.UserAgent, "Set the user-agent to a specified string. The default is rclone/ version")
 	flags.BoolVarP(flagSet, &ci.Immutable, "immutable", "", ci.Immutable, "Do not modify files. Fail if existing files have been modified.")
@@ -102,9 +134,12 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.FVarP(flagSet, &ci.BufferSize, "buffer-size", "", "In memory buffer size when reading files for each --transfer.")
 	flags.FVarP(flagSet, &ci.StreamingUploadCutoff, "streaming-upload-cutoff", "", "Cutoff for switching to chunked upload if file size is unknown. Upload starts after reaching cutoff or when file ends.")
 	flags.FVarP(flagSet, &ci.Dump, "dump", "", "List of items to dump from: "+fs.DumpFlagsList)
+	flags.StringVarP(flagSet, &ci.DumpHAR, "dump-har", "", ci.DumpHAR, "Write all HTTP transactions to this HAR file - may contain sensitive info")
+	flags.FVarP(flagSet, &ci.DumpHARBody, "dump-har-body", "", "Max size of each body to write to the --dump-har file (0 for none)")
 	flags.FVarP(flagSet, &ci.MaxTransfer, "max-transfer", "", "Maximum size of data to transfer.")
 	flags.DurationVarP(flagSet, &ci.MaxDuration, "max-duration", "", 0, "Maximum duration rclone will transfer data for.")
 	flags.FVarP(flagSet, &ci.CutoffMode, "cutoff-mode", "", "Mode to stop transfers when reaching the max transfer limit HARD|SOFT|CAUTIOUS")
+	flags.DurationVarP(flagSet, &ci.ShutdownTimeout, "shutdown-timeout", "", 0, "On a signal or core/quit wait this long for transfers in progress to finish.")
 	flags.IntVarP(flagSet, &ci.MaxBacklog, "max-backlog", "", ci.MaxBacklog, "Maximum number of objects in sync or check backlog.")
 	flags.IntVarP(flagSet, &ci.MaxStatsGroups, "max-stats-groups", "", ci.MaxStatsGroups, "Maximum number of stats groups to keep in memory. On max oldest is discarded.")
 	flags.BoolVarP(flagSet, &ci.StatsOneLine, "stats-one-line", "", ci.StatsOneLine, "Make the stats fit on one line.")
@@ -126,11 +161,16 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.StringArrayVarP(flagSet, &downloadHeaders, "header-download", "", nil, "Set HTTP header for download transactions")
 	flags.StringArrayVarP(flagSet, &headers, "header", "", nil, "Set HTTP header for all transactions")
 	flags.BoolVarP(flagSet, &ci.RefreshTimes, "refresh-times", "", ci.RefreshTimes, "Refresh the modtime of remote files.")
+	flags.BoolVarP(flagSet, &ci.RefreshMetadata, "refresh-metadata", "", ci.RefreshMetadata, "Refresh the metadata of remote files with the same contents.")
+	flags.BoolVarP(flagSet, &ci.Metadata, "metadata", "", ci.Metadata, "If set, preserve the mode, owner and xattrs of objects when copying them.")
+	flags.BoolVarP(flagSet, &ci.Verify, "verify", "", ci.Verify, "Re-read each file after transfer to check it and re-transfer it if corrupted.")
 	flags.BoolVarP(flagSet, &ci.NoConsole, "no-console", "", ci.NoConsole, "Hide console window. Supported on Windows only.")
 	flags.StringVarP(flagSet, &dscp, "dscp", "", "", "Set DSCP value to connections. Can be value or names, eg. CS1, LE, DF, AF21.")
 	flags.DurationVarP(flagSet, &ci.FsCacheExpireDuration, "fs-cache-expire-duration", "", ci.FsCacheExpireDuration, "cache remotes for this long (0 to disable caching)")
 	flags.DurationVarP(flagSet, &ci.FsCacheExpireInterval, "fs-cache-expire-interval", "", ci.FsCacheExpireInterval, "interval to check for expired remotes")
 	flags.BoolVarP(flagSet, &ci.DisableHTTP2, "disable-http2", "", ci.DisableHTTP2, "Disable HTTP/2 in the global transport.")
+	flags.BoolVarP(flagSet, &ci.UseListingCache, "use-listing-cache", "", ci.UseListingCache, "Cache directory listings on disk to share them between commands.")
+	flags.DurationVarP(flagSet, &ci.ListingCacheMaxAge, "listing-cache-max-age", "", ci.ListingCacheMaxAge, "Max age of listings used from --use-listing-cache.")
 }
 
 // ParseHeaders converts the strings passed in via the header flags into HTTPOptions
@@ -152,6 +192,18 @@ func ParseHeaders(headers []string) []*fs.HTTPOption {
 
 // SetFlags converts any flags into config which weren't straight forward
 func SetFlags(ci *fs.ConfigInfo) {
+	// Set path to configuration file
+	if err := config.SetConfigPath(configPath); err != nil {
+		log.Fatalf("--config: Failed to set %q as config path: %v", configPath, err)
+	}
+
+	// Set the flags from the profile before interpreting them
+	if profile != "" {
+		if err := applyProfile(pflag.CommandLine, profile); err != nil {
+			log.Fatalf("--profile: %v", err)
+		}
+	}
+
 	if dumpHeaders {
 		ci.Dump |= fs.DumpHeaders
 		fs.Logf(nil, "--dump-headers is obsolete - please use --dump headers instead")
@@ -173,6 +225,9 @@ func SetFlags(ci *fs.ConfigInfo) {
 	if (ci.DryRun || ci.Interactive) && ci.StatsLogLevel > fs.LogLevelNotice {
 		ci.StatsLogLevel = fs.LogLevelNotice
 	}
+	if ci.Estimate && !ci.DryRun {
+		log.Fatalf("Can't use --estimate without --dry-run")
+	}
 	if quiet {
 		if verbose > 0 {
 			log.Fatalf("Can't set -v and -q")
@@ -188,6 +243,9 @@ func SetFlags(ci *fs.ConfigInfo) {
 			log.Fatalf("Can't set -q and --log-level")
 		}
 	}
+	if strings.Contains(","+fsLog.Opt.Format+",", ",json,") {
+		ci.UseJSONLog = true
+	}
 	if ci.UseJSONLog {
 		logrus.AddHook(fsLog.NewCallerHook())
 		logrus.SetFormatter(&logrus.JSONFormatter{
@@ -238,14 +296,11 @@ func SetFlags(ci *fs.ConfigInfo) {
 	}
 
 	if bindAddr != "" {
-		addrs, err := net.LookupIP(bindAddr)
+		addrs, err := fs.ParseBindAddr(bindAddr)
 		if err != nil {
-			log.Fatalf("--bind: Failed to parse %q as IP address: %v", bindAddr, err)
-		}
-		if len(addrs) != 1 {
-			log.Fatalf("--bind: Expecting 1 IP address for %q but got %d", bindAddr, len(addrs))
+			log.Fatalf("--bind: %v", err)
 		}
-		ci.BindAddr = addrs[0]
+		ci.BindAddr = addrs
 	}
 
 	if disableFeatures != "" {
@@ -255,6 +310,24 @@ func SetFlags(ci *fs.ConfigInfo) {
 		ci.DisableFeatures = strings.Split(disableFeatures, ",")
 	}
 
+	if ci.IPv4Only && ci.IPv6Only {
+		log.Fatalf("Can't use --ipv4-only with --ipv6-only.")
+	}
+	if snapshotTime != "" {
+		age, err := fs.ParseDuration(snapshotTime)
+		if err != nil {
+			log.Fatalf("--snapshot-time: failed to parse %q: %v", snapshotTime, err)
+		}
+		ci.SnapshotTime = time.Now().Add(-age)
+	}
+	if len(dnsOverride) != 0 {
+		overrides, err := fs.ParseDNSOverride(dnsOverride)
+		if err != nil {
+			log.Fatalf("--dns-override: %v", err)
+		}
+		ci.DNSOverride = overrides
+	}
+
 	if len(uploadHeaders) != 0 {
 		ci.UploadHeaders = ParseHeaders(uploadHeaders)
 	}
@@ -272,11 +345,6 @@ func SetFlags(ci *fs.ConfigInfo) {
 		}
 	}
 
-	// Set path to configuration file
-	if err := config.SetConfigPath(configPath); err != nil {
-		log.Fatalf("--config: Failed to set %q as config path: %v", configPath, err)
-	}
-
 	// Set whether multi-thread-streams was set
 	multiThreadStreamsFlag := pflag.Lookup("multi-thread-streams")
 	ci.MultiThreadSet = multiThreadStreamsFlag != nil && multiThreadStreamsFlag.Changed
@@ -292,6 +360,76 @@ func SetFlags(ci *fs.ConfigInfo) {
 	nonZero(&ci.Checkers)
 }
 
+// applyProfile sets the flags in flagSet from the profile called name
+// unless they were set on the command line or in the environment
+func applyProfile(flagSet *pflag.FlagSet, name string) error {
+	values, err := config.GetProfile(name)
+	if err != nil {
+		return err
+	}
+	_, err = applyFlags(flagSet, values, fmt.Sprintf("profile %q", name))
+	return err
+}
+
+// applyFlags sets the flags in flagSet from values, which came from
+// what, unless they have been set already.
+//
+// It returns the names of the flags it set.
+func applyFlags(flagSet *pflag.FlagSet, values map[string]string, what string) (set []string, err error) {
+	for flagName, value := range values {
+		flag := flagSet.Lookup(flagName)
+		if flag == nil {
+			return set, errors.Errorf("unknown flag --%s in %s", flagName, what)
+		}
+		if flag.Changed {
+			fs.Debugf(nil, "Not setting --%s from %s as it is already set", flagName, what)
+			continue
+		}
+		err = flagSet.Set(flagName, value)
+		if err != nil {
+			return set, errors.Wrapf(err, "invalid value for --%s in %s", flagName, what)
+		}
+		fs.Debugf(nil, "Setting --%s %q from %s", flagName, value, what)
+		set = append(set, flagName)
+	}
+	return set, nil
+}
+
+// SetRemoteFlags sets the global flags given in the config of the
+// remote in remotePath with keys like "global.transfers", unless they
+// were set on the command line, in the environment, with --profile or
+// by a remote used before.
+//
+// This should be called before the remote is used.
+func SetRemoteFlags(ctx context.Context, remotePath string) error {
+	parsed, err := fspath.Parse(remotePath)
+	if err != nil {
+		return err
+	}
+	if parsed.Name == "" || strings.HasPrefix(parsed.Name, ":") {
+		return nil
+	}
+	values := config.GetRemoteFlags(parsed.Name)
+	if len(values) == 0 {
+		return nil
+	}
+	set, err := applyFlags(pflag.CommandLine, values, fmt.Sprintf("the config for remote %q", parsed.Name))
+	if err != nil {
+		return err
+	}
+	// Restart the limiters which read their flags at startup
+	ci := fs.GetConfig(ctx)
+	for _, flagName := range set {
+		switch flagName {
+		case "bwlimit":
+			accounting.TokenBucket.SetBwLimit(ci.BwLimit.LimitAt(time.Now()).Bandwidth)
+		case "tpslimit", "tpslimit-burst":
+			accounting.StartLimitTPS(ctx)
+		}
+	}
+	return nil
+}
+
 // parseHeaders converts DSCP names to value
 func parseDSCP(dscp string) (uint8, bool) {
 	if s, err := strconv.ParseUint(dscp, 10, 6); err == nil {
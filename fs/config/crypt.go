@@ -69,6 +69,9 @@ func Decrypt(b io.ReadSeeker) (io.Reader, error) {
 		if l == "RCLONE_ENCRYPT_V0:" {
 			break
 		}
+		if l == "RCLONE_ENCRYPT_V1:" {
+			return decryptRecipients(r)
+		}
 		if strings.HasPrefix(l, "RCLONE_ENCRYPT_V") {
 			return nil, errors.New("unsupported configuration encryption - update rclone for support")
 		}
@@ -188,6 +191,13 @@ func Decrypt(b io.ReadSeeker) (io.Reader, error) {
 
 // Encrypt the config file
 func Encrypt(src io.Reader, dst io.Writer) error {
+	rs, err := getRecipients(context.Background())
+	if err != nil {
+		return err
+	}
+	if !rs.empty() {
+		return encryptRecipients(rs, src, dst)
+	}
 	if len(configKey) == 0 {
 		_, err := io.Copy(dst, src)
 		return err
@@ -204,7 +214,7 @@ func Encrypt(src io.Reader, dst io.Writer) error {
 		return errors.Errorf("nonce short read: %d", n)
 	}
 	enc := base64.NewEncoder(base64.StdEncoding, dst)
-	_, err := enc.Write(nonce[:])
+	_, err = enc.Write(nonce[:])
 	if err != nil {
 		return errors.Errorf("Failed to write config file: %v", err)
 	}
@@ -255,6 +265,7 @@ func SetConfigPassword(password string) error {
 		return err
 	}
 	configKey = sha.Sum(nil)
+	configRecipients = nil
 	if PassConfigKeyForDaemonization {
 		tempFile, err := ioutil.TempFile("", "rclone")
 		if err != nil {
@@ -292,6 +303,7 @@ func SetConfigPassword(password string) error {
 // ClearConfigPassword sets the current the password to empty
 func ClearConfigPassword() {
 	configKey = nil
+	configRecipients = nil
 }
 
 // changeConfigPassword will query the user twice
@@ -0,0 +1,332 @@
+// Config encryption to public keys
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+
+	// For keys which don't say which hashes they prefer
+	_ "golang.org/x/crypto/ripemd160"
+)
+
+// The ENCRYPT_V1 format encrypts the config with a random key like
+// ENCRYPT_V0, then encrypts that key to each of the recipients so
+// anyone with one of their private keys can decrypt it.
+//
+// It looks like this, where each value is base64 encoded apart from
+// the age recipients
+//
+//     RCLONE_ENCRYPT_V1:
+//     age: age1...
+//     gpg: <GPG public key>
+//     key-age: <key encrypted with age to all the age recipients>
+//     key-gpg: <key encrypted with GPG to all the GPG recipients>
+//
+//     <nonce and config encrypted with the key as for ENCRYPT_V0>
+//
+// The recipients are kept in the file so it can be saved again
+// without having been given them.
+
+// ageCommand is the program used to encrypt and decrypt with age
+var ageCommand = "age"
+
+// recipients are the public keys to encrypt the config to
+type recipients struct {
+	age []string           // age recipients
+	gpg openpgp.EntityList // GPG public keys
+}
+
+// empty returns true if there are no recipients
+func (rs *recipients) empty() bool {
+	return rs == nil || (len(rs.age) == 0 && len(rs.gpg) == 0)
+}
+
+// configRecipients are the recipients the config was last decrypted
+// with, used if --config-recipient isn't set
+var configRecipients *recipients
+
+// readGPGKeys reads the GPG keys in path which can be armored or binary
+func readGPGKeys(path string) (openpgp.EntityList, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		keys, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read GPG keys from %q", path)
+	}
+	return keys, nil
+}
+
+// getRecipients returns the recipients to encrypt the config to, or
+// nil if it should be encrypted with the password or not at all
+func getRecipients(ctx context.Context) (*recipients, error) {
+	ci := fs.GetConfig(ctx)
+	if len(ci.ConfigRecipients) == 0 {
+		return configRecipients, nil
+	}
+	rs := &recipients{}
+	for _, recipient := range ci.ConfigRecipients {
+		if strings.HasPrefix(recipient, "age1") {
+			rs.age = append(rs.age, recipient)
+			continue
+		}
+		keys, err := readGPGKeys(recipient)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad --config-recipient")
+		}
+		rs.gpg = append(rs.gpg, keys...)
+	}
+	return rs, nil
+}
+
+// runAge runs age with args, feeding it in and returning its output
+func runAge(in []byte, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(ageCommand, args...)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ers := strings.TrimSpace(stderr.String()); ers != "" {
+			return nil, errors.Wrapf(err, "%s failed: %s", ageCommand, ers)
+		}
+		return nil, errors.Wrapf(err, "%s failed", ageCommand)
+	}
+	return stdout.Bytes(), nil
+}
+
+// encryptGPG encrypts key to all the GPG recipients in keys
+func encryptGPG(keys openpgp.EntityList, key []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := openpgp.Encrypt(&buf, keys, nil, &openpgp.FileHints{IsBinary: true}, nil)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(key)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encryptRecipients writes the config in src to dst encrypted to rs
+func encryptRecipients(rs *recipients, src io.Reader, dst io.Writer) error {
+	var key [32]byte
+	var nonce [24]byte
+	if _, err := io.ReadFull(rand.Reader, key[:]); err != nil {
+		return errors.Wrap(err, "failed to make config key")
+	}
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return errors.Wrap(err, "failed to make nonce")
+	}
+
+	var header bytes.Buffer
+	_, _ = fmt.Fprintln(&header, "# Encrypted rclone configuration File")
+	_, _ = fmt.Fprintln(&header, "")
+	_, _ = fmt.Fprintln(&header, "RCLONE_ENCRYPT_V1:")
+	for _, recipient := range rs.age {
+		_, _ = fmt.Fprintf(&header, "age: %s\n", recipient)
+	}
+	for _, entity := range rs.gpg {
+		var buf bytes.Buffer
+		if err := entity.Serialize(&buf); err != nil {
+			return errors.Wrap(err, "failed to write GPG public key")
+		}
+		_, _ = fmt.Fprintf(&header, "gpg: %s\n", base64.StdEncoding.EncodeToString(buf.Bytes()))
+	}
+	if len(rs.age) > 0 {
+		args := []string{"--encrypt"}
+		for _, recipient := range rs.age {
+			args = append(args, "--recipient", recipient)
+		}
+		sealed, err := runAge(key[:], args...)
+		if err != nil {
+			return errors.Wrap(err, "failed to encrypt config key with age")
+		}
+		_, _ = fmt.Fprintf(&header, "key-age: %s\n", base64.StdEncoding.EncodeToString(sealed))
+	}
+	if len(rs.gpg) > 0 {
+		sealed, err := encryptGPG(rs.gpg, key[:])
+		if err != nil {
+			return errors.Wrap(err, "failed to encrypt config key with GPG")
+		}
+		_, _ = fmt.Fprintf(&header, "key-gpg: %s\n", base64.StdEncoding.EncodeToString(sealed))
+	}
+	_, _ = fmt.Fprintln(&header, "")
+
+	data, err := ioutil.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	if _, err = dst.Write(header.Bytes()); err != nil {
+		return errors.Errorf("Failed to write config file: %v", err)
+	}
+	enc := base64.NewEncoder(base64.StdEncoding, dst)
+	if _, err = enc.Write(nonce[:]); err != nil {
+		return errors.Errorf("Failed to write config file: %v", err)
+	}
+	if _, err = enc.Write(secretbox.Seal(nil, data, &nonce, &key)); err != nil {
+		return errors.Errorf("Failed to write config file: %v", err)
+	}
+	return enc.Close()
+}
+
+// decryptGPG decrypts a key encrypted with encryptGPG using the GPG
+// private keys in keyring
+//
+// Private keys protected by a passphrase are unlocked with
+// RCLONE_CONFIG_PASS.
+func decryptGPG(keyring openpgp.EntityList, sealed []byte) ([]byte, error) {
+	tried := false
+	prompt := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		pass := os.Getenv("RCLONE_CONFIG_PASS")
+		if tried || pass == "" || symmetric {
+			return nil, errors.New("GPG private key is protected by a passphrase - set RCLONE_CONFIG_PASS to it")
+		}
+		tried = true
+		for _, k := range keys {
+			if k.PrivateKey != nil && k.PrivateKey.Encrypted {
+				_ = k.PrivateKey.Decrypt([]byte(pass))
+			}
+		}
+		return nil, nil
+	}
+	md, err := openpgp.ReadMessage(bytes.NewReader(sealed), keyring, prompt, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(md.UnverifiedBody)
+}
+
+// decryptKey decrypts the config key from key-* values in header
+// using the identity files in --config-identity
+func decryptKey(ctx context.Context, header map[string][][]byte) ([]byte, error) {
+	ci := fs.GetConfig(ctx)
+	if len(ci.ConfigIdentities) == 0 {
+		return nil, errors.New("configuration is encrypted to recipients - use --config-identity to supply a private key to decrypt it")
+	}
+	var keyring openpgp.EntityList
+	var ageArgs []string
+	for _, identity := range ci.ConfigIdentities {
+		keys, err := readGPGKeys(identity)
+		if err == nil {
+			keyring = append(keyring, keys...)
+		} else {
+			ageArgs = append(ageArgs, "--identity", identity)
+		}
+	}
+	var errs []string
+	if sealed := header["key-gpg"]; len(sealed) > 0 && len(keyring) > 0 {
+		key, err := decryptGPG(keyring, sealed[0])
+		if err == nil {
+			return key, nil
+		}
+		errs = append(errs, fmt.Sprintf("GPG: %v", err))
+	}
+	if sealed := header["key-age"]; len(sealed) > 0 && len(ageArgs) > 0 {
+		key, err := runAge(sealed[0], append([]string{"--decrypt"}, ageArgs...)...)
+		if err == nil {
+			return key, nil
+		}
+		errs = append(errs, fmt.Sprintf("age: %v", err))
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("none of the keys in --config-identity are for the recipients of the configuration")
+	}
+	return nil, errors.Errorf("unable to decrypt configuration: %s", strings.Join(errs, ", "))
+}
+
+// decryptRecipients decrypts the config in ENCRYPT_V1 format from r
+// which is positioned after the RCLONE_ENCRYPT_V1: line
+func decryptRecipients(r *bufio.Reader) (io.Reader, error) {
+	ctx := context.Background()
+
+	// Read the header up to the blank line
+	header := map[string][][]byte{}
+	rs := &recipients{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if err == io.EOF {
+				return nil, errors.New("configuration data too short")
+			}
+			break
+		}
+		i := strings.Index(line, ": ")
+		if i < 0 {
+			return nil, errors.Errorf("bad line in encrypted configuration header %q", line)
+		}
+		name, value := line[:i], line[i+2:]
+		if name == "age" {
+			rs.age = append(rs.age, value)
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode %q in encrypted configuration header", name)
+		}
+		if name == "gpg" {
+			entity, err := openpgp.ReadEntity(packet.NewReader(bytes.NewReader(decoded)))
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read GPG public key in encrypted configuration header")
+			}
+			rs.gpg = append(rs.gpg, entity)
+			continue
+		}
+		header[name] = append(header[name], decoded)
+	}
+
+	box, err := ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, r))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load base64 encoded data")
+	}
+	if len(box) < 24+secretbox.Overhead {
+		return nil, errors.New("Configuration data too short")
+	}
+
+	keyBytes, err := decryptKey(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyBytes) != 32 {
+		return nil, errors.New("unable to decrypt configuration: bad key")
+	}
+	var nonce [24]byte
+	copy(nonce[:], box[:24])
+	var key [32]byte
+	copy(key[:], keyBytes)
+	out, ok := secretbox.Open(nil, box[24:], &nonce, &key)
+	if !ok {
+		return nil, errors.New("unable to decrypt configuration: corrupted data")
+	}
+	configRecipients = rs
+	return bytes.NewReader(out), nil
+}
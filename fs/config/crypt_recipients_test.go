@@ -0,0 +1,121 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"
+)
+
+const testConfig = "[remote]\ntype = local\n"
+
+// decryptString decrypts the config in s
+func decryptString(t *testing.T, s string) (string, error) {
+	r, err := Decrypt(bytes.NewReader([]byte(s)))
+	if err != nil {
+		return "", err
+	}
+	out, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	return string(out), nil
+}
+
+// encryptString encrypts the config in s
+func encryptString(t *testing.T, s string) string {
+	var buf bytes.Buffer
+	require.NoError(t, Encrypt(bytes.NewReader([]byte(s)), &buf))
+	return buf.String()
+}
+
+// makeGPGKey writes a new GPG key to dir returning the paths of the
+// public and private key files
+func makeGPGKey(t *testing.T, dir string) (public, private string) {
+	entity, err := openpgp.NewEntity("rclone", "test", "rclone@example.com", nil)
+	require.NoError(t, err)
+	var pub, priv bytes.Buffer
+	require.NoError(t, entity.Serialize(&pub))
+	require.NoError(t, entity.SerializePrivate(&priv, nil))
+	public = filepath.Join(dir, "public.gpg")
+	private = filepath.Join(dir, "private.gpg")
+	require.NoError(t, ioutil.WriteFile(public, pub.Bytes(), 0600))
+	require.NoError(t, ioutil.WriteFile(private, priv.Bytes(), 0600))
+	return public, private
+}
+
+func TestEncryptRecipients(t *testing.T) {
+	ctx := context.Background()
+	ci := fs.GetConfig(ctx)
+	oldConfig := *ci
+	oldAgeCommand := ageCommand
+	defer func() {
+		*ci = oldConfig
+		ageCommand = oldAgeCommand
+		ClearConfigPassword()
+	}()
+	dir, err := ioutil.TempDir("", "rclone-config-recipients")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	public, private := makeGPGKey(t, dir)
+	ci.ConfigRecipients = []string{public}
+	if runtime.GOOS != "windows" {
+		// Pretend to be age by not encrypting at all
+		ageCommand = filepath.Join(dir, "age")
+		require.NoError(t, ioutil.WriteFile(ageCommand, []byte("#!/bin/sh\ncat\n"), 0700))
+		ci.ConfigRecipients = append(ci.ConfigRecipients, "age1test")
+	}
+
+	encrypted := encryptString(t, testConfig)
+	assert.Contains(t, encrypted, "RCLONE_ENCRYPT_V1:\n")
+	assert.Contains(t, encrypted, "key-gpg: ")
+	assert.NotContains(t, encrypted, "type = local")
+
+	// Needs an identity to decrypt
+	ci.ConfigRecipients = nil
+	_, err = decryptString(t, encrypted)
+	assert.Error(t, err)
+
+	// The wrong key can't decrypt it
+	otherDir := filepath.Join(dir, "other")
+	require.NoError(t, os.Mkdir(otherDir, 0700))
+	_, otherPrivate := makeGPGKey(t, otherDir)
+	ci.ConfigIdentities = []string{otherPrivate}
+	_, err = decryptString(t, encrypted)
+	assert.Error(t, err)
+
+	// The GPG private key decrypts it
+	ci.ConfigIdentities = []string{private}
+	decrypted, err := decryptString(t, encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, testConfig, decrypted)
+
+	// Saving encrypts to the same recipients without being told them
+	encrypted = encryptString(t, testConfig)
+	assert.Contains(t, encrypted, "RCLONE_ENCRYPT_V1:\n")
+	decrypted, err = decryptString(t, encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, testConfig, decrypted)
+
+	if runtime.GOOS != "windows" {
+		assert.Contains(t, encrypted, "age: age1test\n")
+		// The age identity decrypts it too
+		ci.ConfigIdentities = []string{filepath.Join(dir, "age.key")}
+		decrypted, err = decryptString(t, encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, testConfig, decrypted)
+	}
+
+	// Clearing the password stops encrypting
+	ClearConfigPassword()
+	assert.Equal(t, testConfig, encryptString(t, testConfig))
+}
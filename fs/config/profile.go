@@ -0,0 +1,69 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ProfilePrefix is the start of the names of the config sections
+// which hold profiles rather than remotes, eg [profile.fast]
+const ProfilePrefix = "profile."
+
+// IsProfile returns true if the config section is a profile rather
+// than a remote
+func IsProfile(section string) bool {
+	return strings.HasPrefix(section, ProfilePrefix)
+}
+
+// remoteSections returns the names of the sections in the config
+// file which are remotes
+func remoteSections() []string {
+	sections := LoadedData().GetSectionList()
+	remotes := sections[:0:0]
+	for _, section := range sections {
+		if !IsProfile(section) {
+			remotes = append(remotes, section)
+		}
+	}
+	return remotes
+}
+
+// GlobalPrefix starts the keys in the config section of a remote
+// which set global flags when the remote is used, eg global.transfers
+const GlobalPrefix = "global."
+
+// sectionFlags returns the keys in section which start with prefix
+// with the prefix removed as flag names, and their values
+func sectionFlags(section, prefix string) map[string]string {
+	data := LoadedData()
+	values := map[string]string{}
+	for _, key := range data.GetKeyList(section) {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		value, _ := data.GetValue(section, key)
+		values[strings.Replace(key[len(prefix):], "_", "-", -1)] = value
+	}
+	return values
+}
+
+// GetProfile returns the flags set by the profile called name as a
+// map of flag name without the leading "--" to value.
+//
+// Keys in the config may be written with "_" instead of "-" like
+// backend options are.
+func GetProfile(name string) (map[string]string, error) {
+	section := ProfilePrefix + name
+	if !LoadedData().HasSection(section) {
+		return nil, errors.Errorf("couldn't find [%s] in the config file", section)
+	}
+	return sectionFlags(section, ""), nil
+}
+
+// GetRemoteFlags returns the global flags set in the config of the
+// remote called name with keys starting with GlobalPrefix, as for
+// GetProfile.
+func GetRemoteFlags(name string) map[string]string {
+	return sectionFlags(name, GlobalPrefix)
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfile(t *testing.T) {
+	oldData := Data()
+	defer SetData(oldData)
+	SetData(newDefaultStorage())
+	LoadedData().SetValue("remote", "type", "local")
+	LoadedData().SetValue("remote", "global.tpslimit", "10")
+	LoadedData().SetValue("remote", "global.max_backlog", "1000")
+	LoadedData().SetValue("profile.fast", "transfers", "16")
+	LoadedData().SetValue("profile.fast", "buffer_size", "32M")
+
+	assert.True(t, IsProfile("profile.fast"))
+	assert.False(t, IsProfile("remote"))
+
+	remotes := FileSections()
+	sort.Strings(remotes)
+	assert.Equal(t, []string{"remote"}, remotes)
+
+	profile, err := GetProfile("fast")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"transfers":   "16",
+		"buffer-size": "32M",
+	}, profile)
+
+	_, err = GetProfile("slow")
+	assert.Error(t, err)
+
+	assert.Equal(t, map[string]string{
+		"tpslimit":    "10",
+		"max-backlog": "1000",
+	}, GetRemoteFlags("remote"))
+	assert.Equal(t, map[string]string{}, GetRemoteFlags("notfound"))
+}
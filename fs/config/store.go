@@ -0,0 +1,82 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Store is somewhere other than a local file which the config can be
+// read from and written to.
+//
+// A Store is selected by giving --config a path of the form
+// scheme:path where scheme is the name it was registered with.
+type Store interface {
+	// Read returns the config data, or ErrorConfigFileNotFound if
+	// there isn't any yet
+	Read(ctx context.Context) ([]byte, error)
+	// Write replaces the config data, or returns
+	// ErrorConfigReadOnly if the store can't be written to
+	Write(ctx context.Context, data []byte) error
+}
+
+// StoreFn makes a Store from the path after the scheme
+type StoreFn func(path string) (Store, error)
+
+// ErrorConfigReadOnly is returned by a Store which can't be written to
+var ErrorConfigReadOnly = errors.New("config is read only")
+
+var (
+	storesMu sync.Mutex
+	stores   = map[string]StoreFn{}
+)
+
+// RegisterStore registers a Store to be used for config paths
+// starting with scheme followed by a colon.
+//
+// The scheme must be more than one character long so it isn't
+// confused with a drive letter.
+func RegisterStore(scheme string, fn StoreFn) {
+	if len(scheme) < 2 {
+		panic("config store scheme must be more than one character")
+	}
+	storesMu.Lock()
+	stores[scheme] = fn
+	storesMu.Unlock()
+}
+
+// splitStorePath returns the Store constructor and the rest of the
+// path if path is for a registered Store, or nil if it isn't
+func splitStorePath(path string) (fn StoreFn, rest string) {
+	i := strings.IndexRune(path, ':')
+	if i < 2 {
+		return nil, ""
+	}
+	storesMu.Lock()
+	fn = stores[path[:i]]
+	storesMu.Unlock()
+	return fn, path[i+1:]
+}
+
+// IsStorePath returns true if path is for a registered Store rather
+// than a local file
+func IsStorePath(path string) bool {
+	fn, _ := splitStorePath(path)
+	return fn != nil
+}
+
+// GetStore returns the Store the config path in use is for, or nil
+// if it is a local file
+func GetStore() (Store, error) {
+	fn, rest := splitStorePath(configPath)
+	if fn == nil {
+		return nil, nil
+	}
+	store, err := fn(rest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "bad config path %q", configPath)
+	}
+	return store, nil
+}
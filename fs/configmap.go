@@ -3,10 +3,14 @@
 package fs
 
 import (
+	"io/ioutil"
 	"os"
+	"regexp"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/obscure"
 )
 
 // A configmap.Getter to read from the environment RCLONE_CONFIG_backend_option_name
@@ -94,6 +98,74 @@ func (section getConfigFile) Get(key string) (value string, ok bool) {
 	return value, ok
 }
 
+// interpolateEnv matches ${ENV_VAR} in config file values
+var interpolateEnv = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// interpolateFilePrefix starts config file values which should be
+// read from a file
+const interpolateFilePrefix = "file:"
+
+// interpolate returns value with any ${ENV_VAR} replaced with the
+// value of the environment variable, or the contents of the file if
+// value is file:/path/to/file, and whether it changed anything.
+func interpolate(value string) (string, bool, error) {
+	if strings.HasPrefix(value, interpolateFilePrefix) {
+		data, err := ioutil.ReadFile(value[len(interpolateFilePrefix):])
+		if err != nil {
+			return "", false, err
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	}
+	if !strings.Contains(value, "${") {
+		return value, false, nil
+	}
+	var err error
+	value = interpolateEnv.ReplaceAllStringFunc(value, func(match string) string {
+		name := match[2 : len(match)-1]
+		envValue, found := os.LookupEnv(name)
+		if !found && err == nil {
+			err = errors.Errorf("environment variable %s not set", name)
+		}
+		return envValue
+	})
+	return value, true, err
+}
+
+// A configmap.Getter which interpolates the values of another getter
+// using interpolate
+type interpolateGetter struct {
+	getter configmap.Getter
+	fsInfo *RegInfo
+}
+
+// Get a config item from the getter, interpolating it
+func (ig interpolateGetter) Get(key string) (value string, ok bool) {
+	value, ok = ig.getter.Get(key)
+	if !ok {
+		return value, ok
+	}
+	newValue, changed, err := interpolate(value)
+	if err != nil {
+		Errorf(nil, "Failed to interpolate config %q = %q: %v", key, value, err)
+		return "", false
+	}
+	if !changed {
+		return value, ok
+	}
+	// Passwords are read obscured from the config file, but the
+	// secret will be in plain text, so obscure it
+	if ig.fsInfo != nil {
+		if opt := ig.fsInfo.Options.Get(key); opt != nil && opt.IsPassword {
+			newValue, err = obscure.Obscure(newValue)
+			if err != nil {
+				Errorf(nil, "Failed to obscure interpolated config %q: %v", key, err)
+				return "", false
+			}
+		}
+	}
+	return newValue, ok
+}
+
 // ConfigMap creates a configmap.Map from the *RegInfo and the
 // configName passed in. If connectionStringConfig has any entries (it may be nil),
 // then it will be added to the lookup with the highest priority.
@@ -125,7 +197,7 @@ func ConfigMap(fsInfo *RegInfo, configName string, connectionStringConfig config
 	}
 
 	// config file
-	config.AddGetter(getConfigFile(configName), configmap.PriorityConfig)
+	config.AddGetter(interpolateGetter{getter: getConfigFile(configName), fsInfo: fsInfo}, configmap.PriorityConfig)
 
 	// default values
 	if fsInfo != nil {
@@ -88,6 +88,13 @@ type Features struct {
 	// PublicLink generates a public link to the remote path (usually readable by anyone)
 	PublicLink func(ctx context.Context, remote string, expire Duration, unlink bool) (string, error)
 
+	// PublicLinkWithPassword generates a public link to the remote
+	// path which needs password to read it
+	PublicLinkWithPassword func(ctx context.Context, remote string, expire Duration, password string) (string, error)
+
+	// ListPublicLinks returns the public links to the remote path
+	ListPublicLinks func(ctx context.Context, remote string) ([]PublicLinkInfo, error)
+
 	// Put in to the remote path with the modTime given of the given size
 	//
 	// May create the object even if it returns an error - if so
@@ -115,6 +122,24 @@ type Features struct {
 	// otherwise cleaning up old versions of files.
 	CleanUp func(ctx context.Context) error
 
+	// ListTrash lists the deleted objects in dir and its
+	// subdirectories which can be restored with Undelete
+	ListTrash func(ctx context.Context, dir string) ([]TrashEntry, error)
+
+	// Undelete restores the deleted object described by entry to
+	// where it was deleted from
+	Undelete func(ctx context.Context, entry TrashEntry) (Object, error)
+
+	// ListVersions lists the old versions of the objects in dir,
+	// not recursing, with the time of the version added to their
+	// names as lib/version does.
+	ListVersions func(ctx context.Context, dir string) (DirEntries, error)
+
+	// ListAt lists the objects and directories in dir as they were
+	// at time t, not recursing, using the versions of the objects
+	// which were current then under their usual names.
+	ListAt func(ctx context.Context, dir string, t time.Time) (DirEntries, error)
+
 	// ListR lists the objects and directories of the Fs starting
 	// from dir recursively into out.
 	//
@@ -133,6 +158,20 @@ type Features struct {
 	// of listing recursively that doing a directory traversal.
 	ListR ListRFn
 
+	// ListP lists the objects and directories of the directory dir
+	// calling callback with each page of entries as it is read.
+	//
+	// dir should be "" to list the root, and should not have
+	// trailing slashes.
+	//
+	// This should return ErrDirNotFound if the directory isn't
+	// found.
+	//
+	// The entries need not be returned in any particular order.
+	// If callback returns an error then the listing will stop
+	// immediately.
+	ListP ListRFn
+
 	// About gets quota information from the Fs
 	About func(ctx context.Context) (*Usage, error)
 
@@ -257,6 +296,12 @@ func (ft *Features) Fill(ctx context.Context, f Fs) *Features {
 	if do, ok := f.(PublicLinker); ok {
 		ft.PublicLink = do.PublicLink
 	}
+	if do, ok := f.(PublicLinkPassworder); ok {
+		ft.PublicLinkWithPassword = do.PublicLinkWithPassword
+	}
+	if do, ok := f.(PublicLinkLister); ok {
+		ft.ListPublicLinks = do.ListPublicLinks
+	}
 	if do, ok := f.(PutUncheckeder); ok {
 		ft.PutUnchecked = do.PutUnchecked
 	}
@@ -269,9 +314,24 @@ func (ft *Features) Fill(ctx context.Context, f Fs) *Features {
 	if do, ok := f.(CleanUpper); ok {
 		ft.CleanUp = do.CleanUp
 	}
+	if do, ok := f.(TrashLister); ok {
+		ft.ListTrash = do.ListTrash
+	}
+	if do, ok := f.(Undeleter); ok {
+		ft.Undelete = do.Undelete
+	}
+	if do, ok := f.(VersionLister); ok {
+		ft.ListVersions = do.ListVersions
+	}
+	if do, ok := f.(SnapshotLister); ok {
+		ft.ListAt = do.ListAt
+	}
 	if do, ok := f.(ListRer); ok {
 		ft.ListR = do.ListR
 	}
+	if do, ok := f.(ListPer); ok {
+		ft.ListP = do.ListP
+	}
 	if do, ok := f.(Abouter); ok {
 		ft.About = do.About
 	}
@@ -342,6 +402,12 @@ func (ft *Features) Mask(ctx context.Context, f Fs) *Features {
 	if mask.PublicLink == nil {
 		ft.PublicLink = nil
 	}
+	if mask.PublicLinkWithPassword == nil {
+		ft.PublicLinkWithPassword = nil
+	}
+	if mask.ListPublicLinks == nil {
+		ft.ListPublicLinks = nil
+	}
 	if mask.PutUnchecked == nil {
 		ft.PutUnchecked = nil
 	}
@@ -354,9 +420,24 @@ func (ft *Features) Mask(ctx context.Context, f Fs) *Features {
 	if mask.CleanUp == nil {
 		ft.CleanUp = nil
 	}
+	if mask.ListTrash == nil {
+		ft.ListTrash = nil
+	}
+	if mask.Undelete == nil {
+		ft.Undelete = nil
+	}
+	if mask.ListVersions == nil {
+		ft.ListVersions = nil
+	}
+	if mask.ListAt == nil {
+		ft.ListAt = nil
+	}
 	if mask.ListR == nil {
 		ft.ListR = nil
 	}
+	if mask.ListP == nil {
+		ft.ListP = nil
+	}
 	if mask.About == nil {
 		ft.About = nil
 	}
@@ -527,6 +608,27 @@ type PublicLinker interface {
 	PublicLink(ctx context.Context, remote string, expire Duration, unlink bool) (string, error)
 }
 
+// PublicLinkPassworder is an optional interface for Fs
+type PublicLinkPassworder interface {
+	// PublicLinkWithPassword generates a public link to the remote
+	// path which needs password to read it
+	PublicLinkWithPassword(ctx context.Context, remote string, expire Duration, password string) (string, error)
+}
+
+// PublicLinkInfo describes an existing public link
+type PublicLinkInfo struct {
+	Path     string     `json:"path"`              // path of the file or directory relative to the root of the Fs
+	URL      string     `json:"url"`               // the link
+	Expires  *time.Time `json:"expires,omitempty"` // when the link expires if set
+	Password bool       `json:"password"`          // set if the link needs a password
+}
+
+// PublicLinkLister is an optional interface for Fs
+type PublicLinkLister interface {
+	// ListPublicLinks returns the public links to the remote path
+	ListPublicLinks(ctx context.Context, remote string) ([]PublicLinkInfo, error)
+}
+
 // MergeDirser is an option interface for Fs
 type MergeDirser interface {
 	// MergeDirs merges the contents of all the directories passed
@@ -543,6 +645,44 @@ type CleanUpper interface {
 	CleanUp(ctx context.Context) error
 }
 
+// TrashEntry describes a deleted object which can be restored
+type TrashEntry struct {
+	Remote  string    `json:"path"`         // path of the object relative to the root of the Fs
+	Size    int64     `json:"size"`         // size of the object or -1 if not known
+	Deleted time.Time `json:"deleted"`      // when the object was deleted or zero if not known
+	ID      string    `json:"id,omitempty"` // backend specific ID of the version to restore
+}
+
+// TrashLister is an optional interface for Fs
+type TrashLister interface {
+	// ListTrash lists the deleted objects in dir and its
+	// subdirectories which can be restored with Undelete
+	ListTrash(ctx context.Context, dir string) ([]TrashEntry, error)
+}
+
+// Undeleter is an optional interface for Fs
+type Undeleter interface {
+	// Undelete restores the deleted object described by entry to
+	// where it was deleted from
+	Undelete(ctx context.Context, entry TrashEntry) (Object, error)
+}
+
+// VersionLister is an optional interface for Fs
+type VersionLister interface {
+	// ListVersions lists the old versions of the objects in dir,
+	// not recursing, with the time of the version added to their
+	// names as lib/version does.
+	ListVersions(ctx context.Context, dir string) (DirEntries, error)
+}
+
+// SnapshotLister is an optional interface for Fs
+type SnapshotLister interface {
+	// ListAt lists the objects and directories in dir as they were
+	// at time t, not recursing, using the versions of the objects
+	// which were current then under their usual names.
+	ListAt(ctx context.Context, dir string, t time.Time) (DirEntries, error)
+}
+
 // ListRer is an optional interfaces for Fs
 type ListRer interface {
 	// ListR lists the objects and directories of the Fs starting
@@ -564,6 +704,28 @@ type ListRer interface {
 	ListR(ctx context.Context, dir string, callback ListRCallback) error
 }
 
+// ListPer is an optional interfaces for Fs
+type ListPer interface {
+	// ListP lists the objects and directories of the directory
+	// dir calling callback with each page of entries as it is
+	// read.
+	//
+	// dir should be "" to list the root, and should not have
+	// trailing slashes.
+	//
+	// This should return ErrDirNotFound if the directory isn't
+	// found.
+	//
+	// The entries need not be returned in any particular order.
+	// If callback returns an error then the listing will stop
+	// immediately.
+	//
+	// Implement this if the backend reads directories in pages
+	// so that very large directories can be used before they
+	// have been read completely.
+	ListP(ctx context.Context, dir string, callback ListRCallback) error
+}
+
 // RangeSeeker is the interface that wraps the RangeSeek method.
 //
 // Some of the returns from Object.Open() may optionally implement
@@ -4,23 +4,31 @@ package filter
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fspath"
 	"golang.org/x/sync/errgroup"
 )
 
 // This is the globally active filter
 //
 // This is accessed through GetConfig and AddConfig
-var globalConfig = mustNewFilter(nil)
+var (
+	globalConfigMu sync.RWMutex
+	globalConfig   = mustNewFilter(nil)
+)
 
 // rule is one filter rule
 type rule struct {
@@ -79,6 +87,26 @@ func (rs *rules) len() int {
 // FilesMap describes the map of files to transfer
 type FilesMap map[string]struct{}
 
+// ExcludeFiles is the list of --exclude-if-present file names.
+//
+// As well as a list it can be read from a single JSON string as
+// ExcludeFile was before the flag could be repeated, so rc callers
+// setting it that way still work.
+type ExcludeFiles []string
+
+// UnmarshalJSON reads a list of file names or a single file name
+func (e *ExcludeFiles) UnmarshalJSON(in []byte) error {
+	var name string
+	if err := json.Unmarshal(in, &name); err == nil {
+		*e = nil
+		if name != "" {
+			*e = ExcludeFiles{name}
+		}
+		return nil
+	}
+	return json.Unmarshal(in, (*[]string)(e))
+}
+
 // Opt configures the filter
 type Opt struct {
 	DeleteExcluded bool
@@ -86,24 +114,32 @@ type Opt struct {
 	FilterFrom     []string
 	ExcludeRule    []string
 	ExcludeFrom    []string
-	ExcludeFile    string
+	ExcludeFile    ExcludeFiles
+	IgnoreFile     string
 	IncludeRule    []string
 	IncludeFrom    []string
+	IncludeMeta    []string
+	ExcludeMeta    []string
 	FilesFrom      []string
 	FilesFromRaw   []string
 	MinAge         fs.Duration
 	MaxAge         fs.Duration
 	MinSize        fs.SizeSuffix
 	MaxSize        fs.SizeSuffix
+	MaxDirSize     fs.SizeSuffix
+	ExcludeDirAge  fs.Duration
 	IgnoreCase     bool
+	ReloadInterval fs.Duration
 }
 
 // DefaultOpt is the default config for the filter
 var DefaultOpt = Opt{
-	MinAge:  fs.DurationOff,
-	MaxAge:  fs.DurationOff,
-	MinSize: fs.SizeSuffix(-1),
-	MaxSize: fs.SizeSuffix(-1),
+	MinAge:        fs.DurationOff,
+	MaxAge:        fs.DurationOff,
+	MinSize:       fs.SizeSuffix(-1),
+	MaxSize:       fs.SizeSuffix(-1),
+	MaxDirSize:    fs.SizeSuffix(-1),
+	ExcludeDirAge: fs.DurationOff,
 }
 
 // Filter describes any filtering in operation
@@ -111,10 +147,30 @@ type Filter struct {
 	Opt         Opt
 	ModTimeFrom time.Time
 	ModTimeTo   time.Time
+	DirModFrom  time.Time // directories with no files modified since this are excluded
 	fileRules   rules
 	dirRules    rules
-	files       FilesMap // files if filesFrom
-	dirs        FilesMap // dirs from filesFrom
+	metaRules   rules
+	files       FilesMap       // files if filesFrom
+	dirs        FilesMap       // dirs from filesFrom
+	ignores     *ignoreCache   // ignore files read if IgnoreFile set
+	listingSrc  *listingSource // set by SetListingSource
+}
+
+// listingSource is the Fs whose listings decide the filters which
+// need the whole listing of a directory
+type listingSource struct {
+	f    fs.Fs
+	mu   sync.Mutex
+	dirs map[string]*dirDecision // what was decided about each directory
+}
+
+// dirDecision is whether a directory passed the filters which need its
+// whole listing, decided once for all the Fs listed with the source
+type dirDecision struct {
+	once    sync.Once
+	include bool
+	err     error
 }
 
 // NewFilter parses the command line options and creates a Filter
@@ -129,6 +185,10 @@ func NewFilter(opt *Opt) (f *Filter, err error) {
 		f.Opt = DefaultOpt
 	}
 
+	if f.Opt.IgnoreFile != "" {
+		f.ignores = newIgnoreCache()
+	}
+
 	// Filter flags
 	if f.Opt.MinAge.IsSet() {
 		f.ModTimeTo = time.Now().Add(-time.Duration(f.Opt.MinAge))
@@ -141,6 +201,10 @@ func NewFilter(opt *Opt) (f *Filter, err error) {
 		}
 		fs.Debugf(nil, "--max-age %v to %v", f.Opt.MaxAge, f.ModTimeFrom)
 	}
+	if f.Opt.ExcludeDirAge.IsSet() {
+		f.DirModFrom = time.Now().Add(-time.Duration(f.Opt.ExcludeDirAge))
+		fs.Debugf(nil, "--exclude-dir-older-than %v to %v", f.Opt.ExcludeDirAge, f.DirModFrom)
+	}
 
 	addImplicitExclude := false
 	foundExcludeRule := false
@@ -195,6 +259,24 @@ func NewFilter(opt *Opt) (f *Filter, err error) {
 		}
 	}
 
+	addImplicitMetaExclude := false
+	for _, rule := range f.Opt.IncludeMeta {
+		err = f.AddMeta(true, rule)
+		if err != nil {
+			return nil, err
+		}
+		addImplicitMetaExclude = true
+	}
+	for _, rule := range f.Opt.ExcludeMeta {
+		err = f.AddMeta(false, rule)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if addImplicitMetaExclude {
+		f.metaRules.add(false, regexp.MustCompile(`.*`))
+	}
+
 	inActive := f.InActive()
 
 	for _, rule := range f.Opt.FilesFrom {
@@ -321,6 +403,26 @@ func (f *Filter) AddRule(rule string) error {
 	return errors.Errorf("malformed rule %q", rule)
 }
 
+// AddMeta adds a metadata filter rule with include or exclude status
+// indicated.
+//
+// The rule is of the form key=glob where key is a metadata key, which
+// may itself be a glob, eg "content-type=image/*". Keys are matched
+// case insensitively.
+func (f *Filter) AddMeta(Include bool, rule string) error {
+	i := strings.IndexRune(rule, '=')
+	if i <= 0 {
+		return errors.Errorf("malformed metadata rule %q: must be key=glob", rule)
+	}
+	key, glob := strings.ToLower(rule[:i]), rule[i+1:]
+	re, err := globToRegexp("/"+key+"="+glob, f.Opt.IgnoreCase)
+	if err != nil {
+		return err
+	}
+	f.metaRules.add(Include, re)
+	return nil
+}
+
 // initAddFile creates f.files and f.dirs
 func (f *Filter) initAddFile() {
 	if f.files == nil {
@@ -370,7 +472,11 @@ func (f *Filter) InActive() bool {
 		f.Opt.MaxSize < 0 &&
 		f.fileRules.len() == 0 &&
 		f.dirRules.len() == 0 &&
-		len(f.Opt.ExcludeFile) == 0)
+		f.metaRules.len() == 0 &&
+		f.DirModFrom.IsZero() &&
+		f.Opt.MaxDirSize < 0 &&
+		len(f.Opt.ExcludeFile) == 0 &&
+		len(f.Opt.IgnoreFile) == 0)
 }
 
 // IncludeRemote returns whether this remote passes the filter rules.
@@ -383,6 +489,17 @@ func (f *Filter) IncludeRemote(remote string) bool {
 	return true
 }
 
+// IsExcludeFile returns true if leaf is one of the --exclude-if-present
+// file names
+func (f *Filter) IsExcludeFile(leaf string) bool {
+	for _, excludeFile := range f.Opt.ExcludeFile {
+		if leaf == excludeFile {
+			return true
+		}
+	}
+	return false
+}
+
 // ListContainsExcludeFile checks if exclude file is present in the list.
 func (f *Filter) ListContainsExcludeFile(entries fs.DirEntries) bool {
 	if len(f.Opt.ExcludeFile) == 0 {
@@ -392,7 +509,7 @@ func (f *Filter) ListContainsExcludeFile(entries fs.DirEntries) bool {
 		obj, ok := entry.(fs.Object)
 		if ok {
 			basename := path.Base(obj.Remote())
-			if basename == f.Opt.ExcludeFile {
+			if f.IsExcludeFile(basename) {
 				return true
 			}
 		}
@@ -400,6 +517,111 @@ func (f *Filter) ListContainsExcludeFile(entries fs.DirEntries) bool {
 	return false
 }
 
+// IncludeDirListing returns whether the directory with the listing
+// entries passes the --exclude-dir-older-than and --max-dir-size
+// filters.
+//
+// These only look at the files directly in the directory, so a
+// directory with no files in always passes.
+func (f *Filter) IncludeDirListing(ctx context.Context, entries fs.DirEntries) bool {
+	if f.DirModFrom.IsZero() && f.Opt.MaxDirSize < 0 {
+		return true
+	}
+	var (
+		files   int
+		size    int64
+		newest  time.Time
+		checkMT = !f.DirModFrom.IsZero()
+	)
+	for _, entry := range entries {
+		o, ok := entry.(fs.Object)
+		if !ok {
+			continue
+		}
+		files++
+		if o.Size() > 0 {
+			size += o.Size()
+		}
+		if checkMT {
+			if modTime := o.ModTime(ctx); modTime.After(newest) {
+				newest = modTime
+			}
+		}
+	}
+	if files == 0 {
+		return true
+	}
+	if f.Opt.MaxDirSize >= 0 && size > int64(f.Opt.MaxDirSize) {
+		return false
+	}
+	if checkMT && newest.Before(f.DirModFrom) {
+		return false
+	}
+	return true
+}
+
+// UsesDirListings returns true if the filters need the whole listing
+// of a directory to decide what to include from it.
+func (f *Filter) UsesDirListings() bool {
+	return len(f.Opt.ExcludeFile) > 0 ||
+		f.Opt.IgnoreFile != "" ||
+		!f.DirModFrom.IsZero() ||
+		f.Opt.MaxDirSize >= 0
+}
+
+// SetListingSource returns a context with a copy of the filter in ctx
+// where the listings of fsrc decide the filters which need the whole
+// listing of a directory for every Fs listed with it.
+//
+// This is used when syncing so the same files are excluded from the
+// destination as from the source, as they are with filter rules,
+// otherwise the files the source excludes would be deleted from the
+// destination.
+func SetListingSource(ctx context.Context, fsrc fs.Fs) context.Context {
+	if !GetConfig(ctx).UsesDirListings() {
+		return ctx
+	}
+	ctx, fi := AddConfig(ctx)
+	fi.listingSrc = &listingSource{
+		f:    fsrc,
+		dirs: make(map[string]*dirDecision),
+	}
+	return ctx
+}
+
+// ListingSource returns the Fs set with SetListingSource or nil if
+// there isn't one
+func (f *Filter) ListingSource() fs.Fs {
+	if f.listingSrc == nil {
+		return nil
+	}
+	return f.listingSrc.f
+}
+
+// DecideDirListing returns whether the directory dir passes the
+// filters which need its whole listing, calling decide to work it out.
+//
+// If there is a listing source then decide is only called the first
+// time dir is asked about and its result is returned for every Fs, so
+// the source and destination of a sync include the same directories.
+func (f *Filter) DecideDirListing(dir string, decide func() (bool, error)) (bool, error) {
+	s := f.listingSrc
+	if s == nil {
+		return decide()
+	}
+	s.mu.Lock()
+	d, ok := s.dirs[dir]
+	if !ok {
+		d = &dirDecision{}
+		s.dirs[dir] = d
+	}
+	s.mu.Unlock()
+	d.once.Do(func() {
+		d.include, d.err = decide()
+	})
+	return d.include, d.err
+}
+
 // IncludeDirectory returns a function which checks whether this
 // directory should be included in the sync or not.
 func (f *Filter) IncludeDirectory(ctx context.Context, fs fs.Fs) func(string) (bool, error) {
@@ -432,11 +654,11 @@ func (f *Filter) IncludeDirectory(ctx context.Context, fs fs.Fs) func(string) (b
 }
 
 // DirContainsExcludeFile checks if exclude file is present in a
-// directory. If fs is nil, it works properly if ExcludeFile is an
-// empty string (for testing).
+// directory. If fs is nil, it works properly if ExcludeFile is
+// empty (for testing).
 func (f *Filter) DirContainsExcludeFile(ctx context.Context, fremote fs.Fs, remote string) (bool, error) {
-	if len(f.Opt.ExcludeFile) > 0 {
-		exists, err := fs.FileExists(ctx, fremote, path.Join(remote, f.Opt.ExcludeFile))
+	for _, excludeFile := range f.Opt.ExcludeFile {
+		exists, err := fs.FileExists(ctx, fremote, path.Join(remote, excludeFile))
 		if err != nil {
 			return false, err
 		}
@@ -482,24 +704,95 @@ func (f *Filter) IncludeObject(ctx context.Context, o fs.Object) bool {
 		modTime = time.Unix(0, 0)
 	}
 
-	return f.Include(o.Remote(), o.Size(), modTime)
+	if !f.Include(o.Remote(), o.Size(), modTime) {
+		return false
+	}
+	if f.metaRules.len() > 0 {
+		return f.IncludeMetadata(metadataPairs(ctx, o))
+	}
+	return true
+}
+
+// Keys of the metadata which are available for filtering on all
+// remotes, as well as any Metadata the object has
+const (
+	MetaKeyContentType = "content-type"
+	MetaKeyTier        = "tier"
+)
+
+// metadataPairs returns the metadata of o as "key=value" strings with
+// the keys in lower case for matching against the metadata rules.
+func metadataPairs(ctx context.Context, o fs.Object) (pairs []string) {
+	pairs = append(pairs, MetaKeyContentType+"="+fs.MimeType(ctx, o))
+	if do, ok := o.(fs.GetTierer); ok {
+		if tier := do.GetTier(); tier != "" {
+			pairs = append(pairs, MetaKeyTier+"="+tier)
+		}
+	}
+	meta, err := fs.GetMetadata(ctx, o)
+	if err != nil {
+		fs.Debugf(o, "Failed to read metadata for filtering: %v", err)
+	}
+	for key, value := range meta {
+		pairs = append(pairs, strings.ToLower(key)+"="+value)
+	}
+	return pairs
+}
+
+// IncludeMetadata returns whether an object with the metadata passed
+// in as "key=value" pairs passes the metadata filter rules.
+//
+// The first rule which matches any of the pairs decides.
+func (f *Filter) IncludeMetadata(pairs []string) bool {
+	for _, rule := range f.metaRules.rules {
+		for _, pair := range pairs {
+			if rule.Match(pair) {
+				return rule.Include
+			}
+		}
+	}
+	return true
+}
+
+// openFile opens the file of filter rules pointed to by path which
+// may be "-" for stdin, a local file or a file on a remote, eg
+// "remote:rules.txt"
+func openFile(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	parsed, err := fspath.Parse(path)
+	if err != nil || parsed.ConfigString == "" {
+		return os.Open(path)
+	}
+	ctx := context.Background()
+	parent, leaf, err := fspath.Split(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := fs.NewFs(ctx, parent)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open remote for %q", path)
+	}
+	o, err := f.NewObject(ctx, leaf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find %q", path)
+	}
+	return o.Open(ctx)
 }
 
 // forEachLine calls fn on every line in the file pointed to by path
 //
+// The path may be "-" for stdin or a file on a remote.
+//
 // It ignores empty lines and lines starting with '#' or ';' if raw is false
 func forEachLine(path string, raw bool, fn func(string) error) (err error) {
-	var scanner *bufio.Scanner
-	if path == "-" {
-		scanner = bufio.NewScanner(os.Stdin)
-	} else {
-		in, err := os.Open(path)
-		if err != nil {
-			return err
-		}
-		scanner = bufio.NewScanner(in)
-		defer fs.CheckClose(in, &err)
+	in, err := openFile(path)
+	if err != nil {
+		return err
 	}
+	defer fs.CheckClose(in, &err)
+	scanner := bufio.NewScanner(in)
 	for scanner.Scan() {
 		line := scanner.Text()
 		if !raw {
@@ -533,6 +826,12 @@ func (f *Filter) DumpFilters() string {
 	for _, dirRule := range f.dirRules.rules {
 		rules = append(rules, dirRule.String())
 	}
+	if f.metaRules.len() > 0 {
+		rules = append(rules, "--- Metadata filter rules ---")
+		for _, metaRule := range f.metaRules.rules {
+			rules = append(rules, metaRule.String())
+		}
+	}
 	return strings.Join(rules, "\n")
 }
 
@@ -605,14 +904,71 @@ var configContextKey = configContextKeyType{}
 
 // GetConfig returns the global or context sensitive config
 func GetConfig(ctx context.Context) *Filter {
-	if ctx == nil {
-		return globalConfig
+	if ctx != nil {
+		if c := ctx.Value(configContextKey); c != nil {
+			return c.(*Filter)
+		}
 	}
-	c := ctx.Value(configContextKey)
-	if c == nil {
-		return globalConfig
+	globalConfigMu.RLock()
+	defer globalConfigMu.RUnlock()
+	return globalConfig
+}
+
+// readsStdin returns true if any of the filter rules in opt are read
+// from stdin
+func (opt *Opt) readsStdin() bool {
+	for _, paths := range [][]string{opt.FilterFrom, opt.ExcludeFrom, opt.IncludeFrom, opt.FilesFrom, opt.FilesFromRaw} {
+		for _, path := range paths {
+			if path == "-" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reloadGlobal re-reads the rules of the global filter from the files
+// they came from and makes the result the global filter.
+//
+// The global filter is left unchanged if there is an error.
+func reloadGlobal() error {
+	oldFilter := GetConfig(nil)
+	opt := oldFilter.Opt
+	newFilter, err := NewFilter(&opt)
+	if err != nil {
+		return err
+	}
+	globalConfigMu.Lock()
+	globalConfig = newFilter
+	globalConfigMu.Unlock()
+	if newFilter.DumpFilters() != oldFilter.DumpFilters() {
+		fs.Infof(nil, "Reloaded changed filter rules")
+	}
+	return nil
+}
+
+// ReloadEvery re-reads the rules of the global filter from the files
+// or remotes they came from every interval until ctx is cancelled.
+//
+// Filters in use when they are reloaded carry on with the old rules.
+func ReloadEvery(ctx context.Context, interval time.Duration) {
+	if GetConfig(nil).Opt.readsStdin() {
+		fs.Errorf(nil, "Can't reload filter rules read from stdin")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := reloadGlobal()
+			if err != nil {
+				fs.Errorf(nil, "Failed to reload filter rules - using the previous ones: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
 	}
-	return c.(*Filter)
 }
 
 // CopyConfig copies the global config (if any) from srcCtx into
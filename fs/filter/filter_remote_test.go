@@ -0,0 +1,41 @@
+package filter_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFilterFromRemote(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-filter-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "rules.txt"), []byte("- *.jpg\n+ *\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "files.txt"), []byte("potato.jpg\n"), 0600))
+
+	opt := filter.DefaultOpt
+	opt.FilterFrom = []string{":local:" + filepath.ToSlash(filepath.Join(dir, "rules.txt"))}
+	f, err := filter.NewFilter(&opt)
+	require.NoError(t, err)
+	assert.False(t, f.IncludeRemote("potato.jpg"))
+	assert.True(t, f.IncludeRemote("potato.png"))
+
+	opt = filter.DefaultOpt
+	opt.FilesFrom = []string{":local:" + filepath.ToSlash(filepath.Join(dir, "files.txt"))}
+	f, err = filter.NewFilter(&opt)
+	require.NoError(t, err)
+	assert.Equal(t, filter.FilesMap{"potato.jpg": {}}, f.Files())
+
+	opt = filter.DefaultOpt
+	opt.FilterFrom = []string{":local:" + filepath.ToSlash(filepath.Join(dir, "missing.txt"))}
+	_, err = filter.NewFilter(&opt)
+	assert.Error(t, err)
+}
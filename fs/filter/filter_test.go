@@ -503,6 +503,158 @@ func TestNewFilterMatchesIgnoreCase(t *testing.T) {
 	assert.False(t, f.InActive())
 }
 
+func TestNewFilterMatchesRegexp(t *testing.T) {
+	f, err := NewFilter(nil)
+	require.NoError(t, err)
+	add := func(s string) {
+		err := f.AddRule(s)
+		require.NoError(t, err)
+	}
+	add(`+ /backup-{{\d{4}-\d{2}-\d{2}}}.tar`)
+	add(`+ /logs/{{[a-z]+\.log(\.\d+)?}}`)
+	add(`- *`)
+	testInclude(t, f, []includeTest{
+		{"backup-2021-03-31.tar", 100, 0, true},
+		{"backup-2021-3-31.tar", 100, 0, false},
+		{"backup-latest.tar", 100, 0, false},
+		{"logs/app.log", 100, 0, true},
+		{"logs/app.log.1", 100, 0, true},
+		{"logs/app.log.old", 100, 0, false},
+		{"logs/sub/app.log", 100, 0, false},
+	})
+	testDirInclude(t, f, []includeDirTest{
+		{"logs", true},
+		{"logs/sub", true},
+		{"potato", false},
+	})
+}
+
+// sizedObject is an object with a size and modification time
+type sizedObject struct {
+	mockobject.Object
+	size    int64
+	modTime time.Time
+}
+
+func (o sizedObject) Size() int64 { return o.size }
+
+func (o sizedObject) ModTime(ctx context.Context) time.Time { return o.modTime }
+
+func TestNewFilterDirConditions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	entries := fs.DirEntries{
+		sizedObject{"dir/a", 600, old},
+		sizedObject{"dir/b", 500, old},
+		fs.NewDir("dir/sub", now),
+	}
+
+	f, err := NewFilter(nil)
+	require.NoError(t, err)
+	assert.False(t, f.UsesDirListings())
+	assert.True(t, f.IncludeDirListing(ctx, entries))
+
+	opt := DefaultOpt
+	opt.MaxDirSize = 1000
+	f, err = NewFilter(&opt)
+	require.NoError(t, err)
+	assert.False(t, f.InActive())
+	assert.True(t, f.UsesDirListings())
+	assert.False(t, f.IncludeDirListing(ctx, entries))
+	assert.True(t, f.IncludeDirListing(ctx, entries[1:]))
+
+	opt = DefaultOpt
+	opt.ExcludeDirAge = fs.Duration(24 * time.Hour)
+	f, err = NewFilter(&opt)
+	require.NoError(t, err)
+	assert.False(t, f.InActive())
+	assert.False(t, f.IncludeDirListing(ctx, entries))
+	assert.True(t, f.IncludeDirListing(ctx, append(entries, sizedObject{"dir/new", 1, now})))
+
+	// Directories without files always pass
+	assert.True(t, f.IncludeDirListing(ctx, entries[2:]))
+	assert.True(t, f.IncludeDirListing(ctx, nil))
+}
+
+func TestNewFilterExcludeFiles(t *testing.T) {
+	opt := DefaultOpt
+	opt.ExcludeFile = []string{".nobackup", "CACHEDIR.TAG"}
+	f, err := NewFilter(&opt)
+	require.NoError(t, err)
+	assert.False(t, f.InActive())
+	assert.True(t, f.IsExcludeFile(".nobackup"))
+	assert.True(t, f.IsExcludeFile("CACHEDIR.TAG"))
+	assert.False(t, f.IsExcludeFile("potato"))
+	assert.False(t, f.ListContainsExcludeFile(fs.DirEntries{mockobject.Object("dir/potato")}))
+	assert.True(t, f.ListContainsExcludeFile(fs.DirEntries{mockobject.Object("dir/potato"), mockobject.Object("dir/CACHEDIR.TAG")}))
+}
+
+// metaObject is an object with a mime type, tier and metadata
+type metaObject struct {
+	mockobject.Object
+	mimeType string
+	tier     string
+	meta     fs.Metadata
+}
+
+func (o metaObject) MimeType(ctx context.Context) string { return o.mimeType }
+
+func (o metaObject) GetTier() string { return o.tier }
+
+func (o metaObject) Metadata(ctx context.Context) (fs.Metadata, error) { return o.meta, nil }
+
+func TestNewFilterMeta(t *testing.T) {
+	ctx := context.Background()
+	Opt := DefaultOpt
+	Opt.IncludeMeta = []string{"Content-Type=image/*", "xattr-*=yes"}
+	Opt.ExcludeMeta = []string{"tier=ARCHIVE"}
+	f, err := NewFilter(&Opt)
+	require.NoError(t, err)
+	assert.False(t, f.InActive())
+	assert.Equal(t, `--- File filter rules ---
+--- Directory filter rules ---
+--- Metadata filter rules ---
++ ^content-type=image/[^/]*$
++ ^xattr-[^/]*=yes$
+- ^tier=ARCHIVE$
+- .*`, f.DumpFilters())
+
+	for _, test := range []struct {
+		o    metaObject
+		want bool
+	}{
+		{metaObject{Object: "a.jpg", mimeType: "image/jpeg"}, true},
+		{metaObject{Object: "a.jpg", mimeType: "text/plain"}, false},
+		{metaObject{Object: "a.jpg", mimeType: "image/jpeg", tier: "ARCHIVE"}, true},
+		{metaObject{Object: "a.txt", mimeType: "text/plain", tier: "ARCHIVE"}, false},
+		{metaObject{Object: "a.txt", mimeType: "text/plain", meta: fs.Metadata{"xattr-user.keep": "yes"}}, true},
+		{metaObject{Object: "a.txt", mimeType: "text/plain", meta: fs.Metadata{"xattr-user.keep": "no"}}, false},
+	} {
+		assert.Equal(t, test.want, f.IncludeObject(ctx, test.o), fmt.Sprintf("%+v", test.o))
+	}
+
+	// The mime type is read from the name if the object doesn't have one
+	assert.True(t, f.IncludeObject(ctx, mockobject.Object("potato.png")))
+	assert.False(t, f.IncludeObject(ctx, mockobject.Object("potato.txt")))
+
+	// Excludes alone let everything else through
+	Opt = DefaultOpt
+	Opt.ExcludeMeta = []string{"tier=ARCHIVE"}
+	f, err = NewFilter(&Opt)
+	require.NoError(t, err)
+	assert.False(t, f.IncludeObject(ctx, metaObject{Object: "a.txt", tier: "ARCHIVE"}))
+	assert.True(t, f.IncludeObject(ctx, metaObject{Object: "a.txt", tier: "STANDARD"}))
+
+	// Rules must have a key
+	for _, rule := range []string{"potato", "=potato"} {
+		Opt = DefaultOpt
+		Opt.IncludeMeta = []string{rule}
+		_, err = NewFilter(&Opt)
+		assert.Error(t, err, rule)
+	}
+}
+
 func TestFilterAddDirRuleOrFileRule(t *testing.T) {
 	for _, test := range []struct {
 		included bool
@@ -795,3 +947,42 @@ func TestGetConfig(t *testing.T) {
 	ctx3 := ReplaceConfig(ctx, f)
 	assert.Equal(t, globalConfig, GetConfig(ctx3))
 }
+
+func TestReloadGlobal(t *testing.T) {
+	rules := testFile(t, "- *.jpg\n")
+	defer func() {
+		require.NoError(t, os.Remove(rules))
+	}()
+
+	oldGlobal := globalConfig
+	defer func() {
+		globalConfig = oldGlobal
+	}()
+	Opt := DefaultOpt
+	Opt.FilterFrom = []string{rules}
+	globalConfig = mustNewFilter(&Opt)
+	fi := GetConfig(nil)
+	assert.False(t, fi.IncludeRemote("potato.jpg"))
+	assert.True(t, fi.IncludeRemote("potato.png"))
+
+	// Change the rules and reload
+	require.NoError(t, ioutil.WriteFile(rules, []byte("- *.png\n"), 0600))
+	require.NoError(t, reloadGlobal())
+	newFi := GetConfig(nil)
+	assert.True(t, newFi.IncludeRemote("potato.jpg"))
+	assert.False(t, newFi.IncludeRemote("potato.png"))
+
+	// The filter in use carries on with the old rules
+	assert.False(t, fi.IncludeRemote("potato.jpg"))
+
+	// A failed reload leaves the rules alone
+	require.NoError(t, os.Remove(rules))
+	require.Error(t, reloadGlobal())
+	assert.Equal(t, newFi, GetConfig(nil))
+	require.NoError(t, ioutil.WriteFile(rules, nil, 0600))
+
+	// Rules from stdin can't be reloaded
+	Opt.FilterFrom = []string{"-"}
+	assert.True(t, Opt.readsStdin())
+	assert.False(t, DefaultOpt.readsStdin())
+}
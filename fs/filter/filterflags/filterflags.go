@@ -3,6 +3,8 @@ package filterflags
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/rclone/rclone/fs/config/flags"
 	"github.com/rclone/rclone/fs/filter"
@@ -15,6 +17,11 @@ var (
 	Opt = filter.DefaultOpt
 )
 
+var (
+	reloadMu   sync.Mutex
+	stopReload context.CancelFunc
+)
+
 // Reload the filters from the flags
 func Reload(ctx context.Context) (err error) {
 	fi := filter.GetConfig(ctx)
@@ -23,9 +30,27 @@ func Reload(ctx context.Context) (err error) {
 		return err
 	}
 	*fi = *newFilter
+	startReloading(time.Duration(Opt.ReloadInterval))
 	return nil
 }
 
+// startReloading (re)starts reloading the filter rules every interval
+// if it is set, stopping any previous reloading
+func startReloading(interval time.Duration) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	if stopReload != nil {
+		stopReload()
+		stopReload = nil
+	}
+	if interval <= 0 {
+		return
+	}
+	var ctx context.Context
+	ctx, stopReload = context.WithCancel(context.Background())
+	go filter.ReloadEvery(ctx, interval)
+}
+
 // AddFlags adds the non filing system specific flags to the command
 func AddFlags(flagSet *pflag.FlagSet) {
 	rc.AddOptionReload("filter", &Opt, Reload)
@@ -34,15 +59,21 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.StringArrayVarP(flagSet, &Opt.FilterFrom, "filter-from", "", nil, "Read filtering patterns from a file (use - to read from stdin)")
 	flags.StringArrayVarP(flagSet, &Opt.ExcludeRule, "exclude", "", nil, "Exclude files matching pattern")
 	flags.StringArrayVarP(flagSet, &Opt.ExcludeFrom, "exclude-from", "", nil, "Read exclude patterns from file (use - to read from stdin)")
-	flags.StringVarP(flagSet, &Opt.ExcludeFile, "exclude-if-present", "", "", "Exclude directories if filename is present")
+	flags.StringArrayVarP(flagSet, (*[]string)(&Opt.ExcludeFile), "exclude-if-present", "", nil, "Exclude directories if filename is present")
+	flags.StringVarP(flagSet, &Opt.IgnoreFile, "ignore-file", "", "", "Read gitignore style exclude rules from files with this name in each directory")
 	flags.StringArrayVarP(flagSet, &Opt.IncludeRule, "include", "", nil, "Include files matching pattern")
 	flags.StringArrayVarP(flagSet, &Opt.IncludeFrom, "include-from", "", nil, "Read include patterns from file (use - to read from stdin)")
+	flags.StringArrayVarP(flagSet, &Opt.IncludeMeta, "include-meta", "", nil, "Include files whose metadata matches key=glob")
+	flags.StringArrayVarP(flagSet, &Opt.ExcludeMeta, "exclude-meta", "", nil, "Exclude files whose metadata matches key=glob")
 	flags.StringArrayVarP(flagSet, &Opt.FilesFrom, "files-from", "", nil, "Read list of source-file names from file (use - to read from stdin)")
 	flags.StringArrayVarP(flagSet, &Opt.FilesFromRaw, "files-from-raw", "", nil, "Read list of source-file names from file without any processing of lines (use - to read from stdin)")
 	flags.FVarP(flagSet, &Opt.MinAge, "min-age", "", "Only transfer files older than this in s or suffix ms|s|m|h|d|w|M|y")
 	flags.FVarP(flagSet, &Opt.MaxAge, "max-age", "", "Only transfer files younger than this in s or suffix ms|s|m|h|d|w|M|y")
 	flags.FVarP(flagSet, &Opt.MinSize, "min-size", "", "Only transfer files bigger than this in KiB or suffix B|K|M|G|T|P")
 	flags.FVarP(flagSet, &Opt.MaxSize, "max-size", "", "Only transfer files smaller than this in KiB or suffix B|K|M|G|T|P")
+	flags.FVarP(flagSet, &Opt.ReloadInterval, "filter-reload", "", "Re-read the filter rules from their files at this interval, 0 to disable")
+	flags.FVarP(flagSet, &Opt.ExcludeDirAge, "exclude-dir-older-than", "", "Exclude directories with no files modified within this in s or suffix ms|s|m|h|d|w|M|y")
+	flags.FVarP(flagSet, &Opt.MaxDirSize, "max-dir-size", "", "Exclude directories whose files total more than this in KiB or suffix B|K|M|G|T|P")
 	flags.BoolVarP(flagSet, &Opt.IgnoreCase, "ignore-case", "", false, "Ignore case in filters (case insensitive)")
 	//cvsExclude     = BoolP("cvs-exclude", "C", false, "Exclude files in the same way CVS does")
 }
@@ -12,6 +12,8 @@ import (
 
 // globToRegexp converts an rsync style glob to a regexp
 //
+// Any part of the glob enclosed in {{ }} is used as a regexp as is.
+//
 // documented in filtering.md
 func globToRegexp(glob string, ignoreCase bool) (*regexp.Regexp, error) {
 	var re bytes.Buffer
@@ -42,7 +44,11 @@ func globToRegexp(glob string, ignoreCase bool) (*regexp.Regexp, error) {
 	inBraces := false
 	inBrackets := 0
 	slashed := false
-	for _, c := range glob {
+	skip := 0
+	for i, c := range glob {
+		if i < skip {
+			continue
+		}
 		if slashed {
 			_, _ = re.WriteRune(c)
 			slashed = false
@@ -64,6 +70,25 @@ func globToRegexp(glob string, ignoreCase bool) (*regexp.Regexp, error) {
 			}
 			continue
 		}
+		if c == '{' && strings.HasPrefix(glob[i:], "{{") {
+			// {{regexp}} is copied as is
+			if inBraces {
+				return nil, errors.Errorf("can't nest '{' '}' in glob %q", glob)
+			}
+			j := strings.Index(glob[i+2:], "}}")
+			if j < 0 {
+				return nil, errors.Errorf("mismatched '{{' and '}}' in glob %q", glob)
+			}
+			// the regexp ends at the last of a run of '}'s
+			for strings.HasPrefix(glob[i+2+j+1:], "}}") {
+				j++
+			}
+			_, _ = re.WriteRune('(')
+			_, _ = re.WriteString(glob[i+2 : i+2+j])
+			_, _ = re.WriteRune(')')
+			skip = i + 2 + j + 2
+			continue
+		}
 		switch c {
 		case '\\':
 			_, _ = re.WriteRune(c)
@@ -132,7 +157,13 @@ var (
 // directory globs.  When matched with a directory (with a trailing /)
 // this should answer the question as to whether this glob could be in
 // this directory.
+//
+// Anything from the first {{regexp}} onwards could match any number
+// of directories so is treated as "**".
 func globToDirGlobs(glob string) (out []string) {
+	if i := strings.Index(glob, "{{"); i >= 0 {
+		glob = glob[:i] + "**"
+	}
 	if tooHardRe.MatchString(glob) {
 		// Can't figure this one out so return any directory might match
 		out = append(out, "/**")
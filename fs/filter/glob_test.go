@@ -32,7 +32,9 @@ func TestGlobToRegexp(t *testing.T) {
 		{`***`, `(^|/)`, `too many stars`},
 		{`ab]c`, `(^|/)`, `mismatched ']'`},
 		{`ab[c`, `(^|/)`, `mismatched '[' and ']'`},
-		{`ab{{cd`, `(^|/)`, `can't nest`},
+		{`ab{x{y}cd`, `(^|/)`, `can't nest`},
+		{`ab{{cd`, `(^|/)`, `mismatched '{{' and '}}'`},
+		{`ab{x,{{cd}}}`, `(^|/)`, `can't nest`},
 		{`ab{}}cd`, `(^|/)`, `mismatched '{' and '}'`},
 		{`ab}c`, `(^|/)`, `mismatched '{' and '}'`},
 		{`ab{c`, `(^|/)`, `mismatched '{' and '}'`},
@@ -40,6 +42,11 @@ func TestGlobToRegexp(t *testing.T) {
 		{`[a--b]`, `(^|/)`, `bad glob pattern`},
 		{`a\*b`, `(^|/)a\*b$`, ``},
 		{`a\\b`, `(^|/)a\\b$`, ``},
+		{`{{.*\.jpg}}`, `(^|/)(.*\.jpg)$`, ``},
+		{`/backup-{{\d{4}-\d{2}-\d{2}}}.tar`, `^backup-(\d{4}-\d{2}-\d{2})\.tar$`, ``},
+		{`*.{{jpe?g}}`, `(^|/)[^/]*\.(jpe?g)$`, ``},
+		{`{{a}}{{b}}`, `(^|/)(a)(b)$`, ``},
+		{`{{(}}`, `(^|/)`, `bad glob pattern`},
 	} {
 		for _, ignoreCase := range []bool{false, true} {
 			gotRe, err := globToRegexp(test.in, ignoreCase)
@@ -101,6 +108,8 @@ func TestGlobToDirGlobs(t *testing.T) {
 		{"/sausage2*", []string{`/`}},
 		{"/sausage3**", []string{`/sausage3**/`, "/"}},
 		{"/a/*.jpg", []string{`/a/`, "/"}},
+		{`/a/b/{{\d+}}.jpg`, []string{`/a/b/**/`, "/a/b/", "/a/", "/"}},
+		{`{{a/b}}`, []string{`**/`}},
 	} {
 		_, err := globToRegexp(test.in, false)
 		assert.NoError(t, err)
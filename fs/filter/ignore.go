@@ -0,0 +1,228 @@
+// gitignore style ignore files read from each directory
+
+package filter
+
+import (
+	"bufio"
+	"context"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+)
+
+// ignoreRule is one line of an ignore file
+type ignoreRule struct {
+	negate  bool           // line started with ! so re-includes
+	dirOnly bool           // line ended with / so only matches directories
+	re      *regexp.Regexp // matched against the path relative to the ignore file
+}
+
+// ignoreFile is the parsed contents of the ignore file in a directory
+type ignoreFile struct {
+	size    int64
+	modTime time.Time
+	rules   []ignoreRule // nil if there is no ignore file
+}
+
+// ignoreCache caches the ignore files read keyed on remote and
+// directory
+type ignoreCache struct {
+	mu    sync.Mutex
+	files map[string]*ignoreFile
+}
+
+func newIgnoreCache() *ignoreCache {
+	return &ignoreCache{
+		files: make(map[string]*ignoreFile),
+	}
+}
+
+// parseIgnoreLine parses one line of an ignore file.
+//
+// It returns ok false for blank lines and comments.
+func parseIgnoreLine(line string, ignoreCase bool) (rule ignoreRule, ok bool, err error) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || line[0] == '#' {
+		return rule, false, nil
+	}
+	switch {
+	case line[0] == '!':
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false, nil
+	}
+	// A pattern with a / in is relative to the directory of the
+	// ignore file, otherwise it matches at any level below it.
+	glob := line
+	if strings.HasPrefix(glob, "**/") {
+		glob = glob[3:]
+	} else if strings.ContainsRune(glob, '/') {
+		glob = "/" + strings.TrimLeft(glob, "/")
+	}
+	rule.re, err = globToRegexp(glob, ignoreCase)
+	if err != nil {
+		return rule, false, err
+	}
+	return rule, true, nil
+}
+
+// readIgnoreFile reads and parses the ignore file o
+func readIgnoreFile(ctx context.Context, o fs.Object, ignoreCase bool) (file *ignoreFile, err error) {
+	in, err := o.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	file = &ignoreFile{
+		size:    o.Size(),
+		modTime: o.ModTime(ctx),
+		rules:   []ignoreRule{},
+	}
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		rule, ok, err := parseIgnoreLine(scanner.Text(), ignoreCase)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad line in %q", o.Remote())
+		}
+		if ok {
+			file.rules = append(file.rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// loadIgnoreFile returns the ignore file in dir of fremote.
+//
+// If entries is not nil it is the listing of dir and is used to find
+// the ignore file, otherwise the cached value is used, or it is
+// looked up with NewObject.
+func (f *Filter) loadIgnoreFile(ctx context.Context, fremote fs.Fs, dir string, entries fs.DirEntries) (*ignoreFile, error) {
+	c := f.ignores
+	key := fs.ConfigString(fremote) + "\x00" + dir
+	c.mu.Lock()
+	cached, found := c.files[key]
+	c.mu.Unlock()
+
+	var o fs.Object
+	remote := path.Join(dir, f.Opt.IgnoreFile)
+	if entries != nil {
+		for _, entry := range entries {
+			if obj, ok := entry.(fs.Object); ok && obj.Remote() == remote {
+				o = obj
+				break
+			}
+		}
+	} else if found {
+		return cached, nil
+	} else {
+		var err error
+		o, err = fremote.NewObject(ctx, remote)
+		if err == fs.ErrorObjectNotFound || err == fs.ErrorNotAFile || err == fs.ErrorDirNotFound {
+			o = nil
+		} else if err != nil {
+			return nil, errors.Wrap(err, "failed to find ignore file")
+		}
+	}
+
+	file := &ignoreFile{}
+	if o != nil {
+		if found && cached.rules != nil && cached.size == o.Size() && cached.modTime.Equal(o.ModTime(ctx)) {
+			return cached, nil
+		}
+		var err error
+		file, err = readIgnoreFile(ctx, o, f.Opt.IgnoreCase)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read ignore file")
+		}
+		fs.Debugf(o, "Read %d ignore rules", len(file.rules))
+	}
+	c.mu.Lock()
+	c.files[key] = file
+	c.mu.Unlock()
+	return file, nil
+}
+
+// Ignorer returns a function which returns true if the file or
+// directory remote, which is in dir, should be ignored because of the
+// rules in the --ignore-file files in dir and the directories above
+// it.
+//
+// entries should be the listing of dir which is used to find its
+// ignore file, or nil to look it up. The ignore files of the
+// directories above it are remembered from when they were listed, or
+// looked up if they weren't.
+//
+// It returns a nil function if there are no rules to apply.
+func (f *Filter) Ignorer(ctx context.Context, fremote fs.Fs, dir string, entries fs.DirEntries) (func(remote string, isDir bool) bool, error) {
+	if f.Opt.IgnoreFile == "" || f.ignores == nil {
+		return nil, nil
+	}
+	// Find the directories from dir to the root
+	dirs := []string{dir}
+	for d := dir; d != ""; {
+		d = path.Dir(d)
+		if d == "." {
+			d = ""
+		}
+		dirs = append(dirs, d)
+	}
+	type dirRules struct {
+		prefix string
+		rules  []ignoreRule
+	}
+	var all []dirRules
+	// Read them from the root down as later rules take precedence
+	for i := len(dirs) - 1; i >= 0; i-- {
+		d := dirs[i]
+		var listing fs.DirEntries
+		if d == dir {
+			listing = entries
+		}
+		file, err := f.loadIgnoreFile(ctx, fremote, d, listing)
+		if err != nil {
+			return nil, err
+		}
+		if len(file.rules) == 0 {
+			continue
+		}
+		prefix := ""
+		if d != "" {
+			prefix = d + "/"
+		}
+		all = append(all, dirRules{prefix: prefix, rules: file.rules})
+	}
+	if len(all) == 0 {
+		return nil, nil
+	}
+	return func(remote string, isDir bool) bool {
+		ignored := false
+		for _, d := range all {
+			rel := strings.TrimPrefix(remote, d.prefix)
+			for _, rule := range d.rules {
+				if rule.dirOnly && !isDir {
+					continue
+				}
+				if rule.re.MatchString(rel) {
+					ignored = !rule.negate
+				}
+			}
+		}
+		return ignored
+	}, nil
+}
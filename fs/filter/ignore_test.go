@@ -0,0 +1,106 @@
+package filter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIgnoreLine(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		ok      bool
+		negate  bool
+		dirOnly bool
+		re      string
+	}{
+		{"", false, false, false, ""},
+		{"   ", false, false, false, ""},
+		{"# comment", false, false, false, ""},
+		{"*.log", true, false, false, `(^|/)[^/]*\.log$`},
+		{"*.log  ", true, false, false, `(^|/)[^/]*\.log$`},
+		{"!keep.log", true, true, false, `(^|/)keep\.log$`},
+		{`\!bang`, true, false, false, `(^|/)!bang$`},
+		{`\#hash`, true, false, false, `(^|/)#hash$`},
+		{"build/", true, false, true, `(^|/)build$`},
+		{"/top", true, false, false, `^top$`},
+		{"a/b", true, false, false, `^a/b$`},
+		{"a/**", true, false, false, `^a/.*$`},
+		{"**/a/b", true, false, false, `(^|/)a/b$`},
+		{"/", false, false, true, ""},
+	} {
+		rule, ok, err := parseIgnoreLine(test.in, false)
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.ok, ok, test.in)
+		if !ok {
+			continue
+		}
+		assert.Equal(t, test.negate, rule.negate, test.in)
+		assert.Equal(t, test.dirOnly, rule.dirOnly, test.in)
+		assert.Equal(t, test.re, rule.re.String(), test.in)
+	}
+	_, _, err := parseIgnoreLine("a[b", false)
+	assert.Error(t, err)
+}
+
+func TestIgnorer(t *testing.T) {
+	ctx := context.Background()
+	f := mockfs.NewFs(ctx, "mock", "root")
+	ignoreFile := mockobject.New(".rcloneignore").WithContent([]byte("# comment\n*.log\n!keep.log\nbuild/\n/top.txt\n"), mockobject.SeekModeNone)
+	f.AddObject(ignoreFile)
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+
+	// Not in use
+	fi, err := NewFilter(nil)
+	require.NoError(t, err)
+	ignored, err := fi.Ignorer(ctx, f, "", entries)
+	require.NoError(t, err)
+	assert.Nil(t, ignored)
+
+	opt := DefaultOpt
+	opt.IgnoreFile = ".rcloneignore"
+	fi, err = NewFilter(&opt)
+	require.NoError(t, err)
+	assert.False(t, fi.InActive())
+
+	// No ignore file means no rules
+	ignored, err = fi.Ignorer(ctx, f, "", fs.DirEntries{})
+	require.NoError(t, err)
+	assert.Nil(t, ignored)
+
+	ignored, err = fi.Ignorer(ctx, f, "", entries)
+	require.NoError(t, err)
+	require.NotNil(t, ignored)
+	for _, test := range []struct {
+		remote string
+		isDir  bool
+		want   bool
+	}{
+		{".rcloneignore", false, false},
+		{"a.log", false, true},
+		{"a.log", true, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"top.txt", false, true},
+		{"potato", false, false},
+	} {
+		assert.Equal(t, test.want, ignored(test.remote, test.isDir), test.remote)
+	}
+
+	// The rules from the root apply to the sub directory which
+	// has no ignore file of its own
+	ignored, err = fi.Ignorer(ctx, f, "sub", nil)
+	require.NoError(t, err)
+	require.NotNil(t, ignored)
+	assert.True(t, ignored("sub/a.log", false))
+	assert.False(t, ignored("sub/keep.log", false))
+	assert.True(t, ignored("sub/build", true))
+	assert.False(t, ignored("sub/top.txt", false))
+}
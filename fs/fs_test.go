@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strings"
 	"sync"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/obscure"
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/lib/pacer"
 	"github.com/stretchr/testify/assert"
@@ -152,6 +154,39 @@ func TestPacerCallNoRetry(t *testing.T) {
 	require.Implements(t, (*fserrors.Retrier)(nil), err)
 }
 
+func TestPacerAdaptive(t *testing.T) {
+	ctx, ci := AddConfig(context.Background())
+	ci.AdaptivePacer = true
+	p := NewPacer(withPacerName(ctx, "adaptive-test"), pacer.NewDefault(pacer.MinSleep(1*time.Millisecond), pacer.MaxSleep(2*time.Millisecond)))
+	defer func() {
+		pacersMu.Lock()
+		delete(pacers, "adaptive-test")
+		pacersMu.Unlock()
+	}()
+
+	// The backend's calculator can still be modified
+	p.ModifyCalculator(func(c pacer.Calculator) {
+		_, ok := c.(*pacer.Default)
+		assert.True(t, ok)
+	})
+
+	// Only rate limited retries slow it down
+	_ = p.CallNoRetry(func() (bool, error) {
+		return true, pacer.RateLimitedError(errFoo)
+	})
+	stats, ok := PacerStats()["adaptive-test"]
+	require.True(t, ok)
+	assert.Equal(t, int64(1), stats.Calls)
+	assert.Equal(t, int64(1), stats.Retries)
+	assert.True(t, stats.Rate > 0)
+
+	// Not adaptive so no stats
+	ci.AdaptivePacer = false
+	_ = NewPacer(withPacerName(ctx, "adaptive-test"), nil)
+	_, ok = PacerStats()["adaptive-test"]
+	assert.False(t, ok)
+}
+
 // Test options
 var (
 	nouncOption = Option{
@@ -297,6 +332,62 @@ func TestOptionEnvVarName(t *testing.T) {
 	assert.Equal(t, "RCLONE_LOCAL_CASE_INSENSITIVE", caseInsensitiveOption.EnvVarName("local"))
 }
 
+func TestInterpolateGetter(t *testing.T) {
+	assert.NoError(t, os.Setenv("RCLONE_TEST_SECRET", "potato"))
+	defer func() {
+		assert.NoError(t, os.Unsetenv("RCLONE_TEST_SECRET"))
+	}()
+	secretFile, err := ioutil.TempFile("", "rclone-secret")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.Remove(secretFile.Name())
+	}()
+	_, err = secretFile.WriteString("sausage\n")
+	require.NoError(t, err)
+	require.NoError(t, secretFile.Close())
+
+	fsInfo := &RegInfo{
+		Name:   "test",
+		Prefix: "test",
+		Options: Options{{
+			Name:       "pass",
+			IsPassword: true,
+		}},
+	}
+	getter := interpolateGetter{
+		getter: configmap.Simple{
+			"plain":   "value",
+			"env":     "pre-${RCLONE_TEST_SECRET}-post",
+			"dollar":  "$RCLONE_TEST_SECRET",
+			"file":    "file:" + secretFile.Name(),
+			"missing": "${RCLONE_TEST_SECRET_NOT_SET}",
+			"pass":    "${RCLONE_TEST_SECRET}",
+		},
+		fsInfo: fsInfo,
+	}
+	for _, test := range []struct {
+		key       string
+		wantValue string
+		wantOk    bool
+	}{
+		{"not_found", "", false},
+		{"plain", "value", true},
+		{"env", "pre-potato-post", true},
+		{"dollar", "$RCLONE_TEST_SECRET", true},
+		{"file", "sausage", true},
+		{"missing", "", false},
+	} {
+		gotValue, gotOk := getter.Get(test.key)
+		assert.Equal(t, test.wantValue, gotValue, test.key)
+		assert.Equal(t, test.wantOk, gotOk, test.key)
+	}
+
+	// Passwords are obscured as they are in the config file
+	gotValue, gotOk := getter.Get("pass")
+	assert.True(t, gotOk)
+	assert.Equal(t, "potato", obscure.MustReveal(gotValue))
+}
+
 func TestOptionGetters(t *testing.T) {
 	// Set up env vars
 	envVars := [][2]string{
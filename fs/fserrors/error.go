@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/rclone/rclone/lib/errors"
+	"github.com/rclone/rclone/lib/pacer"
 )
 
 // Retrier is an optional interface for error as to whether the
@@ -438,6 +439,24 @@ func ShouldRetryHTTP(resp *http.Response, retryErrorCodes []int) bool {
 	return false
 }
 
+// RateLimitedHTTP returns err marked with pacer.RateLimitedError if
+// resp has a 429 Too Many Requests or 503 Service Unavailable status,
+// so the adaptive pacer slows down, otherwise it returns err.
+func RateLimitedHTTP(resp *http.Response, err error) error {
+	if resp == nil {
+		return err
+	}
+	return RateLimitedStatus(resp.StatusCode, err)
+}
+
+// RateLimitedStatus is RateLimitedHTTP for an HTTP status code
+func RateLimitedStatus(statusCode int, err error) error {
+	if err != nil && (statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable) {
+		return pacer.RateLimitedError(err)
+	}
+	return err
+}
+
 // ContextError checks to see if ctx is in error.
 //
 // If it is in error then it overwrites *perr with the context error
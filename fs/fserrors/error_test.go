@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"syscall"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/rclone/rclone/lib/pacer"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -189,3 +191,22 @@ func TestContextError(t *testing.T) {
 	assert.True(t, ContextError(ctx, &err))
 	assert.Equal(t, context.Canceled, err)
 }
+
+func TestRateLimitedHTTP(t *testing.T) {
+	base := errors.New("error")
+	for _, test := range []struct {
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{nil, base, false},
+		{&http.Response{StatusCode: http.StatusTooManyRequests}, base, true},
+		{&http.Response{StatusCode: http.StatusServiceUnavailable}, base, true},
+		{&http.Response{StatusCode: http.StatusInternalServerError}, base, false},
+		{&http.Response{StatusCode: http.StatusTooManyRequests}, nil, false},
+	} {
+		err := RateLimitedHTTP(test.resp, test.err)
+		assert.Equal(t, test.want, pacer.IsRateLimited(err), "%+v", test)
+		assert.Equal(t, test.err, errors.Cause(err))
+	}
+}
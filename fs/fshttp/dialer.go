@@ -3,9 +3,11 @@ package fshttp
 import (
 	"context"
 	"net"
+	"net/url"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rclone/rclone/fs"
@@ -23,10 +25,21 @@ type Dialer struct {
 	net.Dialer
 	timeout time.Duration
 	tclass  int
+	proxy   *url.URL          // proxy to connect through if set
+	ipv4    bool              // only connect to IPv4 addresses
+	ipv6    bool              // only connect to IPv6 addresses
+	hosts   map[string]net.IP // host names to connect to these IPs
 }
 
+// bindIndex is incremented for each Dialer made to choose the next
+// local address from --bind
+var bindIndex uint32
+
 // NewDialer creates a Dialer structure with Timeout, Keepalive,
 // LocalAddr and DSCP set from rclone flags.
+//
+// If more than one --bind address is set each Dialer uses the next
+// one in turn.
 func NewDialer(ctx context.Context) *Dialer {
 	ci := fs.GetConfig(ctx)
 	dialer := &Dialer{
@@ -36,9 +49,17 @@ func NewDialer(ctx context.Context) *Dialer {
 		},
 		timeout: ci.Timeout,
 		tclass:  int(ci.TrafficClass),
+		ipv4:    ci.IPv4Only,
+		ipv6:    ci.IPv6Only,
+		hosts:   ci.DNSOverride,
 	}
-	if ci.BindAddr != nil {
-		dialer.Dialer.LocalAddr = &net.TCPAddr{IP: ci.BindAddr}
+	if n := len(ci.BindAddr); n > 0 {
+		// Use the addresses in turn to spread connections over them
+		i := 0
+		if n > 1 {
+			i = int(atomic.AddUint32(&bindIndex, 1) % uint32(n))
+		}
+		dialer.Dialer.LocalAddr = &net.TCPAddr{IP: ci.BindAddr[i]}
 	}
 	return dialer
 }
@@ -53,7 +74,8 @@ var warnDSCPFail, warnDSCPWindows sync.Once
 // DialContext connects to the address on the named network using
 // the provided context.
 func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
-	c, err := d.Dialer.DialContext(ctx, network, address)
+	network, address = d.rewrite(network, address)
+	c, err := d.dial(ctx, network, address)
 	if err != nil {
 		return c, err
 	}
@@ -79,6 +101,34 @@ func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.
 	return newTimeoutConn(c, d.timeout)
 }
 
+// rewrite returns network restricted to the allowed IP version and
+// address with the host replaced if it is in --dns-override
+func (d *Dialer) rewrite(network, address string) (string, string) {
+	switch network {
+	case "tcp":
+		if d.ipv4 {
+			network = "tcp4"
+		} else if d.ipv6 {
+			network = "tcp6"
+		}
+	case "udp":
+		if d.ipv4 {
+			network = "udp4"
+		} else if d.ipv6 {
+			network = "udp6"
+		}
+	}
+	if len(d.hosts) > 0 {
+		host, port, err := net.SplitHostPort(address)
+		if err == nil {
+			if ip, ok := d.hosts[strings.ToLower(host)]; ok {
+				address = net.JoinHostPort(ip.String(), port)
+			}
+		}
+	}
+	return network, address
+}
+
 // A net.Conn that sets a deadline for every Read or Write operation
 type timeoutConn struct {
 	net.Conn
@@ -0,0 +1,61 @@
+package fshttp
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialerRewrite(t *testing.T) {
+	hosts := map[string]net.IP{
+		"s3.example.com": net.ParseIP("192.0.2.1"),
+		"v6.example.com": net.ParseIP("2001:db8::1"),
+	}
+	for _, test := range []struct {
+		ipv4, ipv6  bool
+		network     string
+		address     string
+		wantNetwork string
+		wantAddress string
+	}{
+		{false, false, "tcp", "s3.example.com:443", "tcp", "192.0.2.1:443"},
+		{false, false, "tcp", "S3.Example.COM:443", "tcp", "192.0.2.1:443"},
+		{false, false, "tcp", "v6.example.com:80", "tcp", "[2001:db8::1]:80"},
+		{false, false, "tcp", "other.example.com:443", "tcp", "other.example.com:443"},
+		{true, false, "tcp", "other.example.com:443", "tcp4", "other.example.com:443"},
+		{false, true, "tcp", "other.example.com:443", "tcp6", "other.example.com:443"},
+		{true, false, "udp", "other.example.com:53", "udp4", "other.example.com:53"},
+		{true, false, "tcp6", "other.example.com:443", "tcp6", "other.example.com:443"},
+	} {
+		d := &Dialer{ipv4: test.ipv4, ipv6: test.ipv6, hosts: hosts}
+		gotNetwork, gotAddress := d.rewrite(test.network, test.address)
+		assert.Equal(t, test.wantNetwork, gotNetwork, test.address)
+		assert.Equal(t, test.wantAddress, gotAddress, test.address)
+	}
+}
+
+func TestDialerDNSOverride(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = l.Close() }()
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}()
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.IPv4Only = true
+	ci.DNSOverride = map[string]net.IP{"rclone.invalid": net.ParseIP("127.0.0.1")}
+	conn, err := NewDialer(ctx).DialContext(ctx, "tcp", net.JoinHostPort("rclone.invalid", port))
+	require.NoError(t, err)
+	assert.Equal(t, l.Addr().String(), conn.RemoteAddr().String())
+	require.NoError(t, conn.Close())
+}
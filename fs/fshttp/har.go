@@ -0,0 +1,353 @@
+// Dump HTTP transactions to a HAR file
+
+package fshttp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/atexit"
+)
+
+// The HAR file is written as the transactions complete so that it is
+// useful even if rclone is interrupted. The log is opened when the
+// first Transport is made and closed off when rclone exits.
+//
+// See http://www.softwareishard.com/blog/har-12-spec/ for the format.
+
+// harFile is the open HAR file
+type harFile struct {
+	mu      sync.Mutex
+	out     *os.File
+	entries int
+}
+
+var (
+	harOnce sync.Once
+	har     *harFile
+)
+
+// harNameValue is a header or query parameter
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harPostData is the body of a request
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// harRequest is a request in a HAR entry
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// harContent is the body of a response
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// harResponse is a response in a HAR entry
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+	Error       string         `json:"_error,omitempty"`
+}
+
+// harTimings are the times in ms spent in each part of the transaction
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harEntry is a single HTTP transaction
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+// newHARFile creates the HAR file at path and writes its header
+func newHARFile(path string) (*harFile, error) {
+	out, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	creator, _ := json.Marshal(map[string]string{"name": "rclone", "version": fs.Version})
+	_, err = out.WriteString(`{"log":{"version":"1.2","creator":` + string(creator) + `,"entries":[` + "\n")
+	if err != nil {
+		_ = out.Close()
+		return nil, err
+	}
+	return &harFile{out: out}, nil
+}
+
+// getHARFile opens the HAR file at path the first time it is called
+// returning nil if that failed
+func getHARFile(path string) *harFile {
+	harOnce.Do(func() {
+		var err error
+		har, err = newHARFile(path)
+		if err != nil {
+			fs.Errorf(nil, "Failed to open --dump-har file: %v", err)
+			return
+		}
+		atexit.Register(har.close)
+	})
+	return har
+}
+
+// write adds the entry to the HAR file
+func (h *harFile) write(entry *harEntry) {
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		fs.Errorf(nil, "Failed to encode --dump-har entry: %v", err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.out == nil {
+		return
+	}
+	if h.entries > 0 {
+		_, _ = h.out.WriteString(",\n")
+	}
+	h.entries++
+	if _, err = h.out.Write(buf); err != nil {
+		fs.Errorf(nil, "Failed to write --dump-har file: %v", err)
+	}
+}
+
+// close finishes off the HAR file
+func (h *harFile) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.out == nil {
+		return
+	}
+	_, err := h.out.WriteString("\n]}}\n")
+	if err == nil {
+		err = h.out.Close()
+	}
+	if err != nil {
+		fs.Errorf(nil, "Failed to close --dump-har file: %v", err)
+	}
+	h.out = nil
+}
+
+// harBody records up to limit bytes of a body as it is read
+type harBody struct {
+	buf   bytes.Buffer
+	size  int64
+	limit int64
+}
+
+// record notes the p just read
+func (b *harBody) record(p []byte) {
+	b.size += int64(len(p))
+	if room := b.limit - int64(b.buf.Len()); room > 0 {
+		if int64(len(p)) > room {
+			p = p[:room]
+		}
+		b.buf.Write(p)
+	}
+}
+
+// text returns the recorded body, its encoding and a comment if it
+// was truncated
+func (b *harBody) text() (text, encoding, comment string) {
+	data := b.buf.Bytes()
+	if b.size > int64(len(data)) {
+		comment = "truncated by --dump-har-body"
+	}
+	if utf8.Valid(data) {
+		return string(data), "", comment
+	}
+	return base64.StdEncoding.EncodeToString(data), "base64", comment
+}
+
+// harRequestBody records the request body as the transport reads it
+type harRequestBody struct {
+	io.ReadCloser
+	harBody
+}
+
+// Read the body recording what was read
+func (r *harRequestBody) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	r.record(p[:n])
+	return n, err
+}
+
+// harResponseBody records the response body as the caller reads it
+// and writes the entry when it is closed
+type harResponseBody struct {
+	io.ReadCloser
+	harBody
+	once   sync.Once
+	finish func(*harResponseBody)
+}
+
+// Read the body recording what was read
+func (r *harResponseBody) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	r.record(p[:n])
+	return n, err
+}
+
+// Close the body and write the entry
+func (r *harResponseBody) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(func() {
+		r.finish(r)
+	})
+	return err
+}
+
+// harHeaders converts headers into HAR form, hiding the auth headers
+// unless showAuth is set
+func harHeaders(header http.Header, showAuth bool) []harNameValue {
+	out := []harNameValue{}
+	for name, values := range header {
+		for _, value := range values {
+			if !showAuth && (strings.EqualFold(name, "Authorization") || strings.EqualFold(name, "X-Auth-Token")) {
+				value = "XXXX"
+			}
+			out = append(out, harNameValue{Name: name, Value: value})
+		}
+	}
+	return out
+}
+
+// ms returns d in milliseconds
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// harRoundTrip does the round trip for req with rt recording it in
+// the HAR file
+func (t *Transport) harRoundTrip(req *http.Request, rt func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	showAuth := t.dump&fs.DumpAuth != 0
+	// Don't show the password if there is one
+	u := *req.URL
+	u.User = nil
+	entry := &harEntry{
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         u.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.Header, showAuth),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    req.ContentLength,
+		},
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: name, Value: value})
+		}
+	}
+	var reqBody *harRequestBody
+	if req.Body != nil && req.Body != http.NoBody && t.harBodyLimit > 0 {
+		reqBody = &harRequestBody{ReadCloser: req.Body, harBody: harBody{limit: t.harBodyLimit}}
+		// Don't modify the caller's request
+		req = req.WithContext(req.Context())
+		req.Body = reqBody
+	}
+
+	start := time.Now()
+	entry.StartedDateTime = start.Format(time.RFC3339Nano)
+	resp, err := rt(req)
+	wait := time.Since(start)
+	entry.Timings.Wait = ms(wait)
+	entry.Time = entry.Timings.Wait
+	if reqBody != nil {
+		text, encoding, comment := reqBody.text()
+		entry.Request.PostData = &harPostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     text,
+			Encoding: encoding,
+			Comment:  comment,
+		}
+		entry.Request.BodySize = reqBody.size
+	}
+	if err != nil {
+		entry.Response = harResponse{
+			Cookies: []harNameValue{},
+			Headers: []harNameValue{},
+			Error:   err.Error(),
+		}
+		t.har.write(entry)
+		return resp, err
+	}
+
+	entry.Response = harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(resp.Header, showAuth),
+		Content: harContent{
+			MimeType: resp.Header.Get("Content-Type"),
+		},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+	}
+	if resp.Body == nil {
+		t.har.write(entry)
+		return resp, err
+	}
+	limit := t.harBodyLimit
+	resp.Body = &harResponseBody{
+		ReadCloser: resp.Body,
+		harBody:    harBody{limit: limit},
+		finish: func(body *harResponseBody) {
+			entry.Timings.Receive = ms(time.Since(start) - wait)
+			entry.Time = entry.Timings.Wait + entry.Timings.Receive
+			entry.Response.Content.Size = body.size
+			entry.Response.BodySize = body.size
+			if limit > 0 {
+				entry.Response.Content.Text, entry.Response.Content.Encoding, entry.Response.Content.Comment = body.text()
+			}
+			t.har.write(entry)
+		},
+	}
+	return resp, err
+}
@@ -6,15 +6,20 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httputil"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/lib/structs"
@@ -31,12 +36,18 @@ var (
 	noTransport  = new(sync.Once)
 	cookieJar, _ = cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
 	logMutex     sync.Mutex
+
+	dialTransportsMu sync.Mutex                       // protects dialTransports
+	dialTransports   = map[string]http.RoundTripper{} // transports for remotes with their own network settings
 )
 
 // ResetTransport resets the existing transport, allowing it to take new settings.
 // Should only be used for testing.
 func ResetTransport() {
 	noTransport = new(sync.Once)
+	dialTransportsMu.Lock()
+	dialTransports = map[string]http.RoundTripper{}
+	dialTransportsMu.Unlock()
 }
 
 // NewTransportCustom returns an http.RoundTripper with the correct timeouts.
@@ -48,6 +59,10 @@ func NewTransportCustom(ctx context.Context, customize func(*http.Transport)) ht
 	// This also means we get new stuff when it gets added to go
 	t := new(http.Transport)
 	structs.SetDefaults(t, http.DefaultTransport.(*http.Transport))
+	// Don't share the HTTP/2 connections of the default transport,
+	// which it adds to TLSNextProto once used, but set up our own
+	t.TLSNextProto = nil
+	t.ForceAttemptHTTP2 = true
 	t.Proxy = http.ProxyFromEnvironment
 	t.MaxIdleConnsPerHost = 2 * (ci.Checkers + ci.Transfers + 1)
 	t.MaxIdleConns = 2 * t.MaxIdleConnsPerHost
@@ -64,26 +79,18 @@ func NewTransportCustom(ctx context.Context, customize func(*http.Transport)) ht
 		if ci.ClientCert == "" || ci.ClientKey == "" {
 			log.Fatalf("Both --client-cert and --client-key must be set")
 		}
-		cert, err := tls.LoadX509KeyPair(ci.ClientCert, ci.ClientKey)
+		err := loadClientCert(t, ci.ClientCert, ci.ClientKey)
 		if err != nil {
 			log.Fatalf("Failed to load --client-cert/--client-key pair: %v", err)
 		}
-		t.TLSClientConfig.Certificates = []tls.Certificate{cert}
-		t.TLSClientConfig.BuildNameToCertificate()
 	}
 
 	// Load CA cert
 	if ci.CaCert != "" {
-		caCert, err := ioutil.ReadFile(ci.CaCert)
+		err := loadCACert(t, ci.CaCert)
 		if err != nil {
-			log.Fatalf("Failed to read --ca-cert: %v", err)
-		}
-		caCertPool := x509.NewCertPool()
-		ok := caCertPool.AppendCertsFromPEM(caCert)
-		if !ok {
-			log.Fatalf("Failed to add certificates from --ca-cert")
+			log.Fatalf("Failed to load --ca-cert: %v", err)
 		}
-		t.TLSClientConfig.RootCAs = caCertPool
 	}
 
 	t.DisableCompression = ci.NoGzip
@@ -101,7 +108,7 @@ func NewTransportCustom(ctx context.Context, customize func(*http.Transport)) ht
 	}
 
 	if ci.DisableHTTP2 {
-		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		disableHTTP2(t)
 	}
 
 	// customize the transport if required
@@ -113,8 +120,117 @@ func NewTransportCustom(ctx context.Context, customize func(*http.Transport)) ht
 	return newTransport(ci, t)
 }
 
+// loadClientCert makes t present the client certificate in certFile
+// with the key in keyFile
+func loadClientCert(t *http.Transport, certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	t.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	t.TLSClientConfig.BuildNameToCertificate()
+	return nil
+}
+
+// loadCACert makes t verify servers with the CA certificates in
+// caCertFile only
+func loadCACert(t *http.Transport, caCertFile string) error {
+	caCert, err := ioutil.ReadFile(caCertFile)
+	if err != nil {
+		return err
+	}
+	caCertPool := x509.NewCertPool()
+	ok := caCertPool.AppendCertsFromPEM(caCert)
+	if !ok {
+		return errors.New("no certificates found")
+	}
+	t.TLSClientConfig.RootCAs = caCertPool
+	return nil
+}
+
+// disableHTTP2 stops t upgrading connections to HTTP/2
+func disableHTTP2(t *http.Transport) {
+	t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+}
+
+// TransportOptions are the transport settings a backend can override
+// for a single remote. The zero value uses the global settings.
+type TransportOptions struct {
+	CaCert       string // CA certificate file to verify servers with
+	ClientCert   string // Client certificate file for mutual TLS auth
+	ClientKey    string // Client private key file for mutual TLS auth
+	DisableHTTP2 bool   // Don't use HTTP/2
+}
+
+// NewTransportWithOptions returns an http.RoundTripper like
+// NewTransportCustom with the settings in opt applied after the
+// global ones and before calling customize if set.
+func NewTransportWithOptions(ctx context.Context, opt TransportOptions, customize func(*http.Transport)) (http.RoundTripper, error) {
+	if (opt.ClientCert == "") != (opt.ClientKey == "") {
+		return nil, errors.New("both client_cert and client_key must be set")
+	}
+	var err error
+	rt := NewTransportCustom(ctx, func(t *http.Transport) {
+		if opt.ClientCert != "" {
+			err = loadClientCert(t, opt.ClientCert, opt.ClientKey)
+			if err != nil {
+				err = errors.Wrap(err, "failed to load client_cert/client_key pair")
+				return
+			}
+		}
+		if opt.CaCert != "" {
+			err = loadCACert(t, opt.CaCert)
+			if err != nil {
+				err = errors.Wrap(err, "failed to load ca_cert")
+				return
+			}
+		}
+		if opt.DisableHTTP2 {
+			disableHTTP2(t)
+		}
+		if customize != nil {
+			customize(t)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
+
+// dialKey returns a string identifying the settings of ci used by
+// the Dialer which can be set per remote
+func dialKey(ci *fs.ConfigInfo) string {
+	var out []string
+	for _, addr := range ci.BindAddr {
+		out = append(out, addr.String())
+	}
+	key := strings.Join(out, ",") + fmt.Sprintf(";%v;%v;", ci.IPv4Only, ci.IPv6Only)
+	hosts := make([]string, 0, len(ci.DNSOverride))
+	for host, ip := range ci.DNSOverride {
+		hosts = append(hosts, host+"="+ip.String())
+	}
+	sort.Strings(hosts)
+	return key + strings.Join(hosts, ",")
+}
+
 // NewTransport returns an http.RoundTripper with the correct timeouts
+//
+// If ctx has different network settings to the global config, as set
+// by the bind, ipv4_only, ipv6_only or dns_override parameters of a
+// remote, a transport is shared between the users of those settings.
 func NewTransport(ctx context.Context) http.RoundTripper {
+	key := dialKey(fs.GetConfig(ctx))
+	if key != dialKey(fs.GetConfig(context.Background())) {
+		dialTransportsMu.Lock()
+		defer dialTransportsMu.Unlock()
+		t, ok := dialTransports[key]
+		if !ok {
+			t = NewTransportCustom(ctx, nil)
+			dialTransports[key] = t
+		}
+		return t
+	}
 	(*noTransport).Do(func() {
 		transport = NewTransportCustom(ctx, nil)
 	})
@@ -142,17 +258,24 @@ type Transport struct {
 	filterRequest func(req *http.Request)
 	userAgent     string
 	headers       []*fs.HTTPOption
+	har           *harFile // HAR file to write transactions to if set
+	harBodyLimit  int64    // max size of bodies to write to the HAR file
 }
 
 // newTransport wraps the http.Transport passed in and logs all
 // roundtrips including the body if logBody is set.
 func newTransport(ci *fs.ConfigInfo, transport *http.Transport) *Transport {
-	return &Transport{
+	t := &Transport{
 		Transport: transport,
 		dump:      ci.Dump,
 		userAgent: ci.UserAgent,
 		headers:   ci.Headers,
 	}
+	if ci.DumpHAR != "" {
+		t.har = getHARFile(ci.DumpHAR)
+		t.harBodyLimit = int64(ci.DumpHARBody)
+	}
+	return t
 }
 
 // SetRequestFilter sets a filter to be used on each request
@@ -240,6 +363,57 @@ func cleanAuths(buf []byte) []byte {
 	return buf
 }
 
+// logRoundTrip logs a structured record of each HTTP transaction
+// when using JSON logs with -vv
+func logRoundTrip(req *http.Request, resp *http.Response, err error, duration time.Duration) {
+	ci := fs.GetConfig(req.Context())
+	if !ci.UseJSONLog || ci.LogLevel < fs.LogLevelDebug {
+		return
+	}
+	// Don't log the query as it may contain credentials
+	u := *req.URL
+	u.RawQuery = ""
+	u.User = nil
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	fs.Debugf(nil, "HTTP %s %s: %d (error %v)%v%v%v%v%v",
+		req.Method, u.String(), status, err,
+		fs.LogValueHide("operation", "http"),
+		fs.LogValueHide("method", req.Method),
+		fs.LogValueHide("url", u.String()),
+		fs.LogValueHide("status", status),
+		fs.LogValueHide("duration", duration.Seconds()),
+	)
+}
+
+// apiPathWord matches path segments which are part of an API rather
+// than names or IDs, eg "files" or "v3"
+var apiPathWord = regexp.MustCompile(`^([a-z][a-z_-]{0,31}|v[0-9]+(\.[0-9]+)?|\$[a-z]+)$`)
+
+// maxAPIPathSegments is the number of path segments used to name an
+// API endpoint
+const maxAPIPathSegments = 3
+
+// apiEndpoint returns the name of the API endpoint req is for to
+// count calls in the stats, eg "www.googleapis.com: GET /drive/v3/files"
+//
+// Path segments which look like names or IDs are replaced with "*"
+// so files and directories aren't counted separately.
+func apiEndpoint(req *http.Request) string {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(segments) > maxAPIPathSegments {
+		segments = segments[:maxAPIPathSegments]
+	}
+	for i, segment := range segments {
+		if segment != "" && !apiPathWord.MatchString(segment) {
+			segments[i] = "*"
+		}
+	}
+	return req.URL.Host + ": " + req.Method + " /" + strings.Join(segments, "/")
+}
+
 // RoundTrip implements the RoundTripper interface.
 func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
 	// Limit transactions per second if required
@@ -267,8 +441,16 @@ func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error
 		fs.Debugf(nil, "%s", separatorReq)
 		logMutex.Unlock()
 	}
+	// Count the call in the stats
+	accounting.Stats(req.Context()).APICall(apiEndpoint(req))
 	// Do round trip
-	resp, err = t.Transport.RoundTrip(req)
+	start := time.Now()
+	if t.har != nil {
+		resp, err = t.harRoundTrip(req, t.Transport.RoundTrip)
+	} else {
+		resp, err = t.Transport.RoundTrip(req)
+	}
+	logRoundTrip(req, resp, err, time.Since(start))
 	// Logf response
 	if t.dump&(fs.DumpHeaders|fs.DumpBodies|fs.DumpAuth|fs.DumpRequests|fs.DumpResponses) != 0 {
 		logMutex.Lock()
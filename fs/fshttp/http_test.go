@@ -1,9 +1,20 @@
 package fshttp
 
 import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCleanAuth(t *testing.T) {
@@ -45,3 +56,152 @@ func TestCleanAuths(t *testing.T) {
 		assert.Equal(t, test.want, got, test.in)
 	}
 }
+
+func TestDumpHAR(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "request body", string(body))
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("response body"))
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "rclone-har")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	path := filepath.Join(dir, "dump.har")
+	h, err := newHARFile(path)
+	require.NoError(t, err)
+
+	tr := &Transport{
+		Transport:    http.DefaultTransport.(*http.Transport),
+		har:          h,
+		harBodyLimit: 8,
+	}
+	client := &http.Client{Transport: tr}
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("POST", ts.URL+"/path?q=1", strings.NewReader("request body"))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "response body", string(body))
+		require.NoError(t, resp.Body.Close())
+	}
+	h.close()
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+	var result struct {
+		Log struct {
+			Version string     `json:"version"`
+			Entries []harEntry `json:"entries"`
+		} `json:"log"`
+	}
+	require.NoError(t, json.Unmarshal(data, &result))
+	assert.Equal(t, "1.2", result.Log.Version)
+	require.Len(t, result.Log.Entries, 2)
+	entry := result.Log.Entries[0]
+	assert.Equal(t, "POST", entry.Request.Method)
+	assert.Equal(t, ts.URL+"/path?q=1", entry.Request.URL)
+	assert.Equal(t, []harNameValue{{Name: "q", Value: "1"}}, entry.Request.QueryString)
+	assert.Contains(t, entry.Request.Headers, harNameValue{Name: "Authorization", Value: "XXXX"})
+	require.NotNil(t, entry.Request.PostData)
+	assert.Equal(t, "request ", entry.Request.PostData.Text)
+	assert.Equal(t, int64(12), entry.Request.BodySize)
+	assert.Equal(t, 200, entry.Response.Status)
+	assert.Equal(t, "text/plain", entry.Response.Content.MimeType)
+	assert.Equal(t, "response", entry.Response.Content.Text)
+	assert.NotEqual(t, "", entry.Response.Content.Comment)
+	assert.Equal(t, int64(13), entry.Response.Content.Size)
+}
+
+func TestAPIEndpoint(t *testing.T) {
+	for _, test := range []struct {
+		method string
+		url    string
+		want   string
+	}{
+		{"GET", "https://www.googleapis.com/drive/v3/files?q=x", "www.googleapis.com: GET /drive/v3/files"},
+		{"PATCH", "https://www.googleapis.com/drive/v3/files/1a2B3c4D5e6F7g", "www.googleapis.com: PATCH /drive/v3/files"},
+		{"POST", "https://www.googleapis.com/upload/drive/v3/files", "www.googleapis.com: POST /upload/drive/v3"},
+		{"GET", "https://bucket.s3.amazonaws.com/", "bucket.s3.amazonaws.com: GET /"},
+		{"PUT", "https://bucket.s3.amazonaws.com/Photos/2020/cat.jpg", "bucket.s3.amazonaws.com: PUT /*/*/*"},
+		{"POST", "https://graph.microsoft.com/v1.0/$batch", "graph.microsoft.com: POST /v1.0/$batch"},
+	} {
+		req, err := http.NewRequest(test.method, test.url, nil)
+		require.NoError(t, err)
+		assert.Equal(t, test.want, apiEndpoint(req), test.url)
+	}
+}
+
+func TestNewTransportWithOptions(t *testing.T) {
+	ctx := context.Background()
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "rclone-fshttp-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	caCert := filepath.Join(dir, "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(caCert, certPEM, 0600))
+
+	// Use the default transport first so it has set up its HTTP/2
+	// connection pool in TLSNextProto which mustn't be shared
+	_, err = http.Get(ts.URL)
+	assert.Error(t, err)
+	defaultNextProto := reflect.ValueOf(http.DefaultTransport.(*http.Transport).TLSNextProto).Pointer()
+
+	var transport *http.Transport
+	get := func(opt TransportOptions) (string, error) {
+		rt, err := NewTransportWithOptions(ctx, opt, func(t *http.Transport) {
+			transport = t
+		})
+		if err != nil {
+			return "", err
+		}
+		resp, err := (&http.Client{Transport: rt}).Get(ts.URL)
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := ioutil.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	// Without the CA the server isn't trusted
+	_, err = get(TransportOptions{})
+	assert.Error(t, err)
+
+	// With the CA it is, using HTTP/2
+	proto, err := get(TransportOptions{CaCert: caCert})
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", proto)
+	assert.NotEqual(t, defaultNextProto, reflect.ValueOf(transport.TLSNextProto).Pointer())
+
+	// HTTP/2 can be turned off
+	proto, err = get(TransportOptions{CaCert: caCert, DisableHTTP2: true})
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1", proto)
+
+	// Certificate files which don't exist are errors
+	_, err = get(TransportOptions{CaCert: filepath.Join(dir, "notfound.pem")})
+	assert.Error(t, err)
+
+	// Client certificate and key must be set together
+	_, err = get(TransportOptions{ClientCert: caCert})
+	assert.EqualError(t, err, "both client_cert and client_key must be set")
+}
@@ -0,0 +1,156 @@
+package fshttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
+)
+
+// ParseProxy parses and checks the URL of a proxy which may be of
+// the form
+//
+//     socks5://[user:pass@]host:port
+//     http://[user:pass@]host:port
+//
+// socks5h is accepted as a synonym for socks5. The host names are
+// always resolved by SOCKS5 proxies.
+func ParseProxy(proxyURL string) (*url.URL, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse proxy URL")
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return nil, errors.Errorf("unsupported proxy scheme %q - use socks5 or http", u.Scheme)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, errors.Errorf("proxy URL %q must contain a host and port", proxyURL)
+	}
+	return u, nil
+}
+
+// ProxyURL returns a function to use as http.Transport.Proxy which
+// always uses the proxy u parsed with ParseProxy
+func ProxyURL(u *url.URL) func(*http.Request) (*url.URL, error) {
+	if u.Scheme == "socks5h" {
+		// http.Transport only knows socks5
+		newU := *u
+		newU.Scheme = "socks5"
+		u = &newU
+	}
+	return http.ProxyURL(u)
+}
+
+// SetProxy makes the Dialer connect through the proxy u parsed with
+// ParseProxy. If u is nil connections are made directly.
+func (d *Dialer) SetProxy(u *url.URL) *Dialer {
+	d.proxy = u
+	return d
+}
+
+// dial connects to address directly or through the proxy if set
+func (d *Dialer) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	if d.proxy == nil {
+		return d.Dialer.DialContext(ctx, network, address)
+	}
+	switch d.proxy.Scheme {
+	case "socks5", "socks5h":
+		return d.dialSOCKS5(ctx, network, address)
+	case "http":
+		return d.dialHTTPConnect(ctx, network, address)
+	}
+	return nil, errors.Errorf("unsupported proxy scheme %q", d.proxy.Scheme)
+}
+
+// dialSOCKS5 connects to address through the SOCKS5 proxy
+func (d *Dialer) dialSOCKS5(ctx context.Context, network, address string) (net.Conn, error) {
+	var auth *proxy.Auth
+	if d.proxy.User != nil {
+		auth = &proxy.Auth{User: d.proxy.User.Username()}
+		auth.Password, _ = d.proxy.User.Password()
+	}
+	dialer, err := proxy.SOCKS5("tcp", d.proxy.Host, auth, &d.Dialer)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make SOCKS5 proxy dialer")
+	}
+	conn, err := dialer.(proxy.ContextDialer).DialContext(ctx, network, address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to %s through SOCKS5 proxy", address)
+	}
+	return conn, nil
+}
+
+// dialHTTPConnect connects to address through the HTTP proxy using
+// the CONNECT method
+func (d *Dialer) dialHTTPConnect(ctx context.Context, network, address string) (_ net.Conn, err error) {
+	conn, err := d.Dialer.DialContext(ctx, "tcp", d.proxy.Host)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to HTTP proxy")
+	}
+	defer func() {
+		if err != nil {
+			_ = conn.Close()
+		}
+	}()
+	// Stop the exchange with the proxy if the context is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if d.proxy.User != nil {
+		password, _ := d.proxy.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(d.proxy.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	err = req.Write(conn)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to write CONNECT to HTTP proxy")
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read CONNECT response from HTTP proxy")
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to connect to %s through HTTP proxy: %s", address, resp.Status)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	// The server may have already sent data, eg an FTP greeting
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a net.Conn which reads the data already buffered
+// from it first
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// Read from the buffer then the connection
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
@@ -3,12 +3,14 @@ package hash
 import (
 	"crypto/md5"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"hash"
 	"hash/crc32"
 	"io"
 	"strings"
+	"sync"
 
 	"github.com/jzelinskie/whirlpool"
 	"github.com/pkg/errors"
@@ -71,6 +73,12 @@ var (
 
 	// CRC32 indicates CRC-32 support
 	CRC32 Type
+
+	// SHA256 indicates SHA-256 support
+	SHA256 Type
+
+	// CRC32C indicates CRC-32C (Castagnoli) support
+	CRC32C Type
 )
 
 func init() {
@@ -78,6 +86,10 @@ func init() {
 	SHA1 = RegisterHash("sha1", "SHA-1", 40, sha1.New)
 	Whirlpool = RegisterHash("whirlpool", "Whirlpool", 128, whirlpool.New)
 	CRC32 = RegisterHash("crc32", "CRC-32", 8, func() hash.Hash { return crc32.NewIEEE() })
+	SHA256 = RegisterHash("sha256", "SHA-256", 64, sha256.New)
+	CRC32C = RegisterHash("crc32c", "CRC-32C", 8, func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) })
+	// XXH3 isn't registered as no implementation of it is in the
+	// module dependencies yet - see the note in the hashsum docs
 }
 
 // Supported returns a set of all the supported hashes by
@@ -181,7 +193,40 @@ func toMultiWriter(h map[Type]hash.Hash) io.Writer {
 	for _, v := range h {
 		w = append(w, v)
 	}
-	return io.MultiWriter(w...)
+	if len(w) == 1 {
+		return w[0]
+	}
+	return concurrentWriter(w)
+}
+
+// concurrentMinWrite is the smallest write which is hashed
+// concurrently - smaller writes aren't worth starting goroutines for
+const concurrentMinWrite = 16 * 1024
+
+// concurrentWriter writes to all the hashers at once, each in its
+// own goroutine, so several hashes can be computed in the time it
+// takes to compute the slowest.
+type concurrentWriter []io.Writer
+
+// Write p to all the writers. Writes to a hash.Hash never fail.
+func (cw concurrentWriter) Write(p []byte) (n int, err error) {
+	if len(p) < concurrentMinWrite {
+		for _, w := range cw {
+			_, _ = w.Write(p)
+		}
+		return len(p), nil
+	}
+	var wg sync.WaitGroup
+	wg.Add(len(cw) - 1)
+	for _, w := range cw[1:] {
+		go func(w io.Writer) {
+			defer wg.Done()
+			_, _ = w.Write(p)
+		}(w)
+	}
+	_, _ = cw[0].Write(p)
+	wg.Wait()
+	return len(p), nil
 }
 
 // A MultiHasher will construct various hashes on
@@ -0,0 +1,415 @@
+// On disk cache of directory listings for --use-listing-cache
+
+package list
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// EncodedEntry is a directory entry encoded so it can be stored on
+// disk, as in the listing cache.
+type EncodedEntry struct {
+	Remote  string            `json:"remote"`
+	IsDir   bool              `json:"isDir,omitempty"`
+	Size    int64             `json:"size"`
+	ModTime *time.Time        `json:"modTime,omitempty"` // nil if the backend has slow modtimes
+	Items   int64             `json:"items,omitempty"`
+	ID      string            `json:"id,omitempty"`
+	Hashes  map[string]string `json:"hashes,omitempty"` // only read if the backend has fast hashes
+}
+
+// EncodeEntry encodes entry which was listed from f.
+//
+// The modification times and hashes of objects are only stored if
+// reading them doesn't need an extra transaction.
+func EncodeEntry(ctx context.Context, f fs.Fs, entry fs.DirEntry) (e EncodedEntry, err error) {
+	features := f.Features()
+	e = EncodedEntry{
+		Remote: entry.Remote(),
+		Size:   entry.Size(),
+	}
+	if do, ok := entry.(fs.IDer); ok {
+		e.ID = do.ID()
+	}
+	switch x := entry.(type) {
+	case fs.Directory:
+		e.IsDir = true
+		e.Items = x.Items()
+		modTime := x.ModTime(ctx)
+		e.ModTime = &modTime
+	case fs.Object:
+		if !features.SlowModTime {
+			modTime := x.ModTime(ctx)
+			e.ModTime = &modTime
+		}
+		if !features.SlowHash {
+			for _, ht := range f.Hashes().Array() {
+				sum, err := x.Hash(ctx, ht)
+				if err == nil && sum != "" {
+					if e.Hashes == nil {
+						e.Hashes = make(map[string]string)
+					}
+					e.Hashes[ht.String()] = sum
+				}
+			}
+		}
+	default:
+		return e, errors.Errorf("unknown object type %T", entry)
+	}
+	return e, nil
+}
+
+// Decode returns the directory entry on f which e describes.
+//
+// Objects are returned as a wrapper which looks up the object on f
+// when anything which wasn't stored in e is needed.
+func (e *EncodedEntry) Decode(f fs.Fs) fs.DirEntry {
+	if e.IsDir {
+		var modTime time.Time
+		if e.ModTime != nil {
+			modTime = *e.ModTime
+		}
+		return fs.NewDir(e.Remote, modTime).SetSize(e.Size).SetItems(e.Items).SetID(e.ID)
+	}
+	o := &cachedObject{
+		f:       f,
+		remote:  e.Remote,
+		size:    e.Size,
+		modTime: e.ModTime,
+		id:      e.ID,
+	}
+	if len(e.Hashes) > 0 {
+		o.hashes = make(map[hash.Type]string, len(e.Hashes))
+		for name, sum := range e.Hashes {
+			var ht hash.Type
+			if ht.Set(name) == nil {
+				o.hashes[ht] = sum
+			}
+		}
+	}
+	return o
+}
+
+// Resolve returns the object on the remote if o was made by Decode,
+// looking it up if necessary, or o otherwise.
+//
+// This is needed before passing o to a backend which needs its own
+// object type, for example for a server-side copy.
+func Resolve(ctx context.Context, o fs.Object) (fs.Object, error) {
+	if co, ok := o.(*cachedObject); ok {
+		return co.object(ctx)
+	}
+	return o, nil
+}
+
+// cacheListing is a cached directory listing
+type cacheListing struct {
+	Path    string         `json:"path"`
+	Time    time.Time      `json:"time"`
+	Entries []EncodedEntry `json:"entries"`
+}
+
+// hashString returns a string which can be used as a file name for s
+func hashString(s string) string {
+	h := sha1.Sum([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// cacheRemoteDir returns the directory the cached listings for the
+// remote f is on are stored in
+func cacheRemoteDir(f fs.Info) string {
+	return filepath.Join(config.CacheDir, "listings", hashString(f.Name()))
+}
+
+// cachePath returns the path of the file the listing of dir is
+// cached in along with the path it is a listing of
+func cachePath(f fs.Info, dir string) (cacheFile string, listingPath string) {
+	listingPath = path.Join(f.Root(), dir)
+	return filepath.Join(cacheRemoteDir(f), hashString(listingPath)+".json"), listingPath
+}
+
+// readCache returns the cached listing of dir if it exists and is
+// younger than --listing-cache-max-age.
+func readCache(ctx context.Context, f fs.Fs, dir string) (entries fs.DirEntries, ok bool) {
+	ci := fs.GetConfig(ctx)
+	cacheFile, listingPath := cachePath(f, dir)
+	data, err := ioutil.ReadFile(cacheFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fs.Debugf(dir, "Failed to read listing cache: %v", err)
+		}
+		return nil, false
+	}
+	var listing cacheListing
+	err = json.Unmarshal(data, &listing)
+	if err != nil {
+		fs.Debugf(dir, "Failed to decode listing cache: %v", err)
+		return nil, false
+	}
+	if listing.Path != listingPath {
+		return nil, false
+	}
+	if age := time.Since(listing.Time); age > ci.ListingCacheMaxAge {
+		fs.Debugf(dir, "Cached listing too old (%v)", age)
+		return nil, false
+	}
+	entries = make(fs.DirEntries, 0, len(listing.Entries))
+	for i := range listing.Entries {
+		entries = append(entries, listing.Entries[i].Decode(f))
+	}
+	return entries, true
+}
+
+// writeCache stores entries as the listing of dir.
+func writeCache(ctx context.Context, f fs.Fs, dir string, entries fs.DirEntries) (err error) {
+	cacheFile, listingPath := cachePath(f, dir)
+	listing := cacheListing{
+		Path:    listingPath,
+		Time:    time.Now(),
+		Entries: make([]EncodedEntry, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		e, err := EncodeEntry(ctx, f, entry)
+		if err != nil {
+			return err
+		}
+		listing.Entries = append(listing.Entries, e)
+	}
+	data, err := json.Marshal(&listing)
+	if err != nil {
+		return err
+	}
+	dirPath := filepath.Dir(cacheFile)
+	err = os.MkdirAll(dirPath, 0700)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it into place so other
+	// rclones never see a partial listing
+	tmp, err := ioutil.TempFile(dirPath, filepath.Base(cacheFile)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), cacheFile)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// listDir lists dir on f using the listing cache if
+// --use-listing-cache is set.
+func listDir(ctx context.Context, f fs.Fs, dir string) (entries fs.DirEntries, err error) {
+	ci := fs.GetConfig(ctx)
+	if !ci.UseListingCache {
+		return f.List(ctx, dir)
+	}
+	entries, ok := readCache(ctx, f, dir)
+	if ok {
+		fs.Debugf(dir, "Using cached listing")
+		return entries, nil
+	}
+	entries, err = f.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	err = writeCache(ctx, f, dir, entries)
+	if err != nil {
+		fs.Errorf(dir, "Failed to write listing cache: %v", err)
+	}
+	return entries, nil
+}
+
+// InvalidateCache removes the cached listings of the remote f is on.
+//
+// This should be called after changing f if --use-listing-cache is
+// set so the changes are seen by later listings.
+func InvalidateCache(ctx context.Context, f fs.Info) {
+	ci := fs.GetConfig(ctx)
+	if !ci.UseListingCache {
+		return
+	}
+	err := os.RemoveAll(cacheRemoteDir(f))
+	if err != nil {
+		fs.Errorf(f, "Failed to remove listing cache: %v", err)
+		return
+	}
+	fs.Debugf(f, "Removed listing cache")
+}
+
+// cachedObject is an fs.Object decoded from an EncodedEntry.
+//
+// The size, and the modification time and hashes if they were cached,
+// are returned from the cache. Anything else looks up the object on
+// the remote first.
+type cachedObject struct {
+	f       fs.Fs
+	remote  string
+	size    int64
+	modTime *time.Time
+	id      string
+	hashes  map[hash.Type]string
+
+	mu sync.Mutex
+	o  fs.Object // the object on the remote once looked up
+}
+
+// object returns the object on the remote, looking it up if needed
+func (o *cachedObject) object(ctx context.Context) (fs.Object, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.o == nil {
+		obj, err := o.f.NewObject(ctx, o.remote)
+		if err != nil {
+			return nil, err
+		}
+		o.o = obj
+	}
+	return o.o, nil
+}
+
+// Fs returns the Fs the object was listed from
+func (o *cachedObject) Fs() fs.Info {
+	return o.f
+}
+
+// String returns a description of the Object
+func (o *cachedObject) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *cachedObject) Remote() string {
+	return o.remote
+}
+
+// Size returns the size of the object in bytes
+func (o *cachedObject) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *cachedObject) ModTime(ctx context.Context) time.Time {
+	if o.modTime != nil {
+		return *o.modTime
+	}
+	obj, err := o.object(ctx)
+	if err != nil {
+		fs.Debugf(o, "Failed to read modification time: %v", err)
+		return time.Now()
+	}
+	return obj.ModTime(ctx)
+}
+
+// Hash returns the selected checksum of the object
+func (o *cachedObject) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	if sum, ok := o.hashes[ht]; ok {
+		return sum, nil
+	}
+	obj, err := o.object(ctx)
+	if err != nil {
+		return "", err
+	}
+	return obj.Hash(ctx, ht)
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *cachedObject) ID() string {
+	return o.id
+}
+
+// Storable says whether this object can be stored
+func (o *cachedObject) Storable() bool {
+	return true
+}
+
+// SetModTime sets the modification time of the object
+func (o *cachedObject) SetModTime(ctx context.Context, t time.Time) error {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return err
+	}
+	err = obj.SetModTime(ctx, t)
+	if err != nil {
+		return err
+	}
+	o.modTime = nil
+	return nil
+}
+
+// Open opens the object for reading
+func (o *cachedObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Open(ctx, options...)
+}
+
+// Update the object with the contents of in
+func (o *cachedObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return err
+	}
+	err = obj.Update(ctx, in, src, options...)
+	if err != nil {
+		return err
+	}
+	// The cached information is out of date now
+	o.size = obj.Size()
+	o.modTime = nil
+	o.hashes = nil
+	return nil
+}
+
+// Remove the object
+func (o *cachedObject) Remove(ctx context.Context) error {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return err
+	}
+	return obj.Remove(ctx)
+}
+
+// UnWrap returns the object on the remote, or nil if it can't be
+// found
+func (o *cachedObject) UnWrap() fs.Object {
+	obj, err := o.object(context.Background())
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Object          = (*cachedObject)(nil)
+	_ fs.IDer            = (*cachedObject)(nil)
+	_ fs.ObjectUnWrapper = (*cachedObject)(nil)
+)
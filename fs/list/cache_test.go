@@ -0,0 +1,115 @@
+package list
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTempCacheDir sets config.CacheDir to a temporary directory
+// returning a function to restore it
+func useTempCacheDir(t *testing.T) func() {
+	oldCacheDir := config.CacheDir
+	dir, err := ioutil.TempDir("", "rclone-listing-cache")
+	require.NoError(t, err)
+	config.CacheDir = dir
+	return func() {
+		config.CacheDir = oldCacheDir
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestListingCache(t *testing.T) {
+	defer useTempCacheDir(t)()
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.UseListingCache = true
+
+	f := mockfs.NewFs(ctx, "cachetest", "root")
+	f.SetHashes(hash.Set(hash.MD5))
+	f.AddObject(mockobject.New("a").WithContent([]byte("hello"), mockobject.SeekModeNone))
+
+	entries, err := DirSorted(ctx, f, true, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// The second listing comes from the cache so doesn't see b
+	f.AddObject(mockobject.New("b").WithContent([]byte("potato"), mockobject.SeekModeNone))
+	entries, err = DirSorted(ctx, f, true, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	o, ok := entries[0].(*cachedObject)
+	require.True(t, ok)
+	assert.Equal(t, "a", o.Remote())
+	assert.Equal(t, int64(5), o.Size())
+	sum, err := o.Hash(ctx, hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", sum)
+	assert.Nil(t, o.o, "expecting hash to come from the cache")
+
+	// Reading it looks up the real object
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "hello", string(data))
+
+	// Invalidating the cache lists again
+	InvalidateCache(ctx, f)
+	entries, err = DirSorted(ctx, f, true, "")
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// As does the cache being too old
+	f.AddObject(mockobject.New("c").WithContent([]byte("chips"), mockobject.SeekModeNone))
+	ci.ListingCacheMaxAge = 0
+	entries, err = DirSorted(ctx, f, true, "")
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	// Not using the cache always lists
+	ci.UseListingCache = false
+	f.AddObject(mockobject.New("d").WithContent([]byte("dip"), mockobject.SeekModeNone))
+	entries, err = DirSorted(ctx, f, true, "")
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+}
+
+func TestListingCacheDirs(t *testing.T) {
+	defer useTempCacheDir(t)()
+	ctx := context.Background()
+	f := mockfs.NewFs(ctx, "cachetest", "root")
+
+	_, ok := readCache(ctx, f, "dir")
+	assert.False(t, ok)
+
+	in := fs.DirEntries{
+		fs.NewDir("dir/sub", time.Now()).SetSize(10).SetItems(2).SetID("id"),
+	}
+	require.NoError(t, writeCache(ctx, f, "dir", in))
+	entries, ok := readCache(ctx, f, "dir")
+	require.True(t, ok)
+	require.Len(t, entries, 1)
+	d, ok := entries[0].(fs.Directory)
+	require.True(t, ok)
+	assert.Equal(t, "dir/sub", d.Remote())
+	assert.Equal(t, int64(10), d.Size())
+	assert.Equal(t, int64(2), d.Items())
+	assert.Equal(t, "id", d.ID())
+
+	// A different root doesn't find it
+	f2 := mockfs.NewFs(ctx, "cachetest", "root2")
+	_, ok = readCache(ctx, f2, "dir")
+	assert.False(t, ok)
+}
@@ -21,19 +21,118 @@ import (
 // Files will be returned in sorted order
 func DirSorted(ctx context.Context, f fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err error) {
 	// Get unfiltered entries from the fs
-	entries, err = f.List(ctx, dir)
+	entries, err = listDir(ctx, f, dir)
 	if err != nil {
 		return nil, err
 	}
-	// This should happen only if exclude files lives in the
-	// starting directory, otherwise ListDirSorted should not be
-	// called.
 	fi := filter.GetConfig(ctx)
-	if !includeAll && fi.ListContainsExcludeFile(entries) {
-		fs.Debugf(dir, "Excluded")
-		return nil, nil
+	if !includeAll {
+		include, err := includeDirListing(ctx, fi, f, dir, entries)
+		if err != nil {
+			return nil, err
+		}
+		if !include {
+			return nil, nil
+		}
+	}
+	includeObject, includeDirectory := fi.IncludeObject, fi.IncludeDirectory(ctx, f)
+	if !includeAll {
+		// When syncing the ignore files of the source apply to the
+		// destination too so it keeps the files the source ignores
+		ignoreFs, ignoreEntries := f, entries
+		if src := fi.ListingSource(); src != nil && src != f {
+			ignoreFs, ignoreEntries = src, nil
+		}
+		ignored, err := fi.Ignorer(ctx, ignoreFs, dir, ignoreEntries)
+		if err != nil {
+			return nil, err
+		}
+		if ignored != nil {
+			includeObject = func(ctx context.Context, o fs.Object) bool {
+				return !ignored(o.Remote(), false) && fi.IncludeObject(ctx, o)
+			}
+			includeDirectory = func(remote string) (bool, error) {
+				if ignored(remote, true) {
+					return false, nil
+				}
+				return fi.IncludeDirectory(ctx, f)(remote)
+			}
+		}
+	}
+	return filterAndSortDir(ctx, entries, includeAll, dir, includeObject, includeDirectory)
+}
+
+// includeDirListing returns whether dir of f, which has the listing
+// entries, passes the filters which need the whole listing of a
+// directory.
+//
+// If the filter has a listing source then its listing of dir decides
+// for every Fs, so a directory excluded from the source of a sync is
+// excluded from the destination too and its files there aren't
+// deleted. A directory which isn't in the source is decided from its
+// own listing.
+func includeDirListing(ctx context.Context, fi *filter.Filter, f fs.Fs, dir string, entries fs.DirEntries) (bool, error) {
+	return fi.DecideDirListing(dir, func() (bool, error) {
+		if src := fi.ListingSource(); src != nil && src != f {
+			srcEntries, err := listDir(ctx, src, dir)
+			if err == nil {
+				entries = srcEntries
+			} else if err != fs.ErrorDirNotFound {
+				return false, err
+			}
+		}
+		// The exclude file is usually found when the parent
+		// directory is listed, so this should happen only for the
+		// starting directory or when the exclude file is only in
+		// the source.
+		if fi.ListContainsExcludeFile(entries) {
+			fs.Debugf(dir, "Excluded")
+			return false, nil
+		}
+		if dir != "" && !fi.IncludeDirListing(ctx, entries) {
+			fs.Debugf(dir, "Excluded by directory size or age")
+			return false, nil
+		}
+		return true, nil
+	})
+}
+
+// DirPaged reads Object and *Dir for the given Fs calling callback
+// with each page of entries as it is read.
+//
+// dir is the start directory, "" for root
+//
+// If includeAll is specified all files will be added, otherwise only
+// files and directories passing the filter will be added.
+//
+// The entries in each page are sorted, but the pages may not be in
+// order.
+//
+// If the Fs doesn't support ListP, filters such as
+// --exclude-if-present are in use which need the whole directory, or
+// --use-listing-cache is set, then callback will be called once with
+// all the entries.
+func DirPaged(ctx context.Context, f fs.Fs, includeAll bool, dir string, callback fs.ListRCallback) (err error) {
+	fi := filter.GetConfig(ctx)
+	doListP := f.Features().ListP
+	if doListP == nil || (!includeAll && fi.UsesDirListings()) || fs.GetConfig(ctx).UseListingCache {
+		entries, err := DirSorted(ctx, f, includeAll, dir)
+		if err != nil {
+			return err
+		}
+		return callback(entries)
 	}
-	return filterAndSortDir(ctx, entries, includeAll, dir, fi.IncludeObject, fi.IncludeDirectory(ctx, f))
+	includeDirectory := fi.IncludeDirectory(ctx, f)
+	return doListP(ctx, dir, func(entries fs.DirEntries) error {
+		entries, err := filterAndSortDir(ctx, entries, includeAll, dir, fi.IncludeObject, includeDirectory)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		return callback(entries)
+	})
 }
 
 // filter (if required) and check the entries, then sort them
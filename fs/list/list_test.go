@@ -5,8 +5,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fstest/mockdir"
+	"github.com/rclone/rclone/fstest/mockfs"
 	"github.com/rclone/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -103,3 +106,79 @@ func TestFilterAndSortUnknown(t *testing.T) {
 	assert.Error(t, err, "error")
 	assert.Nil(t, newEntries)
 }
+
+// pagedFs is a mockfs.Fs which returns its listings in pages
+type pagedFs struct {
+	*mockfs.Fs
+	pages []fs.DirEntries
+}
+
+func (f *pagedFs) Features() *fs.Features {
+	features := *f.Fs.Features()
+	features.ListP = func(ctx context.Context, dir string, callback fs.ListRCallback) error {
+		for _, page := range f.pages {
+			// the callback may filter the page in place
+			page = append(fs.DirEntries(nil), page...)
+			if err := callback(page); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return &features
+}
+
+func TestDirPaged(t *testing.T) {
+	ctx := context.Background()
+	oA := mockobject.Object("A")
+	oB := mockobject.Object("B")
+	oC := mockobject.Object("C")
+	da := mockdir.New("a")
+
+	// Without ListP there is a single page from List
+	f := mockfs.NewFs(ctx, "mock", "/")
+	f.AddObject(oB)
+	f.AddObject(oA)
+	var pages []fs.DirEntries
+	callback := func(entries fs.DirEntries) error {
+		pages = append(pages, entries)
+		return nil
+	}
+	require.NoError(t, DirPaged(ctx, f, true, "", callback))
+	assert.Equal(t, []fs.DirEntries{{oA, oB}}, pages)
+
+	// With ListP each page is filtered and sorted
+	pf := &pagedFs{
+		Fs:    mockfs.NewFs(ctx, "mock", "/"),
+		pages: []fs.DirEntries{{oC, oA}, {da}, {oB}},
+	}
+	pages = nil
+	require.NoError(t, DirPaged(ctx, pf, true, "", callback))
+	assert.Equal(t, []fs.DirEntries{{oA, oC}, {da}, {oB}}, pages)
+
+	// Pages which are filtered out completely are skipped
+	fi, err := filter.NewFilter(nil)
+	require.NoError(t, err)
+	require.NoError(t, fi.AddRule("- a/**"))
+	filterCtx := filter.ReplaceConfig(ctx, fi)
+	pages = nil
+	require.NoError(t, DirPaged(filterCtx, pf, false, "", callback))
+	assert.Equal(t, []fs.DirEntries{{oA, oC}, {oB}}, pages)
+
+	// --exclude-if-present needs the whole directory so uses List
+	pf.Fs.AddObject(oC)
+	fi.Opt.ExcludeFile = []string{"C"}
+	pages = nil
+	require.NoError(t, DirPaged(filterCtx, pf, false, "", callback))
+	assert.Equal(t, []fs.DirEntries{nil}, pages)
+
+	// Errors from the callback stop the listing
+	pages = nil
+	stop := errors.New("stop")
+	err = DirPaged(ctx, pf, true, "", func(entries fs.DirEntries) error {
+		pages = append(pages, entries)
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, []fs.DirEntries{{oA, oC}}, pages)
+}
@@ -125,6 +125,23 @@ func (j LogValueItem) String() string {
 	return fmt.Sprint(j.value)
 }
 
+// addObjectFields adds the name of the remote and the path of o to
+// fields if o is an Fs or a directory entry
+func addObjectFields(fields logrus.Fields, o interface{}) {
+	switch x := o.(type) {
+	case Info:
+		fields["remote"] = x.Name()
+		fields["path"] = x.Root()
+	case ObjectInfo:
+		if f := x.Fs(); f != nil {
+			fields["remote"] = f.Name()
+		}
+		fields["path"] = x.Remote()
+	case DirEntry:
+		fields["path"] = x.Remote()
+	}
+}
+
 // LogPrintf produces a log string from the arguments passed in
 func LogPrintf(level LogLevel, o interface{}, text string, args ...interface{}) {
 	out := fmt.Sprintf(text, args...)
@@ -136,6 +153,7 @@ func LogPrintf(level LogLevel, o interface{}, text string, args ...interface{})
 				"object":     fmt.Sprintf("%+v", o),
 				"objectType": fmt.Sprintf("%T", o),
 			}
+			addObjectFields(fields, o)
 		}
 		for _, arg := range args {
 			if item, ok := arg.(LogValueItem); ok {
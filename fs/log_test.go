@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -29,6 +32,30 @@ func TestLogValue(t *testing.T) {
 	assert.Equal(t, "", x.String())
 }
 
+// logInfo is a minimal Info for TestAddObjectFields
+type logInfo struct{}
+
+func (logInfo) Name() string             { return "remote" }
+func (logInfo) Root() string             { return "root" }
+func (logInfo) String() string           { return "remote:root" }
+func (logInfo) Precision() time.Duration { return time.Second }
+func (logInfo) Hashes() hash.Set         { return hash.Set(hash.None) }
+func (logInfo) Features() *Features      { return &Features{} }
+
+func TestAddObjectFields(t *testing.T) {
+	fields := logrus.Fields{}
+	addObjectFields(fields, logInfo{})
+	assert.Equal(t, logrus.Fields{"remote": "remote", "path": "root"}, fields)
+
+	fields = logrus.Fields{}
+	addObjectFields(fields, NewDir("dir/sub", time.Now()))
+	assert.Equal(t, logrus.Fields{"path": "dir/sub"}, fields)
+
+	fields = logrus.Fields{}
+	addObjectFields(fields, "string")
+	assert.Equal(t, logrus.Fields{}, fields)
+}
+
 func TestLogLevelString(t *testing.T) {
 	for _, test := range []struct {
 		in   LogLevel
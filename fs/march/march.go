@@ -32,10 +32,13 @@ type March struct {
 	Callback               Marcher         // object to call with results
 	NoCheckDest            bool            // transfer all objects regardless without checking dst
 	NoUnicodeNormalization bool            // don't normalize unicode characters in filenames
+	StreamFilesFrom        bool            // stream --files-from with NoTraverse without calling Callback for directories
 	// internal state
-	srcListDir listDirFn // function to call to list a directory in the src
-	dstListDir listDirFn // function to call to list a directory in the dst
-	transforms []matchTransformFn
+	srcListDir   listDirFn      // function to call to list a directory in the src
+	dstListDir   listDirFn      // function to call to list a directory in the dst
+	srcListPaged listDirPagedFn // function to call to list a directory in the src in pages
+	dstListPaged listDirPagedFn // function to call to list a directory in the dst in pages
+	transforms   []matchTransformFn
 }
 
 // Marcher is called on each match
@@ -51,9 +54,16 @@ type Marcher interface {
 // init sets up a march over opt.Fsrc, and opt.Fdst calling back callback for each match
 func (m *March) init(ctx context.Context) {
 	ci := fs.GetConfig(ctx)
+	// Make the source decide the filters which need the whole
+	// directory listing so the destination excludes the same files
+	if !m.SrcIncludeAll {
+		m.Ctx = filter.SetListingSource(m.Ctx, m.Fsrc)
+	}
 	m.srcListDir = m.makeListDir(ctx, m.Fsrc, m.SrcIncludeAll)
+	m.srcListPaged = m.makeListDirPaged(ctx, m.Fsrc, m.SrcIncludeAll, m.srcListDir)
 	if !m.NoTraverse {
 		m.dstListDir = m.makeListDir(ctx, m.Fdst, m.DstIncludeAll)
+		m.dstListPaged = m.makeListDirPaged(ctx, m.Fdst, m.DstIncludeAll, m.dstListDir)
 	}
 	// Now create the matching transform
 	// ..normalise the UTF8 first
@@ -74,13 +84,43 @@ func (m *March) init(ctx context.Context) {
 // list a directory into entries, err
 type listDirFn func(dir string) (entries fs.DirEntries, err error)
 
+// list a directory calling callback with pages of entries
+type listDirPagedFn func(dir string, callback fs.ListRCallback) error
+
+// usesDirTree returns true if the listings of f should be read from a
+// directory tree of the whole of f rather than a directory at a time
+func usesDirTree(ctx context.Context, f fs.Fs) bool {
+	ci := fs.GetConfig(ctx)
+	fi := filter.GetConfig(ctx)
+	return (ci.UseListR && f.Features().ListR != nil) || // --fast-list active or
+		(ci.NoTraverse && fi.HaveFilesFrom()) // --files-from and --no-traverse
+}
+
+// makeListDirPaged constructs a paged listing function for the given
+// fs and includeAll flags for marching through the file system.
+//
+// If the listings come from a directory tree then listDir is used to
+// read them in one page.
+func (m *March) makeListDirPaged(ctx context.Context, f fs.Fs, includeAll bool, listDir listDirFn) listDirPagedFn {
+	if !usesDirTree(ctx, f) {
+		return func(dir string, callback fs.ListRCallback) error {
+			return list.DirPaged(m.Ctx, f, includeAll, dir, callback)
+		}
+	}
+	return func(dir string, callback fs.ListRCallback) error {
+		entries, err := listDir(dir)
+		if err != nil {
+			return err
+		}
+		return callback(entries)
+	}
+}
+
 // makeListDir makes constructs a listing function for the given fs
 // and includeAll flags for marching through the file system.
 func (m *March) makeListDir(ctx context.Context, f fs.Fs, includeAll bool) listDirFn {
 	ci := fs.GetConfig(ctx)
-	fi := filter.GetConfig(ctx)
-	if !(ci.UseListR && f.Features().ListR != nil) && // !--fast-list active and
-		!(ci.NoTraverse && fi.HaveFilesFrom()) { // !(--files-from and --no-traverse)
+	if !usesDirTree(ctx, f) {
 		return func(dir string) (entries fs.DirEntries, err error) {
 			return list.DirSorted(m.Ctx, f, includeAll, dir)
 		}
@@ -130,6 +170,10 @@ func (m *March) Run(ctx context.Context) error {
 	fi := filter.GetConfig(ctx)
 	m.init(ctx)
 
+	if m.StreamFilesFrom && m.NoTraverse && fi.HaveFilesFrom() {
+		return m.runFilesFrom(ctx)
+	}
+
 	srcDepth := ci.MaxDepth
 	if srcDepth < 0 {
 		srcDepth = fs.MaxLevel
@@ -146,9 +190,9 @@ func (m *March) Run(ctx context.Context) error {
 	// Start some directory listing go routines
 	var wg sync.WaitGroup         // sync closing of go routines
 	var traversing sync.WaitGroup // running directory traversals
-	checkers := ci.Checkers
-	in := make(chan listDirJob, checkers)
-	for i := 0; i < checkers; i++ {
+	listers := ci.Listers()
+	in := make(chan listDirJob, listers)
+	for i := 0; i < listers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -217,6 +261,52 @@ func (m *March) Run(ctx context.Context) error {
 	return jobError
 }
 
+// runFilesFrom calls the Callback for each file in --files-from found
+// in the source.
+//
+// The files are looked up directly with NewObject, --checkers at a
+// time, rather than being built into a directory tree first. The
+// names themselves aren't streamed as the filter has already read the
+// whole list into memory, so only the memory of the tree is saved.
+func (m *March) runFilesFrom(ctx context.Context) error {
+	ci := fs.GetConfig(ctx)
+	fi := filter.GetConfig(ctx)
+	prefix := ""
+	if m.Dir != "" {
+		prefix = m.Dir + "/"
+	}
+	listR := fi.MakeListR(m.Ctx, m.Fsrc.NewObject)
+	return listR(m.Ctx, m.Dir, func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			if m.aborting() {
+				return m.Ctx.Err()
+			}
+			src, ok := entry.(fs.Object)
+			if !ok {
+				continue
+			}
+			remote := src.Remote()
+			if !strings.HasPrefix(remote, prefix) {
+				continue
+			}
+			if ci.MaxDepth >= 0 && strings.Count(remote[len(prefix):], "/") >= ci.MaxDepth {
+				continue
+			}
+			if m.NoCheckDest {
+				m.Callback.SrcOnly(src)
+				continue
+			}
+			dst, err := m.Fdst.NewObject(m.Ctx, remote)
+			if err == nil {
+				m.Callback.Match(m.Ctx, dst, src)
+			} else {
+				m.Callback.SrcOnly(src)
+			}
+		}
+		return nil
+	})
+}
+
 // Check to see if the context has been cancelled
 func (m *March) aborting() bool {
 	select {
@@ -247,14 +337,18 @@ func (es matchEntries) Swap(i, j int) { es[i], es[j] = es[j], es[i] }
 //
 // Compare in order (name, leaf, remote)
 func (es matchEntries) Less(i, j int) bool {
-	ei, ej := &es[i], &es[j]
-	if ei.name == ej.name {
-		if ei.leaf == ej.leaf {
-			return fs.CompareDirEntries(ei.entry, ej.entry) < 0
+	return es[i].less(&es[j])
+}
+
+// less compares e and o in order (name, leaf, remote)
+func (e *matchEntry) less(o *matchEntry) bool {
+	if e.name == o.name {
+		if e.leaf == o.leaf {
+			return fs.CompareDirEntries(e.entry, o.entry) < 0
 		}
-		return ei.leaf < ej.leaf
+		return e.leaf < o.leaf
 	}
-	return ei.name < ej.name
+	return e.name < o.name
 }
 
 // Sort the directory entries by (name, leaf, remote)
@@ -272,13 +366,7 @@ func (es matchEntries) sort() {
 func newMatchEntries(entries fs.DirEntries, transforms []matchTransformFn) matchEntries {
 	es := make(matchEntries, len(entries))
 	for i := range es {
-		es[i].entry = entries[i]
-		name := path.Base(entries[i].Remote())
-		es[i].leaf = name
-		for _, transform := range transforms {
-			name = transform(name)
-		}
-		es[i].name = name
+		es[i] = newMatchEntry(entries[i], transforms)
 	}
 	es.sort()
 	return es
@@ -304,39 +392,72 @@ type matchTransformFn func(name string) string
 func matchListings(srcListEntries, dstListEntries fs.DirEntries, transforms []matchTransformFn) (srcOnly fs.DirEntries, dstOnly fs.DirEntries, matches []matchPair) {
 	srcList := newMatchEntries(srcListEntries, transforms)
 	dstList := newMatchEntries(dstListEntries, transforms)
+	_ = matchSorted(&sliceIter{es: srcList}, &sliceIter{es: dstList}, func(src, dst fs.DirEntry) error {
+		switch {
+		case src == nil:
+			dstOnly = append(dstOnly, dst)
+		case dst == nil:
+			srcOnly = append(srcOnly, src)
+		default:
+			matches = append(matches, matchPair{src: src, dst: dst})
+		}
+		return nil
+	})
+	return
+}
 
-	for iSrc, iDst := 0, 0; ; iSrc, iDst = iSrc+1, iDst+1 {
+// Match up the items returned by the sorted iterators srcList and
+// dstList calling fn for each src and dst with the same name, or with
+// dst nil for src only entries, or src nil for dst only entries.
+//
+// This checks for duplicates and checks the lists are sorted.
+func matchSorted(srcList, dstList matchIter, fn func(src, dst fs.DirEntry) error) error {
+	var srcPrev, dstPrev *matchEntry
+	srcCur, err := srcList.next()
+	if err != nil {
+		return err
+	}
+	dstCur, err := dstList.next()
+	if err != nil {
+		return err
+	}
+	for srcCur != nil || dstCur != nil {
 		var src, dst fs.DirEntry
 		var srcName, dstName string
-		if iSrc < len(srcList) {
-			src = srcList[iSrc].entry
-			srcName = srcList[iSrc].name
+		if srcCur != nil {
+			src = srcCur.entry
+			srcName = srcCur.name
 		}
-		if iDst < len(dstList) {
-			dst = dstList[iDst].entry
-			dstName = dstList[iDst].name
+		if dstCur != nil {
+			dst = dstCur.entry
+			dstName = dstCur.name
 		}
-		if src == nil && dst == nil {
-			break
-		}
-		if src != nil && iSrc > 0 {
-			prev := srcList[iSrc-1].entry
-			prevName := srcList[iSrc-1].name
+		if src != nil && srcPrev != nil {
+			prev := srcPrev.entry
+			prevName := srcPrev.name
 			if srcName == prevName && fs.DirEntryType(prev) == fs.DirEntryType(src) {
 				fs.Logf(src, "Duplicate %s found in source - ignoring", fs.DirEntryType(src))
-				iDst-- // ignore the src and retry the dst
+				// ignore the src and retry the dst
+				srcPrev = srcCur
+				if srcCur, err = srcList.next(); err != nil {
+					return err
+				}
 				continue
 			} else if srcName < prevName {
 				// this should never happen since we sort the listings
 				panic("Out of order listing in source")
 			}
 		}
-		if dst != nil && iDst > 0 {
-			prev := dstList[iDst-1].entry
-			prevName := dstList[iDst-1].name
+		if dst != nil && dstPrev != nil {
+			prev := dstPrev.entry
+			prevName := dstPrev.name
 			if dstName == prevName && fs.DirEntryType(dst) == fs.DirEntryType(prev) {
 				fs.Logf(dst, "Duplicate %s found in destination - ignoring", fs.DirEntryType(dst))
-				iSrc-- // ignore the dst and retry the src
+				// ignore the dst and retry the src
+				dstPrev = dstCur
+				if dstCur, err = dstList.next(); err != nil {
+					return err
+				}
 				continue
 			} else if dstName < prevName {
 				// this should never happen since we sort the listings
@@ -350,25 +471,29 @@ func matchListings(srcListEntries, dstListEntries fs.DirEntries, transforms []ma
 			dstType := fs.DirEntryType(dst)
 			if srcName > dstName || (srcName == dstName && srcType > dstType) {
 				src = nil
-				iSrc--
 			} else if srcName < dstName || (srcName == dstName && srcType < dstType) {
 				dst = nil
-				iDst--
 			}
 		}
 		// Debugf(nil, "src = %v, dst = %v", src, dst)
-		switch {
-		case src == nil && dst == nil:
-			// do nothing
-		case src == nil:
-			dstOnly = append(dstOnly, dst)
-		case dst == nil:
-			srcOnly = append(srcOnly, src)
-		default:
-			matches = append(matches, matchPair{src: src, dst: dst})
+		err = fn(src, dst)
+		if err != nil {
+			return err
+		}
+		if src != nil {
+			srcPrev = srcCur
+			if srcCur, err = srcList.next(); err != nil {
+				return err
+			}
+		}
+		if dst != nil {
+			dstPrev = dstCur
+			if dstCur, err = dstList.next(); err != nil {
+				return err
+			}
 		}
 	}
-	return
+	return nil
 }
 
 // processJob processes a listDirJob listing the source and
@@ -377,6 +502,10 @@ func matchListings(srcListEntries, dstListEntries fs.DirEntries, transforms []ma
 //
 // returns errors using processError
 func (m *March) processJob(job listDirJob) ([]listDirJob, error) {
+	ci := fs.GetConfig(m.Ctx)
+	if ci.ListCutoff > 0 && !m.NoTraverse {
+		return m.processJobSorted(job, ci.ListCutoff)
+	}
 	var (
 		jobs                   []listDirJob
 		srcList, dstList       fs.DirEntries
@@ -403,30 +532,12 @@ func (m *March) processJob(job listDirJob) ([]listDirJob, error) {
 
 	// Wait for listings to complete and report errors
 	wg.Wait()
-	if srcListErr != nil {
-		if job.srcRemote != "" {
-			fs.Errorf(job.srcRemote, "error reading source directory: %v", srcListErr)
-		} else {
-			fs.Errorf(m.Fsrc, "error reading source root directory: %v", srcListErr)
-		}
-		srcListErr = fs.CountError(srcListErr)
-		return nil, srcListErr
-	}
-	if dstListErr == fs.ErrorDirNotFound {
-		// Copy the stuff anyway
-	} else if dstListErr != nil {
-		if job.dstRemote != "" {
-			fs.Errorf(job.dstRemote, "error reading destination directory: %v", dstListErr)
-		} else {
-			fs.Errorf(m.Fdst, "error reading destination root directory: %v", dstListErr)
-		}
-		dstListErr = fs.CountError(dstListErr)
-		return nil, dstListErr
+	if err := m.listError(job, srcListErr, dstListErr); err != nil {
+		return nil, err
 	}
 
 	// If NoTraverse is set, then try to find a matching object
 	// for each item in the srcList to head dst object
-	ci := fs.GetConfig(m.Ctx)
 	limiter := make(chan struct{}, ci.Checkers)
 	if m.NoTraverse && !m.NoCheckDest {
 		for _, src := range srcList {
@@ -455,6 +566,113 @@ func (m *March) processJob(job listDirJob) ([]listDirJob, error) {
 		if m.aborting() {
 			return nil, m.Ctx.Err()
 		}
+		jobs = m.callback(job, jobs, src, nil)
+	}
+	for _, dst := range dstOnly {
+		if m.aborting() {
+			return nil, m.Ctx.Err()
+		}
+		jobs = m.callback(job, jobs, nil, dst)
+	}
+	for _, match := range matches {
+		if m.aborting() {
+			return nil, m.Ctx.Err()
+		}
+		jobs = m.callback(job, jobs, match.src, match.dst)
+	}
+	return jobs, nil
+}
+
+// processJobSorted processes a listDirJob like processJob, but sorts
+// the listings with a spillSorter so no more than cutoff entries of
+// each are kept in memory, then matches them up as they are read back.
+func (m *March) processJobSorted(job listDirJob, cutoff int) (jobs []listDirJob, err error) {
+	var (
+		srcListErr, dstListErr error
+		wg                     sync.WaitGroup
+	)
+	srcSorter := newSpillSorter(m.Ctx, m.Fsrc, m.transforms, cutoff)
+	defer srcSorter.close()
+	dstSorter := newSpillSorter(m.Ctx, m.Fdst, m.transforms, cutoff)
+	defer dstSorter.close()
+
+	// List the src and dst directories
+	if !job.noSrc {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			srcListErr = m.srcListPaged(job.srcRemote, srcSorter.add)
+		}()
+	}
+	if !job.noDst {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dstListErr = m.dstListPaged(job.dstRemote, dstSorter.add)
+		}()
+	}
+
+	// Wait for listings to complete and report errors
+	wg.Wait()
+	if err := m.listError(job, srcListErr, dstListErr); err != nil {
+		return nil, err
+	}
+
+	// Work out what to do and do it
+	srcList, err := srcSorter.iter()
+	if err == nil {
+		var dstList matchIter
+		dstList, err = dstSorter.iter()
+		if err == nil {
+			err = matchSorted(srcList, dstList, func(src, dst fs.DirEntry) error {
+				if m.aborting() {
+					return m.Ctx.Err()
+				}
+				jobs = m.callback(job, jobs, src, dst)
+				return nil
+			})
+		}
+	}
+	if err != nil {
+		if err != m.Ctx.Err() {
+			fs.Errorf(m.Fsrc, "error matching listings of %q: %v", job.srcRemote, err)
+			err = fs.CountError(err)
+		}
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// listError reports the errors from listing the source and
+// destination directories of job returning the error to return from
+// processing it.
+func (m *March) listError(job listDirJob, srcListErr, dstListErr error) error {
+	if srcListErr != nil {
+		if job.srcRemote != "" {
+			fs.Errorf(job.srcRemote, "error reading source directory: %v", srcListErr)
+		} else {
+			fs.Errorf(m.Fsrc, "error reading source root directory: %v", srcListErr)
+		}
+		return fs.CountError(srcListErr)
+	}
+	if dstListErr == fs.ErrorDirNotFound {
+		// Copy the stuff anyway
+	} else if dstListErr != nil {
+		if job.dstRemote != "" {
+			fs.Errorf(job.dstRemote, "error reading destination directory: %v", dstListErr)
+		} else {
+			fs.Errorf(m.Fdst, "error reading destination root directory: %v", dstListErr)
+		}
+		return fs.CountError(dstListErr)
+	}
+	return nil
+}
+
+// callback calls the Callback for src and dst, one of which may be
+// nil, appending a job to jobs if the directory needs recursing into.
+func (m *March) callback(job listDirJob, jobs []listDirJob, src, dst fs.DirEntry) []listDirJob {
+	switch {
+	case dst == nil:
 		recurse := m.Callback.SrcOnly(src)
 		if recurse && job.srcDepth > 0 {
 			jobs = append(jobs, listDirJob{
@@ -464,12 +682,7 @@ func (m *March) processJob(job listDirJob) ([]listDirJob, error) {
 				noDst:     true,
 			})
 		}
-
-	}
-	for _, dst := range dstOnly {
-		if m.aborting() {
-			return nil, m.Ctx.Err()
-		}
+	case src == nil:
 		recurse := m.Callback.DstOnly(dst)
 		if recurse && job.dstDepth > 0 {
 			jobs = append(jobs, listDirJob{
@@ -479,20 +692,16 @@ func (m *March) processJob(job listDirJob) ([]listDirJob, error) {
 				noSrc:     true,
 			})
 		}
-	}
-	for _, match := range matches {
-		if m.aborting() {
-			return nil, m.Ctx.Err()
-		}
-		recurse := m.Callback.Match(m.Ctx, match.dst, match.src)
+	default:
+		recurse := m.Callback.Match(m.Ctx, dst, src)
 		if recurse && job.srcDepth > 0 && job.dstDepth > 0 {
 			jobs = append(jobs, listDirJob{
-				srcRemote: match.src.Remote(),
-				dstRemote: match.dst.Remote(),
+				srcRemote: src.Remote(),
+				dstRemote: dst.Remote(),
 				srcDepth:  job.srcDepth - 1,
 				dstDepth:  job.dstDepth - 1,
 			})
 		}
 	}
-	return jobs, nil
+	return jobs
 }
@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 	"testing"
@@ -16,6 +17,7 @@ import (
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/fstest/mockdir"
+	"github.com/rclone/rclone/fstest/mockfs"
 	"github.com/rclone/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -284,6 +286,55 @@ func TestMarchNoTraverse(t *testing.T) {
 	}
 }
 
+func TestMarchStreamFilesFrom(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, ci := fs.AddConfig(ctx)
+	ci.NoTraverse = true
+
+	srcOnly := []fstest.Item{
+		r.WriteFile("srcOnly", "hello world", t1),
+		r.WriteFile("srcOnlyDir/sub", "hello world", t1),
+	}
+	match := []fstest.Item{
+		r.WriteBoth(ctx, "match", "hello world", t1),
+		r.WriteBoth(ctx, "matchDir/match file", "hello world", t1),
+	}
+	r.WriteFile("notListed", "hello world", t1)
+
+	fi, err := filter.NewFilter(nil)
+	require.NoError(t, err)
+	for _, remote := range []string{"srcOnly", "srcOnlyDir/sub", "match", "matchDir/match file", "notFound"} {
+		require.NoError(t, fi.AddFile(remote))
+	}
+	ctx = filter.ReplaceConfig(ctx, fi)
+
+	mt := &marchTester{
+		ctx:        ctx,
+		cancel:     cancel,
+		noTraverse: true,
+	}
+	m := &March{
+		Ctx:             ctx,
+		Fdst:            r.Fremote,
+		Fsrc:            r.Flocal,
+		Dir:             "",
+		NoTraverse:      true,
+		Callback:        mt,
+		StreamFilesFrom: true,
+	}
+	mt.processError(m.Run(ctx))
+	mt.cancel()
+	require.NoError(t, mt.currentError())
+
+	// No directories are reported when streaming
+	precision := fs.GetModifyWindow(ctx, r.Fremote, r.Flocal)
+	fstest.CompareItems(t, mt.srcOnly, srcOnly, nil, precision, "srcOnly")
+	fstest.CompareItems(t, mt.match, match, nil, precision, "match")
+	assert.Len(t, mt.dstOnly, 0)
+}
+
 func TestNewMatchEntries(t *testing.T) {
 	var (
 		a = mockobject.Object("path/a")
@@ -531,3 +582,90 @@ func TestMatchListings(t *testing.T) {
 		})
 	}
 }
+
+func TestSpillSorter(t *testing.T) {
+	ctx := context.Background()
+	f := mockfs.NewFs(ctx, "spilltest", "root")
+	var entries fs.DirEntries
+	for _, remote := range []string{"g", "F", "e", "D", "c", "B", "a", "f"} {
+		entries = append(entries, mockobject.Object(remote))
+	}
+	entries = append(entries, fs.NewDir("b", t1))
+
+	for _, cutoff := range []int{1, 3, 100} {
+		t.Run(fmt.Sprintf("cutoff=%d", cutoff), func(t *testing.T) {
+			s := newSpillSorter(ctx, f, []matchTransformFn{strings.ToLower}, cutoff)
+			// add the entries in pages of 2
+			for i := 0; i < len(entries); i += 2 {
+				end := i + 2
+				if end > len(entries) {
+					end = len(entries)
+				}
+				require.NoError(t, s.add(entries[i:end]))
+			}
+			runs := append([]string(nil), s.runs...)
+			if cutoff < len(entries) {
+				assert.NotEmpty(t, runs)
+			} else {
+				assert.Empty(t, runs)
+			}
+
+			it, err := s.iter()
+			require.NoError(t, err)
+			var got []string
+			for {
+				e, err := it.next()
+				require.NoError(t, err)
+				if e == nil {
+					break
+				}
+				got = append(got, fs.DirEntryType(e.entry)+":"+e.entry.Remote())
+			}
+			assert.Equal(t, []string{
+				"object:a", "object:B", "directory:b", "object:c", "object:D",
+				"object:e", "object:F", "object:f", "object:g",
+			}, got)
+
+			s.close()
+			for _, name := range runs {
+				_, err := os.Stat(name)
+				assert.True(t, os.IsNotExist(err), name)
+			}
+		})
+	}
+}
+
+func TestMarchListCutoff(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, ci := fs.AddConfig(ctx)
+	ci.ListCutoff = 2
+
+	var srcOnly, dstOnly, match []fstest.Item
+	for i := 0; i < 5; i++ {
+		srcOnly = append(srcOnly, r.WriteFile(fmt.Sprintf("dir/srcOnly%d", i), "hello world", t1))
+		dstOnly = append(dstOnly, r.WriteObject(ctx, fmt.Sprintf("dir/dstOnly%d", i), "hello world", t1))
+		match = append(match, r.WriteBoth(ctx, fmt.Sprintf("dir/match%d", i), "hello world", t1))
+	}
+
+	mt := &marchTester{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	m := &March{
+		Ctx:      ctx,
+		Fdst:     r.Fremote,
+		Fsrc:     r.Flocal,
+		Dir:      "",
+		Callback: mt,
+	}
+	mt.processError(m.Run(ctx))
+	mt.cancel()
+	require.NoError(t, mt.currentError())
+
+	precision := fs.GetModifyWindow(ctx, r.Fremote, r.Flocal)
+	fstest.CompareItems(t, mt.srcOnly, srcOnly, nil, precision, "srcOnly")
+	fstest.CompareItems(t, mt.dstOnly, dstOnly, nil, precision, "dstOnly")
+	fstest.CompareItems(t, mt.match, match, []string{"dir"}, precision, "match")
+}
@@ -0,0 +1,287 @@
+// Sorting of directory listings too big to fit in memory
+
+package march
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/list"
+)
+
+// matchIter returns matchEntry~s in sorted order
+type matchIter interface {
+	// next returns the next entry or nil at the end
+	next() (*matchEntry, error)
+}
+
+// sliceIter is a matchIter over an already sorted matchEntries
+type sliceIter struct {
+	es matchEntries
+	i  int
+}
+
+// next returns the next entry or nil at the end
+func (it *sliceIter) next() (*matchEntry, error) {
+	if it.i >= len(it.es) {
+		return nil, nil
+	}
+	e := &it.es[it.i]
+	it.i++
+	return e, nil
+}
+
+// spillSorter sorts a directory listing into (name, leaf, remote)
+// order like matchEntries.sort but without keeping more than cutoff
+// entries in memory.
+//
+// Entries are added a page at a time. Each time cutoff entries have
+// been added they are sorted and written to a temporary file, then
+// the files are merged when they are read back.
+type spillSorter struct {
+	ctx        context.Context
+	f          fs.Fs
+	transforms []matchTransformFn
+	cutoff     int
+	entries    matchEntries
+	runs       []string // names of the sorted temporary files
+	readers    []*runIter
+}
+
+// newSpillSorter makes a spillSorter for entries listed from f
+func newSpillSorter(ctx context.Context, f fs.Fs, transforms []matchTransformFn, cutoff int) *spillSorter {
+	return &spillSorter{
+		ctx:        ctx,
+		f:          f,
+		transforms: transforms,
+		cutoff:     cutoff,
+	}
+}
+
+// newMatchEntry makes a matchEntry for entry
+func newMatchEntry(entry fs.DirEntry, transforms []matchTransformFn) matchEntry {
+	leaf := path.Base(entry.Remote())
+	name := leaf
+	for _, transform := range transforms {
+		name = transform(name)
+	}
+	return matchEntry{entry: entry, leaf: leaf, name: name}
+}
+
+// add a page of entries to the sorter - this is an fs.ListRCallback
+func (s *spillSorter) add(entries fs.DirEntries) error {
+	for _, entry := range entries {
+		s.entries = append(s.entries, newMatchEntry(entry, s.transforms))
+		if len(s.entries) >= s.cutoff {
+			err := s.spill()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// spill sorts the entries in memory and writes them to a new
+// temporary file
+func (s *spillSorter) spill() (err error) {
+	s.entries.sort()
+	fd, err := ioutil.TempFile("", "rclone-listing-")
+	if err != nil {
+		return errors.Wrap(err, "failed to make listing spill file")
+	}
+	s.runs = append(s.runs, fd.Name())
+	fs.Debugf(s.f, "Sorting %d entries to %q", len(s.entries), fd.Name())
+	defer fs.CheckClose(fd, &err)
+	out := bufio.NewWriter(fd)
+	enc := json.NewEncoder(out)
+	for i := range s.entries {
+		e, err := list.EncodeEntry(s.ctx, s.f, s.entries[i].entry)
+		if err != nil {
+			return err
+		}
+		err = enc.Encode(&e)
+		if err != nil {
+			return errors.Wrap(err, "failed to write listing spill file")
+		}
+		// Don't keep the entry alive until it is overwritten
+		s.entries[i] = matchEntry{}
+	}
+	s.entries = s.entries[:0]
+	err = out.Flush()
+	if err != nil {
+		return errors.Wrap(err, "failed to write listing spill file")
+	}
+	return nil
+}
+
+// iter returns a matchIter which returns the entries added in sorted
+// order.
+//
+// If nothing was spilled to disk this returns the entries in memory
+// directly, otherwise it merges the files with the entries in memory.
+func (s *spillSorter) iter() (matchIter, error) {
+	s.entries.sort()
+	if len(s.runs) == 0 {
+		return &sliceIter{es: s.entries}, nil
+	}
+	m := &mergeIter{}
+	for _, name := range s.runs {
+		r, err := newRunIter(s, name)
+		if err != nil {
+			return nil, err
+		}
+		s.readers = append(s.readers, r)
+		err = m.push(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// The entries in memory were listed last, so sort after the
+	// files to keep the sort stable.
+	err := m.push(&sliceIter{es: s.entries})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// close removes the temporary files
+func (s *spillSorter) close() {
+	for _, r := range s.readers {
+		_ = r.fd.Close()
+	}
+	for _, name := range s.runs {
+		err := os.Remove(name)
+		if err != nil {
+			fs.Errorf(s.f, "Failed to remove listing spill file: %v", err)
+		}
+	}
+	s.readers = nil
+	s.runs = nil
+	s.entries = nil
+}
+
+// runIter is a matchIter reading entries from a file written by
+// spillSorter.spill
+type runIter struct {
+	s   *spillSorter
+	fd  *os.File
+	dec *json.Decoder
+}
+
+// newRunIter opens the file name for reading
+func newRunIter(s *spillSorter, name string) (*runIter, error) {
+	fd, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open listing spill file")
+	}
+	return &runIter{
+		s:   s,
+		fd:  fd,
+		dec: json.NewDecoder(bufio.NewReader(fd)),
+	}, nil
+}
+
+// next returns the next entry or nil at the end
+func (r *runIter) next() (*matchEntry, error) {
+	var e list.EncodedEntry
+	err := r.dec.Decode(&e)
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read listing spill file")
+	}
+	me := newMatchEntry(e.Decode(r.s.f), r.s.transforms)
+	return &me, nil
+}
+
+// mergeItem is the current entry of one of the iterators being merged
+type mergeItem struct {
+	e     *matchEntry
+	it    matchIter
+	order int // order the iterator was added in
+}
+
+// mergeIter merges several sorted matchIter~s into one.
+//
+// Equal entries are returned in the order their iterators were added.
+type mergeIter struct {
+	items []mergeItem
+	n     int
+}
+
+// Len is part of heap.Interface.
+func (m *mergeIter) Len() int { return len(m.items) }
+
+// Swap is part of heap.Interface.
+func (m *mergeIter) Swap(i, j int) { m.items[i], m.items[j] = m.items[j], m.items[i] }
+
+// Less is part of heap.Interface.
+func (m *mergeIter) Less(i, j int) bool {
+	ei, ej := m.items[i].e, m.items[j].e
+	if ei.less(ej) {
+		return true
+	} else if ej.less(ei) {
+		return false
+	}
+	return m.items[i].order < m.items[j].order
+}
+
+// Push is part of heap.Interface.
+func (m *mergeIter) Push(x interface{}) { m.items = append(m.items, x.(mergeItem)) }
+
+// Pop is part of heap.Interface.
+func (m *mergeIter) Pop() interface{} {
+	item := m.items[len(m.items)-1]
+	m.items = m.items[:len(m.items)-1]
+	return item
+}
+
+// push adds it to the iterators being merged
+func (m *mergeIter) push(it matchIter) error {
+	e, err := it.next()
+	if err != nil {
+		return err
+	}
+	if e != nil {
+		heap.Push(m, mergeItem{e: e, it: it, order: m.n})
+	}
+	m.n++
+	return nil
+}
+
+// next returns the next entry or nil at the end
+func (m *mergeIter) next() (*matchEntry, error) {
+	if len(m.items) == 0 {
+		return nil, nil
+	}
+	item := &m.items[0]
+	e := item.e
+	next, err := item.it.next()
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		heap.Pop(m)
+	} else {
+		item.e = next
+		heap.Fix(m, 0)
+	}
+	return e, nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ matchIter = (*sliceIter)(nil)
+	_ matchIter = (*runIter)(nil)
+	_ matchIter = (*mergeIter)(nil)
+)
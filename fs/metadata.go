@@ -0,0 +1,88 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Metadata is the POSIX style metadata of an Object as key value
+// pairs.
+//
+// The keys are
+//
+//     mode     - the permission bits as an octal number, eg "644"
+//     uid      - the numeric user id, eg "1000"
+//     gid      - the numeric group id, eg "1000"
+//     xattr-*  - extended attributes, eg "xattr-user.comment"
+//
+// Backends only return the keys they can store.
+type Metadata map[string]string
+
+// Keys used in Metadata
+const (
+	MetadataMode        = "mode"
+	MetadataUID         = "uid"
+	MetadataGID         = "gid"
+	MetadataXattrPrefix = "xattr-"
+)
+
+// GetMetadata returns the metadata of o or nil if it doesn't
+// support metadata
+func GetMetadata(ctx context.Context, o ObjectInfo) (Metadata, error) {
+	do, ok := o.(Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata(ctx)
+}
+
+// Mode returns the permission bits stored in m, if any
+func (m Metadata) Mode() (mode os.FileMode, ok bool) {
+	value, ok := m[MetadataMode]
+	if !ok {
+		return 0, false
+	}
+	perm, err := strconv.ParseUint(value, 8, 32)
+	if err != nil {
+		return 0, false
+	}
+	return os.FileMode(perm) & os.ModePerm, true
+}
+
+// SetMode stores the permission bits of mode in m
+func (m Metadata) SetMode(mode os.FileMode) {
+	m[MetadataMode] = strconv.FormatUint(uint64(mode&os.ModePerm), 8)
+}
+
+// ID returns the numeric user or group id stored under key in m, if
+// any
+func (m Metadata) ID(key string) (id uint32, ok bool) {
+	value, ok := m[key]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(n), true
+}
+
+// SetID stores the numeric user or group id under key in m
+func (m Metadata) SetID(key string, id uint32) {
+	m[key] = strconv.FormatUint(uint64(id), 10)
+}
+
+// Xattrs returns the extended attributes stored in m keyed on their
+// names without the MetadataXattrPrefix
+func (m Metadata) Xattrs() map[string]string {
+	xattrs := map[string]string{}
+	for key, value := range m {
+		if strings.HasPrefix(key, MetadataXattrPrefix) {
+			xattrs[key[len(MetadataXattrPrefix):]] = value
+		}
+	}
+	return xattrs
+}
@@ -0,0 +1,39 @@
+package fs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadata(t *testing.T) {
+	m := Metadata{}
+	_, ok := m.Mode()
+	assert.False(t, ok)
+	_, ok = m.ID(MetadataUID)
+	assert.False(t, ok)
+
+	m.SetMode(os.ModeDir | 0750)
+	assert.Equal(t, "750", m[MetadataMode])
+	mode, ok := m.Mode()
+	assert.True(t, ok)
+	assert.Equal(t, os.FileMode(0750), mode)
+
+	m.SetID(MetadataUID, 1000)
+	assert.Equal(t, "1000", m[MetadataUID])
+	id, ok := m.ID(MetadataUID)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1000), id)
+
+	m[MetadataGID] = "potato"
+	_, ok = m.ID(MetadataGID)
+	assert.False(t, ok)
+	m[MetadataMode] = "999"
+	_, ok = m.Mode()
+	assert.False(t, ok)
+
+	m[MetadataXattrPrefix+"user.comment"] = "hello"
+	assert.Equal(t, map[string]string{"user.comment": "hello"}, m.Xattrs())
+	assert.Equal(t, map[string]string{}, Metadata(nil).Xattrs())
+}
@@ -7,10 +7,13 @@ import (
 	"crypto/md5"
 	"encoding/base64"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/rclone/rclone/fs/fspath"
 )
@@ -30,6 +33,10 @@ func NewFs(ctx context.Context, path string) (Fs, error) {
 	if err != nil {
 		return nil, err
 	}
+	ctx, err = addNetworkConfig(ctx, configName, config)
+	if err != nil {
+		return nil, err
+	}
 	overridden := fsInfo.Options.Overridden(config)
 	if len(overridden) > 0 {
 		extraConfig := overridden.String()
@@ -48,7 +55,95 @@ func NewFs(ctx context.Context, path string) (Fs, error) {
 		// These need to work as filesystem names as the VFS cache will use them
 		configName += suffix
 	}
-	return fsInfo.NewFs(ctx, configName, fsPath, config)
+	mode, restricted, err := restrictModeFromConfig(configName, config)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := versionsFromConfig(configName, fsInfo, config)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withPacerName(ctx, configName)
+	f, err := fsInfo.NewFs(ctx, configName, fsPath, config)
+	if versions && f != nil {
+		f, err = wrapVersions(ctx, fsInfo, configName, fsPath, config, f, err)
+	}
+	if t := GetConfig(ctx).SnapshotTime; !t.IsZero() && f != nil {
+		f, err = wrapSnapshot(f, err, t)
+	}
+	if restricted && f != nil {
+		f = newRestrictedFs(f, mode)
+	}
+	return f, err
+}
+
+// addNetworkConfig returns ctx with the network options set in the
+// config of the remote, which apply to any backend, overriding the
+// global ones.
+func addNetworkConfig(ctx context.Context, configName string, config configmap.Getter) (context.Context, error) {
+	var ci *ConfigInfo
+	override := func() {
+		if ci == nil {
+			ctx, ci = AddConfig(ctx)
+		}
+	}
+	if bind, ok := config.Get("bind"); ok && bind != "" {
+		addrs, err := ParseBindAddr(bind)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: bad bind parameter", configName)
+		}
+		override()
+		ci.BindAddr = addrs
+	}
+	only := map[string]bool{}
+	for _, key := range []string{"ipv4_only", "ipv6_only"} {
+		value, ok := config.Get(key)
+		if !ok || value == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: bad %s parameter", configName, key)
+		}
+		only[key] = b
+	}
+	if only["ipv4_only"] && only["ipv6_only"] {
+		return nil, errors.Errorf("%s: can't use ipv4_only with ipv6_only", configName)
+	}
+	if len(only) > 0 {
+		override()
+		// Choosing one family for the remote overrides the global choice
+		switch {
+		case only["ipv4_only"]:
+			ci.IPv4Only, ci.IPv6Only = true, false
+		case only["ipv6_only"]:
+			ci.IPv4Only, ci.IPv6Only = false, true
+		default:
+			if b, ok := only["ipv4_only"]; ok {
+				ci.IPv4Only = b
+			}
+			if b, ok := only["ipv6_only"]; ok {
+				ci.IPv6Only = b
+			}
+		}
+	}
+	if value, ok := config.Get("dns_override"); ok && value != "" {
+		overrides, err := ParseDNSOverride([]string{value})
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: bad dns_override parameter", configName)
+		}
+		override()
+		// Add to the global overrides without modifying them
+		merged := make(map[string]net.IP, len(ci.DNSOverride)+len(overrides))
+		for host, ip := range ci.DNSOverride {
+			merged[host] = ip
+		}
+		for host, ip := range overrides {
+			merged[host] = ip
+		}
+		ci.DNSOverride = merged
+	}
+	return ctx, nil
 }
 
 // ConfigFs makes the config for calling NewFs with.
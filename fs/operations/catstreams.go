@@ -0,0 +1,218 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"golang.org/x/sync/errgroup"
+)
+
+// catStreamsChunkSize is the size of the chunks CatStreams fetches.
+// Up to streams of these are held in memory at once.
+var catStreamsChunkSize int64 = 4 << 20
+
+// catChunk is a chunk fetched by CatStreams
+type catChunk struct {
+	buf []byte
+	err error
+}
+
+// CatStreams is like Cat but reads each file with streams range
+// requests at once, writing the chunks to w in order.
+//
+// Files of unknown size and streams <= 1 are read as Cat does.
+func CatStreams(ctx context.Context, f fs.Fs, w io.Writer, offset, count int64, streams int) error {
+	if streams <= 1 {
+		return Cat(ctx, f, w, offset, count)
+	}
+	var mu sync.Mutex
+	return ListFn(ctx, f, func(o fs.Object) {
+		if o.Size() < 0 {
+			catObject(ctx, &mu, w, o, offset, count)
+			return
+		}
+		var err error
+		tr := accounting.Stats(ctx).NewTransfer(o)
+		defer func() {
+			tr.Done(ctx, err)
+		}()
+		err = catStreams(ctx, o, tr.Account(ctx, nil), &mu, w, catRange(o, offset, count), streams)
+		if err != nil {
+			err = fs.CountError(err)
+			fs.Errorf(o, "Failed to cat: %v", err)
+		}
+	})
+}
+
+// catStreams writes opt of o to w fetching it in chunks with streams
+// range requests at once
+func catStreams(ctx context.Context, o fs.Object, acc *accounting.Account, mu *sync.Mutex, w io.Writer, opt fs.RangeOption, streams int) error {
+	ci := fs.GetConfig(ctx)
+	start, end := opt.Start, o.Size()
+	if opt.End >= 0 && opt.End+1 < end {
+		end = opt.End + 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Fetch the chunks queueing their results in order
+	results := make(chan chan catChunk, streams-1)
+	go func() {
+		defer close(results)
+		for chunkStart := start; chunkStart < end; chunkStart += catStreamsChunkSize {
+			chunkEnd := chunkStart + catStreamsChunkSize
+			if chunkEnd > end {
+				chunkEnd = end
+			}
+			result := make(chan catChunk, 1)
+			select {
+			case results <- result:
+			case <-ctx.Done():
+				return
+			}
+			go func(chunkStart, chunkEnd int64) {
+				var chunk catChunk
+				chunk.buf, chunk.err = readRange(ctx, o, chunkStart, chunkEnd, ci.LowLevelRetries, acc)
+				result <- chunk
+			}(chunkStart, chunkEnd)
+		}
+	}()
+
+	// take the lock just before we output stuff, so at the last possible moment
+	first := true
+	for result := range results {
+		chunk := <-result
+		if chunk.err != nil {
+			return chunk.err
+		}
+		if first {
+			mu.Lock()
+			defer mu.Unlock()
+			first = false
+		}
+		_, err := w.Write(chunk.buf)
+		if err != nil {
+			return errors.Wrap(err, "failed to send to output")
+		}
+	}
+	return nil
+}
+
+// readRange reads the bytes from start up to end of o accounting them
+// to acc
+func readRange(ctx context.Context, o fs.Object, start, end int64, maxTries int, acc *accounting.Account) (buf []byte, err error) {
+	ci := fs.GetConfig(ctx)
+	options := []fs.OpenOption{&fs.RangeOption{Start: start, End: end - 1}}
+	for _, option := range ci.DownloadHeaders {
+		options = append(options, option)
+	}
+	in, err := NewReOpen(ctx, o, maxTries, options...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open")
+	}
+	defer fs.CheckClose(in, &err)
+	buf = make([]byte, end-start)
+	n, err := io.ReadFull(in, buf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %d-%d", start, end)
+	}
+	return buf[:n], acc.AccountRead(n)
+}
+
+// ReadStreamStats is the result of reading one of the streams in
+// BenchmarkRead
+type ReadStreamStats struct {
+	Stream   int           // number of the stream from 1
+	Start    int64         // offset the stream started reading at
+	Bytes    int64         // bytes read
+	Duration time.Duration // time taken including opening
+}
+
+// Rate returns the speed of the stream in bytes/s
+func (s ReadStreamStats) Rate() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Duration.Seconds()
+}
+
+// BenchmarkRead reads the part of o given by offset and count, as
+// passed to Cat, split evenly between streams range requests at once
+// discarding the data and returns the speed of each stream.
+func BenchmarkRead(ctx context.Context, o fs.Object, offset, count int64, streams int) (stats []ReadStreamStats, err error) {
+	ci := fs.GetConfig(ctx)
+	if o.Size() < 0 {
+		return nil, errors.New("can't benchmark reading a file of unknown size")
+	}
+	if streams < 1 {
+		streams = 1
+	}
+	opt := catRange(o, offset, count)
+	start, end := opt.Start, o.Size()
+	if opt.End >= 0 && opt.End+1 < end {
+		end = opt.End + 1
+	}
+	partSize := (end - start + int64(streams) - 1) / int64(streams)
+	if partSize <= 0 {
+		partSize = 1
+	}
+	tr := accounting.Stats(ctx).NewTransfer(o)
+	defer func() {
+		tr.Done(ctx, err)
+	}()
+	acc := tr.Account(ctx, nil)
+	g, gCtx := errgroup.WithContext(ctx)
+	stats = make([]ReadStreamStats, streams)
+	for i := range stats {
+		i := i
+		partStart := start + int64(i)*partSize
+		partEnd := partStart + partSize
+		if partEnd > end {
+			partEnd = end
+		}
+		stats[i] = ReadStreamStats{Stream: i + 1, Start: partStart}
+		if partStart >= partEnd {
+			continue
+		}
+		g.Go(func() (err error) {
+			options := []fs.OpenOption{&fs.RangeOption{Start: partStart, End: partEnd - 1}}
+			for _, option := range ci.DownloadHeaders {
+				options = append(options, option)
+			}
+			startTime := time.Now()
+			defer func() {
+				stats[i].Duration = time.Since(startTime)
+			}()
+			in, err := o.Open(gCtx, options...)
+			if err != nil {
+				return errors.Wrapf(err, "stream %d: failed to open", i+1)
+			}
+			defer fs.CheckClose(in, &err)
+			buf := make([]byte, multithreadBufferSize)
+			for {
+				n, err := in.Read(buf)
+				if n > 0 {
+					stats[i].Bytes += int64(n)
+					if err := acc.AccountRead(n); err != nil {
+						return err
+					}
+				}
+				if err == io.EOF {
+					return nil
+				} else if err != nil {
+					return errors.Wrapf(err, "stream %d: failed to read", i+1)
+				}
+			}
+		})
+	}
+	err = g.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,73 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var catT1 = fstest.Time("2001-02-03T04:05:06.499999999Z")
+
+func TestCatStreams(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	contents := random.String(1000)
+	file1 := r.WriteObject(ctx, "file1", contents, catT1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	oldChunkSize := catStreamsChunkSize
+	catStreamsChunkSize = 64
+	defer func() {
+		catStreamsChunkSize = oldChunkSize
+	}()
+
+	for _, test := range []struct {
+		offset int64
+		count  int64
+		want   string
+	}{
+		{0, -1, contents},
+		{0, 100, contents[:100]},
+		{-300, -1, contents[700:]},
+		{10, 500, contents[10:510]},
+		{990, 100, contents[990:]},
+	} {
+		for _, streams := range []int{1, 2, 5} {
+			t.Run(fmt.Sprintf("offset=%d,count=%d,streams=%d", test.offset, test.count, streams), func(t *testing.T) {
+				var buf bytes.Buffer
+				err := CatStreams(ctx, r.Fremote, &buf, test.offset, test.count, streams)
+				require.NoError(t, err)
+				assert.Equal(t, test.want, buf.String())
+			})
+		}
+	}
+}
+
+func TestBenchmarkRead(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject(ctx, "file1", random.String(1000), catT1)
+	fstest.CheckItems(t, r.Fremote, file1)
+	o, err := r.Fremote.NewObject(ctx, "file1")
+	require.NoError(t, err)
+
+	stats, err := BenchmarkRead(ctx, o, 100, -1, 3)
+	require.NoError(t, err)
+	require.Len(t, stats, 3)
+	var total int64
+	for i, stat := range stats {
+		assert.Equal(t, i+1, stat.Stream)
+		assert.Equal(t, int64(100+300*i), stat.Start)
+		assert.Equal(t, int64(300), stat.Bytes)
+		total += stat.Bytes
+	}
+	assert.Equal(t, int64(900), total)
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"math/rand"
 	"os"
 	"regexp"
 	"sync"
@@ -329,9 +330,10 @@ func CheckIdenticalDownload(ctx context.Context, dst, src fs.Object) (differ boo
 	return differ, err
 }
 
-// Does the work for CheckIdenticalDownload
-func checkIdenticalDownload(ctx context.Context, dst, src fs.Object) (differ bool, err error) {
-	in1, err := dst.Open(ctx)
+// Does the work for CheckIdenticalDownload reading the parts of dst
+// and src given by options
+func checkIdenticalDownload(ctx context.Context, dst, src fs.Object, options ...fs.OpenOption) (differ bool, err error) {
+	in1, err := dst.Open(ctx, options...)
 	if err != nil {
 		return true, errors.Wrapf(err, "failed to open %q", dst)
 	}
@@ -341,7 +343,7 @@ func checkIdenticalDownload(ctx context.Context, dst, src fs.Object) (differ boo
 	}()
 	in1 = tr1.Account(ctx, in1).WithBuffer() // account and buffer the transfer
 
-	in2, err := src.Open(ctx)
+	in2, err := src.Open(ctx, options...)
 	if err != nil {
 		return true, errors.Wrapf(err, "failed to open %q", src)
 	}
@@ -370,7 +372,93 @@ func CheckDownload(ctx context.Context, opt *CheckOpt) error {
 	return CheckFn(ctx, &optCopy)
 }
 
+// sampleRanges returns the ranges of an object of size to compare
+// when sampling sampleSize bytes from the start, the end and a random
+// place in the middle.
+//
+// If the object is too small to sample it returns a single range
+// covering the whole object.
+func sampleRanges(size, sampleSize int64) []*fs.RangeOption {
+	if size <= 3*sampleSize {
+		return []*fs.RangeOption{{Start: 0, End: size - 1}}
+	}
+	middle := sampleSize + rand.Int63n(size-3*sampleSize+1)
+	return []*fs.RangeOption{
+		{Start: 0, End: sampleSize - 1},
+		{Start: middle, End: middle + sampleSize - 1},
+		{Start: size - sampleSize, End: size - 1},
+	}
+}
+
+// CheckIdenticalSample checks to see if dst and src are identical by
+// reading sampleSize bytes from the start, the end and a random place
+// in the middle of each.
+//
+// dst and src should be the same size. Objects of unknown size are
+// read in full.
+//
+// it returns true if differences were found
+func CheckIdenticalSample(ctx context.Context, dst, src fs.Object, sampleSize int64) (differ bool, err error) {
+	size := src.Size()
+	if size < 0 || sampleSize <= 0 {
+		return CheckIdenticalDownload(ctx, dst, src)
+	}
+	if size == 0 {
+		return false, nil
+	}
+	ci := fs.GetConfig(ctx)
+	for _, rangeOption := range sampleRanges(size, sampleSize) {
+		err = Retry(ctx, src, ci.LowLevelRetries, func() error {
+			differ, err = checkIdenticalDownload(ctx, dst, src, rangeOption)
+			return err
+		})
+		if err != nil || differ {
+			return differ, err
+		}
+	}
+	return false, nil
+}
+
+// CheckSample checks the files in fsrc and fdst according to Size and
+// hash if there is a common hash, otherwise by comparing samples of
+// sampleSize bytes from the start, the end and a random place in the
+// middle of the files.
+//
+// This catches most corruption without downloading the whole of
+// every file.
+func CheckSample(ctx context.Context, opt *CheckOpt, sampleSize int64) error {
+	optCopy := *opt
+	optCopy.Check = func(ctx context.Context, dst, src fs.Object) (differ bool, noHash bool, err error) {
+		same, ht, err := CheckHashes(ctx, src, dst)
+		if err != nil {
+			return true, false, err
+		}
+		if ht != hash.None {
+			if !same {
+				err = errors.Errorf("%v differ", ht)
+				fs.Errorf(src, "%v", err)
+				return true, false, nil
+			}
+			return false, false, nil
+		}
+		differ, err = CheckIdenticalSample(ctx, dst, src, sampleSize)
+		if err != nil {
+			return true, true, errors.Wrap(err, "failed to download sample")
+		}
+		if differ {
+			fs.Errorf(src, "Sampled contents differ")
+		}
+		return differ, false, nil
+	}
+	return CheckFn(ctx, &optCopy)
+}
+
 // CheckSum checks filesystem hashes against a SUM file
+//
+// If hashType is hash.None it is worked out from the width of the
+// sums in the file. Files are downloaded and hashed if download is
+// set, if the file system doesn't support hashType, or if it has no
+// hash for a file.
 func CheckSum(ctx context.Context, fsrc, fsum fs.Fs, sumFile string, hashType hash.Type, opt *CheckOpt, download bool) error {
 	var options CheckOpt
 	if opt != nil {
@@ -384,10 +472,6 @@ func CheckSum(ctx context.Context, fsrc, fsum fs.Fs, sumFile string, hashType ha
 	options.Fdst = fsrc // denotes the file system to check
 	opt = &options      // override supplied argument
 
-	if !download && (hashType == hash.None || !opt.Fdst.Hashes().Contains(hashType)) {
-		return errors.Errorf("%s: hash type is not supported by file system: %s", hashType, opt.Fdst)
-	}
-
 	if sumFile == "" {
 		return errors.Errorf("not a sum file: %s", fsum)
 	}
@@ -400,6 +484,18 @@ func CheckSum(ctx context.Context, fsrc, fsum fs.Fs, sumFile string, hashType ha
 		return errors.Wrap(err, "failed to parse sum file")
 	}
 
+	if hashType == hash.None {
+		hashType, err = guessHashType(hashes)
+		if err != nil {
+			return err
+		}
+		fs.Infof(nil, "Checking %v hashes from the sum file", hashType)
+	}
+	if !download && !opt.Fdst.Hashes().Contains(hashType) {
+		fs.Logf(opt.Fdst, "%v hash not supported so downloading files to check them", hashType)
+		download = true
+	}
+
 	ci := fs.GetConfig(ctx)
 	c := &checkMarch{
 		tokens: make(chan struct{}, ci.Checkers),
@@ -462,8 +558,11 @@ func (c *checkMarch) checkSum(ctx context.Context, obj fs.Object, download bool,
 	if !download {
 		var objHash string
 		objHash, err = obj.Hash(ctx, hashType)
-		c.matchSum(ctx, sumHash, objHash, obj, err, hashType)
-		return
+		if err != nil || objHash != "" || sumHash == "" {
+			c.matchSum(ctx, sumHash, objHash, obj, err, hashType)
+			return
+		}
+		fs.Debugf(obj, "No %v hash on %v so downloading to check it", hashType, c.opt.Fdst)
 	}
 
 	c.wg.Add(1)
@@ -529,6 +628,26 @@ func (c *checkMarch) matchSum(ctx context.Context, sumHash, objHash string, obj
 	}
 }
 
+// guessHashType returns the type of the hashes in a SUM file going by
+// their width. Where several hash types have the same width the
+// standard ones are preferred.
+func guessHashType(hashes HashSums) (hash.Type, error) {
+	width := -1
+	for _, sum := range hashes {
+		width = len(sum)
+		break
+	}
+	if width < 0 {
+		return hash.None, errors.New("no sums found in sum file")
+	}
+	for _, ht := range []hash.Type{hash.MD5, hash.SHA1, hash.SHA256, hash.CRC32, hash.Whirlpool} {
+		if hash.Width(ht) == width {
+			return ht, nil
+		}
+	}
+	return hash.None, errors.Errorf("can't work out the hash type of %d character sums - please give it", width)
+}
+
 // HashSums represents a parsed SUM file
 type HashSums map[string]string
 
@@ -17,6 +17,7 @@ import (
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/mockobject"
 	"github.com/rclone/rclone/lib/readers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -207,6 +208,49 @@ func TestCheckDownload(t *testing.T) {
 	testCheck(t, operations.CheckDownload)
 }
 
+func TestCheckSample(t *testing.T) {
+	testCheck(t, func(ctx context.Context, opt *operations.CheckOpt) error {
+		return operations.CheckSample(ctx, opt, 4)
+	})
+}
+
+func TestCheckIdenticalSample(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("0123456789abcdefghijklmnopqrstu") // 31 bytes
+	object := func(content []byte) fs.Object {
+		return mockobject.New("potato").WithContent(content, mockobject.SeekModeNone)
+	}
+	change := func(i int) []byte {
+		changed := append([]byte{}, content...)
+		changed[i] = '*'
+		return changed
+	}
+	for _, test := range []struct {
+		name       string
+		sampleSize int64
+		dst        []byte
+		differ     bool
+	}{
+		{"identical", 10, content, false},
+		{"start differs", 10, change(0), true},
+		{"end differs", 10, change(30), true},
+		{"middle differs", 10, change(15), true},
+		{"whole file", 20, change(25), true},
+		{"no sampling", 0, change(25), true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			differ, err := operations.CheckIdenticalSample(ctx, object(test.dst), object(content), test.sampleSize)
+			require.NoError(t, err)
+			assert.Equal(t, test.differ, differ)
+		})
+	}
+
+	// Empty files are identical
+	differ, err := operations.CheckIdenticalSample(ctx, object(nil), object(nil), 10)
+	require.NoError(t, err)
+	assert.False(t, differ)
+}
+
 func TestCheckSizeOnly(t *testing.T) {
 	ctx := context.Background()
 	ci := fs.GetConfig(ctx)
@@ -324,7 +368,8 @@ func TestParseSumFile(t *testing.T) {
 	}
 }
 
-func testCheckSum(t *testing.T, download bool) {
+// testCheckSum checks MD5 sums passing checkHashType to CheckSum
+func testCheckSum(t *testing.T, download bool, checkHashType hash.Type) {
 	const dataDir = "data"
 	const sumFile = "test.sum"
 
@@ -402,7 +447,7 @@ func testCheckSum(t *testing.T, download bool) {
 			MissingOnSrc: new(bytes.Buffer),
 			MissingOnDst: new(bytes.Buffer),
 		}
-		err := operations.CheckSum(ctx, dataFs, r.Fremote, sumFile, hashType, &opt, download)
+		err := operations.CheckSum(ctx, dataFs, r.Fremote, sumFile, checkHashType, &opt, download)
 
 		gotErrors := int(accounting.GlobalStats().GetErrors())
 		if wantErrors == 0 {
@@ -522,9 +567,13 @@ func testCheckSum(t *testing.T, download bool) {
 }
 
 func TestCheckSum(t *testing.T) {
-	testCheckSum(t, false)
+	testCheckSum(t, false, hash.MD5)
 }
 
 func TestCheckSumDownload(t *testing.T) {
-	testCheckSum(t, true)
+	testCheckSum(t, true, hash.MD5)
+}
+
+func TestCheckSumGuessType(t *testing.T) {
+	testCheckSum(t, false, hash.None)
 }
@@ -0,0 +1,142 @@
+// Checkpoints of the files deleted so an interrupted delete or purge
+// can be continued
+
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+)
+
+// checkpointEntry is one line of the checkpoint. The first has the
+// Root which was being deleted from and the rest the Remote of each
+// object deleted.
+type checkpointEntry struct {
+	Root   string `json:"root,omitempty"`
+	Remote string `json:"remote,omitempty"`
+}
+
+// deleteCheckpoint records the objects deleted from an Fs so that if
+// the delete is interrupted they aren't deleted again when it is run
+// again, which stops eventually consistent listings causing errors.
+//
+// The methods may be called on a nil *deleteCheckpoint in which case
+// they do nothing.
+type deleteCheckpoint struct {
+	mu      sync.Mutex
+	path    string
+	fd      *os.File
+	enc     *json.Encoder
+	deleted map[string]struct{}
+}
+
+// openDeleteCheckpoint opens the checkpoint at path for deleting from
+// f, reading the objects already deleted if it exists.
+//
+// It returns nil if path is empty or --dry-run is set.
+func openDeleteCheckpoint(ctx context.Context, path string, f fs.Fs) (*deleteCheckpoint, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if fs.GetConfig(ctx).DryRun {
+		fs.Debugf(nil, "Not using checkpoint %q with --dry-run", path)
+		return nil, nil
+	}
+	root := fs.ConfigString(f)
+	fd, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open checkpoint")
+	}
+	c := &deleteCheckpoint{
+		path:    path,
+		fd:      fd,
+		enc:     json.NewEncoder(fd),
+		deleted: make(map[string]struct{}),
+	}
+	dec := json.NewDecoder(fd)
+	for first := true; ; first = false {
+		var e checkpointEntry
+		err = dec.Decode(&e)
+		if err == io.EOF && first {
+			// A new checkpoint so record what it is for
+			err = c.enc.Encode(checkpointEntry{Root: root})
+			break
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// Ignore a partly written last entry
+			err = nil
+			break
+		}
+		if err != nil {
+			err = errors.Wrap(err, "failed to read checkpoint")
+			break
+		}
+		if first && e.Root != root {
+			err = errors.Errorf("checkpoint %q is for %q not %q", path, e.Root, root)
+			break
+		}
+		if e.Remote != "" {
+			c.deleted[e.Remote] = struct{}{}
+		}
+	}
+	if err != nil {
+		_ = fd.Close()
+		return nil, err
+	}
+	if len(c.deleted) > 0 {
+		fs.Infof(nil, "Continuing from checkpoint %q with %d files already deleted", path, len(c.deleted))
+	}
+	return c, nil
+}
+
+// isDeleted returns true if remote was deleted according to the
+// checkpoint
+func (c *deleteCheckpoint) isDeleted(remote string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, found := c.deleted[remote]
+	return found
+}
+
+// add records that remote was deleted
+func (c *deleteCheckpoint) add(remote string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleted[remote] = struct{}{}
+	err := c.enc.Encode(checkpointEntry{Remote: remote})
+	if err != nil {
+		return errors.Wrap(err, "failed to write checkpoint")
+	}
+	return nil
+}
+
+// close the checkpoint, removing it if the delete finished without
+// error as it is no longer needed
+func (c *deleteCheckpoint) close(deleteErr error) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.fd.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to close checkpoint")
+	}
+	if deleteErr != nil {
+		fs.Infof(nil, "Run again with the same checkpoint %q to continue", c.path)
+		return nil
+	}
+	return os.Remove(c.path)
+}
@@ -0,0 +1,92 @@
+// Transform the data of transfers with --transfer-filter-cmd
+
+package operations
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+)
+
+// filterCmdReader reads the output of the --transfer-filter-cmd which
+// is fed the data of the source
+type filterCmdReader struct {
+	out     io.ReadCloser // output of the command
+	in      io.ReadCloser // data of the source
+	cmd     *exec.Cmd
+	stderr  bytes.Buffer
+	waited  bool
+	waitErr error
+}
+
+// newFilterCmdReader starts the command in args reading from in and
+// returns a reader of its output.
+//
+// The details of the transfer are passed to the command in the
+// same environment variables as the transfer hooks.
+func newFilterCmdReader(ctx context.Context, args fs.SpaceSepList, in io.ReadCloser, info *TransferHookInfo) (io.ReadCloser, error) {
+	r := &filterCmdReader{
+		in:  in,
+		cmd: exec.CommandContext(ctx, args[0], args[1:]...),
+	}
+	r.cmd.Env = info.environ()
+	r.cmd.Stdin = in
+	r.cmd.Stderr = &r.stderr
+	out, err := r.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	r.out = out
+	err = r.cmd.Start()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to start transfer filter command %q", args[0])
+	}
+	return r, nil
+}
+
+// wait for the command to finish returning its error if it failed
+func (r *filterCmdReader) wait() error {
+	if r.waited {
+		return r.waitErr
+	}
+	r.waited = true
+	err := r.cmd.Wait()
+	if err != nil {
+		if stderr := strings.TrimSpace(r.stderr.String()); stderr != "" {
+			err = errors.Wrap(err, stderr)
+		}
+		r.waitErr = errors.Wrapf(err, "transfer filter command %q failed", r.cmd.Args[0])
+	}
+	return r.waitErr
+}
+
+// Read the output of the command.
+//
+// The error from the command is returned instead of io.EOF if it
+// failed so that a truncated output isn't uploaded.
+func (r *filterCmdReader) Read(p []byte) (n int, err error) {
+	n, err = r.out.Read(p)
+	if err == io.EOF {
+		if waitErr := r.wait(); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// Close the output of the command, wait for it to finish and close
+// the source
+func (r *filterCmdReader) Close() error {
+	_ = r.out.Close()
+	err := r.wait()
+	inErr := r.in.Close()
+	if err == nil {
+		err = inErr
+	}
+	return err
+}
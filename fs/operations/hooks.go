@@ -0,0 +1,170 @@
+// Commands and webhooks run before and after each transfer
+
+package operations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+)
+
+// TransferHookInfo describes a transfer to the transfer hooks. It is
+// sent as the body of the --post-transfer-url webhook.
+type TransferHookInfo struct {
+	Stage  string `json:"stage"`           // "pre" or "post"
+	Path   string `json:"path"`            // path of the file relative to the destination root
+	Src    string `json:"src"`             // source remote
+	Dst    string `json:"dst"`             // destination remote
+	Size   int64  `json:"size"`            // size of the file or -1 if unknown
+	Result string `json:"result"`          // "ok" or "error" for post transfer hooks
+	Error  string `json:"error,omitempty"` // the error if the transfer failed
+}
+
+// newTransferHookInfo makes the description of the transfer of src
+// to remote on fdst
+func newTransferHookInfo(stage string, fdst fs.Fs, remote string, src fs.ObjectInfo, err error) *TransferHookInfo {
+	info := &TransferHookInfo{
+		Stage:  stage,
+		Path:   remote,
+		Src:    infoString(src.Fs()),
+		Dst:    fs.ConfigString(fdst),
+		Size:   src.Size(),
+		Result: "ok",
+	}
+	if err != nil {
+		info.Result = "error"
+		info.Error = err.Error()
+	}
+	return info
+}
+
+// infoString returns the remote:path of info
+func infoString(info fs.Info) string {
+	if f, ok := info.(fs.Fs); ok {
+		return fs.ConfigString(f)
+	}
+	return info.Name() + ":" + info.Root()
+}
+
+// environ returns the environment for the transfer hook commands
+func (info *TransferHookInfo) environ() []string {
+	return append(os.Environ(),
+		"RCLONE_TRANSFER_STAGE="+info.Stage,
+		"RCLONE_TRANSFER_PATH="+info.Path,
+		"RCLONE_TRANSFER_SRC="+info.Src,
+		"RCLONE_TRANSFER_DST="+info.Dst,
+		fmt.Sprintf("RCLONE_TRANSFER_SIZE=%d", info.Size),
+		"RCLONE_TRANSFER_RESULT="+info.Result,
+		"RCLONE_TRANSFER_ERROR="+info.Error,
+	)
+}
+
+// runTransferHookCmd runs the command in args with the details of the
+// transfer in its environment
+func runTransferHookCmd(ctx context.Context, args fs.SpaceSepList, info *TransferHookInfo) error {
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = info.environ()
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	if output := strings.TrimSpace(out.String()); output != "" {
+		fs.Debugf(info.Path, "%s transfer command output: %s", info.Stage, output)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "%s transfer command %q failed", info.Stage, args[0])
+	}
+	return nil
+}
+
+// postTransferHookURL sends the details of the transfer to url as JSON
+func postTransferHookURL(ctx context.Context, url string, info *TransferHookInfo) (err error) {
+	body, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := fshttp.NewClient(ctx).Do(req)
+	if err != nil {
+		return errors.Wrap(err, "post transfer webhook failed")
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("post transfer webhook failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// preTransferHook runs the --pre-transfer-cmd if set before src is
+// transferred to remote on fdst.
+//
+// If it fails the transfer shouldn't go ahead so the error returned
+// isn't retried.
+func preTransferHook(ctx context.Context, fdst fs.Fs, remote string, src fs.ObjectInfo) error {
+	ci := fs.GetConfig(ctx)
+	if len(ci.PreTransferCmd) == 0 {
+		return nil
+	}
+	info := newTransferHookInfo("pre", fdst, remote, src, nil)
+	err := runTransferHookCmd(ctx, ci.PreTransferCmd, info)
+	if err != nil {
+		return fserrors.NoRetryError(err)
+	}
+	return nil
+}
+
+// postTransferHook runs the --post-transfer-cmd and calls the
+// --post-transfer-url if set after src was transferred to remote on
+// fdst with the result err.
+//
+// The transfer has already happened so failures are only logged.
+func postTransferHook(ctx context.Context, fdst fs.Fs, remote string, src fs.ObjectInfo, err error) {
+	ci := fs.GetConfig(ctx)
+	if len(ci.PostTransferCmd) == 0 && ci.PostTransferURL == "" {
+		return
+	}
+	info := newTransferHookInfo("post", fdst, remote, src, err)
+	if len(ci.PostTransferCmd) != 0 {
+		if err := runTransferHookCmd(ctx, ci.PostTransferCmd, info); err != nil {
+			fs.Errorf(remote, "%v", err)
+		}
+	}
+	if ci.PostTransferURL != "" {
+		if err := postTransferHookURL(ctx, ci.PostTransferURL, info); err != nil {
+			fs.Errorf(remote, "%v", err)
+		}
+	}
+}
+
+// withoutTransferHooks returns a ctx with the transfer hooks, filter,
+// scanner and report removed, for the transfers done by Copy which
+// may Copy again.
+func withoutTransferHooks(ctx context.Context) context.Context {
+	ci := fs.GetConfig(ctx)
+	if len(ci.PreTransferCmd) == 0 && len(ci.PostTransferCmd) == 0 && ci.PostTransferURL == "" && len(ci.TransferFilterCmd) == 0 && ci.ScanClamd == "" && ci.ScanICAP == "" && ci.Report == "" {
+		return ctx
+	}
+	ctx, ci = fs.AddConfig(ctx)
+	ci.Report = ""
+	ci.PreTransferCmd = nil
+	ci.PostTransferCmd = nil
+	ci.PostTransferURL = ""
+	ci.TransferFilterCmd = nil
+	ci.ScanClamd = ""
+	ci.ScanICAP = ""
+	return ctx
+}
@@ -81,7 +81,7 @@ func TestListDirSorted(t *testing.T) {
 	assert.Equal(t, "sub dir/sub sub dir/", str(1))
 
 	// testing ignore file
-	fi.Opt.ExcludeFile = ".ignore"
+	fi.Opt.ExcludeFile = []string{".ignore"}
 
 	items, err = list.DirSorted(context.Background(), r.Fremote, false, "sub dir")
 	require.NoError(t, err)
@@ -98,10 +98,73 @@ func TestListDirSorted(t *testing.T) {
 	assert.Equal(t, "sub dir/ignore dir/.ignore", str(0))
 	assert.Equal(t, "sub dir/ignore dir/should be ignored", str(1))
 
-	fi.Opt.ExcludeFile = ""
+	// any of the exclude files will do
+	fi.Opt.ExcludeFile = []string{".nothing", ".ignore"}
+	items, err = list.DirSorted(context.Background(), r.Fremote, false, "sub dir")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "sub dir/sub sub dir/", str(0))
+
+	fi.Opt.ExcludeFile = nil
 	items, err = list.DirSorted(context.Background(), r.Fremote, false, "sub dir/ignore dir")
 	require.NoError(t, err)
 	require.Len(t, items, 2)
 	assert.Equal(t, "sub dir/ignore dir/.ignore", str(0))
 	assert.Equal(t, "sub dir/ignore dir/should be ignored", str(1))
+
+	// directories whose files are too big are excluded
+	fi.Opt.MaxDirSize = 10
+	items, err = list.DirSorted(context.Background(), r.Fremote, false, "sub dir/ignore dir")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	fi.Opt.MaxDirSize = 5
+	items, err = list.DirSorted(context.Background(), r.Fremote, false, "sub dir/ignore dir")
+	require.NoError(t, err)
+	require.Len(t, items, 0)
+	fi.Opt.MaxDirSize = -1
+}
+
+// TestListDirSortedIgnoreFile tests --ignore-file rules are applied
+// to the directory they are in and those below it
+func TestListDirSortedIgnoreFile(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	ctx := context.Background()
+	opt := filter.DefaultOpt
+	opt.IgnoreFile = ".rcloneignore"
+	fi, err := filter.NewFilter(&opt)
+	require.NoError(t, err)
+	ctx = filter.ReplaceConfig(ctx, fi)
+
+	files := []fstest.Item{
+		r.WriteObject(ctx, ".rcloneignore", "*.tmp\ncache/\n", t1),
+		r.WriteObject(ctx, "a.txt", "a", t1),
+		r.WriteObject(ctx, "a.tmp", "a", t1),
+		r.WriteObject(ctx, "cache/file", "file", t1),
+		r.WriteObject(ctx, "sub/.rcloneignore", "!keep.tmp\n/local.txt\n", t1),
+		r.WriteObject(ctx, "sub/b.tmp", "b", t1),
+		r.WriteObject(ctx, "sub/keep.tmp", "keep", t1),
+		r.WriteObject(ctx, "sub/local.txt", "local", t1),
+		r.WriteObject(ctx, "sub/deeper/local.txt", "local", t1),
+		r.WriteObject(ctx, "sub/deeper/c.tmp", "c", t1),
+	}
+	fstest.CheckItems(t, r.Fremote, files...)
+
+	names := func(dir string) (names []string) {
+		items, err := list.DirSorted(ctx, r.Fremote, false, dir)
+		require.NoError(t, err)
+		for _, item := range items {
+			names = append(names, item.Remote())
+		}
+		return names
+	}
+	assert.Equal(t, []string{".rcloneignore", "a.txt", "sub"}, names(""))
+	assert.Equal(t, []string{"sub/.rcloneignore", "sub/deeper", "sub/keep.tmp"}, names("sub"))
+	assert.Equal(t, []string{"sub/deeper/local.txt"}, names("sub/deeper"))
+
+	// includeAll ignores the rules
+	items, err := list.DirSorted(ctx, r.Fremote, true, "")
+	require.NoError(t, err)
+	assert.Len(t, items, 5)
 }
@@ -2,7 +2,10 @@ package operations
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 	"path"
+	"sort"
 	"time"
 
 	"github.com/pkg/errors"
@@ -27,6 +30,7 @@ type ListJSONItem struct {
 	OrigID        string            `json:",omitempty"`
 	Tier          string            `json:",omitempty"`
 	IsBucket      bool              `json:",omitempty"`
+	Metadata      fs.Metadata       `json:",omitempty"`
 }
 
 // Timestamp a time in the provided format
@@ -43,6 +47,46 @@ func (t Timestamp) MarshalJSON() (out []byte, err error) {
 	return []byte(`"` + t.When.Format(t.Format) + `"`), nil
 }
 
+// UnmarshalJSON turns JSON written by MarshalJSON back into a Timestamp
+func (t *Timestamp) UnmarshalJSON(in []byte) error {
+	var s string
+	if err := json.Unmarshal(in, &s); err != nil {
+		return err
+	}
+	t.Format = time.RFC3339Nano
+	if s == "" {
+		t.When = time.Time{}
+		return nil
+	}
+	when, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+	t.When = when
+	return nil
+}
+
+// equal returns whether t and other are the same time when written
+// out. Blank times are equal to any other time as they weren't read.
+func (t Timestamp) equal(other Timestamp) bool {
+	if t.When.IsZero() || other.When.IsZero() {
+		return true
+	}
+	return t.rounded().Equal(other.rounded())
+}
+
+// rounded returns the time to the precision it is written out with
+func (t Timestamp) rounded() time.Time {
+	if t.Format == "" {
+		return t.When
+	}
+	when, err := time.Parse(time.RFC3339Nano, t.When.Format(t.Format))
+	if err != nil {
+		return t.When
+	}
+	return when
+}
+
 // Returns a time format for the given precision
 func formatForPrecision(precision time.Duration) string {
 	switch {
@@ -79,43 +123,133 @@ type ListJSONOpt struct {
 	DirsOnly      bool     `json:"dirsOnly"`
 	FilesOnly     bool     `json:"filesOnly"`
 	HashTypes     []string `json:"hashTypes"` // hash types to show if ShowHash is set, e.g. "MD5", "SHA-1"
+	Metadata      bool     `json:"metadata"`
 }
 
-// ListJSON lists fsrc using the options in opt calling callback for each item
-func ListJSON(ctx context.Context, fsrc fs.Fs, remote string, opt *ListJSONOpt, callback func(*ListJSONItem) error) error {
-	var cipher *crypt.Cipher
+// listJSON holds the settings for turning entries into ListJSONItem
+type listJSON struct {
+	opt        *ListJSONOpt
+	cipher     *crypt.Cipher
+	canGetTier bool
+	format     string
+	isBucket   bool
+	showHash   bool
+	hashTypes  []hash.Type
+}
+
+// newListJSON makes a listJSON for listing remote in fsrc
+func newListJSON(fsrc fs.Fs, remote string, opt *ListJSONOpt) (*listJSON, error) {
+	lj := &listJSON{
+		opt: opt,
+	}
 	if opt.ShowEncrypted {
 		fsInfo, _, _, config, err := fs.ConfigFs(fsrc.Name() + ":" + fsrc.Root())
 		if err != nil {
-			return errors.Wrap(err, "ListJSON failed to load config for crypt remote")
+			return nil, errors.Wrap(err, "ListJSON failed to load config for crypt remote")
 		}
 		if fsInfo.Name != "crypt" {
-			return errors.New("The remote needs to be of type \"crypt\"")
+			return nil, errors.New("The remote needs to be of type \"crypt\"")
 		}
-		cipher, err = crypt.NewCipher(config)
+		lj.cipher, err = crypt.NewCipher(config)
 		if err != nil {
-			return errors.Wrap(err, "ListJSON failed to make new crypt remote")
+			return nil, errors.Wrap(err, "ListJSON failed to make new crypt remote")
 		}
 	}
 	features := fsrc.Features()
-	canGetTier := features.GetTier
-	format := formatForPrecision(fsrc.Precision())
-	isBucket := features.BucketBased && remote == "" && fsrc.Root() == "" // if bucket based remote listing the root mark directories as buckets
-	showHash := opt.ShowHash
-	hashTypes := fsrc.Hashes().Array()
+	lj.canGetTier = features.GetTier
+	lj.format = formatForPrecision(fsrc.Precision())
+	lj.isBucket = features.BucketBased && remote == "" && fsrc.Root() == "" // if bucket based remote listing the root mark directories as buckets
+	lj.showHash = opt.ShowHash
+	lj.hashTypes = fsrc.Hashes().Array()
 	if len(opt.HashTypes) != 0 {
-		showHash = true
-		hashTypes = []hash.Type{}
+		lj.showHash = true
+		lj.hashTypes = []hash.Type{}
 		for _, hashType := range opt.HashTypes {
 			var ht hash.Type
 			err := ht.Set(hashType)
 			if err != nil {
-				return err
+				return nil, err
 			}
-			hashTypes = append(hashTypes, ht)
+			lj.hashTypes = append(lj.hashTypes, ht)
 		}
 	}
-	err := walk.ListR(ctx, fsrc, remote, false, ConfigMaxDepth(ctx, opt.Recurse), walk.ListAll, func(entries fs.DirEntries) (err error) {
+	return lj, nil
+}
+
+// entry turns entry into a ListJSONItem
+func (lj *listJSON) entry(ctx context.Context, entry fs.DirEntry) (*ListJSONItem, error) {
+	var err error
+	item := &ListJSONItem{
+		Path: entry.Remote(),
+		Name: path.Base(entry.Remote()),
+		Size: entry.Size(),
+	}
+	if !lj.opt.NoModTime {
+		item.ModTime = Timestamp{When: entry.ModTime(ctx), Format: lj.format}
+	}
+	if !lj.opt.NoMimeType {
+		item.MimeType = fs.MimeTypeDirEntry(ctx, entry)
+	}
+	if lj.cipher != nil {
+		switch entry.(type) {
+		case fs.Directory:
+			item.EncryptedPath = lj.cipher.EncryptDirName(entry.Remote())
+		case fs.Object:
+			item.EncryptedPath = lj.cipher.EncryptFileName(entry.Remote())
+		default:
+			fs.Errorf(nil, "Unknown type %T in listing", entry)
+		}
+		item.Encrypted = path.Base(item.EncryptedPath)
+	}
+	if do, ok := entry.(fs.IDer); ok {
+		item.ID = do.ID()
+	}
+	if o, ok := entry.(fs.Object); lj.opt.ShowOrigIDs && ok {
+		if do, ok := fs.UnWrapObject(o).(fs.IDer); ok {
+			item.OrigID = do.ID()
+		}
+	}
+	switch x := entry.(type) {
+	case fs.Directory:
+		item.IsDir = true
+		item.IsBucket = lj.isBucket
+	case fs.Object:
+		item.IsDir = false
+		if lj.showHash {
+			item.Hashes = make(map[string]string)
+			for _, hashType := range lj.hashTypes {
+				hash, err := x.Hash(ctx, hashType)
+				if err != nil {
+					fs.Errorf(x, "Failed to read hash: %v", err)
+				} else if hash != "" {
+					item.Hashes[hashType.String()] = hash
+				}
+			}
+		}
+		if lj.canGetTier {
+			if do, ok := x.(fs.GetTierer); ok {
+				item.Tier = do.GetTier()
+			}
+		}
+		if lj.opt.Metadata {
+			item.Metadata, err = fs.GetMetadata(ctx, x)
+			if err != nil {
+				fs.Errorf(x, "Failed to read metadata: %v", err)
+			}
+		}
+	default:
+		fs.Errorf(nil, "Unknown type %T in listing in ListJSON", entry)
+	}
+	return item, nil
+}
+
+// ListJSON lists fsrc using the options in opt calling callback for each item
+func ListJSON(ctx context.Context, fsrc fs.Fs, remote string, opt *ListJSONOpt, callback func(*ListJSONItem) error) error {
+	lj, err := newListJSON(fsrc, remote, opt)
+	if err != nil {
+		return err
+	}
+	err = walk.ListR(ctx, fsrc, remote, false, ConfigMaxDepth(ctx, opt.Recurse), walk.ListAll, func(entries fs.DirEntries) (err error) {
 		for _, entry := range entries {
 			switch entry.(type) {
 			case fs.Directory:
@@ -129,63 +263,11 @@ func ListJSON(ctx context.Context, fsrc fs.Fs, remote string, opt *ListJSONOpt,
 			default:
 				fs.Errorf(nil, "Unknown type %T in listing", entry)
 			}
-
-			item := ListJSONItem{
-				Path: entry.Remote(),
-				Name: path.Base(entry.Remote()),
-				Size: entry.Size(),
-			}
-			if !opt.NoModTime {
-				item.ModTime = Timestamp{When: entry.ModTime(ctx), Format: format}
-			}
-			if !opt.NoMimeType {
-				item.MimeType = fs.MimeTypeDirEntry(ctx, entry)
-			}
-			if cipher != nil {
-				switch entry.(type) {
-				case fs.Directory:
-					item.EncryptedPath = cipher.EncryptDirName(entry.Remote())
-				case fs.Object:
-					item.EncryptedPath = cipher.EncryptFileName(entry.Remote())
-				default:
-					fs.Errorf(nil, "Unknown type %T in listing", entry)
-				}
-				item.Encrypted = path.Base(item.EncryptedPath)
-			}
-			if do, ok := entry.(fs.IDer); ok {
-				item.ID = do.ID()
-			}
-			if o, ok := entry.(fs.Object); opt.ShowOrigIDs && ok {
-				if do, ok := fs.UnWrapObject(o).(fs.IDer); ok {
-					item.OrigID = do.ID()
-				}
-			}
-			switch x := entry.(type) {
-			case fs.Directory:
-				item.IsDir = true
-				item.IsBucket = isBucket
-			case fs.Object:
-				item.IsDir = false
-				if showHash {
-					item.Hashes = make(map[string]string)
-					for _, hashType := range hashTypes {
-						hash, err := x.Hash(ctx, hashType)
-						if err != nil {
-							fs.Errorf(x, "Failed to read hash: %v", err)
-						} else if hash != "" {
-							item.Hashes[hashType.String()] = hash
-						}
-					}
-				}
-				if canGetTier {
-					if do, ok := x.(fs.GetTierer); ok {
-						item.Tier = do.GetTier()
-					}
-				}
-			default:
-				fs.Errorf(nil, "Unknown type %T in listing in ListJSON", entry)
+			item, err := lj.entry(ctx, entry)
+			if err != nil {
+				return err
 			}
-			err = callback(&item)
+			err = callback(item)
 			if err != nil {
 				return errors.Wrap(err, "callback failed in ListJSON")
 			}
@@ -198,3 +280,148 @@ func ListJSON(ctx context.Context, fsrc fs.Fs, remote string, opt *ListJSONOpt,
 	}
 	return nil
 }
+
+// StatJSON returns a ListJSONItem for remote in fsrc using the
+// options in opt or nil if it isn't found.
+//
+// A file is found with NewObject so its parent directory isn't
+// listed. The parent directory is only listed to find a directory.
+// If remote is "" then an item for the root of fsrc is returned.
+func StatJSON(ctx context.Context, fsrc fs.Fs, remote string, opt *ListJSONOpt) (*ListJSONItem, error) {
+	if remote == "" {
+		if opt.FilesOnly {
+			return nil, nil
+		}
+		return &ListJSONItem{
+			Path:  "",
+			Name:  "",
+			IsDir: true,
+		}, nil
+	}
+	parent := path.Dir(remote)
+	if parent == "." || parent == "/" {
+		parent = ""
+	}
+	lj, err := newListJSON(fsrc, parent, opt)
+	if err != nil {
+		return nil, err
+	}
+	if !opt.DirsOnly {
+		o, err := fsrc.NewObject(ctx, remote)
+		switch errors.Cause(err) {
+		case nil:
+			return lj.entry(ctx, o)
+		case fs.ErrorObjectNotFound, fs.ErrorNotAFile:
+		default:
+			return nil, errors.Wrap(err, "error in StatJSON")
+		}
+	}
+	if opt.FilesOnly {
+		return nil, nil
+	}
+	entries, err := fsrc.List(ctx, parent)
+	if err == fs.ErrorDirNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "error in StatJSON")
+	}
+	for _, entry := range entries {
+		if dir, ok := entry.(fs.Directory); ok && dir.Remote() == remote {
+			return lj.entry(ctx, dir)
+		}
+	}
+	return nil, nil
+}
+
+// Changes found by ListJSONDiff
+const (
+	ListJSONAdded   = "added"
+	ListJSONRemoved = "removed"
+	ListJSONChanged = "changed"
+)
+
+// ListJSONDiffItem is an item which ListJSONDiff found had changed
+//
+// It has the fields of the current ListJSONItem, or the old one if
+// it was removed, and for changed items the Old one too.
+type ListJSONDiffItem struct {
+	Change string
+	ListJSONItem
+	Old *ListJSONItem `json:",omitempty"`
+}
+
+// ReadListJSON reads a listing written by lsjson from in
+func ReadListJSON(in io.Reader) (items []*ListJSONItem, err error) {
+	err = json.NewDecoder(in).Decode(&items)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read lsjson listing")
+	}
+	return items, nil
+}
+
+// listJSONChanged returns whether item has changed from old.
+//
+// Files have changed if their size, modification time or any hash in
+// both has changed. Directories have only changed if they are now a
+// file.
+func listJSONChanged(old, item *ListJSONItem) bool {
+	if old.IsDir != item.IsDir {
+		return true
+	}
+	if item.IsDir {
+		return false
+	}
+	if old.Size != item.Size || !old.ModTime.equal(item.ModTime) {
+		return true
+	}
+	for hashType, hash := range item.Hashes {
+		if oldHash, found := old.Hashes[hashType]; found && oldHash != hash {
+			return true
+		}
+	}
+	return false
+}
+
+// ListJSONDiff lists fsrc using the options in opt like ListJSON,
+// comparing it with the old listing and calling callback for each
+// item which has been added, removed or changed since.
+//
+// The removed items are found after the listing is complete so are
+// returned last, sorted by Path.
+func ListJSONDiff(ctx context.Context, fsrc fs.Fs, remote string, opt *ListJSONOpt, old []*ListJSONItem, callback func(*ListJSONDiffItem) error) error {
+	oldItems := make(map[string]*ListJSONItem, len(old))
+	for _, item := range old {
+		oldItems[item.Path] = item
+	}
+	err := ListJSON(ctx, fsrc, remote, opt, func(item *ListJSONItem) error {
+		oldItem, found := oldItems[item.Path]
+		delete(oldItems, item.Path)
+		switch {
+		case !found:
+			return callback(&ListJSONDiffItem{Change: ListJSONAdded, ListJSONItem: *item})
+		case listJSONChanged(oldItem, item):
+			return callback(&ListJSONDiffItem{Change: ListJSONChanged, ListJSONItem: *item, Old: oldItem})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	removed := make([]*ListJSONItem, 0, len(oldItems))
+	for _, item := range oldItems {
+		if (opt.FilesOnly && item.IsDir) || (opt.DirsOnly && !item.IsDir) {
+			continue
+		}
+		removed = append(removed, item)
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].Path < removed[j].Path
+	})
+	for _, item := range removed {
+		err = callback(&ListJSONDiffItem{Change: ListJSONRemoved, ListJSONItem: *item})
+		if err != nil {
+			return errors.Wrap(err, "callback failed in ListJSONDiff")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package operations_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListJSONDiff(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt := &operations.ListJSONOpt{Recurse: true, ShowHash: true}
+
+	r.WriteObject(ctx, "same", "same contents", t1)
+	r.WriteObject(ctx, "dir/changed", "old contents", t1)
+	r.WriteObject(ctx, "removed", "removed contents", t1)
+
+	// save a listing the way lsjson writes it and read it back
+	var saved []*operations.ListJSONItem
+	err := operations.ListJSON(ctx, r.Fremote, "", opt, func(item *operations.ListJSONItem) error {
+		saved = append(saved, item)
+		return nil
+	})
+	require.NoError(t, err)
+	data, err := json.Marshal(saved)
+	require.NoError(t, err)
+	old, err := operations.ReadListJSON(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, old, len(saved))
+
+	// nothing has changed yet
+	var changes []*operations.ListJSONDiffItem
+	diff := func() {
+		changes = nil
+		err := operations.ListJSONDiff(ctx, r.Fremote, "", opt, old, func(item *operations.ListJSONDiffItem) error {
+			changes = append(changes, item)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	diff()
+	assert.Len(t, changes, 0)
+
+	r.WriteObject(ctx, "dir/changed", "new contents!", t2)
+	r.WriteObject(ctx, "added", "added contents", t1)
+	removed, err := r.Fremote.NewObject(ctx, "removed")
+	require.NoError(t, err)
+	require.NoError(t, removed.Remove(ctx))
+
+	diff()
+	require.Len(t, changes, 3)
+	got := map[string]string{}
+	for _, change := range changes {
+		got[change.Path] = change.Change
+	}
+	assert.Equal(t, map[string]string{
+		"added":       operations.ListJSONAdded,
+		"dir/changed": operations.ListJSONChanged,
+		"removed":     operations.ListJSONRemoved,
+	}, got)
+	assert.Equal(t, "removed", changes[2].Path, "removed items come last")
+	for _, change := range changes {
+		if change.Change == operations.ListJSONChanged {
+			require.NotNil(t, change.Old)
+			assert.Equal(t, int64(12), change.Old.Size)
+			assert.Equal(t, int64(13), change.Size)
+		}
+	}
+
+	// the diff items marshal with the Change next to the item fields
+	out, err := json.Marshal(changes[2])
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `{"Change":"removed","Path":"removed","Name":"removed",`)
+}
@@ -2,6 +2,7 @@
 package operations
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/base64"
@@ -10,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"os"
 	"path"
@@ -30,6 +32,7 @@ import (
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/fshttp"
 	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/list"
 	"github.com/rclone/rclone/fs/object"
 	"github.com/rclone/rclone/fs/walk"
 	"github.com/rclone/rclone/lib/atexit"
@@ -116,7 +119,10 @@ func checkHashes(ctx context.Context, src fs.ObjectInfo, dst fs.Object, ht hash.
 // If the size is the same and mtime is different, unreadable or
 // --checksum is set and the hash is the same then the file is
 // considered to be equal.  In this case the mtime on the dst is
-// updated if --checksum is not set.
+// updated if --checksum is not set or --refresh-times is set.
+//
+// If --refresh-metadata is set then the metadata of a dst with the
+// same hash as the src is updated too.
 //
 // Otherwise the file is considered to be not equal including if there
 // were errors reading info.
@@ -195,6 +201,14 @@ func equal(ctx context.Context, src fs.ObjectInfo, dst fs.Object, opt equalOpt)
 			fs.Debugf(src, "Size of src and dst objects identical")
 		} else {
 			fs.Debugf(src, "Size and %v of src and dst objects identical", ht)
+			// The contents are the same so bring the modtime
+			// and metadata up to date if required
+			if ci.RefreshTimes && opt.updateModTime && !modTimesEqual(ctx, src, dst) {
+				if !updateModTime(ctx, src, dst, src.ModTime(ctx)) {
+					return false
+				}
+			}
+			refreshMetadata(ctx, src, dst)
 		}
 		return true
 	}
@@ -211,6 +225,11 @@ func equal(ctx context.Context, src fs.ObjectInfo, dst fs.Object, opt equalOpt)
 		dt := dstModTime.Sub(srcModTime)
 		if dt < modifyWindow && dt > -modifyWindow {
 			fs.Debugf(src, "Size and modification time the same (differ by %s, within tolerance %s)", dt, modifyWindow)
+			if ci.RefreshMetadata {
+				if same, ht, _ := CheckHashes(ctx, src, dst); same && ht != hash.None {
+					refreshMetadata(ctx, src, dst)
+				}
+			}
 			return true
 		}
 
@@ -229,43 +248,91 @@ func equal(ctx context.Context, src fs.ObjectInfo, dst fs.Object, opt equalOpt)
 	}
 
 	// mod time differs but hash is the same to reset mod time if required
-	if opt.updateModTime {
-		if !SkipDestructive(ctx, src, "update modification time") {
-			// Size and hash the same but mtime different
-			// Error if objects are treated as immutable
-			if ci.Immutable {
-				fs.Errorf(dst, "Timestamp mismatch between immutable objects")
-				return false
-			}
-			// Update the mtime of the dst object here
-			err := dst.SetModTime(ctx, srcModTime)
-			if err == fs.ErrorCantSetModTime {
-				logModTimeUpload(dst)
-				fs.Infof(dst, "src and dst identical but can't set mod time without re-uploading")
-				return false
-			} else if err == fs.ErrorCantSetModTimeWithoutDelete {
-				logModTimeUpload(dst)
-				fs.Infof(dst, "src and dst identical but can't set mod time without deleting and re-uploading")
-				// Remove the file if BackupDir isn't set.  If BackupDir is set we would rather have the old file
-				// put in the BackupDir than deleted which is what will happen if we don't delete it.
-				if ci.BackupDir == "" {
-					err = dst.Remove(ctx)
-					if err != nil {
-						fs.Errorf(dst, "failed to delete before re-upload: %v", err)
-					}
-				}
-				return false
-			} else if err != nil {
-				err = fs.CountError(err)
-				fs.Errorf(dst, "Failed to set modification time: %v", err)
-			} else {
-				fs.Infof(src, "Updated modification time in destination")
+	if opt.updateModTime && !updateModTime(ctx, src, dst, srcModTime) {
+		return false
+	}
+	if ht != hash.None {
+		refreshMetadata(ctx, src, dst)
+	}
+	return true
+}
+
+// modTimesEqual returns whether the modtimes of src and dst are
+// the same within the modify window
+func modTimesEqual(ctx context.Context, src fs.ObjectInfo, dst fs.Object) bool {
+	modifyWindow := fs.GetModifyWindow(ctx, src.Fs(), dst.Fs())
+	if modifyWindow == fs.ModTimeNotSupported {
+		return true
+	}
+	dt := dst.ModTime(ctx).Sub(src.ModTime(ctx))
+	return dt < modifyWindow && dt > -modifyWindow
+}
+
+// updateModTime sets the modtime of dst to srcModTime as src and dst
+// have the same contents.
+//
+// It returns false if dst needs uploading again to set it.
+func updateModTime(ctx context.Context, src fs.ObjectInfo, dst fs.Object, srcModTime time.Time) bool {
+	ci := fs.GetConfig(ctx)
+	if SkipDestructive(ctx, src, "update modification time") {
+		return true
+	}
+	// Size and hash the same but mtime different
+	// Error if objects are treated as immutable
+	if ci.Immutable {
+		fs.Errorf(dst, "Timestamp mismatch between immutable objects")
+		return false
+	}
+	// Update the mtime of the dst object here
+	err := dst.SetModTime(ctx, srcModTime)
+	if err == fs.ErrorCantSetModTime {
+		logModTimeUpload(dst)
+		fs.Infof(dst, "src and dst identical but can't set mod time without re-uploading")
+		return false
+	} else if err == fs.ErrorCantSetModTimeWithoutDelete {
+		logModTimeUpload(dst)
+		fs.Infof(dst, "src and dst identical but can't set mod time without deleting and re-uploading")
+		// Remove the file if BackupDir isn't set.  If BackupDir is set we would rather have the old file
+		// put in the BackupDir than deleted which is what will happen if we don't delete it.
+		if ci.BackupDir == "" {
+			err = dst.Remove(ctx)
+			if err != nil {
+				fs.Errorf(dst, "failed to delete before re-upload: %v", err)
 			}
 		}
+		return false
+	} else if err != nil {
+		err = fs.CountError(err)
+		fs.Errorf(dst, "Failed to set modification time: %v", err)
+	} else {
+		fs.Infof(src, "Updated modification time in destination")
 	}
 	return true
 }
 
+// refreshMetadata sets the metadata of dst to that of src if
+// --refresh-metadata is in use, as src and dst have the same
+// contents.
+func refreshMetadata(ctx context.Context, src fs.ObjectInfo, dst fs.Object) {
+	if !fs.GetConfig(ctx).RefreshMetadata {
+		return
+	}
+	changed, err := changedMetadata(ctx, src, dst)
+	if err == nil && len(changed) > 0 {
+		if SkipDestructive(ctx, src, "update metadata") {
+			return
+		}
+		err = setMetadata(ctx, dst, changed)
+		if err == nil {
+			fs.Infof(src, "Updated metadata in destination")
+		}
+	}
+	if err != nil {
+		err = fs.CountError(err)
+		fs.Errorf(dst, "Failed to update metadata: %v", err)
+	}
+}
+
 // Used to remove a failed copy
 //
 // Returns whether the file was successfully removed or not
@@ -282,6 +349,76 @@ func removeFailedCopy(ctx context.Context, dst fs.Object) bool {
 	return true
 }
 
+// useAtomicUpload returns whether uploads to f should be made to a
+// temporary name and renamed when complete for --atomic-upload.
+//
+// This needs a server-side move and isn't needed on bucket based
+// remotes where objects only appear once they are complete.
+func useAtomicUpload(ctx context.Context, f fs.Fs) bool {
+	if !fs.GetConfig(ctx).AtomicUpload {
+		return false
+	}
+	features := f.Features()
+	return features.Move != nil && !features.BucketBased
+}
+
+// partialName returns the temporary name to upload remote to
+func partialName(ctx context.Context, remote string) string {
+	return remote + "." + random.String(8) + fs.GetConfig(ctx).PartialSuffix
+}
+
+// removePartial removes whatever is left of a failed upload to remote
+func removePartial(ctx context.Context, f fs.Fs, remote string) {
+	o, err := f.NewObject(ctx, remote)
+	if err == nil {
+		removeFailedCopy(ctx, o)
+	}
+}
+
+// renamePartial moves the complete upload partial to remote replacing
+// existing if it isn't nil.
+//
+// If it fails then the partial is removed and old is what is left at
+// remote, which is existing put back or nil if that couldn't be done.
+func renamePartial(ctx context.Context, f fs.Fs, partial, existing fs.Object, remote string) (dst, old fs.Object, err error) {
+	move := f.Features().Move
+	// Renaming onto an existing file replaces it atomically on
+	// local disks but may fail or make a duplicate elsewhere, so
+	// move the existing file out of the way first and put it back
+	// if the rename fails
+	var backup fs.Object
+	if existing != nil && !f.Features().IsLocal {
+		backup, err = move(ctx, existing, partialName(ctx, remote))
+		if err != nil {
+			removeFailedCopy(ctx, partial)
+			return nil, existing, errors.Wrap(err, "failed to move existing file out of the way")
+		}
+		fs.Debugf(backup, "Moved existing file out of the way")
+	}
+	dst, err = move(ctx, partial, remote)
+	if err != nil {
+		removeFailedCopy(ctx, partial)
+		if backup == nil {
+			return nil, existing, err
+		}
+		var moveBackErr error
+		old, moveBackErr = move(ctx, backup, remote)
+		if moveBackErr != nil {
+			fs.Errorf(backup, "Failed to move existing file back to %q - it has been left here: %v", remote, moveBackErr)
+			return nil, nil, err
+		}
+		return nil, old, err
+	}
+	fs.Debugf(dst, "Renamed from partial upload %q", partial.Remote())
+	if backup != nil {
+		err = backup.Remove(ctx)
+		if err != nil {
+			fs.Errorf(backup, "Failed to remove replaced file: %v", err)
+		}
+	}
+	return dst, nil, nil
+}
+
 // OverrideRemote is a wrapper to override the Remote for an
 // ObjectInfo
 type OverrideRemote struct {
@@ -337,6 +474,11 @@ func (o *OverrideRemote) GetTier() string {
 	return ""
 }
 
+// Metadata returns the metadata of the Object if known, or nil if not
+func (o *OverrideRemote) Metadata(ctx context.Context) (fs.Metadata, error) {
+	return fs.GetMetadata(ctx, o.ObjectInfo)
+}
+
 // Check all optional interfaces satisfied
 var _ fs.FullObjectInfo = (*OverrideRemote)(nil)
 
@@ -357,6 +499,21 @@ func CommonHash(ctx context.Context, fa, fb fs.Info) (hash.Type, *fs.HashesOptio
 	return hashType, &fs.HashesOption{Hashes: common}
 }
 
+// estimateTransfer accounts for src in the --estimate stats of a
+// --dry-run copy or move to f, working out whether it would have been
+// done server-side or streamed
+func estimateTransfer(ctx context.Context, f fs.Fs, src fs.Object, move bool) {
+	ci := fs.GetConfig(ctx)
+	if !ci.Estimate {
+		return
+	}
+	features := f.Features()
+	sameRemote := SameConfig(src.Fs(), f) || (SameRemoteType(src.Fs(), f) && features.ServerSideAcrossConfigs)
+	canCopy := features.Copy != nil && len(ci.TransferFilterCmd) == 0
+	canMove := move && features.Move != nil
+	accounting.Stats(ctx).Estimated(sameRemote && (canCopy || canMove), src.Size())
+}
+
 // Copy src object to dst or f if nil.  If dst is nil then it uses
 // remote as the name of the new object.
 //
@@ -364,6 +521,7 @@ func CommonHash(ctx context.Context, fa, fb fs.Info) (hash.Type, *fs.HashesOptio
 // be nil.
 func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
 	ci := fs.GetConfig(ctx)
+	start := time.Now()
 	tr := accounting.Stats(ctx).NewTransfer(src)
 	defer func() {
 		tr.Done(ctx, err)
@@ -372,14 +530,40 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 	if SkipDestructive(ctx, src, "copy") {
 		in := tr.Account(ctx, nil)
 		in.DryRun(src.Size())
+		estimateTransfer(ctx, f, src, false)
 		return newDst, nil
 	}
+	defer func() {
+		postTransferHook(ctx, f, remote, src, err)
+	}()
+	var actionTaken string
+	defer func() {
+		reportTransfer(ctx, ReportCopy, f, remote, src, start, actionTaken, err)
+	}()
+	if err = preTransferHook(ctx, f, remote, src); err != nil {
+		fs.Errorf(src, "Not copying: %v", err)
+		return newDst, err
+	}
+	if err = scanTransfer(ctx, remote, src); err != nil {
+		fs.Errorf(src, "Not copying: %v", err)
+		return newDst, err
+	}
 	maxTries := ci.LowLevelRetries
 	tries := 0
 	doUpdate := dst != nil
 	hashType, hashOption := CommonHash(ctx, f, src.Fs())
+	// Upload to a temporary name and rename it into place when
+	// complete if required
+	existing := dst
+	uploadRemote := remote
+	atomic := useAtomicUpload(ctx, f)
+	if atomic {
+		uploadRemote = partialName(ctx, remote)
+	}
+	uploadedPartial := false
+	// Transform the data through the --transfer-filter-cmd if set
+	filtered := len(ci.TransferFilterCmd) != 0
 
-	var actionTaken string
 	for {
 		// Try server-side copy first - if has optional interface and
 		// is same underlying remote
@@ -398,10 +582,14 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 				return nil, accounting.ErrorMaxTransferLimitReachedGraceful
 			}
 		}
-		if doCopy := f.Features().Copy; doCopy != nil && (SameConfig(src.Fs(), f) || (SameRemoteType(src.Fs(), f) && f.Features().ServerSideAcrossConfigs)) {
+		if doCopy := f.Features().Copy; doCopy != nil && !filtered && (SameConfig(src.Fs(), f) || (SameRemoteType(src.Fs(), f) && f.Features().ServerSideAcrossConfigs)) {
 			in := tr.Account(ctx, nil) // account the transfer
 			in.ServerSideCopyStart()
-			newDst, err = doCopy(ctx, src, remote)
+			var srcObj fs.Object
+			srcObj, err = list.Resolve(ctx, src)
+			if err == nil {
+				newDst, err = doCopy(ctx, srcObj, remote)
+			}
 			if err == nil {
 				dst = newDst
 				in.ServerSideCopyEnd(dst.Size()) // account the bytes for the server-side transfer
@@ -417,7 +605,7 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 		}
 		// If can't server-side copy, do it manually
 		if err == fs.ErrorCantCopy {
-			if doMultiThreadCopy(ctx, f, src) {
+			if !filtered && doMultiThreadCopy(ctx, f, src) {
 				// Number of streams proportional to size
 				streams := src.Size() / int64(ci.MultiThreadCutoff)
 				// With maximum
@@ -427,7 +615,8 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 				if streams < 2 {
 					streams = 2
 				}
-				dst, err = multiThreadCopy(ctx, f, remote, src, int(streams), tr)
+				dst, err = multiThreadCopy(ctx, f, uploadRemote, src, int(streams), tr)
+				uploadedPartial = atomic
 				if doUpdate {
 					actionTaken = "Multi-thread Copied (replaced existing)"
 				} else {
@@ -442,6 +631,23 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 				in0, err = NewReOpen(ctx, src, ci.LowLevelRetries, options...)
 				if err != nil {
 					err = errors.Wrap(err, "failed to open source object")
+				} else if filtered {
+					// The size of the output of the filter isn't known so use Rcat
+					if doUpdate {
+						actionTaken = "Copied (filtered, replaced existing)"
+					} else {
+						actionTaken = "Copied (filtered, new)"
+					}
+					var filterIn io.ReadCloser
+					filterIn, err = newFilterCmdReader(ctx, ci.TransferFilterCmd, in0, newTransferHookInfo("filter", f, remote, src, nil))
+					if err != nil {
+						_ = in0.Close()
+					} else {
+						// NB Rcat closes filterIn which closes in0
+						dst, err = Rcat(withoutTransferHooks(ctx), f, uploadRemote, filterIn, src.ModTime(ctx))
+						newDst = dst
+						uploadedPartial = atomic
+					}
 				} else {
 					if src.Size() == -1 {
 						// -1 indicates unknown size. Use Rcat to handle both remotes supporting and not supporting PutStream.
@@ -451,14 +657,15 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 							actionTaken = "Copied (Rcat, new)"
 						}
 						// NB Rcat closes in0
-						dst, err = Rcat(ctx, f, remote, in0, src.ModTime(ctx))
+						dst, err = Rcat(withoutTransferHooks(ctx), f, uploadRemote, in0, src.ModTime(ctx))
 						newDst = dst
+						uploadedPartial = atomic
 					} else {
 						in := tr.Account(ctx, in0).WithBuffer() // account and buffer the transfer
 						var wrappedSrc fs.ObjectInfo = src
 						// We try to pass the original object if possible
-						if src.Remote() != remote {
-							wrappedSrc = NewOverrideRemote(src, remote)
+						if src.Remote() != uploadRemote {
+							wrappedSrc = NewOverrideRemote(src, uploadRemote)
 						}
 						options := []fs.OpenOption{hashOption}
 						for _, option := range ci.UploadHeaders {
@@ -466,10 +673,14 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 						}
 						if doUpdate {
 							actionTaken = "Copied (replaced existing)"
-							err = dst.Update(ctx, in, wrappedSrc, options...)
 						} else {
 							actionTaken = "Copied (new)"
+						}
+						if doUpdate && !atomic {
+							err = dst.Update(ctx, in, wrappedSrc, options...)
+						} else {
 							dst, err = f.Put(ctx, in, wrappedSrc, options...)
+							uploadedPartial = atomic
 						}
 						closeErr := in.Close()
 						if err == nil {
@@ -480,6 +691,26 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 				}
 			}
 		}
+		// Read the file back and check it if --verify is set,
+		// transferring it again if it doesn't match
+		if err == nil && ci.Verify && !filtered {
+			var ok bool
+			ok, err = verifyCopy(ctx, f, dst.Remote(), src)
+			if err != nil {
+				err = errors.Wrap(err, "failed to verify copy")
+			} else if !ok {
+				accounting.Stats(ctx).VerifyFailures(1)
+				err = fserrors.RetryErrorf("corrupted on transfer: verify failed")
+				fs.Errorf(dst, "%v", err)
+				removeFailedCopy(ctx, dst)
+				dst, newDst, doUpdate = nil, nil, false
+				if atomic {
+					dst, newDst, doUpdate = existing, existing, existing != nil
+				}
+			} else {
+				accounting.Stats(ctx).Verifies(1)
+			}
+		}
 		tries++
 		if tries >= maxTries {
 			break
@@ -505,22 +736,30 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 		break
 	}
 	if err != nil {
+		if uploadedPartial {
+			removePartial(ctx, f, uploadRemote)
+			newDst = existing
+		}
 		err = fs.CountError(err)
 		fs.Errorf(src, "Failed to copy: %v", err)
 		return newDst, err
 	}
 
-	// Verify sizes are the same after transfer
-	if sizeDiffers(ctx, src, dst) {
+	// Verify sizes are the same after transfer - the filter changes
+	// the data so Rcat has checked it instead
+	if !filtered && sizeDiffers(ctx, src, dst) {
 		err = errors.Errorf("corrupted on transfer: sizes differ %d vs %d", src.Size(), dst.Size())
 		fs.Errorf(dst, "%v", err)
 		err = fs.CountError(err)
 		removeFailedCopy(ctx, dst)
+		if uploadedPartial {
+			newDst = existing
+		}
 		return newDst, err
 	}
 
 	// Verify hashes are the same after transfer - ignoring blank hashes
-	if hashType != hash.None {
+	if hashType != hash.None && !filtered {
 		// checkHashes has logged and counted errors
 		equal, _, srcSum, dstSum, _ := checkHashes(ctx, src, dst, hashType)
 		if !equal {
@@ -528,17 +767,147 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 			fs.Errorf(dst, "%v", err)
 			err = fs.CountError(err)
 			removeFailedCopy(ctx, dst)
+			if uploadedPartial {
+				newDst = existing
+			}
+			return newDst, err
+		}
+	}
+	if uploadedPartial {
+		var old fs.Object
+		dst, old, err = renamePartial(ctx, f, dst, existing, remote)
+		if err != nil {
+			err = fs.CountError(err)
+			fs.Errorf(src, "Failed to rename partial upload %q: %v", uploadRemote, err)
+			return old, err
+		}
+		newDst = dst
+	}
+	if ci.Metadata {
+		err = copyMetadata(ctx, src, dst)
+		if err != nil {
+			err = fs.CountError(err)
+			fs.Errorf(dst, "Failed to copy metadata: %v", err)
 			return newDst, err
 		}
 	}
+	operation, duration := fs.LogValueHide("operation", "copy"), fs.LogValueHide("duration", time.Since(start).Seconds())
 	if newDst != nil && src.String() != newDst.String() {
-		fs.Infof(src, "%s to: %s", actionTaken, newDst.String())
+		fs.Infof(src, "%s to: %s%v%v", actionTaken, newDst.String(), operation, duration)
 	} else {
-		fs.Infof(src, actionTaken)
+		fs.Infof(src, "%s%v%v", actionTaken, operation, duration)
 	}
 	return newDst, err
 }
 
+// verifyCopy reads the object at remote back from f and checks it
+// is the same as src for --verify.
+//
+// If f has a hash in common with src the backend is asked for the
+// hash of the object, otherwise it is downloaded and hashed, or
+// compared with src if src has no hashes.
+func verifyCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object) (ok bool, err error) {
+	dst, err := f.NewObject(ctx, remote)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to read back object")
+	}
+	if sizeDiffers(ctx, src, dst) {
+		fs.Debugf(dst, "Verify: sizes differ %d vs %d", src.Size(), dst.Size())
+		return false, nil
+	}
+	ht := src.Fs().Hashes().Overlap(f.Hashes()).GetOne()
+	download := ht == hash.None
+	if download {
+		ht = src.Fs().Hashes().GetOne()
+	}
+	srcHash := ""
+	if ht != hash.None {
+		srcHash, err = src.Hash(ctx, ht)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to read source hash")
+		}
+	}
+	if srcHash == "" {
+		differ, err := checkIdenticalDownload(ctx, dst, src)
+		if err != nil {
+			return false, err
+		}
+		return !differ, nil
+	}
+	dstHash := ""
+	if !download {
+		dstHash, err = dst.Hash(ctx, ht)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to read destination hash")
+		}
+	}
+	if dstHash == "" {
+		sums, err := hashSums(ctx, []hash.Type{ht}, true, dst)
+		if err != nil {
+			return false, err
+		}
+		dstHash = sums[0]
+	}
+	if srcHash != dstHash {
+		fs.Debugf(dst, "Verify: %v differ %q vs %q", ht, srcHash, dstHash)
+		return false, nil
+	}
+	fs.Debugf(dst, "Verify: %v = %s OK", ht, dstHash)
+	return true, nil
+}
+
+// copyMetadata sets the metadata of dst to that of src if --metadata
+// is in use.
+//
+// Only the keys which differ are set. It is not an error if either
+// object doesn't support metadata or dst can't store some of the keys.
+func copyMetadata(ctx context.Context, src fs.ObjectInfo, dst fs.Object) error {
+	changed, err := changedMetadata(ctx, src, dst)
+	if err != nil || len(changed) == 0 {
+		return err
+	}
+	return setMetadata(ctx, dst, changed)
+}
+
+// changedMetadata returns the keys of the metadata of src which are
+// missing or different in the metadata of dst, or none if dst can't
+// store metadata.
+func changedMetadata(ctx context.Context, src fs.ObjectInfo, dst fs.Object) (fs.Metadata, error) {
+	srcMeta, err := fs.GetMetadata(ctx, src)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read source metadata")
+	}
+	if len(srcMeta) == 0 {
+		return nil, nil
+	}
+	if _, ok := dst.(fs.SetMetadataer); !ok {
+		fs.Debugf(dst, "Can't copy metadata as the destination doesn't support it")
+		return nil, nil
+	}
+	dstMeta, err := fs.GetMetadata(ctx, dst)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read destination metadata")
+	}
+	changed := fs.Metadata{}
+	for key, value := range srcMeta {
+		if current, ok := dstMeta[key]; !ok || current != value {
+			changed[key] = value
+		}
+	}
+	return changed, nil
+}
+
+// setMetadata stores the keys in changed on dst which must support
+// SetMetadata. It is not an error if dst can't store some of them.
+func setMetadata(ctx context.Context, dst fs.Object, changed fs.Metadata) error {
+	err := dst.(fs.SetMetadataer).SetMetadata(ctx, changed)
+	if errors.Cause(err) == fs.ErrorCantSetMetadata {
+		fs.Debugf(dst, "Metadata not fully copied: %v", err)
+		return nil
+	}
+	return err
+}
+
 // SameObject returns true if src and dst could be pointing to the
 // same object.
 func SameObject(src, dst fs.Object) bool {
@@ -586,6 +955,7 @@ func Move(ctx context.Context, fdst fs.Fs, dst fs.Object, remote string, src fs.
 	if SkipDestructive(ctx, src, "move") {
 		in := tr.Account(ctx, nil)
 		in.DryRun(src.Size())
+		estimateTransfer(ctx, fdst, src, true)
 		return newDst, nil
 	}
 	// See if we have Move available
@@ -598,13 +968,26 @@ func Move(ctx context.Context, fdst fs.Fs, dst fs.Object, remote string, src fs.
 			}
 		}
 		// Move dst <- src
-		newDst, err = doMove(ctx, src, remote)
+		srcObj, err := list.Resolve(ctx, src)
+		if err != nil {
+			return newDst, err
+		}
+		start := time.Now()
+		newDst, err = doMove(ctx, srcObj, remote)
+		if err != fs.ErrorCantMove {
+			action := ReportMove
+			if Same(src.Fs(), fdst) {
+				action = ReportRename
+			}
+			reportTransfer(ctx, action, fdst, remote, src, start, "Moved (server-side)", err)
+		}
 		switch err {
 		case nil:
+			operation, duration := fs.LogValueHide("operation", "move"), fs.LogValueHide("duration", time.Since(start).Seconds())
 			if newDst != nil && src.String() != newDst.String() {
-				fs.Infof(src, "Moved (server-side) to: %s", newDst.String())
+				fs.Infof(src, "Moved (server-side) to: %s%v%v", newDst.String(), operation, duration)
 			} else {
-				fs.Infof(src, "Moved (server-side)")
+				fs.Infof(src, "Moved (server-side)%v%v", operation, duration)
 			}
 
 			return newDst, nil
@@ -671,6 +1054,7 @@ func DeleteFileWithBackupDir(ctx context.Context, dst fs.Object, backupDir fs.Fs
 	if backupDir != nil {
 		action, actioned = "move into backup dir", "Moved into backup dir"
 	}
+	start := time.Now()
 	skip := SkipDestructive(ctx, dst, action)
 	if skip {
 		// do nothing
@@ -679,11 +1063,15 @@ func DeleteFileWithBackupDir(ctx context.Context, dst fs.Object, backupDir fs.Fs
 	} else {
 		err = dst.Remove(ctx)
 	}
+	if !skip {
+		reportDelete(ctx, dst, start, action, err)
+	}
+	operation := fs.LogValueHide("operation", action)
 	if err != nil {
-		fs.Errorf(dst, "Couldn't %s: %v", action, err)
+		fs.Errorf(dst, "Couldn't %s: %v%v", action, err, operation)
 		err = fs.CountError(err)
 	} else if !skip {
-		fs.Infof(dst, actioned)
+		fs.Infof(dst, "%s%v%v", actioned, operation, fs.LogValueHide("duration", time.Since(start).Seconds()))
 	}
 	return err
 }
@@ -702,6 +1090,12 @@ func DeleteFile(ctx context.Context, dst fs.Object) (err error) {
 // If backupDir is set the files will be placed into that directory
 // instead of being deleted.
 func DeleteFilesWithBackupDir(ctx context.Context, toBeDeleted fs.ObjectsChan, backupDir fs.Fs) error {
+	return deleteFiles(ctx, toBeDeleted, backupDir, nil)
+}
+
+// deleteFiles removes all the files passed in the channel recording
+// them in checkpoint if set
+func deleteFiles(ctx context.Context, toBeDeleted fs.ObjectsChan, backupDir fs.Fs, checkpoint *deleteCheckpoint) error {
 	var wg sync.WaitGroup
 	ci := fs.GetConfig(ctx)
 	wg.Add(ci.Transfers)
@@ -713,6 +1107,13 @@ func DeleteFilesWithBackupDir(ctx context.Context, toBeDeleted fs.ObjectsChan, b
 			defer wg.Done()
 			for dst := range toBeDeleted {
 				err := DeleteFileWithBackupDir(ctx, dst, backupDir)
+				if err == nil {
+					err = checkpoint.add(dst.Remote())
+					if err != nil {
+						err = fserrors.FatalError(err)
+						fs.Errorf(dst, "%v", err)
+					}
+				}
 				if err != nil {
 					atomic.AddInt32(&errorCount, 1)
 					if fserrors.IsFatalError(err) {
@@ -887,18 +1288,28 @@ func ListLong(ctx context.Context, f fs.Fs, w io.Writer) error {
 	})
 }
 
-// hashSum returns the human readable hash for ht passed in.  This may
-// be UNSUPPORTED or ERROR. If it isn't returning a valid hash it will
-// return an error.
-func hashSum(ctx context.Context, ht hash.Type, downloadFlag bool, o fs.Object) (string, error) {
-	var sum string
+// hashSums returns the human readable hashes for the types in hts
+// passed in.  These may be UNSUPPORTED or ERROR. If they aren't all
+// valid hashes it will return an error.
+//
+// If downloadFlag is set the hashes are all computed in one pass
+// over the data.
+func hashSums(ctx context.Context, hts []hash.Type, downloadFlag bool, o fs.Object) ([]string, error) {
+	sums := make([]string, len(hts))
 	var err error
 
 	// If downloadFlag is true, download and hash the file.
 	// If downloadFlag is false, call o.Hash asking the remote for the hash
 	if downloadFlag {
-		// Setup: Define accounting, open the file with NewReOpen to provide restarts, account for the transfer, and setup a multi-hasher with the appropriate type
-		// Execution: io.Copy file to hasher, get hash and encode in hex
+		// Setup: Define accounting, open the file with NewReOpen to provide restarts, account for the transfer, and setup a multi-hasher with the appropriate types
+		// Execution: io.Copy file to hasher, get hashes and encode in hex
+		fail := func(sum string, e error) ([]string, error) {
+			for i := range sums {
+				sums[i] = sum
+			}
+			err = e
+			return sums, err
+		}
 
 		tr := accounting.Stats(ctx).NewTransfer(o)
 		defer func() {
@@ -912,51 +1323,68 @@ func hashSum(ctx context.Context, ht hash.Type, downloadFlag bool, o fs.Object)
 		}
 		in, err := NewReOpen(ctx, o, fs.GetConfig(ctx).LowLevelRetries, options...)
 		if err != nil {
-			return "ERROR", errors.Wrapf(err, "Failed to open file %v", o)
+			return fail("ERROR", errors.Wrapf(err, "Failed to open file %v", o))
 		}
 
 		// Account and buffer the transfer
 		in = tr.Account(ctx, in).WithBuffer()
 
 		// Setup hasher
-		hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(ht))
+		hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(hts...))
 		if err != nil {
-			return "UNSUPPORTED", errors.Wrap(err, "Hash unsupported")
+			return fail("UNSUPPORTED", errors.Wrap(err, "Hash unsupported"))
 		}
 
 		// Copy to hasher, downloading the file and passing directly to hash
 		_, err = io.Copy(hasher, in)
 		if err != nil {
-			return "ERROR", errors.Wrap(err, "Failed to copy file to hasher")
+			return fail("ERROR", errors.Wrap(err, "Failed to copy file to hasher"))
 		}
 
-		// Get hash and encode as hex
-		byteSum, err := hasher.Sum(ht)
-		if err != nil {
-			return "ERROR", errors.Wrap(err, "Hasher returned an error")
+		// Get hashes and encode as hex
+		for i, ht := range hts {
+			byteSum, err := hasher.Sum(ht)
+			if err != nil {
+				return fail("ERROR", errors.Wrap(err, "Hasher returned an error"))
+			}
+			sums[i] = hex.EncodeToString(byteSum)
 		}
-		sum = hex.EncodeToString(byteSum)
 	} else {
 		tr := accounting.Stats(ctx).NewCheckingTransfer(o)
 		defer func() {
 			tr.Done(ctx, err)
 		}()
 
-		sum, err = o.Hash(ctx, ht)
-		if err == hash.ErrUnsupported {
-			return "UNSUPPORTED", errors.Wrap(err, "Hash unsupported")
-		} else if err != nil {
-			return "ERROR", errors.Wrapf(err, "Failed to get hash %v from backed: %v", ht, err)
+		for i, ht := range hts {
+			sum, hashErr := o.Hash(ctx, ht)
+			if hashErr == hash.ErrUnsupported {
+				sum, hashErr = "UNSUPPORTED", errors.Wrap(hashErr, "Hash unsupported")
+			} else if hashErr != nil {
+				sum, hashErr = "ERROR", errors.Wrapf(hashErr, "Failed to get hash %v from backed: %v", ht, hashErr)
+			}
+			sums[i] = sum
+			if err == nil {
+				err = hashErr
+			}
 		}
 	}
 
-	return sum, nil
+	return sums, err
 }
 
 // HashLister does an md5sum equivalent for the hash type passed in
 // Updated to handle both standard hex encoding and base64
 // Updated to perform multiple hashes concurrently
 func HashLister(ctx context.Context, ht hash.Type, outputBase64 bool, downloadFlag bool, f fs.Fs, w io.Writer) error {
+	return HashListerTypes(ctx, []hash.Type{ht}, outputBase64, downloadFlag, f, w)
+}
+
+// HashListerTypes is like HashLister but outputs a column for each of
+// the hash types passed in before the file name.
+//
+// When downloading all the hashes of a file are computed in a single
+// pass over its data.
+func HashListerTypes(ctx context.Context, hts []hash.Type, outputBase64 bool, downloadFlag bool, f fs.Fs, w io.Writer) error {
 	concurrencyControl := make(chan struct{}, fs.GetConfig(ctx).Transfers)
 	var wg sync.WaitGroup
 	err := ListFn(ctx, f, func(o fs.Object) {
@@ -967,15 +1395,19 @@ func HashLister(ctx context.Context, ht hash.Type, outputBase64 bool, downloadFl
 				<-concurrencyControl
 				wg.Done()
 			}()
-			sum, err := hashSum(ctx, ht, downloadFlag, o)
-			if outputBase64 && err == nil {
-				hexBytes, _ := hex.DecodeString(sum)
-				sum = base64.URLEncoding.EncodeToString(hexBytes)
-				width := base64.URLEncoding.EncodedLen(hash.Width(ht) / 2)
-				syncFprintf(w, "%*s  %s\n", width, sum, o.Remote())
-			} else {
-				syncFprintf(w, "%*s  %s\n", hash.Width(ht), sum, o.Remote())
+			sums, err := hashSums(ctx, hts, downloadFlag, o)
+			var line strings.Builder
+			for i, ht := range hts {
+				sum := sums[i]
+				width := hash.Width(ht)
+				if outputBase64 && err == nil {
+					hexBytes, _ := hex.DecodeString(sum)
+					sum = base64.URLEncoding.EncodeToString(hexBytes)
+					width = base64.URLEncoding.EncodedLen(hash.Width(ht) / 2)
+				}
+				_, _ = fmt.Fprintf(&line, "%*s  ", width, sum)
 			}
+			syncFprintf(w, "%s%s\n", line.String(), o.Remote())
 			if err != nil {
 				err = fs.CountError(err)
 				fs.Errorf(o, "%v", err)
@@ -1057,10 +1489,22 @@ func Rmdir(ctx context.Context, f fs.Fs, dir string) error {
 	return err
 }
 
+// DeleteOpt configures DeleteWithOpt and PurgeWithOpt
+type DeleteOpt struct {
+	TPSAware   bool   // delete each object through the pacer with progress rather than using the backend's Purge
+	Checkpoint string // if set record the objects deleted in this file so an interrupted delete can be continued
+}
+
 // Purge removes a directory and all of its contents
 func Purge(ctx context.Context, f fs.Fs, dir string) (err error) {
+	return PurgeWithOpt(ctx, f, dir, &DeleteOpt{})
+}
+
+// PurgeWithOpt removes a directory and all of its contents as
+// configured by opt
+func PurgeWithOpt(ctx context.Context, f fs.Fs, dir string, opt *DeleteOpt) (err error) {
 	doFallbackPurge := true
-	if doPurge := f.Features().Purge; doPurge != nil {
+	if doPurge := f.Features().Purge; doPurge != nil && !opt.TPSAware {
 		doFallbackPurge = false
 		accounting.Stats(ctx).DeletedDirs(1)
 		if SkipDestructive(ctx, fs.LogDirName(f, dir), "purge directory") {
@@ -1072,12 +1516,21 @@ func Purge(ctx context.Context, f fs.Fs, dir string) (err error) {
 		}
 	}
 	if doFallbackPurge {
-		// DeleteFiles and Rmdir observe --dry-run
-		err = DeleteFiles(ctx, listToChan(ctx, f, dir))
+		var checkpoint *deleteCheckpoint
+		checkpoint, err = openDeleteCheckpoint(ctx, opt.Checkpoint, f)
+		if err != nil {
+			return fs.CountError(err)
+		}
+		// deleteFiles and Rmdir observe --dry-run
+		err = deleteFiles(ctx, listToChan(ctx, f, dir, checkpoint), nil, checkpoint)
+		if err == nil {
+			err = Rmdirs(ctx, f, dir, false)
+		}
+		closeErr := checkpoint.close(err)
 		if err != nil {
 			return err
 		}
-		err = Rmdirs(ctx, f, dir, false)
+		err = closeErr
 	}
 	if err != nil {
 		err = fs.CountError(err)
@@ -1089,13 +1542,28 @@ func Purge(ctx context.Context, f fs.Fs, dir string) (err error) {
 // Delete removes all the contents of a container.  Unlike Purge, it
 // obeys includes and excludes.
 func Delete(ctx context.Context, f fs.Fs) error {
+	return DeleteWithOpt(ctx, f, &DeleteOpt{})
+}
+
+// DeleteWithOpt removes all the contents of a container as
+// configured by opt.  Unlike Purge, it obeys includes and excludes.
+func DeleteWithOpt(ctx context.Context, f fs.Fs, opt *DeleteOpt) error {
 	ci := fs.GetConfig(ctx)
+	checkpoint, err := openDeleteCheckpoint(ctx, opt.Checkpoint, f)
+	if err != nil {
+		return fs.CountError(err)
+	}
 	delChan := make(fs.ObjectsChan, ci.Transfers)
 	delErr := make(chan error, 1)
 	go func() {
-		delErr <- DeleteFiles(ctx, delChan)
+		delErr <- deleteFiles(ctx, delChan, nil, checkpoint)
 	}()
-	err := ListFn(ctx, f, func(o fs.Object) {
+	err = ListFn(ctx, f, func(o fs.Object) {
+		if checkpoint.isDeleted(o.Remote()) {
+			fs.Debugf(o, "Not deleting as already deleted according to checkpoint")
+			return
+		}
+		accounting.Stats(ctx).DeleteQueue(1)
 		delChan <- o
 	})
 	close(delChan)
@@ -1103,6 +1571,10 @@ func Delete(ctx context.Context, f fs.Fs) error {
 	if err == nil {
 		err = delError
 	}
+	closeErr := checkpoint.close(err)
+	if err == nil {
+		err = closeErr
+	}
 	return err
 }
 
@@ -1112,13 +1584,20 @@ func Delete(ctx context.Context, f fs.Fs) error {
 // channel.
 //
 // If the error was ErrorDirNotFound then it will be ignored
-func listToChan(ctx context.Context, f fs.Fs, dir string) fs.ObjectsChan {
+//
+// Objects deleted according to checkpoint are skipped.
+func listToChan(ctx context.Context, f fs.Fs, dir string, checkpoint *deleteCheckpoint) fs.ObjectsChan {
 	ci := fs.GetConfig(ctx)
 	o := make(fs.ObjectsChan, ci.Checkers)
 	go func() {
 		defer close(o)
 		err := walk.ListR(ctx, f, dir, true, ci.MaxDepth, walk.ListObjects, func(entries fs.DirEntries) error {
 			entries.ForObject(func(obj fs.Object) {
+				if checkpoint.isDeleted(obj.Remote()) {
+					fs.Debugf(obj, "Not deleting as already deleted according to checkpoint")
+					return
+				}
+				accounting.Stats(ctx).DeleteQueue(1)
 				o <- obj
 			})
 			return nil
@@ -1150,6 +1629,19 @@ type readCloser struct {
 	io.Closer
 }
 
+// catRange returns the range of o to read for offset and count as
+// passed to Cat
+func catRange(o fs.Object, offset, count int64) fs.RangeOption {
+	opt := fs.RangeOption{Start: offset, End: -1}
+	if opt.Start < 0 {
+		opt.Start += o.Size()
+	}
+	if count >= 0 {
+		opt.End = opt.Start + count - 1
+	}
+	return opt
+}
+
 // Cat any files to the io.Writer
 //
 // if offset == 0 it will be ignored
@@ -1160,49 +1652,47 @@ type readCloser struct {
 // if count >= 0 then only that many characters will be output
 func Cat(ctx context.Context, f fs.Fs, w io.Writer, offset, count int64) error {
 	var mu sync.Mutex
-	ci := fs.GetConfig(ctx)
 	return ListFn(ctx, f, func(o fs.Object) {
-		var err error
-		tr := accounting.Stats(ctx).NewTransfer(o)
-		defer func() {
-			tr.Done(ctx, err)
-		}()
-		opt := fs.RangeOption{Start: offset, End: -1}
-		size := o.Size()
-		if opt.Start < 0 {
-			opt.Start += size
-		}
-		if count >= 0 {
-			opt.End = opt.Start + count - 1
-		}
-		var options []fs.OpenOption
-		if opt.Start > 0 || opt.End >= 0 {
-			options = append(options, &opt)
-		}
-		for _, option := range ci.DownloadHeaders {
-			options = append(options, option)
-		}
-		in, err := o.Open(ctx, options...)
-		if err != nil {
-			err = fs.CountError(err)
-			fs.Errorf(o, "Failed to open: %v", err)
-			return
-		}
-		if count >= 0 {
-			in = &readCloser{Reader: &io.LimitedReader{R: in, N: count}, Closer: in}
-		}
-		in = tr.Account(ctx, in).WithBuffer() // account and buffer the transfer
-		// take the lock just before we output stuff, so at the last possible moment
-		mu.Lock()
-		defer mu.Unlock()
-		_, err = io.Copy(w, in)
-		if err != nil {
-			err = fs.CountError(err)
-			fs.Errorf(o, "Failed to send to output: %v", err)
-		}
+		catObject(ctx, &mu, w, o, offset, count)
 	})
 }
 
+// catObject writes o to w as described in Cat holding mu while writing
+func catObject(ctx context.Context, mu *sync.Mutex, w io.Writer, o fs.Object, offset, count int64) {
+	ci := fs.GetConfig(ctx)
+	var err error
+	tr := accounting.Stats(ctx).NewTransfer(o)
+	defer func() {
+		tr.Done(ctx, err)
+	}()
+	opt := catRange(o, offset, count)
+	var options []fs.OpenOption
+	if opt.Start > 0 || opt.End >= 0 {
+		options = append(options, &opt)
+	}
+	for _, option := range ci.DownloadHeaders {
+		options = append(options, option)
+	}
+	in, err := o.Open(ctx, options...)
+	if err != nil {
+		err = fs.CountError(err)
+		fs.Errorf(o, "Failed to open: %v", err)
+		return
+	}
+	if count >= 0 {
+		in = &readCloser{Reader: &io.LimitedReader{R: in, N: count}, Closer: in}
+	}
+	in = tr.Account(ctx, in).WithBuffer() // account and buffer the transfer
+	// take the lock just before we output stuff, so at the last possible moment
+	mu.Lock()
+	defer mu.Unlock()
+	_, err = io.Copy(w, in)
+	if err != nil {
+		err = fs.CountError(err)
+		fs.Errorf(o, "Failed to send to output: %v", err)
+	}
+}
+
 // Rcat reads data from the Reader until EOF and uploads it to a file on remote
 func Rcat(ctx context.Context, fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (dst fs.Object, err error) {
 	ci := fs.GetConfig(ctx)
@@ -1307,21 +1797,43 @@ func PublicLink(ctx context.Context, f fs.Fs, remote string, expire fs.Duration,
 	return doPublicLink(ctx, remote, expire, unlink)
 }
 
+// PublicLinkWithPassword makes a public link to the given file or
+// folder which needs password to read it.
+func PublicLinkWithPassword(ctx context.Context, f fs.Fs, remote string, expire fs.Duration, password string) (string, error) {
+	doPublicLink := f.Features().PublicLinkWithPassword
+	if doPublicLink == nil {
+		return "", errors.Errorf("%v doesn't support public links with passwords", f)
+	}
+	return doPublicLink(ctx, remote, expire, password)
+}
+
+// ListPublicLinks returns the existing public links to the given file
+// or folder.
+func ListPublicLinks(ctx context.Context, f fs.Fs, remote string) ([]fs.PublicLinkInfo, error) {
+	doListPublicLinks := f.Features().ListPublicLinks
+	if doListPublicLinks == nil {
+		return nil, errors.Errorf("%v doesn't support listing public links", f)
+	}
+	return doListPublicLinks(ctx, remote)
+}
+
 // Rmdirs removes any empty directories (or directories only
 // containing empty directories) under f, including f.
 //
+// The directories are found with a single recursive listing and
+// removed deepest first, with --checkers of the directories at each
+// depth removed in parallel.
+//
+// If leaveRoot is set then dir isn't removed. If dir is the root of
+// a bucket based remote then the buckets aren't removed either.
+//
 // Rmdirs obeys the filters
 func Rmdirs(ctx context.Context, f fs.Fs, dir string, leaveRoot bool) error {
 	ci := fs.GetConfig(ctx)
 	fi := filter.GetConfig(ctx)
 	dirEmpty := make(map[string]bool)
 	dirEmpty[dir] = !leaveRoot
-	err := walk.Walk(ctx, f, dir, false, ci.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
-		if err != nil {
-			err = fs.CountError(err)
-			fs.Errorf(f, "Failed to list %q: %v", dirPath, err)
-			return nil
-		}
+	err := walk.ListR(ctx, f, dir, false, ci.MaxDepth, walk.ListAll, func(entries fs.DirEntries) error {
 		for _, entry := range entries {
 			switch x := entry.(type) {
 			case fs.Directory:
@@ -1335,10 +1847,7 @@ func Rmdirs(ctx context.Context, f fs.Fs, dir string, leaveRoot bool) error {
 				// mark the parents of the file as being non-empty
 				dir := x.Remote()
 				for dir != "" {
-					dir = path.Dir(dir)
-					if dir == "." || dir == "/" {
-						dir = ""
-					}
+					dir = parentDir(dir)
 					empty, found := dirEmpty[dir]
 					// End if we reach a directory which is non-empty
 					if found && !empty {
@@ -1351,31 +1860,94 @@ func Rmdirs(ctx context.Context, f fs.Fs, dir string, leaveRoot bool) error {
 		return nil
 	})
 	if err != nil {
+		err = fs.CountError(err)
 		return errors.Wrap(err, "failed to rmdirs")
 	}
-	// Now delete the empty directories, starting from the longest path
-	var toDelete []string
+	// The buckets are the roots of a bucket based remote
+	leaveBuckets := leaveRoot && f.Features().BucketBased && path.Join(f.Root(), dir) == ""
+	// Now sort the empty directories by depth so they can be
+	// deleted from the deepest up
+	byDepth := make(map[int][]string)
+	maxDepth := 0
 	for dir, empty := range dirEmpty {
-		if empty {
-			toDelete = append(toDelete, dir)
+		if !empty {
+			continue
 		}
-	}
-	sort.Strings(toDelete)
-	for i := len(toDelete) - 1; i >= 0; i-- {
-		dir := toDelete[i]
 		// If a filter matches the directory then that
 		// directory is a candidate for deletion
 		if !fi.Include(dir+"/", 0, time.Now()) {
 			continue
 		}
-		err = TryRmdir(ctx, f, dir)
-		if err != nil {
-			err = fs.CountError(err)
-			fs.Errorf(dir, "Failed to rmdir: %v", err)
-			return err
+		depth := 0
+		if dir != "" {
+			depth = strings.Count(dir, "/") + 1
+		}
+		if leaveBuckets && depth == 1 {
+			continue
+		}
+		byDepth[depth] = append(byDepth[depth], dir)
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+	}
+	var (
+		mu      sync.Mutex
+		failed  = make(map[string]struct{}) // directories whose children couldn't be removed
+		lastErr error
+	)
+	workers := ci.Checkers
+	if workers < 1 {
+		workers = 1
+	}
+	for depth := maxDepth; depth >= 0; depth-- {
+		dirs := byDepth[depth]
+		sort.Strings(dirs)
+		dirsChan := make(chan string)
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for dir := range dirsChan {
+					mu.Lock()
+					_, blocked := failed[dir]
+					mu.Unlock()
+					if blocked {
+						fs.Debugf(fs.LogDirName(f, dir), "Not removing directory as removing a directory in it failed")
+						continue
+					}
+					err := TryRmdir(ctx, f, dir)
+					if err == nil {
+						continue
+					}
+					err = fs.CountError(err)
+					fs.Errorf(dir, "Failed to rmdir: %v", err)
+					mu.Lock()
+					lastErr = err
+					for dir != "" {
+						dir = parentDir(dir)
+						failed[dir] = struct{}{}
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+		for _, dir := range dirs {
+			dirsChan <- dir
 		}
+		close(dirsChan)
+		wg.Wait()
 	}
-	return nil
+	return lastErr
+}
+
+// parentDir returns the parent directory of remote or "" for the root
+func parentDir(remote string) string {
+	dir := path.Dir(remote)
+	if dir == "." || dir == "/" {
+		dir = ""
+	}
+	return dir
 }
 
 // GetCompareDest sets up --compare-dest
@@ -1412,6 +1984,7 @@ func compareDest(ctx context.Context, dst, src fs.Object, CompareDest fs.Fs) (No
 	opt.updateModTime = false
 	if equal(ctx, src, CompareDestFile, opt) {
 		fs.Debugf(src, "Destination found in --compare-dest, skipping")
+		reportNeedTransfer(ctx, src, false, "Destination found in --compare-dest, skipping")
 		return true, nil
 	}
 	return false, nil
@@ -1477,6 +2050,7 @@ func copyDest(ctx context.Context, fdst fs.Fs, dst, src fs.Object, CopyDest, bac
 			return true, nil
 		}
 		fs.Debugf(src, "Unchanged skipping")
+		reportNeedTransfer(ctx, src, false, "Unchanged skipping")
 		return true, nil
 	}
 	fs.Debugf(src, "Destination not found in --copy-dest")
@@ -1513,20 +2087,30 @@ func CompareOrCopyDest(ctx context.Context, fdst fs.Fs, dst, src fs.Object, Comp
 // Returns a flag which indicates whether the file needs to be
 // transferred or not.
 func NeedTransfer(ctx context.Context, dst, src fs.Object) bool {
+	need, reason := needTransfer(ctx, dst, src)
+	reportNeedTransfer(ctx, src, need, reason)
+	return need
+}
+
+// needTransfer is NeedTransfer but also returns the reason why the
+// file does or doesn't need transferring
+func needTransfer(ctx context.Context, dst, src fs.Object) (need bool, reason string) {
 	ci := fs.GetConfig(ctx)
+	because := func(need bool, format string, args ...interface{}) (bool, string) {
+		reason := fmt.Sprintf(format, args...)
+		fs.Debugf(src, "%s", reason)
+		return need, reason
+	}
 	if dst == nil {
-		fs.Debugf(src, "Need to transfer - File not found at Destination")
-		return true
+		return because(true, "Need to transfer - File not found at Destination")
 	}
 	// If we should ignore existing files, don't transfer
 	if ci.IgnoreExisting {
-		fs.Debugf(src, "Destination exists, skipping")
-		return false
+		return because(false, "Destination exists, skipping")
 	}
 	// If we should upload unconditionally
 	if ci.IgnoreTimes {
-		fs.Debugf(src, "Transferring unconditionally as --ignore-times is in use")
-		return true
+		return because(true, "Transferring unconditionally as --ignore-times is in use")
 	}
 	// If UpdateOlder is in effect, skip if dst is newer than src
 	if ci.UpdateOlder {
@@ -1543,34 +2127,30 @@ func NeedTransfer(ctx context.Context, dst, src fs.Object) bool {
 		}
 		switch {
 		case dt >= modifyWindow:
-			fs.Debugf(src, "Destination is newer than source, skipping")
-			return false
+			return because(false, "Destination is newer than source, skipping")
 		case dt <= -modifyWindow:
 			// force --checksum on for the check and do update modtimes by default
 			opt := defaultEqualOpt(ctx)
 			opt.forceModTimeMatch = true
 			if equal(ctx, src, dst, opt) {
-				fs.Debugf(src, "Unchanged skipping")
-				return false
+				return because(false, "Unchanged skipping")
 			}
 		default:
 			// Do a size only compare unless --checksum is set
 			opt := defaultEqualOpt(ctx)
 			opt.sizeOnly = !ci.CheckSum
 			if equal(ctx, src, dst, opt) {
-				fs.Debugf(src, "Destination mod time is within %v of source and files identical, skipping", modifyWindow)
-				return false
+				return because(false, "Destination mod time is within %v of source and files identical, skipping", modifyWindow)
 			}
-			fs.Debugf(src, "Destination mod time is within %v of source but files differ, transferring", modifyWindow)
+			return because(true, "Destination mod time is within %v of source but files differ, transferring", modifyWindow)
 		}
 	} else {
 		// Check to see if changed or not
 		if Equal(ctx, src, dst) {
-			fs.Debugf(src, "Unchanged skipping")
-			return false
+			return because(false, "Unchanged skipping")
 		}
 	}
-	return true
+	return true, "Source and destination differ"
 }
 
 // RcatSize reads data from the Reader until EOF and uploads it to a file on remote.
@@ -1620,7 +2200,11 @@ type copyURLFunc func(ctx context.Context, dstFileName string, in io.ReadCloser,
 // copyURLFn copies the data from the url to the function supplied
 func copyURLFn(ctx context.Context, dstFileName string, url string, dstFileNameFromURL bool, fn copyURLFunc) (err error) {
 	client := fshttp.NewClient(ctx)
-	resp, err := client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -1633,31 +2217,135 @@ func copyURLFn(ctx context.Context, dstFileName string, url string, dstFileNameF
 		modTime = time.Now()
 	}
 	if dstFileNameFromURL {
-		dstFileName = path.Base(resp.Request.URL.Path)
-		if dstFileName == "." || dstFileName == "/" {
-			return errors.Errorf("CopyURL failed: file name wasn't found in url")
+		if dstFileName = contentDispositionName(resp.Header.Get("Content-Disposition")); dstFileName != "" {
+			fs.Debugf(dstFileName, "File name found in Content-Disposition")
+		} else {
+			dstFileName = path.Base(resp.Request.URL.Path)
+			if dstFileName == "." || dstFileName == "/" {
+				return errors.Errorf("CopyURL failed: file name wasn't found in url")
+			}
+			fs.Debugf(dstFileName, "File name found in url")
 		}
-		fs.Debugf(dstFileName, "File name found in url")
 	}
 	return fn(ctx, dstFileName, resp.Body, resp.ContentLength, modTime)
 }
 
-// CopyURL copies the data from the url to (fdst, dstFileName)
-func CopyURL(ctx context.Context, fdst fs.Fs, dstFileName string, url string, dstFileNameFromURL bool, noClobber bool) (dst fs.Object, err error) {
+// contentDispositionName returns the file name from a
+// Content-Disposition header without any directories or "" if there
+// isn't a usable one
+func contentDispositionName(header string) string {
+	if header == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return ""
+	}
+	name := params["filename"]
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "." || name == ".." {
+		return ""
+	}
+	return name
+}
 
-	err = copyURLFn(ctx, dstFileName, url, dstFileNameFromURL, func(ctx context.Context, dstFileName string, in io.ReadCloser, size int64, modTime time.Time) (err error) {
+// copyURLToFs returns a copyURLFunc which uploads to fdst setting
+// *dst to the object made
+func copyURLToFs(fdst fs.Fs, noClobber bool, dst *fs.Object) copyURLFunc {
+	return func(ctx context.Context, dstFileName string, in io.ReadCloser, size int64, modTime time.Time) (err error) {
 		if noClobber {
 			_, err = fdst.NewObject(ctx, dstFileName)
 			if err == nil {
 				return errors.New("CopyURL failed: file already exist")
 			}
 		}
-		dst, err = RcatSize(ctx, fdst, dstFileName, in, size, modTime)
+		*dst, err = RcatSize(ctx, fdst, dstFileName, in, size, modTime)
 		return err
-	})
+	}
+}
+
+// CopyURL copies the data from the url to (fdst, dstFileName)
+func CopyURL(ctx context.Context, fdst fs.Fs, dstFileName string, url string, dstFileNameFromURL bool, noClobber bool) (dst fs.Object, err error) {
+	err = copyURLFn(ctx, dstFileName, url, dstFileNameFromURL, copyURLToFs(fdst, noClobber, &dst))
 	return dst, err
 }
 
+// CopyURLs copies the data from each of the urls read from in, one
+// per line, to fdst naming the files from the Content-Disposition or
+// the url.
+//
+// Blank lines and lines starting with # are ignored. It downloads
+// --transfers urls at once calling fn, if set, with each object made.
+// Errors are logged and counted and the last one is returned.
+func CopyURLs(ctx context.Context, fdst fs.Fs, in io.Reader, noClobber bool, fn func(dst fs.Object)) (err error) {
+	ci := fs.GetConfig(ctx)
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex // protect the below
+		names   = make(map[string]string)
+		lastErr error
+		urls    = make(chan string, ci.Transfers)
+	)
+	for i := 0; i < ci.Transfers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range urls {
+				var dst fs.Object
+				upload := copyURLToFs(fdst, noClobber, &dst)
+				err := copyURLFn(ctx, "", url, true, func(ctx context.Context, dstFileName string, in io.ReadCloser, size int64, modTime time.Time) error {
+					mu.Lock()
+					previous, found := names[dstFileName]
+					if !found {
+						names[dstFileName] = url
+					}
+					mu.Unlock()
+					if found {
+						return errors.Errorf("CopyURL failed: file name %q already used by %s", dstFileName, previous)
+					}
+					return upload(ctx, dstFileName, in, size, modTime)
+				})
+				if err != nil {
+					err = fs.CountError(err)
+					fs.Errorf(url, "Failed to copy: %v", err)
+					mu.Lock()
+					lastErr = err
+					mu.Unlock()
+					continue
+				}
+				if fn != nil {
+					fn(dst)
+				}
+			}
+		}()
+	}
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		url := strings.TrimSpace(scanner.Text())
+		if url == "" || strings.HasPrefix(url, "#") {
+			continue
+		}
+		select {
+		case urls <- url:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(urls)
+	wg.Wait()
+	if err = scanner.Err(); err != nil {
+		return errors.Wrap(err, "failed to read urls")
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	return lastErr
+}
+
 // CopyURLToWriter copies the data from the url to the io.Writer supplied
 func CopyURLToWriter(ctx context.Context, url string, out io.Writer) (err error) {
 	return copyURLFn(ctx, "", url, false, func(ctx context.Context, dstFileName string, in io.ReadCloser, size int64, modTime time.Time) (err error) {
@@ -1828,27 +2516,13 @@ func CopyFile(ctx context.Context, fdst fs.Fs, fsrc fs.Fs, dstFileName string, s
 	return moveOrCopyFile(ctx, fdst, fsrc, dstFileName, srcFileName, true)
 }
 
-// SetTier changes tier of object in remote
-func SetTier(ctx context.Context, fsrc fs.Fs, tier string) error {
-	return ListFn(ctx, fsrc, func(o fs.Object) {
-		objImpl, ok := o.(fs.SetTierer)
-		if !ok {
-			fs.Errorf(fsrc, "Remote object does not implement SetTier")
-			return
-		}
-		err := objImpl.SetTier(tier)
-		if err != nil {
-			fs.Errorf(fsrc, "Failed to do SetTier, %v", err)
-		}
-	})
-}
-
 // ListFormat defines files information print format
 type ListFormat struct {
 	separator string
 	dirSlash  bool
 	absolute  bool
 	output    []func(entry *ListJSONItem) string
+	header    []string
 	csv       *csv.Writer
 	buf       bytes.Buffer
 }
@@ -1886,18 +2560,19 @@ func (l *ListFormat) SetCSV(useCSV bool) {
 // SetOutput sets functions used to create files information
 func (l *ListFormat) SetOutput(output []func(entry *ListJSONItem) string) {
 	l.output = output
+	l.header = make([]string, len(output))
 }
 
 // AddModTime adds file's Mod Time to output
 func (l *ListFormat) AddModTime() {
-	l.AppendOutput(func(entry *ListJSONItem) string {
+	l.addColumn("ModTime", func(entry *ListJSONItem) string {
 		return entry.ModTime.When.Local().Format("2006-01-02 15:04:05")
 	})
 }
 
 // AddSize adds file's size to output
 func (l *ListFormat) AddSize() {
-	l.AppendOutput(func(entry *ListJSONItem) string {
+	l.addColumn("Size", func(entry *ListJSONItem) string {
 		return strconv.FormatInt(entry.Size, 10)
 	})
 }
@@ -1915,14 +2590,14 @@ func (l *ListFormat) normalisePath(entry *ListJSONItem, remote string) string {
 
 // AddPath adds path to file to output
 func (l *ListFormat) AddPath() {
-	l.AppendOutput(func(entry *ListJSONItem) string {
+	l.addColumn("Path", func(entry *ListJSONItem) string {
 		return l.normalisePath(entry, entry.Path)
 	})
 }
 
 // AddEncrypted adds the encrypted path to file to output
 func (l *ListFormat) AddEncrypted() {
-	l.AppendOutput(func(entry *ListJSONItem) string {
+	l.addColumn("Encrypted", func(entry *ListJSONItem) string {
 		return l.normalisePath(entry, entry.Encrypted)
 	})
 }
@@ -1930,7 +2605,7 @@ func (l *ListFormat) AddEncrypted() {
 // AddHash adds the hash of the type given to the output
 func (l *ListFormat) AddHash(ht hash.Type) {
 	hashName := ht.String()
-	l.AppendOutput(func(entry *ListJSONItem) string {
+	l.addColumn(hashName, func(entry *ListJSONItem) string {
 		if entry.IsDir {
 			return ""
 		}
@@ -1940,35 +2615,54 @@ func (l *ListFormat) AddHash(ht hash.Type) {
 
 // AddID adds file's ID to the output if known
 func (l *ListFormat) AddID() {
-	l.AppendOutput(func(entry *ListJSONItem) string {
+	l.addColumn("ID", func(entry *ListJSONItem) string {
 		return entry.ID
 	})
 }
 
 // AddOrigID adds file's Original ID to the output if known
 func (l *ListFormat) AddOrigID() {
-	l.AppendOutput(func(entry *ListJSONItem) string {
+	l.addColumn("OrigID", func(entry *ListJSONItem) string {
 		return entry.OrigID
 	})
 }
 
 // AddTier adds file's Tier to the output if known
 func (l *ListFormat) AddTier() {
-	l.AppendOutput(func(entry *ListJSONItem) string {
+	l.addColumn("Tier", func(entry *ListJSONItem) string {
 		return entry.Tier
 	})
 }
 
 // AddMimeType adds file's MimeType to the output if known
 func (l *ListFormat) AddMimeType() {
-	l.AppendOutput(func(entry *ListJSONItem) string {
+	l.addColumn("MimeType", func(entry *ListJSONItem) string {
 		return entry.MimeType
 	})
 }
 
+// AddMetadata adds the value of the metadata key given to the output
+// if known
+func (l *ListFormat) AddMetadata(key string) {
+	l.addColumn(key, func(entry *ListJSONItem) string {
+		return entry.Metadata[key]
+	})
+}
+
 // AppendOutput adds string generated by specific function to printed output
 func (l *ListFormat) AppendOutput(functionToAppend func(item *ListJSONItem) string) {
+	l.addColumn("", functionToAppend)
+}
+
+// addColumn adds a column called name to the output
+func (l *ListFormat) addColumn(name string, functionToAppend func(item *ListJSONItem) string) {
 	l.output = append(l.output, functionToAppend)
+	l.header = append(l.header, name)
+}
+
+// Header returns the names of the columns in the format defined
+func (l *ListFormat) Header() string {
+	return l.join(l.header)
 }
 
 // Format prints information about the DirEntry in the format defined
@@ -1977,6 +2671,11 @@ func (l *ListFormat) Format(entry *ListJSONItem) (result string) {
 	for _, fun := range l.output {
 		out = append(out, fun(entry))
 	}
+	return l.join(out)
+}
+
+// join the columns in out with the separator or as CSV
+func (l *ListFormat) join(out []string) (result string) {
 	if l.csv != nil {
 		l.buf.Reset()
 		_ = l.csv.Write(out) // can't fail writing to bytes.Buffer
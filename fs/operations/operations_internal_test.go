@@ -9,8 +9,11 @@ import (
 	"time"
 
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSizeDiffers(t *testing.T) {
@@ -41,3 +44,66 @@ func TestSizeDiffers(t *testing.T) {
 		assert.Equal(t, test.want, got, fmt.Sprintf("ignoreSize=%v, srcSize=%v, dstSize=%v", test.ignoreSize, test.srcSize, test.dstSize))
 	}
 }
+
+func TestVerifyCopy(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	stats := accounting.Stats(ctx)
+	defer stats.ResetCounters()
+	t1 := fstest.Time("2001-02-03T04:05:06.499999999Z")
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	src, err := r.Flocal.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+
+	ci.Verify = true
+	_, err = Copy(ctx, r.Fremote, nil, file1.Path, src)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1)
+	assert.Equal(t, int64(1), stats.Verifies(0))
+	assert.Equal(t, int64(0), stats.VerifyFailures(0))
+
+	ok, err := verifyCopy(ctx, r.Fremote, file1.Path, src)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Corrupt the copy keeping the size the same
+	r.WriteObject(ctx, file1.Path, "file1 CONTENTS", t1)
+	ok, err = verifyCopy(ctx, r.Fremote, file1.Path, src)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestSampleRanges(t *testing.T) {
+	assert.Equal(t, []*fs.RangeOption{{Start: 0, End: 29}}, sampleRanges(30, 10))
+	for i := 0; i < 100; i++ {
+		ranges := sampleRanges(100, 10)
+		require.Len(t, ranges, 3)
+		assert.Equal(t, fs.RangeOption{Start: 0, End: 9}, *ranges[0])
+		assert.Equal(t, fs.RangeOption{Start: 90, End: 99}, *ranges[2])
+		middle := ranges[1]
+		assert.Equal(t, int64(9), middle.End-middle.Start)
+		assert.True(t, middle.Start >= 10, middle.Start)
+		assert.True(t, middle.End <= 89, middle.End)
+	}
+}
+
+func TestAtomicUpload(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	assert.False(t, useAtomicUpload(ctx, r.Flocal))
+	ci.AtomicUpload = true
+	assert.True(t, useAtomicUpload(ctx, r.Flocal))
+	fmemory, err := fs.NewFs(ctx, ":memory:")
+	require.NoError(t, err)
+	assert.False(t, useAtomicUpload(ctx, fmemory), "bucket based")
+
+	name := partialName(ctx, "dir/file.txt")
+	assert.Regexp(t, `^dir/file\.txt\.[0-9A-Za-z]{8}\.partial$`, name)
+	assert.NotEqual(t, name, partialName(ctx, "dir/file.txt"))
+}
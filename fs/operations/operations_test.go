@@ -20,20 +20,28 @@
 package operations_test
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"regexp"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	_ "github.com/rclone/rclone/backend/all" // import all backends
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
@@ -330,6 +338,19 @@ func TestHashSums(t *testing.T) {
 	}
 }
 
+func TestHashSumsTypes(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteBoth(ctx, "potato2", "------------------------------------------------------------", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	var buf bytes.Buffer
+	err := operations.HashListerTypes(ctx, []hash.Type{hash.MD5, hash.SHA256, hash.CRC32C}, false, true, r.Fremote, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "d6548b156ea68a4e003e786df99eee76  d398f81cd00b370b116d049d2f3b73a3a7ed35446486effb789791a7e0b98e9c  598793cc  potato2\n", buf.String())
+}
+
 func TestSuffixName(t *testing.T) {
 	ctx := context.Background()
 	ctx, ci := fs.AddConfig(ctx)
@@ -393,6 +414,46 @@ func TestDelete(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file3)
 }
 
+func TestDeleteWithOptCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject(ctx, "small", "1234567890", t2)
+	file2 := r.WriteObject(ctx, "dir/medium", "------------------------------------------------------------", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	checkpointDir, err := ioutil.TempDir("", "rclone-checkpoint")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(checkpointDir)
+	}()
+	checkpoint := checkpointDir + "/checkpoint.json"
+	opt := &operations.DeleteOpt{Checkpoint: checkpoint}
+
+	// a checkpoint for a different remote is an error
+	require.NoError(t, ioutil.WriteFile(checkpoint, []byte(`{"root":"potato:"}`+"\n"), 0600))
+	err = operations.DeleteWithOpt(ctx, r.Fremote, opt)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "potato")
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	// pretend small was deleted before being interrupted, with a
+	// partly written entry at the end
+	root, err := json.Marshal(fs.ConfigString(r.Fremote))
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(checkpoint, []byte(`{"root":`+string(root)+"}\n"+`{"remote":"small"}`+"\n"+`{"remo`), 0600))
+	accounting.GlobalStats().ResetCounters()
+	err = operations.DeleteWithOpt(ctx, r.Fremote, opt)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1)
+	assert.Equal(t, int64(1), accounting.GlobalStats().DeleteQueue(0))
+	assert.Equal(t, int64(1), accounting.GlobalStats().Deletes(0))
+
+	// the checkpoint is removed when finished
+	_, err = os.Stat(checkpoint)
+	assert.True(t, os.IsNotExist(err))
+}
+
 func TestRetry(t *testing.T) {
 	ctx := context.Background()
 
@@ -524,6 +585,22 @@ func TestPurge(t *testing.T) {
 
 }
 
+func TestPurgeTPSAware(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject(ctx, "A1/one", "aaa", t1)
+	file2 := r.WriteObject(ctx, "A1/B1/two", "bbb", t2)
+	file3 := r.WriteObject(ctx, "A2/three", "ccc", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, operations.PurgeWithOpt(ctx, r.Fremote, "A1", &operations.DeleteOpt{TPSAware: true}))
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file3}, []string{"A2"}, fs.GetModifyWindow(ctx, r.Fremote))
+	assert.Equal(t, int64(2), accounting.GlobalStats().DeleteQueue(0))
+	assert.Equal(t, int64(2), accounting.GlobalStats().Deletes(0))
+}
+
 func TestRmdirsNoLeaveRoot(t *testing.T) {
 	ctx := context.Background()
 	r := fstest.NewRun(t)
@@ -682,6 +759,28 @@ func TestRmdirsWithFilter(t *testing.T) {
 	)
 }
 
+func TestRmdirsLeaveRootBuckets(t *testing.T) {
+	ctx := context.Background()
+	f, err := fs.NewFs(ctx, ":memory:")
+	require.NoError(t, err)
+	require.NoError(t, f.Mkdir(ctx, "rmdirs-bucket1"))
+	require.NoError(t, f.Mkdir(ctx, "rmdirs-bucket2"))
+
+	// the buckets are left as they are roots too
+	require.NoError(t, operations.Rmdirs(ctx, f, "", true))
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	// but are removed without --leave-root
+	require.NoError(t, operations.Rmdirs(ctx, f, "rmdirs-bucket1", false))
+	entries, err = f.List(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "rmdirs-bucket2", entries[0].Remote())
+	require.NoError(t, f.Rmdir(ctx, "rmdirs-bucket2"))
+}
+
 func TestCopyURL(t *testing.T) {
 	ctx := context.Background()
 	ctx, ci := fs.AddConfig(ctx)
@@ -781,6 +880,52 @@ func TestCopyURLToWriter(t *testing.T) {
 	assert.Equal(t, 0, len(buf.String()))
 }
 
+func TestCopyURLs(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	r.Mkdir(ctx, r.Fremote)
+
+	contents := "file contents\n"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.Error(w, "not here", http.StatusNotFound)
+			return
+		case "/download":
+			w.Header().Set("Content-Disposition", `attachment; filename="../named.txt"`)
+		}
+		_, err := w.Write([]byte(contents))
+		assert.NoError(t, err)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	urls := strings.Join([]string{
+		"# a comment",
+		ts.URL + "/file1.txt",
+		"",
+		ts.URL + "/download",
+		ts.URL + "/missing",
+		ts.URL + "/dir/file1.txt",
+	}, "\n")
+	var mu sync.Mutex
+	var names []string
+	err := operations.CopyURLs(ctx, r.Fremote, strings.NewReader(urls), false, func(dst fs.Object) {
+		mu.Lock()
+		names = append(names, dst.Remote())
+		mu.Unlock()
+	})
+	require.Error(t, err)
+	accounting.GlobalStats().ResetCounters()
+	sort.Strings(names)
+	assert.Equal(t, []string{"file1.txt", "named.txt"}, names)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{
+		fstest.NewItem("file1.txt", contents, t1),
+		fstest.NewItem("named.txt", contents, t1),
+	}, nil, fs.ModTimeNotSupported)
+}
+
 func TestMoveFile(t *testing.T) {
 	ctx := context.Background()
 	r := fstest.NewRun(t)
@@ -898,6 +1043,468 @@ func TestCopyFile(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file2)
 }
 
+func TestCopyFileMetadata(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no POSIX permissions on Windows")
+	}
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	src, err := r.Flocal.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+	require.NoError(t, src.(fs.SetMetadataer).SetMetadata(ctx, fs.Metadata{fs.MetadataMode: "600"}))
+
+	for _, metadata := range []bool{false, true} {
+		ci.Metadata = metadata
+		dst, err := operations.Copy(ctx, r.Fremote, nil, fmt.Sprintf("file%v", metadata), src)
+		require.NoError(t, err)
+		if _, ok := dst.(fs.SetMetadataer); !ok {
+			t.Skip("remote doesn't support metadata")
+		}
+		m, err := fs.GetMetadata(ctx, dst)
+		require.NoError(t, err)
+		if metadata {
+			assert.Equal(t, "600", m[fs.MetadataMode])
+		} else {
+			assert.NotEqual(t, "600", m[fs.MetadataMode])
+		}
+	}
+}
+
+func TestCopyFileRefreshMetadata(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no POSIX permissions on Windows")
+	}
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if r.Fremote.Hashes().Overlap(r.Flocal.Hashes()).Count() == 0 {
+		t.Skip("Can't check this if no hashes supported")
+	}
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	r.WriteObject(ctx, "file1", "file1 contents", t1)
+	src, err := r.Flocal.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+	require.NoError(t, src.(fs.SetMetadataer).SetMetadata(ctx, fs.Metadata{fs.MetadataMode: "600"}))
+
+	for _, refresh := range []bool{false, true} {
+		ci.RefreshMetadata = refresh
+		err = operations.CopyFile(ctx, r.Fremote, r.Flocal, file1.Path, file1.Path)
+		require.NoError(t, err)
+		dst, err := r.Fremote.NewObject(ctx, file1.Path)
+		require.NoError(t, err)
+		if _, ok := dst.(fs.SetMetadataer); !ok {
+			t.Skip("remote doesn't support metadata")
+		}
+		m, err := fs.GetMetadata(ctx, dst)
+		require.NoError(t, err)
+		if refresh {
+			assert.Equal(t, "600", m[fs.MetadataMode])
+		} else {
+			assert.NotEqual(t, "600", m[fs.MetadataMode])
+		}
+	}
+	fstest.CheckItems(t, r.Fremote, file1)
+}
+
+func TestCopyFileAtomicUpload(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if r.Fremote.Features().Move == nil || r.Fremote.Features().BucketBased {
+		t.Skip("Skipping test as remote can't rename uploads")
+	}
+	ci.AtomicUpload = true
+
+	file1 := r.WriteFile("dir/file1", "file1 contents", t1)
+	err := operations.CopyFile(ctx, r.Fremote, r.Flocal, file1.Path, file1.Path)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// replacing an existing file
+	file2 := r.WriteFile("dir/file1", "file1 contents changed", t2)
+	err = operations.CopyFile(ctx, r.Fremote, r.Flocal, file2.Path, file2.Path)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file2)
+}
+
+// badHashObject is an fs.Object whose hash never matches its data
+type badHashObject struct {
+	fs.Object
+}
+
+// Hash returns a hash which doesn't match the data
+func (o badHashObject) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	sum, err := o.Object.Hash(ctx, ht)
+	if err != nil || sum == "" {
+		return sum, err
+	}
+	return strings.Repeat("0", len(sum)), nil
+}
+
+func TestCopyAtomicUploadCorrupted(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if r.Fremote.Features().Move == nil || r.Fremote.Features().BucketBased {
+		t.Skip("Skipping test as remote can't rename uploads")
+	}
+	if r.Fremote.Hashes().Overlap(r.Flocal.Hashes()).GetOne() == hash.None {
+		t.Skip("Skipping test as remote has no common hash")
+	}
+	ci.AtomicUpload = true
+
+	file1 := r.WriteObject(ctx, "file1", "file1 contents", t1)
+	existing, err := r.Fremote.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+	r.WriteFile("file1", "file1 contents changed", t2)
+	src, err := r.Flocal.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+
+	// the partial fails the hash check so the existing file is
+	// returned untouched
+	newDst, err := operations.Copy(ctx, r.Fremote, existing, file1.Path, badHashObject{src})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "hash differ")
+	require.NotNil(t, newDst)
+	assert.Equal(t, existing, newDst)
+	fstest.CheckItems(t, r.Fremote, file1)
+}
+
+// notLocalFs makes an Fs look like a remote which isn't local so
+// atomic uploads move the existing file out of the way, with the
+// first move of a partial upload onto its final name failing if
+// failRename is set
+type notLocalFs struct {
+	fs.Fs
+	features   *fs.Features
+	failRename bool
+}
+
+func newNotLocalFs(f fs.Fs, failRename bool) *notLocalFs {
+	n := &notLocalFs{Fs: f, failRename: failRename}
+	features := *f.Features()
+	features.IsLocal = false
+	move := features.Move
+	features.Move = func(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+		if n.failRename && strings.HasSuffix(src.Remote(), ".partial") && !strings.HasSuffix(remote, ".partial") {
+			n.failRename = false
+			return nil, errors.New("rename failed")
+		}
+		return move(ctx, src, remote)
+	}
+	n.features = &features
+	return n
+}
+
+// Features returns the modified features
+func (n *notLocalFs) Features() *fs.Features {
+	return n.features
+}
+
+func TestCopyAtomicUploadNotLocal(t *testing.T) {
+	for _, failRename := range []bool{false, true} {
+		t.Run(fmt.Sprintf("failRename=%v", failRename), func(t *testing.T) {
+			ctx := context.Background()
+			ctx, ci := fs.AddConfig(ctx)
+			r := fstest.NewRun(t)
+			defer r.Finalise()
+			if r.Fremote.Features().Move == nil || r.Fremote.Features().BucketBased {
+				t.Skip("Skipping test as remote can't rename uploads")
+			}
+			ci.AtomicUpload = true
+			f := newNotLocalFs(r.Fremote, failRename)
+
+			file1 := r.WriteObject(ctx, "file1", "file1 contents", t1)
+			existing, err := r.Fremote.NewObject(ctx, file1.Path)
+			require.NoError(t, err)
+			file1changed := r.WriteFile("file1", "file1 contents changed", t2)
+			src, err := r.Flocal.NewObject(ctx, file1.Path)
+			require.NoError(t, err)
+
+			newDst, err := operations.Copy(ctx, f, existing, file1.Path, src)
+			if failRename {
+				// the existing file is put back when the
+				// rename fails
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "rename failed")
+				require.NotNil(t, newDst)
+				assert.Equal(t, file1.Path, newDst.Remote())
+				fstest.CheckItems(t, r.Fremote, file1)
+			} else {
+				// the existing file is replaced and the
+				// moved out copy of it removed
+				require.NoError(t, err)
+				require.NotNil(t, newDst)
+				fstest.CheckItems(t, r.Fremote, file1changed)
+			}
+		})
+	}
+}
+
+func TestCopyFileTransferHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test as it needs a shell")
+	}
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	var posted []operations.TransferHookInfo
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var info operations.TransferHookInfo
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&info))
+		posted = append(posted, info)
+	}))
+	defer ts.Close()
+
+	logDir, err := ioutil.TempDir("", "rclone-hooks")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(logDir)
+	}()
+	logFile := logDir + "/log"
+	ci.PreTransferCmd = fs.SpaceSepList{"sh", "-c", `echo "$RCLONE_TRANSFER_STAGE $RCLONE_TRANSFER_PATH $RCLONE_TRANSFER_SIZE" >> ` + logFile}
+	ci.PostTransferCmd = fs.SpaceSepList{"sh", "-c", `echo "$RCLONE_TRANSFER_STAGE $RCLONE_TRANSFER_PATH $RCLONE_TRANSFER_RESULT" >> ` + logFile}
+	ci.PostTransferURL = ts.URL
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	err = operations.CopyFile(ctx, r.Fremote, r.Flocal, file1.Path, file1.Path)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	log, err := ioutil.ReadFile(logFile)
+	require.NoError(t, err)
+	assert.Equal(t, "pre file1 14\npost file1 ok\n", string(log))
+	require.Len(t, posted, 1)
+	assert.Equal(t, "post", posted[0].Stage)
+	assert.Equal(t, "file1", posted[0].Path)
+	assert.Equal(t, int64(14), posted[0].Size)
+	assert.Equal(t, "ok", posted[0].Result)
+
+	// a failing pre transfer command stops the transfer
+	ci.PreTransferCmd = fs.SpaceSepList{"false"}
+	file2 := r.WriteFile("file2", "file2 contents", t1)
+	err = operations.CopyFile(ctx, r.Fremote, r.Flocal, file2.Path, file2.Path)
+	require.Error(t, err)
+	assert.False(t, fserrors.IsRetryError(err))
+	fstest.CheckItems(t, r.Fremote, file1)
+	require.Len(t, posted, 2)
+	assert.Equal(t, "error", posted[1].Result)
+}
+
+func TestCopyFileTransferFilterCmd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test as it needs tr and sh")
+	}
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	ci.TransferFilterCmd = fs.SpaceSepList{"tr", "a-z", "A-Z"}
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	err := operations.CopyFile(ctx, r.Fremote, r.Flocal, file1.Path, file1.Path)
+	require.NoError(t, err)
+	filtered1 := fstest.NewItem("file1", "FILE1 CONTENTS", t1)
+	fstest.CheckItems(t, r.Fremote, filtered1)
+
+	// a failing filter command fails the transfer
+	ci.TransferFilterCmd = fs.SpaceSepList{"sh", "-c", "cat; exit 1"}
+	file2 := r.WriteFile("file2", "file2 contents", t1)
+	err = operations.CopyFile(ctx, r.Fremote, r.Flocal, file2.Path, file2.Path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "transfer filter command")
+	fstest.CheckItems(t, r.Fremote, filtered1)
+}
+
+// fakeClamd answers clamd INSTREAM requests flagging data containing
+// "INFECTED"
+func fakeClamd(t *testing.T) (address string, stop func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			_, _ = r.ReadString(0)
+			var data []byte
+			for {
+				var size uint32
+				if binary.Read(r, binary.BigEndian, &size) != nil || size == 0 {
+					break
+				}
+				chunk := make([]byte, size)
+				_, _ = io.ReadFull(r, chunk)
+				data = append(data, chunk...)
+			}
+			if bytes.Contains(data, []byte("INFECTED")) {
+				_, _ = conn.Write([]byte("stream: Test-Signature FOUND\x00"))
+			} else {
+				_, _ = conn.Write([]byte("stream: OK\x00"))
+			}
+			_ = conn.Close()
+		}
+	}()
+	return l.Addr().String(), func() { _ = l.Close() }
+}
+
+func TestCopyFileScan(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	address, stop := fakeClamd(t)
+	defer stop()
+	ci.ScanClamd = address
+	quarantineDir, err := ioutil.TempDir("", "rclone-quarantine")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(quarantineDir)
+	}()
+	ci.ScanQuarantine = quarantineDir
+	accounting.GlobalStats().ResetCounters()
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	err = operations.CopyFile(ctx, r.Fremote, r.Flocal, file1.Path, file1.Path)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	file2 := r.WriteFile("dir/file2", "file2 INFECTED contents", t1)
+	err = operations.CopyFile(ctx, r.Fremote, r.Flocal, file2.Path, file2.Path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Test-Signature")
+	assert.False(t, fserrors.IsRetryError(err))
+	fstest.CheckItems(t, r.Fremote, file1)
+	assert.Equal(t, int64(1), accounting.GlobalStats().Flagged(0))
+
+	fquarantine, err := fs.NewFs(ctx, quarantineDir)
+	require.NoError(t, err)
+	fstest.CheckItems(t, fquarantine, file2)
+}
+
+func TestCopyFileReport(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	reportDir, err := ioutil.TempDir("", "rclone-report")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(reportDir)
+	}()
+	ci.Report = reportDir + "/report.json"
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	err = operations.CopyFile(ctx, r.Fremote, r.Flocal, file1.Path, file1.Path)
+	require.NoError(t, err)
+	err = operations.CopyFile(ctx, r.Fremote, r.Flocal, file1.Path, file1.Path)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1)
+	dst, err := r.Fremote.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+	require.NoError(t, operations.DeleteFile(ctx, dst))
+
+	// a copy of unknown size goes through Rcat which copies again
+	// but is only reported once
+	file2 := r.WriteFile("file2", "file2 contents", t1)
+	src2, err := r.Flocal.NewObject(ctx, file2.Path)
+	require.NoError(t, err)
+	_, err = operations.Copy(ctx, r.Fremote, nil, file2.Path, unknownSizeObject{src2})
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadFile(ci.Report)
+	require.NoError(t, err)
+	var entries []operations.ReportEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e operations.ReportEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &e), line)
+		entries = append(entries, e)
+	}
+	require.Len(t, entries, 4)
+
+	assert.Equal(t, operations.ReportCopy, entries[0].Action)
+	assert.Equal(t, "file1", entries[0].Path)
+	assert.Equal(t, fs.ConfigString(r.Flocal), entries[0].Src)
+	assert.Equal(t, fs.ConfigString(r.Fremote), entries[0].Dst)
+	assert.Equal(t, int64(14), entries[0].Size)
+	assert.Equal(t, "Need to transfer - File not found at Destination", entries[0].Reason)
+	assert.Equal(t, "Copied (new)", entries[0].Detail)
+
+	assert.Equal(t, operations.ReportSkip, entries[1].Action)
+	assert.Equal(t, "file1", entries[1].Path)
+	assert.Equal(t, "Unchanged skipping", entries[1].Reason)
+
+	assert.Equal(t, operations.ReportDelete, entries[2].Action)
+	assert.Equal(t, "file1", entries[2].Path)
+	assert.Equal(t, fs.ConfigString(r.Fremote), entries[2].Dst)
+	assert.Equal(t, "delete", entries[2].Detail)
+
+	assert.Equal(t, operations.ReportCopy, entries[3].Action)
+	assert.Equal(t, "file2", entries[3].Path)
+	assert.Equal(t, "Copied (Rcat, new)", entries[3].Detail)
+}
+
+// unknownSizeObject is an fs.Object whose size isn't known
+type unknownSizeObject struct {
+	fs.Object
+}
+
+// Size returns -1 as the size isn't known
+func (o unknownSizeObject) Size() int64 {
+	return -1
+}
+
+func TestDryRunEstimate(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	ci.DryRun = true
+	ci.Estimate = true
+	accounting.GlobalStats().ResetCounters()
+	defer accounting.GlobalStats().ResetCounters()
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	file2 := r.WriteFile("file2", "file2 longer contents", t1)
+
+	// copies to a different backend are streamed
+	fmemory, err := fs.NewFs(ctx, ":memory:estimate")
+	require.NoError(t, err)
+	err = operations.CopyFile(ctx, fmemory, r.Flocal, file1.Path, file1.Path)
+	require.NoError(t, err)
+	fstest.CheckItems(t, fmemory)
+
+	out, err := accounting.GlobalStats().RemoteStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), out["estimatedStreams"])
+	assert.Equal(t, int64(14), out["estimatedStreamBytes"])
+	assert.Equal(t, int64(0), out["estimatedServerSide"])
+
+	// moves within the local backend can be done server-side
+	err = operations.MoveFile(ctx, r.Flocal, r.Flocal, "file3", file2.Path)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+
+	out, err = accounting.GlobalStats().RemoteStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), out["estimatedStreams"])
+	assert.Equal(t, int64(1), out["estimatedServerSide"])
+	assert.Equal(t, int64(21), out["estimatedServerSideBytes"])
+}
+
 func TestCopyFileBackupDir(t *testing.T) {
 	ctx := context.Background()
 	ctx, ci := fs.AddConfig(ctx)
@@ -1210,8 +1817,10 @@ func TestListFormat(t *testing.T) {
 			"sha1":     "86f7e437faa5a7fce15d1ddcb9eaeaea377667b8",
 			"dropbox":  "bf5d3affb73efd2ec6c36ad3112dd933efed63c4e1cbffcfa88e2759c144f2d8",
 			"quickxor": "6100000000000000000000000100000000000000"},
-		ID:     "fileID",
-		OrigID: "fileOrigID",
+		ID:       "fileID",
+		OrigID:   "fileOrigID",
+		Tier:     "hot",
+		Metadata: fs.Metadata{"mode": "644"},
 	}
 
 	item1 := &operations.ListJSONItem{
@@ -1262,6 +1871,13 @@ func TestListFormat(t *testing.T) {
 	assert.Contains(t, list.Format(item0), "/")
 	assert.Equal(t, "inode/directory", list.Format(item1))
 
+	list.SetOutput(nil)
+	list.AddTier()
+	list.AddMetadata("mode")
+	assert.Equal(t, "Tier|mode", list.Header())
+	assert.Equal(t, "hot|644", list.Format(item0))
+	assert.Equal(t, "|", list.Format(item1))
+
 	list.SetOutput(nil)
 	list.AddPath()
 	list.SetAbsolute(true)
@@ -31,6 +31,9 @@ func init() {
     - showEncrypted -  If set show decrypted names
     - showOrigIDs - If set show the IDs for each item if known
     - showHash - If set return a dictionary of hashes
+    - filesOnly - If set only show files
+    - dirsOnly - If set only show directories
+    - metadata - If set return a dictionary of metadata
 
 The result is
 
@@ -66,6 +69,51 @@ func rcList(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	return out, nil
 }
 
+func init() {
+	rc.Add(rc.Call{
+		Path:         "operations/stat",
+		AuthRequired: true,
+		Fn:           rcStat,
+		Title:        "Give information about the supplied file or directory",
+		Help: `This takes the following parameters
+
+- fs - a remote name string e.g. "drive:"
+- remote - a path within that remote e.g. "dir"
+- opt - a dictionary of options to control the listing (optional)
+    - see operations/list for the options
+
+The result is
+
+- item - an object as described in the lsjson command. Will be null if not found.
+
+Note that if you are only interested in files then it is much more
+efficient to set the filesOnly flag in the options.
+
+See the [lsjson command](/commands/rclone_lsjson/) for more information on the above and examples.
+`,
+	})
+}
+
+// Stat a file or directory
+func rcStat(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	f, remote, err := rc.GetFsAndRemote(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	var opt ListJSONOpt
+	err = in.GetStruct("opt", &opt)
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	item, err := StatJSON(ctx, f, remote, &opt)
+	if err != nil {
+		return nil, err
+	}
+	out = make(rc.Params)
+	out["item"] = item
+	return out, nil
+}
+
 func init() {
 	rc.Add(rc.Call{
 		Path:         "operations/about",
@@ -78,7 +126,7 @@ func init() {
 
 The result is as returned from rclone about --json
 
-See the [about command](/commands/rclone_size/) command for more information on the above.
+See the [about command](/commands/rclone_about/) command for more information on the above.
 `,
 	})
 }
@@ -305,6 +353,50 @@ func rcSize(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	return out, nil
 }
 
+func init() {
+	rc.Add(rc.Call{
+		Path:         "operations/settier",
+		AuthRequired: true,
+		Fn:           rcSetTier,
+		Title:        "Change the storage tier of the objects in remote",
+		Help: `This takes the following parameters
+
+- fs - a remote name string e.g. "s3:bucket/path/to/dir"
+- tier - the tier to change the objects to e.g. "GLACIER"
+
+Returns
+
+- before - the count and bytes of the objects in each tier before
+- after - the count and bytes of the objects in each tier after
+- changed - the count and bytes of the objects changed
+- errors - the count and bytes of the objects which failed to change
+
+See the [settier command](/commands/rclone_settier/) command for more information on the above.
+`,
+	})
+}
+
+// Set the tier of the objects in a directory
+func rcSetTier(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	f, err := rc.GetFs(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	tier, err := in.GetString("tier")
+	if err != nil {
+		return nil, err
+	}
+	result, err := SetTier(ctx, f, tier)
+	if err != nil {
+		return nil, err
+	}
+	err = rc.Reshape(&out, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "settier Reshape failed")
+	}
+	return out, nil
+}
+
 func init() {
 	rc.Add(rc.Call{
 		Path:         "operations/publiclink",
@@ -317,11 +409,33 @@ func init() {
 - remote - a path within that remote e.g. "dir"
 - unlink - boolean - if set removes the link rather than adding it (optional)
 - expire - string - the expiry time of the link e.g. "1d" (optional)
+- password - string - the password needed to use the link (optional)
 
 Returns
 
 - url - URL of the resource
 
+See the [link command](/commands/rclone_link/) command for more information on the above.
+`,
+	})
+	rc.Add(rc.Call{
+		Path:         "operations/listpubliclinks",
+		AuthRequired: true,
+		Fn:           rcListPublicLinks,
+		Title:        "List the public links to the given file or folder.",
+		Help: `This takes the following parameters
+
+- fs - a remote name string e.g. "drive:"
+- remote - a path within that remote e.g. "dir"
+
+Returns
+
+- links - an array of the links, each with
+    - path - the path of the file or folder
+    - url - URL of the resource
+    - expires - when the link expires, if it does
+    - password - true if the link needs a password
+
 See the [link command](/commands/rclone_link/) command for more information on the above.
 `,
 	})
@@ -338,7 +452,16 @@ func rcPublicLink(ctx context.Context, in rc.Params) (out rc.Params, err error)
 	if err != nil && !rc.IsErrParamNotFound(err) {
 		return nil, err
 	}
-	url, err := PublicLink(ctx, f, remote, fs.Duration(expire), unlink)
+	password, err := in.GetString("password")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	var url string
+	if password != "" && !unlink {
+		url, err = PublicLinkWithPassword(ctx, f, remote, fs.Duration(expire), password)
+	} else {
+		url, err = PublicLink(ctx, f, remote, fs.Duration(expire), unlink)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -347,6 +470,21 @@ func rcPublicLink(ctx context.Context, in rc.Params) (out rc.Params, err error)
 	return out, nil
 }
 
+// List the public links
+func rcListPublicLinks(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	f, remote, err := rc.GetFsAndRemote(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	links, err := ListPublicLinks(ctx, f, remote)
+	if err != nil {
+		return nil, err
+	}
+	out = make(rc.Params)
+	out["links"] = links
+	return out, nil
+}
+
 func init() {
 	rc.Add(rc.Call{
 		Path:  "operations/fsinfo",
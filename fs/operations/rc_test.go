@@ -12,6 +12,7 @@ import (
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/fstest"
@@ -261,6 +262,64 @@ func TestRcList(t *testing.T) {
 	checkFile2(list[2])
 }
 
+// operations/stat: Stat the given remote and path in JSON format.
+func TestRcStat(t *testing.T) {
+	r, call := rcNewRun(t, "operations/stat")
+	defer r.Finalise()
+
+	file1 := r.WriteObject(context.Background(), "subdir/a", "a", t1)
+
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	stat := func(remote string, opt rc.Params) *operations.ListJSONItem {
+		in := rc.Params{
+			"fs":     r.FremoteName,
+			"remote": remote,
+		}
+		if opt != nil {
+			in["opt"] = opt
+		}
+		out, err := call.Fn(context.Background(), in)
+		require.NoError(t, err)
+		return out["item"].(*operations.ListJSONItem)
+	}
+
+	t.Run("File", func(t *testing.T) {
+		got := stat("subdir/a", rc.Params{"showHash": true})
+		require.NotNil(t, got)
+		assert.WithinDuration(t, t1, got.ModTime.When, time.Second)
+		assert.Equal(t, "subdir/a", got.Path)
+		assert.Equal(t, "a", got.Name)
+		assert.Equal(t, int64(1), got.Size)
+		assert.Equal(t, false, got.IsDir)
+		if r.Fremote.Hashes().Contains(hash.MD5) {
+			assert.Equal(t, "0cc175b9c0f1b6a831c399e269772661", got.Hashes[hash.MD5.String()])
+		}
+	})
+
+	t.Run("Dir", func(t *testing.T) {
+		got := stat("subdir", nil)
+		require.NotNil(t, got)
+		assert.Equal(t, "subdir", got.Path)
+		assert.Equal(t, "subdir", got.Name)
+		assert.Equal(t, true, got.IsDir)
+		assert.Nil(t, stat("subdir", rc.Params{"filesOnly": true}))
+	})
+
+	t.Run("Root", func(t *testing.T) {
+		got := stat("", nil)
+		require.NotNil(t, got)
+		assert.Equal(t, "", got.Path)
+		assert.Equal(t, true, got.IsDir)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		assert.Nil(t, stat("subdir/notfound", nil))
+		assert.Nil(t, stat("notfound/a", nil))
+		assert.Nil(t, stat("subdir/a", rc.Params{"dirsOnly": true}))
+	})
+}
+
 // operations/mkdir: Make a destination directory or container
 func TestRcMkdir(t *testing.T) {
 	ctx := context.Background()
@@ -416,6 +475,24 @@ func TestRcPublicLink(t *testing.T) {
 	_, err := call.Fn(context.Background(), in)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "doesn't support public links")
+
+	in["password"] = "potato"
+	_, err = call.Fn(context.Background(), in)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "doesn't support public links with passwords")
+}
+
+// operations/listpubliclinks: List the public links to the given file or folder.
+func TestRcListPublicLinks(t *testing.T) {
+	r, call := rcNewRun(t, "operations/listpubliclinks")
+	defer r.Finalise()
+	in := rc.Params{
+		"fs":     r.FremoteName,
+		"remote": "",
+	}
+	_, err := call.Fn(context.Background(), in)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "doesn't support listing public links")
 }
 
 // operations/fsinfo: Return information about the remote
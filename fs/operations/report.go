@@ -0,0 +1,227 @@
+// Report of the action taken on each file written with --report
+
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/atexit"
+)
+
+// Actions recorded in the report
+const (
+	ReportCopy   = "copy"   // Path was copied from Src to Dst
+	ReportMove   = "move"   // Path was moved from Src to Dst
+	ReportRename = "rename" // From was renamed to Path on Dst
+	ReportDelete = "delete" // Path was deleted from Dst
+	ReportSkip   = "skip"   // Path didn't need transferring
+	ReportError  = "error"  // an action on Path failed
+)
+
+// ReportEntry is one line of the report
+type ReportEntry struct {
+	Time     time.Time `json:"time"`               // when the action started
+	Action   string    `json:"action"`             // one of the Report* constants
+	Path     string    `json:"path"`               // path of the file relative to the roots
+	From     string    `json:"from,omitempty"`     // the old path of a renamed file
+	Src      string    `json:"src,omitempty"`      // the source remote
+	Dst      string    `json:"dst,omitempty"`      // the remote acted on
+	Size     int64     `json:"size"`               // size of the file or -1 if unknown
+	Reason   string    `json:"reason,omitempty"`   // why the action was taken
+	Detail   string    `json:"detail,omitempty"`   // how the action was done or which action failed
+	Error    string    `json:"error,omitempty"`    // the error if the action failed
+	Duration float64   `json:"duration,omitempty"` // time taken in seconds
+}
+
+// report writes ReportEntry~s to the --report file.
+//
+// The methods may be called on a nil *report in which case they do
+// nothing.
+type report struct {
+	mu      sync.Mutex
+	path    string
+	fd      *os.File
+	enc     *json.Encoder
+	reasons map[string]string // why the files waiting to be transferred need it
+}
+
+var (
+	reportMu      sync.Mutex
+	globalReport  *report
+	reportAtexit  atexit.FnHandle
+	reportOpenErr error
+)
+
+// getReport returns the report set with --report, opening it if
+// necessary, or nil if there isn't one
+func getReport(ctx context.Context) *report {
+	path := fs.GetConfig(ctx).Report
+	if path == "" {
+		return nil
+	}
+	reportMu.Lock()
+	defer reportMu.Unlock()
+	if globalReport != nil && globalReport.path == path {
+		return globalReport
+	}
+	if globalReport != nil {
+		globalReport.close()
+	}
+	globalReport = nil
+	fd, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		if reportOpenErr == nil {
+			fs.Errorf(nil, "Failed to open --report: %v", err)
+		}
+		reportOpenErr = err
+		return nil
+	}
+	reportOpenErr = nil
+	globalReport = &report{
+		path:    path,
+		fd:      fd,
+		enc:     json.NewEncoder(fd),
+		reasons: make(map[string]string),
+	}
+	if reportAtexit == nil {
+		reportAtexit = atexit.Register(func() {
+			reportMu.Lock()
+			defer reportMu.Unlock()
+			globalReport.close()
+		})
+	}
+	return globalReport
+}
+
+// write e to the report
+func (r *report) write(e ReportEntry) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fd == nil {
+		return
+	}
+	if err := r.enc.Encode(e); err != nil {
+		fs.Errorf(nil, "Failed to write --report: %v", err)
+	}
+}
+
+// close the report
+func (r *report) close() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fd == nil {
+		return
+	}
+	if err := r.fd.Close(); err != nil {
+		fs.Errorf(nil, "Failed to close --report: %v", err)
+	}
+	r.fd = nil
+}
+
+// setReason records why remote needs transferring for its entry
+func (r *report) setReason(remote, reason string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.reasons[remote] = reason
+	r.mu.Unlock()
+}
+
+// popReason returns and forgets why remote needed transferring
+func (r *report) popReason(remote string) string {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reason := r.reasons[remote]
+	delete(r.reasons, remote)
+	return reason
+}
+
+// reportTransfer records the result err of the action taken on src
+// to put it at remote on fdst which started at start
+func reportTransfer(ctx context.Context, action string, fdst fs.Fs, remote string, src fs.ObjectInfo, start time.Time, detail string, err error) {
+	r := getReport(ctx)
+	if r == nil {
+		return
+	}
+	e := ReportEntry{
+		Time:     start,
+		Action:   action,
+		Path:     remote,
+		Src:      infoString(src.Fs()),
+		Dst:      fs.ConfigString(fdst),
+		Size:     src.Size(),
+		Reason:   r.popReason(src.Remote()),
+		Detail:   detail,
+		Duration: time.Since(start).Seconds(),
+	}
+	if action == ReportRename {
+		e.From = src.Remote()
+		e.Src = ""
+	}
+	if err != nil {
+		e.Action = ReportError
+		e.Detail = "failed to " + action
+		e.Error = err.Error()
+	}
+	r.write(e)
+}
+
+// reportDelete records the result err of deleting dst with action
+// which started at start
+func reportDelete(ctx context.Context, dst fs.Object, start time.Time, action string, err error) {
+	r := getReport(ctx)
+	if r == nil {
+		return
+	}
+	e := ReportEntry{
+		Time:     start,
+		Action:   ReportDelete,
+		Path:     dst.Remote(),
+		Dst:      infoString(dst.Fs()),
+		Size:     dst.Size(),
+		Detail:   action,
+		Duration: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		e.Action = ReportError
+		e.Detail = "failed to " + action
+		e.Error = err.Error()
+	}
+	r.write(e)
+}
+
+// reportNeedTransfer records why src does or doesn't need
+// transferring
+func reportNeedTransfer(ctx context.Context, src fs.ObjectInfo, need bool, reason string) {
+	r := getReport(ctx)
+	if r == nil {
+		return
+	}
+	if need {
+		r.setReason(src.Remote(), reason)
+		return
+	}
+	r.write(ReportEntry{
+		Time:   time.Now(),
+		Action: ReportSkip,
+		Path:   src.Remote(),
+		Src:    infoString(src.Fs()),
+		Size:   src.Size(),
+		Reason: reason,
+	})
+}
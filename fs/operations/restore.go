@@ -0,0 +1,88 @@
+package operations
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
+)
+
+// ListTrash lists the deleted objects in dir of f and its
+// subdirectories which pass the filters and which were deleted at or
+// after since, unless it is zero.
+//
+// The entries are sorted by path, most recently deleted first.
+func ListTrash(ctx context.Context, f fs.Fs, dir string, since time.Time) ([]fs.TrashEntry, error) {
+	do := f.Features().ListTrash
+	if do == nil {
+		return nil, errors.Errorf("%v doesn't support listing deleted files", f)
+	}
+	entries, err := do(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	fi := filter.GetConfig(ctx)
+	var out []fs.TrashEntry
+	for _, entry := range entries {
+		if !fi.IncludeRemote(entry.Remote) {
+			continue
+		}
+		if !since.IsZero() && entry.Deleted.Before(since) {
+			if entry.Deleted.IsZero() {
+				fs.Debugf(entry.Remote, "Ignoring deleted file as don't know when it was deleted")
+			}
+			continue
+		}
+		out = append(out, entry)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Remote != out[j].Remote {
+			return out[i].Remote < out[j].Remote
+		}
+		return out[i].Deleted.After(out[j].Deleted)
+	})
+	return out, nil
+}
+
+// Restore restores the deleted objects in dir of f and its
+// subdirectories which pass the filters and which were deleted at or
+// after since, unless it is zero.
+//
+// Only the most recently deleted version of each object is restored
+// and objects which exist already are left alone.
+func Restore(ctx context.Context, f fs.Fs, dir string, since time.Time) error {
+	undelete := f.Features().Undelete
+	if undelete == nil {
+		return errors.Errorf("%v doesn't support restoring deleted files", f)
+	}
+	entries, err := ListTrash(ctx, f, dir, since)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for i, entry := range entries {
+		if i > 0 && entries[i-1].Remote == entry.Remote {
+			fs.Debugf(entry.Remote, "Not restoring older deleted version from %v", entry.Deleted)
+			continue
+		}
+		if o, err := f.NewObject(ctx, entry.Remote); err == nil {
+			fs.Logf(o, "Not restoring as it exists already")
+			continue
+		}
+		if SkipDestructive(ctx, entry.Remote, "restore") {
+			continue
+		}
+		o, err := undelete(ctx, entry)
+		if err != nil {
+			err = fs.CountError(err)
+			fs.Errorf(entry.Remote, "Failed to restore: %v", err)
+			lastErr = err
+			continue
+		}
+		fs.Infof(o, "Restored")
+	}
+	return lastErr
+}
@@ -0,0 +1,102 @@
+package operations_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTrashFs makes a mock Fs with a trash containing trash and
+// the objects in existing
+func newTrashFs(ctx context.Context, trash []fs.TrashEntry, existing ...string) (f *mockfs.Fs, restored *[]fs.TrashEntry) {
+	f = mockfs.NewFs(ctx, "mock", "")
+	for _, remote := range existing {
+		f.AddObject(mockobject.New(remote))
+	}
+	restored = &[]fs.TrashEntry{}
+	f.Features().ListTrash = func(ctx context.Context, dir string) ([]fs.TrashEntry, error) {
+		return append([]fs.TrashEntry{}, trash...), nil
+	}
+	f.Features().Undelete = func(ctx context.Context, entry fs.TrashEntry) (fs.Object, error) {
+		if entry.Remote == "fail" {
+			return nil, errors.New("undelete failed")
+		}
+		*restored = append(*restored, entry)
+		return mockobject.New(entry.Remote), nil
+	}
+	return f, restored
+}
+
+func TestListTrash(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	trash := []fs.TrashEntry{
+		{Remote: "b", Deleted: now.Add(-time.Hour)},
+		{Remote: "a", Deleted: now.Add(-48 * time.Hour)},
+		{Remote: "b", Deleted: now.Add(-time.Minute)},
+		{Remote: "c"},
+		{Remote: "d.jpg", Deleted: now},
+	}
+	f, _ := newTrashFs(ctx, trash)
+
+	entries, err := operations.ListTrash(ctx, f, "", time.Time{})
+	require.NoError(t, err)
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Remote)
+	}
+	assert.Equal(t, []string{"a", "b", "b", "c", "d.jpg"}, got)
+	assert.Equal(t, now.Add(-time.Minute), entries[1].Deleted, "most recent first")
+
+	entries, err = operations.ListTrash(ctx, f, "", now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	got = nil
+	for _, entry := range entries {
+		got = append(got, entry.Remote)
+	}
+	assert.Equal(t, []string{"b", "b", "d.jpg"}, got)
+
+	// with a filter
+	ctx, fi := filter.AddConfig(ctx)
+	require.NoError(t, fi.AddRule("- *.jpg"))
+	entries, err = operations.ListTrash(ctx, f, "", time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+
+	// not supported
+	_, err = operations.ListTrash(ctx, mockfs.NewFs(ctx, "mock", ""), "", time.Time{})
+	assert.Error(t, err)
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	trash := []fs.TrashEntry{
+		{Remote: "a", Deleted: now.Add(-time.Hour), ID: "old"},
+		{Remote: "a", Deleted: now.Add(-time.Minute), ID: "new"},
+		{Remote: "exists", Deleted: now},
+		{Remote: "fail", Deleted: now},
+		{Remote: "z", Deleted: now},
+	}
+	f, restored := newTrashFs(ctx, trash, "exists")
+
+	err := operations.Restore(ctx, f, "", time.Time{})
+	require.Error(t, err)
+	assert.Equal(t, []fs.TrashEntry{trash[1], trash[4]}, *restored)
+
+	// dry run
+	f, restored = newTrashFs(ctx, trash[:2])
+	ctx, ci := fs.AddConfig(ctx)
+	ci.DryRun = true
+	require.NoError(t, operations.Restore(ctx, f, "", time.Time{}))
+	assert.Len(t, *restored, 0)
+}
@@ -0,0 +1,96 @@
+// Scan files for malware before they are transferred
+
+package operations
+
+import (
+	"context"
+	"net"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/lib/scan"
+)
+
+// newScanner returns the scanner set with --scan-clamd or
+// --scan-icap or nil if neither is set
+func newScanner(ctx context.Context) (scan.Scanner, error) {
+	ci := fs.GetConfig(ctx)
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		if network == "unix" {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		}
+		return fshttp.NewDialer(ctx).DialContext(ctx, network, address)
+	}
+	switch {
+	case ci.ScanClamd != "" && ci.ScanICAP != "":
+		return nil, errors.New("can't use --scan-clamd and --scan-icap together")
+	case ci.ScanClamd != "":
+		return scan.NewClamd(ci.ScanClamd, dial), nil
+	case ci.ScanICAP != "":
+		return scan.NewICAP(ci.ScanICAP, dial)
+	}
+	return nil, nil
+}
+
+// scanObject reads src and returns the name of the threat the
+// scanner found in it or "" if it is clean
+func scanObject(ctx context.Context, scanner scan.Scanner, src fs.Object) (threat string, err error) {
+	in, err := src.Open(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to open file to scan")
+	}
+	defer fs.CheckClose(in, &err)
+	return scanner.Scan(ctx, in)
+}
+
+// scanTransfer scans src before it is transferred to remote if a
+// scanner is set.
+//
+// If the scanner flags src it is copied to --scan-quarantine if set
+// and an error is returned so it isn't transferred. The error isn't
+// retried.
+func scanTransfer(ctx context.Context, remote string, src fs.Object) error {
+	scanner, err := newScanner(ctx)
+	if err != nil || scanner == nil {
+		return err
+	}
+	threat, err := scanObject(ctx, scanner, src)
+	if err != nil {
+		return errors.Wrapf(err, "failed to scan with %v", scanner)
+	}
+	if threat == "" {
+		fs.Debugf(src, "Scanned with %v: OK", scanner)
+		return nil
+	}
+	accounting.Stats(ctx).Flagged(1)
+	err = errors.Errorf("flagged by %v: %s", scanner, threat)
+	if quarantine := fs.GetConfig(ctx).ScanQuarantine; quarantine != "" {
+		if qErr := quarantineObject(ctx, quarantine, remote, src); qErr != nil {
+			fs.Errorf(src, "Failed to quarantine: %v", qErr)
+		} else {
+			fs.Infof(src, "Quarantined to %s", quarantine)
+		}
+	}
+	return fserrors.NoRetryError(err)
+}
+
+// quarantineObject copies src to remote in the quarantine directory
+// without scanning it again
+func quarantineObject(ctx context.Context, quarantine string, remote string, src fs.Object) error {
+	fquarantine, err := cache.Get(ctx, quarantine)
+	if err != nil {
+		return errors.Wrap(err, "failed to make fs for --scan-quarantine")
+	}
+	ctx = withoutTransferHooks(ctx)
+	dst, err := fquarantine.NewObject(ctx, remote)
+	if err != nil {
+		dst = nil
+	}
+	_, err = Copy(ctx, fquarantine, dst, remote, src)
+	return err
+}
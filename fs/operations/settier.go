@@ -0,0 +1,143 @@
+package operations
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+)
+
+// TierStats is the number of objects and bytes in a tier
+type TierStats struct {
+	Count int64 `json:"count"`
+	Bytes int64 `json:"bytes"`
+}
+
+// add o to the stats
+func (ts *TierStats) add(o fs.Object) {
+	ts.Count++
+	if size := o.Size(); size > 0 {
+		ts.Bytes += size
+	}
+}
+
+// SetTierResult describes the objects SetTier found and changed
+type SetTierResult struct {
+	Before  map[string]*TierStats `json:"before"`  // objects by tier before
+	After   map[string]*TierStats `json:"after"`   // objects by tier after
+	Changed TierStats             `json:"changed"` // objects moved to the new tier
+	Errors  TierStats             `json:"errors"`  // objects which failed to change
+}
+
+// getTier returns the tier of o or "" if unknown
+func getTier(o fs.Object) string {
+	if do, ok := o.(fs.GetTierer); ok {
+		return do.GetTier()
+	}
+	return ""
+}
+
+// tierOf returns the stats for tier in tiers, creating them if needed
+func tierOf(tiers map[string]*TierStats, tier string) *TierStats {
+	ts := tiers[tier]
+	if ts == nil {
+		ts = &TierStats{}
+		tiers[tier] = ts
+	}
+	return ts
+}
+
+// SetTier changes the tier of all the objects in fsrc to tier
+//
+// This obeys the filters so can be used to change the tier of, for
+// example, only the objects older than --min-age. Objects which are
+// already in tier are left alone. Depending on the backend the tier
+// is either changed in place or by copying the object to itself
+// server-side.
+//
+// --checkers objects are changed at once. It returns the number of
+// objects and bytes in each tier before and after.
+func SetTier(ctx context.Context, fsrc fs.Fs, tier string) (*SetTierResult, error) {
+	if !fsrc.Features().SetTier {
+		return nil, errors.Errorf("remote %s does not support settier", fsrc.Name())
+	}
+	ci := fs.GetConfig(ctx)
+	result := &SetTierResult{
+		Before: map[string]*TierStats{},
+		After:  map[string]*TierStats{},
+	}
+	var mu sync.Mutex // protects result
+	var wg sync.WaitGroup
+	in := make(fs.ObjectsChan, ci.Checkers)
+	var lastErr error
+	for i := 0; i < ci.Checkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for o := range in {
+				oldTier := getTier(o)
+				newTier, err := setTier(ctx, o, oldTier, tier)
+				if err != nil {
+					err = fs.CountError(err)
+					fs.Errorf(o, "Failed to set tier to %s: %v", tier, err)
+				}
+				mu.Lock()
+				tierOf(result.Before, oldTier).add(o)
+				tierOf(result.After, newTier).add(o)
+				if err != nil {
+					result.Errors.add(o)
+					lastErr = err
+				} else if newTier != oldTier {
+					result.Changed.add(o)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	err := ListFn(ctx, fsrc, func(o fs.Object) {
+		in <- o
+	})
+	close(in)
+	wg.Wait()
+	if err != nil {
+		return result, err
+	}
+	if result.Errors.Count > 0 {
+		return result, errors.Wrapf(lastErr, "failed to set tier on %d objects", result.Errors.Count)
+	}
+	return result, nil
+}
+
+// setTier sets the tier of o which is currently oldTier to tier
+// returning the tier it is in afterwards
+func setTier(ctx context.Context, o fs.Object, oldTier, tier string) (newTier string, err error) {
+	tr := accounting.Stats(ctx).NewCheckingTransfer(o)
+	defer func() {
+		tr.Done(ctx, err)
+	}()
+	if strings.EqualFold(oldTier, tier) {
+		fs.Debugf(o, "Already in tier %s", oldTier)
+		return oldTier, nil
+	}
+	do, ok := o.(fs.SetTierer)
+	if !ok {
+		return oldTier, errors.New("object does not implement SetTier")
+	}
+	if SkipDestructive(ctx, o, "set tier") {
+		return tier, nil
+	}
+	err = do.SetTier(tier)
+	if err != nil {
+		return oldTier, err
+	}
+	// use the tier as the backend reports it if possible
+	newTier = getTier(o)
+	if newTier == "" || newTier == oldTier {
+		newTier = tier
+	}
+	fs.Infof(o, "Set tier to %s from %s", newTier, oldTier)
+	return newTier, nil
+}
@@ -0,0 +1,117 @@
+package operations_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tierObject is a mock object with a tier
+type tierObject struct {
+	*mockobject.ContentMockObject
+	mu   sync.Mutex
+	tier string
+	fail bool
+}
+
+func (o *tierObject) SetTier(tier string) error {
+	if o.fail {
+		return errors.New("set tier failed")
+	}
+	o.mu.Lock()
+	o.tier = tier
+	o.mu.Unlock()
+	return nil
+}
+
+func (o *tierObject) GetTier() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.tier
+}
+
+func newTierFs(ctx context.Context, objects ...*tierObject) fs.Fs {
+	f := mockfs.NewFs(ctx, "mock", "")
+	f.Features().SetTier = true
+	f.Features().GetTier = true
+	for _, o := range objects {
+		f.AddObject(o)
+	}
+	return f
+}
+
+func newTierObject(remote, contents, tier string) *tierObject {
+	return &tierObject{
+		ContentMockObject: mockobject.New(remote).WithContent([]byte(contents), mockobject.SeekModeNone),
+		tier:              tier,
+	}
+}
+
+func TestSetTier(t *testing.T) {
+	ctx := context.Background()
+	o1 := newTierObject("a", "a", "STANDARD")
+	o2 := newTierObject("b", "bb", "STANDARD")
+	o3 := newTierObject("c", "ccc", "GLACIER")
+	f := newTierFs(ctx, o1, o2, o3)
+
+	result, err := operations.SetTier(ctx, f, "GLACIER")
+	require.NoError(t, err)
+	assert.Equal(t, "GLACIER", o1.GetTier())
+	assert.Equal(t, "GLACIER", o2.GetTier())
+	assert.Equal(t, map[string]*operations.TierStats{
+		"STANDARD": {Count: 2, Bytes: 3},
+		"GLACIER":  {Count: 1, Bytes: 3},
+	}, result.Before)
+	assert.Equal(t, map[string]*operations.TierStats{
+		"GLACIER": {Count: 3, Bytes: 6},
+	}, result.After)
+	assert.Equal(t, operations.TierStats{Count: 2, Bytes: 3}, result.Changed)
+	assert.Equal(t, operations.TierStats{}, result.Errors)
+}
+
+func TestSetTierDryRun(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.DryRun = true
+	o1 := newTierObject("a", "a", "STANDARD")
+	f := newTierFs(ctx, o1)
+
+	result, err := operations.SetTier(ctx, f, "GLACIER")
+	require.NoError(t, err)
+	assert.Equal(t, "STANDARD", o1.GetTier())
+	assert.Equal(t, map[string]*operations.TierStats{
+		"GLACIER": {Count: 1, Bytes: 1},
+	}, result.After)
+	assert.Equal(t, operations.TierStats{Count: 1, Bytes: 1}, result.Changed)
+}
+
+func TestSetTierErrors(t *testing.T) {
+	ctx := context.Background()
+	o1 := newTierObject("a", "a", "Hot")
+	o2 := newTierObject("b", "bb", "Hot")
+	o2.fail = true
+	f := newTierFs(ctx, o1, o2)
+
+	result, err := operations.SetTier(ctx, f, "Cool")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to set tier on 1 objects")
+	assert.Equal(t, map[string]*operations.TierStats{
+		"Cool": {Count: 1, Bytes: 1},
+		"Hot":  {Count: 1, Bytes: 2},
+	}, result.After)
+	assert.Equal(t, operations.TierStats{Count: 1, Bytes: 2}, result.Errors)
+
+	// not supported
+	f.Features().SetTier = false
+	_, err = operations.SetTier(ctx, f, "Cool")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not support settier")
+}
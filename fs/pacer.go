@@ -4,6 +4,7 @@ package fs
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/rclone/rclone/fs/fserrors"
@@ -13,12 +14,29 @@ import (
 // Pacer is a simple wrapper around a pacer.Pacer with logging.
 type Pacer struct {
 	*pacer.Pacer
+	adaptive bool // wrap the calculator in a pacer.Adaptive
 }
 
 type logCalculator struct {
 	pacer.Calculator
 }
 
+type pacerNameKeyType struct{}
+
+// Context key for the name of the remote being made
+var pacerNameKey = pacerNameKeyType{}
+
+// withPacerName returns a context which names the pacers made with
+// it after the remote name
+func withPacerName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, pacerNameKey, name)
+}
+
+var (
+	pacersMu sync.Mutex
+	pacers   = map[string]*Pacer{} // the last pacer made for each remote
+)
+
 // NewPacer creates a Pacer for the given Fs and Calculator.
 func NewPacer(ctx context.Context, c pacer.Calculator) *Pacer {
 	ci := GetConfig(ctx)
@@ -33,11 +51,36 @@ func NewPacer(ctx context.Context, c pacer.Calculator) *Pacer {
 			pacer.RetriesOption(retries),
 			pacer.CalculatorOption(c),
 		),
+		adaptive: ci.AdaptivePacer,
 	}
 	p.SetCalculator(c)
+	if name, ok := ctx.Value(pacerNameKey).(string); ok && name != "" {
+		pacersMu.Lock()
+		pacers[name] = p
+		pacersMu.Unlock()
+	}
 	return p
 }
 
+// PacerStats returns the stats of the adaptive pacer of each remote
+// made so far, keyed by remote name
+func PacerStats() map[string]pacer.AdaptiveStats {
+	pacersMu.Lock()
+	defer pacersMu.Unlock()
+	out := make(map[string]pacer.AdaptiveStats, len(pacers))
+	for name, p := range pacers {
+		p.Pacer.ModifyCalculator(func(c pacer.Calculator) {
+			if lc, ok := c.(*logCalculator); ok {
+				c = lc.Calculator
+			}
+			if a, ok := c.(*pacer.Adaptive); ok {
+				out[name] = a.Stats()
+			}
+		})
+	}
+	return out
+}
+
 func (d *logCalculator) Calculate(state pacer.State) time.Duration {
 	oldSleepTime := state.SleepTime
 	newSleepTime := d.Calculator.Calculate(state)
@@ -62,8 +105,12 @@ func (p *Pacer) SetCalculator(c pacer.Calculator) {
 	case *logCalculator:
 		Logf("pacer", "Invalid Calculator in fs.Pacer.SetCalculator")
 	case nil:
-		c = &logCalculator{pacer.NewDefault()}
-	default:
+		c = pacer.NewDefault()
+	}
+	if _, ok := c.(*logCalculator); !ok {
+		if p.adaptive {
+			c = pacer.NewAdaptive(c)
+		}
 		c = &logCalculator{c}
 	}
 
@@ -73,10 +120,14 @@ func (p *Pacer) SetCalculator(c pacer.Calculator) {
 // ModifyCalculator calls the given function with the currently configured
 // Calculator and the Pacer lock held.
 func (p *Pacer) ModifyCalculator(f func(pacer.Calculator)) {
-	p.ModifyCalculator(func(c pacer.Calculator) {
+	p.Pacer.ModifyCalculator(func(c pacer.Calculator) {
 		switch _c := c.(type) {
 		case *logCalculator:
-			f(_c.Calculator)
+			if a, ok := _c.Calculator.(*pacer.Adaptive); ok {
+				f(a.Base())
+			} else {
+				f(_c.Calculator)
+			}
 		default:
 			Logf("pacer", "Invalid Calculator in fs.Pacer: %t", c)
 			f(c)
@@ -87,7 +138,7 @@ func (p *Pacer) ModifyCalculator(f func(pacer.Calculator)) {
 func pacerInvoker(try, retries int, f pacer.Paced) (retry bool, err error) {
 	retry, err = f()
 	if retry {
-		Debugf("pacer", "low level retry %d/%d (error %v)", try, retries, err)
+		Debugf("pacer", "low level retry %d/%d (error %v)%v", try, retries, err, LogValueHide("retry", try))
 		err = fserrors.RetryError(err)
 	}
 	return
@@ -165,6 +165,43 @@ func rcGc(ctx context.Context, in Params) (out Params, err error) {
 	return nil, nil
 }
 
+func init() {
+	Add(Call{
+		Path:  "core/pacer",
+		Fn:    rcPacer,
+		Title: "Shows the state of the adaptive pacer of each remote.",
+		Help: `
+This shows what the adaptive pacer has learnt about the rate of API
+calls each remote in use can sustain. It returns
+
+- remotes - a map of remote name to
+    - interval - seconds between API calls or 0 if not rate limited
+    - rate - API calls per second or 0 if not rate limited
+    - errorRate - moving average of the fraction of calls retried
+    - calls - number of API calls
+    - retries - number of API calls retried
+
+Remotes are only shown if they use the pacer and --adaptive-pacer is
+set, which it is by default.
+`,
+	})
+}
+
+// Return the state of the adaptive pacers
+func rcPacer(ctx context.Context, in Params) (out Params, err error) {
+	remotes := Params{}
+	for name, stats := range fs.PacerStats() {
+		remotes[name] = Params{
+			"interval":  stats.Interval.Seconds(),
+			"rate":      stats.Rate,
+			"errorRate": stats.ErrorRate,
+			"calls":     stats.Calls,
+			"retries":   stats.Retries,
+		}
+	}
+	return Params{"remotes": remotes}, nil
+}
+
 func init() {
 	Add(Call{
 		Path:  "core/version",
@@ -247,6 +284,9 @@ func init() {
 		Help: `
 (optional) Pass an exit code to be used for terminating the app:
 - exitCode - int
+
+If --shutdown-timeout is set then rclone waits up to that long for the
+transfers in progress to finish before exiting.
 `,
 	})
 }
@@ -265,6 +305,7 @@ func rcQuit(ctx context.Context, in Params) (out Params, err error) {
 
 	go func(exitCode int) {
 		time.Sleep(time.Millisecond * 1500)
+		atexit.Drain()
 		atexit.Run()
 		os.Exit(exitCode)
 	}(exitCode)
@@ -302,6 +302,34 @@ func TestExecuteJobWithFilter(t *testing.T) {
 	assert.Equal(t, true, called)
 }
 
+func TestExecuteJobWithFilterExcludeFile(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		in   interface{}
+		want filter.ExcludeFiles
+	}{
+		{in: ".ignore", want: filter.ExcludeFiles{".ignore"}},
+		{in: "", want: nil},
+		{in: []string{".ignore", ".nobackup"}, want: filter.ExcludeFiles{".ignore", ".nobackup"}},
+	} {
+		called := false
+		jobID = 0
+		jobFn := func(ctx context.Context, in rc.Params) (rc.Params, error) {
+			fi := filter.GetConfig(ctx)
+			assert.Equal(t, test.want, fi.Opt.ExcludeFile)
+			called = true
+			return nil, nil
+		}
+		_, _, err := NewJob(ctx, jobFn, rc.Params{
+			"_filter": rc.Params{
+				"ExcludeFile": test.in,
+			},
+		})
+		require.NoError(t, err, test.in)
+		assert.Equal(t, true, called, test.in)
+	}
+}
+
 func TestExecuteJobWithGroup(t *testing.T) {
 	ctx := context.Background()
 	jobID = 0
@@ -0,0 +1,332 @@
+// Read only and write only remotes
+
+package fs
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/fserrors"
+)
+
+// restrictMode says what a restricted Fs refuses to do
+type restrictMode int
+
+const (
+	restrictReadOnly  restrictMode = iota // refuse to write or delete anything
+	restrictWriteOnly                     // refuse to read the data of objects
+)
+
+// err returns the error for the things mode refuses to do
+func (mode restrictMode) err() error {
+	if mode == restrictReadOnly {
+		return fserrors.NoRetryError(ErrorReadOnly)
+	}
+	return fserrors.NoRetryError(ErrorWriteOnly)
+}
+
+// restrictModeFromConfig reads the read_only and write_only options
+// from the config of the remote. It returns ok false if neither is
+// set.
+func restrictModeFromConfig(configName string, config configmap.Getter) (mode restrictMode, ok bool, err error) {
+	set := map[string]bool{}
+	for _, key := range []string{"read_only", "write_only"} {
+		value, found := config.Get(key)
+		if !found || value == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return mode, false, errors.Wrapf(err, "%s: bad %s parameter", configName, key)
+		}
+		set[key] = b
+	}
+	switch {
+	case set["read_only"] && set["write_only"]:
+		return mode, false, errors.Errorf("%s: can't use read_only with write_only", configName)
+	case set["read_only"]:
+		return restrictReadOnly, true, nil
+	case set["write_only"]:
+		return restrictWriteOnly, true, nil
+	}
+	return mode, false, nil
+}
+
+// restrictedFs wraps an Fs refusing to write or delete anything if it
+// is read only or to read the data of objects if it is write only.
+type restrictedFs struct {
+	Fs
+	mode     restrictMode
+	features *Features
+}
+
+// newRestrictedFs wraps f so it refuses the operations mode restricts
+func newRestrictedFs(f Fs, mode restrictMode) *restrictedFs {
+	r := &restrictedFs{
+		Fs:   f,
+		mode: mode,
+	}
+	features := *f.Features()
+	if mode == restrictReadOnly {
+		features.SetTier = false
+		features.Purge = nil
+		features.Copy = nil
+		features.Move = nil
+		features.DirMove = nil
+		features.PutUnchecked = nil
+		features.PutStream = nil
+		features.MergeDirs = nil
+		features.CleanUp = nil
+		features.OpenWriterAt = nil
+		features.Command = nil
+		features.Undelete = nil
+	}
+	if do := features.Copy; do != nil {
+		features.Copy = func(ctx context.Context, src Object, remote string) (Object, error) {
+			return r.wrapObject(do(ctx, unwrapRestrictedObject(src), remote))
+		}
+	}
+	if do := features.Move; do != nil {
+		features.Move = func(ctx context.Context, src Object, remote string) (Object, error) {
+			return r.wrapObject(do(ctx, unwrapRestrictedObject(src), remote))
+		}
+	}
+	if do := features.DirMove; do != nil {
+		features.DirMove = func(ctx context.Context, src Fs, srcRemote, dstRemote string) error {
+			if srcRestricted, ok := src.(*restrictedFs); ok {
+				src = srcRestricted.Fs
+			}
+			return do(ctx, src, srcRemote, dstRemote)
+		}
+	}
+	if do := features.PutUnchecked; do != nil {
+		features.PutUnchecked = func(ctx context.Context, in io.Reader, src ObjectInfo, options ...OpenOption) (Object, error) {
+			return r.wrapObject(do(ctx, in, src, options...))
+		}
+	}
+	if do := features.PutStream; do != nil {
+		features.PutStream = func(ctx context.Context, in io.Reader, src ObjectInfo, options ...OpenOption) (Object, error) {
+			return r.wrapObject(do(ctx, in, src, options...))
+		}
+	}
+	if do := features.Undelete; do != nil {
+		features.Undelete = func(ctx context.Context, entry TrashEntry) (Object, error) {
+			return r.wrapObject(do(ctx, entry))
+		}
+	}
+	features.ListR = r.wrapListR(features.ListR)
+	features.ListP = r.wrapListR(features.ListP)
+	r.features = &features
+	return r
+}
+
+// Features returns the optional features of the wrapped Fs less the
+// ones which the mode refuses
+func (r *restrictedFs) Features() *Features {
+	return r.features
+}
+
+// wrapObject wraps o, if it isn't nil, so it refuses the operations
+// the mode restricts
+func (r *restrictedFs) wrapObject(o Object, err error) (Object, error) {
+	if o == nil {
+		return nil, err
+	}
+	return &restrictedObject{Object: o, f: r}, err
+}
+
+// wrapEntries wraps the objects in entries in place
+func (r *restrictedFs) wrapEntries(entries DirEntries) DirEntries {
+	for i, entry := range entries {
+		if o, ok := entry.(Object); ok {
+			entries[i], _ = r.wrapObject(o, nil)
+		}
+	}
+	return entries
+}
+
+// wrapListR wraps the objects returned by listR
+func (r *restrictedFs) wrapListR(listR ListRFn) ListRFn {
+	if listR == nil {
+		return nil
+	}
+	return func(ctx context.Context, dir string, callback ListRCallback) error {
+		return listR(ctx, dir, func(entries DirEntries) error {
+			return callback(r.wrapEntries(entries))
+		})
+	}
+}
+
+// List the objects and directories in dir into entries
+func (r *restrictedFs) List(ctx context.Context, dir string) (entries DirEntries, err error) {
+	entries, err = r.Fs.List(ctx, dir)
+	return r.wrapEntries(entries), err
+}
+
+// NewObject finds the Object at remote
+func (r *restrictedFs) NewObject(ctx context.Context, remote string) (Object, error) {
+	return r.wrapObject(r.Fs.NewObject(ctx, remote))
+}
+
+// Put in to the remote path with the modTime given of the given size
+func (r *restrictedFs) Put(ctx context.Context, in io.Reader, src ObjectInfo, options ...OpenOption) (Object, error) {
+	if r.mode == restrictReadOnly {
+		return nil, r.mode.err()
+	}
+	return r.wrapObject(r.Fs.Put(ctx, in, src, options...))
+}
+
+// Mkdir makes the directory
+func (r *restrictedFs) Mkdir(ctx context.Context, dir string) error {
+	if r.mode == restrictReadOnly {
+		return r.mode.err()
+	}
+	return r.Fs.Mkdir(ctx, dir)
+}
+
+// Rmdir removes the directory if it is empty
+func (r *restrictedFs) Rmdir(ctx context.Context, dir string) error {
+	if r.mode == restrictReadOnly {
+		return r.mode.err()
+	}
+	return r.Fs.Rmdir(ctx, dir)
+}
+
+// restrictedObject wraps an Object refusing the operations the mode
+// of its Fs restricts
+type restrictedObject struct {
+	Object
+	f *restrictedFs
+}
+
+// unwrapRestrictedObject returns the Object o wraps if it is a
+// restrictedObject, otherwise o
+func unwrapRestrictedObject(o Object) Object {
+	if restricted, ok := o.(*restrictedObject); ok {
+		return restricted.Object
+	}
+	return o
+}
+
+// Fs returns the restricted Fs the object is in
+func (o *restrictedObject) Fs() Info {
+	return o.f
+}
+
+// Open opens the file for read
+func (o *restrictedObject) Open(ctx context.Context, options ...OpenOption) (io.ReadCloser, error) {
+	if o.f.mode == restrictWriteOnly {
+		return nil, o.f.mode.err()
+	}
+	return o.Object.Open(ctx, options...)
+}
+
+// Update the object with the contents of the io.Reader
+func (o *restrictedObject) Update(ctx context.Context, in io.Reader, src ObjectInfo, options ...OpenOption) error {
+	if o.f.mode == restrictReadOnly {
+		return o.f.mode.err()
+	}
+	return o.Object.Update(ctx, in, src, options...)
+}
+
+// Remove the object
+func (o *restrictedObject) Remove(ctx context.Context) error {
+	if o.f.mode == restrictReadOnly {
+		return o.f.mode.err()
+	}
+	return o.Object.Remove(ctx)
+}
+
+// SetModTime sets the modification time of the object
+func (o *restrictedObject) SetModTime(ctx context.Context, t time.Time) error {
+	if o.f.mode == restrictReadOnly {
+		return o.f.mode.err()
+	}
+	return o.Object.SetModTime(ctx, t)
+}
+
+// MimeType returns the content type of the Object if known
+func (o *restrictedObject) MimeType(ctx context.Context) string {
+	if do, ok := o.Object.(MimeTyper); ok {
+		return do.MimeType(ctx)
+	}
+	return ""
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *restrictedObject) ID() string {
+	if do, ok := o.Object.(IDer); ok {
+		return do.ID()
+	}
+	return ""
+}
+
+// ParentID returns the ID of the parent directory of the Object if
+// known, or "" if not
+func (o *restrictedObject) ParentID() string {
+	if do, ok := o.Object.(ParentIDer); ok {
+		return do.ParentID()
+	}
+	return ""
+}
+
+// GetTier returns the storage tier of the Object if known
+func (o *restrictedObject) GetTier() string {
+	if do, ok := o.Object.(GetTierer); ok {
+		return do.GetTier()
+	}
+	return ""
+}
+
+// SetTier changes the storage tier of the Object
+func (o *restrictedObject) SetTier(tier string) error {
+	if o.f.mode == restrictReadOnly {
+		return o.f.mode.err()
+	}
+	if do, ok := o.Object.(SetTierer); ok {
+		return do.SetTier(tier)
+	}
+	return errors.New("can't set tier")
+}
+
+// UnWrap returns the Object this is wrapping
+func (o *restrictedObject) UnWrap() Object {
+	return o.Object
+}
+
+// Metadata returns the metadata of the Object
+func (o *restrictedObject) Metadata(ctx context.Context) (Metadata, error) {
+	if do, ok := o.Object.(Metadataer); ok {
+		return do.Metadata(ctx)
+	}
+	return nil, nil
+}
+
+// SetMetadata stores the keys in m on the Object
+func (o *restrictedObject) SetMetadata(ctx context.Context, m Metadata) error {
+	if o.f.mode == restrictReadOnly {
+		return o.f.mode.err()
+	}
+	if do, ok := o.Object.(SetMetadataer); ok {
+		return do.SetMetadata(ctx, m)
+	}
+	return ErrorCantSetMetadata
+}
+
+// Check the interfaces are satisfied
+var (
+	_ Fs              = (*restrictedFs)(nil)
+	_ Object          = (*restrictedObject)(nil)
+	_ MimeTyper       = (*restrictedObject)(nil)
+	_ IDer            = (*restrictedObject)(nil)
+	_ ParentIDer      = (*restrictedObject)(nil)
+	_ GetTierer       = (*restrictedObject)(nil)
+	_ SetTierer       = (*restrictedObject)(nil)
+	_ Metadataer      = (*restrictedObject)(nil)
+	_ SetMetadataer   = (*restrictedObject)(nil)
+	_ ObjectUnWrapper = (*restrictedObject)(nil)
+)
@@ -0,0 +1,110 @@
+package fs_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	_ "github.com/rclone/rclone/backend/memory"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// put makes an object with contents at remote in f
+func put(ctx context.Context, f fs.Fs, remote, contents string) (fs.Object, error) {
+	src := object.NewStaticObjectInfo(remote, time.Now(), int64(len(contents)), true, nil, nil)
+	return f.Put(ctx, bytes.NewBufferString(contents), src)
+}
+
+func TestRestrictReadOnly(t *testing.T) {
+	ctx := context.Background()
+	f, err := fs.NewFs(ctx, ":memory:restrict-read-only")
+	require.NoError(t, err)
+	_, err = put(ctx, f, "file.txt", "hello")
+	require.NoError(t, err)
+
+	fro, err := fs.NewFs(ctx, ":memory,read_only:restrict-read-only")
+	require.NoError(t, err)
+	assert.Nil(t, fro.Features().Purge)
+	assert.Nil(t, fro.Features().Copy)
+	assert.Nil(t, fro.Features().PutStream)
+
+	isReadOnly := func(err error) {
+		assert.Equal(t, fs.ErrorReadOnly, errors.Cause(err))
+		assert.True(t, fserrors.IsNoRetryError(err))
+	}
+	_, err = put(ctx, fro, "new.txt", "potato")
+	isReadOnly(err)
+	isReadOnly(fro.Mkdir(ctx, "dir"))
+	isReadOnly(fro.Rmdir(ctx, ""))
+
+	entries, err := fro.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	o, ok := entries[0].(fs.Object)
+	require.True(t, ok)
+	assert.Equal(t, fro, o.Fs())
+
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	isReadOnly(o.Remove(ctx))
+	isReadOnly(o.SetModTime(ctx, time.Now()))
+	isReadOnly(o.Update(ctx, bytes.NewBufferString("x"), object.NewStaticObjectInfo("file.txt", time.Now(), 1, true, nil, nil)))
+
+	// check the backend's object can be found
+	unwrapped := fs.UnWrapObject(o)
+	require.NotNil(t, unwrapped)
+	assert.NotEqual(t, o, unwrapped)
+	assert.Equal(t, "file.txt", unwrapped.Remote())
+	assert.NotEqual(t, fro, unwrapped.Fs())
+
+	// check the object wasn't touched
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), o.Size())
+}
+
+func TestRestrictWriteOnly(t *testing.T) {
+	ctx := context.Background()
+	fwo, err := fs.NewFs(ctx, ":memory,write_only:restrict-write-only")
+	require.NoError(t, err)
+
+	o, err := put(ctx, fwo, "file.txt", "hello")
+	require.NoError(t, err)
+	_, err = o.Open(ctx)
+	assert.Equal(t, fs.ErrorWriteOnly, errors.Cause(err))
+
+	o, err = fwo.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), o.Size())
+	_, err = o.Open(ctx)
+	assert.Equal(t, fs.ErrorWriteOnly, errors.Cause(err))
+
+	require.NotNil(t, fwo.Features().ListR)
+	err = fwo.Features().ListR(ctx, "", func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			if o, ok := entry.(fs.Object); ok {
+				_, err := o.Open(ctx)
+				assert.Equal(t, fs.ErrorWriteOnly, errors.Cause(err))
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, o.Remove(ctx))
+}
+
+func TestRestrictBoth(t *testing.T) {
+	_, err := fs.NewFs(context.Background(), ":memory,read_only,write_only:restrict-both")
+	assert.Error(t, err)
+
+	_, err = fs.NewFs(context.Background(), ":memory,read_only=potato:restrict-both")
+	assert.Error(t, err)
+}
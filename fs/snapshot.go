@@ -0,0 +1,90 @@
+// Point in time views of remotes
+
+package fs
+
+import (
+	"context"
+	"path"
+	"time"
+)
+
+// snapshotFs wraps an Fs showing its objects and directories as they
+// were at a point in time
+type snapshotFs struct {
+	Fs
+	t        time.Time
+	listAt   func(ctx context.Context, dir string, t time.Time) (DirEntries, error)
+	features *Features
+}
+
+// wrapSnapshot wraps f and err returned from NewFs so it shows the
+// remote as it was at t, read only, if the backend can list old
+// versions of objects. Otherwise it returns them unchanged.
+func wrapSnapshot(f Fs, err error, t time.Time) (Fs, error) {
+	if err != nil && err != ErrorIsFile {
+		return f, err
+	}
+	features := *f.Features()
+	if features.ListAt == nil {
+		Debugf(f, "Not showing remote as at %v as it doesn't support --snapshot-time", t)
+		return f, err
+	}
+	s := &snapshotFs{
+		Fs:     f,
+		t:      t,
+		listAt: features.ListAt,
+	}
+	// The backend listings are of the current objects and the
+	// snapshot doesn't change
+	features.ListR = nil
+	features.ListP = nil
+	features.ListTrash = nil
+	features.ListVersions = nil
+	features.ChangeNotify = nil
+	s.features = &features
+	return newRestrictedFs(s, restrictReadOnly), err
+}
+
+// Features returns the optional features of the wrapped Fs less the
+// listings which would show the current objects
+func (s *snapshotFs) Features() *Features {
+	return s.features
+}
+
+// String returns a description of the snapshot
+func (s *snapshotFs) String() string {
+	return s.Fs.String() + " at " + s.t.Format(time.RFC3339)
+}
+
+// List the objects and directories in dir into entries as they were
+// at the time of the snapshot
+func (s *snapshotFs) List(ctx context.Context, dir string) (entries DirEntries, err error) {
+	return s.listAt(ctx, dir, s.t)
+}
+
+// NewObject finds the Object at remote as it was at the time of the
+// snapshot
+func (s *snapshotFs) NewObject(ctx context.Context, remote string) (Object, error) {
+	dir := path.Dir(remote)
+	if dir == "." {
+		dir = ""
+	}
+	entries, err := s.listAt(ctx, dir, s.t)
+	if err != nil {
+		if err == ErrorDirNotFound {
+			return nil, ErrorObjectNotFound
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if o, ok := entry.(Object); ok && o.Remote() == remote {
+			return o, nil
+		}
+	}
+	return nil, ErrorObjectNotFound
+}
+
+// Check the interfaces are satisfied
+var (
+	_ Fs = (*snapshotFs)(nil)
+)
@@ -0,0 +1,69 @@
+package fs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var snapshotCreated = time.Date(2021, 1, 2, 15, 4, 5, 0, time.UTC)
+
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name: "snapshotmock",
+		NewFs: func(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+			f := mockfs.NewFs(ctx, name, root)
+			f.AddObject(mockobject.New("new.txt"))
+			f.Features().ListAt = func(ctx context.Context, dir string, t time.Time) (fs.DirEntries, error) {
+				if t.Before(snapshotCreated) {
+					return nil, nil
+				}
+				return fs.DirEntries{mockobject.New("old.txt")}, nil
+			}
+			return f, nil
+		},
+	})
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.SnapshotTime = snapshotCreated.Add(time.Hour)
+
+	f, err := fs.NewFs(ctx, ":snapshotmock:")
+	require.NoError(t, err)
+	assert.Nil(t, f.Features().ListR)
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "old.txt", entries[0].Remote())
+
+	o, err := f.NewObject(ctx, "old.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "old.txt", o.Remote())
+	assert.Equal(t, fs.ErrorReadOnly, errors.Cause(o.Remove(ctx)))
+	_, err = f.NewObject(ctx, "new.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	assert.Equal(t, fs.ErrorReadOnly, errors.Cause(f.Mkdir(ctx, "dir")))
+
+	// before anything existed
+	ci.SnapshotTime = snapshotCreated.Add(-time.Hour)
+	f, err = fs.NewFs(ctx, ":snapshotmock:")
+	require.NoError(t, err)
+	entries, err = f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, entries, 0)
+
+	// remotes which can't list old versions are left alone
+	f, err = fs.NewFs(ctx, ":memory:snapshot")
+	require.NoError(t, err)
+	require.NoError(t, f.Mkdir(ctx, ""))
+}
@@ -0,0 +1,338 @@
+// Journal of the changes a sync makes to the destination so an
+// interrupted sync can be continued or rolled back
+
+package sync
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// Operations recorded in the journal
+const (
+	journalStart  = "start"  // a sync was started
+	journalCopy   = "copy"   // about to copy Remote
+	journalDone   = "done"   // finished copying Remote
+	journalBackup = "backup" // about to move Remote to the backup dir
+	journalDelete = "delete" // about to delete Remote
+	journalRename = "rename" // about to rename From to Remote
+	journalFinish = "finish" // the sync finished successfully
+)
+
+// journalEntry is one line of the journal
+type journalEntry struct {
+	Op                  string     `json:"op"`
+	Remote              string     `json:"remote,omitempty"`
+	From                string     `json:"from,omitempty"`
+	Replace             bool       `json:"replace,omitempty"`
+	Time                *time.Time `json:"time,omitempty"`
+	Src                 string     `json:"src,omitempty"`
+	Dst                 string     `json:"dst,omitempty"`
+	BackupDir           string     `json:"backupDir,omitempty"`
+	Suffix              string     `json:"suffix,omitempty"`
+	SuffixKeepExtension bool       `json:"suffixKeepExtension,omitempty"`
+	CreateEmptySrcDirs  bool       `json:"createEmptySrcDirs,omitempty"`
+	RolledBack          bool       `json:"rolledBack,omitempty"`
+}
+
+// journal writes journalEntry~s to a file, syncing each one to disk
+// before returning so it is written before the operation is done.
+//
+// The methods may be called on a nil *journal in which case they do
+// nothing.
+type journal struct {
+	mu  sync.Mutex
+	fd  *os.File
+	enc *json.Encoder
+}
+
+// openJournal opens the journal at path for appending
+func openJournal(path string) (*journal, error) {
+	fd, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open journal")
+	}
+	return &journal{
+		fd:  fd,
+		enc: json.NewEncoder(fd),
+	}, nil
+}
+
+// write e to the journal
+func (j *journal) write(e journalEntry) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	err := j.enc.Encode(e)
+	if err == nil {
+		err = j.fd.Sync()
+	}
+	if err != nil {
+		return fserrors.FatalError(errors.Wrap(err, "failed to write journal"))
+	}
+	return nil
+}
+
+// close the journal
+func (j *journal) close() error {
+	if j == nil {
+		return nil
+	}
+	return j.fd.Close()
+}
+
+// readJournal reads the entries of the journal at path since the
+// last sync which finished.
+func readJournal(path string) (entries []journalEntry, err error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open journal")
+	}
+	defer fs.CheckClose(fd, &err)
+	scanner := bufio.NewScanner(fd)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var e journalEntry
+		err = json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			// The last line may be truncated if rclone was killed
+			// while writing it, and the operation it describes
+			// wasn't started.
+			fs.Debugf(nil, "Ignoring bad line in journal: %v", err)
+			continue
+		}
+		if e.Op == journalFinish {
+			entries = append(entries[:0], e)
+		} else {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read journal")
+	}
+	return entries, nil
+}
+
+// SyncJournal syncs fsrc into fdst like Sync writing a journal of
+// the changes made to fdst to the file journalPath.
+//
+// Each change is written to the journal before it is made, so if the
+// sync is interrupted it can be continued or rolled back with
+// ResumeJournal.
+func SyncJournal(ctx context.Context, fdst, fsrc fs.Fs, copyEmptySrcDirs bool, journalPath string) (err error) {
+	ci := fs.GetConfig(ctx)
+	if ci.DryRun {
+		fs.Logf(nil, "Not writing journal as --dry-run is set")
+		return Sync(ctx, fdst, fsrc, copyEmptySrcDirs)
+	}
+	start := journalEntry{
+		Op:                  journalStart,
+		Src:                 fs.ConfigString(fsrc),
+		Dst:                 fs.ConfigString(fdst),
+		Suffix:              ci.Suffix,
+		SuffixKeepExtension: ci.SuffixKeepExtension,
+		CreateEmptySrcDirs:  copyEmptySrcDirs,
+	}
+	if ci.BackupDir != "" || ci.Suffix != "" {
+		backupDir, err := operations.BackupDir(ctx, fdst, fsrc, "")
+		if err != nil {
+			return err
+		}
+		start.BackupDir = fs.ConfigString(backupDir)
+	}
+	j, err := openJournal(journalPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := j.close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+	now := time.Now()
+	start.Time = &now
+	err = j.write(start)
+	if err != nil {
+		return err
+	}
+	err = runSyncCopyMove(ctx, fdst, fsrc, ci.DeleteMode, false, false, copyEmptySrcDirs, j)
+	if err != nil {
+		return err
+	}
+	return j.write(journalEntry{Op: journalFinish})
+}
+
+// ResumeJournal finds the interrupted sync recorded in the journal at
+// journalPath and continues it, or if rollback is set, undoes the
+// changes it made to the destination.
+//
+// Continuing runs the sync again with the same source and
+// destination, appending to the journal.
+func ResumeJournal(ctx context.Context, journalPath string, rollback bool) error {
+	entries, err := readJournal(journalPath)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 && entries[0].Op == journalFinish {
+		entries = entries[1:]
+	}
+	if len(entries) == 0 {
+		fs.Logf(nil, "Nothing to do as the sync in journal %q finished", journalPath)
+		return nil
+	}
+	// Use the most recent start as the sync may have been retried
+	var start *journalEntry
+	interrupted := 0
+	for i := range entries {
+		switch entries[i].Op {
+		case journalStart:
+			start = &entries[i]
+		case journalCopy, journalBackup, journalDelete, journalRename:
+			interrupted++
+		case journalDone:
+			interrupted--
+		}
+	}
+	if start == nil {
+		return errors.Errorf("no sync found in journal %q", journalPath)
+	}
+	fdst, err := cache.Get(ctx, start.Dst)
+	if err != nil {
+		return errors.Wrap(err, "failed to make destination")
+	}
+	if rollback {
+		err = rollbackJournal(ctx, fdst, start, entries)
+		if err != nil {
+			return err
+		}
+		j, err := openJournal(journalPath)
+		if err != nil {
+			return err
+		}
+		err = j.write(journalEntry{Op: journalFinish, RolledBack: true})
+		closeErr := j.close()
+		if err != nil {
+			return err
+		}
+		return closeErr
+	}
+	fsrc, err := cache.Get(ctx, start.Src)
+	if err != nil {
+		return errors.Wrap(err, "failed to make source")
+	}
+	fs.Logf(fdst, "Continuing sync from %v with up to %d changes interrupted", fsrc, interrupted)
+	return SyncJournal(ctx, fdst, fsrc, start.CreateEmptySrcDirs, journalPath)
+}
+
+// rollbackJournal undoes the changes recorded in entries, most recent
+// first.
+//
+// New files are deleted and files which are in the backup dir are put
+// back. Files which were overwritten or deleted without a backup dir
+// can't be restored so an error is returned for them.
+func rollbackJournal(ctx context.Context, fdst fs.Fs, start *journalEntry, entries []journalEntry) error {
+	ctx, ci := fs.AddConfig(ctx)
+	ci.Suffix = start.Suffix
+	ci.SuffixKeepExtension = start.SuffixKeepExtension
+	var backupDir fs.Fs
+	if start.BackupDir != "" {
+		var err error
+		backupDir, err = cache.Get(ctx, start.BackupDir)
+		if err != nil {
+			return errors.Wrap(err, "failed to make backup dir")
+		}
+	}
+
+	// restore moves the backup of remote back if it exists
+	restore := func(remote string) (restored bool, err error) {
+		if backupDir == nil {
+			return false, nil
+		}
+		backup, err := backupDir.NewObject(ctx, operations.SuffixName(ctx, remote))
+		if err == fs.ErrorObjectNotFound {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		existing, _ := fdst.NewObject(ctx, remote)
+		_, err = operations.Move(ctx, fdst, existing, remote, backup)
+		if err != nil {
+			return false, err
+		}
+		fs.Infof(remote, "Restored from backup")
+		return true, nil
+	}
+
+	var errCount int
+	fail := func(remote string, err error) {
+		errCount++
+		err = fs.CountError(err)
+		fs.Errorf(remote, "Failed to roll back: %v", err)
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		switch e.Op {
+		case journalCopy:
+			if e.Replace {
+				fail(e.Remote, errors.New("can't restore file which may have been overwritten without --backup-dir"))
+				continue
+			}
+			o, err := fdst.NewObject(ctx, e.Remote)
+			if err == fs.ErrorObjectNotFound {
+				continue
+			} else if err != nil {
+				fail(e.Remote, err)
+				continue
+			}
+			err = operations.DeleteFile(ctx, o)
+			if err != nil {
+				fail(e.Remote, err)
+			}
+		case journalBackup, journalDelete:
+			restored, err := restore(e.Remote)
+			if err != nil {
+				fail(e.Remote, err)
+			} else if !restored {
+				if _, err := fdst.NewObject(ctx, e.Remote); err != nil {
+					fail(e.Remote, errors.New("can't restore file which was deleted without --backup-dir"))
+				}
+			}
+		case journalRename:
+			if e.Replace {
+				fail(e.Remote, errors.New("can't restore file which may have been overwritten by a rename"))
+				continue
+			}
+			if _, err := fdst.NewObject(ctx, e.From); err == nil {
+				continue
+			}
+			o, err := fdst.NewObject(ctx, e.Remote)
+			if err == fs.ErrorObjectNotFound {
+				continue
+			} else if err != nil {
+				fail(e.Remote, err)
+				continue
+			}
+			_, err = operations.Move(ctx, fdst, nil, e.From, o)
+			if err != nil {
+				fail(e.Remote, err)
+			}
+		}
+	}
+	if errCount > 0 {
+		return errors.Errorf("failed to roll back %d changes", errCount)
+	}
+	return nil
+}
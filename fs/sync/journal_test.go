@@ -0,0 +1,119 @@
+package sync
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// interruptJournal removes the finish line from the end of the
+// journal at path as if the sync was interrupted just before the end
+func interruptJournal(t *testing.T, path string) {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Contains(t, lines[len(lines)-1], `"op":"finish"`)
+	lines = lines[:len(lines)-1]
+	require.NoError(t, ioutil.WriteFile(path, []byte(strings.Join(lines, "")), 0600))
+}
+
+func TestSyncJournal(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	if !operations.CanServerSideMove(r.Fremote) {
+		t.Skip("Skipping test as remote does not support server-side move")
+	}
+	r.Mkdir(ctx, r.Fremote)
+	ci.BackupDir = r.FremoteName + "/backup"
+
+	journalDir, err := ioutil.TempDir("", "rclone-journal")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(journalDir)
+	}()
+	journalPath := filepath.Join(journalDir, "journal")
+
+	// dst has one, two and three and the source has one
+	// (different), two (same) and four
+	file1 := r.WriteObject(ctx, "dst/one", "one", t1)
+	file2 := r.WriteObject(ctx, "dst/two", "two", t1)
+	file3 := r.WriteObject(ctx, "dst/three", "three", t1)
+	file1a := r.WriteFile("one", "oneA", t2)
+	file2a := r.WriteFile("two", "two", t1)
+	file4a := r.WriteFile("four", "four", t1)
+	fstest.CheckItems(t, r.Flocal, file1a, file2a, file4a)
+
+	fdst, err := fs.NewFs(ctx, r.FremoteName+"/dst")
+	require.NoError(t, err)
+
+	require.NoError(t, SyncJournal(ctx, fdst, r.Flocal, false, journalPath))
+	synced1, synced4 := file1a, file4a
+	synced1.Path, synced4.Path = "dst/one", "dst/four"
+	backup1, backup3 := file1, file3
+	backup1.Path, backup3.Path = "backup/one", "backup/three"
+	fstest.CheckItems(t, r.Fremote, synced1, file2, synced4, backup1, backup3)
+
+	// Nothing to do as the sync finished
+	require.NoError(t, ResumeJournal(ctx, journalPath, false))
+	fstest.CheckItems(t, r.Fremote, synced1, file2, synced4, backup1, backup3)
+
+	// Continuing an interrupted sync runs it again
+	interruptJournal(t, journalPath)
+	require.NoError(t, ResumeJournal(ctx, journalPath, false))
+	fstest.CheckItems(t, r.Fremote, synced1, file2, synced4, backup1, backup3)
+	entries, err := readJournal(journalPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, journalFinish, entries[0].Op)
+
+	// Rolling back puts the destination back as it was
+	interruptJournal(t, journalPath)
+	require.NoError(t, ResumeJournal(ctx, journalPath, true))
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+	entries, err = readJournal(journalPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].RolledBack)
+}
+
+func TestRollbackJournalNoBackupDir(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	defer accounting.GlobalStats().ResetCounters()
+
+	journalDir, err := ioutil.TempDir("", "rclone-journal")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(journalDir)
+	}()
+	journalPath := filepath.Join(journalDir, "journal")
+
+	r.WriteObject(ctx, "one", "one", t1)
+	file2 := r.WriteObject(ctx, "two", "two", t1)
+	file2a := r.WriteFile("two", "two", t1)
+	file3a := r.WriteFile("three", "three", t1)
+
+	require.NoError(t, SyncJournal(ctx, r.Fremote, r.Flocal, false, journalPath))
+	fstest.CheckItems(t, r.Fremote, file2a, file3a)
+
+	// The new file is removed but the deleted one can't be restored
+	interruptJournal(t, journalPath)
+	err = ResumeJournal(ctx, journalPath, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to roll back 1 changes")
+	fstest.CheckItems(t, r.Fremote, file2)
+}
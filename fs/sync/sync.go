@@ -16,6 +16,7 @@ import (
 	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/list"
 	"github.com/rclone/rclone/fs/march"
 	"github.com/rclone/rclone/fs/operations"
 )
@@ -73,6 +74,17 @@ type syncCopyMove struct {
 	compareCopyDest        []fs.Fs                // place to check for files to server side copy
 	backupDir              fs.Fs                  // place to store overwrites/deletes
 	checkFirst             bool                   // if set run all the checkers before starting transfers
+	journal                *journal               // if set write changes here before making them
+	retryMu                sync.Mutex             // protect retryQueue
+	retryQueue             []retryItem            // failed transfers to retry at the end
+	retryPass              int                    // number of retry passes done
+	retrying               map[string]bool        // remotes being retried - read only during a pass
+}
+
+// retryItem is a failed transfer waiting to be retried
+type retryItem struct {
+	pair fs.ObjectPair
+	err  error
 }
 
 type trackRenamesStrategy byte
@@ -95,7 +107,7 @@ func (strategy trackRenamesStrategy) leaf() bool {
 	return (strategy & trackRenamesStrategyLeaf) != 0
 }
 
-func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) (*syncCopyMove, error) {
+func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool, j *journal) (*syncCopyMove, error) {
 	if (deleteMode != fs.DeleteModeOff || DoMove) && operations.Overlapping(fdst, fsrc) {
 		return nil, fserrors.FatalError(fs.ErrorOverlapping)
 	}
@@ -125,6 +137,7 @@ func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 		modifyWindow:           fs.GetModifyWindow(ctx, fsrc, fdst),
 		trackRenamesCh:         make(chan fs.Object, ci.Checkers),
 		checkFirst:             ci.CheckFirst,
+		journal:                j,
 	}
 	backlog := ci.MaxBacklog
 	if s.checkFirst {
@@ -330,7 +343,10 @@ func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, fraction int, wg *sync.W
 				} else {
 					// If destination already exists, then we must move it into --backup-dir if required
 					if pair.Dst != nil && s.backupDir != nil {
-						err := operations.MoveBackupDir(s.ctx, s.backupDir, pair.Dst)
+						err := s.journal.write(journalEntry{Op: journalBackup, Remote: pair.Dst.Remote()})
+						if err == nil {
+							err = operations.MoveBackupDir(s.ctx, s.backupDir, pair.Dst)
+						}
 						if err != nil {
 							s.processError(err)
 						} else {
@@ -394,11 +410,26 @@ func (s *syncCopyMove) pairCopyOrMove(ctx context.Context, in *pipe, fdst fs.Fs,
 			return
 		}
 		src := pair.Src
+		err = s.journal.write(journalEntry{Op: journalCopy, Remote: src.Remote(), Replace: pair.Dst != nil})
+		if err != nil {
+			s.processError(err)
+			continue
+		}
+		if s.retrying[src.Remote()] {
+			// Take the error from the failed attempt off the count
+			accounting.GlobalStats().UncountError()
+		}
 		if s.DoMove {
 			_, err = operations.Move(ctx, fdst, pair.Dst, src.Remote(), src)
 		} else {
 			_, err = operations.Copy(ctx, fdst, pair.Dst, src.Remote(), src)
 		}
+		if err != nil && s.queueRetry(pair, err) {
+			continue
+		}
+		if err == nil {
+			err = s.journal.write(journalEntry{Op: journalDone, Remote: src.Remote()})
+		}
 		s.processError(err)
 	}
 }
@@ -435,6 +466,74 @@ func (s *syncCopyMove) stopTransfers() {
 	s.transfersWg.Wait()
 }
 
+// queueRetry puts the failed transfer of pair on the retry queue
+// returning false if it shouldn't be retried
+func (s *syncCopyMove) queueRetry(pair fs.ObjectPair, err error) bool {
+	if s.retryPass >= s.ci.TransferRetries || s.aborting() || s.inCtx.Err() != nil {
+		return false
+	}
+	if fserrors.IsFatalError(err) || fserrors.IsNoRetryError(err) || err == context.Canceled || err == context.DeadlineExceeded {
+		return false
+	}
+	s.retryMu.Lock()
+	s.retryQueue = append(s.retryQueue, retryItem{pair: pair, err: err})
+	s.retryMu.Unlock()
+	return true
+}
+
+// retryFailed retries the failed transfers on the retry queue with
+// the sleep between passes doubling each time until they succeed or
+// --transfer-retries is used up.
+//
+// It is called with the transfers stopped.
+func (s *syncCopyMove) retryFailed() {
+	sleep := s.ci.TransferRetriesSleep
+	for {
+		s.retryMu.Lock()
+		queue := s.retryQueue
+		s.retryQueue = nil
+		s.retryMu.Unlock()
+		if len(queue) == 0 {
+			return
+		}
+		s.retryPass++
+		fs.Logf(s.fdst, "Retrying %d failed transfers in %v (retry %d/%d)", len(queue), sleep, s.retryPass, s.ci.TransferRetries)
+		select {
+		case <-time.After(sleep):
+		case <-s.inCtx.Done():
+		}
+		if s.inCtx.Err() != nil {
+			for _, item := range queue {
+				s.processError(item.err)
+			}
+			return
+		}
+		sleep *= 2
+		var err error
+		s.toBeUploaded, err = newPipe(s.ci.OrderBy, accounting.Stats(s.ctx).SetTransferQueue, s.ci.MaxBacklog)
+		if err != nil {
+			s.processError(err)
+			return
+		}
+		s.retrying = make(map[string]bool, len(queue))
+		for _, item := range queue {
+			s.retrying[item.pair.Src.Remote()] = true
+		}
+		s.startTransfers()
+		for _, item := range queue {
+			// The failed transfer may have left a partial or new object
+			item.pair.Dst, err = s.fdst.NewObject(s.ctx, item.pair.Src.Remote())
+			if err != nil {
+				item.pair.Dst = nil
+			}
+			if !s.toBeUploaded.Put(s.ctx, item.pair) {
+				break
+			}
+		}
+		s.stopTransfers()
+	}
+}
+
 // This starts the background renamers.
 func (s *syncCopyMove) startRenamers() {
 	if !s.trackRenames {
@@ -527,6 +626,10 @@ func (s *syncCopyMove) deleteFiles(checkSrcMap bool) error {
 			if s.aborting() {
 				break
 			}
+			if err := s.journal.write(journalEntry{Op: journalDelete, Remote: remote}); err != nil {
+				s.processError(err)
+				break
+			}
 			select {
 			case <-s.ctx.Done():
 				break outer
@@ -792,7 +895,12 @@ func (s *syncCopyMove) tryRename(src fs.Object) bool {
 	dstOverwritten, _ := s.fdst.NewObject(s.ctx, src.Remote())
 
 	// Rename dst to have name src.Remote()
-	_, err := operations.Move(s.ctx, s.fdst, dstOverwritten, src.Remote(), dst)
+	err := s.journal.write(journalEntry{Op: journalRename, Remote: src.Remote(), From: dst.Remote(), Replace: dstOverwritten != nil})
+	if err != nil {
+		s.processError(err)
+		return false
+	}
+	_, err = operations.Move(s.ctx, s.fdst, dstOverwritten, src.Remote(), dst)
 	if err != nil {
 		fs.Debugf(src, "Failed to rename to %q: %v", dst.Remote(), err)
 		return false
@@ -842,6 +950,7 @@ func (s *syncCopyMove) run() error {
 		DstIncludeAll:          s.fi.Opt.DeleteExcluded,
 		NoCheckDest:            s.noCheckDest,
 		NoUnicodeNormalization: s.noUnicodeNormalization,
+		StreamFilesFrom:        !s.trackRenames && !s.copyEmptySrcDirs && !(s.DoMove && s.deleteEmptySrcDirs),
 	}
 	s.processError(m.Run(s.ctx))
 
@@ -866,6 +975,7 @@ func (s *syncCopyMove) run() error {
 	}
 	s.stopRenamers()
 	s.stopTransfers()
+	s.retryFailed()
 	s.stopDeleters()
 
 	if s.copyEmptySrcDirs {
@@ -905,6 +1015,14 @@ func (s *syncCopyMove) run() error {
 		fs.Infof(nil, "There was nothing to transfer")
 	}
 
+	// Drop the cached listings of anything we changed
+	if !s.ci.DryRun {
+		list.InvalidateCache(s.ctx, s.fdst)
+		if s.DoMove {
+			list.InvalidateCache(s.ctx, s.fsrc)
+		}
+	}
+
 	// cancel the context to free resources
 	s.cancel()
 	return s.currentError()
@@ -924,6 +1042,10 @@ func (s *syncCopyMove) DstOnly(dst fs.DirEntry) (recurse bool) {
 			s.dstFiles[x.Remote()] = x
 			s.dstFilesMu.Unlock()
 		case fs.DeleteModeDuring, fs.DeleteModeOnly:
+			if err := s.journal.write(journalEntry{Op: journalDelete, Remote: x.Remote()}); err != nil {
+				s.processError(err)
+				return false
+			}
 			select {
 			case <-s.ctx.Done():
 				return
@@ -1052,7 +1174,9 @@ func (s *syncCopyMove) Match(ctx context.Context, dst, src fs.DirEntry) (recurse
 // If DoMove is true then files will be moved instead of copied
 //
 // dir is the start directory, "" for root
-func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) error {
+// If j is set then changes to fdst are written to it before they are
+// made.
+func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool, j *journal) error {
 	ci := fs.GetConfig(ctx)
 	if deleteMode != fs.DeleteModeOff && DoMove {
 		return fserrors.FatalError(errors.New("can't delete and move at the same time"))
@@ -1063,7 +1187,7 @@ func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 			return fserrors.FatalError(errors.New("can't use --delete-before with --track-renames"))
 		}
 		// only delete stuff during in this pass
-		do, err := newSyncCopyMove(ctx, fdst, fsrc, fs.DeleteModeOnly, false, deleteEmptySrcDirs, copyEmptySrcDirs)
+		do, err := newSyncCopyMove(ctx, fdst, fsrc, fs.DeleteModeOnly, false, deleteEmptySrcDirs, copyEmptySrcDirs, j)
 		if err != nil {
 			return err
 		}
@@ -1074,7 +1198,7 @@ func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 		// Next pass does a copy only
 		deleteMode = fs.DeleteModeOff
 	}
-	do, err := newSyncCopyMove(ctx, fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs, copyEmptySrcDirs)
+	do, err := newSyncCopyMove(ctx, fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs, copyEmptySrcDirs, j)
 	if err != nil {
 		return err
 	}
@@ -1084,17 +1208,17 @@ func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 // Sync fsrc into fdst
 func Sync(ctx context.Context, fdst, fsrc fs.Fs, copyEmptySrcDirs bool) error {
 	ci := fs.GetConfig(ctx)
-	return runSyncCopyMove(ctx, fdst, fsrc, ci.DeleteMode, false, false, copyEmptySrcDirs)
+	return runSyncCopyMove(ctx, fdst, fsrc, ci.DeleteMode, false, false, copyEmptySrcDirs, nil)
 }
 
 // CopyDir copies fsrc into fdst
 func CopyDir(ctx context.Context, fdst, fsrc fs.Fs, copyEmptySrcDirs bool) error {
-	return runSyncCopyMove(ctx, fdst, fsrc, fs.DeleteModeOff, false, false, copyEmptySrcDirs)
+	return runSyncCopyMove(ctx, fdst, fsrc, fs.DeleteModeOff, false, false, copyEmptySrcDirs, nil)
 }
 
 // moveDir moves fsrc into fdst
 func moveDir(ctx context.Context, fdst, fsrc fs.Fs, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) error {
-	return runSyncCopyMove(ctx, fdst, fsrc, fs.DeleteModeOff, true, deleteEmptySrcDirs, copyEmptySrcDirs)
+	return runSyncCopyMove(ctx, fdst, fsrc, fs.DeleteModeOff, true, deleteEmptySrcDirs, copyEmptySrcDirs, nil)
 }
 
 // MoveDir moves fsrc into fdst
@@ -5,6 +5,7 @@ package sync
 import (
 	"context"
 	"fmt"
+	"io"
 	"runtime"
 	"strings"
 	"testing"
@@ -608,6 +609,36 @@ func TestSyncAfterChangingModtimeOnlyWithNoUpdateModTime(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file2)
 }
 
+func TestSyncWithChecksumAndRefreshTimes(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	if r.Fremote.Hashes().Overlap(r.Flocal.Hashes()).Count() == 0 {
+		t.Skip("Can't check this if no hashes supported")
+	}
+
+	ci.CheckSum = true
+	file1 := r.WriteFile("empty space", "-", t2)
+	file2 := r.WriteObject(ctx, "empty space", "-", t1)
+
+	fstest.CheckItems(t, r.Flocal, file1)
+	fstest.CheckItems(t, r.Fremote, file2)
+
+	// Without --refresh-times the modtime is left alone
+	accounting.GlobalStats().ResetCounters()
+	err := Sync(ctx, r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file2)
+
+	ci.RefreshTimes = true
+	accounting.GlobalStats().ResetCounters()
+	err = Sync(ctx, r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1)
+}
+
 func TestSyncDoesntUpdateModtime(t *testing.T) {
 	ctx := context.Background()
 	r := fstest.NewRun(t)
@@ -988,6 +1019,67 @@ func TestSyncWithExcludeAndDeleteExcluded(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, file2)
 }
 
+// Test with directories excluded by their size, age or an exclude file
+func TestSyncWithDirListingFilters(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	now := time.Now()
+	file1 := r.WriteFile("big/file1", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", now) // 100 bytes
+	file2 := r.WriteFile("old/file2", "-", t1)
+	file3 := r.WriteFile("small/file3", "-", now)
+	file4 := r.WriteObject(ctx, "big/file4", "-", now)
+	file5 := r.WriteObject(ctx, "old/file5", "-", now)
+	file6 := r.WriteObject(ctx, "small/file6", "-", now)
+	file7 := r.WriteFile("marked/.nobackup", "", now)
+	file8 := r.WriteObject(ctx, "marked/file8", "-", now)
+	fstest.CheckItems(t, r.Flocal, file1, file2, file3, file7)
+	fstest.CheckItems(t, r.Fremote, file4, file5, file6, file8)
+
+	fi, err := filter.NewFilter(nil)
+	require.NoError(t, err)
+	fi.Opt.MaxDirSize = 40
+	fi.DirModFrom = now.Add(-24 * time.Hour)
+	fi.Opt.ExcludeFile = []string{".nobackup"}
+	ctx = filter.ReplaceConfig(ctx, fi)
+
+	// Check the source decides which directories are excluded so
+	// the files in the destination of the excluded ones aren't
+	// deleted even though the destination directories would pass
+	accounting.GlobalStats().ResetCounters()
+	err = Sync(ctx, r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file3, file4, file5, file8)
+}
+
+// Test with an ignore file only in the source
+func TestSyncWithIgnoreFile(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile(".rcloneignore", "*.log\n", t1)
+	file2 := r.WriteFile("file2.txt", "file2", t1)
+	file3 := r.WriteFile("dir/new.log", "new", t1)
+	file4 := r.WriteObject(ctx, "old.log", "old", t1)
+	file5 := r.WriteObject(ctx, "dir/other.log", "other", t1)
+	file6 := r.WriteObject(ctx, "extra.txt", "extra", t1)
+	fstest.CheckItems(t, r.Flocal, file1, file2, file3)
+	fstest.CheckItems(t, r.Fremote, file4, file5, file6)
+
+	opt := filter.DefaultOpt
+	opt.IgnoreFile = ".rcloneignore"
+	fi, err := filter.NewFilter(&opt)
+	require.NoError(t, err)
+	ctx = filter.ReplaceConfig(ctx, fi)
+
+	// Check the files the source ignores aren't deleted from the
+	// destination even though it has no ignore file yet
+	accounting.GlobalStats().ResetCounters()
+	err = Sync(ctx, r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file4, file5)
+}
+
 // Test with UpdateOlder set
 func TestSyncWithUpdateOlder(t *testing.T) {
 	ctx := context.Background()
@@ -2044,3 +2136,89 @@ func TestSyncConcurrentDelete(t *testing.T) {
 func TestSyncConcurrentTruncate(t *testing.T) {
 	testSyncConcurrent(t, "truncate")
 }
+
+// failingObject is an fs.Object which fails to open the first fails times
+type failingObject struct {
+	fs.Object
+	fails int
+}
+
+// Open the object failing if required
+func (o *failingObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	if o.fails > 0 {
+		o.fails--
+		return nil, errors.New("injected open failure")
+	}
+	return o.Object.Open(ctx, options...)
+}
+
+func TestSyncTransferRetries(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("retry me", "hello world", t1)
+	r.Mkdir(ctx, r.Fremote)
+	ci.LowLevelRetries = 1
+	ci.TransferRetries = 2
+	ci.TransferRetriesSleep = time.Millisecond
+
+	stats := accounting.GlobalStats()
+	stats.ResetCounters()
+	defer stats.ResetCounters()
+	s, err := newSyncCopyMove(ctx, r.Fremote, r.Flocal, fs.DeleteModeOff, false, false, false, nil)
+	require.NoError(t, err)
+	defer s.cancel()
+	src, err := r.Flocal.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+
+	// Fatal errors aren't retried
+	assert.False(t, s.queueRetry(fs.ObjectPair{Src: src}, fserrors.FatalError(errors.New("fatal"))))
+
+	// Fail the first two attempts
+	s.startTransfers()
+	require.True(t, s.toBeUploaded.Put(ctx, fs.ObjectPair{Src: &failingObject{Object: src, fails: 2}}))
+	s.stopTransfers()
+	assert.Len(t, s.retryQueue, 1)
+	assert.Equal(t, int64(1), stats.GetErrors())
+	assert.Equal(t, []string{file1.Path}, stats.FailedTransfers())
+
+	s.retryFailed()
+	assert.Equal(t, 2, s.retryPass)
+	assert.Len(t, s.retryQueue, 0)
+	assert.NoError(t, s.currentError())
+	assert.Equal(t, int64(0), stats.GetErrors())
+	assert.Equal(t, []string{}, stats.FailedTransfers())
+	fstest.CheckItems(t, r.Fremote, file1)
+}
+
+func TestSyncTransferRetriesUsedUp(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("retry me", "hello world", t1)
+	r.Mkdir(ctx, r.Fremote)
+	ci.LowLevelRetries = 1
+	ci.TransferRetries = 1
+	ci.TransferRetriesSleep = time.Millisecond
+
+	stats := accounting.GlobalStats()
+	stats.ResetCounters()
+	defer stats.ResetCounters()
+	s, err := newSyncCopyMove(ctx, r.Fremote, r.Flocal, fs.DeleteModeOff, false, false, false, nil)
+	require.NoError(t, err)
+	defer s.cancel()
+	src, err := r.Flocal.NewObject(ctx, file1.Path)
+	require.NoError(t, err)
+
+	s.startTransfers()
+	require.True(t, s.toBeUploaded.Put(ctx, fs.ObjectPair{Src: &failingObject{Object: src, fails: 2}}))
+	s.stopTransfers()
+	s.retryFailed()
+	assert.Equal(t, 1, s.retryPass)
+	assert.Error(t, s.currentError())
+	assert.Equal(t, int64(1), stats.GetErrors())
+	assert.Equal(t, []string{file1.Path}, stats.FailedTransfers())
+	fstest.CheckItems(t, r.Fremote)
+}
@@ -179,6 +179,19 @@ type GetTierer interface {
 	GetTier() string
 }
 
+// Metadataer is an optional interface for Object
+type Metadataer interface {
+	// Metadata returns the metadata of the Object
+	Metadata(ctx context.Context) (Metadata, error)
+}
+
+// SetMetadataer is an optional interface for Object
+type SetMetadataer interface {
+	// SetMetadata stores the keys in m on the Object leaving any
+	// other keys unchanged
+	SetMetadata(ctx context.Context, m Metadata) error
+}
+
 // FullObjectInfo contains all the read-only optional interfaces
 //
 // Use for checking making wrapping ObjectInfos implement everything
@@ -188,6 +201,7 @@ type FullObjectInfo interface {
 	IDer
 	ObjectUnWrapper
 	GetTierer
+	Metadataer
 }
 
 // FullObject contains all the optional interfaces for Object
@@ -249,7 +263,12 @@ func NewUsageValue(value int64) *int64 {
 
 // Usage is returned by the About call
 //
-// If a value is nil then it isn't supported by that backend
+// All the values are in bytes except Objects which is a count. If a
+// value is nil then it isn't supported by that backend or it is
+// unlimited, and it is left out of the JSON.
+//
+// This is the JSON output of rclone about --json and operations/about
+// so the field names shouldn't be changed.
 type Usage struct {
 	Total   *int64 `json:"total,omitempty"`   // quota of bytes that can be used
 	Used    *int64 `json:"used,omitempty"`    // bytes in use
@@ -0,0 +1,139 @@
+// Versions view of remotes
+
+package fs
+
+import (
+	"context"
+	"path"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/lib/version"
+)
+
+// versionsFromConfig reads the versions option from the config of
+// the remote. It returns false if it isn't set or if the backend has
+// a versions option of its own which does the same thing.
+func versionsFromConfig(configName string, fsInfo *RegInfo, config configmap.Getter) (bool, error) {
+	if fsInfo.Options.Get("versions") != nil {
+		return false, nil
+	}
+	value, found := config.Get("versions")
+	if !found || value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.Wrapf(err, "%s: bad versions parameter", configName)
+	}
+	return b, nil
+}
+
+// versionsFs wraps an Fs showing the old versions of its objects
+// alongside the current ones
+type versionsFs struct {
+	Fs
+	features *Features
+}
+
+// newVersionsFs wraps f so it lists the old versions of its objects.
+//
+// The view is read only, as --b2-versions is, so a sync to it can't
+// delete the old versions it shows or write files with their names.
+func newVersionsFs(f Fs) (Fs, error) {
+	features := *f.Features()
+	if features.ListVersions == nil {
+		return nil, errors.Errorf("%v: versions isn't supported", f)
+	}
+	// The backend listings don't include the old versions
+	features.ListR = nil
+	features.ListP = nil
+	v := &versionsFs{
+		Fs:       f,
+		features: &features,
+	}
+	return newRestrictedFs(v, restrictReadOnly), nil
+}
+
+// Features returns the optional features of the wrapped Fs less the
+// listings which wouldn't include the old versions
+func (v *versionsFs) Features() *Features {
+	return v.features
+}
+
+// wrapVersions wraps f and err returned from the NewFs of fsInfo in
+// a versionsFs.
+//
+// If fsPath is an old version of a file then it returns a versionsFs
+// pointing at its parent and ErrorIsFile, as NewFs does for files.
+func wrapVersions(ctx context.Context, fsInfo *RegInfo, configName, fsPath string, config configmap.Mapper, f Fs, err error) (Fs, error) {
+	if err != nil && err != ErrorIsFile {
+		return f, err
+	}
+	v, vErr := newVersionsFs(f)
+	if vErr != nil {
+		return nil, vErr
+	}
+	leaf := path.Base(fsPath)
+	if err != nil || !version.Match(leaf) {
+		return v, err
+	}
+	parentPath := path.Dir(fsPath)
+	if parentPath == "." {
+		parentPath = ""
+	}
+	parent, pErr := fsInfo.NewFs(ctx, configName, parentPath, config)
+	if pErr != nil {
+		return v, nil
+	}
+	pv, pErr := newVersionsFs(parent)
+	if pErr != nil {
+		return v, nil
+	}
+	if _, pErr = pv.NewObject(ctx, leaf); pErr != nil {
+		return v, nil
+	}
+	return pv, ErrorIsFile
+}
+
+// List the objects and directories in dir into entries including
+// the old versions of the objects
+func (v *versionsFs) List(ctx context.Context, dir string) (entries DirEntries, err error) {
+	entries, err = v.Fs.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := v.features.ListVersions(ctx, dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list versions")
+	}
+	return append(entries, versions...), nil
+}
+
+// NewObject finds the Object at remote which may be an old version
+func (v *versionsFs) NewObject(ctx context.Context, remote string) (Object, error) {
+	o, err := v.Fs.NewObject(ctx, remote)
+	if err != ErrorObjectNotFound || !version.Match(remote) {
+		return o, err
+	}
+	dir := path.Dir(remote)
+	if dir == "." {
+		dir = ""
+	}
+	versions, err := v.features.ListVersions(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range versions {
+		if o, ok := entry.(Object); ok && o.Remote() == remote {
+			return o, nil
+		}
+	}
+	return nil, ErrorObjectNotFound
+}
+
+// Check the interfaces are satisfied
+var (
+	_ Fs = (*versionsFs)(nil)
+)
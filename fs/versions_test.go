@@ -0,0 +1,82 @@
+package fs_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var oldVersion = version.Add("file.txt", time.Date(2021, 1, 2, 15, 4, 5, 0, time.UTC))
+
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name: "versionsmock",
+		NewFs: func(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+			f := mockfs.NewFs(ctx, name, root)
+			f.AddObject(mockobject.New("file.txt"))
+			f.Features().ListVersions = func(ctx context.Context, dir string) (fs.DirEntries, error) {
+				return fs.DirEntries{mockobject.New(oldVersion)}, nil
+			}
+			return f, nil
+		},
+	})
+}
+
+func TestVersions(t *testing.T) {
+	ctx := context.Background()
+	f, err := fs.NewFs(ctx, ":versionsmock:")
+	require.NoError(t, err)
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	f, err = fs.NewFs(ctx, ":versionsmock,versions:")
+	require.NoError(t, err)
+	assert.Nil(t, f.Features().ListR)
+	entries, err = f.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "file.txt", entries[0].Remote())
+	assert.Equal(t, oldVersion, entries[1].Remote())
+
+	o, err := f.NewObject(ctx, oldVersion)
+	require.NoError(t, err)
+	assert.Equal(t, oldVersion, o.Remote())
+	_, err = f.NewObject(ctx, version.Add("file.txt", time.Now()))
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	_, err = f.NewObject(ctx, "potato.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// the view is read only so a sync can't delete old versions
+	assert.Equal(t, fs.ErrorReadOnly, errors.Cause(o.Remove(ctx)))
+	assert.Equal(t, fs.ErrorReadOnly, errors.Cause(entries[1].(fs.Object).Remove(ctx)))
+	_, err = f.Put(ctx, bytes.NewBufferString("potato"), object.NewStaticObjectInfo("new.txt", time.Now(), 6, true, nil, nil))
+	assert.Equal(t, fs.ErrorReadOnly, errors.Cause(err))
+	assert.Nil(t, f.Features().Purge)
+
+	// pointing at an old version
+	f, err = fs.NewFs(ctx, ":versionsmock,versions:"+oldVersion)
+	assert.Equal(t, fs.ErrorIsFile, err)
+	require.NotNil(t, f)
+	assert.Equal(t, "", f.Root())
+}
+
+func TestVersionsErrors(t *testing.T) {
+	ctx := context.Background()
+	_, err := fs.NewFs(ctx, ":memory,versions:versions-errors")
+	assert.Error(t, err)
+
+	_, err = fs.NewFs(ctx, ":versionsmock,versions=potato:")
+	assert.Error(t, err)
+}
@@ -148,7 +148,7 @@ func ListR(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel
 	if doListR == nil || // ...no ListR
 		fi.HaveFilesFrom() || // ...using --files-from
 		maxLevel >= 0 || // ...using bounded recursion
-		len(fi.Opt.ExcludeFile) > 0 || // ...using --exclude-file
+		fi.UsesDirListings() || // ...using --exclude-if-present or other filters needing whole directories
 		fi.UsesDirectoryFilters() { // ...using any directory filters
 		return listRwalk(ctx, f, path, includeAll, maxLevel, listType, fn)
 	}
@@ -369,7 +369,8 @@ func walk(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel i
 		depth  int
 	}
 
-	in := make(chan listJob, ci.Checkers)
+	listers := ci.Listers()
+	in := make(chan listJob, listers)
 	errs := make(chan error, 1)
 	quit := make(chan struct{})
 	closeQuit := func() {
@@ -382,7 +383,7 @@ func walk(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel i
 			}()
 		})
 	}
-	for i := 0; i < ci.Checkers; i++ {
+	for i := 0; i < listers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -483,7 +484,7 @@ func walkRDirTree(ctx context.Context, f fs.Fs, startPath string, includeAll boo
 				// Check if we need to prune a directory later.
 				if !includeAll && len(fi.Opt.ExcludeFile) > 0 {
 					basename := path.Base(x.Remote())
-					if basename == fi.Opt.ExcludeFile {
+					if fi.IsExcludeFile(basename) {
 						excludeDir := parentDir(x.Remote())
 						toPrune[excludeDir] = true
 						fs.Debugf(basename, "Excluded from sync (and deletion) based on exclude file")
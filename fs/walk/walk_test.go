@@ -7,6 +7,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs"
@@ -296,6 +297,48 @@ a/b/c/d/
 `, entries.String())
 }
 
+func TestWalkListParallelism(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.ListParallelism = 3
+
+	// A root with lots of sibling directories
+	var root fs.DirEntries
+	for i := 0; i < 20; i++ {
+		root = append(root, mockdir.New(fmt.Sprintf("dir%02d", i)))
+	}
+	var (
+		mu         sync.Mutex
+		running    int
+		maxRunning int
+		listed     int
+	)
+	listDir := func(ctx context.Context, f fs.Fs, includeAll bool, dir string) (fs.DirEntries, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		listed++
+		mu.Unlock()
+		if dir == "" {
+			return root, nil
+		}
+		return nil, nil
+	}
+	err := walk(ctx, nil, "", true, -1, func(path string, entries fs.DirEntries, err error) error {
+		return err
+	}, listDir)
+	require.NoError(t, err)
+	assert.Equal(t, 21, listed)
+	assert.True(t, maxRunning > 1, "expecting directories to be listed in parallel")
+	assert.True(t, maxRunning <= 3, "expecting at most 3 directories to be listed at once but got %d", maxRunning)
+}
+
 func testWalkLevelsNoRecursive(t *testing.T) *listDirs {
 	da := mockdir.New("a")
 	oA := mockobject.Object("A")
@@ -650,13 +693,16 @@ b/c/d/
   e
 `, nil, "", -1, "ign", true},
 	} {
-		fi.Opt.ExcludeFile = test.excludeFile
+		fi.Opt.ExcludeFile = nil
+		if test.excludeFile != "" {
+			fi.Opt.ExcludeFile = []string{test.excludeFile}
+		}
 		r, err := walkRDirTree(context.Background(), nil, test.root, test.includeAll, test.level, makeListRCallback(test.entries, test.err))
 		assert.Equal(t, test.err, err, fmt.Sprintf("%+v", test))
 		assert.Equal(t, test.want, r.String(), fmt.Sprintf("%+v", test))
 	}
 	// Set to default value, to avoid side effects
-	fi.Opt.ExcludeFile = ""
+	fi.Opt.ExcludeFile = nil
 }
 
 func TestListType(t *testing.T) {
@@ -1011,6 +1011,37 @@ func Run(t *testing.T, opt *Opt) {
 				TestFsListLevel2(t)
 			})
 
+			// TestFsListP tests ListP returns the same as List
+			t.Run("FsListP", func(t *testing.T) {
+				skipIfNotOk(t)
+				doListP := f.Features().ListP
+				if doListP == nil {
+					t.Skip("FS has no ListP interface")
+				}
+				dir := path.Dir(file2.Path)
+				want, err := f.List(ctx, dir)
+				require.NoError(t, err)
+				var got fs.DirEntries
+				err = doListP(ctx, dir, func(entries fs.DirEntries) error {
+					got = append(got, entries...)
+					return nil
+				})
+				require.NoError(t, err)
+				names := func(entries fs.DirEntries) (names []string) {
+					for _, entry := range entries {
+						names = append(names, entry.Remote())
+					}
+					sort.Strings(names)
+					return names
+				}
+				assert.Equal(t, names(want), names(got))
+
+				err = doListP(ctx, "does not exist", func(entries fs.DirEntries) error {
+					return nil
+				})
+				assert.Equal(t, fs.ErrorDirNotFound, errors.Cause(err))
+			})
+
 			// TestFsListFile1 tests file present
 			t.Run("FsListFile1", func(t *testing.T) {
 				skipIfNotOk(t)
@@ -21,6 +21,9 @@ var (
 	registerOnce sync.Once
 	signalled    int32
 	runCalled    int32
+	drainMu      sync.Mutex
+	drainFn      func()
+	drainOnce    sync.Once
 )
 
 // FnHandle is the type of the handle returned by function `Register`
@@ -37,7 +40,13 @@ func Register(fn func()) FnHandle {
 	fns[&fn] = true
 	fnsMutex.Unlock()
 
-	// Run AtExit handlers on exitSignals so everything gets tidied up properly
+	startSignalHandler()
+	return &fn
+}
+
+// startSignalHandler runs the AtExit handlers on exitSignals so
+// everything gets tidied up properly
+func startSignalHandler() {
 	registerOnce.Do(func() {
 		exitChan = make(chan os.Signal, 1)
 		signal.Notify(exitChan, exitSignals...)
@@ -46,16 +55,57 @@ func Register(fn func()) FnHandle {
 			if sig == nil {
 				return
 			}
-			signal.Stop(exitChan)
 			atomic.StoreInt32(&signalled, 1)
-			fs.Infof(nil, "Signal received: %s", sig)
+			if getDrain() != nil {
+				fs.Logf(nil, "Signal received: %s - shutting down gracefully, send it again to exit now", sig)
+				done := make(chan struct{})
+				go func() {
+					Drain()
+					close(done)
+				}()
+				select {
+				case <-done:
+				case sig = <-exitChan:
+					if sig == nil {
+						return
+					}
+					fs.Logf(nil, "Signal received again: %s", sig)
+				}
+			} else {
+				fs.Infof(nil, "Signal received: %s", sig)
+			}
+			signal.Stop(exitChan)
 			Run()
 			fs.Infof(nil, "Exiting...")
 			os.Exit(exitCode(sig))
 		}()
 	})
+}
 
-	return &fn
+// SetDrain sets fn to be called by Drain to shut down gracefully when
+// an exit signal is received, before the at exit functions are run.
+//
+// fn should return when the program is ready to exit. A second exit
+// signal exits without waiting for it.
+func SetDrain(fn func()) {
+	drainMu.Lock()
+	drainFn = fn
+	drainMu.Unlock()
+	startSignalHandler()
+}
+
+// getDrain returns the function set with SetDrain or nil
+func getDrain() func() {
+	drainMu.Lock()
+	defer drainMu.Unlock()
+	return drainFn
+}
+
+// Drain calls the function set with SetDrain, if any, once
+func Drain() {
+	if fn := getDrain(); fn != nil {
+		drainOnce.Do(fn)
+	}
 }
 
 // Signalled returns true if an exit signal has been received
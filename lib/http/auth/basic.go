@@ -88,8 +88,8 @@ func HtPasswdAuth(path, realm string) httplib.Middleware {
 func SingleAuth(user, pass, realm string) httplib.Middleware {
 	fs.Infof(nil, "Using --user %s --pass XXXX as authenticated user", user)
 	pass = string(auth.MD5Crypt([]byte(pass), []byte("dlPL2MqE"), []byte("$1$")))
-	secretProvider := func(user, realm string) string {
-		if user == user {
+	secretProvider := func(gotUser, realm string) string {
+		if gotUser == user {
 			return pass
 		}
 		return ""
@@ -102,18 +102,26 @@ func SingleAuth(user, pass, realm string) httplib.Middleware {
 func CustomAuth(fn CustomAuthFn, realm string) httplib.Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Reuse BasicAuth error reporting
+			requireAuth := func() {
+				auth.NewBasicAuthenticator(realm, func(user, realm string) string { return "" }).RequireAuth(w, r)
+			}
 			user, pass, ok := parseAuthorization(r)
-			if ok {
-				value, err := fn(user, pass)
-				if err != nil {
-					fs.Infof(r.URL.Path, "%s: Auth failed from %s: %v", r.RemoteAddr, user, err)
-					auth.NewBasicAuthenticator(realm, func(user, realm string) string { return "" }).RequireAuth(w, r) //Reuse BasicAuth error reporting
-					return
-				}
-				if value != nil {
-					r = r.WithContext(context.WithValue(r.Context(), ContextAuthKey, value))
-				}
+			if !ok {
+				requireAuth()
+				return
+			}
+			value, err := fn(user, pass)
+			if err != nil {
+				fs.Infof(r.URL.Path, "%s: Auth failed from %s: %v", r.RemoteAddr, user, err)
+				requireAuth()
+				return
+			}
+			ctx := context.WithValue(r.Context(), ContextUserKey, user)
+			if value != nil {
+				ctx = context.WithValue(ctx, ContextAuthKey, value)
 			}
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
@@ -322,6 +322,16 @@ func start() error {
 	return nil
 }
 
+// Wait blocks until the default http server is shut down
+func Wait() {
+	defaultServerMutex.Lock()
+	s := defaultServer
+	defaultServerMutex.Unlock()
+	if s != nil {
+		s.closing.Wait()
+	}
+}
+
 // Shutdown gracefully shuts down the default http server
 func Shutdown() error {
 	defaultServerMutex.Lock()
@@ -0,0 +1,91 @@
+package serve
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CheckPreconditions evaluates the conditional request headers
+// (If-Match, If-Unmodified-Since, If-None-Match and If-Modified-Since)
+// of r against the modTime and etag of the resource as described in
+// RFC 7232.  Either of modTime and etag may be empty if unknown.
+//
+// If the request shouldn't proceed it writes a 304 Not Modified or
+// 412 Precondition Failed response and returns true.
+func CheckPreconditions(w http.ResponseWriter, r *http.Request, modTime time.Time, etag string) (done bool) {
+	isGetOrHead := r.Method == "GET" || r.Method == "HEAD"
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !etagListMatches(ifMatch, etag, false) {
+			writeStatus(w, http.StatusPreconditionFailed)
+			return true
+		}
+	} else if since, ok := parseTimeHeader(r, "If-Unmodified-Since"); ok && !modTime.IsZero() {
+		if modTime.Truncate(time.Second).After(since) {
+			writeStatus(w, http.StatusPreconditionFailed)
+			return true
+		}
+	}
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if etagListMatches(ifNoneMatch, etag, true) {
+			if isGetOrHead {
+				writeStatus(w, http.StatusNotModified)
+			} else {
+				writeStatus(w, http.StatusPreconditionFailed)
+			}
+			return true
+		}
+	} else if since, ok := parseTimeHeader(r, "If-Modified-Since"); ok && isGetOrHead && !modTime.IsZero() {
+		if !modTime.Truncate(time.Second).After(since) {
+			writeStatus(w, http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// parseTimeHeader parses the HTTP date in the header name of r
+func parseTimeHeader(r *http.Request, name string) (t time.Time, ok bool) {
+	value := r.Header.Get(name)
+	if value == "" {
+		return t, false
+	}
+	t, err := http.ParseTime(value)
+	return t, err == nil
+}
+
+// etagListMatches returns true if etag is in the comma separated list
+// of entity tags.  Weak comparison ignores the W/ prefix.
+func etagListMatches(list, etag string, weak bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return etag != ""
+	}
+	if etag == "" {
+		return false
+	}
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	} else if strings.HasPrefix(etag, "W/") {
+		// weak tags never match strongly
+		return false
+	}
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeStatus writes a bodiless response without the entity headers
+func writeStatus(w http.ResponseWriter, status int) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	h.Del("Content-Range")
+	w.WriteHeader(status)
+}
@@ -0,0 +1,61 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPreconditions(t *testing.T) {
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 700, time.UTC)
+	before := modTime.Add(-time.Hour).Format(http.TimeFormat)
+	at := modTime.Format(http.TimeFormat)
+	const etag = `"abc"`
+	for _, test := range []struct {
+		method  string
+		headers map[string]string
+		etag    string
+		want    int // 0 for carry on
+	}{
+		{"GET", nil, etag, 0},
+		{"GET", map[string]string{"If-None-Match": `"abc"`}, etag, http.StatusNotModified},
+		{"HEAD", map[string]string{"If-None-Match": `"x", W/"abc"`}, etag, http.StatusNotModified},
+		{"GET", map[string]string{"If-None-Match": `"x"`}, etag, 0},
+		{"GET", map[string]string{"If-None-Match": `*`}, etag, http.StatusNotModified},
+		{"PUT", map[string]string{"If-None-Match": `"abc"`}, etag, http.StatusPreconditionFailed},
+		{"GET", map[string]string{"If-None-Match": `"abc"`}, "", 0},
+		{"GET", map[string]string{"If-Modified-Since": at}, etag, http.StatusNotModified},
+		{"GET", map[string]string{"If-Modified-Since": before}, etag, 0},
+		{"GET", map[string]string{"If-Modified-Since": "potato"}, etag, 0},
+		// If-None-Match takes precedence over If-Modified-Since
+		{"GET", map[string]string{"If-None-Match": `"x"`, "If-Modified-Since": at}, etag, 0},
+		{"GET", map[string]string{"If-Match": `"abc"`}, etag, 0},
+		{"GET", map[string]string{"If-Match": `"x"`}, etag, http.StatusPreconditionFailed},
+		{"GET", map[string]string{"If-Match": `W/"abc"`}, `W/"abc"`, http.StatusPreconditionFailed},
+		{"GET", map[string]string{"If-Match": `*`}, "", http.StatusPreconditionFailed},
+		{"GET", map[string]string{"If-Unmodified-Since": at}, etag, 0},
+		{"GET", map[string]string{"If-Unmodified-Since": before}, etag, http.StatusPreconditionFailed},
+	} {
+		r := httptest.NewRequest(test.method, "http://example.com/aFile", nil)
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		w.Header().Set("Content-Length", "5")
+		done := CheckPreconditions(w, r, modTime, test.etag)
+		what := test.method + " " + test.etag
+		for k, v := range test.headers {
+			what += " " + k + ": " + v
+		}
+		if test.want == 0 {
+			assert.False(t, done, what)
+			continue
+		}
+		assert.True(t, done, what)
+		assert.Equal(t, test.want, w.Code, what)
+		assert.Equal(t, "", w.Header().Get("Content-Length"), what)
+	}
+}
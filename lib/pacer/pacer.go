@@ -194,8 +194,22 @@ func (p *Pacer) call(fn Paced, retries int) (err error) {
 	var retry bool
 	for i := 1; i <= retries; i++ {
 		p.beginCall()
-		retry, err = p.invoker(i, retries, fn)
-		p.endCall(retry, err)
+		// Take off the rate limited mark so the invoker and the
+		// caller see the error as it was, putting it back for the
+		// calculator
+		rateLimited := false
+		retry, err = p.invoker(i, retries, func() (bool, error) {
+			retry, err := fn()
+			if r, ok := err.(*rateLimitedError); ok {
+				rateLimited, err = true, r.error
+			}
+			return retry, err
+		})
+		if rateLimited {
+			p.endCall(retry, RateLimitedError(err))
+		} else {
+			p.endCall(retry, err)
+		}
 		if !retry {
 			break
 		}
@@ -262,3 +276,44 @@ func IsRetryAfter(err error) (retryAfter time.Duration, isRetryAfter bool) {
 	})
 	return
 }
+
+type rateLimitedError struct {
+	error
+}
+
+func (r *rateLimitedError) Error() string {
+	return r.error.Error()
+}
+
+func (r *rateLimitedError) Cause() error {
+	return r.error
+}
+
+// RateLimitedError returns a wrapped error which says the server
+// rejected the call for being too fast, e.g. with a 429 Too Many
+// Requests or 503 Service Unavailable status, which can be used by
+// Calculator implementations.
+//
+// The Pacer takes the wrapping off before returning the error.
+func RateLimitedError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &rateLimitedError{
+		error: err,
+	}
+}
+
+// IsRateLimited returns true if the error or any of it's Cause's is an
+// error returned by RateLimitedError or RetryAfterError.
+func IsRateLimited(err error) (isRateLimited bool) {
+	errors.Walk(err, func(err error) bool {
+		switch err.(type) {
+		case *rateLimitedError, *retryAfterError:
+			isRateLimited = true
+			return true
+		}
+		return false
+	})
+	return isRateLimited
+}
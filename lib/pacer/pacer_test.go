@@ -283,6 +283,103 @@ func TestS3Pacer(t *testing.T) {
 	}
 }
 
+func TestAdaptivePacer(t *testing.T) {
+	c := NewAdaptive(NewS3(MinSleep(10*time.Millisecond), MaxSleep(1*time.Second)), AdaptiveIncrease(10))
+	retryAfter := RetryAfterError(errors.New("too many requests"), 3*time.Second)
+	rateLimited := RateLimitedError(errors.New("slow down"))
+	for _, test := range []struct {
+		state State
+		want  time.Duration
+	}{
+		{State{SleepTime: 0}, 0}, // Not limited so no sleep
+		{State{SleepTime: 0, ConsecutiveRetries: 1, LastError: rateLimited}, 20 * time.Millisecond}, // Limited so halve the rate from minSleep
+		{State{SleepTime: 20 * time.Millisecond, ConsecutiveRetries: 2}, 40 * time.Millisecond},     // Further retries back off with the base
+		{State{SleepTime: 40 * time.Millisecond}, 30 * time.Millisecond},                            // Decay with the base, increasing the rate 50/s => 60/s
+		{State{SleepTime: 16666666, ConsecutiveRetries: 1, LastError: retryAfter}, 3 * time.Second}, // Retry-After is honoured
+		{State{SleepTime: 3 * time.Second}, 2250 * time.Millisecond},                                // Sleep is never less than the base
+	} {
+		got := c.Calculate(test.state)
+		assert.Equal(t, test.want, got, "test: %+v", test)
+	}
+	// Increase the rate 40/s => 50/s
+	got := c.Calculate(State{SleepTime: 25 * time.Millisecond})
+	assert.InDelta(t, float64(20*time.Millisecond), float64(got), float64(time.Microsecond))
+	stats := c.Stats()
+	assert.Equal(t, int64(7), stats.Calls)
+	assert.Equal(t, int64(3), stats.Retries)
+	assert.InDelta(t, float64(20*time.Millisecond), float64(stats.Interval), float64(time.Microsecond))
+	assert.InDelta(t, 50.0, stats.Rate, 0.001)
+	assert.True(t, stats.ErrorRate > 0)
+
+	// Successes increase the rate until it no longer limits
+	for i := 0; i < 100; i++ {
+		c.Calculate(State{SleepTime: 0})
+	}
+	assert.Equal(t, time.Duration(0), c.Calculate(State{SleepTime: 0}))
+	stats = c.Stats()
+	assert.Equal(t, time.Duration(0), stats.Interval)
+	assert.Equal(t, 0.0, stats.Rate)
+}
+
+func TestAdaptivePacerPlainRetry(t *testing.T) {
+	newBase := func() Calculator {
+		return NewS3(MinSleep(10*time.Millisecond), MaxSleep(1*time.Second))
+	}
+	base := newBase()
+	c := NewAdaptive(newBase(), AdaptiveIncrease(10))
+	plain := errors.New("connection reset")
+
+	// Retries which weren't rate limited leave the rate alone
+	for _, state := range []State{
+		{SleepTime: 0},
+		{SleepTime: 0, ConsecutiveRetries: 1, LastError: plain},
+		{SleepTime: 20 * time.Millisecond, ConsecutiveRetries: 2, LastError: plain},
+		{SleepTime: 40 * time.Millisecond},
+	} {
+		assert.Equal(t, base.Calculate(state), c.Calculate(state), "state: %+v", state)
+	}
+	stats := c.Stats()
+	assert.Equal(t, time.Duration(0), stats.Interval)
+	assert.Equal(t, int64(2), stats.Retries)
+
+	// And don't halve a rate which has been learnt
+	c.Calculate(State{SleepTime: 0, ConsecutiveRetries: 1, LastError: RateLimitedError(plain)})
+	c.Calculate(State{SleepTime: 0})
+	interval := c.Stats().Interval
+	assert.NotEqual(t, time.Duration(0), interval)
+	c.Calculate(State{SleepTime: 0, ConsecutiveRetries: 1, LastError: plain})
+	assert.Equal(t, interval, c.Stats().Interval)
+}
+
+func TestIsRateLimited(t *testing.T) {
+	assert.False(t, IsRateLimited(nil))
+	assert.False(t, IsRateLimited(errFoo))
+	assert.True(t, IsRateLimited(RateLimitedError(errFoo)))
+	assert.True(t, IsRateLimited(RetryAfterError(errFoo, time.Second)))
+	assert.True(t, IsRateLimited(errors.Wrap(RateLimitedError(errFoo), "wrapped")))
+	assert.Nil(t, RateLimitedError(nil))
+}
+
+func TestCallRateLimited(t *testing.T) {
+	p := New(RetriesOption(1), CalculatorOption(NewDefault(MinSleep(1*time.Millisecond), MaxSleep(2*time.Millisecond))))
+	err := p.Call(func() (bool, error) {
+		return true, RateLimitedError(errFoo)
+	})
+	assert.Equal(t, errFoo, err)
+	assert.True(t, IsRateLimited(p.state.LastError))
+
+	// The invoker doesn't see the mark either
+	p = New(RetriesOption(1), InvokerOption(func(try, tries int, f Paced) (bool, error) {
+		retry, err := f()
+		return retry, errors.Wrap(err, "invoked")
+	}))
+	err = p.Call(func() (bool, error) {
+		return true, RateLimitedError(errFoo)
+	})
+	assert.False(t, IsRateLimited(err))
+	assert.Equal(t, errFoo, errors.Cause(err))
+}
+
 func TestEndCall(t *testing.T) {
 	p := New(MaxConnectionsOption(5))
 	emptyTokens(p)
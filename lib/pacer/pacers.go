@@ -2,6 +2,7 @@ package pacer
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -353,3 +354,153 @@ func (c *S3) Calculate(state State) time.Duration {
 	}
 	return sleepTime
 }
+
+// Adaptive wraps another Calculator to learn the rate the API can
+// sustain from its rate limiting errors.
+//
+// It implements an additive increase, multiplicative decrease (AIMD)
+// controller on the rate of calls. Each time the provider says a call
+// was too fast, with an error marked by RateLimitedError or
+// RetryAfterError, the rate is halved and each successful call raises
+// it a little, so rather than going straight back to the minimum sleep
+// after an error and being rate limited again, the rate settles just
+// below the limit of the provider. Only the first rate limited retry
+// of a run of retries halves the rate. Other retries, e.g. for network
+// errors or 500 errors, leave it unchanged.
+//
+// The sleep is never less than the wrapped Calculator gives, so its
+// minimum sleep time, burst and retry backoff still apply, and a
+// Retry-After is always honoured.
+type Adaptive struct {
+	mu        sync.Mutex
+	base      Calculator    // the Calculator being wrapped
+	interval  time.Duration // learnt interval between calls or 0 if not rate limited yet
+	increase  float64       // rate increase in calls/s on each success
+	errorRate float64       // moving average of the fraction of calls retried
+	calls     int64         // number of calls
+	retries   int64         // number of calls retried
+	limited   bool          // set if the rate was halved in this run of retries
+}
+
+// AdaptiveOption is the interface implemented by all options for the Adaptive Calculator
+type AdaptiveOption interface {
+	ApplyAdaptive(*Adaptive)
+}
+
+// AdaptiveIncrease configures the rate increase in calls/s on each
+// successful call of an Adaptive Calculator
+type AdaptiveIncrease float64
+
+// ApplyAdaptive updates the value on the Calculator
+func (o AdaptiveIncrease) ApplyAdaptive(c *Adaptive) {
+	c.increase = float64(o)
+}
+
+const (
+	// adaptiveMinInterval is the interval used when first rate
+	// limited if there was no sleep at all
+	adaptiveMinInterval = 10 * time.Millisecond
+	// adaptiveMaxInterval is the largest interval learnt
+	adaptiveMaxInterval = 10 * time.Second
+	// adaptiveDecay is the weight of each call in errorRate
+	adaptiveDecay = 0.05
+)
+
+// NewAdaptive returns a new Adaptive Calculator wrapping base which
+// will use the Default Calculator if nil
+func NewAdaptive(base Calculator, opts ...AdaptiveOption) *Adaptive {
+	if base == nil {
+		base = NewDefault()
+	}
+	c := &Adaptive{
+		base:     base,
+		increase: 0.1,
+	}
+	c.Update(opts...)
+	return c
+}
+
+// Update applies the Calculator options.
+func (c *Adaptive) Update(opts ...AdaptiveOption) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, opt := range opts {
+		opt.ApplyAdaptive(c)
+	}
+}
+
+// Base returns the Calculator being wrapped
+func (c *Adaptive) Base() Calculator {
+	return c.base
+}
+
+// Calculate takes the current Pacer state and return the wait time until the next try.
+func (c *Adaptive) Calculate(state State) time.Duration {
+	sleepTime := c.base.Calculate(state)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if state.ConsecutiveRetries > 0 {
+		c.retries++
+		c.errorRate += (1 - c.errorRate) * adaptiveDecay
+		// Halve the rate we were calling at when we were told it
+		// was too fast, leaving the backoff for further retries
+		// to the base
+		if !c.limited && IsRateLimited(state.LastError) {
+			c.limited = true
+			if c.interval == 0 {
+				c.interval = state.SleepTime
+				if c.interval < adaptiveMinInterval {
+					c.interval = adaptiveMinInterval
+				}
+			}
+			c.interval *= 2
+			if c.interval > adaptiveMaxInterval {
+				c.interval = adaptiveMaxInterval
+			}
+		}
+	} else {
+		c.limited = false
+		c.errorRate -= c.errorRate * adaptiveDecay
+		// Increase the rate slowly until it is no longer limiting
+		if c.interval > 0 {
+			rate := float64(time.Second)/float64(c.interval) + c.increase
+			c.interval = time.Duration(float64(time.Second) / rate)
+			if c.interval < adaptiveMinInterval/2 {
+				c.interval = 0
+			}
+		}
+	}
+	if t, ok := IsRetryAfter(state.LastError); ok && t > sleepTime {
+		sleepTime = t
+	}
+	if c.interval > sleepTime {
+		sleepTime = c.interval
+	}
+	return sleepTime
+}
+
+// AdaptiveStats are the statistics of an Adaptive Calculator
+type AdaptiveStats struct {
+	Interval  time.Duration // learnt interval between calls or 0 if not limited
+	Rate      float64       // learnt rate in calls/s or 0 if not limited
+	ErrorRate float64       // moving average of the fraction of calls retried
+	Calls     int64         // number of calls
+	Retries   int64         // number of calls retried
+}
+
+// Stats returns the current statistics of the Calculator
+func (c *Adaptive) Stats() (stats AdaptiveStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats = AdaptiveStats{
+		Interval:  c.interval,
+		ErrorRate: c.errorRate,
+		Calls:     c.calls,
+		Retries:   c.retries,
+	}
+	if c.interval > 0 {
+		stats.Rate = float64(time.Second) / float64(c.interval)
+	}
+	return stats
+}
@@ -10,6 +10,10 @@
 //     go build -buildmode=plugin -o librcloneplugin_NAME.so
 //
 // where NAME equals the plugin's fs.RegInfo.Name.
+//
+// Plugins must be built with the same version of Go and rclone as the
+// binary which loads them.  Backends can instead be run as a separate
+// program, written in any language, with the external backend.
 package plugin
 
 // Build for plugin for unsupported platforms to stop go complaining
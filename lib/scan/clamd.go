@@ -0,0 +1,119 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// clamdChunkSize is the size of the chunks sent with INSTREAM
+	clamdChunkSize = 64 * 1024
+	// clamdDefaultPort is the port used if the address doesn't have one
+	clamdDefaultPort = "3310"
+)
+
+// clamd scans data with the INSTREAM command of the ClamAV daemon
+type clamd struct {
+	network string
+	address string
+	dial    DialContextFn
+}
+
+// NewClamd makes a Scanner which uses the clamd at address.
+//
+// address is either a path to the unix socket of clamd, optionally
+// prefixed with "unix:", or a host with an optional port for TCP.
+func NewClamd(address string, dial DialContextFn) Scanner {
+	c := &clamd{
+		network: "tcp",
+		address: address,
+		dial:    dial,
+	}
+	if strings.HasPrefix(address, "unix:") || strings.HasPrefix(address, "/") {
+		c.network = "unix"
+		c.address = strings.TrimPrefix(address, "unix:")
+	} else if _, _, err := net.SplitHostPort(address); err != nil {
+		c.address = net.JoinHostPort(address, clamdDefaultPort)
+	}
+	return c
+}
+
+// String describes the scanner
+func (c *clamd) String() string {
+	return "clamd " + c.address
+}
+
+// Scan reads all of in and returns the name of the threat found in
+// it or "" if it is clean
+func (c *clamd) Scan(ctx context.Context, in io.Reader) (threat string, err error) {
+	conn, err := c.dial(ctx, c.network, c.address)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to connect to clamd")
+	}
+	defer func() {
+		closeErr := conn.Close()
+		if err == nil && closeErr != nil {
+			err = errors.Wrap(closeErr, "failed to close clamd connection")
+		}
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	w := bufio.NewWriterSize(conn, clamdChunkSize+4)
+	if _, err = w.WriteString("zINSTREAM\x00"); err != nil {
+		return "", errors.Wrap(err, "failed to send to clamd")
+	}
+	buf := make([]byte, clamdChunkSize)
+	var size [4]byte
+	for {
+		n, readErr := in.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, err = w.Write(size[:]); err != nil {
+				return "", errors.Wrap(err, "failed to send to clamd")
+			}
+			if _, err = w.Write(buf[:n]); err != nil {
+				return "", errors.Wrap(err, "failed to send to clamd")
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return "", errors.Wrap(readErr, "failed to read data to scan")
+		}
+	}
+	// A zero length chunk ends the stream
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err = w.Write(size[:]); err != nil {
+		return "", errors.Wrap(err, "failed to send to clamd")
+	}
+	if err = w.Flush(); err != nil {
+		return "", errors.Wrap(err, "failed to send to clamd")
+	}
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && err != io.EOF {
+		return "", errors.Wrap(err, "failed to read reply from clamd")
+	}
+	return parseClamdReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseClamdReply parses a reply like "stream: OK" or
+// "stream: Eicar-Test-Signature FOUND" returning the threat found
+func parseClamdReply(reply string) (threat string, err error) {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case result == "OK":
+		return "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return strings.TrimSuffix(result, " FOUND"), nil
+	}
+	return "", errors.Errorf("clamd failed to scan: %q", reply)
+}
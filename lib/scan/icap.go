@@ -0,0 +1,151 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// icapChunkSize is the size of the chunks of the body sent
+	icapChunkSize = 64 * 1024
+	// icapDefaultPort is the port used if the URL doesn't have one
+	icapDefaultPort = "1344"
+	// icapResHdr is the HTTP response the data is sent as
+	icapResHdr = "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n"
+)
+
+// icap scans data by sending it to an ICAP server in a RESPMOD
+// request as described in RFC 3507
+type icap struct {
+	url     string
+	host    string
+	address string
+	dial    DialContextFn
+}
+
+// NewICAP makes a Scanner which uses the ICAP service at rawURL, eg
+// icap://localhost:1344/avscan
+func NewICAP(rawURL string, dial DialContextFn) (Scanner, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad ICAP URL")
+	}
+	if u.Scheme != "icap" {
+		return nil, errors.Errorf("bad ICAP URL %q: scheme must be icap://", rawURL)
+	}
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), icapDefaultPort)
+	}
+	return &icap{
+		url:     rawURL,
+		host:    u.Host,
+		address: address,
+		dial:    dial,
+	}, nil
+}
+
+// String describes the scanner
+func (c *icap) String() string {
+	return "ICAP " + c.url
+}
+
+// Scan reads all of in and returns the name of the threat found in
+// it or "" if it is clean
+func (c *icap) Scan(ctx context.Context, in io.Reader) (threat string, err error) {
+	conn, err := c.dial(ctx, "tcp", c.address)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to connect to ICAP server")
+	}
+	defer func() {
+		closeErr := conn.Close()
+		if err == nil && closeErr != nil {
+			err = errors.Wrap(closeErr, "failed to close ICAP connection")
+		}
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	w := bufio.NewWriterSize(conn, icapChunkSize+16)
+	_, _ = fmt.Fprintf(w, "RESPMOD %s ICAP/1.0\r\n", c.url)
+	_, _ = fmt.Fprintf(w, "Host: %s\r\n", c.host)
+	_, _ = fmt.Fprintf(w, "Allow: 204\r\n")
+	_, _ = fmt.Fprintf(w, "Encapsulated: res-hdr=0, res-body=%d\r\n\r\n", len(icapResHdr))
+	_, _ = w.WriteString(icapResHdr)
+	buf := make([]byte, icapChunkSize)
+	for {
+		n, readErr := in.Read(buf)
+		if n > 0 {
+			_, _ = fmt.Fprintf(w, "%x\r\n", n)
+			_, _ = w.Write(buf[:n])
+			if _, err = w.WriteString("\r\n"); err != nil {
+				return "", errors.Wrap(err, "failed to send to ICAP server")
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return "", errors.Wrap(readErr, "failed to read data to scan")
+		}
+	}
+	_, _ = w.WriteString("0\r\n\r\n")
+	if err = w.Flush(); err != nil {
+		return "", errors.Wrap(err, "failed to send to ICAP server")
+	}
+	r := textproto.NewReader(bufio.NewReader(conn))
+	status, err := r.ReadLine()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read reply from ICAP server")
+	}
+	header, err := r.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return "", errors.Wrap(err, "failed to read reply from ICAP server")
+	}
+	return parseICAPReply(status, header)
+}
+
+// parseICAPReply parses the status line and headers returned by the
+// ICAP server returning the threat found
+func parseICAPReply(status string, header textproto.MIMEHeader) (threat string, err error) {
+	fields := strings.SplitN(status, " ", 3)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "ICAP/") {
+		return "", errors.Errorf("bad reply from ICAP server: %q", status)
+	}
+	code, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", errors.Errorf("bad reply from ICAP server: %q", status)
+	}
+	switch code {
+	case 204:
+		// No modifications needed so the data is clean
+		return "", nil
+	case 200:
+		// The server changed the response so something was found
+		if found := header.Get("X-Infection-Found"); found != "" {
+			for _, part := range strings.Split(found, ";") {
+				part = strings.TrimSpace(part)
+				if strings.HasPrefix(part, "Threat=") {
+					return strings.TrimPrefix(part, "Threat="), nil
+				}
+			}
+			return found, nil
+		}
+		for _, key := range []string{"X-Virus-ID", "X-Violations-Found"} {
+			if found := header.Get(key); found != "" {
+				return found, nil
+			}
+		}
+		return "unknown threat", nil
+	}
+	return "", errors.Errorf("ICAP server failed to scan: %q", status)
+}
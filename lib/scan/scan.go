@@ -0,0 +1,22 @@
+// Package scan implements clients for the virus scanners which can
+// check data before it is uploaded
+package scan
+
+import (
+	"context"
+	"io"
+	"net"
+)
+
+// Scanner checks data for malware
+type Scanner interface {
+	// Scan reads all of in and returns the name of the threat
+	// found in it or "" if it is clean
+	Scan(ctx context.Context, in io.Reader) (threat string, err error)
+
+	// String describes the scanner
+	String() string
+}
+
+// DialContextFn is used to connect to the scanners
+type DialContextFn func(ctx context.Context, network, address string) (net.Conn, error)
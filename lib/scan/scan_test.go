@@ -0,0 +1,201 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// infected is the data the fake scanners flag. The real EICAR test
+// file isn't used so virus scanners don't quarantine this file.
+const infected = "RCLONE-SCAN-TEST-INFECTED-DATA"
+
+var dialer net.Dialer
+
+// serve runs handle on a connection to a listener on localhost
+// returning its address
+func serve(t *testing.T, handle func(conn net.Conn)) (address string, stop func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				handle(conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+	return l.Addr().String(), func() { _ = l.Close() }
+}
+
+// fakeClamd reads an INSTREAM and flags data containing infected
+func fakeClamd(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	command, err := r.ReadString(0)
+	if err != nil || command != "zINSTREAM\x00" {
+		_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+	var data []byte
+	for {
+		var size uint32
+		if binary.Read(r, binary.BigEndian, &size) != nil {
+			return
+		}
+		if size == 0 {
+			break
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return
+		}
+		data = append(data, chunk...)
+	}
+	if strings.Contains(string(data), infected) {
+		_, _ = conn.Write([]byte("stream: Rclone-Test-Signature FOUND\x00"))
+	} else {
+		_, _ = conn.Write([]byte("stream: OK\x00"))
+	}
+}
+
+// fakeICAP reads a RESPMOD and flags bodies containing infected
+func fakeICAP(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	tp := textproto.NewReader(r)
+	request, err := tp.ReadLine()
+	if err != nil || !strings.HasPrefix(request, "RESPMOD icap://") {
+		_, _ = conn.Write([]byte("ICAP/1.0 400 Bad Request\r\n\r\n"))
+		return
+	}
+	if _, err = tp.ReadMIMEHeader(); err != nil {
+		return
+	}
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		return
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return
+	}
+	if strings.Contains(string(data), infected) {
+		_, _ = conn.Write([]byte("ICAP/1.0 200 OK\r\nX-Infection-Found: Type=0; Resolution=2; Threat=Rclone-Test-Signature;\r\nEncapsulated: null-body=0\r\n\r\n"))
+	} else {
+		_, _ = conn.Write([]byte("ICAP/1.0 204 No Content\r\n\r\n"))
+	}
+}
+
+func testScanner(t *testing.T, s Scanner) {
+	ctx := context.Background()
+	threat, err := s.Scan(ctx, strings.NewReader("clean data"))
+	require.NoError(t, err)
+	assert.Equal(t, "", threat)
+
+	threat, err = s.Scan(ctx, strings.NewReader(infected))
+	require.NoError(t, err)
+	assert.Equal(t, "Rclone-Test-Signature", threat)
+
+	// bigger than a chunk
+	threat, err = s.Scan(ctx, strings.NewReader(strings.Repeat("x", 200*1024)+infected))
+	require.NoError(t, err)
+	assert.Equal(t, "Rclone-Test-Signature", threat)
+
+	threat, err = s.Scan(ctx, strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Equal(t, "", threat)
+}
+
+func TestClamd(t *testing.T) {
+	address, stop := serve(t, fakeClamd)
+	defer stop()
+	s := NewClamd(address, dialer.DialContext)
+	assert.Equal(t, "clamd "+address, s.String())
+	testScanner(t, s)
+}
+
+func TestNewClamd(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		network string
+		address string
+	}{
+		{"localhost", "tcp", "localhost:3310"},
+		{"localhost:1234", "tcp", "localhost:1234"},
+		{"/run/clamav/clamd.ctl", "unix", "/run/clamav/clamd.ctl"},
+		{"unix:clamd.ctl", "unix", "clamd.ctl"},
+	} {
+		c := NewClamd(test.in, dialer.DialContext).(*clamd)
+		assert.Equal(t, test.network, c.network, test.in)
+		assert.Equal(t, test.address, c.address, test.in)
+	}
+}
+
+func TestParseClamdReply(t *testing.T) {
+	for _, test := range []struct {
+		in     string
+		threat string
+		err    bool
+	}{
+		{"stream: OK", "", false},
+		{"stream: Win.Test.EICAR_HDB-1 FOUND", "Win.Test.EICAR_HDB-1", false},
+		{"INSTREAM size limit exceeded. ERROR", "", true},
+		{"", "", true},
+	} {
+		threat, err := parseClamdReply(test.in)
+		assert.Equal(t, test.threat, threat, test.in)
+		assert.Equal(t, test.err, err != nil, test.in)
+	}
+}
+
+func TestICAP(t *testing.T) {
+	address, stop := serve(t, fakeICAP)
+	defer stop()
+	s, err := NewICAP("icap://"+address+"/avscan", dialer.DialContext)
+	require.NoError(t, err)
+	assert.Equal(t, "ICAP icap://"+address+"/avscan", s.String())
+	testScanner(t, s)
+}
+
+func TestNewICAP(t *testing.T) {
+	s, err := NewICAP("icap://localhost/avscan", dialer.DialContext)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:1344", s.(*icap).address)
+
+	_, err = NewICAP("http://localhost/avscan", dialer.DialContext)
+	assert.Error(t, err)
+}
+
+func TestParseICAPReply(t *testing.T) {
+	for _, test := range []struct {
+		status string
+		header textproto.MIMEHeader
+		threat string
+		err    bool
+	}{
+		{"ICAP/1.0 204 No Content", nil, "", false},
+		{"ICAP/1.0 200 OK", textproto.MIMEHeader{"X-Infection-Found": {"Type=0; Resolution=2; Threat=Eicar;"}}, "Eicar", false},
+		{"ICAP/1.0 200 OK", textproto.MIMEHeader{"X-Virus-Id": {"Eicar"}}, "Eicar", false},
+		{"ICAP/1.0 200 OK", nil, "unknown threat", false},
+		{"ICAP/1.0 500 Server Error", nil, "", true},
+		{"HTTP/1.1 200 OK", nil, "", true},
+	} {
+		threat, err := parseICAPReply(test.status, test.header)
+		assert.Equal(t, test.threat, threat, test.status)
+		assert.Equal(t, test.err, err != nil, test.status)
+	}
+}
@@ -34,6 +34,7 @@ type Dir struct {
 	read    time.Time         // time directory entry last read
 	items   map[string]Node   // directory entries - can be empty but not nil
 	virtual map[string]vState // virtual directory entries - may be nil
+	paging  manageVirtuals    // names listed so far while reading in pages - nil otherwise
 	sys     atomic.Value      // user defined info to be attached here
 
 	modTimeMu sync.Mutex // protects the following
@@ -242,6 +243,7 @@ func (d *Dir) changeNotify(relativePath string, entryType fs.EntryType) {
 	if entryType == fs.EntryDirectory {
 		d.invalidateDir(absPath)
 	}
+	d.vfs.notifyChange(absPath)
 }
 
 // ForgetPath clears the cache for itself and all subdirectories if
@@ -451,7 +453,7 @@ func (d *Dir) _readDir() error {
 	} else {
 		return nil
 	}
-	entries, err := list.DirSorted(context.TODO(), d.f, false, d.path)
+	entries, err := list.DirSorted(d.vfs.ctx, d.f, false, d.path)
 	if err == fs.ErrorDirNotFound {
 		// We treat directory not found as empty because we
 		// create directories on the fly
@@ -611,54 +613,64 @@ func (mv manageVirtuals) end(d *Dir) {
 // update d.items and if dirTree is not nil update each dir in the DirTree below this one and
 // set the last read time - must be called with the lock held
 func (d *Dir) _readDirFromEntries(entries fs.DirEntries, dirTree dirtree.DirTree, when time.Time) error {
-	var err error
 	mv := d._newManageVirtuals()
 	for _, entry := range entries {
-		name := path.Base(entry.Remote())
-		if name == "." || name == ".." {
-			continue
+		_, err := d._addEntry(mv, entry, dirTree, when)
+		if err != nil {
+			return err
 		}
-		node := d.items[name]
-		if mv.add(d, name) {
-			continue
+	}
+	mv.end(d)
+	return nil
+}
+
+// update d.items with a single entry from a listing returning the
+// node, or nil if the entry was skipped - must be called with the
+// lock held
+func (d *Dir) _addEntry(mv manageVirtuals, entry fs.DirEntry, dirTree dirtree.DirTree, when time.Time) (node Node, err error) {
+	name := d.vfs.entryName(entry)
+	if name == "." || name == ".." {
+		return nil, nil
+	}
+	node = d.items[name]
+	if mv.add(d, name) {
+		return nil, nil
+	}
+	switch item := entry.(type) {
+	case fs.Object:
+		obj := item
+		// Reuse old file value if it exists
+		if file, ok := node.(*File); node != nil && ok {
+			file.setObjectNoUpdate(obj)
+		} else {
+			node = newFile(d, d.path, obj, path.Base(obj.Remote()))
 		}
-		switch item := entry.(type) {
-		case fs.Object:
-			obj := item
-			// Reuse old file value if it exists
-			if file, ok := node.(*File); node != nil && ok {
-				file.setObjectNoUpdate(obj)
+	case fs.Directory:
+		// Reuse old dir value if it exists
+		if node == nil || !node.IsDir() {
+			node = newDir(d.vfs, d.f, d, item)
+		}
+		if dirTree != nil {
+			dir := node.(*Dir)
+			dir.mu.Lock()
+			err = dir._readDirFromDirTree(dirTree, when)
+			if err != nil {
+				dir.read = time.Time{}
 			} else {
-				node = newFile(d, d.path, obj, name)
+				dir.read = when
 			}
-		case fs.Directory:
-			// Reuse old dir value if it exists
-			if node == nil || !node.IsDir() {
-				node = newDir(d.vfs, d.f, d, item)
-			}
-			if dirTree != nil {
-				dir := node.(*Dir)
-				dir.mu.Lock()
-				err = dir._readDirFromDirTree(dirTree, when)
-				if err != nil {
-					dir.read = time.Time{}
-				} else {
-					dir.read = when
-				}
-				dir.mu.Unlock()
-				if err != nil {
-					return err
-				}
+			dir.mu.Unlock()
+			if err != nil {
+				return nil, err
 			}
-		default:
-			err = errors.Errorf("unknown type %T", item)
-			fs.Errorf(d, "readDir error: %v", err)
-			return err
 		}
-		d.items[name] = node
+	default:
+		err = errors.Errorf("unknown type %T", item)
+		fs.Errorf(d, "readDir error: %v", err)
+		return nil, err
 	}
-	mv.end(d)
-	return nil
+	d.items[name] = node
+	return node, nil
 }
 
 // readDirTree forces a refresh of the complete directory tree
@@ -668,7 +680,7 @@ func (d *Dir) readDirTree() error {
 	d.mu.RUnlock()
 	when := time.Now()
 	fs.Debugf(path, "Reading directory tree")
-	dt, err := walk.NewDirTree(context.TODO(), f, path, false, -1)
+	dt, err := walk.NewDirTree(d.vfs.ctx, f, path, false, -1)
 	if err != nil {
 		return err
 	}
@@ -700,6 +712,14 @@ func (d *Dir) readDir() error {
 func (d *Dir) stat(leaf string) (Node, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	// While the directory is being read in pages, entries which
+	// have been listed already can be found without reading it
+	// all again
+	if _, listed := d.paging[leaf]; listed {
+		if item, ok := d.items[leaf]; ok {
+			return item, nil
+		}
+	}
 	err := d._readDir()
 	if err != nil {
 		return nil, err
@@ -827,6 +847,88 @@ func (d *Dir) ReadDirAll() (items Nodes, err error) {
 	return items, nil
 }
 
+// ReadDirPaged reads the directory calling fn with each page of
+// nodes as it is listed.
+//
+// If the directory is cached, --vfs-lazy-listing isn't set or the
+// remote can't list in pages then fn is called once with all the
+// nodes, as returned by ReadDirAll.
+//
+// The lock isn't held while fn is called. If fn returns an error the
+// listing stops and the error is returned.
+func (d *Dir) ReadDirPaged(fn func(items Nodes) error) error {
+	d.mu.Lock()
+	_, stale := d._age(time.Now())
+	paged := stale && d.paging == nil && d.vfs.Opt.LazyListing && d.f.Features().ListP != nil
+	if paged {
+		d.paging = d._newManageVirtuals()
+	}
+	d.mu.Unlock()
+	if !paged {
+		items, err := d.ReadDirAll()
+		if err != nil {
+			return err
+		}
+		return fn(items)
+	}
+	return d.readDirPaged(fn)
+}
+
+// readDirPaged does the work for ReadDirPaged with d.paging set up
+func (d *Dir) readDirPaged(fn func(items Nodes) error) (err error) {
+	when := time.Now()
+	d.mu.RLock()
+	f, dirPath, mv := d.f, d.path, d.paging
+	d.mu.RUnlock()
+	defer func() {
+		d.mu.Lock()
+		d.paging = nil
+		d.mu.Unlock()
+	}()
+	fs.Debugf(dirPath, "Reading directory in pages")
+	err = list.DirPaged(d.vfs.ctx, f, false, dirPath, func(entries fs.DirEntries) error {
+		var items Nodes
+		d.mu.Lock()
+		for _, entry := range entries {
+			node, err := d._addEntry(mv, entry, nil, time.Time{})
+			if err != nil {
+				d.mu.Unlock()
+				return err
+			}
+			if node != nil {
+				items = append(items, node)
+			}
+		}
+		d.mu.Unlock()
+		return fn(items)
+	})
+	if err == fs.ErrorDirNotFound {
+		// We treat directory not found as empty because we
+		// create directories on the fly
+	} else if err != nil {
+		return err
+	}
+
+	// Pass on the entries which weren't listed, eg files being
+	// uploaded
+	var items Nodes
+	d.mu.Lock()
+	mv.end(d)
+	for name, node := range d.items {
+		if _, listed := mv[name]; !listed {
+			items = append(items, node)
+		}
+	}
+	d.read = when
+	d.mu.Unlock()
+	fs.Debugf(dirPath, "Reading directory in pages done in %s", time.Since(when))
+	if len(items) == 0 {
+		return nil
+	}
+	sort.Sort(items)
+	return fn(items)
+}
+
 // accessModeMask masks off the read modes from the flags
 const accessModeMask = (os.O_RDONLY | os.O_WRONLY | os.O_RDWR)
 
@@ -11,6 +11,7 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -252,6 +253,82 @@ func checkListing(t *testing.T, dir *Dir, want []string) {
 	assert.Equal(t, want, got)
 }
 
+func TestDirReadDirPaged(t *testing.T) {
+	opt := vfscommon.DefaultOpt
+	opt.LazyListing = true
+	r, vfs, cleanup := newTestVFSOpt(t, &opt)
+	defer cleanup()
+	if r.Fremote.Features().ListP == nil {
+		t.Skip("remote can't list in pages")
+	}
+
+	file1 := r.WriteObject(context.Background(), "dir/file1", "file1 contents", t1)
+	file2 := r.WriteObject(context.Background(), "dir/file2", "file2- contents", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	node, err := vfs.Stat("dir")
+	require.NoError(t, err)
+	dir := node.(*Dir)
+	checkListing(t, dir, []string{"file1,14,false", "file2,15,false"})
+	dir.AddVirtual("virtualFile", 17, false)
+
+	readPages := func() (pages [][]string) {
+		err := dir.ReadDirPaged(func(items Nodes) error {
+			var page []string
+			for _, item := range items {
+				page = append(page, item.Name())
+			}
+			pages = append(pages, page)
+			return nil
+		})
+		require.NoError(t, err)
+		return pages
+	}
+
+	// Cached so read in one go
+	assert.Equal(t, [][]string{{"file1", "file2", "virtualFile"}}, readPages())
+
+	// Expire the cache and read in pages - the virtual entry comes last
+	dir.mu.Lock()
+	dir.read = time.Time{}
+	dir.mu.Unlock()
+	assert.Equal(t, [][]string{{"file1", "file2"}, {"virtualFile"}}, readPages())
+	dir.mu.RLock()
+	assert.False(t, dir.read.IsZero())
+	assert.Nil(t, dir.paging)
+	dir.mu.RUnlock()
+
+	// Entries already listed can be found while paging without
+	// reading the directory again
+	dir.mu.Lock()
+	dir.read = time.Time{}
+	dir.mu.Unlock()
+	err = dir.ReadDirPaged(func(items Nodes) error {
+		if items[0].Name() != "file1" {
+			return nil
+		}
+		node, err := dir.Stat("file1")
+		require.NoError(t, err)
+		assert.Equal(t, "file1", node.Name())
+		dir.mu.RLock()
+		assert.True(t, dir.read.IsZero())
+		dir.mu.RUnlock()
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Errors from the callback are returned
+	dir.mu.Lock()
+	dir.read = time.Time{}
+	dir.mu.Unlock()
+	assert.Equal(t, EPERM, dir.ReadDirPaged(func(items Nodes) error {
+		return EPERM
+	}))
+	dir.mu.RLock()
+	assert.True(t, dir.read.IsZero())
+	dir.mu.RUnlock()
+}
+
 func TestDirReadDirAll(t *testing.T) {
 	r, vfs, cleanup := newTestVFS(t)
 	defer cleanup()
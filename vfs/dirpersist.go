@@ -0,0 +1,395 @@
+package vfs
+
+// This file persists the directory cache to disk so it can be used
+// to serve listings straight away when the VFS is next created.
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/atexit"
+	"github.com/rclone/rclone/lib/file"
+)
+
+// dirCacheVersion is the version of the persisted directory cache
+// format - bump it on incompatible changes
+const dirCacheVersion = 1
+
+// dirCacheHeader is the first record in the persisted directory cache
+type dirCacheHeader struct {
+	Version int       // version of the format
+	Fs      string    // fs.ConfigString of the Fs the cache is for
+	Saved   time.Time // when the cache was saved
+}
+
+// dirCacheDir is the record for each directory which had been read
+//
+// Directories are written parents first.
+type dirCacheDir struct {
+	Path    string
+	Entries []dirCacheEntry
+}
+
+// dirCacheEntry is a file or directory in a dirCacheDir
+type dirCacheEntry struct {
+	Name    string
+	IsDir   bool `json:",omitempty"`
+	Size    int64
+	ModTime time.Time
+}
+
+// dirCachePath returns the file the directory cache for f is stored in
+func dirCachePath(f fs.Fs) string {
+	sum := md5.Sum([]byte(fs.ConfigString(f)))
+	return file.UNCPath(filepath.Join(config.CacheDir, "vfsDir", hex.EncodeToString(sum[:])+".json.gz"))
+}
+
+// startDirCache loads the persisted directory cache, refreshes it in
+// the background and arranges for it to be saved on exit
+func (vfs *VFS) startDirCache() {
+	vfs.dirCacheRefreshed = make(chan struct{})
+	dirs, err := vfs.loadDirCache()
+	if err != nil {
+		fs.Errorf(vfs.f, "Discarding directory cache: %v", err)
+		vfs.root.ForgetAll()
+		dirs = 0
+	}
+	if dirs > 0 {
+		go vfs.refreshDirCache()
+	} else {
+		close(vfs.dirCacheRefreshed)
+	}
+	vfs.dirCacheAtExit = atexit.Register(func() {
+		err := vfs.saveDirCache()
+		if err != nil {
+			fs.Errorf(vfs.f, "Failed to save directory cache: %v", err)
+		}
+	})
+}
+
+// stopDirCache saves the directory cache and cancels the save on exit
+func (vfs *VFS) stopDirCache() {
+	atexit.Unregister(vfs.dirCacheAtExit)
+	err := vfs.saveDirCache()
+	if err != nil {
+		fs.Errorf(vfs.f, "Failed to save directory cache: %v", err)
+	}
+}
+
+// saveDirCache writes the directories which have been read to disk
+func (vfs *VFS) saveDirCache() (err error) {
+	vfs.dirCacheMu.Lock()
+	defer vfs.dirCacheMu.Unlock()
+	start := time.Now()
+	cachePath := dirCachePath(vfs.f)
+	err = os.MkdirAll(filepath.Dir(cachePath), 0700)
+	if err != nil {
+		return errors.Wrap(err, "failed to make directory cache directory")
+	}
+	tmpPath := cachePath + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to create directory cache")
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+	gz := gzip.NewWriter(out)
+	enc := json.NewEncoder(gz)
+	err = enc.Encode(dirCacheHeader{
+		Version: dirCacheVersion,
+		Fs:      fs.ConfigString(vfs.f),
+		Saved:   start,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to write directory cache")
+	}
+	dirs := 0
+	err = vfs.root.saveDirCache(enc, &dirs)
+	if err != nil {
+		return errors.Wrap(err, "failed to write directory cache")
+	}
+	err = gz.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to write directory cache")
+	}
+	err = out.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to close directory cache")
+	}
+	err = os.Rename(tmpPath, cachePath)
+	if err != nil {
+		return errors.Wrap(err, "failed to rename directory cache")
+	}
+	fs.Debugf(vfs.f, "Saved directory cache of %d directories in %v", dirs, time.Since(start))
+	return nil
+}
+
+// saveDirCache writes this directory and the directories below it
+// to enc if they have been read
+func (d *Dir) saveDirCache(enc *json.Encoder, dirs *int) error {
+	d.mu.RLock()
+	if d.read.IsZero() {
+		d.mu.RUnlock()
+		return nil
+	}
+	record := dirCacheDir{
+		Path:    d.path,
+		Entries: make([]dirCacheEntry, 0, len(d.items)),
+	}
+	var subdirs []*Dir
+	for name, node := range d.items {
+		// Don't save things which aren't on the remote yet
+		switch d.virtual[name] {
+		case vAddFile, vAddDir:
+			continue
+		}
+		switch x := node.(type) {
+		case *Dir:
+			record.Entries = append(record.Entries, dirCacheEntry{
+				Name:    name,
+				IsDir:   true,
+				ModTime: x.ModTime(),
+			})
+			subdirs = append(subdirs, x)
+		case *File:
+			// Save what is on the remote rather than any dirty
+			// item in the cache
+			o := x.getObject()
+			if o == nil {
+				continue
+			}
+			entry := dirCacheEntry{
+				Name: name,
+				Size: o.Size(),
+			}
+			if !d.vfs.Opt.NoModTime {
+				entry.ModTime = o.ModTime(context.TODO())
+			}
+			record.Entries = append(record.Entries, entry)
+		}
+	}
+	d.mu.RUnlock()
+	err := enc.Encode(&record)
+	if err != nil {
+		return err
+	}
+	*dirs++
+	for _, subdir := range subdirs {
+		err = subdir.saveDirCache(enc, dirs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadDirCache reads the persisted directory cache into the
+// directory tree, returning the number of directories loaded.
+//
+// The directories loaded are marked as freshly read so they are
+// served from the cache until the next refresh.
+func (vfs *VFS) loadDirCache() (dirs int, err error) {
+	in, err := os.Open(dirCachePath(vfs.f))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, errors.Wrap(err, "failed to open directory cache")
+	}
+	defer fs.CheckClose(in, &err)
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read directory cache")
+	}
+	dec := json.NewDecoder(gz)
+	var header dirCacheHeader
+	err = dec.Decode(&header)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read directory cache header")
+	}
+	if header.Version != dirCacheVersion || header.Fs != fs.ConfigString(vfs.f) {
+		fs.Debugf(vfs.f, "Ignoring directory cache for %q version %d", header.Fs, header.Version)
+		return 0, nil
+	}
+	when := time.Now()
+	found := map[string]*Dir{"": vfs.root}
+	for {
+		var record dirCacheDir
+		err = dec.Decode(&record)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return dirs, errors.Wrap(err, "failed to read directory cache")
+		}
+		d := found[record.Path]
+		if d == nil {
+			fs.Debugf(record.Path, "Ignoring cached directory with no parent")
+			continue
+		}
+		d.mu.Lock()
+		for _, entry := range record.Entries {
+			remote := path.Join(d.path, entry.Name)
+			if entry.IsDir {
+				dir := newDir(vfs, vfs.f, d, fs.NewDir(remote, entry.ModTime))
+				d.items[entry.Name] = dir
+				found[remote] = dir
+			} else {
+				o := &persistedObject{
+					f:       vfs.f,
+					remote:  remote,
+					size:    entry.Size,
+					modTime: entry.ModTime,
+				}
+				d.items[entry.Name] = newFile(d, d.path, o, entry.Name)
+			}
+		}
+		d.read = when
+		d.mu.Unlock()
+		dirs++
+	}
+	fs.Infof(vfs.f, "Loaded directory cache of %d directories saved at %v", dirs, header.Saved.Format(time.RFC3339))
+	return dirs, nil
+}
+
+// refreshDirCache re-reads the directory tree from the remote and
+// saves it, closing vfs.dirCacheRefreshed when done.
+//
+// This is run in the background after the directory cache is loaded.
+func (vfs *VFS) refreshDirCache() {
+	defer close(vfs.dirCacheRefreshed)
+	fs.Debugf(vfs.f, "Refreshing directory cache in the background")
+	err := vfs.root.readDirTree()
+	if err != nil {
+		fs.Errorf(vfs.f, "Failed to refresh directory cache: %v", err)
+		return
+	}
+	err = vfs.saveDirCache()
+	if err != nil {
+		fs.Errorf(vfs.f, "Failed to save directory cache: %v", err)
+	}
+}
+
+// persistedObject is an fs.Object loaded from the directory cache.
+//
+// It has the size and modification time from the cache and finds
+// the real object on the remote when anything else is needed.  It
+// is replaced with the real object when the directory is re-read.
+type persistedObject struct {
+	f       fs.Fs
+	remote  string
+	size    int64
+	modTime time.Time
+
+	mu sync.Mutex
+	o  fs.Object // the real object once found
+}
+
+// check interfaces
+var _ fs.Object = (*persistedObject)(nil)
+
+// object finds the real object on the remote
+func (o *persistedObject) object(ctx context.Context) (fs.Object, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.o != nil {
+		return o.o, nil
+	}
+	obj, err := o.f.NewObject(ctx, o.remote)
+	if err != nil {
+		return nil, err
+	}
+	o.o = obj
+	return obj, nil
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *persistedObject) Fs() fs.Info {
+	return o.f
+}
+
+// String returns the remote path
+func (o *persistedObject) String() string {
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *persistedObject) Remote() string {
+	return o.remote
+}
+
+// ModTime returns the cached modification time
+func (o *persistedObject) ModTime(ctx context.Context) time.Time {
+	return o.modTime
+}
+
+// Size returns the cached size
+func (o *persistedObject) Size() int64 {
+	return o.size
+}
+
+// Storable says whether this object can be stored
+func (o *persistedObject) Storable() bool {
+	return true
+}
+
+// Hash returns the hash of the real object
+func (o *persistedObject) Hash(ctx context.Context, ty hash.Type) (string, error) {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return "", err
+	}
+	return obj.Hash(ctx, ty)
+}
+
+// SetModTime sets the modification time of the real object
+func (o *persistedObject) SetModTime(ctx context.Context, t time.Time) error {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return err
+	}
+	return obj.SetModTime(ctx, t)
+}
+
+// Open opens the real object for read
+func (o *persistedObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Open(ctx, options...)
+}
+
+// Update updates the real object with the contents of in
+func (o *persistedObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return err
+	}
+	return obj.Update(ctx, in, src, options...)
+}
+
+// Remove removes the real object
+func (o *persistedObject) Remove(ctx context.Context) error {
+	obj, err := o.object(ctx)
+	if err != nil {
+		return err
+	}
+	return obj.Remove(ctx)
+}
@@ -0,0 +1,95 @@
+package vfs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirCachePersist(t *testing.T) {
+	ctx := context.Background()
+	cacheDir, err := ioutil.TempDir("", "rclone-vfs-dir-cache")
+	require.NoError(t, err)
+	oldCacheDir := config.CacheDir
+	config.CacheDir = cacheDir
+	defer func() {
+		config.CacheDir = oldCacheDir
+		require.NoError(t, os.RemoveAll(cacheDir))
+	}()
+
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject(ctx, "dir/file1", "file1 contents", t1)
+	file2 := r.WriteObject(ctx, "file2", "file2", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	opt := vfscommon.DefaultOpt
+	opt.DirCachePersist = true
+
+	// Read the tree and save it on shutdown
+	vfs := New(r.Fremote, &opt)
+	_, err = vfs.ReadDir("dir")
+	require.NoError(t, err)
+	<-vfs.dirCacheRefreshed
+	vfs.Shutdown()
+	_, err = os.Stat(dirCachePath(r.Fremote))
+	require.NoError(t, err)
+
+	// Change the remote behind the cache's back
+	file3 := r.WriteObject(ctx, "dir/file3", "file3", t3)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	// Load the saved tree into a VFS without refreshing it
+	vfs = New(r.Fremote, nil)
+	dirs, err := vfs.loadDirCache()
+	require.NoError(t, err)
+	assert.Equal(t, 2, dirs)
+	dir := vfs.root.cachedDir("dir")
+	require.NotNil(t, dir)
+	dir.mu.RLock()
+	assert.False(t, dir.read.IsZero())
+	assert.Equal(t, 1, len(dir.items))
+	node := dir.items["file1"]
+	dir.mu.RUnlock()
+	require.NotNil(t, node)
+	f := node.(*File)
+	o, ok := f.getObject().(*persistedObject)
+	require.True(t, ok)
+	assert.Equal(t, "dir/file1", o.Remote())
+	assert.Equal(t, int64(len("file1 contents")), o.Size())
+	fstest.AssertTimeEqualWithPrecision(t, o.Remote(), t1, o.ModTime(ctx), r.Fremote.Precision())
+
+	// Reading the file finds the real object
+	fd, err := vfs.OpenFile("dir/file1", os.O_RDONLY, 0)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(fd)
+	require.NoError(t, err)
+	require.NoError(t, fd.Close())
+	assert.Equal(t, "file1 contents", string(data))
+	cleanupVFS(t, vfs)
+
+	// A VFS with persistence refreshes the tree in the background
+	vfs = New(r.Fremote, &opt)
+	defer cleanupVFS(t, vfs)
+	select {
+	case <-vfs.dirCacheRefreshed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for directory cache refresh")
+	}
+	node, err = vfs.Stat("dir/file3")
+	require.NoError(t, err)
+	_, ok = node.(*File).getObject().(*persistedObject)
+	assert.False(t, ok)
+	node, err = vfs.Stat("dir/file1")
+	require.NoError(t, err)
+	_, ok = node.(*File).getObject().(*persistedObject)
+	assert.False(t, ok)
+}
@@ -46,6 +46,8 @@ type File struct {
 	writers          []Handle                        // writers for this file
 	nwriters         int32                           // len(writers) which is read/updated with atomic
 	pendingModTime   time.Time                       // will be applied once o becomes available, i.e. after file was written
+	pendingMetadata  fs.Metadata                     // will be applied once o becomes available, like pendingModTime
+	meta             fs.Metadata                     // cached metadata of o if read
 	pendingRenameFun func(ctx context.Context) error // will be run/renamed after all writers close
 	appendMode       bool                            // file was opened with O_APPEND
 	sys              atomic.Value                    // user defined info to be attached here
@@ -90,12 +92,20 @@ func (f *File) IsDir() bool {
 
 // Mode bits of the file or directory - satisfies Node interface
 func (f *File) Mode() (mode os.FileMode) {
+	mode = f.VFS().Opt.FilePerms
+	if f.VFS().Opt.Metadata {
+		if perm, ok := f.metadata().Mode(); ok {
+			mode = perm
+		}
+	}
 	f.mu.RLock()
 	defer f.mu.RUnlock()
-	mode = f.d.vfs.Opt.FilePerms
 	if f.appendMode {
 		mode |= os.ModeAppend
 	}
+	if f.d.vfs.isLink(f.leaf) {
+		mode |= os.ModeSymlink
+	}
 	return mode
 }
 
@@ -103,7 +113,7 @@ func (f *File) Mode() (mode os.FileMode) {
 func (f *File) Name() (name string) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
-	return f.leaf
+	return f._name()
 }
 
 // _path returns the full path of the file
@@ -172,6 +182,9 @@ func (f *File) rename(ctx context.Context, destDir *Dir, newName string) error {
 	}
 
 	oldPath := f.Path()
+	if f.IsSymlink() {
+		newName += vfscommon.LinkSuffix
+	}
 	// File.mu is unlocked here to call Dir.Path()
 	newPath := path.Join(destDir.Path(), newName)
 
@@ -453,7 +466,9 @@ func (f *File) setSize(n int64) {
 func (f *File) setObject(o fs.Object) {
 	f.mu.Lock()
 	f.o = o
+	f.meta = nil
 	_ = f._applyPendingModTime()
+	_ = f._applyPendingMetadata()
 	d := f.d
 	f.mu.Unlock()
 
@@ -466,6 +481,7 @@ func (f *File) setObject(o fs.Object) {
 func (f *File) setObjectNoUpdate(o fs.Object) {
 	f.mu.Lock()
 	f.o = o
+	f.meta = nil
 	f.mu.Unlock()
 }
 
@@ -762,7 +778,7 @@ func (f *File) Truncate(size int64) (err error) {
 	}
 
 	// If no writers, and size is already correct then all done
-	if o.Size() == size {
+	if o != nil && o.Size() == size {
 		return nil
 	}
 
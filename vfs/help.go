@@ -39,6 +39,19 @@ the directory cache expires if the backend configured does not support
 polling for changes. If the backend supports polling, changes will be
 picked up within the polling interval.
 
+If the backend doesn't support polling, the !--vfs-poll-listings! flag
+makes rclone poll for changes itself by re-reading every directory in
+the cache each !--poll-interval!. This costs a listing per cached
+directory per interval so is best used with a longer interval on large
+trees.
+
+    --vfs-poll-listings   Poll for changes by re-reading cached directories if the remote can't notify changes.
+
+When a change is noticed by either method, !rclone mount! and
+!rclone mount2! also tell the kernel to drop its cached copy of the
+entry and its data, so changed files appear without waiting for
+!--attr-timeout! to expire.
+
 You can send a !SIGHUP! signal to rclone for it to flush all
 directory caches, regardless of how old they are.  Assuming only one
 rclone instance is running, you can reset the cache like this:
@@ -54,6 +67,42 @@ Or individual files or directories:
 
     rclone rc vfs/forget file=path/to/file dir=path/to/dir
 
+### VFS Lazy Listing
+
+Normally a directory is listed completely before any of its entries
+are returned, so opening a directory with hundreds of thousands of
+entries in a file manager can stall for minutes. With
+!--vfs-lazy-listing! rclone returns the entries page by page as the
+backend lists them, and files which have been returned already can be
+opened before the listing finishes.
+
+    --vfs-lazy-listing   Return directory entries page by page while large directories are being listed.
+
+This only has an effect on backends which can list in pages (currently
+!local!) and, for mounts, with !rclone mount2!. Other backends and
+mounts read the whole directory first as before.
+
+### VFS Directory Cache Persistence
+
+Reading the directory tree of a large remote can take a long time, and
+normally it has to be done afresh every time the VFS starts.
+
+If the !--vfs-dir-cache-persist! flag is set then the directories
+which have been read are saved to disk under the cache directory
+(!--cache-dir!) when rclone exits and after each refresh. Next time
+the same remote is served, the saved directory tree is loaded and used
+to answer listings straight away while the whole tree is re-read from
+the remote in the background. Once that is done the directory cache
+works as normal.
+
+    --vfs-dir-cache-persist   Save the directory cache to disk and reload it on start.
+
+Until the background refresh is finished, listings may be out of date
+by however long rclone was stopped, so don't use this if other
+programs change the remote and rclone must see those changes
+immediately. Only the names, sizes and modification times are saved;
+anything else needed, for example the hash, is read from the remote.
+
 ### VFS File Buffering
 
 The !--buffer-size! flag determines the amount of memory,
@@ -68,8 +117,16 @@ buffer will only use memory for data that is downloaded but not not
 yet read. If the buffer is empty, only a small amount of memory will
 be used.
 
+When a file is read sequentially, for example when streaming media,
+the amount rclone reads ahead starts off small and doubles each time
+that much has been read, up to !--buffer-size! plus !--vfs-read-ahead!.
+Seeking in the file shrinks it back down again. This means that
+streaming from high latency remotes is smooth without every open file
+needing a large buffer. With !--vfs-cache-mode full! the
+!--vfs-read-ahead! is buffered on disk instead, see below.
+
 The maximum memory used by rclone for buffering can be up to
-!--buffer-size * open files!.
+!(--buffer-size + --vfs-read-ahead) * open files!.
 
 ### VFS File Caching
 
@@ -130,6 +187,7 @@ This will mean some operations are not possible
   * Files open for read with O_TRUNC will be opened write only
   * Files open for write only will behave as if O_TRUNC was supplied
   * Open modes O_APPEND, O_TRUNC are ignored
+  * Files can't be truncated or preallocated except to 0 bytes
   * If an upload fails it can't be retried
 
 #### --vfs-cache-mode minimal
@@ -153,6 +211,11 @@ first.
 
 This mode should support all normal file system operations.
 
+Writes to files opened with O_APPEND always go to the end of the file,
+even if another handle has extended it. Files can be truncated to any
+size and, with !rclone mount2!, preallocated with
+!fallocate(2)! (the space is not reserved on the remote).
+
 If an upload fails it will be retried at exponentially increasing
 intervals up to 1 minute.
 
@@ -260,8 +323,55 @@ If the flag is not provided on the command line, then its default value depends
 on the operating system where rclone runs: "true" on Windows and macOS, "false"
 otherwise. If the flag is provided without a value, then it is "true".
 
+### VFS Metadata
+
+By default every file is shown with the permissions from !--file-perms!
+and the owner from !--uid! and !--gid!, and changing them with !chmod!
+or !chown! is silently ignored.
+
+If !--vfs-metadata! is set then rclone reads and writes the mode,
+owner and extended attributes of files on backends which can store
+them
+
+- local stores them all, with extended attributes on Linux and macOS only
+- sftp stores the mode and owner
+- s3 stores the mode and owner in the object's metadata
+
+so !chmod!, !chown! and !setfattr! work and are seen next time the
+file is read. Extended attributes can't be removed. On other backends
+the flag has no effect.
+
+The metadata is cached with the directory listing. On s3 reading it
+needs an extra HEAD request per file the first time it is used so
+listing large directories will be slower. Directories are always
+shown with !--dir-perms!.
+
+### VFS Symlinks
+
+Most remotes can't store symlinks. If !--vfs-links! is set then files
+whose names end in !.rclonelink! are shown as symlinks without the
+suffix, the contents of the file being the target of the link.
+Making a symlink creates a file like this, so symlinks can be stored
+on any remote, eg
+
+    $ ln -s ../target /mnt/remote/link
+    $ rclone cat remote:link.rclonelink
+    ../target
+
+This is the same format the local backend uses with !--links! so
+files copied with !rclone copy --links! from a local disk show as
+symlinks in the mount, and symlinks made in the mount show as
+symlinks when copied back. The targets are not checked or followed by
+rclone - this is done by the operating system.
+
 ### Alternate report of used bytes
 
+The sizes reported by !df! on the filesystem come from the backend's
+about call (see !rclone about!), re-read every !--dir-cache-time!.
+Values the backend doesn't report are worked out from the others if
+possible. If the backend reports nothing the free space is shown as
+1 PiB. If reading them fails the last values read are used.
+
 Some backends, most notably S3, do not report the amount of bytes used.
 If you need this information to be available when running !df! on the
 filesystem, then pass the flag !--vfs-used-is-size! to rclone.
@@ -0,0 +1,184 @@
+package vfs
+
+// This file exposes the POSIX mode, owner and extended attributes of
+// files on backends which can store them.
+
+import (
+	"context"
+	"os"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// metadata returns the cached metadata of the file reading it from
+// the object if necessary.
+//
+// It returns nil if the object isn't available or doesn't support
+// metadata. Call without the lock held.
+func (f *File) metadata() fs.Metadata {
+	f.mu.RLock()
+	o, meta := f.o, f.meta
+	f.mu.RUnlock()
+	if meta != nil || o == nil {
+		return meta
+	}
+	meta, err := fs.GetMetadata(context.TODO(), o)
+	if err != nil {
+		fs.Debugf(o, "Failed to read metadata: %v", err)
+		return nil
+	}
+	if meta == nil {
+		// don't ask again
+		meta = fs.Metadata{}
+	}
+	f.mu.Lock()
+	if f.o == o {
+		f.meta = meta
+	}
+	f.mu.Unlock()
+	return meta
+}
+
+// Metadata returns the mode, owner and extended attributes stored
+// on the remote for the file.
+//
+// It returns an empty Metadata if the remote doesn't support
+// metadata or the file is still being written.
+func (f *File) Metadata() fs.Metadata {
+	meta := fs.Metadata{}
+	for key, value := range f.metadata() {
+		meta[key] = value
+	}
+	return meta
+}
+
+// SetMetadata stores the keys in m on the remote leaving any other
+// keys unchanged.
+//
+// If the file is being written the metadata is applied when the
+// upload has finished.
+func (f *File) SetMetadata(m fs.Metadata) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.d.vfs.Opt.ReadOnly {
+		return EROFS
+	}
+	if f.o != nil {
+		if _, ok := f.o.(fs.SetMetadataer); !ok {
+			return ENOSYS
+		}
+	}
+	if f.pendingMetadata == nil {
+		f.pendingMetadata = fs.Metadata{}
+	}
+	for key, value := range m {
+		f.pendingMetadata[key] = value
+	}
+
+	// Only update the metadata when there are no writers, setObject will do it
+	if !f._writingInProgress() {
+		return f._applyPendingMetadata()
+	}
+
+	// queue up for later, hoping f.o becomes available
+	return nil
+}
+
+// Apply pending metadata
+// Call with the mutex held
+func (f *File) _applyPendingMetadata() error {
+	if len(f.pendingMetadata) == 0 {
+		return nil
+	}
+	defer func() { f.pendingMetadata = nil }()
+	do, ok := f.o.(fs.SetMetadataer)
+	if !ok {
+		fs.Debugf(f._path(), "Can't apply pending metadata as the remote doesn't support it")
+		return ENOSYS
+	}
+	err := do.SetMetadata(context.TODO(), f.pendingMetadata)
+	f.meta = nil
+	if err != nil {
+		fs.Errorf(f.o, "Failed to apply metadata: %v", err)
+		return err
+	}
+	fs.Debugf(f.o, "Applied metadata %v OK", f.pendingMetadata)
+	return nil
+}
+
+// Chmod changes the permission bits of the file
+//
+// It returns ENOSYS unless --vfs-metadata is in use and the remote
+// supports metadata.
+func (f *File) Chmod(mode os.FileMode) error {
+	if !f.VFS().Opt.Metadata {
+		return ENOSYS
+	}
+	m := fs.Metadata{}
+	m.SetMode(mode)
+	return f.SetMetadata(m)
+}
+
+// Chown changes the numeric uid and gid of the file. A value of -1
+// leaves that id unchanged.
+//
+// It returns ENOSYS unless --vfs-metadata is in use and the remote
+// supports metadata.
+func (f *File) Chown(uid, gid int) error {
+	if !f.VFS().Opt.Metadata {
+		return ENOSYS
+	}
+	m := fs.Metadata{}
+	if uid >= 0 {
+		m.SetID(fs.MetadataUID, uint32(uid))
+	}
+	if gid >= 0 {
+		m.SetID(fs.MetadataGID, uint32(gid))
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return f.SetMetadata(m)
+}
+
+// Owner returns the numeric uid and gid of the file.
+//
+// These are read from the remote if --vfs-metadata is in use and it
+// has them, otherwise they are the --uid and --gid of the VFS.
+func (f *File) Owner() (uid, gid uint32) {
+	opt := &f.VFS().Opt
+	uid, gid = opt.UID, opt.GID
+	if !opt.Metadata {
+		return uid, gid
+	}
+	meta := f.metadata()
+	if id, ok := meta.ID(fs.MetadataUID); ok {
+		uid = id
+	}
+	if id, ok := meta.ID(fs.MetadataGID); ok {
+		gid = id
+	}
+	return uid, gid
+}
+
+// Xattrs returns the extended attributes of the file keyed on name
+//
+// It returns an empty map unless --vfs-metadata is in use and the
+// remote supports extended attributes.
+func (f *File) Xattrs() map[string]string {
+	if !f.VFS().Opt.Metadata {
+		return map[string]string{}
+	}
+	return f.metadata().Xattrs()
+}
+
+// SetXattr sets the extended attribute name to value
+//
+// It returns ENOSYS unless --vfs-metadata is in use and the remote
+// supports metadata.
+func (f *File) SetXattr(name, value string) error {
+	if !f.VFS().Opt.Metadata {
+		return ENOSYS
+	}
+	return f.SetMetadata(fs.Metadata{fs.MetadataXattrPrefix + name: value})
+}
@@ -0,0 +1,91 @@
+package vfs
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMetadataDisabled(t *testing.T) {
+	r, vfs, cleanup := newTestVFS(t)
+	defer cleanup()
+	file1 := r.WriteObject(context.Background(), "file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+	node, err := vfs.Stat("file1")
+	require.NoError(t, err)
+	file := node.(*File)
+
+	assert.Equal(t, ENOSYS, file.Chmod(0600))
+	assert.Equal(t, ENOSYS, file.Chown(0, 0))
+	assert.Equal(t, ENOSYS, file.SetXattr("user.potato", "yes"))
+	assert.Equal(t, vfs.Opt.FilePerms, file.Mode())
+	uid, gid := file.Owner()
+	assert.Equal(t, vfs.Opt.UID, uid)
+	assert.Equal(t, vfs.Opt.GID, gid)
+	assert.Equal(t, map[string]string{}, file.Xattrs())
+}
+
+func TestFileMetadata(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no POSIX permissions on Windows")
+	}
+	ctx := context.Background()
+	opt := vfscommon.DefaultOpt
+	opt.Metadata = true
+	opt.CacheMode = vfscommon.CacheModeWrites
+	r, vfs, cleanup := newTestVFSOpt(t, &opt)
+	defer cleanup()
+	file1 := r.WriteObject(ctx, "file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+	o, err := r.Fremote.NewObject(ctx, "file1")
+	require.NoError(t, err)
+	if _, ok := o.(fs.SetMetadataer); !ok {
+		t.Skip("remote doesn't support metadata")
+	}
+	node, err := vfs.Stat("file1")
+	require.NoError(t, err)
+	file := node.(*File)
+
+	require.NoError(t, file.Chmod(0640))
+	assert.Equal(t, os.FileMode(0640), file.Mode())
+	assert.Equal(t, "640", file.Metadata()[fs.MetadataMode])
+
+	// Check it was stored on the remote
+	o, err = r.Fremote.NewObject(ctx, "file1")
+	require.NoError(t, err)
+	m, err := fs.GetMetadata(ctx, o)
+	require.NoError(t, err)
+	assert.Equal(t, "640", m[fs.MetadataMode])
+	wantUID, ok := m.ID(fs.MetadataUID)
+	require.True(t, ok)
+	uid, _ := file.Owner()
+	assert.Equal(t, wantUID, uid)
+
+	// Chown to the current owner leaves it unchanged
+	require.NoError(t, file.Chown(int(uid), -1))
+	uid2, _ := file.Owner()
+	assert.Equal(t, uid, uid2)
+
+	// Metadata set while writing is applied when the upload finishes
+	fd, err := vfs.OpenFile("file2", os.O_WRONLY|os.O_CREATE, 0777)
+	require.NoError(t, err)
+	_, err = fd.Write([]byte("file2 contents"))
+	require.NoError(t, err)
+	file2 := fd.Node().(*File)
+	require.NoError(t, file2.Chmod(0600))
+	require.NoError(t, fd.Close())
+	vfs.WaitForWriters(waitForWritersDelay)
+	o, err = r.Fremote.NewObject(ctx, "file2")
+	require.NoError(t, err)
+	m, err = fs.GetMetadata(ctx, o)
+	require.NoError(t, err)
+	assert.Equal(t, "600", m[fs.MetadataMode])
+	assert.Equal(t, os.FileMode(0600), file2.Mode())
+}
@@ -0,0 +1,180 @@
+package vfs
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/list"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// ChangeNotifyFn is called when an entry in the directory tree has
+// been changed on the remote and the directory cache invalidated.
+//
+// parent is the cached directory the entry is in, leaf is its name and
+// node is the cached entry, or nil if it wasn't cached.
+//
+// It is used by the mounts to invalidate the kernel cache.
+type ChangeNotifyFn func(parent *Dir, leaf string, node Node)
+
+// RegisterChangeNotify registers fn to be called whenever a change on
+// the remote is noticed, either from the backend's ChangeNotify or
+// from polling the listings.
+func (vfs *VFS) RegisterChangeNotify(fn ChangeNotifyFn) {
+	vfs.notifyMu.Lock()
+	vfs.notifyFns = append(vfs.notifyFns, fn)
+	vfs.notifyMu.Unlock()
+}
+
+// notifyChange calls the registered ChangeNotifyFn for absPath
+func (vfs *VFS) notifyChange(absPath string) {
+	vfs.notifyMu.Lock()
+	fns := vfs.notifyFns
+	vfs.notifyMu.Unlock()
+	if len(fns) == 0 || absPath == "" {
+		return
+	}
+	parent, ok := vfs.root.cachedNode(vfscommon.FindParent(absPath)).(*Dir)
+	if !ok {
+		return
+	}
+	leaf := path.Base(absPath)
+	parent.mu.RLock()
+	node, ok := parent.items[leaf]
+	if !ok && vfs.isLink(leaf) {
+		// the change was to the file behind a symlink
+		leaf = leaf[:len(leaf)-len(vfscommon.LinkSuffix)]
+		node = parent.items[leaf]
+	}
+	parent.mu.RUnlock()
+	for _, fn := range fns {
+		fn(parent, leaf, node)
+	}
+}
+
+// pollListings polls the cached directories for changes every
+// PollInterval until quit is closed.
+//
+// This is used for remotes which can't notify changes themselves.
+func (vfs *VFS) pollListings(quit <-chan struct{}) {
+	ctx := vfs.ctx
+	ticker := time.NewTicker(vfs.Opt.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			vfs.pollListingsOnce(ctx)
+		case <-quit:
+			return
+		}
+	}
+}
+
+// pollListingsOnce re-lists each cached directory once and notifies
+// any changes found
+func (vfs *VFS) pollListingsOnce(ctx context.Context) {
+	var dirs []*Dir
+	vfs.root.walk(func(d *Dir) {
+		// NB d.mu is held by walk() here
+		if !d.read.IsZero() {
+			dirs = append(dirs, d)
+		}
+	})
+	changes := 0
+	for _, d := range dirs {
+		changes += d.pollListing(ctx)
+	}
+	if changes > 0 {
+		fs.Debugf(vfs.f, "Polling %d directories found %d changes", len(dirs), changes)
+	}
+}
+
+// pollListing re-lists the directory and calls changeNotify for each
+// entry which differs from the cache, returning the number found.
+func (d *Dir) pollListing(ctx context.Context) (changes int) {
+	d.mu.RLock()
+	dirPath, read := d.path, d.read
+	d.mu.RUnlock()
+	if read.IsZero() {
+		// already invalidated
+		return 0
+	}
+	entries, err := list.DirSorted(ctx, d.f, false, dirPath)
+	if err == fs.ErrorDirNotFound {
+		// treat as empty as we create directories on the fly
+	} else if err != nil {
+		fs.Debugf(dirPath, "Failed to poll directory: %v", err)
+		return 0
+	}
+
+	type change struct {
+		leaf      string
+		entryType fs.EntryType
+	}
+	var found []change
+	listed := make(map[string]struct{}, len(entries))
+	d.mu.RLock()
+	for _, entry := range entries {
+		leaf := d.vfs.entryName(entry)
+		listed[leaf] = struct{}{}
+		if _, isVirtual := d.virtual[leaf]; isVirtual {
+			continue
+		}
+		node := d.items[leaf]
+		switch x := entry.(type) {
+		case fs.Object:
+			file, ok := node.(*File)
+			if !ok || d._fileChanged(file, x) {
+				found = append(found, change{leaf, fs.EntryObject})
+			}
+		case fs.Directory:
+			if node == nil || !node.IsDir() {
+				found = append(found, change{leaf, fs.EntryDirectory})
+			}
+		}
+	}
+	for leaf, node := range d.items {
+		if _, ok := listed[leaf]; ok {
+			continue
+		}
+		if _, isVirtual := d.virtual[leaf]; isVirtual {
+			continue
+		}
+		entryType := fs.EntryObject
+		if node.IsDir() {
+			entryType = fs.EntryDirectory
+		}
+		found = append(found, change{leaf, entryType})
+	}
+	d.mu.RUnlock()
+
+	for _, c := range found {
+		fs.Debugf(path.Join(dirPath, c.leaf), "Change noticed by polling")
+		d.changeNotify(c.leaf, c.entryType)
+	}
+	return len(found)
+}
+
+// _fileChanged returns true if the object listed is different to the
+// cached file - must be called with the lock held
+func (d *Dir) _fileChanged(file *File, o fs.Object) bool {
+	cached := file.getObject()
+	if cached == nil {
+		// file is being created
+		return false
+	}
+	if cached.Size() != o.Size() {
+		return true
+	}
+	if d.vfs.Opt.NoModTime {
+		return false
+	}
+	ctx := context.TODO()
+	dt := cached.ModTime(ctx).Sub(o.ModTime(ctx))
+	if dt < 0 {
+		dt = -dt
+	}
+	return dt >= fs.GetModifyWindow(ctx, d.f)
+}
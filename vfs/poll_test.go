@@ -0,0 +1,115 @@
+package vfs
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// changeRecorder records the calls to a ChangeNotifyFn
+type changeRecorder struct {
+	mu      sync.Mutex
+	changes []string
+}
+
+func (cr *changeRecorder) notify(parent *Dir, leaf string, node Node) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	what := parent.Path() + "|" + leaf
+	if node != nil {
+		what += "|cached"
+	}
+	cr.changes = append(cr.changes, what)
+}
+
+func (cr *changeRecorder) get() []string {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	changes := cr.changes
+	cr.changes = nil
+	sort.Strings(changes)
+	return changes
+}
+
+func TestVFSChangeNotify(t *testing.T) {
+	r, vfs, cleanup := newTestVFS(t)
+	defer cleanup()
+	var cr changeRecorder
+	vfs.RegisterChangeNotify(cr.notify)
+
+	file1 := r.WriteObject(context.Background(), "dir/file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+	_, err := vfs.Stat("dir/file1")
+	require.NoError(t, err)
+
+	// Changes to cached entries are passed on
+	vfs.root.changeNotify("dir/file1", fs.EntryObject)
+	assert.Equal(t, []string{"dir|file1|cached"}, cr.get())
+
+	// As are ones which aren't cached
+	vfs.root.changeNotify("dir/potato", fs.EntryObject)
+	assert.Equal(t, []string{"dir|potato"}, cr.get())
+
+	// But not ones in directories which aren't cached
+	vfs.root.changeNotify("other/potato", fs.EntryObject)
+	assert.Equal(t, []string(nil), cr.get())
+}
+
+func TestVFSPollListings(t *testing.T) {
+	ctx := context.Background()
+	r, vfs, cleanup := newTestVFS(t)
+	defer cleanup()
+	var cr changeRecorder
+	vfs.RegisterChangeNotify(cr.notify)
+
+	file1 := r.WriteObject(ctx, "dir/file1", "file1 contents", t1)
+	file2 := r.WriteObject(ctx, "dir/file2", "file2 contents", t1)
+	file3 := r.WriteObject(ctx, "file3", "file3", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	// Nothing is polled until it is cached
+	vfs.pollListingsOnce(ctx)
+	assert.Equal(t, []string(nil), cr.get())
+
+	_, err := vfs.ReadDir("dir")
+	require.NoError(t, err)
+
+	// No changes means no notifications
+	vfs.pollListingsOnce(ctx)
+	assert.Equal(t, []string(nil), cr.get())
+
+	// Change the remote without the VFS knowing
+	file1 = r.WriteObject(ctx, "dir/file1", "file1 contents changed", t2)
+	newFile := r.WriteObject(ctx, "dir/new", "new", t2)
+	o, err := r.Fremote.NewObject(ctx, "dir/file2")
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	fstest.CheckItems(t, r.Fremote, file1, file3, newFile)
+
+	vfs.pollListingsOnce(ctx)
+	assert.Equal(t, []string{
+		"dir|file1|cached",
+		"dir|file2|cached",
+		"dir|new",
+	}, cr.get())
+
+	// The directory was invalidated so will be re-read
+	dir := vfs.root.cachedDir("dir")
+	require.NotNil(t, dir)
+	dir.mu.RLock()
+	assert.True(t, dir.read.IsZero())
+	dir.mu.RUnlock()
+	items, err := vfs.ReadDir("dir")
+	require.NoError(t, err)
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Name())
+	}
+	assert.Equal(t, []string{"file1", "new"}, names)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/asyncreader"
 	"github.com/rclone/rclone/fs/chunkedreader"
 	"github.com/rclone/rclone/fs/hash"
 )
@@ -32,6 +33,9 @@ type ReadFileHandle struct {
 	hash        *hash.MultiHasher
 	opened      bool
 	remote      string
+	readAhead   int64 // current size of the read ahead window
+	maxAhead    int64 // the read ahead window can grow to this
+	seqBytes    int64 // bytes read sequentially since the last seek
 }
 
 // Check interfaces
@@ -79,12 +83,41 @@ func (fh *ReadFileHandle) openPending() (err error) {
 	}
 	tr := accounting.GlobalStats().NewTransfer(o)
 	fh.done = tr.Done
-	fh.r = tr.Account(context.TODO(), r).WithBuffer() // account the transfer
+	// The read ahead window grows from a single buffer up to
+	// --buffer-size plus --vfs-read-ahead on sequential reads
+	if bufferSize := fs.GetConfig(context.TODO()).BufferSize; bufferSize > 0 {
+		fh.maxAhead = int64(bufferSize) + int64(fh.file.VFS().Opt.ReadAhead)
+	}
+	fh.r = tr.Account(context.TODO(), r).WithReadAhead(fh.maxAhead) // account the transfer
+	fh.resetReadAhead()
 	fh.opened = true
 
 	return nil
 }
 
+// resetReadAhead shrinks the read ahead window back to its initial
+// size - call with the lock held
+func (fh *ReadFileHandle) resetReadAhead() {
+	fh.readAhead = asyncreader.BufferSize
+	fh.seqBytes = 0
+}
+
+// growReadAhead notes that n bytes were read sequentially and doubles
+// the read ahead window each time a window's worth has been read,
+// without seeking, up to maxAhead - call with the lock held
+func (fh *ReadFileHandle) growReadAhead(n int) {
+	fh.seqBytes += int64(n)
+	if fh.seqBytes < fh.readAhead || fh.readAhead >= fh.maxAhead {
+		return
+	}
+	fh.readAhead *= 2
+	if fh.readAhead > fh.maxAhead {
+		fh.readAhead = fh.maxAhead
+	}
+	fs.Debugf(fh.remote, "ReadFileHandle.Read sequential read: increasing read ahead to %v", fs.SizeSuffix(fh.readAhead))
+	fh.r.GrowBuffer(fh.readAhead)
+}
+
 // String converts it to printable
 func (fh *ReadFileHandle) String() string {
 	if fh == nil {
@@ -159,6 +192,7 @@ func (fh *ReadFileHandle) seek(offset int64, reopen bool) (err error) {
 		}
 	}
 	fh.r.UpdateReader(context.TODO(), r)
+	fh.resetReadAhead() // UpdateReader made a new small buffer
 	fh.offset = offset
 	return nil
 }
@@ -214,7 +248,7 @@ func (fh *ReadFileHandle) ReadAt(p []byte, off int64) (n int, err error) {
 
 // This waits for *poff to equal off or aborts after the timeout.
 //
-// Waits here potentially affect all seeks so need to keep them short
+// # Waits here potentially affect all seeks so need to keep them short
 //
 // Call with fh.mu Locked
 func waitSequential(what string, remote string, cond *sync.Cond, maxWait time.Duration, poff *int64, off int64) {
@@ -325,6 +359,7 @@ func (fh *ReadFileHandle) readAt(p []byte, off int64) (n int, err error) {
 		fs.Errorf(fh.remote, "ReadFileHandle.Read error: %v", err)
 	} else {
 		fh.offset = newOffset
+		fh.growReadAhead(n)
 		// fs.Debugf(fh.remote, "ReadFileHandle.Read OK")
 
 		if fh.hash != nil {
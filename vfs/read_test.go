@@ -4,8 +4,10 @@ import (
 	"context"
 	"io"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/rclone/rclone/fs/asyncreader"
 	"github.com/rclone/rclone/fstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -122,6 +124,43 @@ func TestReadFileHandleSeek(t *testing.T) {
 	assert.Equal(t, nil, fh.Close())
 }
 
+func TestReadFileHandleReadAhead(t *testing.T) {
+	r, vfs, cleanup := newTestVFS(t)
+	defer cleanup()
+
+	contents := strings.Repeat("0123456789abcdef", 4*asyncreader.BufferSize/16)
+	file1 := r.WriteObject(context.Background(), "file1", contents, t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+	h, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+	require.NoError(t, err)
+	fh := h.(*ReadFileHandle)
+
+	readAhead := func() int {
+		ar := fh.r.GetAsyncReader()
+		require.NotNil(t, ar)
+		return ar.ReadAhead()
+	}
+	buf := make([]byte, asyncreader.BufferSize)
+
+	// Read ahead starts small and grows on sequential reads
+	_, err = fh.ReadAt(buf[:1], 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, readAhead())
+	_, err = fh.ReadAt(buf, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, readAhead())
+	assert.Equal(t, int64(2*asyncreader.BufferSize), fh.readAhead)
+
+	// Seeking resets it
+	_, err = fh.ReadAt(buf[:16], 0)
+	require.NoError(t, err)
+	assert.Equal(t, contents[:16], string(buf[:16]))
+	assert.Equal(t, 1, readAhead())
+	assert.Equal(t, int64(asyncreader.BufferSize), fh.readAhead)
+
+	assert.NoError(t, fh.Close())
+}
+
 func TestReadFileHandleReadAt(t *testing.T) {
 	_, _, fh, cleanup := readHandleCreate(t)
 	defer cleanup()
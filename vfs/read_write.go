@@ -328,14 +328,20 @@ func (fh *RWFileHandle) _writeAt(b []byte, off int64, release bool) (n int, err
 	if err = fh.openPending(); err != nil {
 		return n, err
 	}
+	fh.writeCalled = true
 	if fh.flags&os.O_APPEND != 0 {
 		// From open(2): Before each write(2), the file offset is
 		// positioned at the end of the file, as if with lseek(2).
-		size := fh._size()
-		fh.offset = size
-		off = fh.offset
+		// The adjustment of the file offset and the write
+		// operation are performed as an atomic step.
+		off, n, err = fh.item.WriteAtEnd(b)
+		fh.offset = off
+		if err != nil {
+			return n, err
+		}
+		_ = fh._size()
+		return n, err
 	}
-	fh.writeCalled = true
 	if release {
 		// Do the writing with fh.mu unlocked
 		fh.mu.Unlock()
@@ -395,12 +401,46 @@ func (fh *RWFileHandle) Truncate(size int64) (err error) {
 	if fh.closed {
 		return ECLOSED
 	}
+	if fh.readOnly() {
+		return EBADF
+	}
+	if size < 0 {
+		return EINVAL
+	}
 	if err = fh.openPending(); err != nil {
 		return err
 	}
 	return fh._truncate(size)
 }
 
+// Allocate makes sure the file is at least off+size bytes long,
+// extending it with zeros if necessary, unless keepSize is set.
+//
+// The space can't be reserved on the remote so this doesn't reserve
+// any space in the cache either, but programs which preallocate
+// files, like databases, see the size they expect.
+func (fh *RWFileHandle) Allocate(off, size int64, keepSize bool) (err error) {
+	defer log.Trace(fh.logPrefix(), "off=%d, size=%d, keepSize=%v", off, size, keepSize)("err=%v", &err)
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	if fh.closed {
+		return ECLOSED
+	}
+	if fh.readOnly() {
+		return EBADF
+	}
+	if off < 0 || size <= 0 {
+		return EINVAL
+	}
+	if err = fh.openPending(); err != nil {
+		return err
+	}
+	if keepSize || off+size <= fh._size() {
+		return nil
+	}
+	return fh._truncate(off + size)
+}
+
 // Sync commits the current contents of the file to stable storage. Typically,
 // this means flushing the file system's in-memory copy of recently written
 // data to disk.
@@ -6,6 +6,8 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -562,6 +564,71 @@ func TestRWFileHandleSizeCreateNew(t *testing.T) {
 	assertSize(t, vfs, nil, "file1", 5)
 }
 
+func TestRWFileHandleAppendConcurrent(t *testing.T) {
+	_, vfs, fh1, cleanup := rwHandleCreateFlags(t, true, "dir/file1", os.O_WRONLY|os.O_APPEND)
+	defer cleanup()
+	h, err := vfs.OpenFile("dir/file1", os.O_WRONLY|os.O_APPEND, 0777)
+	require.NoError(t, err)
+	fh2 := h.(*RWFileHandle)
+
+	// Interleave appends from two handles - none should be lost
+	const n = 100
+	var wg sync.WaitGroup
+	for _, fh := range []*RWFileHandle{fh1, fh2} {
+		wg.Add(1)
+		go func(fh *RWFileHandle) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				_, err := fh.Write([]byte("abcd"))
+				assert.NoError(t, err)
+			}
+		}(fh)
+	}
+	wg.Wait()
+
+	assertSize(t, vfs, fh1, "dir/file1", 16+2*n*4)
+	assert.NoError(t, fh1.Close())
+	assert.NoError(t, fh2.Close())
+	assertSize(t, vfs, nil, "dir/file1", 16+2*n*4)
+}
+
+func TestRWFileHandleTruncateReadOnly(t *testing.T) {
+	_, _, fh, cleanup := rwHandleCreateReadOnly(t)
+	defer cleanup()
+
+	assert.Equal(t, EBADF, fh.Truncate(0))
+	assert.Equal(t, EBADF, fh.Allocate(0, 100, false))
+	assert.NoError(t, fh.Close())
+}
+
+func TestRWFileHandleAllocate(t *testing.T) {
+	_, vfs, fh, cleanup := rwHandleCreateFlags(t, true, "dir/file1", os.O_RDWR)
+	defer cleanup()
+
+	assert.Equal(t, EINVAL, fh.Allocate(-1, 100, false))
+	assert.Equal(t, EINVAL, fh.Allocate(0, 0, false))
+
+	// Allocating within the file leaves it alone
+	require.NoError(t, fh.Allocate(0, 10, false))
+	assertSize(t, vfs, fh, "dir/file1", 16)
+
+	// With keepSize the size doesn't change
+	require.NoError(t, fh.Allocate(10, 100, true))
+	assertSize(t, vfs, fh, "dir/file1", 16)
+
+	// Otherwise the file is extended with zeros
+	require.NoError(t, fh.Allocate(10, 20, false))
+	assertSize(t, vfs, fh, "dir/file1", 30)
+	buf := make([]byte, 30)
+	_, err := fh.ReadAt(buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789abcdef"+strings.Repeat("\x00", 14), string(buf))
+
+	assert.NoError(t, fh.Close())
+	assertSize(t, vfs, nil, "dir/file1", 30)
+	assert.Equal(t, ECLOSED, fh.Allocate(0, 100, false))
+}
+
 func testRWFileHandleOpenTest(t *testing.T, vfs *VFS, test *openTest) {
 	fileName := "open-test-file"
 
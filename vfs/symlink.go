@@ -0,0 +1,128 @@
+package vfs
+
+// This file shows files with a .rclonelink suffix as symlinks when
+// --vfs-links is in use.
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+)
+
+// isLink returns true if leaf, the name of an object on the remote,
+// should be shown as a symlink
+func (vfs *VFS) isLink(leaf string) bool {
+	return vfs.Opt.Links && len(leaf) > len(vfscommon.LinkSuffix) && strings.HasSuffix(leaf, vfscommon.LinkSuffix)
+}
+
+// entryName returns the leaf name entry is shown with in the VFS
+func (vfs *VFS) entryName(entry fs.DirEntry) string {
+	name := path.Base(entry.Remote())
+	if _, ok := entry.(fs.Object); ok && vfs.isLink(name) {
+		name = name[:len(name)-len(vfscommon.LinkSuffix)]
+	}
+	return name
+}
+
+// _name returns the leaf name the file is shown with - call with
+// the lock held
+func (f *File) _name() string {
+	if f.d.vfs.isLink(f.leaf) {
+		return f.leaf[:len(f.leaf)-len(vfscommon.LinkSuffix)]
+	}
+	return f.leaf
+}
+
+// IsSymlink returns true if the file is shown as a symlink
+func (f *File) IsSymlink() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.d.vfs.isLink(f.leaf)
+}
+
+// Readlink returns the target of the symlink
+//
+// It returns EINVAL if the file isn't a symlink.
+func (f *File) Readlink() (target string, err error) {
+	if !f.IsSymlink() {
+		return "", EINVAL
+	}
+	fd, err := f.Open(os.O_RDONLY)
+	if err != nil {
+		return "", err
+	}
+	defer fs.CheckClose(fd, &err)
+	b, err := ioutil.ReadAll(fd)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Symlink makes a symlink called name in the directory pointing to
+// target.
+//
+// It returns ENOSYS unless --vfs-links is in use.
+func (d *Dir) Symlink(target, name string) (file *File, err error) {
+	if !d.vfs.Opt.Links {
+		return nil, ENOSYS
+	}
+	if d.vfs.Opt.ReadOnly {
+		return nil, EROFS
+	}
+	_, err = d.stat(name)
+	if err == nil {
+		return nil, EEXIST
+	} else if err != ENOENT {
+		return nil, err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	file, err = d.Create(name+vfscommon.LinkSuffix, flags)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := file.Open(flags)
+	if err != nil {
+		return nil, err
+	}
+	_, err = fd.WriteString(target)
+	closeErr := fd.Close()
+	if err != nil {
+		return nil, err
+	}
+	if closeErr != nil {
+		return nil, closeErr
+	}
+	return file, nil
+}
+
+// Symlink makes newname a symlink pointing to oldname
+//
+// It returns ENOSYS unless --vfs-links is in use.
+func (vfs *VFS) Symlink(oldname, newname string) error {
+	dir, leaf, err := vfs.StatParent(newname)
+	if err != nil {
+		return err
+	}
+	_, err = dir.Symlink(oldname, leaf)
+	return err
+}
+
+// Readlink returns the target of the symlink name
+//
+// It returns EINVAL if name isn't a symlink.
+func (vfs *VFS) Readlink(name string) (string, error) {
+	node, err := vfs.Stat(name)
+	if err != nil {
+		return "", err
+	}
+	file, ok := node.(*File)
+	if !ok {
+		return "", EINVAL
+	}
+	return file.Readlink()
+}
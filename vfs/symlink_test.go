@@ -0,0 +1,90 @@
+package vfs
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymlinksDisabled(t *testing.T) {
+	r, vfs, cleanup := newTestVFS(t)
+	defer cleanup()
+	file1 := r.WriteObject(context.Background(), "link"+vfscommon.LinkSuffix, "target", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	node, err := vfs.Stat("link" + vfscommon.LinkSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0), node.Mode()&os.ModeSymlink)
+	_, err = vfs.Stat("link")
+	assert.Equal(t, ENOENT, err)
+
+	assert.Equal(t, ENOSYS, vfs.Symlink("target", "link2"))
+	_, err = vfs.Readlink("link" + vfscommon.LinkSuffix)
+	assert.Equal(t, EINVAL, err)
+}
+
+func TestSymlinks(t *testing.T) {
+	ctx := context.Background()
+	opt := vfscommon.DefaultOpt
+	opt.Links = true
+	r, vfs, cleanup := newTestVFSOpt(t, &opt)
+	defer cleanup()
+	file1 := r.WriteObject(ctx, "dir/link"+vfscommon.LinkSuffix, "../target", t1)
+	file2 := r.WriteObject(ctx, "dir/file", "contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	// Existing links are shown without the suffix
+	node, err := vfs.Stat("dir/link")
+	require.NoError(t, err)
+	assert.Equal(t, "link", node.Name())
+	assert.Equal(t, os.ModeSymlink, node.Mode()&os.ModeSymlink)
+	assert.Equal(t, int64(len("../target")), node.Size())
+	_, err = vfs.Stat("dir/link" + vfscommon.LinkSuffix)
+	assert.Equal(t, ENOENT, err)
+	target, err := vfs.Readlink("dir/link")
+	require.NoError(t, err)
+	assert.Equal(t, "../target", target)
+
+	// Other files and directories are not links
+	node, err = vfs.Stat("dir/file")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0), node.Mode()&os.ModeSymlink)
+	_, err = vfs.Readlink("dir/file")
+	assert.Equal(t, EINVAL, err)
+	_, err = vfs.Readlink("dir")
+	assert.Equal(t, EINVAL, err)
+
+	// Make a new link
+	require.NoError(t, vfs.Symlink("file", "dir/link2"))
+	assert.Equal(t, EEXIST, vfs.Symlink("file", "dir/link2"))
+	assert.Equal(t, EEXIST, vfs.Symlink("file", "dir/file"))
+	target, err = vfs.Readlink("dir/link2")
+	require.NoError(t, err)
+	assert.Equal(t, "file", target)
+	vfs.WaitForWriters(waitForWritersDelay)
+	file3 := fstest.NewItem("dir/link2"+vfscommon.LinkSuffix, "file", t1)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file2, file3}, []string{"dir"}, fs.ModTimeNotSupported)
+
+	// Renaming keeps it a link
+	require.NoError(t, vfs.Rename("dir/link2", "dir/link3"))
+	target, err = vfs.Readlink("dir/link3")
+	require.NoError(t, err)
+	assert.Equal(t, "file", target)
+	file3.Path = "dir/link3" + vfscommon.LinkSuffix
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file2, file3}, []string{"dir"}, fs.ModTimeNotSupported)
+
+	// Directory listings show the links
+	names := []string{}
+	infos, err := vfs.ReadDir("dir")
+	require.NoError(t, err)
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	assert.Equal(t, []string{"file", "link", "link3"}, names)
+}
@@ -33,10 +33,13 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fs/log"
 	"github.com/rclone/rclone/fs/walk"
+	"github.com/rclone/rclone/lib/atexit"
 	"github.com/rclone/rclone/vfs/vfscache"
 	"github.com/rclone/rclone/vfs/vfscommon"
 )
@@ -118,6 +121,7 @@ type Handle interface {
 	Flush() error
 	Release() error
 	Node() Node
+	Allocate(off, size int64, keepSize bool) error
 	//	Size() int64
 }
 
@@ -144,6 +148,7 @@ func (h baseHandle) WriteString(s string) (n int, err error)              { retu
 func (h baseHandle) Flush() (err error)                                   { return ENOSYS }
 func (h baseHandle) Release() (err error)                                 { return ENOSYS }
 func (h baseHandle) Node() Node                                           { return nil }
+func (h baseHandle) Allocate(off, size int64, keepSize bool) error        { return ENOTSUP }
 
 //func (h baseHandle) Size() int64                                          { return 0 }
 
@@ -159,6 +164,7 @@ var (
 // VFS represents the top level filing system
 type VFS struct {
 	f           fs.Fs
+	ctx         context.Context // for the filters and global config used when listing
 	root        *Dir
 	Opt         vfscommon.Options
 	cache       *vfscache.Cache
@@ -168,6 +174,14 @@ type VFS struct {
 	usage       *fs.Usage
 	pollChan    chan time.Duration
 	inUse       int32 // count of number of opens accessed with atomic
+
+	dirCacheMu        sync.Mutex      // serialises saving the directory cache
+	dirCacheRefreshed chan struct{}   // closed when the persisted directory cache has been refreshed
+	dirCacheAtExit    atexit.FnHandle // saves the directory cache on exit
+
+	notifyMu  sync.Mutex       // protects notifyFns
+	notifyFns []ChangeNotifyFn // called when changes are noticed on the remote
+	pollQuit  chan struct{}    // close to stop polling the listings, nil if not polling
 }
 
 // Keep track of active VFS keyed on fs.ConfigString(f)
@@ -179,11 +193,28 @@ var (
 // New creates a new VFS and root directory.  If opt is nil, then
 // DefaultOpt will be used
 func New(f fs.Fs, opt *vfscommon.Options) *VFS {
+	return NewWithContext(context.Background(), f, opt)
+}
+
+// NewWithContext creates a new VFS and root directory like New but
+// uses the filters and global config in ctx when listing directories.
+//
+// ctx is only used for its values so it may be cancelled when this
+// returns.
+func NewWithContext(ctx context.Context, f fs.Fs, opt *vfscommon.Options) *VFS {
 	fsDir := fs.NewDir("", time.Now())
 	vfs := &VFS{
 		f:     f,
+		ctx:   filter.CopyConfig(fs.CopyConfig(context.Background(), ctx), ctx),
 		inUse: int32(1),
 	}
+	// The VFS caches directories itself for --dir-cache-time so
+	// don't use the on disk listing cache too
+	if fs.GetConfig(vfs.ctx).UseListingCache {
+		var ci *fs.ConfigInfo
+		vfs.ctx, ci = fs.AddConfig(vfs.ctx)
+		ci.UseListingCache = false
+	}
 
 	// Make a copy of the options
 	if opt != nil {
@@ -204,7 +235,7 @@ func New(f fs.Fs, opt *vfscommon.Options) *VFS {
 	defer activeMu.Unlock()
 	configName := fs.ConfigString(f)
 	for _, activeVFS := range active[configName] {
-		if vfs.Opt == activeVFS.Opt {
+		if vfs.Opt == activeVFS.Opt && filter.GetConfig(vfs.ctx) == filter.GetConfig(activeVFS.ctx) {
 			fs.Debugf(f, "Re-using VFS from active cache")
 			atomic.AddInt32(&activeVFS.inUse, 1)
 			return activeVFS
@@ -216,6 +247,11 @@ func New(f fs.Fs, opt *vfscommon.Options) *VFS {
 	// Create root directory
 	vfs.root = newDir(vfs, f, nil, fsDir)
 
+	// Load the directory cache from disk if required
+	if vfs.Opt.DirCachePersist {
+		vfs.startDirCache()
+	}
+
 	// Start polling function
 	features := vfs.f.Features()
 	if do := features.ChangeNotify; do != nil {
@@ -223,7 +259,12 @@ func New(f fs.Fs, opt *vfscommon.Options) *VFS {
 		do(context.TODO(), vfs.root.changeNotify, vfs.pollChan)
 		vfs.pollChan <- vfs.Opt.PollInterval
 	} else if vfs.Opt.PollInterval > 0 {
-		fs.Infof(f, "poll-interval is not supported by this remote")
+		if vfs.Opt.PollListings {
+			vfs.pollQuit = make(chan struct{})
+			go vfs.pollListings(vfs.pollQuit)
+		} else {
+			fs.Infof(f, "poll-interval is not supported by this remote - use --vfs-poll-listings to poll by re-reading directories")
+		}
 	}
 
 	// Warn if can't stream
@@ -307,6 +348,13 @@ func (vfs *VFS) Shutdown() {
 	}
 	activeMu.Unlock()
 
+	if vfs.pollQuit != nil {
+		close(vfs.pollQuit)
+		vfs.pollQuit = nil
+	}
+	if vfs.Opt.DirCachePersist {
+		vfs.stopDirCache()
+	}
 	vfs.shutdownCache()
 }
 
@@ -539,6 +587,10 @@ func fillInMissingSizes(total, used, free, unknownFree int64) (newTotal, newUsed
 	// used is now defined
 	if free < 0 {
 		free = total - used
+		if free < 0 {
+			// used can be more than the quota
+			free = 0
+		}
 	}
 	return total, used, free
 }
@@ -546,9 +598,43 @@ func fillInMissingSizes(total, used, free, unknownFree int64) (newTotal, newUsed
 // If the total size isn't known then we will aim for this many bytes free (1 PiB)
 const unknownFreeBytes = 1 << 50
 
+// readUsage reads the usage of the remote from About, if supported,
+// and by adding up the sizes of the objects if --vfs-used-is-size is
+// set.
+func (vfs *VFS) readUsage(doAbout func(ctx context.Context) (*fs.Usage, error)) (usage *fs.Usage, err error) {
+	ctx := vfs.ctx
+	usage = &fs.Usage{}
+	if doAbout != nil {
+		usage, err = doAbout(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if usage == nil {
+			usage = &fs.Usage{}
+		}
+	}
+	if vfs.Opt.UsedIsSize {
+		var usedBySizeAlgorithm int64 = 0
+		// Algorithm from `rclone size`
+		err = walk.ListR(ctx, vfs.f, "", true, -1, walk.ListObjects, func(entries fs.DirEntries) error {
+			entries.ForObject(func(o fs.Object) {
+				usedBySizeAlgorithm += nonNegative(o.Size())
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read size of remote")
+		}
+		usage.Used = &usedBySizeAlgorithm
+	}
+	return usage, nil
+}
+
 // Statfs returns into about the filing system if known
 //
-// The values will be -1 if they aren't known
+// Values which the remote doesn't report are worked out from the
+// others if possible, otherwise the free space is reported as a very
+// large number.
 //
 // This information is cached for the DirCacheTime interval
 func (vfs *VFS) Statfs() (total, used, free int64) {
@@ -558,28 +644,14 @@ func (vfs *VFS) Statfs() (total, used, free int64) {
 	total, used, free = -1, -1, -1
 	doAbout := vfs.f.Features().About
 	if (doAbout != nil || vfs.Opt.UsedIsSize) && (vfs.usageTime.IsZero() || time.Since(vfs.usageTime) >= vfs.Opt.DirCacheTime) {
-		var err error
-		ctx := context.TODO()
-		if doAbout == nil {
-			vfs.usage = &fs.Usage{}
-		} else {
-			vfs.usage, err = doAbout(ctx)
-		}
-		if vfs.Opt.UsedIsSize {
-			var usedBySizeAlgorithm int64 = 0
-			// Algorithm from `rclone size`
-			err = walk.ListR(ctx, vfs.f, "", true, -1, walk.ListObjects, func(entries fs.DirEntries) error {
-				entries.ForObject(func(o fs.Object) {
-					usedBySizeAlgorithm += o.Size()
-				})
-				return nil
-			})
-			vfs.usage.Used = &usedBySizeAlgorithm
-		}
+		usage, err := vfs.readUsage(doAbout)
 		vfs.usageTime = time.Now()
 		if err != nil {
+			// carry on with the previous values if there are any
+			// rather than reporting a made up size
 			fs.Errorf(vfs.f, "Statfs failed: %v", err)
-			return
+		} else {
+			vfs.usage = usage
 		}
 	}
 	if u := vfs.usage; u != nil {
@@ -13,6 +13,7 @@ import (
 	"github.com/pkg/errors"
 	_ "github.com/rclone/rclone/backend/all" // import all the backends
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
@@ -107,6 +108,9 @@ func TestVFSbaseHandle(t *testing.T) {
 	err = fh.Truncate(0)
 	assert.Equal(t, ENOSYS, err)
 
+	err = fh.Allocate(0, 1, false)
+	assert.Equal(t, ENOTSUP, err)
+
 	_, err = fh.Write(nil)
 	assert.Equal(t, ENOSYS, err)
 
@@ -176,6 +180,42 @@ func TestVFSNewWithOpts(t *testing.T) {
 	assert.Equal(t, os.FileMode(0664), vfs.Opt.FilePerms)
 }
 
+// TestVFSNewWithContext checks the filters in the context are used
+func TestVFSNewWithContext(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject(ctx, "dir/file1.txt", "file1 contents", t1)
+	file2 := r.WriteObject(ctx, "dir/file2.jpg", "file2 contents", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	fi, err := filter.NewFilter(nil)
+	require.NoError(t, err)
+	require.NoError(t, fi.AddRule("- *.jpg"))
+	filterCtx, cancel := context.WithCancel(filter.ReplaceConfig(ctx, fi))
+
+	vfs := NewWithContext(filterCtx, r.Fremote, nil)
+	defer cleanupVFS(t, vfs)
+	// the context should only be used for its values
+	cancel()
+
+	// A VFS without the filters isn't shared
+	vfs2 := New(r.Fremote, nil)
+	defer cleanupVFS(t, vfs2)
+	assert.NotEqual(t, fmt.Sprintf("%p", vfs), fmt.Sprintf("%p", vfs2))
+
+	readDir := func(vfs *VFS) (names []string) {
+		items, err := vfs.ReadDir("dir")
+		require.NoError(t, err)
+		for _, item := range items {
+			names = append(names, item.Name())
+		}
+		return names
+	}
+	assert.Equal(t, []string{"file1.txt"}, readDir(vfs))
+	assert.Equal(t, []string{"file1.txt", "file2.jpg"}, readDir(vfs2))
+}
+
 // TestRoot checks root directory is present and correct
 func TestVFSRoot(t *testing.T) {
 	_, vfs, cleanup := newTestVFS(t)
@@ -372,6 +412,25 @@ func TestVFSStatfs(t *testing.T) {
 	assert.Equal(t, oldTime, vfs.usageTime)
 }
 
+func TestVFSStatfsUsedIsSize(t *testing.T) {
+	opt := vfscommon.DefaultOpt
+	opt.UsedIsSize = true
+	r, vfs, cleanup := newTestVFSOpt(t, &opt)
+	defer cleanup()
+
+	file1 := r.WriteObject(context.Background(), "file1", "file1 contents", t1)
+	file2 := r.WriteObject(context.Background(), "dir/file2", "file2 contents!", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	total, used, free := vfs.Statfs()
+	assert.Equal(t, file1.Size+file2.Size, used)
+	assert.True(t, total >= used)
+	assert.True(t, free >= 0)
+	require.NotNil(t, vfs.usage)
+	require.NotNil(t, vfs.usage.Used)
+	assert.Equal(t, used, *vfs.usage.Used)
+}
+
 func TestFillInMissingSizes(t *testing.T) {
 	const unknownFree = 10
 	for _, test := range []struct {
@@ -410,6 +469,10 @@ func TestFillInMissingSizes(t *testing.T) {
 			total: -1, free: -1, used: -1,
 			wantTotal: 10, wantFree: 10, wantUsed: 0,
 		},
+		{
+			total: 20, free: -1, used: 25,
+			wantTotal: 20, wantFree: 0, wantUsed: 25,
+		},
 	} {
 		t.Run(fmt.Sprintf("total=%d,free=%d,used=%d", test.total, test.free, test.used), func(t *testing.T) {
 			gotTotal, gotUsed, gotFree := fillInMissingSizes(test.total, test.used, test.free, unknownFree)
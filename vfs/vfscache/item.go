@@ -1288,6 +1288,38 @@ func (item *Item) WriteAt(b []byte, off int64) (n int, err error) {
 	return n, err
 }
 
+// WriteAtEnd writes b to the end of the file returning the offset it
+// was written at.
+//
+// Unlike reading the size then calling WriteAt this holds the lock
+// while writing so concurrent appends from different handles can't
+// overwrite each other.
+func (item *Item) WriteAtEnd(b []byte) (off int64, n int, err error) {
+	item.preAccess()
+	defer item.postAccess()
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	if item.fd == nil {
+		return 0, 0, errors.New("vfs cache item WriteAtEnd: internal error: didn't Open file")
+	}
+	off, err = item._getSize()
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "vfs cache item WriteAtEnd: failed to read size")
+	}
+	n, err = item.fd.WriteAt(b, off)
+	if err == nil && n != len(b) {
+		err = errors.Errorf("short write: tried to write %d but only %d written", len(b), n)
+	}
+	item._written(off, int64(n))
+	if n > 0 {
+		item._dirty()
+	}
+	if end := off + int64(n); end > item.info.Size {
+		item.info.Size = end
+	}
+	return off, n, err
+}
+
 // WriteAtNoOverwrite writes b to the file, but will not overwrite
 // already present ranges.
 //
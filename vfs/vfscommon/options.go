@@ -15,12 +15,17 @@ type Options struct {
 	ReadOnly          bool          // if set VFS is read only
 	NoModTime         bool          // don't read mod times for files
 	DirCacheTime      time.Duration // how long to consider directory listing cache valid
+	DirCachePersist   bool          // save the directory cache to disk and reload it on start
 	PollInterval      time.Duration
+	PollListings      bool // poll by re-reading directories if the remote can't notify changes
+	LazyListing       bool // return directory entries page by page as they are listed
 	Umask             int
 	UID               uint32
 	GID               uint32
 	DirPerms          os.FileMode
 	FilePerms         os.FileMode
+	Metadata          bool          // use the mode, owner and xattrs from the remote if it supports them
+	Links             bool          // show files with LinkSuffix as symlinks
 	ChunkSize         fs.SizeSuffix // if > 0 read files in chunks
 	ChunkSizeLimit    fs.SizeSuffix // if > ChunkSize double the chunk size after each chunk until reached
 	CacheMode         CacheMode
@@ -31,7 +36,7 @@ type Options struct {
 	WriteWait         time.Duration // time to wait for in-sequence write
 	ReadWait          time.Duration // time to wait for in-sequence read
 	WriteBack         time.Duration // time to wait before writing back dirty files
-	ReadAhead         fs.SizeSuffix // bytes to read ahead in cache mode "full"
+	ReadAhead         fs.SizeSuffix // bytes to read ahead over --buffer-size
 	UsedIsSize        bool          // if true, use the `rclone size` algorithm for Used size
 }
 
@@ -5,6 +5,11 @@ import (
 	"path/filepath"
 )
 
+// LinkSuffix is the suffix of the files which are shown as symlinks
+// with --vfs-links. It is the same as the local backend uses for
+// --links.
+const LinkSuffix = ".rclonelink"
+
 // OsFindParent returns the parent directory of name, or "" for the
 // root for OS native paths.
 func OsFindParent(name string) string {
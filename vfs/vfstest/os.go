@@ -49,6 +49,11 @@ func (f realOsFile) Node() vfs.Node {
 	return nil
 }
 
+// Allocate
+func (f realOsFile) Allocate(off, size int64, keepSize bool) error {
+	return vfs.ENOTSUP
+}
+
 // Chtimes
 func (r realOs) Chtimes(name string, atime time.Time, mtime time.Time) error {
 	return os.Chtimes(name, atime, mtime)